@@ -0,0 +1,462 @@
+// Package acp lets editors use Crush as their agent through the Agent Client
+// Protocol, mapping its sessions and tool permissions onto the ones of the
+// app.
+package acp
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/version"
+)
+
+// The options of the permission requests, by the action of the permission
+// service they're answered with.
+const (
+	optionAllow        = "allow"
+	optionAllowSession = "allow_session"
+	optionDeny         = "deny"
+)
+
+// Serve runs the agent side of ACP for the app, reading the messages of
+// the client from input and writing the ones of the agent to output, until
+// input is closed.
+func Serve(ctx context.Context, app *app.App, input io.Reader, output io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := newServer(app, newConn(output))
+	s.forward(ctx)
+	return s.conn.serve(ctx, input, s.handle)
+}
+
+type server struct {
+	app  *app.App
+	conn *conn
+
+	// sessions are the sessions created or loaded by the client.
+	sessions *csync.Map[string, bool]
+
+	// sentMu guards what was already sent of the messages: the length of
+	// their text and reasoning, and the tool calls and results.
+	sentMu         sync.Mutex
+	textBytes      map[string]int
+	reasoningBytes map[string]int
+	sent           map[string]bool
+}
+
+func newServer(app *app.App, conn *conn) *server {
+	return &server{
+		app:            app,
+		conn:           conn,
+		sessions:       csync.NewMap[string, bool](),
+		textBytes:      make(map[string]int),
+		reasoningBytes: make(map[string]int),
+		sent:           make(map[string]bool),
+	}
+}
+
+func (s *server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p initializeParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams("invalid initialize params: %v", err)
+		}
+		return initializeResult{
+			ProtocolVersion: protocolVersion,
+			AgentCapabilities: agentCapabilities{
+				LoadSession: true,
+				PromptCapabilities: promptCapabilities{
+					Image:           true,
+					EmbeddedContext: true,
+				},
+			},
+			AgentInfo:   implementation{Name: "crush", Title: "Crush", Version: version.Version},
+			AuthMethods: []struct{}{},
+		}, nil
+	case "authenticate":
+		// Providers are authenticated by the configuration of Crush.
+		return struct{}{}, nil
+	case "session/new":
+		var p newSessionParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams("invalid session/new params: %v", err)
+		}
+		s.checkSession(p.Cwd, p.MCPServers)
+		sess, err := s.app.Sessions.Create(ctx, "New Session")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create session: %w", err)
+		}
+		s.sessions.Set(sess.ID, true)
+		return newSessionResult{SessionID: sess.ID}, nil
+	case "session/load":
+		var p loadSessionParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams("invalid session/load params: %v", err)
+		}
+		s.checkSession(p.Cwd, p.MCPServers)
+		return nil, s.load(ctx, p.SessionID)
+	case "session/prompt":
+		var p promptParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams("invalid session/prompt params: %v", err)
+		}
+		return s.prompt(ctx, p)
+	case "session/cancel":
+		var p cancelParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams("invalid session/cancel params: %v", err)
+		}
+		s.app.AgentCoordinator.Cancel(p.SessionID)
+		return nil, nil
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// checkSession warns about what the client asks for that Crush doesn't do:
+// working in another directory than the one it started in, and connecting
+// to the MCP servers of the client.
+func (s *server) checkSession(cwd string, mcpServers []json.RawMessage) {
+	if cwd != "" && filepath.Clean(cwd) != filepath.Clean(s.app.Config().WorkingDir()) {
+		slog.Warn("ACP session asked for another working directory", "cwd", cwd, "working_dir", s.app.Config().WorkingDir())
+	}
+	if len(mcpServers) > 0 {
+		slog.Warn("ACP client MCP servers are ignored, configure them in Crush instead", "count", len(mcpServers))
+	}
+}
+
+// load replays the messages of a session to the client.
+func (s *server) load(ctx context.Context, sessionID string) error {
+	if _, err := s.app.Sessions.Get(ctx, sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return invalidParams("session %s not found", sessionID)
+		}
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := s.app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list session messages: %w", err)
+	}
+	for _, msg := range msgs {
+		if msg.Role == message.User {
+			if text := msg.Content().Text; text != "" {
+				s.update(sessionID, sessionUpdate{SessionUpdate: updateUserMessage, Content: textBlock(text)})
+			}
+			continue
+		}
+		s.message(msg)
+	}
+	s.sessions.Set(sessionID, true)
+	return nil
+}
+
+// prompt runs a prompt turn in the session, returning once it's over.
+func (s *server) prompt(ctx context.Context, p promptParams) (promptResult, error) {
+	if _, ok := s.sessions.Get(p.SessionID); !ok {
+		return promptResult{}, invalidParams("session %s wasn't created or loaded", p.SessionID)
+	}
+	if s.app.AgentCoordinator.IsSessionBusy(p.SessionID) {
+		return promptResult{}, agent.ErrSessionBusy
+	}
+	text, attachments, err := promptContent(p.Prompt)
+	if err != nil {
+		return promptResult{}, invalidParams("%v", err)
+	}
+
+	_, runErr := s.app.AgentCoordinator.Run(ctx, p.SessionID, text, attachments...)
+
+	// The last updates may have been dropped by the subscription, send what's
+	// left from the database.
+	msgs, err := s.app.Messages.List(context.WithoutCancel(ctx), p.SessionID)
+	if err == nil {
+		for _, msg := range msgs {
+			s.message(msg)
+		}
+	}
+
+	switch {
+	case errors.Is(runErr, context.Canceled) || errors.Is(runErr, agent.ErrRequestCancelled):
+		return promptResult{StopReason: stopCancelled}, nil
+	case runErr != nil:
+		return promptResult{}, runErr
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role != message.Assistant {
+			continue
+		}
+		switch msgs[i].FinishReason() {
+		case message.FinishReasonMaxTokens:
+			return promptResult{StopReason: stopMaxTokens}, nil
+		case message.FinishReasonCanceled:
+			return promptResult{StopReason: stopCancelled}, nil
+		}
+		break
+	}
+	return promptResult{StopReason: stopEndTurn}, nil
+}
+
+// promptContent returns the text of a prompt and its attachments: the
+// images and the resources embedded in it. The resources linked to are
+// referred to by their path for the agent to read them.
+func promptContent(blocks []contentBlock) (string, []message.Attachment, error) {
+	var text []string
+	var attachments []message.Attachment
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "image":
+			data, err := base64.StdEncoding.DecodeString(block.Data)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid image data: %w", err)
+			}
+			name := cmp.Or(filepath.Base(uriPath(block.URI)), "image")
+			attachments = append(attachments, message.Attachment{FileName: name, MimeType: block.MimeType, Content: data})
+		case "resource_link":
+			text = append(text, "@"+cmp.Or(uriPath(block.URI), block.Name))
+		case "resource":
+			if block.Resource == nil || block.Resource.Text == "" {
+				continue
+			}
+			path := uriPath(block.Resource.URI)
+			attachments = append(attachments, message.Attachment{
+				FilePath: path,
+				FileName: filepath.Base(path),
+				MimeType: cmp.Or(block.Resource.MimeType, "text/plain"),
+				Content:  []byte(block.Resource.Text),
+			})
+		}
+	}
+	prompt := strings.TrimSpace(strings.Join(text, "\n"))
+	if prompt == "" {
+		return "", nil, errors.New("the prompt has no text")
+	}
+	return prompt, attachments, nil
+}
+
+// uriPath returns the path of a file URI, or the URI itself otherwise.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// forward sends the updates of the messages of the sessions of the client,
+// and asks it for the permissions the agent requests, until ctx is done.
+func (s *server) forward(ctx context.Context) {
+	messages := s.app.Messages.Subscribe(ctx)
+	permissions := s.app.Permissions.Subscribe(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-messages:
+				if _, ok := s.sessions.Get(event.Payload.SessionID); ok && event.Payload.Role != message.User {
+					s.message(event.Payload)
+				}
+			case event := <-permissions:
+				go s.requestPermission(ctx, event.Payload)
+			}
+		}
+	}()
+}
+
+// requestPermission asks the client for a permission the agent requests,
+// denying it when it's canceled.
+func (s *server) requestPermission(ctx context.Context, req permission.PermissionRequest) {
+	sessionID, ok := s.clientSession(ctx, req.SessionID)
+	if !ok {
+		slog.Warn("Denying permission requested outside of the ACP sessions", "session_id", req.SessionID, "tool", req.ToolName)
+		s.app.Permissions.Deny(req)
+		return
+	}
+	params := requestPermissionParams{
+		SessionID: sessionID,
+		ToolCall: sessionUpdate{
+			ToolCallID: req.ToolCallID,
+			Title:      cmp.Or(req.Description, req.ToolName),
+			Kind:       toolKind(req.ToolName),
+			Status:     toolPending,
+		},
+		Options: []permissionOption{
+			{OptionID: optionAllow, Name: "Allow", Kind: "allow_once"},
+			{OptionID: optionAllowSession, Name: "Allow for this session", Kind: "allow_always"},
+			{OptionID: optionDeny, Name: "Deny", Kind: "reject_once"},
+		},
+	}
+	if req.Path != "" {
+		params.ToolCall.Locations = []toolCallLocation{{Path: req.Path}}
+	}
+	var result requestPermissionResult
+	if err := s.conn.call(ctx, "session/request_permission", params, &result); err != nil {
+		slog.Warn("Failed to request permission from the ACP client", "error", err)
+		s.app.Permissions.Deny(req)
+		return
+	}
+	switch {
+	case result.Outcome.Outcome != "selected":
+		s.app.Permissions.Deny(req)
+	case result.Outcome.OptionID == optionAllow:
+		s.app.Permissions.Grant(req)
+	case result.Outcome.OptionID == optionAllowSession:
+		s.app.Permissions.GrantPersistent(req)
+	default:
+		s.app.Permissions.Deny(req)
+	}
+}
+
+// clientSession returns the session of the client sessionID belongs to,
+// itself or the one of the agent that started it.
+func (s *server) clientSession(ctx context.Context, sessionID string) (string, bool) {
+	for sessionID != "" {
+		if _, ok := s.sessions.Get(sessionID); ok {
+			return sessionID, true
+		}
+		sess, err := s.app.Sessions.Get(ctx, sessionID)
+		if err != nil {
+			return "", false
+		}
+		sessionID = sess.ParentSessionID
+	}
+	return "", false
+}
+
+// message sends what's new in msg since it was last sent.
+func (s *server) message(msg message.Message) {
+	s.sentMu.Lock()
+	defer s.sentMu.Unlock()
+
+	switch msg.Role {
+	case message.Assistant:
+		s.delta(updateAgentThought, msg, msg.ReasoningContent().Thinking, s.reasoningBytes)
+		s.delta(updateAgentMessage, msg, msg.Content().Text, s.textBytes)
+		for _, call := range msg.ToolCalls() {
+			if !s.sent["call:"+call.ID] {
+				s.sent["call:"+call.ID] = true
+				update := toolCallUpdate(updateToolCall, call)
+				if !call.Finished {
+					update.Status = toolPending
+					update.RawInput = nil
+				}
+				s.sent["input:"+call.ID] = call.Finished
+				s.update(msg.SessionID, update)
+				continue
+			}
+			if call.Finished && !s.sent["input:"+call.ID] {
+				s.sent["input:"+call.ID] = true
+				s.update(msg.SessionID, toolCallUpdate(updateToolUpdate, call))
+			}
+		}
+	case message.Tool:
+		for _, result := range msg.ToolResults() {
+			if s.sent["result:"+result.ToolCallID] {
+				continue
+			}
+			s.sent["result:"+result.ToolCallID] = true
+			status := toolCompleted
+			if result.IsError {
+				status = toolFailed
+			}
+			s.update(msg.SessionID, sessionUpdate{
+				SessionUpdate: updateToolUpdate,
+				ToolCallID:    result.ToolCallID,
+				Status:        status,
+				Content:       []toolCallContent{{Type: "content", Content: textBlock(result.Content)}},
+				RawOutput:     &toolCallOutput{Content: result.Content, IsError: result.IsError},
+			})
+		}
+	}
+}
+
+// delta sends the part of content of msg added since it was last sent, as
+// counted by readBytes.
+func (s *server) delta(kind string, msg message.Message, content string, readBytes map[string]int) {
+	read := readBytes[msg.ID]
+	if len(content) <= read {
+		return
+	}
+	readBytes[msg.ID] = len(content)
+	s.update(msg.SessionID, sessionUpdate{SessionUpdate: kind, Content: textBlock(content[read:])})
+}
+
+func (s *server) update(sessionID string, update sessionUpdate) {
+	if err := s.conn.notify("session/update", sessionNotification{SessionID: sessionID, Update: update}); err != nil {
+		slog.Error("Failed to send ACP session update", "error", err)
+	}
+}
+
+// toolCallUpdate returns the update of kind of a tool call whose input was
+// received.
+func toolCallUpdate(kind string, call message.ToolCall) sessionUpdate {
+	update := sessionUpdate{
+		SessionUpdate: kind,
+		ToolCallID:    call.ID,
+		Title:         call.Name,
+		Kind:          toolKind(call.Name),
+		Status:        toolInProgress,
+	}
+	var input map[string]any
+	if json.Unmarshal([]byte(call.Input), &input) != nil {
+		return update
+	}
+	update.RawInput = json.RawMessage(call.Input)
+	for _, key := range []string{"file_path", "path"} {
+		if path, ok := input[key].(string); ok && path != "" {
+			update.Locations = []toolCallLocation{{Path: path}}
+			update.Title = call.Name + " " + path
+			break
+		}
+	}
+	for _, key := range []string{"command", "pattern", "query", "url"} {
+		if value, ok := input[key].(string); ok && value != "" {
+			update.Title = call.Name + " " + value
+			break
+		}
+	}
+	return update
+}
+
+// toolKind returns the kind of tool ACP clients show a tool as.
+func toolKind(name string) string {
+	switch name {
+	case tools.ViewToolName, tools.LSToolName, tools.DiagnosticsToolName:
+		return "read"
+	case tools.EditToolName, tools.MultiEditToolName, tools.WriteToolName, tools.ApplyPatchToolName:
+		return "edit"
+	case tools.GlobToolName, tools.GrepToolName, tools.SourcegraphToolName,
+		tools.DefinitionToolName, tools.ReferencesToolName, tools.SymbolsToolName,
+		tools.SemanticSearchToolName:
+		return "search"
+	case tools.BashToolName, tools.JobOutputToolName, tools.JobKillToolName:
+		return "execute"
+	case tools.FetchToolName, tools.WebFetchToolName, tools.WebSearchToolName,
+		tools.DownloadToolName, tools.AgenticFetchToolName:
+		return "fetch"
+	case agent.AgentToolName:
+		return "think"
+	default:
+		return "other"
+	}
+}
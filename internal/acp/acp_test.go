@@ -0,0 +1,238 @@
+package acp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func testApp(t *testing.T) *app.App {
+	t.Helper()
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	return &app.App{
+		Sessions:    session.NewService(q, ""),
+		Messages:    message.NewService(q),
+		Permissions: permission.NewPermissionService(t.TempDir(), false, nil, ""),
+	}
+}
+
+// updates returns the session updates written to output.
+func updates(t *testing.T, output *bytes.Buffer) []sessionUpdate {
+	t.Helper()
+	var result []sessionUpdate
+	for line := range strings.SplitSeq(strings.TrimSpace(output.String()), "\n") {
+		var msg struct {
+			Method string              `json:"method"`
+			Params sessionNotification `json:"params"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		require.Equal(t, "session/update", msg.Method)
+		result = append(result, msg.Params.Update)
+	}
+	return result
+}
+
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	app := testApp(t)
+	var output bytes.Buffer
+	s := newServer(app, newConn(&output))
+	ctx := t.Context()
+
+	result, err := s.handle(ctx, "initialize", json.RawMessage(`{"protocolVersion":1}`))
+	require.NoError(t, err)
+	require.True(t, result.(initializeResult).AgentCapabilities.LoadSession)
+
+	result, err = s.handle(ctx, "session/new", json.RawMessage(`{"mcpServers":[]}`))
+	require.NoError(t, err)
+	_, ok := s.sessions.Get(result.(newSessionResult).SessionID)
+	require.True(t, ok)
+
+	sess, err := app.Sessions.Create(ctx, "Parser")
+	require.NoError(t, err)
+	for _, msg := range []message.CreateMessageParams{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "List the files"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look."},
+			message.ToolCall{ID: "call", Name: "ls", Input: `{"path":"."}`, Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call", Name: "ls", Content: "- main.go"}}},
+	} {
+		_, err := app.Messages.Create(ctx, sess.ID, msg)
+		require.NoError(t, err)
+	}
+	_, err = s.handle(ctx, "session/load", json.RawMessage(`{"sessionId":"`+sess.ID+`","mcpServers":[]}`))
+	require.NoError(t, err)
+
+	loaded := updates(t, &output)
+	require.Len(t, loaded, 4)
+	require.Equal(t, updateUserMessage, loaded[0].SessionUpdate)
+	require.Equal(t, updateAgentMessage, loaded[1].SessionUpdate)
+	require.Equal(t, updateToolCall, loaded[2].SessionUpdate)
+	require.Equal(t, "read", loaded[2].Kind)
+	require.Equal(t, "ls .", loaded[2].Title)
+	require.Equal(t, toolCompleted, loaded[3].Status)
+
+	_, err = s.handle(ctx, "session/load", json.RawMessage(`{"sessionId":"missing"}`))
+	require.ErrorContains(t, err, "not found")
+	_, err = s.handle(ctx, "session/prompt", json.RawMessage(`{"sessionId":"other","prompt":[{"type":"text","text":"Hi"}]}`))
+	require.ErrorContains(t, err, "wasn't created or loaded")
+	_, err = s.handle(ctx, "session/dance", nil)
+	var rpcErr *rpcError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, codeMethodNotFound, rpcErr.Code)
+}
+
+func TestMessageUpdates(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	s := newServer(&app.App{}, newConn(&output))
+	msg := message.Message{ID: "msg", SessionID: "sess", Role: message.Assistant, Parts: []message.ContentPart{
+		message.ReasoningContent{Thinking: "Hmm"},
+		message.TextContent{Text: "Let me"},
+		message.ToolCall{ID: "call", Name: "bash"},
+	}}
+	s.message(msg)
+	msg.Parts = []message.ContentPart{
+		message.ReasoningContent{Thinking: "Hmm"},
+		message.TextContent{Text: "Let me run it."},
+		message.ToolCall{ID: "call", Name: "bash", Input: `{"command":"go test ./..."}`, Finished: true},
+	}
+	s.message(msg)
+	s.message(msg)
+
+	sent := updates(t, &output)
+	var kinds []string
+	for _, update := range sent {
+		kinds = append(kinds, update.SessionUpdate)
+	}
+	require.Equal(t, []string{
+		updateAgentThought, updateAgentMessage, updateToolCall, updateAgentMessage, updateToolUpdate,
+	}, kinds)
+	require.Equal(t, toolPending, sent[2].Status)
+	require.Equal(t, "execute", sent[2].Kind)
+	require.Equal(t, toolInProgress, sent[4].Status)
+	require.Equal(t, "bash go test ./...", sent[4].Title)
+}
+
+func TestPromptContent(t *testing.T) {
+	t.Parallel()
+
+	text, attachments, err := promptContent([]contentBlock{
+		{Type: "text", Text: "Explain"},
+		{Type: "resource_link", URI: "file:///src/main.go", Name: "main.go"},
+		{Type: "resource", Resource: &embeddedResource{URI: "file:///src/go.mod", Text: "module example"}},
+		{Type: "image", Data: "aGk=", MimeType: "image/png"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Explain\n@/src/main.go", text)
+	require.Len(t, attachments, 2)
+	require.Equal(t, "go.mod", attachments[0].FileName)
+	require.True(t, attachments[0].IsText())
+	require.Equal(t, []byte("hi"), attachments[1].Content)
+
+	_, _, err = promptContent([]contentBlock{{Type: "image", Data: "aGk=", MimeType: "image/png"}})
+	require.Error(t, err, "a prompt needs text")
+}
+
+func TestRequestPermission(t *testing.T) {
+	t.Parallel()
+
+	app := testApp(t)
+	sess, err := app.Sessions.Create(t.Context(), "Parser")
+	require.NoError(t, err)
+
+	clientInput, agentOutput := io.Pipe()
+	agentInput, clientOutput := io.Pipe()
+	t.Cleanup(func() {
+		clientOutput.Close()
+		agentOutput.Close()
+	})
+	go Serve(t.Context(), app, agentInput, agentOutput) //nolint:errcheck
+
+	lines := bufio.NewScanner(clientInput)
+	send := func(msg string) {
+		t.Helper()
+		_, err := io.WriteString(clientOutput, msg+"\n")
+		require.NoError(t, err)
+	}
+	send(`{"jsonrpc":"2.0","id":1,"method":"session/load","params":{"sessionId":"` + sess.ID + `","mcpServers":[]}}`)
+	require.True(t, lines.Scan())
+	require.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":null}`, lines.Text())
+
+	granted := make(chan bool, 1)
+	go func() {
+		granted <- app.Permissions.Request(permission.CreatePermissionRequest{
+			SessionID:   sess.ID,
+			ToolCallID:  "call",
+			ToolName:    "bash",
+			Description: "Run go test",
+			Action:      "execute",
+		})
+	}()
+
+	require.True(t, lines.Scan())
+	var request struct {
+		ID     json.RawMessage         `json:"id"`
+		Method string                  `json:"method"`
+		Params requestPermissionParams `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(lines.Bytes(), &request))
+	require.Equal(t, "session/request_permission", request.Method)
+	require.Equal(t, sess.ID, request.Params.SessionID)
+	require.Equal(t, "Run go test", request.Params.ToolCall.Title)
+	send(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":{"outcome":{"outcome":"selected","optionId":"allow"}}}`)
+	require.True(t, <-granted)
+}
+
+func TestServeCancelsRequestsWhenInputCloses(t *testing.T) {
+	t.Parallel()
+
+	clientInput, agentOutput := io.Pipe()
+	agentInput, clientOutput := io.Pipe()
+	t.Cleanup(func() { agentOutput.Close() })
+
+	c := newConn(agentOutput)
+	handle := func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		var result requestPermissionResult
+		return nil, c.call(ctx, "session/request_permission", requestPermissionParams{}, &result)
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.serve(t.Context(), agentInput, handle) }()
+
+	_, err := io.WriteString(clientOutput, `{"jsonrpc":"2.0","id":1,"method":"session/prompt","params":{}}`+"\n")
+	require.NoError(t, err)
+	lines := bufio.NewScanner(clientInput)
+	require.True(t, lines.Scan())
+	require.Contains(t, lines.Text(), "session/request_permission")
+	go func() {
+		for lines.Scan() {
+		}
+	}()
+
+	// The client goes away while the permission request is pending.
+	require.NoError(t, clientOutput.Close())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the input was closed")
+	}
+}
@@ -0,0 +1,178 @@
+package acp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/crush/internal/csync"
+)
+
+// The JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// rpcMessage is a JSON-RPC request, notification or response.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// invalidParams returns the error of a request with invalid parameters.
+func invalidParams(format string, args ...any) error {
+	return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// handler answers the requests and notifications of the client. The result
+// of notifications is ignored.
+type handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// conn is a JSON-RPC connection over newline-delimited JSON, as ACP runs
+// on the standard input and output of the agent.
+type conn struct {
+	writeMu sync.Mutex
+	encoder *json.Encoder
+
+	nextID  atomic.Int64
+	pending *csync.Map[string, chan rpcMessage]
+}
+
+func newConn(output io.Writer) *conn {
+	return &conn{
+		encoder: json.NewEncoder(output),
+		pending: csync.NewMap[string, chan rpcMessage](),
+	}
+}
+
+func (c *conn) write(msg rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.encoder.Encode(msg)
+}
+
+// serve reads the messages of input, running each request and notification
+// with handle in a goroutine of its own, until input is closed. Requests
+// are canceled once it returns.
+func (c *conn) serve(ctx context.Context, input io.Reader, handle handler) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Canceled before waiting for the requests, for the ones waiting on the
+	// client to return once it's gone.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			_ = c.write(rpcMessage{ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if msg.Method == "" {
+			// The response to a request of the agent.
+			if ch, ok := c.pending.Take(string(msg.ID)); ok {
+				ch <- msg
+			}
+			continue
+		}
+		wg.Go(func() {
+			result, err := handle(ctx, msg.Method, msg.Params)
+			if msg.ID == nil {
+				if err != nil {
+					slog.Warn("ACP notification failed", "method", msg.Method, "error", err)
+				}
+				return
+			}
+			c.reply(msg.ID, result, err)
+		})
+	}
+	return scanner.Err()
+}
+
+func (c *conn) reply(id json.RawMessage, result any, err error) {
+	msg := rpcMessage{ID: id}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		msg.Error = rpcErr
+	} else {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			msg.Error = &rpcError{Code: codeInternalError, Message: marshalErr.Error()}
+		} else {
+			msg.Result = data
+		}
+	}
+	if err := c.write(msg); err != nil {
+		slog.Error("Failed to write ACP response", "error", err)
+	}
+}
+
+// notify sends a notification to the client.
+func (c *conn) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(rpcMessage{Method: method, Params: data})
+}
+
+// call sends a request to the client and decodes its result into result,
+// waiting for it until ctx is done.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	ch := make(chan rpcMessage, 1)
+	c.pending.Set(string(id), ch)
+	defer c.pending.Del(string(id))
+
+	if err := c.write(rpcMessage{ID: id, Method: method, Params: data}); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		return json.Unmarshal(msg.Result, result)
+	}
+}
@@ -0,0 +1,162 @@
+package acp
+
+import "encoding/json"
+
+// protocolVersion is the version of ACP implemented.
+const protocolVersion = 1
+
+type initializeParams struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+type initializeResult struct {
+	ProtocolVersion   int               `json:"protocolVersion"`
+	AgentCapabilities agentCapabilities `json:"agentCapabilities"`
+	AgentInfo         implementation    `json:"agentInfo"`
+	AuthMethods       []struct{}        `json:"authMethods"`
+}
+
+type agentCapabilities struct {
+	LoadSession        bool               `json:"loadSession"`
+	PromptCapabilities promptCapabilities `json:"promptCapabilities"`
+}
+
+type promptCapabilities struct {
+	Image           bool `json:"image"`
+	Audio           bool `json:"audio"`
+	EmbeddedContext bool `json:"embeddedContext"`
+}
+
+type implementation struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version"`
+}
+
+type newSessionParams struct {
+	Cwd        string            `json:"cwd"`
+	MCPServers []json.RawMessage `json:"mcpServers"`
+}
+
+type newSessionResult struct {
+	SessionID string `json:"sessionId"`
+}
+
+type loadSessionParams struct {
+	SessionID  string            `json:"sessionId"`
+	Cwd        string            `json:"cwd"`
+	MCPServers []json.RawMessage `json:"mcpServers"`
+}
+
+type promptParams struct {
+	SessionID string         `json:"sessionId"`
+	Prompt    []contentBlock `json:"prompt"`
+}
+
+type promptResult struct {
+	StopReason string `json:"stopReason"`
+}
+
+// The reasons a prompt turn stops for.
+const (
+	stopEndTurn   = "end_turn"
+	stopMaxTokens = "max_tokens"
+	stopCancelled = "cancelled"
+)
+
+type cancelParams struct {
+	SessionID string `json:"sessionId"`
+}
+
+// contentBlock is a piece of a prompt or of a message: text, an image, a
+// link to a resource or an embedded resource.
+type contentBlock struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"`
+	MimeType string            `json:"mimeType,omitempty"`
+	URI      string            `json:"uri,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Resource *embeddedResource `json:"resource,omitempty"`
+}
+
+type embeddedResource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+func textBlock(text string) contentBlock {
+	return contentBlock{Type: "text", Text: text}
+}
+
+type sessionNotification struct {
+	SessionID string        `json:"sessionId"`
+	Update    sessionUpdate `json:"update"`
+}
+
+// sessionUpdate is a chunk of a message, or a tool call or an update of
+// one, depending on its kind. The content of chunks is a contentBlock, and
+// the one of tool calls a list of toolCallContent.
+type sessionUpdate struct {
+	SessionUpdate string             `json:"sessionUpdate,omitempty"`
+	Content       any                `json:"content,omitempty"`
+	ToolCallID    string             `json:"toolCallId,omitempty"`
+	Title         string             `json:"title,omitempty"`
+	Kind          string             `json:"kind,omitempty"`
+	Status        string             `json:"status,omitempty"`
+	RawInput      json.RawMessage    `json:"rawInput,omitempty"`
+	Locations     []toolCallLocation `json:"locations,omitempty"`
+	RawOutput     *toolCallOutput    `json:"rawOutput,omitempty"`
+}
+
+// The kinds of session updates.
+const (
+	updateUserMessage  = "user_message_chunk"
+	updateAgentMessage = "agent_message_chunk"
+	updateAgentThought = "agent_thought_chunk"
+	updateToolCall     = "tool_call"
+	updateToolUpdate   = "tool_call_update"
+)
+
+// The statuses of tool calls.
+const (
+	toolPending    = "pending"
+	toolInProgress = "in_progress"
+	toolCompleted  = "completed"
+	toolFailed     = "failed"
+)
+
+type toolCallLocation struct {
+	Path string `json:"path"`
+}
+
+type toolCallOutput struct {
+	Content string `json:"content"`
+	IsError bool   `json:"isError,omitempty"`
+}
+
+type toolCallContent struct {
+	Type    string       `json:"type"`
+	Content contentBlock `json:"content"`
+}
+
+type requestPermissionParams struct {
+	SessionID string             `json:"sessionId"`
+	ToolCall  sessionUpdate      `json:"toolCall"`
+	Options   []permissionOption `json:"options"`
+}
+
+type permissionOption struct {
+	OptionID string `json:"optionId"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+}
+
+type requestPermissionResult struct {
+	Outcome struct {
+		Outcome  string `json:"outcome"`
+		OptionID string `json:"optionId,omitempty"`
+	} `json:"outcome"`
+}
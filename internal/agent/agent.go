@@ -15,6 +15,8 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,9 +33,13 @@ import (
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/repomap"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/stringext"
+	"github.com/charmbracelet/crush/internal/tokens"
+	"github.com/charmbracelet/crush/internal/tracing"
 )
 
 //go:embed templates/title.md
@@ -53,12 +59,26 @@ type SessionAgentCall struct {
 	TopK             *int64
 	FrequencyPenalty *float64
 	PresencePenalty  *float64
+
+	// resume is the response cut off by the network the call goes on with,
+	// for the resumes-th time, instead of sending the prompt.
+	resume  *message.Message
+	resumes int
+	// retry is set when the prompt is sent again with a fallback model,
+	// after its response failed.
+	retry bool
+	// model is the model the call is sent to instead of the large model.
+	model *Model
+	// tools are the names of the only tools of the agent the call can use,
+	// when not nil.
+	tools []string
 }
 
 type SessionAgent interface {
 	Run(context.Context, SessionAgentCall) (*fantasy.AgentResult, error)
 	SetModels(large Model, small Model)
 	SetTools(tools []fantasy.AgentTool)
+	SetSystemPrompt(systemPrompt string)
 	Cancel(sessionID string)
 	CancelAll()
 	IsSessionBusy(sessionID string) bool
@@ -66,6 +86,7 @@ type SessionAgent interface {
 	QueuedPrompts(sessionID string) int
 	ClearQueue(sessionID string)
 	Summarize(context.Context, string, fantasy.ProviderOptions) error
+	GenerateCommitMessage(ctx context.Context, diff string) (string, error)
 	Model() Model
 }
 
@@ -73,6 +94,9 @@ type Model struct {
 	Model      fantasy.LanguageModel
 	CatwalkCfg catwalk.Model
 	ModelCfg   config.SelectedModel
+	// Counter counts the tokens of the requests when the provider doesn't
+	// report them. Without it they're estimated.
+	Counter tokens.Counter
 }
 
 type sessionAgent struct {
@@ -85,6 +109,15 @@ type sessionAgent struct {
 	messages             message.Service
 	disableAutoSummarize bool
 	isYolo               bool
+	autoCompactThreshold float64
+	compactKeepTurns     int
+	repoMap              *repomap.Map
+	// repoMaps are the maps of the repository sent to each session, which
+	// keep its system prompt the same for the provider to cache it.
+	repoMaps *csync.Map[string, string]
+	// checkBudget stops the run before a step once the session spent its
+	// budget.
+	checkBudget func(ctx context.Context, sessionID string) error
 
 	messageQueue   *csync.Map[string, []SessionAgentCall]
 	activeRequests *csync.Map[string, context.CancelFunc]
@@ -100,8 +133,25 @@ type SessionAgentOptions struct {
 	Sessions             session.Service
 	Messages             message.Service
 	Tools                []fantasy.AgentTool
+	// AutoCompactThreshold is the fraction of the context window at which
+	// the conversation is compacted automatically, zero uses the default.
+	AutoCompactThreshold float64
+	// CompactKeepTurns is the number of recent user turns kept verbatim on
+	// automatic compaction, zero uses the default and a negative number
+	// summarizes everything.
+	CompactKeepTurns int
+	// RepoMap, when set, is added to the system prompt, as it is when the
+	// first prompt of each session is sent.
+	RepoMap *repomap.Map
+	// CheckBudget, when set, is called before each step and stops the run
+	// with the error it returns.
+	CheckBudget func(ctx context.Context, sessionID string) error
 }
 
+// defaultCompactKeepTurns is the number of recent user turns kept verbatim
+// when compacting automatically.
+const defaultCompactKeepTurns = 2
+
 func NewSessionAgent(
 	opts SessionAgentOptions,
 ) SessionAgent {
@@ -115,6 +165,11 @@ func NewSessionAgent(
 		disableAutoSummarize: opts.DisableAutoSummarize,
 		tools:                opts.Tools,
 		isYolo:               opts.IsYolo,
+		autoCompactThreshold: opts.AutoCompactThreshold,
+		compactKeepTurns:     cmp.Or(opts.CompactKeepTurns, defaultCompactKeepTurns),
+		repoMap:              opts.RepoMap,
+		repoMaps:             csync.NewMap[string, string](),
+		checkBudget:          opts.CheckBudget,
 		messageQueue:         csync.NewMap[string, []SessionAgentCall](),
 		activeRequests:       csync.NewMap[string, context.CancelFunc](),
 	}
@@ -139,28 +194,65 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		return nil, nil
 	}
 
-	if len(a.tools) > 0 {
-		// Add Anthropic caching to the last tool.
-		a.tools[len(a.tools)-1].SetProviderOptions(a.getCacheControlOptions())
+	largeModel := a.largeModel
+	if call.model != nil {
+		largeModel = *call.model
 	}
 
-	agent := fantasy.NewAgent(
-		a.largeModel.Model,
-		fantasy.WithSystemPrompt(a.systemPrompt),
-		fantasy.WithTools(a.tools...),
-	)
-
 	sessionLock := sync.Mutex{}
+	// Copilot bills the first request of a prompt only.
+	firstRequest := true
 	currentSession, err := a.sessions.Get(ctx, call.SessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
+	// Finish the response crush may have exited in the middle of, unless
+	// it's the one going on.
+	if call.resume == nil {
+		if err := recoverInterrupted(ctx, a.messages, call.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
 	msgs, err := a.getSessionMessages(ctx, currentSession)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session messages: %w", err)
 	}
 
+	// Compact a conversation the prompt would overflow before sending it,
+	// rather than once the request failed.
+	if call.resume == nil && !call.retry && len(msgs) > 0 && a.countPrompt(ctx, largeModel, &currentSession, msgs, call) {
+		if err := a.summarize(ctx, call.SessionID, call.ProviderOptions, true); err != nil {
+			return nil, err
+		}
+		if currentSession, err = a.sessions.Get(ctx, call.SessionID); err != nil {
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if msgs, err = a.getSessionMessages(ctx, currentSession); err != nil {
+			return nil, fmt.Errorf("failed to get session messages: %w", err)
+		}
+	}
+
+	// The summary of a compacted conversation stays the same until the next
+	// compaction, so it takes the cache breakpoint of the last tool: along
+	// with the system prompt and the last 2 messages, that's the 4
+	// breakpoints Anthropic allows. The tools come before the system prompt
+	// and are cached along with it.
+	hasSummary := currentSession.SummaryMessageID != ""
+	prefetcher := newToolPrefetcher(maxParallelToolCalls)
+	agentTools := prefetcher.wrap(traceTools(callTools(a.tools, call.tools)))
+	if len(agentTools) > 0 && !hasSummary {
+		// Add Anthropic caching to the last tool.
+		last := len(agentTools) - 1
+		agentTools[last] = &cachedTool{AgentTool: agentTools[last], options: a.getCacheControlOptions()}
+	}
+	agent := fantasy.NewAgent(
+		largeModel.Model,
+		fantasy.WithSystemPrompt(a.fullSystemPrompt(call.SessionID)),
+		fantasy.WithTools(agentTools...),
+	)
+
 	var wg sync.WaitGroup
 	// Generate title if first message.
 	if len(msgs) == 0 {
@@ -171,10 +263,18 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		})
 	}
 
-	// Add the user message to the session.
-	_, err = a.createUserMessage(ctx, call)
-	if err != nil {
-		return nil, err
+	// Retried prompts whose response failed are already in the session.
+	var stored bool
+	if call.retry {
+		msgs, call, stored = retriedCall(msgs, call)
+	}
+
+	// Add the user message to the session, unless resuming its response.
+	if call.resume == nil && !stored {
+		_, err = a.createUserMessage(ctx, call)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Add the session to the context.
@@ -182,19 +282,26 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 
 	genCtx, cancel := context.WithCancel(ctx)
 	a.activeRequests.Set(call.SessionID, cancel)
+	genCtx, runSpan := tracing.Start(genCtx, "invoke_agent", attrSessionID.String(call.SessionID))
+	var request requestSpan
 
 	defer cancel()
 	defer a.activeRequests.Del(call.SessionID)
 
-	history, files := a.preparePrompt(msgs, call.Attachments...)
+	history, files := a.preparePrompt(largeModel, msgs, call.Attachments...)
+	prompt := promptText(call)
 
 	startTime := time.Now()
 	a.eventPromptSent(call.SessionID)
 
-	var currentAssistant *message.Message
+	currentAssistant := call.resume
 	var shouldSummarize bool
+	// toolCtx is the context the tools of the current step run with.
+	toolCtx := genCtx
+	// stepMessages are the messages sent in the current step.
+	var stepMessages []fantasy.Message
 	result, err := agent.Stream(genCtx, fantasy.AgentStreamCall{
-		Prompt:           call.Prompt,
+		Prompt:           prompt,
 		Files:            files,
 		Messages:         history,
 		ProviderOptions:  call.ProviderOptions,
@@ -206,14 +313,37 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		FrequencyPenalty: call.FrequencyPenalty,
 		// Before each step create a new assistant message.
 		PrepareStep: func(callContext context.Context, options fantasy.PrepareStepFunctionOptions) (_ context.Context, prepared fantasy.PrepareStepResult, err error) {
+			// Steps cost too, so a long run can go over the budget it
+			// started within.
+			if a.checkBudget != nil {
+				if err := a.checkBudget(callContext, call.SessionID); err != nil {
+					var budgetErr *BudgetExceededError
+					if errors.As(err, &budgetErr) {
+						budgetErr.Stopped = true
+					}
+					return callContext, prepared, err
+				}
+			}
+
 			prepared.Messages = options.Messages
 			// Reset all cached items.
 			for i := range prepared.Messages {
 				prepared.Messages[i].ProviderOptions = nil
 			}
 
-			queuedCalls, _ := a.messageQueue.Get(call.SessionID)
-			a.messageQueue.Del(call.SessionID)
+			// A resumed response goes on from its partial text, which
+			// providers supporting it continue as is, without the prompt
+			// asking for it. Without text there's nothing to ask for.
+			resuming := call.resume != nil && options.StepNumber == 0
+			if resuming && (supportsPrefill(largeModel.Model.Provider()) || call.resume.Content().Text == "") {
+				prepared.Messages = prepared.Messages[:len(prepared.Messages)-1]
+			}
+
+			var queuedCalls []SessionAgentCall
+			if !resuming {
+				queuedCalls, _ = a.messageQueue.Get(call.SessionID)
+				a.messageQueue.Del(call.SessionID)
+			}
 			for _, queued := range queuedCalls {
 				userMessage, createErr := a.createUserMessage(callContext, queued)
 				if createErr != nil {
@@ -237,23 +367,46 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 					prepared.Messages[i].ProviderOptions = a.getCacheControlOptions()
 				}
 			}
+			// Cache the summary of a compacted conversation on its own.
+			if hasSummary {
+				if idx := slices.IndexFunc(prepared.Messages, func(m fantasy.Message) bool {
+					return m.Role != fantasy.MessageRoleSystem
+				}); idx != -1 {
+					prepared.Messages[idx].ProviderOptions = a.getCacheControlOptions()
+				}
+			}
 
 			if promptPrefix := a.promptPrefix(); promptPrefix != "" {
 				prepared.Messages = append([]fantasy.Message{fantasy.NewSystemMessage(promptPrefix)}, prepared.Messages...)
 			}
 
+			// Tell the model about the files it read that changed since, until
+			// it reads them again. The note isn't kept in the session.
+			if note := staleFilesNote(tools.StaleFiles(call.SessionID)); note != "" && !resuming {
+				prepared.Messages = append(prepared.Messages, fantasy.NewUserMessage(note))
+			}
+
+			// The resumed response is written on.
 			var assistantMsg message.Message
-			assistantMsg, err = a.messages.Create(callContext, call.SessionID, message.CreateMessageParams{
-				Role:     message.Assistant,
-				Parts:    []message.ContentPart{},
-				Model:    a.largeModel.ModelCfg.Model,
-				Provider: a.largeModel.ModelCfg.Provider,
-			})
-			if err != nil {
-				return callContext, prepared, err
+			if resuming {
+				assistantMsg = *call.resume
+			} else {
+				assistantMsg, err = a.messages.Create(callContext, call.SessionID, message.CreateMessageParams{
+					Role:     message.Assistant,
+					Parts:    []message.ContentPart{},
+					Model:    largeModel.ModelCfg.Model,
+					Provider: largeModel.ModelCfg.Provider,
+				})
+				if err != nil {
+					return callContext, prepared, err
+				}
 			}
 			callContext = context.WithValue(callContext, tools.MessageIDContextKey, assistantMsg.ID)
 			currentAssistant = &assistantMsg
+			toolCtx = callContext
+			stepMessages = prepared.Messages
+			prefetcher.startStep()
+			request.start(callContext, largeModel)
 			return callContext, prepared, err
 		},
 		OnReasoningStart: func(id string, reasoning fantasy.ReasoningContent) error {
@@ -309,6 +462,7 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 			// TODO: implement
 		},
 		OnToolCall: func(tc fantasy.ToolCallContent) error {
+			prefetcher.prefetch(toolCtx, agentTools, tc)
 			toolCall := message.ToolCall{
 				ID:               tc.ToolCallID,
 				Name:             tc.ToolName,
@@ -355,6 +509,10 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 			}
 			return nil
 		},
+		OnStreamFinish: func(usage fantasy.Usage, finishReason fantasy.FinishReason, _ fantasy.ProviderMetadata) error {
+			request.finish(usage, finishReason)
+			return nil
+		},
 		OnStepFinish: func(stepResult fantasy.StepResult) error {
 			finishReason := message.FinishReasonUnknown
 			switch stepResult.FinishReason {
@@ -366,7 +524,19 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				finishReason = message.FinishReasonToolUse
 			}
 			currentAssistant.AddFinish(finishReason, "", "")
-			a.updateSessionUsage(a.largeModel, &currentSession, stepResult.Usage, a.openrouterCost(stepResult.ProviderMetadata))
+			usage := completeUsage(genCtx, largeModel, stepResult.Usage, stepMessages, agentTools, stepResult.Content)
+			overrideCost := a.openrouterCost(stepResult.ProviderMetadata)
+			if premiumCost := copilotCost(largeModel, firstRequest); premiumCost != nil {
+				overrideCost = premiumCost
+			}
+			firstRequest = false
+			cost, usageErr := a.updateSessionUsage(genCtx, largeModel, &currentSession, usage, overrideCost)
+			if usageErr != nil {
+				return usageErr
+			}
+			currentAssistant.Cost = cost
+			currentAssistant.PromptTokens = currentSession.PromptTokens
+			currentAssistant.CompletionTokens = currentSession.CompletionTokens
 			sessionLock.Lock()
 			_, sessionErr := a.sessions.Save(genCtx, currentSession)
 			sessionLock.Unlock()
@@ -377,16 +547,9 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		},
 		StopWhen: []fantasy.StopCondition{
 			func(_ []fantasy.StepResult) bool {
-				cw := int64(a.largeModel.CatwalkCfg.ContextWindow)
+				cw := int64(largeModel.CatwalkCfg.ContextWindow)
 				tokens := currentSession.CompletionTokens + currentSession.PromptTokens
-				remaining := cw - tokens
-				var threshold int64
-				if cw > 200_000 {
-					threshold = 20_000
-				} else {
-					threshold = int64(float64(cw) * 0.2)
-				}
-				if (remaining <= threshold) && !a.disableAutoSummarize {
+				if contextLimitReached(cw, tokens, a.autoCompactThreshold) && !a.disableAutoSummarize {
 					shouldSummarize = true
 					return true
 				}
@@ -396,6 +559,8 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	})
 
 	a.eventPromptResponded(call.SessionID, time.Since(startTime).Truncate(time.Second))
+	request.end(err)
+	tracing.End(runSpan, err)
 
 	if err != nil {
 		isCancelErr := errors.Is(err, context.Canceled)
@@ -403,6 +568,19 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		if currentAssistant == nil {
 			return result, err
 		}
+		// Go on with a response cut off by the network, keeping what was
+		// written so far.
+		if call.resumes < maxStreamResumes && canResume(err, currentAssistant) {
+			slog.Warn("Response stream interrupted, resuming it", "session_id", call.SessionID, "error", err)
+			wg.Wait()
+			resumed := call
+			resumed.Attachments = nil
+			resumed.resume = currentAssistant
+			resumed.resumes++
+			a.activeRequests.Del(call.SessionID)
+			cancel()
+			return a.Run(ctx, resumed)
+		}
 		// Ensure we finish thinking on error to close the reasoning state.
 		currentAssistant.FinishThinking()
 		toolCalls := currentAssistant.ToolCalls()
@@ -468,6 +646,8 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 			currentAssistant.AddFinish(message.FinishReasonCanceled, "User canceled request", "")
 		} else if isPermissionErr {
 			currentAssistant.AddFinish(message.FinishReasonPermissionDenied, "User denied permission", "")
+		} else if errors.Is(err, ErrBudgetExceeded) {
+			currentAssistant.AddFinish(message.FinishReasonError, "Budget exceeded", err.Error())
 		} else if errors.As(err, &providerErr) {
 			currentAssistant.AddFinish(message.FinishReasonError, cmp.Or(stringext.Capitalize(providerErr.Title), defaultTitle), providerErr.Message)
 		} else if errors.As(err, &fantasyErr) {
@@ -487,7 +667,7 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 
 	if shouldSummarize {
 		a.activeRequests.Del(call.SessionID)
-		if summarizeErr := a.Summarize(genCtx, call.SessionID, call.ProviderOptions); summarizeErr != nil {
+		if summarizeErr := a.summarize(genCtx, call.SessionID, call.ProviderOptions, true); summarizeErr != nil {
 			return nil, summarizeErr
 		}
 		// If the agent wasn't done...
@@ -516,7 +696,79 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	return a.Run(ctx, firstQueuedMessage)
 }
 
+// contextLimitReached reports whether a conversation using tokens is close
+// enough to the context window to be compacted. fraction is the share of the
+// context window that triggers it; when it's not within (0, 1), the limit is
+// reached once less than 20% of the window (or 20K tokens for large windows)
+// remains.
+func contextLimitReached(contextWindow, tokens int64, fraction float64) bool {
+	if contextWindow <= 0 {
+		return false
+	}
+	if fraction > 0 && fraction < 1 {
+		return float64(tokens) >= float64(contextWindow)*fraction
+	}
+	var threshold int64
+	if contextWindow > 200_000 {
+		threshold = 20_000
+	} else {
+		threshold = int64(float64(contextWindow) * 0.2)
+	}
+	return contextWindow-tokens <= threshold
+}
+
+// compactSplit returns the index of the first message kept verbatim when
+// compacting msgs, keeping up to keepTurns user turns as long as they use at
+// most maxKept of the total tokens. It returns 0 when everything should be
+// summarized.
+func compactSplit(msgs []message.Message, keepTurns int, total, maxKept int64) int {
+	for turns := keepTurns; turns > 0; turns-- {
+		split := turnStart(msgs, turns)
+		if split <= 0 {
+			continue
+		}
+		if total-contextTokensBefore(msgs, split) <= maxKept {
+			return split
+		}
+	}
+	return 0
+}
+
+// turnStart returns the index of the user message starting the turns-th
+// last turn, or -1 if there aren't that many turns.
+func turnStart(msgs []message.Message, turns int) int {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role != message.User {
+			continue
+		}
+		turns--
+		if turns == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// contextTokensBefore returns the context size recorded by the last
+// assistant message before idx.
+func contextTokensBefore(msgs []message.Message, idx int) int64 {
+	for i := idx - 1; i >= 0; i-- {
+		if msgs[i].Role == message.Assistant && msgs[i].PromptTokens+msgs[i].CompletionTokens > 0 {
+			return msgs[i].PromptTokens + msgs[i].CompletionTokens
+		}
+	}
+	return 0
+}
+
 func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fantasy.ProviderOptions) error {
+	return a.summarize(ctx, sessionID, opts, false)
+}
+
+// summarize replaces the history of a session with a summary. When
+// compacting automatically, the summary is written by the small model when
+// the conversation fits its context window, and the most recent turns are
+// kept verbatim.
+func (a *sessionAgent) summarize(ctx context.Context, sessionID string, opts fantasy.ProviderOptions, compact bool) error {
 	if a.IsSessionBusy(sessionID) {
 		return ErrSessionBusy
 	}
@@ -534,20 +786,43 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		return nil
 	}
 
-	aiMsgs, _ := a.preparePrompt(msgs)
+	total := currentSession.PromptTokens + currentSession.CompletionTokens
+	model := a.largeModel
+	var keepFrom int
+	var keptTokens int64
+	if compact {
+		var isSmall bool
+		if model, isSmall = a.auxiliaryModel(total); isSmall {
+			// The options were built for the large model.
+			opts = nil
+		}
+		cw := int64(a.largeModel.CatwalkCfg.ContextWindow)
+		if a.compactKeepTurns > 0 {
+			// Don't keep so much that the next request needs compacting again.
+			keepFrom = compactSplit(msgs, a.compactKeepTurns, total, cw/4)
+		}
+		if keepFrom > 0 {
+			keptTokens = total - contextTokensBefore(msgs, keepFrom)
+		}
+	}
+	toSummarize := msgs
+	if keepFrom > 0 {
+		toSummarize = msgs[:keepFrom]
+	}
+	aiMsgs, _ := a.preparePrompt(model, toSummarize)
 
 	genCtx, cancel := context.WithCancel(ctx)
 	a.activeRequests.Set(sessionID, cancel)
 	defer a.activeRequests.Del(sessionID)
 	defer cancel()
 
-	agent := fantasy.NewAgent(a.largeModel.Model,
+	agent := fantasy.NewAgent(model.Model,
 		fantasy.WithSystemPrompt(string(summaryPrompt)),
 	)
 	summaryMessage, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:             message.Assistant,
-		Model:            a.largeModel.Model.Model(),
-		Provider:         a.largeModel.Model.Provider(),
+		Model:            model.Model.Model(),
+		Provider:         model.Model.Provider(),
 		IsSummaryMessage: true,
 	})
 	if err != nil {
@@ -594,12 +869,6 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		return err
 	}
 
-	summaryMessage.AddFinish(message.FinishReasonEndTurn, "", "")
-	err = a.messages.Update(genCtx, summaryMessage)
-	if err != nil {
-		return err
-	}
-
 	var openrouterCost *float64
 	for _, step := range resp.Steps {
 		stepCost := a.openrouterCost(step.ProviderMetadata)
@@ -612,15 +881,36 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		}
 	}
 
-	a.updateSessionUsage(a.largeModel, &currentSession, resp.TotalUsage, openrouterCost)
+	if premiumCost := copilotCost(model, true); premiumCost != nil {
+		openrouterCost = premiumCost
+	}
+	summaryMessage.Cost, err = a.updateSessionUsage(genCtx, model, &currentSession, resp.TotalUsage, openrouterCost)
+	if err != nil {
+		return err
+	}
+	summaryMessage.PromptTokens = currentSession.PromptTokens
+	summaryMessage.CompletionTokens = currentSession.CompletionTokens
+	summaryMessage.AddFinish(message.FinishReasonEndTurn, "", "")
+	err = a.messages.Update(genCtx, summaryMessage)
+	if err != nil {
+		return err
+	}
 
 	// Just in case, get just the last usage info.
 	usage := resp.Response.Usage
 	currentSession.SummaryMessageID = summaryMessage.ID
+	currentSession.SummaryKeepFromID = ""
+	if keepFrom > 0 {
+		currentSession.SummaryKeepFromID = msgs[keepFrom].ID
+	}
 	currentSession.CompletionTokens = usage.OutputTokens
-	currentSession.PromptTokens = 0
-	_, err = a.sessions.Save(genCtx, currentSession)
-	return err
+	currentSession.PromptTokens = keptTokens
+	if _, err := a.sessions.Save(genCtx, currentSession); err != nil {
+		return err
+	}
+	// The cache starts over with the summary, and so can the map.
+	a.repoMaps.Del(sessionID)
+	return nil
 }
 
 func (a *sessionAgent) getCacheControlOptions() fantasy.ProviderOptions {
@@ -637,6 +927,21 @@ func (a *sessionAgent) getCacheControlOptions() fantasy.ProviderOptions {
 	}
 }
 
+// cachedTool sets the provider options of a tool for the run of a prompt
+// only, the tools being shared by the sessions.
+type cachedTool struct {
+	fantasy.AgentTool
+	options fantasy.ProviderOptions
+}
+
+func (t *cachedTool) ProviderOptions() fantasy.ProviderOptions {
+	return t.options
+}
+
+func (t *cachedTool) SetProviderOptions(opts fantasy.ProviderOptions) {
+	t.options = opts
+}
+
 func (a *sessionAgent) createUserMessage(ctx context.Context, call SessionAgentCall) (message.Message, error) {
 	var attachmentParts []message.ContentPart
 	for _, attachment := range call.Attachments {
@@ -654,7 +959,10 @@ func (a *sessionAgent) createUserMessage(ctx context.Context, call SessionAgentC
 	return msg, nil
 }
 
-func (a *sessionAgent) preparePrompt(msgs []message.Message, attachments ...message.Attachment) ([]fantasy.Message, []fantasy.FilePart) {
+// preparePrompt returns the history of msgs and the files of attachments to
+// send to model. The images of the history are left out for the models that
+// can't see them, which reject requests having any.
+func (a *sessionAgent) preparePrompt(model Model, msgs []message.Message, attachments ...message.Attachment) ([]fantasy.Message, []fantasy.FilePart) {
 	var history []fantasy.Message
 	for _, m := range msgs {
 		if len(m.Parts) == 0 {
@@ -667,9 +975,15 @@ func (a *sessionAgent) preparePrompt(msgs []message.Message, attachments ...mess
 		}
 		history = append(history, m.ToAIMessage()...)
 	}
+	if !model.CatwalkCfg.SupportsImages {
+		history = withoutImages(history)
+	}
 
 	var files []fantasy.FilePart
 	for _, attachment := range attachments {
+		if attachment.IsText() {
+			continue
+		}
 		files = append(files, fantasy.FilePart{
 			Filename:  attachment.FileName,
 			Data:      attachment.Content,
@@ -680,6 +994,31 @@ func (a *sessionAgent) preparePrompt(msgs []message.Message, attachments ...mess
 	return history, files
 }
 
+// withoutImages replaces the images of history with a note saying they were
+// left out.
+func withoutImages(history []fantasy.Message) []fantasy.Message {
+	for i, msg := range history {
+		if !slices.ContainsFunc(msg.Content, isImagePart) {
+			continue
+		}
+		parts := make([]fantasy.MessagePart, len(msg.Content))
+		for j, part := range msg.Content {
+			parts[j] = part
+			if isImagePart(part) {
+				file, _ := fantasy.AsMessagePart[fantasy.FilePart](part)
+				parts[j] = fantasy.TextPart{Text: fmt.Sprintf("[Image %s left out: the model doesn't support images]", file.Filename)}
+			}
+		}
+		history[i].Content = parts
+	}
+	return history
+}
+
+func isImagePart(part fantasy.MessagePart) bool {
+	file, ok := fantasy.AsMessagePart[fantasy.FilePart](part)
+	return ok && strings.HasPrefix(file.MediaType, "image/")
+}
+
 func (a *sessionAgent) getSessionMessages(ctx context.Context, session session.Session) ([]message.Message, error) {
 	msgs, err := a.messages.List(ctx, session.ID)
 	if err != nil {
@@ -695,13 +1034,45 @@ func (a *sessionAgent) getSessionMessages(ctx context.Context, session session.S
 			}
 		}
 		if summaryMsgInex != -1 {
-			msgs = msgs[summaryMsgInex:]
-			msgs[0].Role = message.User
+			summary := msgs[summaryMsgInex]
+			summary.Role = message.User
+			kept := keptMessages(msgs[:summaryMsgInex], session.SummaryKeepFromID)
+			msgs = append(append([]message.Message{summary}, kept...), msgs[summaryMsgInex+1:]...)
 		}
 	}
 	return msgs, nil
 }
 
+// keptMessages returns the messages from keepFromID onwards, which were kept
+// verbatim when the older ones were summarized. Earlier summaries are left
+// out.
+func keptMessages(msgs []message.Message, keepFromID string) []message.Message {
+	if keepFromID == "" {
+		return nil
+	}
+	idx := slices.IndexFunc(msgs, func(m message.Message) bool { return m.ID == keepFromID })
+	if idx == -1 {
+		return nil
+	}
+	var kept []message.Message
+	for _, m := range msgs[idx:] {
+		if !m.IsSummaryMessage {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// auxiliaryModel returns the model for auxiliary tasks, like summaries,
+// working on about tokens of context: the small model when it can handle
+// them, the large one otherwise.
+func (a *sessionAgent) auxiliaryModel(tokens int64) (Model, bool) {
+	if a.smallModel.Model == nil || int64(a.smallModel.CatwalkCfg.ContextWindow) <= tokens {
+		return a.largeModel, false
+	}
+	return a.smallModel, true
+}
+
 func (a *sessionAgent) generateTitle(ctx context.Context, session *session.Session, prompt string) {
 	if prompt == "" {
 		return
@@ -761,7 +1132,12 @@ func (a *sessionAgent) generateTitle(ctx context.Context, session *session.Sessi
 		}
 	}
 
-	a.updateSessionUsage(a.smallModel, session, resp.TotalUsage, openrouterCost)
+	if premiumCost := copilotCost(a.smallModel, true); premiumCost != nil {
+		openrouterCost = premiumCost
+	}
+	if _, err := a.updateSessionUsage(ctx, a.smallModel, session, resp.TotalUsage, openrouterCost); err != nil {
+		slog.Error("failed to save title usage", "error", err)
+	}
 	_, saveErr := a.sessions.Save(ctx, *session)
 	if saveErr != nil {
 		slog.Error("failed to save session title & usage", "error", saveErr)
@@ -782,7 +1158,93 @@ func (a *sessionAgent) openrouterCost(metadata fantasy.ProviderMetadata) *float6
 	return &opts.Usage.Cost
 }
 
-func (a *sessionAgent) updateSessionUsage(model Model, session *session.Session, usage fantasy.Usage, overrideCost *float64) {
+// copilotCost returns what a request to a Copilot model costs past the
+// allowance of the plan: the price of the premium requests it counts as when
+// it's the first request of a prompt, nothing for the ones going on after its
+// tool calls. It returns nil for the models of the other providers.
+func copilotCost(model Model, first bool) *float64 {
+	if model.ModelCfg.Provider != copilot.ProviderID {
+		return nil
+	}
+	var cost float64
+	if first {
+		cost = copilot.PremiumRequestMultiplier(model.CatwalkCfg.ID) * copilot.PremiumRequestCost
+	}
+	return &cost
+}
+
+// promptText returns the text the call sends, with its text attachments.
+func promptText(call SessionAgentCall) string {
+	prompt := call.Prompt
+	if call.resume != nil {
+		prompt = resumePrompt
+	}
+	for _, attachment := range call.Attachments {
+		if attachment.IsText() {
+			prompt += "\n\n" + message.TextAttachment(attachment.FilePath, attachment.Content)
+		}
+	}
+	return prompt
+}
+
+// countPrompt counts the tokens of the request sending the prompt of call
+// after msgs, and reports whether they reach the limit past which the
+// conversation is compacted. Counted by the provider, they're saved as the
+// context of the session for the context meter to show them right away.
+func (a *sessionAgent) countPrompt(ctx context.Context, model Model, currentSession *session.Session, msgs []message.Message, call SessionAgentCall) bool {
+	history, files := a.preparePrompt(model, msgs, call.Attachments...)
+	sent := append([]fantasy.Message{fantasy.NewSystemMessage(a.fullSystemPrompt(currentSession.ID))}, history...)
+	sent = append(sent, fantasy.NewUserMessage(promptText(call), files...))
+	count, exact := countTokens(ctx, model, sent, a.tools)
+	if exact {
+		currentSession.PromptTokens = count
+		currentSession.CompletionTokens = 0
+		if _, err := a.sessions.Save(ctx, *currentSession); err != nil {
+			slog.Warn("Failed to save the tokens of the prompt", "session", currentSession.ID, "error", err)
+		}
+	}
+	cw := int64(model.CatwalkCfg.ContextWindow)
+	return !a.disableAutoSummarize && contextLimitReached(cw, count, a.autoCompactThreshold)
+}
+
+// countTokens counts the tokens of a request sending msgs and agentTools
+// with the counter of the model, estimating them when it has none or fails,
+// and reports whether they were counted rather than estimated.
+func countTokens(ctx context.Context, model Model, msgs []fantasy.Message, agentTools []fantasy.AgentTool) (int64, bool) {
+	if _, estimator := model.Counter.(tokens.Estimator); model.Counter != nil && !estimator {
+		count, err := model.Counter.Count(ctx, msgs, agentTools)
+		if err == nil {
+			return count, true
+		}
+		if !errors.Is(err, tokens.ErrUnsupported) {
+			slog.Warn("Failed to count the tokens sent, estimating them", "model", model.ModelCfg.Model, "error", err)
+		}
+	}
+	count, _ := tokens.Estimator{}.Count(ctx, msgs, agentTools)
+	return count, false
+}
+
+// completeUsage fills in the usage of a step the provider didn't report,
+// counting the tokens sent with the counter of the model and estimating the
+// ones of the response, so the context meter, the compaction and the costs
+// keep working.
+func completeUsage(ctx context.Context, model Model, usage fantasy.Usage, sent []fantasy.Message, agentTools []fantasy.AgentTool, response fantasy.ResponseContent) fantasy.Usage {
+	if usage.InputTokens+usage.CacheCreationTokens+usage.CacheReadTokens == 0 && len(sent) > 0 {
+		usage.InputTokens, _ = countTokens(ctx, model, sent, agentTools)
+	}
+	if usage.OutputTokens == 0 {
+		usage.OutputTokens = tokens.Estimate(response.ReasoningText()) + tokens.Estimate(response.Text())
+		for _, call := range response.ToolCalls() {
+			usage.OutputTokens += tokens.Estimate(call.ToolName + " " + call.Input)
+		}
+	}
+	return usage
+}
+
+// updateSessionUsage adds the usage of a request to the totals of the
+// session, in a single update as other runs may add theirs meanwhile, sets
+// its context size and returns what the request cost.
+func (a *sessionAgent) updateSessionUsage(ctx context.Context, model Model, currentSession *session.Session, usage fantasy.Usage, overrideCost *float64) (float64, error) {
 	modelConfig := model.CatwalkCfg
 	cost := modelConfig.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
 		modelConfig.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
@@ -793,16 +1255,30 @@ func (a *sessionAgent) updateSessionUsage(model Model, session *session.Session,
 		cost = 0
 	}
 
-	a.eventTokensUsed(session.ID, model, usage, cost)
+	a.eventTokensUsed(currentSession.ID, model, usage, cost)
 
 	if overrideCost != nil {
-		session.Cost += *overrideCost
-	} else {
-		session.Cost += cost
+		cost = *overrideCost
 	}
 
-	session.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
-	session.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+	currentSession.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
+	currentSession.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+	updated, err := a.sessions.AddUsage(ctx, currentSession.ID, session.Usage{
+		Cost:             cost,
+		PromptTokens:     currentSession.PromptTokens,
+		CompletionTokens: currentSession.CompletionTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheCreationTokens,
+	})
+	if err != nil {
+		return cost, fmt.Errorf("failed to add usage to session: %w", err)
+	}
+	currentSession.Cost = updated.Cost
+	currentSession.TotalPromptTokens = updated.TotalPromptTokens
+	currentSession.TotalCompletionTokens = updated.TotalCompletionTokens
+	currentSession.TotalCacheReadTokens = updated.TotalCacheReadTokens
+	currentSession.TotalCacheWriteTokens = updated.TotalCacheWriteTokens
+	return cost, nil
 }
 
 func (a *sessionAgent) Cancel(sessionID string) {
@@ -879,14 +1355,68 @@ func (a *sessionAgent) SetModels(large Model, small Model) {
 	a.smallModel = small
 }
 
+// callTools returns the tools a call can use: the ones named in allowed, or
+// all of them when allowed is nil.
+func callTools(tools []fantasy.AgentTool, allowed []string) []fantasy.AgentTool {
+	if allowed == nil {
+		return tools
+	}
+	return slices.DeleteFunc(slices.Clone(tools), func(tool fantasy.AgentTool) bool {
+		return !slices.Contains(allowed, tool.Info().Name)
+	})
+}
+
 func (a *sessionAgent) SetTools(tools []fantasy.AgentTool) {
 	a.tools = tools
 }
 
+// SetSystemPrompt replaces the system prompt, and with it the maps of the
+// repository each session was sent.
+func (a *sessionAgent) SetSystemPrompt(systemPrompt string) {
+	a.systemPrompt = systemPrompt
+	a.repoMaps.Reset(map[string]string{})
+}
+
 func (a *sessionAgent) Model() Model {
 	return a.largeModel
 }
 
+// fullSystemPrompt returns the system prompt of the session, followed by the
+// map of the repository, if any. The map is the one of the first prompt of
+// the session, or of the first one since it was summarized, for the system
+// prompt to be cached.
+func (a *sessionAgent) fullSystemPrompt(sessionID string) string {
+	if a.repoMap == nil {
+		return a.systemPrompt
+	}
+	repoMap, ok := a.repoMaps.Get(sessionID)
+	if !ok {
+		repoMap = a.repoMap.String()
+		if repoMap != "" {
+			a.repoMaps.Set(sessionID, repoMap)
+		}
+	}
+	if repoMap == "" {
+		return a.systemPrompt
+	}
+	return a.systemPrompt + "\n\n<repository_map>\nThe files of the repository with their top-level symbols, the most imported ones first when they don't all fit:\n" + repoMap + "</repository_map>"
+}
+
+// staleFilesNote warns the model that files it read changed outside of Crush,
+// so it doesn't edit them based on their old content.
+func staleFilesNote(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<stale_files>\nThese files changed outside of Crush since you last read them. View them again before editing them:\n")
+	for _, path := range paths {
+		fmt.Fprintf(&sb, "- %s\n", filepath.ToSlash(path))
+	}
+	sb.WriteString("</stale_files>")
+	return sb.String()
+}
+
 func (a *sessionAgent) promptPrefix() string {
 	if a.isClaudeCode() {
 		return "You are Claude Code, Anthropic's official CLI for Claude."
@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -9,8 +11,14 @@ import (
 
 	"charm.land/fantasy"
 	"charm.land/x/vcr"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/repomap"
+	"github.com/charmbracelet/crush/internal/tokens"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -615,3 +623,225 @@ func TestCoderAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestContextLimitReached(t *testing.T) {
+	t.Parallel()
+
+	// Default: 20% of the window free, or 20K for large windows.
+	require.False(t, contextLimitReached(100_000, 79_000, 0))
+	require.True(t, contextLimitReached(100_000, 80_000, 0))
+	require.False(t, contextLimitReached(1_000_000, 970_000, 0))
+	require.True(t, contextLimitReached(1_000_000, 980_000, 0))
+
+	// Configured fraction.
+	require.False(t, contextLimitReached(100_000, 59_000, 0.6))
+	require.True(t, contextLimitReached(100_000, 60_000, 0.6))
+	// Out of range fractions fall back to the default.
+	require.False(t, contextLimitReached(100_000, 60_000, 1.5))
+
+	require.False(t, contextLimitReached(0, 60_000, 0.6))
+}
+
+func TestCompactSplit(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{ID: "u1", Role: message.User},
+		{ID: "a1", Role: message.Assistant, PromptTokens: 1_000, CompletionTokens: 500},
+		{ID: "u2", Role: message.User},
+		{ID: "a2", Role: message.Assistant, PromptTokens: 50_000, CompletionTokens: 1_000},
+		{ID: "u3", Role: message.User},
+		{ID: "a3", Role: message.Assistant, PromptTokens: 60_000, CompletionTokens: 1_000},
+	}
+
+	// Keeping the last two turns costs 61K - 1.5K tokens.
+	require.Equal(t, 2, compactSplit(msgs, 2, 61_000, 60_000))
+	// Too many tokens: only the last turn, 61K - 51K, is kept.
+	require.Equal(t, 4, compactSplit(msgs, 2, 61_000, 20_000))
+	// Nothing fits.
+	require.Zero(t, compactSplit(msgs, 2, 61_000, 1_000))
+	// The first turn can't be kept, there would be nothing to summarize.
+	require.Equal(t, 2, compactSplit(msgs, 5, 61_000, 60_000))
+	require.Zero(t, compactSplit(msgs, 0, 61_000, 60_000))
+}
+
+func TestKeptMessages(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{ID: "u1", Role: message.User},
+		{ID: "u2", Role: message.User},
+		{ID: "s1", Role: message.Assistant, IsSummaryMessage: true},
+		{ID: "u3", Role: message.User},
+	}
+
+	kept := keptMessages(msgs, "u2")
+	require.Len(t, kept, 2)
+	require.Equal(t, "u2", kept[0].ID)
+	require.Equal(t, "u3", kept[1].ID)
+
+	require.Nil(t, keptMessages(msgs, ""))
+	require.Nil(t, keptMessages(msgs, "missing"))
+}
+
+func TestAuxiliaryModel(t *testing.T) {
+	t.Parallel()
+
+	large := Model{CatwalkCfg: catwalk.Model{ID: "large", ContextWindow: 200_000}}
+	small := Model{Model: struct{ fantasy.LanguageModel }{}, CatwalkCfg: catwalk.Model{ID: "small", ContextWindow: 50_000}}
+	a := &sessionAgent{largeModel: large, smallModel: small}
+
+	model, isSmall := a.auxiliaryModel(10_000)
+	require.True(t, isSmall)
+	require.Equal(t, "small", model.CatwalkCfg.ID)
+
+	// Too much context for the small model.
+	model, isSmall = a.auxiliaryModel(60_000)
+	require.False(t, isSmall)
+	require.Equal(t, "large", model.CatwalkCfg.ID)
+}
+
+type countFunc func(msgs []fantasy.Message) (int64, error)
+
+func (f countFunc) Count(_ context.Context, msgs []fantasy.Message, _ []fantasy.AgentTool) (int64, error) {
+	return f(msgs)
+}
+
+func TestCompleteUsage(t *testing.T) {
+	t.Parallel()
+
+	sent := []fantasy.Message{fantasy.NewSystemMessage("system"), fantasy.NewUserMessage("hello")}
+	response := fantasy.ResponseContent{fantasy.TextContent{Text: "Hello there"}}
+	model := Model{Counter: countFunc(func(msgs []fantasy.Message) (int64, error) {
+		return int64(100 * len(msgs)), nil
+	})}
+
+	// Reported usage is kept.
+	usage := completeUsage(t.Context(), model, fantasy.Usage{InputTokens: 10, OutputTokens: 5}, sent, nil, response)
+	require.Equal(t, fantasy.Usage{InputTokens: 10, OutputTokens: 5}, usage)
+	usage = completeUsage(t.Context(), model, fantasy.Usage{CacheReadTokens: 10, OutputTokens: 5}, sent, nil, response)
+	require.Zero(t, usage.InputTokens)
+
+	usage = completeUsage(t.Context(), model, fantasy.Usage{}, sent, nil, response)
+	require.Equal(t, int64(200), usage.InputTokens)
+	require.Equal(t, int64(2), usage.OutputTokens)
+
+	// Counting errors fall back to the estimate.
+	model.Counter = countFunc(func([]fantasy.Message) (int64, error) {
+		return 0, errors.New("unavailable")
+	})
+	usage = completeUsage(t.Context(), model, fantasy.Usage{}, sent, nil, response)
+	estimate, _ := tokens.Estimator{}.Count(t.Context(), sent, nil)
+	require.Equal(t, estimate, usage.InputTokens)
+}
+
+func TestCountTokens(t *testing.T) {
+	t.Parallel()
+
+	sent := []fantasy.Message{fantasy.NewUserMessage("hello")}
+	estimate, _ := tokens.Estimator{}.Count(t.Context(), sent, nil)
+
+	count, exact := countTokens(t.Context(), Model{Counter: countFunc(func([]fantasy.Message) (int64, error) {
+		return 42, nil
+	})}, sent, nil)
+	require.True(t, exact)
+	require.Equal(t, int64(42), count)
+
+	for _, counter := range []tokens.Counter{nil, tokens.Estimator{}, countFunc(func([]fantasy.Message) (int64, error) {
+		return 0, tokens.ErrUnsupported
+	})} {
+		count, exact = countTokens(t.Context(), Model{Counter: counter}, sent, nil)
+		require.False(t, exact)
+		require.Equal(t, estimate, count)
+	}
+}
+
+func TestCopilotCost(t *testing.T) {
+	t.Parallel()
+
+	opus := Model{
+		CatwalkCfg: catwalk.Model{ID: "claude-opus-4"},
+		ModelCfg:   config.SelectedModel{Provider: copilot.ProviderID, Model: "claude-opus-4"},
+	}
+	require.InDelta(t, 0.4, *copilotCost(opus, true), 1e-9)
+	require.Zero(t, *copilotCost(opus, false), "requests going on after tool calls are free")
+
+	included := opus
+	included.CatwalkCfg.ID = "gpt-4.1"
+	require.Zero(t, *copilotCost(included, true))
+
+	other := opus
+	other.ModelCfg.Provider = "anthropic"
+	require.Nil(t, copilotCost(other, true))
+}
+
+func TestCallTools(t *testing.T) {
+	t.Parallel()
+
+	newTool := func(name string) fantasy.AgentTool {
+		return fantasy.NewAgentTool(name, name, func(ctx context.Context, input fakeToolInput, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return fantasy.NewTextResponse(name), nil
+		})
+	}
+	agentTools := []fantasy.AgentTool{newTool("view"), newTool("edit"), newTool("grep")}
+	names := func(agentTools []fantasy.AgentTool) []string {
+		var names []string
+		for _, tool := range agentTools {
+			names = append(names, tool.Info().Name)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"view", "edit", "grep"}, names(callTools(agentTools, nil)))
+	require.Equal(t, []string{"view", "grep"}, names(callTools(agentTools, []string{"grep", "view", "ls"})))
+	require.Empty(t, callTools(agentTools, []string{}))
+	require.Equal(t, []string{"view", "edit", "grep"}, names(agentTools), "the tools of the agent stay as they are")
+}
+
+func TestWithoutImages(t *testing.T) {
+	t.Parallel()
+
+	image := fantasy.FilePart{Filename: "shot.png", Data: []byte("png"), MediaType: "image/png"}
+	pdf := fantasy.FilePart{Filename: "spec.pdf", Data: []byte("pdf"), MediaType: "application/pdf"}
+	history := []fantasy.Message{
+		fantasy.NewUserMessage("What's wrong?", image, pdf),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "The button."}}},
+	}
+
+	got := withoutImages(history)
+	require.Equal(t, []fantasy.MessagePart{
+		fantasy.TextPart{Text: "What's wrong?"},
+		fantasy.TextPart{Text: "[Image shot.png left out: the model doesn't support images]"},
+		pdf,
+	}, got[0].Content)
+	require.Equal(t, []fantasy.MessagePart{fantasy.TextPart{Text: "The button."}}, got[1].Content)
+}
+
+func TestFullSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	m := repomap.New(root, 1000)
+	a := &sessionAgent{systemPrompt: "You are Crush.", repoMap: m, repoMaps: csync.NewMap[string, string]()}
+	require.Equal(t, "You are Crush.", a.fullSystemPrompt("first"), "the map isn't there before it's built")
+
+	m.Refresh()
+	first := a.fullSystemPrompt("first")
+	require.Contains(t, first, "func main()")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "util.go"), []byte("package main\n\nfunc helper() {}\n"), 0o644))
+	m.Refresh()
+	require.Equal(t, first, a.fullSystemPrompt("first"), "a session keeps its map for the cache")
+	require.Contains(t, a.fullSystemPrompt("second"), "func helper()")
+
+	a.repoMaps.Del("first")
+	require.Contains(t, a.fullSystemPrompt("first"), "func helper()")
+
+	// Excluded files leave the maps of the sessions along with the prompt.
+	m.SetExclude(func(rel string) bool { return rel == "util.go" })
+	a.SetSystemPrompt("You are Crush, again.")
+	again := a.fullSystemPrompt("first")
+	require.True(t, strings.HasPrefix(again, "You are Crush, again."))
+	require.NotContains(t, again, "func helper()")
+}
@@ -1,27 +1,42 @@
 package agent
 
 import (
+	"cmp"
 	"context"
 	_ "embed"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"charm.land/fantasy"
 
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/session"
 )
 
 //go:embed templates/agent_tool.md
 var agentToolDescription []byte
 
+//go:embed templates/agent_output_tool.md
+var agentOutputToolDescription []byte
+
 type AgentParams struct {
-	Prompt string `json:"prompt" description:"The task for the agent to perform"`
+	Prompt          string `json:"prompt" description:"The task for the agent to perform"`
+	Subagent        string `json:"subagent,omitempty" description:"The name of a configured subagent to delegate the task to. Leave empty for the general search agent"`
+	Model           string `json:"model,omitempty" description:"The model to run the agent with: large (default) for complex tasks, or small for simple and cheap ones"`
+	RunInBackground bool   `json:"run_in_background,omitempty" description:"Set to true (boolean) to run the agent in the background while you keep working. Use agent_output to read its result later."`
+}
+
+type AgentOutputParams struct {
+	TaskID string `json:"task_id" description:"The ID of the background agent to read the result of"`
 }
 
 const (
-	AgentToolName = "agent"
+	AgentToolName       = "agent"
+	AgentOutputToolName = "agent_output"
 )
 
 func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error) {
@@ -40,7 +55,7 @@ func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error)
 	}
 	return fantasy.NewAgentTool(
 		AgentToolName,
-		string(agentToolDescription),
+		string(agentToolDescription)+c.subagentsDescription(),
 		func(ctx context.Context, params AgentParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
 			if params.Prompt == "" {
 				return fantasy.NewTextErrorResponse("prompt is required"), nil
@@ -56,50 +71,219 @@ func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error)
 				return fantasy.ToolResponse{}, errors.New("agent message id missing from context")
 			}
 
+			taskPrompt, taskCfg := prompt, agentCfg
+			if params.Subagent != "" {
+				var ok bool
+				taskPrompt, taskCfg, ok, err = c.subagent(params.Subagent)
+				if err != nil {
+					return fantasy.ToolResponse{}, err
+				}
+				if !ok {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("unknown subagent %q, available subagents: %s", params.Subagent, strings.Join(c.cfg.SubagentNames(), ", "))), nil
+				}
+			}
+
+			modelType := cmp.Or(config.SelectedModelType(params.Model), taskCfg.Model)
+			if modelType != config.SelectedModelTypeLarge && modelType != config.SelectedModelTypeSmall {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("unknown model %q, use large or small", params.Model)), nil
+			}
+			taskAgent := agent
+			if params.Subagent != "" || modelType == config.SelectedModelTypeSmall {
+				taskAgent, err = c.buildTaskAgent(ctx, taskPrompt, taskCfg, modelType)
+				if err != nil {
+					return fantasy.ToolResponse{}, fmt.Errorf("error building agent: %w", err)
+				}
+			}
+
 			agentToolSessionID := c.sessions.CreateAgentToolSessionID(agentMessageID, call.ID)
-			session, err := c.sessions.CreateTaskSession(ctx, agentToolSessionID, sessionID, "New Agent Session")
+			title := "New Agent Session"
+			if params.RunInBackground {
+				title = "Background Agent Session"
+			}
+			if params.Subagent != "" {
+				title = fmt.Sprintf("%s (%s)", title, params.Subagent)
+			}
+			session, err := c.sessions.CreateTaskSession(ctx, agentToolSessionID, sessionID, title)
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
 			}
-			model := agent.Model()
-			maxTokens := model.CatwalkCfg.DefaultMaxTokens
-			if model.ModelCfg.MaxTokens != 0 {
-				maxTokens = model.ModelCfg.MaxTokens
-			}
+			// The task runs in YOLO mode when its parent session does.
+			c.permissions.SetSessionSkipRequests(session.ID, c.permissions.SessionSkipRequests(sessionID))
 
-			providerCfg, ok := c.cfg.Providers.Get(model.ModelCfg.Provider)
-			if !ok {
-				return fantasy.ToolResponse{}, errors.New("model provider not configured")
+			if params.RunInBackground {
+				task, err := c.background.start(BackgroundTask{
+					ID:              c.background.newID(),
+					ParentSessionID: sessionID,
+					SessionID:       session.ID,
+					Prompt:          params.Prompt,
+					Model:           taskAgent.Model().CatwalkCfg.Name,
+				}, func(ctx context.Context) (string, error) {
+					return c.runTaskAgent(ctx, taskAgent, sessionID, session.ID, params.Prompt)
+				})
+				if err != nil {
+					return fantasy.NewTextErrorResponse(err.Error()), nil
+				}
+				return fantasy.NewTextResponse(fmt.Sprintf("Background agent started with ID: %s\n\nUse the agent_output tool to read its result once it's done.", task.ID)), nil
 			}
-			result, err := agent.Run(ctx, SessionAgentCall{
-				SessionID:        session.ID,
-				Prompt:           params.Prompt,
-				MaxOutputTokens:  maxTokens,
-				ProviderOptions:  getProviderOptions(model, providerCfg),
-				Temperature:      model.ModelCfg.Temperature,
-				TopP:             model.ModelCfg.TopP,
-				TopK:             model.ModelCfg.TopK,
-				FrequencyPenalty: model.ModelCfg.FrequencyPenalty,
-				PresencePenalty:  model.ModelCfg.PresencePenalty,
-			})
-			if err != nil {
+
+			result, err := c.runTaskAgent(ctx, taskAgent, sessionID, session.ID, params.Prompt)
+			if errors.Is(err, errTaskAgentFailed) {
 				return fantasy.NewTextErrorResponse("error generating response"), nil
 			}
-			updatedSession, err := c.sessions.Get(ctx, session.ID)
-			if err != nil {
-				return fantasy.ToolResponse{}, fmt.Errorf("error getting session: %s", err)
-			}
-			parentSession, err := c.sessions.Get(ctx, sessionID)
 			if err != nil {
-				return fantasy.ToolResponse{}, fmt.Errorf("error getting parent session: %s", err)
+				return fantasy.ToolResponse{}, err
 			}
+			return fantasy.NewTextResponse(result), nil
+		}), nil
+}
 
-			parentSession.Cost += updatedSession.Cost
+// errTaskAgentFailed is returned when the task agent couldn't respond.
+var errTaskAgentFailed = errors.New("error generating response")
 
-			_, err = c.sessions.Save(ctx, parentSession)
-			if err != nil {
-				return fantasy.ToolResponse{}, fmt.Errorf("error saving parent session: %s", err)
+// runTaskAgent runs a task agent in its own session, adding its usage to the
+// parent session, and returns its response.
+func (c *coordinator) runTaskAgent(ctx context.Context, agent SessionAgent, parentSessionID, sessionID, prompt string) (string, error) {
+	call, err := c.taskAgentCall(agent, sessionID, prompt)
+	if err != nil {
+		return "", err
+	}
+	result, err := agent.Run(ctx, call)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errTaskAgentFailed, err)
+	}
+	updatedSession, err := c.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("error getting session: %s", err)
+	}
+	_, err = c.sessions.AddUsage(ctx, parentSessionID, session.Usage{
+		Cost:             updatedSession.Cost,
+		PromptTokens:     updatedSession.TotalPromptTokens,
+		CompletionTokens: updatedSession.TotalCompletionTokens,
+		CacheReadTokens:  updatedSession.TotalCacheReadTokens,
+		CacheWriteTokens: updatedSession.TotalCacheWriteTokens,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error saving parent session: %s", err)
+	}
+	return result.Response.Content.Text(), nil
+}
+
+// taskAgentCall returns the call running prompt with a task agent in
+// sessionID, with the settings of its model.
+func (c *coordinator) taskAgentCall(agent SessionAgent, sessionID, prompt string) (SessionAgentCall, error) {
+	model := agent.Model()
+	maxTokens := model.CatwalkCfg.DefaultMaxTokens
+	if model.ModelCfg.MaxTokens != 0 {
+		maxTokens = model.ModelCfg.MaxTokens
+	}
+
+	providerCfg, ok := c.cfg.Providers.Get(model.ModelCfg.Provider)
+	if !ok {
+		return SessionAgentCall{}, errors.New("model provider not configured")
+	}
+	return SessionAgentCall{
+		SessionID:        sessionID,
+		Prompt:           prompt,
+		MaxOutputTokens:  maxTokens,
+		ProviderOptions:  getProviderOptions(model, providerCfg),
+		Temperature:      model.ModelCfg.Temperature,
+		TopP:             model.ModelCfg.TopP,
+		TopK:             model.ModelCfg.TopK,
+		FrequencyPenalty: model.ModelCfg.FrequencyPenalty,
+		PresencePenalty:  model.ModelCfg.PresencePenalty,
+	}, nil
+}
+
+// subagent returns the prompt and agent configuration of a user-defined
+// subagent, reporting whether it exists.
+func (c *coordinator) subagent(name string) (*prompt.Prompt, config.Agent, bool, error) {
+	agentCfg, ok := c.cfg.Agents[name]
+	if !ok || agentCfg.Instructions == "" {
+		return nil, config.Agent{}, false, nil
+	}
+	p, err := subagentPrompt(
+		name,
+		prompt.WithWorkingDir(c.cfg.WorkingDir()),
+		prompt.WithInstructions(agentCfg.Instructions),
+	)
+	if err != nil {
+		return nil, config.Agent{}, false, err
+	}
+	return p, agentCfg, true, nil
+}
+
+// subagentsDescription lists the user-defined subagents for the agent tool
+// description.
+func (c *coordinator) subagentsDescription() string {
+	names := c.cfg.SubagentNames()
+	if len(names) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n<subagents>\nSet subagent to one of the following names to delegate a task to a specialized agent, when its description matches the task:\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "- %s: %s\n", name, c.cfg.Agents[name].Description)
+	}
+	sb.WriteString("</subagents>\n")
+	return sb.String()
+}
+
+// buildTaskAgent builds a task agent running with the given model type,
+// with its tools ready to use.
+func (c *coordinator) buildTaskAgent(ctx context.Context, prompt *prompt.Prompt, agentCfg config.Agent, modelType config.SelectedModelType) (SessionAgent, error) {
+	large, small, err := c.buildAgentModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	model := large
+	if modelType == config.SelectedModelTypeSmall {
+		model = small
+	}
+	systemPrompt, err := prompt.Build(ctx, model.Model.Provider(), model.Model.Model(), *c.cfg)
+	if err != nil {
+		return nil, err
+	}
+	agentTools, err := c.buildTools(ctx, agentCfg)
+	if err != nil {
+		return nil, err
+	}
+	providerCfg, _ := c.cfg.Providers.Get(model.ModelCfg.Provider)
+	return NewSessionAgent(SessionAgentOptions{
+		LargeModel:           model,
+		SmallModel:           small,
+		SystemPromptPrefix:   providerCfg.SystemPromptPrefix,
+		SystemPrompt:         systemPrompt,
+		DisableAutoSummarize: c.cfg.Options.DisableAutoSummarize,
+		IsYolo:               c.permissions.SkipRequests(),
+		Sessions:             c.sessions,
+		Messages:             c.messages,
+		Tools:                agentTools,
+		AutoCompactThreshold: c.cfg.Options.AutoCompactThreshold,
+		CompactKeepTurns:     c.cfg.Options.CompactKeepTurns,
+		CheckBudget:          c.checkBudget,
+	}), nil
+}
+
+func (c *coordinator) agentOutputTool() fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		AgentOutputToolName,
+		string(agentOutputToolDescription),
+		func(ctx context.Context, params AgentOutputParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if params.TaskID == "" {
+				return fantasy.NewTextErrorResponse("missing task_id"), nil
 			}
-			return fantasy.NewTextResponse(result.Response.Content.Text()), nil
-		}), nil
+			task, ok := c.background.get(params.TaskID)
+			if !ok {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("background agent not found: %s", params.TaskID)), nil
+			}
+			switch task.Status {
+			case BackgroundTaskRunning:
+				return fantasy.NewTextResponse(fmt.Sprintf("Background agent %s is still running, started %s ago.", task.ID, time.Since(task.StartedAt).Round(time.Second))), nil
+			case BackgroundTaskFailed:
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("Background agent %s failed: %s", task.ID, task.Error)), nil
+			case BackgroundTaskCancelled:
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("Background agent %s was cancelled.", task.ID)), nil
+			}
+			return fantasy.NewTextResponse(task.Result), nil
+		})
 }
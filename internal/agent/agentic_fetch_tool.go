@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
 )
 
 //go:embed templates/agentic_fetch.md
@@ -74,6 +75,9 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 			if err != nil {
 				return fantasy.NewTextErrorResponse(err.Error()), nil
 			}
+			if err := tools.CheckFetchURL(params.URL, c.cfg.Tools.Fetch); err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
 
 			p := c.permissions.Request(
 				permission.CreatePermissionRequest{
@@ -91,7 +95,7 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
 			}
 
-			content, err := tools.FetchURLAndConvert(ctx, client, params.URL)
+			content, err := tools.FetchURLAndConvert(ctx, client, params.URL, c.cfg.Tools.Fetch)
 			if err != nil {
 				return fantasy.NewTextErrorResponse(fmt.Sprintf("Failed to fetch URL: %s", err)), nil
 			}
@@ -147,7 +151,7 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 				return fantasy.ToolResponse{}, errors.New("small model provider not configured")
 			}
 
-			webFetchTool := tools.NewWebFetchTool(tmpDir, client)
+			webFetchTool := tools.NewWebFetchTool(tmpDir, client, c.cfg.Tools.Fetch)
 			fetchTools := []fantasy.AgentTool{
 				webFetchTool,
 				tools.NewGlobTool(tmpDir),
@@ -165,15 +169,18 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 				Sessions:             c.sessions,
 				Messages:             c.messages,
 				Tools:                fetchTools,
+				AutoCompactThreshold: c.cfg.Options.AutoCompactThreshold,
+				CompactKeepTurns:     c.cfg.Options.CompactKeepTurns,
+				CheckBudget:          c.checkBudget,
 			})
 
 			agentToolSessionID := c.sessions.CreateAgentToolSessionID(validationResult.AgentMessageID, call.ID)
-			session, err := c.sessions.CreateTaskSession(ctx, agentToolSessionID, validationResult.SessionID, "Fetch Analysis")
+			fetchSession, err := c.sessions.CreateTaskSession(ctx, agentToolSessionID, validationResult.SessionID, "Fetch Analysis")
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
 			}
 
-			c.permissions.AutoApproveSession(session.ID)
+			c.permissions.AutoApproveSession(fetchSession.ID)
 
 			// Use small model for web content analysis (faster and cheaper)
 			maxTokens := small.CatwalkCfg.DefaultMaxTokens
@@ -182,7 +189,7 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 			}
 
 			result, err := agent.Run(ctx, SessionAgentCall{
-				SessionID:        session.ID,
+				SessionID:        fetchSession.ID,
 				Prompt:           fullPrompt,
 				MaxOutputTokens:  maxTokens,
 				ProviderOptions:  getProviderOptions(small, smallProviderCfg),
@@ -196,18 +203,11 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 				return fantasy.NewTextErrorResponse("error generating response"), nil
 			}
 
-			updatedSession, err := c.sessions.Get(ctx, session.ID)
+			updatedSession, err := c.sessions.Get(ctx, fetchSession.ID)
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error getting session: %s", err)
 			}
-			parentSession, err := c.sessions.Get(ctx, validationResult.SessionID)
-			if err != nil {
-				return fantasy.ToolResponse{}, fmt.Errorf("error getting parent session: %s", err)
-			}
-
-			parentSession.Cost += updatedSession.Cost
-
-			_, err = c.sessions.Save(ctx, parentSession)
+			_, err = c.sessions.AddUsage(ctx, validationResult.SessionID, session.Usage{Cost: updatedSession.Cost})
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error saving parent session: %s", err)
 			}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/git"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+// AutoCommitToolName is the name the confirmation of automatic commits is
+// requested under.
+const AutoCommitToolName = "auto_commit"
+
+// autoCommit commits the files the tools of the agent wrote since the
+// prompt started at since, a Unix time, with a message written by the small
+// model, once the user confirms it. The changes are staged in an index of
+// their own, so the user's index and other changes are left alone. Nothing
+// is done outside of git repositories, or when no file was written.
+func (c *coordinator) autoCommit(ctx context.Context, sessionID string, since int64) error {
+	dir := c.cfg.WorkingDir()
+	root, err := git.Run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil
+	}
+	paths, err := c.writtenFiles(ctx, sessionID, since, dir, root)
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+
+	indexDir, err := os.MkdirTemp("", "crush-commit-")
+	if err != nil {
+		return fmt.Errorf("failed to create commit index: %w", err)
+	}
+	defer os.RemoveAll(indexDir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(indexDir, "index")}
+	if _, err := git.RunEnv(ctx, root, env, "read-tree", "HEAD"); err != nil {
+		if _, err := git.RunEnv(ctx, root, env, "read-tree", "--empty"); err != nil {
+			return err
+		}
+	}
+	if _, err := git.RunEnv(ctx, root, env, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+		return err
+	}
+	stat, err := git.RunEnv(ctx, root, env, "diff", "--cached", "--stat")
+	if err != nil || stat == "" {
+		return err
+	}
+	diff, err := git.RunEnv(ctx, root, env, "diff", "--cached")
+	if err != nil {
+		return err
+	}
+
+	msg, err := c.GenerateCommitMessage(ctx, diff)
+	if err != nil {
+		return err
+	}
+	msg = withAttribution(msg, c.cfg.Options.Attribution, c.currentAgent.Model().CatwalkCfg.Name)
+
+	if !c.permissions.Confirm(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		ToolName:    AutoCommitToolName,
+		Description: msg,
+		Action:      "commit",
+		Params:      stat,
+		Path:        dir,
+	}) {
+		return nil
+	}
+	if _, err := git.RunEnv(ctx, root, env, "commit", "--quiet", "-m", msg); err != nil {
+		return err
+	}
+	// The committed files are now as in HEAD, in the user's index too.
+	_, err = git.Run(ctx, root, append([]string{"reset", "--quiet", "--"}, paths...)...)
+	return err
+}
+
+// writtenFiles returns the paths, relative to the root of the repository,
+// of the files of the repository the tools of the agent working in dir wrote
+// in the session since the Unix time since.
+func (c *coordinator) writtenFiles(ctx context.Context, sessionID string, since int64, dir, root string) ([]string, error) {
+	files, err := c.history.ListBySession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files of the session: %w", err)
+	}
+	var paths []string
+	for _, f := range files {
+		if f.CreatedAt < since {
+			continue
+		}
+		path := f.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || !filepath.IsLocal(rel) || slices.Contains(paths, rel) {
+			continue
+		}
+		// Files created then deleted by the agent were never in git.
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			if tracked, _ := git.Run(ctx, root, "ls-files", "--", rel); tracked == "" {
+				continue
+			}
+		}
+		paths = append(paths, rel)
+	}
+	return paths, nil
+}
+
+// withAttribution appends the lines crediting Crush and the model to a
+// commit message, following the attribution settings.
+func withAttribution(msg string, attribution *config.Attribution, modelName string) string {
+	if attribution == nil {
+		attribution = &config.Attribution{TrailerStyle: config.TrailerStyleAssistedBy, GeneratedWith: true}
+	}
+	if attribution.GeneratedWith {
+		msg += "\n\n💘 Generated with Crush"
+	}
+	switch attribution.TrailerStyle {
+	case config.TrailerStyleAssistedBy:
+		msg += fmt.Sprintf("\n\nAssisted-by: %s via Crush <crush@charm.land>", modelName)
+	case config.TrailerStyleCoAuthoredBy:
+		msg += "\n\nCo-Authored-By: Crush <crush@charm.land>"
+	}
+	return msg
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAttribution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		attribution *config.Attribution
+		expected    string
+	}{
+		{
+			name:        "defaults",
+			attribution: nil,
+			expected:    "Fix parser\n\n💘 Generated with Crush\n\nAssisted-by: Model via Crush <crush@charm.land>",
+		},
+		{
+			name:        "co-authored-by",
+			attribution: &config.Attribution{TrailerStyle: config.TrailerStyleCoAuthoredBy},
+			expected:    "Fix parser\n\nCo-Authored-By: Crush <crush@charm.land>",
+		},
+		{
+			name:        "none",
+			attribution: &config.Attribution{TrailerStyle: config.TrailerStyleNone},
+			expected:    "Fix parser",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, withAttribution("Fix parser", tt.attribution, "Model"))
+		})
+	}
+}
+
+func TestWrittenFiles(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not in $PATH")
+	}
+
+	env := testEnv(t)
+	root := env.workingDir
+	_, err := git.Run(t.Context(), root, "init", "--quiet")
+	require.NoError(t, err)
+	write := func(name string) string {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+		return path
+	}
+
+	c := &coordinator{history: env.history}
+	sess, err := env.sessions.Create(t.Context(), "Session")
+	require.NoError(t, err)
+	_, err = env.history.Create(t.Context(), sess.ID, write("old.go"), "")
+	require.NoError(t, err)
+	since := time.Now().Unix() + 1
+	time.Sleep(time.Until(time.Unix(since, 0)))
+
+	_, err = env.history.Create(t.Context(), sess.ID, write("main.go"), "")
+	require.NoError(t, err)
+	_, err = env.history.CreateVersion(t.Context(), sess.ID, filepath.Join(root, "main.go"), "main.go")
+	require.NoError(t, err)
+	_, err = env.history.Create(t.Context(), sess.ID, filepath.Join(root, "gone.go"), "")
+	require.NoError(t, err)
+	_, err = env.history.Create(t.Context(), sess.ID, filepath.Join(t.TempDir(), "outside.go"), "")
+	require.NoError(t, err)
+	write("user.go")
+
+	paths, err := c.writtenFiles(t.Context(), sess.ID, since, root, root)
+	require.NoError(t, err)
+	require.Equal(t, []string{"main.go"}, paths)
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// azureScope is the scope of the Entra ID tokens for Azure OpenAI.
+const azureScope = "https://cognitiveservices.azure.com/.default"
+
+// azureTokenRefresh is how long before they expire tokens are refreshed.
+const azureTokenRefresh = 5 * time.Minute
+
+// azureTransport adds the api-version to the requests to Azure OpenAI and,
+// given a credential, authenticates them with Microsoft Entra ID instead of
+// the API key.
+type azureTransport struct {
+	transport  http.RoundTripper
+	apiVersion string
+	credential azcore.TokenCredential
+
+	mu    sync.Mutex
+	token azcore.AccessToken
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *azureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.apiVersion != "" {
+		query := req.URL.Query()
+		query.Set("api-version", t.apiVersion)
+		req.URL.RawQuery = query.Encode()
+	}
+	if t.credential != nil {
+		token, err := t.accessToken(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Del("Api-Key")
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// accessToken returns the cached Entra ID token, getting a new one when it is
+// about to expire.
+func (t *azureTransport) accessToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token.Token != "" && time.Until(t.token.ExpiresOn) > azureTokenRefresh {
+		return t.token.Token, nil
+	}
+	token, err := t.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{azureScope}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get microsoft entra id token: %w", err)
+	}
+	t.token = token
+	return token.Token, nil
+}
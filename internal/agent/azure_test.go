@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCredential struct {
+	calls int
+}
+
+func (c *fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.calls++
+	return azcore.AccessToken{Token: "entra-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestAzureTransport(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		apiVersion, apiKey, authorization string
+	}
+	requests := make(chan received, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- received{
+			apiVersion:    r.URL.Query().Get("api-version"),
+			apiKey:        r.Header.Get("Api-Key"),
+			authorization: r.Header.Get("Authorization"),
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	send := func(transport *azureTransport) received {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+"/openai/v1/responses", nil)
+		require.NoError(t, err)
+		req.Header.Set("Api-Key", "secret")
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return <-requests
+	}
+
+	t.Run("api key", func(t *testing.T) {
+		got := send(&azureTransport{transport: http.DefaultTransport})
+		require.Equal(t, received{apiKey: "secret"}, got)
+	})
+
+	t.Run("entra id", func(t *testing.T) {
+		credential := &fakeCredential{}
+		transport := &azureTransport{
+			transport:  http.DefaultTransport,
+			apiVersion: "preview",
+			credential: credential,
+		}
+		for range 2 {
+			got := send(transport)
+			require.Equal(t, received{apiVersion: "preview", authorization: "Bearer entra-token"}, got)
+		}
+		require.Equal(t, 1, credential.calls, "token should be cached")
+	})
+}
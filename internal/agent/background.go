@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// MaxBackgroundTasks is the number of background agents that can run at once.
+const MaxBackgroundTasks = 5
+
+// BackgroundTaskStatus is the state of a background agent run.
+type BackgroundTaskStatus string
+
+const (
+	BackgroundTaskRunning   BackgroundTaskStatus = "running"
+	BackgroundTaskCompleted BackgroundTaskStatus = "completed"
+	BackgroundTaskFailed    BackgroundTaskStatus = "failed"
+	BackgroundTaskCancelled BackgroundTaskStatus = "cancelled"
+)
+
+// BackgroundTask is an agent run started in the background, which keeps
+// going while the conversation continues.
+type BackgroundTask struct {
+	ID string
+	// ParentSessionID is the session that started the task, and SessionID
+	// the one holding its own messages.
+	ParentSessionID string
+	SessionID       string
+	Prompt          string
+	// Model is the name of the model the task runs with.
+	Model  string
+	Status BackgroundTaskStatus
+	// Result is the final response of the agent, and Error why it failed.
+	Result     string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Done reports whether the task stopped running.
+func (t BackgroundTask) Done() bool {
+	return t.Status != BackgroundTaskRunning
+}
+
+var backgroundTaskBroker = pubsub.NewBroker[BackgroundTask]()
+
+// SubscribeBackgroundTasks returns a channel receiving the background tasks
+// when they start and finish.
+func SubscribeBackgroundTasks(ctx context.Context) <-chan pubsub.Event[BackgroundTask] {
+	return backgroundTaskBroker.Subscribe(ctx)
+}
+
+// backgroundTasks keeps track of the background agent runs.
+type backgroundTasks struct {
+	tasks   *csync.Map[string, BackgroundTask]
+	cancels *csync.Map[string, context.CancelFunc]
+	counter atomic.Int64
+	// slots holds a value per running task, reserved before it starts.
+	slots chan struct{}
+}
+
+func newBackgroundTasks() *backgroundTasks {
+	return &backgroundTasks{
+		tasks:   csync.NewMap[string, BackgroundTask](),
+		cancels: csync.NewMap[string, context.CancelFunc](),
+		slots:   make(chan struct{}, MaxBackgroundTasks),
+	}
+}
+
+// newID returns the ID of the next task.
+func (b *backgroundTasks) newID() string {
+	return fmt.Sprintf("agent-%03X", b.counter.Add(1))
+}
+
+// start runs task in the background until run returns or the task is
+// cancelled.
+func (b *backgroundTasks) start(task BackgroundTask, run func(ctx context.Context) (string, error)) (BackgroundTask, error) {
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		return BackgroundTask{}, fmt.Errorf("maximum number of background agents (%d) reached, wait for one to finish or cancel it", MaxBackgroundTasks)
+	}
+
+	// The task outlives the tool call starting it.
+	ctx, cancel := context.WithCancel(context.Background())
+	task.Status = BackgroundTaskRunning
+	task.StartedAt = time.Now()
+	b.tasks.Set(task.ID, task)
+	b.cancels.Set(task.ID, cancel)
+	backgroundTaskBroker.Publish(pubsub.CreatedEvent, task)
+
+	go func(task BackgroundTask) {
+		defer cancel()
+		result, err := run(ctx)
+		b.cancels.Del(task.ID)
+		<-b.slots
+
+		task.FinishedAt = time.Now()
+		switch {
+		case ctx.Err() != nil || (err != nil && isCancelledErr(err)):
+			task.Status = BackgroundTaskCancelled
+		case err != nil:
+			task.Status = BackgroundTaskFailed
+			task.Error = err.Error()
+		default:
+			task.Status = BackgroundTaskCompleted
+			task.Result = result
+		}
+		b.tasks.Set(task.ID, task)
+		backgroundTaskBroker.Publish(pubsub.UpdatedEvent, task)
+	}(task)
+	return task, nil
+}
+
+func (b *backgroundTasks) get(id string) (BackgroundTask, bool) {
+	return b.tasks.Get(id)
+}
+
+// list returns the tasks, oldest first.
+func (b *backgroundTasks) list() []BackgroundTask {
+	tasks := slices.Collect(b.tasks.Seq())
+	slices.SortFunc(tasks, func(a, b BackgroundTask) int {
+		return cmp.Or(a.StartedAt.Compare(b.StartedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return tasks
+}
+
+// cancel stops a running task, reporting whether there was one.
+func (b *backgroundTasks) cancel(id string) bool {
+	cancel, ok := b.cancels.Take(id)
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (b *backgroundTasks) cancelAll() {
+	for id := range b.cancels.Seq2() {
+		b.cancel(id)
+	}
+}
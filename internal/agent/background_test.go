@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundTasks(t *testing.T) {
+	t.Parallel()
+
+	waitDone := func(t *testing.T, b *backgroundTasks, id string) BackgroundTask {
+		t.Helper()
+		var task BackgroundTask
+		require.Eventually(t, func() bool {
+			task, _ = b.get(id)
+			return task.Done()
+		}, time.Second, time.Millisecond)
+		return task
+	}
+
+	t.Run("completed", func(t *testing.T) {
+		t.Parallel()
+		b := newBackgroundTasks()
+		task, err := b.start(BackgroundTask{ID: b.newID()}, func(ctx context.Context) (string, error) {
+			return "done", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "agent-001", task.ID)
+		require.Equal(t, BackgroundTaskRunning, task.Status)
+
+		task = waitDone(t, b, task.ID)
+		require.Equal(t, BackgroundTaskCompleted, task.Status)
+		require.Equal(t, "done", task.Result)
+		require.False(t, task.FinishedAt.IsZero())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+		b := newBackgroundTasks()
+		task, err := b.start(BackgroundTask{ID: b.newID()}, func(ctx context.Context) (string, error) {
+			return "", errors.New("boom")
+		})
+		require.NoError(t, err)
+
+		task = waitDone(t, b, task.ID)
+		require.Equal(t, BackgroundTaskFailed, task.Status)
+		require.Equal(t, "boom", task.Error)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+		b := newBackgroundTasks()
+		task, err := b.start(BackgroundTask{ID: b.newID()}, func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		require.NoError(t, err)
+		require.True(t, b.cancel(task.ID))
+		require.False(t, b.cancel(task.ID))
+
+		task = waitDone(t, b, task.ID)
+		require.Equal(t, BackgroundTaskCancelled, task.Status)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		t.Parallel()
+		b := newBackgroundTasks()
+		release := make(chan struct{})
+		run := func(ctx context.Context) (string, error) {
+			<-release
+			return "", nil
+		}
+		for range MaxBackgroundTasks {
+			_, err := b.start(BackgroundTask{ID: b.newID()}, run)
+			require.NoError(t, err)
+		}
+		_, err := b.start(BackgroundTask{ID: b.newID()}, run)
+		require.Error(t, err)
+
+		close(release)
+		tasks := b.list()
+		require.Len(t, tasks, MaxBackgroundTasks)
+		for _, task := range tasks {
+			waitDone(t, b, task.ID)
+		}
+		_, err = b.start(BackgroundTask{ID: b.newID()}, run)
+		require.NoError(t, err)
+	})
+
+	t.Run("concurrent limit", func(t *testing.T) {
+		t.Parallel()
+		b := newBackgroundTasks()
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		run := func(ctx context.Context) (string, error) {
+			<-release
+			return "", nil
+		}
+		var started atomic.Int64
+		var wg sync.WaitGroup
+		for range 4 * MaxBackgroundTasks {
+			wg.Go(func() {
+				if _, err := b.start(BackgroundTask{ID: b.newID()}, run); err == nil {
+					started.Add(1)
+				}
+			})
+		}
+		wg.Wait()
+		require.Equal(t, int64(MaxBackgroundTasks), started.Load())
+	})
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+)
+
+// bedrockGeoPrefixes are the prefixes of the IDs of the cross-region
+// inference profiles.
+var bedrockGeoPrefixes = []string{"us.", "eu.", "apac.", "jp.", "au.", "ca.", "us-gov.", "global."}
+
+// bedrockGeographies are the geographies of the cross-region inference
+// profiles of the regions, by prefix of the region.
+var bedrockGeographies = []struct {
+	region, geography string
+}{
+	{"us-gov-", "us-gov"},
+	{"us-", "us"},
+	{"eu-", "eu"},
+	{"ap-", "apac"},
+}
+
+// bedrockModelID returns the ID Bedrock is asked for the model: inference
+// profile ARNs and IDs are used as they are, and the other models go through
+// the cross-region inference profile of the geography of region. Regions out
+// of those geographies ask for the model itself.
+func bedrockModelID(modelID, region string) string {
+	if strings.HasPrefix(modelID, "arn:") {
+		return modelID
+	}
+	for _, prefix := range bedrockGeoPrefixes {
+		if strings.HasPrefix(modelID, prefix) {
+			return modelID
+		}
+	}
+	for _, g := range bedrockGeographies {
+		if strings.HasPrefix(region, g.region) {
+			return g.geography + "." + modelID
+		}
+	}
+	return modelID
+}
+
+// isBedrockAnthropicModel reports whether the Bedrock model is an Anthropic
+// one, directly or through an inference profile. Application inference
+// profile ARNs don't name their model, so they aren't.
+func isBedrockAnthropicModel(modelID string) bool {
+	if strings.HasPrefix(modelID, "arn:") {
+		// arn:partition:bedrock:region:account:kind/id
+		parts := strings.SplitN(modelID, ":", 6)
+		_, modelID, _ = strings.Cut(parts[len(parts)-1], "/")
+	}
+	for _, prefix := range bedrockGeoPrefixes {
+		modelID = strings.TrimPrefix(modelID, prefix)
+	}
+	return strings.HasPrefix(modelID, "anthropic.")
+}
+
+// bedrockTransport sends the requests of the provider to the model and the
+// region they are meant for, and signs them with the credentials of the AWS
+// chain, SSO included. The provider prefixes the models with the geography
+// of AWS_REGION alone, which breaks inference profile ARNs and IDs.
+type bedrockTransport struct {
+	transport http.RoundTripper
+	config    aws.Config
+	signer    *v4.Signer
+	// models are the IDs of the models of the provider, as configured.
+	models []string
+	// bearer is set when authenticating with a Bedrock API key, which the
+	// provider already sets.
+	bearer bool
+}
+
+func newBedrockTransport(transport http.RoundTripper, config aws.Config, models []string, bearer bool) *bedrockTransport {
+	return &bedrockTransport{
+		transport: transport,
+		config:    config,
+		signer:    v4.NewSigner(),
+		models:    models,
+		bearer:    bearer,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *bedrockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Host = fmt.Sprintf("bedrock-runtime.%s.amazonaws.com", t.config.Region)
+	req.Host = ""
+	if rest, ok := strings.CutPrefix(req.URL.EscapedPath(), "/model/"); ok {
+		escaped, method, _ := strings.Cut(rest, "/")
+		modelID, err := url.QueryUnescape(escaped)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bedrock model %q: %w", escaped, err)
+		}
+		modelID = t.configuredModel(modelID)
+		if isBedrockAnthropicModel(modelID) {
+			modelID = bedrockModelID(modelID, t.config.Region)
+		}
+		req.URL.Path = fmt.Sprintf("/model/%s/%s", modelID, method)
+		req.URL.RawPath = fmt.Sprintf("/model/%s/%s", url.QueryEscape(modelID), method)
+	}
+	if t.bearer {
+		return t.transport.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	for _, header := range []string{"Authorization", "X-Amz-Date", "X-Amz-Security-Token"} {
+		req.Header.Del(header)
+	}
+	credentials, err := t.config.Credentials.Retrieve(req.Context())
+	if err != nil {
+		if invalid := new(ssocreds.InvalidTokenError); errors.As(err, &invalid) {
+			return nil, fmt.Errorf("aws sso session expired, run `aws sso login`: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get aws credentials: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := t.signer.SignHTTP(req.Context(), credentials, req, hex.EncodeToString(hash[:]), "bedrock", t.config.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign bedrock request: %w", err)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// configuredModel returns the model as configured, without the prefix the
+// provider adds when it doesn't start with it already: the first two letters
+// of AWS_REGION, whatever the region of the AWS config.
+func (t *bedrockTransport) configuredModel(modelID string) string {
+	if slices.Contains(t.models, modelID) {
+		return modelID
+	}
+	if prefix, rest, ok := strings.Cut(modelID, "."); ok && len(prefix) == 2 {
+		return rest
+	}
+	return modelID
+}
@@ -0,0 +1,542 @@
+package agent
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/object"
+	"charm.land/fantasy/providers/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+)
+
+// bedrockProvider serves the Anthropic models of Bedrock with the Anthropic
+// provider, and the other ones through the Converse API, which takes the same
+// requests whatever the model.
+type bedrockProvider struct {
+	fantasy.Provider
+	client  *http.Client
+	region  string
+	apiKey  string
+	headers map[string]string
+}
+
+// LanguageModel implements fantasy.Provider.
+func (p *bedrockProvider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	if isBedrockAnthropicModel(modelID) {
+		return p.Provider.LanguageModel(ctx, modelID)
+	}
+	return &converseModel{
+		client:  p.client,
+		baseURL: fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", p.region),
+		modelID: modelID,
+		apiKey:  p.apiKey,
+		headers: p.headers,
+	}, nil
+}
+
+// converseModel is a Bedrock model streaming its responses with the
+// ConverseStream API.
+type converseModel struct {
+	client  *http.Client
+	baseURL string
+	modelID string
+	// apiKey is the Bedrock API key, when not signing the requests with the
+	// AWS credentials.
+	apiKey  string
+	headers map[string]string
+}
+
+// Provider implements fantasy.LanguageModel.
+func (m *converseModel) Provider() string {
+	return bedrock.Name
+}
+
+// Model implements fantasy.LanguageModel.
+func (m *converseModel) Model() string {
+	return m.modelID
+}
+
+// GenerateObject implements fantasy.LanguageModel.
+func (m *converseModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return object.GenerateWithTool(ctx, m, call)
+}
+
+// StreamObject implements fantasy.LanguageModel.
+func (m *converseModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return object.StreamWithTool(ctx, m, call)
+}
+
+// Generate implements fantasy.LanguageModel, collecting the streamed
+// response.
+func (m *converseModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	stream, err := m.Stream(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	resp := &fantasy.Response{}
+	var text, reasoning strings.Builder
+	for part := range stream {
+		switch part.Type {
+		case fantasy.StreamPartTypeWarnings:
+			resp.Warnings = append(resp.Warnings, part.Warnings...)
+		case fantasy.StreamPartTypeTextDelta:
+			text.WriteString(part.Delta)
+		case fantasy.StreamPartTypeTextEnd:
+			resp.Content = append(resp.Content, fantasy.TextContent{Text: text.String()})
+			text.Reset()
+		case fantasy.StreamPartTypeReasoningDelta:
+			reasoning.WriteString(part.Delta)
+		case fantasy.StreamPartTypeReasoningEnd:
+			resp.Content = append(resp.Content, fantasy.ReasoningContent{Text: reasoning.String()})
+			reasoning.Reset()
+		case fantasy.StreamPartTypeToolCall:
+			resp.Content = append(resp.Content, fantasy.ToolCallContent{
+				ToolCallID: part.ID,
+				ToolName:   part.ToolCallName,
+				Input:      part.ToolCallInput,
+			})
+		case fantasy.StreamPartTypeFinish:
+			resp.FinishReason = part.FinishReason
+			resp.Usage = part.Usage
+		case fantasy.StreamPartTypeError:
+			return nil, part.Error
+		}
+	}
+	return resp, nil
+}
+
+// Stream implements fantasy.LanguageModel.
+func (m *converseModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	body, warnings, err := converseRequest(call)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/model/%s/converse-stream", m.baseURL, url.PathEscape(m.modelID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range m.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &apiErr)
+		return nil, &fantasy.ProviderError{
+			Title:        fantasy.ErrorTitleForStatusCode(resp.StatusCode),
+			Message:      cmp.Or(apiErr.Message, strings.TrimSpace(string(respBody))),
+			URL:          endpoint,
+			StatusCode:   resp.StatusCode,
+			RequestBody:  body,
+			ResponseBody: respBody,
+		}
+	}
+
+	return func(yield func(fantasy.StreamPart) bool) {
+		defer resp.Body.Close()
+		if len(warnings) > 0 && !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeWarnings, Warnings: warnings}) {
+			return
+		}
+		readConverseStream(resp.Body, yield)
+	}, nil
+}
+
+// converseBlock is a content block of a Converse message.
+type converseBlock map[string]any
+
+type converseMessage struct {
+	Role    string          `json:"role"`
+	Content []converseBlock `json:"content"`
+}
+
+// converseRequest returns the body of the ConverseStream request of call,
+// and the warnings about what it can't send.
+func converseRequest(call fantasy.Call) ([]byte, []fantasy.CallWarning, error) {
+	var warnings []fantasy.CallWarning
+	var system []converseBlock
+	var messages []converseMessage
+	add := func(role string, blocks ...converseBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Messages must alternate between the user and the assistant.
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, blocks...)
+			return
+		}
+		messages = append(messages, converseMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range call.Prompt {
+		var blocks []converseBlock
+		for _, part := range msg.Content {
+			switch part := part.(type) {
+			case fantasy.TextPart:
+				if part.Text != "" {
+					blocks = append(blocks, converseBlock{"text": part.Text})
+				}
+			case fantasy.FilePart:
+				block, ok := converseFile(part.MediaType, part.Data)
+				if !ok {
+					warnings = append(warnings, fantasy.CallWarning{
+						Type:    fantasy.CallWarningTypeOther,
+						Message: fmt.Sprintf("file %s of type %s isn't supported", part.Filename, part.MediaType),
+					})
+					continue
+				}
+				blocks = append(blocks, block)
+			case fantasy.ToolCallPart:
+				input := json.RawMessage(part.Input)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, converseBlock{"toolUse": map[string]any{
+					"toolUseId": part.ToolCallID,
+					"name":      part.ToolName,
+					"input":     input,
+				}})
+			case fantasy.ToolResultPart:
+				blocks = append(blocks, converseToolResult(part))
+			}
+			// Reasoning can't be sent back without the signature of the
+			// model, and is left out.
+		}
+
+		switch msg.Role {
+		case fantasy.MessageRoleSystem:
+			system = append(system, blocks...)
+		case fantasy.MessageRoleAssistant:
+			add("assistant", blocks...)
+		default:
+			add("user", blocks...)
+		}
+	}
+
+	body := map[string]any{"messages": messages}
+	if len(system) > 0 {
+		body["system"] = system
+	}
+	inference := map[string]any{}
+	if call.MaxOutputTokens != nil {
+		inference["maxTokens"] = *call.MaxOutputTokens
+	}
+	if call.Temperature != nil {
+		inference["temperature"] = *call.Temperature
+	}
+	if call.TopP != nil {
+		inference["topP"] = *call.TopP
+	}
+	if len(inference) > 0 {
+		body["inferenceConfig"] = inference
+	}
+	for _, setting := range []struct {
+		name string
+		set  bool
+	}{
+		{"top_k", call.TopK != nil},
+		{"presence_penalty", call.PresencePenalty != nil},
+		{"frequency_penalty", call.FrequencyPenalty != nil},
+	} {
+		if setting.set {
+			warnings = append(warnings, fantasy.CallWarning{Type: fantasy.CallWarningTypeUnsupportedSetting, Setting: setting.name})
+		}
+	}
+
+	if toolConfig := converseTools(call, &warnings); toolConfig != nil {
+		body["toolConfig"] = toolConfig
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode converse request: %w", err)
+	}
+	return data, warnings, nil
+}
+
+// converseFile returns the block of an attached file, as an image or as
+// text, if the Converse API takes it.
+func converseFile(mediaType string, data []byte) (converseBlock, bool) {
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return converseBlock{"image": map[string]any{
+			"format": strings.TrimPrefix(mediaType, "image/"),
+			"source": map[string]any{"bytes": data},
+		}}, true
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return converseBlock{"text": string(data)}, true
+	}
+	return nil, false
+}
+
+func converseToolResult(part fantasy.ToolResultPart) converseBlock {
+	status := "success"
+	var content []converseBlock
+	switch output := part.Output.(type) {
+	case fantasy.ToolResultOutputContentText:
+		content = append(content, converseBlock{"text": output.Text})
+	case fantasy.ToolResultOutputContentError:
+		status = "error"
+		if output.Error != nil {
+			content = append(content, converseBlock{"text": output.Error.Error()})
+		}
+	case fantasy.ToolResultOutputContentMedia:
+		data, err := base64.StdEncoding.DecodeString(output.Data)
+		if block, ok := converseFile(output.MediaType, data); err == nil && ok {
+			content = append(content, block)
+		}
+	}
+	if len(content) == 0 {
+		content = append(content, converseBlock{"text": ""})
+	}
+	return converseBlock{"toolResult": map[string]any{
+		"toolUseId": part.ToolCallID,
+		"content":   content,
+		"status":    status,
+	}}
+}
+
+// converseTools returns the tool configuration of call, if it has tools the
+// model may use.
+func converseTools(call fantasy.Call, warnings *[]fantasy.CallWarning) map[string]any {
+	if call.ToolChoice != nil && *call.ToolChoice == fantasy.ToolChoiceNone {
+		return nil
+	}
+	var tools []converseBlock
+	for _, tool := range call.Tools {
+		fn, ok := tool.(fantasy.FunctionTool)
+		if !ok {
+			*warnings = append(*warnings, fantasy.CallWarning{Type: fantasy.CallWarningTypeUnsupportedTool, Tool: tool})
+			continue
+		}
+		schema := fn.InputSchema
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, converseBlock{"toolSpec": map[string]any{
+			"name":        fn.Name,
+			"description": fn.Description,
+			"inputSchema": map[string]any{"json": schema},
+		}})
+	}
+	if len(tools) == 0 {
+		return nil
+	}
+
+	config := map[string]any{"tools": tools}
+	if call.ToolChoice != nil {
+		switch choice := *call.ToolChoice; choice {
+		case fantasy.ToolChoiceAuto:
+			config["toolChoice"] = map[string]any{"auto": map[string]any{}}
+		case fantasy.ToolChoiceRequired:
+			config["toolChoice"] = map[string]any{"any": map[string]any{}}
+		default:
+			config["toolChoice"] = map[string]any{"tool": map[string]any{"name": string(choice)}}
+		}
+	}
+	return config
+}
+
+// converseEvent is the payload of an event of a ConverseStream response.
+type converseEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+	Start             *struct {
+		ToolUse *struct {
+			ToolUseID string `json:"toolUseId"`
+			Name      string `json:"name"`
+		} `json:"toolUse"`
+	} `json:"start"`
+	Delta *struct {
+		Text    *string `json:"text"`
+		ToolUse *struct {
+			Input string `json:"input"`
+		} `json:"toolUse"`
+		ReasoningContent *struct {
+			Text string `json:"text"`
+		} `json:"reasoningContent"`
+	} `json:"delta"`
+	StopReason string `json:"stopReason"`
+	Usage      *struct {
+		InputTokens           int64 `json:"inputTokens"`
+		OutputTokens          int64 `json:"outputTokens"`
+		TotalTokens           int64 `json:"totalTokens"`
+		CacheReadInputTokens  int64 `json:"cacheReadInputTokens"`
+		CacheWriteInputTokens int64 `json:"cacheWriteInputTokens"`
+	} `json:"usage"`
+	Message string `json:"message"`
+}
+
+// converseBlockState is what was streamed of a content block.
+type converseBlockState struct {
+	kind  fantasy.ContentType
+	id    string
+	name  string
+	input strings.Builder
+}
+
+// converseExceptions are the status codes of the exceptions of a stream,
+// for the retries and fallbacks to handle them as the same HTTP errors.
+var converseExceptions = map[string]int{
+	"throttlingException":         http.StatusTooManyRequests,
+	"serviceUnavailableException": http.StatusServiceUnavailable,
+	"internalServerException":     http.StatusInternalServerError,
+	"modelStreamErrorException":   http.StatusFailedDependency,
+	"validationException":         http.StatusBadRequest,
+}
+
+// readConverseStream yields the parts of the events of a ConverseStream
+// response read from r.
+func readConverseStream(r io.Reader, yield func(fantasy.StreamPart) bool) {
+	decoder := eventstream.NewDecoder()
+	blocks := map[int]*converseBlockState{}
+	finish := fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonUnknown}
+	for {
+		msg, err := decoder.Decode(r, nil)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: fmt.Errorf("failed to read converse stream: %w", err)})
+			return
+		}
+		var event converseEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: fmt.Errorf("failed to decode converse event: %w", err)})
+			return
+		}
+
+		if messageType := eventHeader(msg, ":message-type"); messageType != "event" {
+			exception := eventHeader(msg, ":exception-type")
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: &fantasy.ProviderError{
+				Title:      cmp.Or(exception, messageType),
+				Message:    event.Message,
+				StatusCode: converseExceptions[exception],
+			}})
+			return
+		}
+
+		index := event.ContentBlockIndex
+		var parts []fantasy.StreamPart
+		switch eventHeader(msg, ":event-type") {
+		case "contentBlockStart":
+			if event.Start != nil && event.Start.ToolUse != nil {
+				blocks[index] = &converseBlockState{
+					kind: fantasy.ContentTypeToolCall,
+					id:   event.Start.ToolUse.ToolUseID,
+					name: event.Start.ToolUse.Name,
+				}
+				parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeToolInputStart, ID: event.Start.ToolUse.ToolUseID, ToolCallName: event.Start.ToolUse.Name})
+			}
+		case "contentBlockDelta":
+			if event.Delta == nil {
+				continue
+			}
+			id := strconv.Itoa(index)
+			block := blocks[index]
+			switch {
+			case event.Delta.Text != nil:
+				if block == nil {
+					block = &converseBlockState{kind: fantasy.ContentTypeText}
+					blocks[index] = block
+					parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeTextStart, ID: id})
+				}
+				parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, ID: id, Delta: *event.Delta.Text})
+			case event.Delta.ReasoningContent != nil:
+				if block == nil {
+					block = &converseBlockState{kind: fantasy.ContentTypeReasoning}
+					blocks[index] = block
+					parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningStart, ID: id})
+				}
+				if event.Delta.ReasoningContent.Text != "" {
+					parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningDelta, ID: id, Delta: event.Delta.ReasoningContent.Text})
+				}
+			case event.Delta.ToolUse != nil && block != nil:
+				block.input.WriteString(event.Delta.ToolUse.Input)
+				parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeToolInputDelta, ID: block.id, Delta: event.Delta.ToolUse.Input})
+			}
+		case "contentBlockStop":
+			block := blocks[index]
+			if block == nil {
+				continue
+			}
+			delete(blocks, index)
+			id := strconv.Itoa(index)
+			switch block.kind {
+			case fantasy.ContentTypeText:
+				parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: id})
+			case fantasy.ContentTypeReasoning:
+				parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningEnd, ID: id})
+			case fantasy.ContentTypeToolCall:
+				input := cmp.Or(block.input.String(), "{}")
+				parts = append(parts,
+					fantasy.StreamPart{Type: fantasy.StreamPartTypeToolInputEnd, ID: block.id},
+					fantasy.StreamPart{Type: fantasy.StreamPartTypeToolCall, ID: block.id, ToolCallName: block.name, ToolCallInput: input},
+				)
+			}
+		case "messageStop":
+			finish.FinishReason = converseFinishReason(event.StopReason)
+		case "metadata":
+			if event.Usage != nil {
+				finish.Usage = fantasy.Usage{
+					InputTokens:         event.Usage.InputTokens,
+					OutputTokens:        event.Usage.OutputTokens,
+					TotalTokens:         event.Usage.TotalTokens,
+					CacheReadTokens:     event.Usage.CacheReadInputTokens,
+					CacheCreationTokens: event.Usage.CacheWriteInputTokens,
+				}
+			}
+		}
+		for _, part := range parts {
+			if !yield(part) {
+				return
+			}
+		}
+	}
+	yield(finish)
+}
+
+func eventHeader(msg eventstream.Message, name string) string {
+	if v := msg.Headers.Get(name); v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+func converseFinishReason(stopReason string) fantasy.FinishReason {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return fantasy.FinishReasonStop
+	case "tool_use":
+		return fantasy.FinishReasonToolCalls
+	case "max_tokens":
+		return fantasy.FinishReasonLength
+	case "guardrail_intervened", "content_filtered":
+		return fantasy.FinishReasonContentFilter
+	default:
+		return fantasy.FinishReasonUnknown
+	}
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverseModel(t *testing.T) {
+	t.Parallel()
+
+	var stream bytes.Buffer
+	encoder := eventstream.NewEncoder()
+	event := func(eventType, payload string) {
+		var headers eventstream.Headers
+		headers.Set(":message-type", eventstream.StringValue("event"))
+		headers.Set(":event-type", eventstream.StringValue(eventType))
+		require.NoError(t, encoder.Encode(&stream, eventstream.Message{Headers: headers, Payload: []byte(payload)}))
+	}
+	event("messageStart", `{"role":"assistant"}`)
+	event("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Let me "}}`)
+	event("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"look."}}`)
+	event("contentBlockStop", `{"contentBlockIndex":0}`)
+	event("contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"tool-1","name":"ls"}}}`)
+	event("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"path\":"}}}`)
+	event("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\".\"}"}}}`)
+	event("contentBlockStop", `{"contentBlockIndex":1}`)
+	event("messageStop", `{"stopReason":"tool_use"}`)
+	event("metadata", `{"usage":{"inputTokens":120,"outputTokens":30,"totalTokens":150},"metrics":{"latencyMs":300}}`)
+
+	var request *http.Request
+	var requestBody []byte
+	model := &converseModel{
+		client: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			request = req
+			requestBody, _ = io.ReadAll(req.Body)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(&stream)}, nil
+		})},
+		baseURL: "https://bedrock-runtime.eu-west-1.amazonaws.com",
+		modelID: "amazon.nova-pro-v1:0",
+	}
+
+	maxTokens := int64(1000)
+	choice := fantasy.ToolChoiceAuto
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{
+			fantasy.NewSystemMessage("You are a coder."),
+			fantasy.NewUserMessage("List the files"),
+			{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+				fantasy.ReasoningPart{Text: "The ls tool lists them."},
+				fantasy.ToolCallPart{ToolCallID: "tool-0", ToolName: "ls", Input: `{}`},
+			}},
+			{Role: fantasy.MessageRoleTool, Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{ToolCallID: "tool-0", Output: fantasy.ToolResultOutputContentText{Text: "- main.go"}},
+			}},
+			fantasy.NewUserMessage("And in the current directory?"),
+		},
+		MaxOutputTokens: &maxTokens,
+		Tools: []fantasy.Tool{fantasy.FunctionTool{
+			Name:        "ls",
+			Description: "Lists files",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+		}},
+		ToolChoice: &choice,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "/model/amazon.nova-pro-v1:0/converse-stream", request.URL.Path)
+	require.JSONEq(t, `{
+		"system": [{"text": "You are a coder."}],
+		"messages": [
+			{"role": "user", "content": [{"text": "List the files"}]},
+			{"role": "assistant", "content": [{"toolUse": {"toolUseId": "tool-0", "name": "ls", "input": {}}}]},
+			{"role": "user", "content": [
+				{"toolResult": {"toolUseId": "tool-0", "content": [{"text": "- main.go"}], "status": "success"}},
+				{"text": "And in the current directory?"}
+			]}
+		],
+		"inferenceConfig": {"maxTokens": 1000},
+		"toolConfig": {
+			"tools": [{"toolSpec": {"name": "ls", "description": "Lists files", "inputSchema": {"json": {"type": "object", "properties": {"path": {"type": "string"}}}}}}],
+			"toolChoice": {"auto": {}}
+		}
+	}`, string(requestBody))
+
+	require.Equal(t, fantasy.FinishReasonToolCalls, resp.FinishReason)
+	require.Equal(t, fantasy.Usage{InputTokens: 120, OutputTokens: 30, TotalTokens: 150}, resp.Usage)
+	require.Equal(t, "Let me look.", resp.Content.Text())
+	calls := resp.Content.ToolCalls()
+	require.Len(t, calls, 1)
+	require.Equal(t, "tool-1", calls[0].ToolCallID)
+	require.Equal(t, "ls", calls[0].ToolName)
+	require.JSONEq(t, `{"path":"."}`, calls[0].Input)
+}
+
+func TestConverseModelErrors(t *testing.T) {
+	t.Parallel()
+
+	respond := func(status int, body []byte) *converseModel {
+		return &converseModel{
+			client: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewReader(body))}, nil
+			})},
+			baseURL: "https://bedrock-runtime.us-east-1.amazonaws.com",
+			modelID: "meta.llama3-70b-instruct-v1:0",
+		}
+	}
+	call := fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("Hi")}}
+
+	_, err := respond(http.StatusForbidden, []byte(`{"message":"You don't have access to the model."}`)).Generate(t.Context(), call)
+	var providerErr *fantasy.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, http.StatusForbidden, providerErr.StatusCode)
+	require.Equal(t, "You don't have access to the model.", providerErr.Message)
+
+	var stream bytes.Buffer
+	var headers eventstream.Headers
+	headers.Set(":message-type", eventstream.StringValue("exception"))
+	headers.Set(":exception-type", eventstream.StringValue("throttlingException"))
+	payload, err := json.Marshal(map[string]string{"message": "Too many requests."})
+	require.NoError(t, err)
+	require.NoError(t, eventstream.NewEncoder().Encode(&stream, eventstream.Message{Headers: headers, Payload: payload}))
+
+	_, err = respond(http.StatusOK, stream.Bytes()).Generate(t.Context(), call)
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, http.StatusTooManyRequests, providerErr.StatusCode)
+	require.Equal(t, "throttlingException: Too many requests.", providerErr.Error())
+}
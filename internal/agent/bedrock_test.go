@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBedrockModelID(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		modelID, region, expected string
+	}{
+		{"anthropic.claude-sonnet-4-20250514-v1:0", "us-west-2", "us.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"anthropic.claude-sonnet-4-20250514-v1:0", "eu-central-1", "eu.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"anthropic.claude-sonnet-4-20250514-v1:0", "ap-northeast-1", "apac.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"anthropic.claude-sonnet-4-20250514-v1:0", "us-gov-west-1", "us-gov.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"global.anthropic.claude-sonnet-4-5-20250929-v1:0", "eu-west-1", "global.anthropic.claude-sonnet-4-5-20250929-v1:0"},
+		{"eu.anthropic.claude-sonnet-4-20250514-v1:0", "us-east-1", "eu.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3", "us-east-1", "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3"},
+		{"anthropic.claude-sonnet-4-20250514-v1:0", "sa-east-1", "anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"anthropic.claude-sonnet-4-20250514-v1:0", "me-central-1", "anthropic.claude-sonnet-4-20250514-v1:0"},
+	} {
+		require.Equal(t, tc.expected, bedrockModelID(tc.modelID, tc.region), tc.modelID+" in "+tc.region)
+	}
+}
+
+func TestIsBedrockAnthropicModel(t *testing.T) {
+	t.Parallel()
+
+	for modelID, expected := range map[string]bool{
+		"anthropic.claude-sonnet-4-20250514-v1:0":                                                     true,
+		"global.anthropic.claude-sonnet-4-5-20250929-v1:0":                                            true,
+		"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-3-haiku-v1:0":   true,
+		"arn:aws:bedrock:us-east-1::foundation-model/anthropic.claude-3-5-sonnet-20240620-v1:0":       true,
+		"arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3":                 false,
+		"meta.llama3-70b-instruct-v1:0":                                                               false,
+		"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.meta.llama3-2-90b-instruct-v1:0": false,
+		"arn:aws:bedrock:us-east-1::foundation-model/amazon.nova-pro-v1:0":                            false,
+	} {
+		require.Equal(t, expected, isBedrockAnthropicModel(modelID), modelID)
+	}
+}
+
+func TestBedrockTransport(t *testing.T) {
+	t.Parallel()
+
+	arn := "arn:aws:bedrock:eu-west-1:123456789012:application-inference-profile/a1b2c3"
+	var received *http.Request
+	transport := newBedrockTransport(
+		roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			received = req
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+		aws.Config{
+			Region:      "eu-west-1",
+			Credentials: credentials.NewStaticCredentialsProvider("id", "secret", "session"),
+		},
+		[]string{arn},
+		false,
+	)
+
+	send := func(modelID string) {
+		// As the provider sends it, prefixed with the geography of
+		// AWS_REGION.
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com", strings.NewReader("{}"))
+		require.NoError(t, err)
+		req.URL.Path = "/model/us." + modelID + "/invoke-with-response-stream"
+		req.URL.RawPath = "/model/us." + strings.ReplaceAll(strings.ReplaceAll(modelID, ":", "%3A"), "/", "%2F") + "/invoke-with-response-stream"
+		req.Header.Set("Authorization", "Bearer ")
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	send(arn)
+	require.Equal(t, "bedrock-runtime.eu-west-1.amazonaws.com", received.URL.Host)
+	require.Equal(t, "/model/"+arn+"/invoke-with-response-stream", received.URL.Path)
+	require.True(t, strings.HasPrefix(received.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "))
+	require.Contains(t, received.Header.Get("Authorization"), "/eu-west-1/bedrock/")
+	require.Equal(t, "session", received.Header.Get("X-Amz-Security-Token"))
+	body, err := io.ReadAll(received.Body)
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(body))
+
+	send("anthropic.claude-sonnet-4-20250514-v1:0")
+	require.Equal(t, "/model/eu.anthropic.claude-sonnet-4-20250514-v1:0/invoke-with-response-stream", received.URL.Path)
+
+	send("amazon.nova-pro-v1:0")
+	require.Equal(t, "/model/amazon.nova-pro-v1:0/invoke-with-response-stream", received.URL.Path, "only the Anthropic models go through the inference profile of the region")
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+)
+
+// budgetWarningFraction is the fraction of a budget spent at which users are
+// warned.
+const budgetWarningFraction = 0.8
+
+// BudgetExceededError is returned by Run when the session, or all the sessions
+// of the month, cost more than their budget. The prompt can be sent anyway
+// after allowing the session over budget.
+type BudgetExceededError struct {
+	// Monthly is whether the monthly budget was exceeded, rather than the
+	// budget of the session.
+	Monthly bool
+	Spent   float64
+	Limit   float64
+	// Stopped is whether the run was stopped between two steps, after its
+	// prompt was added to the session.
+	Stopped bool
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Monthly {
+		return fmt.Sprintf("the sessions of this month cost $%.2f, reaching the monthly budget of $%.2f", e.Spent, e.Limit)
+	}
+	return fmt.Sprintf("the session cost $%.2f, reaching its budget of $%.2f", e.Spent, e.Limit)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// budgets tracks the sessions allowed over budget and the warnings given.
+type budgets struct {
+	// allowed holds the sessions the user allowed over budget.
+	allowed *csync.Map[string, bool]
+	// warned holds the sessions and the months warned about.
+	warned *csync.Map[string, bool]
+}
+
+func newBudgets() *budgets {
+	return &budgets{
+		allowed: csync.NewMap[string, bool](),
+		warned:  csync.NewMap[string, bool](),
+	}
+}
+
+// AllowOverBudget implements Coordinator.
+func (c *coordinator) AllowOverBudget(sessionID string) {
+	c.budgets.allowed.Set(sessionID, true)
+}
+
+// BudgetWarning implements Coordinator.
+func (c *coordinator) BudgetWarning(ctx context.Context, sessionID string) string {
+	if _, ok := c.budgets.allowed.Get(sessionID); ok {
+		return ""
+	}
+	sessionCost, monthlyCost, err := c.spent(ctx, sessionID)
+	if err != nil {
+		return ""
+	}
+	opts := c.cfg.Options
+	if warn(opts.MaxMonthlyCost, monthlyCost) && c.warnOnce("month:"+time.Now().Format("2006-01")) {
+		return fmt.Sprintf("The sessions of this month cost $%.2f of the monthly budget of $%.2f", monthlyCost, opts.MaxMonthlyCost)
+	}
+	if warn(opts.MaxSessionCost, sessionCost) && c.warnOnce("session:"+sessionID) {
+		return fmt.Sprintf("This session cost $%.2f of its budget of $%.2f", sessionCost, opts.MaxSessionCost)
+	}
+	return ""
+}
+
+// checkBudget returns a BudgetExceededError when the session, or the sessions
+// of the month, spent their budget, unless the session is allowed over it.
+// The sessions of task agents count against the budget of the session that
+// started them, background ones included.
+func (c *coordinator) checkBudget(ctx context.Context, sessionID string) error {
+	opts := c.cfg.Options
+	if opts.MaxSessionCost <= 0 && opts.MaxMonthlyCost <= 0 {
+		return nil
+	}
+	rootID, err := c.rootSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.budgets.allowed.Get(rootID); ok {
+		return nil
+	}
+	sessionCost, monthlyCost, err := c.spent(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if exceeded(opts.MaxMonthlyCost, monthlyCost) {
+		return &BudgetExceededError{Monthly: true, Spent: monthlyCost, Limit: opts.MaxMonthlyCost}
+	}
+	if exceeded(opts.MaxSessionCost, sessionCost) {
+		return &BudgetExceededError{Spent: sessionCost, Limit: opts.MaxSessionCost}
+	}
+	return nil
+}
+
+// rootSession returns the session that started the task session, or the
+// session itself when it isn't a task.
+func (c *coordinator) rootSession(ctx context.Context, sessionID string) (string, error) {
+	for {
+		session, err := c.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get session: %w", err)
+		}
+		if session.ParentSessionID == "" {
+			return sessionID, nil
+		}
+		sessionID = session.ParentSessionID
+	}
+}
+
+// spent returns the cost of the session and of the sessions of the current
+// month, when they have a budget. The cost of a task session adds up with
+// the costs of the sessions that started it, which only get it once the
+// task finishes.
+func (c *coordinator) spent(ctx context.Context, sessionID string) (sessionCost, monthlyCost float64, err error) {
+	opts := c.cfg.Options
+	for id := sessionID; opts.MaxSessionCost > 0 && id != ""; {
+		session, err := c.sessions.Get(ctx, id)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get session: %w", err)
+		}
+		sessionCost += session.Cost
+		id = session.ParentSessionID
+	}
+	if opts.MaxMonthlyCost > 0 {
+		now := time.Now()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if monthlyCost, err = c.messages.CostSince(ctx, monthStart); err != nil {
+			return 0, 0, fmt.Errorf("failed to get the cost of this month: %w", err)
+		}
+	}
+	return sessionCost, monthlyCost, nil
+}
+
+// warnOnce reports whether key wasn't warned about yet, marking it.
+func (c *coordinator) warnOnce(key string) bool {
+	_, warned := c.budgets.warned.Get(key)
+	c.budgets.warned.Set(key, true)
+	return !warned
+}
+
+func warn(limit, spent float64) bool {
+	return limit > 0 && spent >= limit*budgetWarningFraction && spent < limit
+}
+
+func exceeded(limit, spent float64) bool {
+	return limit > 0 && spent >= limit
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	c := &coordinator{
+		cfg:      &config.Config{Options: &config.Options{MaxSessionCost: 1, MaxMonthlyCost: 10}},
+		sessions: env.sessions,
+		messages: env.messages,
+		budgets:  newBudgets(),
+	}
+	sess, err := env.sessions.Create(t.Context(), "Session")
+	require.NoError(t, err)
+	require.NoError(t, c.checkBudget(t.Context(), sess.ID))
+	require.Empty(t, c.BudgetWarning(t.Context(), sess.ID))
+
+	sess, err = env.sessions.AddUsage(t.Context(), sess.ID, session.Usage{Cost: 0.85})
+	require.NoError(t, err)
+	require.NoError(t, c.checkBudget(t.Context(), sess.ID))
+	require.Equal(t, "This session cost $0.85 of its budget of $1.00", c.BudgetWarning(t.Context(), sess.ID))
+	require.Empty(t, c.BudgetWarning(t.Context(), sess.ID), "warnings are given once")
+
+	sess, err = env.sessions.AddUsage(t.Context(), sess.ID, session.Usage{Cost: 0.35})
+	require.NoError(t, err)
+	err = c.checkBudget(t.Context(), sess.ID)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	require.EqualError(t, err, "the session cost $1.20, reaching its budget of $1.00")
+
+	// Task sessions count against the budget of the session starting them,
+	// which only gets their cost once they finish.
+	parent, err := env.sessions.Create(t.Context(), "Parent")
+	require.NoError(t, err)
+	_, err = env.sessions.AddUsage(t.Context(), parent.ID, session.Usage{Cost: 0.6})
+	require.NoError(t, err)
+	task, err := env.sessions.CreateTaskSession(t.Context(), "task", parent.ID, "Task")
+	require.NoError(t, err)
+	require.NoError(t, c.checkBudget(t.Context(), task.ID))
+	_, err = env.sessions.AddUsage(t.Context(), task.ID, session.Usage{Cost: 0.5})
+	require.NoError(t, err)
+	require.EqualError(t, c.checkBudget(t.Context(), task.ID), "the session cost $1.10, reaching its budget of $1.00")
+	c.AllowOverBudget(parent.ID)
+	require.NoError(t, c.checkBudget(t.Context(), task.ID))
+
+	c.AllowOverBudget(sess.ID)
+	require.NoError(t, c.checkBudget(t.Context(), sess.ID))
+
+	// The monthly budget counts the messages of all the sessions.
+	other, err := env.sessions.Create(t.Context(), "Other")
+	require.NoError(t, err)
+	msg, err := env.messages.Create(t.Context(), other.ID, message.CreateMessageParams{Role: message.Assistant})
+	require.NoError(t, err)
+	msg.Cost = 10.5
+	require.NoError(t, env.messages.Update(t.Context(), msg))
+	err = c.checkBudget(t.Context(), other.ID)
+	var budgetErr *BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	require.True(t, budgetErr.Monthly)
+	require.Equal(t, 10.5, budgetErr.Spent)
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/tokens"
+)
+
+//go:embed templates/commit_message.md
+var commitMessagePrompt []byte
+
+// GenerateCommitMessage writes a commit message for a diff of staged changes.
+func (a *sessionAgent) GenerateCommitMessage(ctx context.Context, diff string) (string, error) {
+	if strings.TrimSpace(diff) == "" {
+		return "", ErrEmptyDiff
+	}
+
+	model, _ := a.auxiliaryModel(tokens.Estimate(diff))
+	agent := fantasy.NewAgent(model.Model,
+		fantasy.WithSystemPrompt(string(commitMessagePrompt)+"\n /no_think"),
+		fantasy.WithMaxOutputTokens(cmp.Or(model.CatwalkCfg.DefaultMaxTokens, 1000)),
+	)
+	resp, err := agent.Stream(ctx, fantasy.AgentStreamCall{
+		Prompt: fmt.Sprintf("Write a commit message for the following diff:\n\n%s\n <think>\n\n</think>", diff),
+		PrepareStep: func(callContext context.Context, options fantasy.PrepareStepFunctionOptions) (_ context.Context, prepared fantasy.PrepareStepResult, err error) {
+			prepared.Messages = options.Messages
+			if a.systemPromptPrefix != "" {
+				prepared.Messages = append([]fantasy.Message{fantasy.NewSystemMessage(a.systemPromptPrefix)}, prepared.Messages...)
+			}
+			return callContext, prepared, nil
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	msg := resp.Response.Content.Text()
+	// Remove thinking tags if present.
+	if idx := strings.Index(msg, "</think>"); idx > 0 {
+		msg = msg[idx+len("</think>"):]
+	}
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		return "", errors.New("failed to generate commit message: empty response")
+	}
+	return msg, nil
+}
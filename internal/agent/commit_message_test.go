@@ -0,0 +1,15 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCommitMessage(t *testing.T) {
+	t.Parallel()
+
+	a := &sessionAgent{}
+	_, err := a.GenerateCommitMessage(t.Context(), "  \n")
+	require.ErrorIs(t, err, ErrEmptyDiff)
+}
@@ -9,19 +9,21 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"maps"
 	"net/http"
 	"os"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"charm.land/fantasy"
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/git"
 	"github.com/charmbracelet/crush/internal/history"
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
@@ -29,7 +31,10 @@ import (
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/copilot"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/repomap"
+	"github.com/charmbracelet/crush/internal/semindex"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tokens"
 	"golang.org/x/sync/errgroup"
 
 	"charm.land/fantasy/providers/anthropic"
@@ -39,6 +44,8 @@ import (
 	"charm.land/fantasy/providers/openai"
 	"charm.land/fantasy/providers/openaicompat"
 	"charm.land/fantasy/providers/openrouter"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	openaisdk "github.com/openai/openai-go/v2/option"
 	"github.com/qjebbs/go-jsons"
 )
@@ -54,8 +61,33 @@ type Coordinator interface {
 	QueuedPrompts(sessionID string) int
 	ClearQueue(sessionID string)
 	Summarize(context.Context, string) error
+	// GenerateCommitMessage writes a commit message for a diff with the
+	// small model.
+	GenerateCommitMessage(ctx context.Context, diff string) (string, error)
+	// BackgroundTasks returns the agents started in the background, oldest
+	// first.
+	BackgroundTasks() []BackgroundTask
+	// CancelBackgroundTask stops a background agent, reporting whether it
+	// was running.
+	CancelBackgroundTask(id string) bool
 	Model() Model
 	UpdateModels(ctx context.Context) error
+	// AllowOverBudget lets the session send prompts over the session and
+	// the monthly budgets.
+	AllowOverBudget(sessionID string)
+	// BudgetWarning returns a warning the first time the session, or the
+	// sessions of the month, spent most of their budget.
+	BudgetWarning(ctx context.Context, sessionID string) string
+	// RecoverSession finishes the response of the session crush exited in
+	// the middle of, keeping what was written so far.
+	RecoverSession(ctx context.Context, sessionID string) error
+	// Review runs the review agent, which can only read files, over a
+	// request for review in the session, returning its response.
+	Review(ctx context.Context, sessionID, request string) (string, error)
+	// Plan sends a prompt in the session like Run, but with the read-only
+	// tools of the task agent only, so that the agent can't change anything
+	// before its plan is reviewed.
+	Plan(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
 }
 
 type coordinator struct {
@@ -64,12 +96,30 @@ type coordinator struct {
 	messages    message.Service
 	permissions permission.Service
 	history     history.Service
+	checkpoints checkpoint.Service
 	lspClients  *csync.Map[string, *lsp.Client]
 
 	currentAgent SessionAgent
 	agents       map[string]SessionAgent
+	background   *backgroundTasks
+	budgets      *budgets
 
 	readyWg errgroup.Group
+
+	// coderPrompt builds the system prompt of the coder agent.
+	coderPrompt *prompt.Prompt
+	// repoMap is the map of the repository the agents send along with their
+	// system prompt, when enabled.
+	repoMap *repomap.Map
+
+	contentExclusionsMu sync.Mutex
+	contentExclusions   *copilot.ContentExclusions
+	// promptExcluded reports whether the system prompt was built again
+	// without the context files the content exclusions leave out.
+	promptExcluded bool
+
+	semanticIndexMu sync.Mutex
+	semanticIndex   *semindex.Index
 }
 
 func NewCoordinator(
@@ -79,6 +129,7 @@ func NewCoordinator(
 	messages message.Service,
 	permissions permission.Service,
 	history history.Service,
+	checkpoints checkpoint.Service,
 	lspClients *csync.Map[string, *lsp.Client],
 ) (Coordinator, error) {
 	c := &coordinator{
@@ -87,8 +138,11 @@ func NewCoordinator(
 		messages:    messages,
 		permissions: permissions,
 		history:     history,
+		checkpoints: checkpoints,
 		lspClients:  lspClients,
 		agents:      make(map[string]SessionAgent),
+		background:  newBackgroundTasks(),
+		budgets:     newBudgets(),
 	}
 
 	agentCfg, ok := cfg.Agents[config.AgentCoder]
@@ -97,7 +151,11 @@ func NewCoordinator(
 	}
 
 	// TODO: make this dynamic when we support multiple agents
-	prompt, err := coderPrompt(prompt.WithWorkingDir(c.cfg.WorkingDir()))
+	prompt, err := coderPrompt(
+		prompt.WithWorkingDir(c.cfg.WorkingDir()),
+		prompt.WithTools(agentCfg.AllowedTools),
+		prompt.WithFragments(cfg.PromptFragments()...),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +164,7 @@ func NewCoordinator(
 	if err != nil {
 		return nil, err
 	}
+	c.coderPrompt = prompt
 	c.currentAgent = agent
 	c.agents[config.AgentCoder] = agent
 	return c, nil
@@ -113,18 +172,91 @@ func NewCoordinator(
 
 // Run implements Coordinator.
 func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
+	return c.send(ctx, SessionAgentCall{
+		SessionID:   sessionID,
+		Prompt:      prompt,
+		Attachments: attachments,
+	})
+}
+
+// Plan implements Coordinator.
+func (c *coordinator) Plan(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
+	agentCfg, ok := c.cfg.Agents[config.AgentTask]
+	if !ok {
+		return nil, errors.New("task agent not configured")
+	}
+	return c.send(ctx, SessionAgentCall{
+		SessionID:   sessionID,
+		Prompt:      prompt,
+		Attachments: attachments,
+		// Not nil even when the task agent has no tools, to leave all out.
+		tools: append([]string{}, agentCfg.AllowedTools...),
+	})
+}
+
+// send sends call to the coder agent, falling back to the fallback models
+// while their providers fail.
+func (c *coordinator) send(ctx context.Context, call SessionAgentCall) (*fantasy.AgentResult, error) {
 	if err := c.readyWg.Wait(); err != nil {
 		return nil, err
 	}
 
+	sessionID, prompt := call.SessionID, call.Prompt
+	if err := c.checkBudget(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	// Snapshot the working directory before the agent changes it, unless the
+	// prompt is queued behind a running one.
+	if c.checkpoints != nil && !c.currentAgent.IsSessionBusy(sessionID) {
+		if _, err := c.checkpoints.Create(ctx, sessionID, prompt); errors.Is(err, checkpoint.ErrTooManyFiles) {
+			slog.Debug("Skipping checkpoint", "session", sessionID, "error", err)
+		} else if err != nil {
+			slog.Warn("Failed to create checkpoint", "session", sessionID, "error", err)
+		}
+	}
+
+	tried := []config.SelectedModel{c.currentAgent.Model().ModelCfg}
+	started := time.Now().Unix()
+	result, err := c.run(ctx, call)
+	// Send the prompt again with the fallback models while their providers
+	// fail.
+	for isFallbackErr(err) {
+		fallback, ok := c.fallBack(ctx, sessionID, tried, err)
+		if !ok {
+			break
+		}
+		tried = append(tried, fallback.ModelCfg)
+		call.retry = true
+		call.model = &fallback
+		result, err = c.run(ctx, call)
+	}
+	if err == nil && c.cfg.Options.AutoCommit {
+		if err := c.autoCommit(ctx, sessionID, started); err != nil {
+			slog.Warn("Failed to commit changes", "session", sessionID, "error", err)
+		}
+	}
+	return result, err
+}
+
+// run sends call with its model, or else the large one, after setting it
+// up for it.
+func (c *coordinator) run(ctx context.Context, call SessionAgentCall) (*fantasy.AgentResult, error) {
 	model := c.currentAgent.Model()
+	if call.model != nil {
+		model = *call.model
+	}
 	maxTokens := model.CatwalkCfg.DefaultMaxTokens
 	if model.ModelCfg.MaxTokens != 0 {
 		maxTokens = model.ModelCfg.MaxTokens
 	}
 
-	if !model.CatwalkCfg.SupportsImages && attachments != nil {
-		attachments = nil
+	// Text attachments are sent as part of the prompt, images only to the
+	// models that can see them.
+	if !model.CatwalkCfg.SupportsImages && slices.ContainsFunc(call.Attachments, func(a message.Attachment) bool {
+		return !a.IsText()
+	}) {
+		return nil, fmt.Errorf("%s: %w", model.CatwalkCfg.Name, ErrImagesNotSupported)
 	}
 
 	providerCfg, ok := c.cfg.Providers.Get(model.ModelCfg.Provider)
@@ -144,24 +276,58 @@ func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string,
 		}
 
 		// Rebuild models with refreshed token
-		if updateErr := c.UpdateModels(ctx); updateErr != nil {
+		if updateErr := c.updateCallModel(ctx, &call); updateErr != nil {
 			slog.Error("Failed to update models after token refresh", "error", updateErr)
 			return nil, updateErr
 		}
 	}
-	result, err := c.currentAgent.Run(ctx, SessionAgentCall{
-		SessionID:        sessionID,
-		Prompt:           prompt,
-		Attachments:      attachments,
-		MaxOutputTokens:  maxTokens,
-		ProviderOptions:  mergedOptions,
-		Temperature:      temp,
-		TopP:             topP,
-		TopK:             topK,
-		FrequencyPenalty: freqPenalty,
-		PresencePenalty:  presPenalty,
-	})
-	return result, err
+	if switched := c.pinSessionAccount(ctx, call.SessionID, providerCfg); switched {
+		if updateErr := c.updateCallModel(ctx, &call); updateErr != nil {
+			slog.Error("Failed to update models after account switch", "error", updateErr)
+			return nil, updateErr
+		}
+		providerCfg, _ = c.cfg.Providers.Get(providerCfg.ID)
+	}
+	if providerCfg.ID == copilot.ProviderID {
+		exclusions, err := c.copilotContentExclusions(ctx, providerCfg)
+		if err != nil {
+			return nil, err
+		}
+		if exclusions != nil {
+			ctx = context.WithValue(ctx, tools.ContentExcluderContextKey, exclusions)
+			if err := c.excludeFromSystemPrompt(ctx, exclusions); err != nil {
+				return nil, err
+			}
+		}
+		for _, a := range call.Attachments {
+			if a.FilePath != "" && tools.IsContentExcluded(ctx, c.cfg.WorkingDir(), a.FilePath) {
+				return nil, fmt.Errorf("%s: %w", a.FileName, ErrAttachmentExcluded)
+			}
+		}
+	}
+
+	call.MaxOutputTokens = maxTokens
+	call.ProviderOptions = mergedOptions
+	call.Temperature = temp
+	call.TopP = topP
+	call.TopK = topK
+	call.FrequencyPenalty = freqPenalty
+	call.PresencePenalty = presPenalty
+	return c.currentAgent.Run(ctx, call)
+}
+
+// updateCallModel builds the model of call again after its provider
+// changed, or the models of the agent when it has none.
+func (c *coordinator) updateCallModel(ctx context.Context, call *SessionAgentCall) error {
+	if call.model == nil {
+		return c.UpdateModels(ctx)
+	}
+	model, err := c.buildModel(ctx, config.SelectedModelTypeLarge, call.model.ModelCfg, call.model.ModelCfg)
+	if err != nil {
+		return err
+	}
+	call.model = &model
+	return nil
 }
 
 func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.ProviderOptions {
@@ -233,18 +399,8 @@ func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.
 				options[openai.Name] = parsed
 			}
 		}
-	case anthropic.Name:
-		_, hasThink := mergedOptions["thinking"]
-		if !hasThink && model.ModelCfg.Think {
-			mergedOptions["thinking"] = map[string]any{
-				// TODO: kujtim see if we need to make this dynamic
-				"budget_tokens": 2000,
-			}
-		}
-		parsed, err := anthropic.ParseOptions(mergedOptions)
-		if err == nil {
-			options[anthropic.Name] = parsed
-		}
+	case anthropic.Name, bedrock.Name:
+		setAnthropicOptions(options, mergedOptions, model.ModelCfg)
 
 	case openrouter.Name:
 		_, hasReasoning := mergedOptions["reasoning"]
@@ -258,11 +414,18 @@ func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.
 		if err == nil {
 			options[openrouter.Name] = parsed
 		}
+	case "google-vertex":
+		// Vertex AI serves the Claude models with the Anthropic API.
+		if config.ThinksWithToggle(providerCfg.Type, model.CatwalkCfg.ID) {
+			setAnthropicOptions(options, mergedOptions, model.ModelCfg)
+			break
+		}
+		fallthrough
 	case google.Name:
 		_, hasReasoning := mergedOptions["thinking_config"]
 		if !hasReasoning {
 			mergedOptions["thinking_config"] = map[string]any{
-				"thinking_budget":  2000,
+				"thinking_budget":  model.ModelCfg.Budget(),
 				"include_thoughts": true,
 			}
 		}
@@ -284,13 +447,32 @@ func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.
 	return options
 }
 
+// setAnthropicOptions sets the options of the Anthropic API, thinking with
+// the budget of the model when it should think.
+func setAnthropicOptions(options fantasy.ProviderOptions, mergedOptions map[string]any, modelCfg config.SelectedModel) {
+	_, hasThink := mergedOptions["thinking"]
+	if !hasThink && modelCfg.Think {
+		mergedOptions["thinking"] = map[string]any{
+			"budget_tokens": modelCfg.Budget(),
+		}
+	}
+	parsed, err := anthropic.ParseOptions(mergedOptions)
+	if err == nil {
+		options[anthropic.Name] = parsed
+	}
+}
+
 func mergeCallOptions(model Model, cfg config.ProviderConfig) (fantasy.ProviderOptions, *float64, *float64, *int64, *float64, *float64) {
 	modelOptions := getProviderOptions(model, cfg)
-	temp := cmp.Or(model.ModelCfg.Temperature, model.CatwalkCfg.Options.Temperature)
-	topP := cmp.Or(model.ModelCfg.TopP, model.CatwalkCfg.Options.TopP)
+	overrides := cfg.ModelOptions[model.CatwalkCfg.ID]
+	temp := cmp.Or(model.ModelCfg.Temperature, overrides.Temperature, model.CatwalkCfg.Options.Temperature)
+	if !cfg.SupportsTemperature(model.CatwalkCfg.ID) {
+		temp = nil
+	}
+	topP := cmp.Or(model.ModelCfg.TopP, overrides.TopP, model.CatwalkCfg.Options.TopP)
 	topK := cmp.Or(model.ModelCfg.TopK, model.CatwalkCfg.Options.TopK)
-	freqPenalty := cmp.Or(model.ModelCfg.FrequencyPenalty, model.CatwalkCfg.Options.FrequencyPenalty)
-	presPenalty := cmp.Or(model.ModelCfg.PresencePenalty, model.CatwalkCfg.Options.PresencePenalty)
+	freqPenalty := cmp.Or(model.ModelCfg.FrequencyPenalty, overrides.FrequencyPenalty, model.CatwalkCfg.Options.FrequencyPenalty)
+	presPenalty := cmp.Or(model.ModelCfg.PresencePenalty, overrides.PresencePenalty, model.CatwalkCfg.Options.PresencePenalty)
 	return modelOptions, temp, topP, topK, freqPenalty, presPenalty
 }
 
@@ -305,6 +487,11 @@ func (c *coordinator) buildAgent(ctx context.Context, prompt *prompt.Prompt, age
 		return nil, err
 	}
 
+	if c.cfg.Options.RepoMapTokens > 0 && c.repoMap == nil {
+		c.repoMap = repomap.New(c.cfg.WorkingDir(), c.cfg.Options.RepoMapTokens)
+		go c.repoMap.Refresh()
+	}
+
 	largeProviderCfg, _ := c.cfg.Providers.Get(large.ModelCfg.Provider)
 	result := NewSessionAgent(SessionAgentOptions{
 		large,
@@ -316,6 +503,10 @@ func (c *coordinator) buildAgent(ctx context.Context, prompt *prompt.Prompt, age
 		c.sessions,
 		c.messages,
 		nil,
+		c.cfg.Options.AutoCompactThreshold,
+		c.cfg.Options.CompactKeepTurns,
+		c.repoMap,
+		c.checkBudget,
 	})
 	c.readyWg.Go(func() error {
 		tools, err := c.buildTools(ctx, agent)
@@ -336,7 +527,7 @@ func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fan
 		if err != nil {
 			return nil, err
 		}
-		allTools = append(allTools, agentTool)
+		allTools = append(allTools, agentTool, c.agentOutputTool())
 	}
 
 	if slices.Contains(agent.AllowedTools, tools.AgenticFetchToolName) {
@@ -349,30 +540,36 @@ func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fan
 
 	// Get the model name for the agent
 	modelName := ""
-	if modelCfg, ok := c.cfg.Models[agent.Model]; ok {
+	if modelCfg, ok := c.cfg.GetSelectedModel(agent.Model); ok {
 		if model := c.cfg.GetModel(modelCfg.Provider, modelCfg.Model); model != nil {
 			modelName = model.Name
 		}
 	}
 
 	allTools = append(allTools,
-		tools.NewBashTool(c.permissions, c.cfg.WorkingDir(), c.cfg.Options.Attribution, modelName),
+		tools.NewBashTool(c.permissions, c.cfg.WorkingDir(), c.cfg.Options.Attribution, modelName, c.cfg.Tools.Bash),
 		tools.NewJobOutputTool(),
 		tools.NewJobKillTool(),
-		tools.NewDownloadTool(c.permissions, c.cfg.WorkingDir(), nil),
+		tools.NewDownloadTool(c.permissions, c.cfg.WorkingDir(), nil, c.cfg.Tools.Fetch),
 		tools.NewEditTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
 		tools.NewMultiEditTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
-		tools.NewFetchTool(c.permissions, c.cfg.WorkingDir(), nil),
+		tools.NewApplyPatchTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
+		tools.NewFetchTool(c.permissions, c.cfg.WorkingDir(), nil, c.cfg.Tools.Fetch),
 		tools.NewGlobTool(c.cfg.WorkingDir()),
 		tools.NewGrepTool(c.cfg.WorkingDir()),
 		tools.NewLsTool(c.permissions, c.cfg.WorkingDir(), c.cfg.Tools.Ls),
 		tools.NewSourcegraphTool(nil),
+		c.webSearchTool(),
 		tools.NewViewTool(c.lspClients, c.permissions, c.cfg.WorkingDir()),
 		tools.NewWriteTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
 	)
 
+	if index := c.copilotSemanticIndex(); index != nil {
+		allTools = append(allTools, tools.NewSemanticSearchTool(index, c.cfg.WorkingDir()))
+	}
+
 	if len(c.cfg.LSP) > 0 {
-		allTools = append(allTools, tools.NewDiagnosticsTool(c.lspClients), tools.NewReferencesTool(c.lspClients))
+		allTools = append(allTools, tools.NewDiagnosticsTool(c.lspClients, c.cfg.WorkingDir()), tools.NewReferencesTool(c.lspClients, c.cfg.WorkingDir()), tools.NewDefinitionTool(c.lspClients, c.cfg.WorkingDir()), tools.NewSymbolsTool(c.lspClients, c.cfg.WorkingDir()))
 	}
 
 	var filteredTools []fantasy.AgentTool
@@ -410,88 +607,102 @@ func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fan
 	return filteredTools, nil
 }
 
+// webSearchTool builds the web_search tool with the configured provider,
+// falling back to DuckDuckGo when it can't be used.
+func (c *coordinator) webSearchTool() fantasy.AgentTool {
+	cfg := c.cfg.Tools.WebSearch
+	provider := cfg.ProviderOrDefault()
+	apiKey := ""
+	if provider != config.SearchProviderDuckDuckGo {
+		var err error
+		if apiKey, err = c.cfg.Resolve(cfg.APIKeyOrDefault()); err != nil {
+			slog.Warn("Failed to resolve the API key of the search provider", "provider", provider, "error", err)
+		}
+	}
+	backend, err := tools.NewSearchBackend(provider, apiKey, nil)
+	if err != nil {
+		slog.Warn("Falling back to DuckDuckGo for web searches", "error", err)
+		backend, _ = tools.NewSearchBackend(config.SearchProviderDuckDuckGo, "", nil)
+	}
+	return tools.NewWebSearchTool(backend, cfg.MaxResultsOrDefault())
+}
+
 // TODO: when we support multiple agents we need to change this so that we pass in the agent specific model config
 func (c *coordinator) buildAgentModels(ctx context.Context) (Model, Model, error) {
-	largeModelCfg, ok := c.cfg.Models[config.SelectedModelTypeLarge]
+	largeModelCfg, ok := c.cfg.GetSelectedModel(config.SelectedModelTypeLarge)
 	if !ok {
 		return Model{}, Model{}, errors.New("large model not selected")
 	}
-	smallModelCfg, ok := c.cfg.Models[config.SelectedModelTypeSmall]
+	smallModelCfg, ok := c.cfg.GetSelectedModel(config.SelectedModelTypeSmall)
 	if !ok {
 		return Model{}, Model{}, errors.New("small model not selected")
 	}
 
-	largeProviderCfg, ok := c.cfg.Providers.Get(largeModelCfg.Provider)
-	if !ok {
-		return Model{}, Model{}, errors.New("large model provider not configured")
+	largeModel, err := c.buildModel(ctx, config.SelectedModelTypeLarge, largeModelCfg, largeModelCfg)
+	if err != nil {
+		return Model{}, Model{}, err
 	}
-
-	largeProvider, err := c.buildProvider(largeProviderCfg, largeModelCfg)
+	smallModel, err := c.buildModel(ctx, config.SelectedModelTypeSmall, smallModelCfg, largeModelCfg)
 	if err != nil {
 		return Model{}, Model{}, err
 	}
+	return largeModel, smallModel, nil
+}
 
-	smallProviderCfg, ok := c.cfg.Providers.Get(smallModelCfg.Provider)
+// buildModel builds the model of modelCfg, selected as modelType, with its
+// provider set up for the large model largeModelCfg.
+func (c *coordinator) buildModel(ctx context.Context, modelType config.SelectedModelType, modelCfg, largeModelCfg config.SelectedModel) (Model, error) {
+	providerCfg, ok := c.cfg.Providers.Get(modelCfg.Provider)
 	if !ok {
-		return Model{}, Model{}, errors.New("large model provider not configured")
+		return Model{}, fmt.Errorf("%s model provider not configured", modelType)
 	}
 
-	smallProvider, err := c.buildProvider(smallProviderCfg, largeModelCfg)
+	provider, err := c.buildProvider(providerCfg, largeModelCfg)
 	if err != nil {
-		return Model{}, Model{}, err
+		return Model{}, err
 	}
 
-	var largeCatwalkModel *catwalk.Model
-	var smallCatwalkModel *catwalk.Model
-
-	for _, m := range largeProviderCfg.Models {
-		if m.ID == largeModelCfg.Model {
-			largeCatwalkModel = &m
-		}
-	}
-	for _, m := range smallProviderCfg.Models {
-		if m.ID == smallModelCfg.Model {
-			smallCatwalkModel = &m
+	var catwalkModel *catwalk.Model
+	for _, m := range providerCfg.Models {
+		if m.ID == modelCfg.Model {
+			catwalkModel = &m
 		}
 	}
-
-	if largeCatwalkModel == nil {
-		return Model{}, Model{}, errors.New("large model not found in provider config")
-	}
-
-	if smallCatwalkModel == nil {
-		return Model{}, Model{}, errors.New("snall model not found in provider config")
+	if catwalkModel == nil {
+		return Model{}, fmt.Errorf("%s model not found in provider config", modelType)
 	}
 
-	largeModelID := largeModelCfg.Model
-	smallModelID := smallModelCfg.Model
-
-	if largeModelCfg.Provider == openrouter.Name && isExactoSupported(largeModelID) {
-		largeModelID += ":exacto"
+	modelID := modelCfg.Model
+	if modelCfg.Provider == openrouter.Name && isExactoSupported(modelID) {
+		modelID += ":exacto"
 	}
 
-	if smallModelCfg.Provider == openrouter.Name && isExactoSupported(smallModelID) {
-		smallModelID += ":exacto"
+	model, err := provider.LanguageModel(ctx, modelID)
+	if err != nil {
+		return Model{}, err
 	}
+	return Model{
+		Model:      model,
+		CatwalkCfg: *catwalkModel,
+		ModelCfg:   modelCfg,
+		Counter:    c.tokenCounter(providerCfg, modelID),
+	}, nil
+}
 
-	largeModel, err := largeProvider.LanguageModel(ctx, largeModelID)
-	if err != nil {
-		return Model{}, Model{}, err
+// tokenCounter returns the counter of the tokens sent to a model, for when
+// its provider doesn't report them: the token counting endpoint for the
+// Anthropic API, an estimate otherwise.
+func (c *coordinator) tokenCounter(providerCfg config.ProviderConfig, modelID string) tokens.Counter {
+	if providerCfg.Type != anthropic.Name {
+		return tokens.Estimator{}
 	}
-	smallModel, err := smallProvider.LanguageModel(ctx, smallModelID)
-	if err != nil {
-		return Model{}, Model{}, err
+	apiKey, _ := c.cfg.Resolve(providerCfg.APIKey)
+	if providerCfg.AuthHeader != "" {
+		// The key is already in its header.
+		apiKey = ""
 	}
-
-	return Model{
-			Model:      largeModel,
-			CatwalkCfg: *largeCatwalkModel,
-			ModelCfg:   largeModelCfg,
-		}, Model{
-			Model:      smallModel,
-			CatwalkCfg: *smallCatwalkModel,
-			ModelCfg:   smallModelCfg,
-		}, nil
+	baseURL, _ := c.cfg.Resolve(providerCfg.BaseURL)
+	return tokens.NewAnthropicCounter(baseURL, apiKey, modelID, providerCfg.ResolvedHeaders(c.cfg.Resolver()))
 }
 
 func (c *coordinator) buildAnthropicProvider(baseURL, apiKey string, headers map[string]string) (fantasy.Provider, error) {
@@ -555,7 +766,7 @@ func (c *coordinator) buildOpenrouterProvider(_, apiKey string, headers map[stri
 	return openrouter.New(opts...)
 }
 
-func (c *coordinator) buildOpenaiCompatProvider(baseURL, apiKey string, headers map[string]string, extraBody map[string]any) (fantasy.Provider, error) {
+func (c *coordinator) buildOpenaiCompatProvider(baseURL, apiKey string, headers map[string]string, extraBody map[string]any, omitToolChoice bool) (fantasy.Provider, error) {
 	opts := []openaicompat.Option{
 		openaicompat.WithBaseURL(baseURL),
 		openaicompat.WithAPIKey(apiKey),
@@ -571,26 +782,37 @@ func (c *coordinator) buildOpenaiCompatProvider(baseURL, apiKey string, headers
 	for extraKey, extraValue := range extraBody {
 		opts = append(opts, openaicompat.WithSDKOptions(openaisdk.WithJSONSet(extraKey, extraValue)))
 	}
+	if omitToolChoice {
+		opts = append(opts, openaicompat.WithSDKOptions(openaisdk.WithJSONDel("tool_choice")))
+	}
 
 	return openaicompat.New(opts...)
 }
 
-func (c *coordinator) buildAzureProvider(baseURL, apiKey string, headers map[string]string, options map[string]string) (fantasy.Provider, error) {
+func (c *coordinator) buildAzureProvider(baseURL, apiKey string, headers map[string]string, options map[string]string, azureOpts *config.AzureOptions) (fantasy.Provider, error) {
 	opts := []azure.Option{
 		azure.WithBaseURL(baseURL),
 		azure.WithAPIKey(apiKey),
 		azure.WithUseResponsesAPI(),
 	}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, azure.WithHTTPClient(httpClient))
+	// The v1 API the provider uses takes no api-version, set it only when
+	// configured.
+	transport := &azureTransport{
+		transport:  http.DefaultTransport,
+		apiVersion: options["apiVersion"],
 	}
-	if options == nil {
-		options = make(map[string]string)
+	if azureOpts.UsesEntraID() {
+		credential, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find microsoft entra id credentials: %w", err)
+		}
+		transport.credential = credential
 	}
-	if apiVersion, ok := options["apiVersion"]; ok {
-		opts = append(opts, azure.WithAPIVersion(apiVersion))
+	var roundTripper http.RoundTripper = transport
+	if c.cfg.Options.Debug {
+		roundTripper = &log.HTTPRoundTripLogger{Transport: transport}
 	}
+	opts = append(opts, azure.WithHTTPClient(&http.Client{Transport: roundTripper}))
 	if len(headers) > 0 {
 		opts = append(opts, azure.WithHeaders(headers))
 	}
@@ -598,20 +820,50 @@ func (c *coordinator) buildAzureProvider(baseURL, apiKey string, headers map[str
 	return azure.New(opts...)
 }
 
-func (c *coordinator) buildBedrockProvider(headers map[string]string) (fantasy.Provider, error) {
-	var opts []bedrock.Option
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, bedrock.WithHTTPClient(httpClient))
+func (c *coordinator) buildBedrockProvider(providerCfg config.ProviderConfig, headers map[string]string) (fantasy.Provider, error) {
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if region := providerCfg.ExtraParams["region"]; region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(region))
 	}
-	if len(headers) > 0 {
-		opts = append(opts, bedrock.WithHeaders(headers))
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, errors.New("no aws region configured, set AWS_REGION or the region of your profile")
 	}
+
+	// The transport signs the requests, the provider is left to set a
+	// Bedrock API key only.
 	bearerToken := os.Getenv("AWS_BEARER_TOKEN_BEDROCK")
+	opts := []bedrock.Option{bedrock.WithSkipAuth(bearerToken == "")}
 	if bearerToken != "" {
 		opts = append(opts, bedrock.WithAPIKey(bearerToken))
 	}
-	return bedrock.New(opts...)
+	models := make([]string, 0, len(providerCfg.Models))
+	for _, m := range providerCfg.Models {
+		models = append(models, m.ID)
+	}
+	var transport http.RoundTripper = newBedrockTransport(http.DefaultTransport, awsCfg, models, bearerToken != "")
+	if c.cfg.Options.Debug {
+		transport = &log.HTTPRoundTripLogger{Transport: transport}
+	}
+	client := &http.Client{Transport: transport}
+	opts = append(opts, bedrock.WithHTTPClient(client))
+	if len(headers) > 0 {
+		opts = append(opts, bedrock.WithHeaders(headers))
+	}
+	provider, err := bedrock.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &bedrockProvider{
+		Provider: provider,
+		client:   client,
+		region:   awsCfg.Region,
+		apiKey:   bearerToken,
+		headers:  headers,
+	}, nil
 }
 
 func (c *coordinator) buildGoogleProvider(baseURL, apiKey string, headers map[string]string) (fantasy.Provider, error) {
@@ -648,6 +900,23 @@ func (c *coordinator) buildGoogleVertexProvider(headers map[string]string, optio
 }
 
 func (c *coordinator) buildCopilotProvider(providerCfg config.ProviderConfig) (fantasy.Provider, error) {
+	httpClient := &http.Client{
+		Transport: c.copilotTransport(providerCfg),
+		Timeout:   5 * time.Minute,
+	}
+
+	opts := []openaicompat.Option{
+		openaicompat.WithBaseURL(copilot.CopilotAPIBaseURL),
+		openaicompat.WithAPIKey("placeholder"), // Not used - transport handles auth.
+		openaicompat.WithHTTPClient(httpClient),
+	}
+
+	return openaicompat.New(opts...)
+}
+
+// copilotTransport returns the transport authenticating the requests to the
+// Copilot API with the token of the provider, refreshing it as needed.
+func (c *coordinator) copilotTransport(providerCfg config.ProviderConfig) *copilot.Transport {
 	// Token provider - returns the current OAuth token from config.
 	tokenProvider := func() (*oauth.Token, error) {
 		cfg, ok := c.cfg.Providers.Get(providerCfg.ID)
@@ -668,10 +937,7 @@ func (c *coordinator) buildCopilotProvider(providerCfg config.ProviderConfig) (f
 		c.cfg.Providers.Set(providerCfg.ID, cfg)
 
 		// Persist to config file.
-		return c.cfg.SetConfigField(
-			fmt.Sprintf("providers.%s.oauth", providerCfg.ID),
-			token,
-		)
+		return c.cfg.SetProviderToken(oauth.TokenKey{ProviderID: providerCfg.ID}, token)
 	}
 
 	// Create custom transport that handles token management.
@@ -681,19 +947,114 @@ func (c *coordinator) buildCopilotProvider(providerCfg config.ProviderConfig) (f
 		// Wrap the debug transport if debugging is enabled.
 		transport.SetBaseTransport(log.NewHTTPClient().Transport)
 	}
+	return transport
+}
 
-	httpClient := &http.Client{
-		Transport: transport,
-		Timeout:   5 * time.Minute,
+// copilotSemanticIndex returns the semantic index of the project, embedded with
+// Copilot, or nil when no Copilot account is signed in. It's built once, for
+// the files embedded to be kept across the tools rebuilt.
+func (c *coordinator) copilotSemanticIndex() *semindex.Index {
+	c.semanticIndexMu.Lock()
+	defer c.semanticIndexMu.Unlock()
+	if c.semanticIndex != nil {
+		return c.semanticIndex
+	}
+	providerCfg, ok := c.cfg.Providers.Get(copilot.ProviderID)
+	if !ok || providerCfg.Disable || providerCfg.OAuthToken == nil {
+		return nil
+	}
+	embedder := copilot.NewEmbeddingsClient(c.copilotTransport(providerCfg))
+	c.semanticIndex = semindex.New(c.cfg.WorkingDir(), embedder)
+	return c.semanticIndex
+}
+
+// pinSessionAccount records the provider account a session is created with,
+// and switches back to it when the session is resumed under another account.
+// It reports whether the active account was switched.
+func (c *coordinator) pinSessionAccount(ctx context.Context, sessionID string, providerCfg config.ProviderConfig) bool {
+	if providerCfg.ActiveAccount == "" {
+		return false
+	}
+	sess, err := c.sessions.Get(ctx, sessionID)
+	if err != nil {
+		slog.Warn("Failed to get session for account pinning", "session", sessionID, "error", err)
+		return false
 	}
 
-	opts := []openaicompat.Option{
-		openaicompat.WithBaseURL(copilot.CopilotAPIBaseURL),
-		openaicompat.WithAPIKey("placeholder"), // Not used - transport handles auth.
-		openaicompat.WithHTTPClient(httpClient),
+	switch {
+	case sess.Account == "":
+		sess.AccountProvider = providerCfg.ID
+		sess.Account = providerCfg.ActiveAccount
+		if _, err := c.sessions.Save(ctx, sess); err != nil {
+			slog.Warn("Failed to pin session account", "session", sessionID, "error", err)
+		}
+		return false
+	case sess.AccountProvider != providerCfg.ID || sess.Account == providerCfg.ActiveAccount:
+		return false
 	}
 
-	return openaicompat.New(opts...)
+	if _, ok := providerCfg.Account(sess.Account); !ok {
+		slog.Warn("Session account no longer exists, using active account", "session", sessionID, "account", sess.Account)
+		return false
+	}
+	slog.Info("Switching to session account", "provider", providerCfg.ID, "account", sess.Account)
+	if err := c.cfg.SwitchProviderAccount(providerCfg.ID, sess.Account); err != nil {
+		slog.Error("Failed to switch to session account", "error", err)
+		return false
+	}
+	return true
+}
+
+// copilotContentExclusions fetches the Copilot content exclusion rules for
+// the current repository and caches them for the coordinator lifetime once
+// fetched. When they can't be fetched, the run fails rather than going on
+// without them, and they're fetched again on the next run.
+func (c *coordinator) copilotContentExclusions(ctx context.Context, providerCfg config.ProviderConfig) (*copilot.ContentExclusions, error) {
+	c.contentExclusionsMu.Lock()
+	defer c.contentExclusionsMu.Unlock()
+	if c.contentExclusions != nil {
+		return c.contentExclusions, nil
+	}
+	if providerCfg.OAuthToken == nil || providerCfg.OAuthToken.RefreshToken == "" {
+		return nil, nil
+	}
+
+	var repos []string
+	if remote, err := git.Run(ctx, c.cfg.WorkingDir(), "remote", "get-url", "origin"); err == nil {
+		if repo := copilot.RepoFromRemoteURL(remote); repo != "" {
+			repos = append(repos, repo)
+		}
+	}
+
+	exclusions, err := copilot.FetchContentExclusions(ctx, providerCfg.OAuthToken.RefreshToken, repos)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrContentExclusionsUnavailable, err)
+	}
+	slog.Info("Loaded Copilot content exclusions", "patterns", len(exclusions.Patterns()))
+	c.contentExclusions = exclusions
+	return exclusions, nil
+}
+
+// excludeFromSystemPrompt builds the system prompt of the coder agent and the
+// map of the repository again, once, leaving out the files excluded by
+// exclusions, the content excluder of ctx.
+func (c *coordinator) excludeFromSystemPrompt(ctx context.Context, exclusions *copilot.ContentExclusions) error {
+	c.contentExclusionsMu.Lock()
+	defer c.contentExclusionsMu.Unlock()
+	if c.promptExcluded || len(exclusions.Patterns()) == 0 {
+		return nil
+	}
+	if c.repoMap != nil {
+		c.repoMap.SetExclude(exclusions.IsExcluded)
+	}
+	large := c.currentAgent.Model()
+	systemPrompt, err := c.coderPrompt.Build(ctx, large.Model.Provider(), large.Model.Model(), *c.cfg)
+	if err != nil {
+		return err
+	}
+	c.currentAgent.SetSystemPrompt(systemPrompt)
+	c.promptExcluded = true
+	return nil
 }
 
 func (c *coordinator) isAnthropicThinking(model config.SelectedModel) bool {
@@ -716,10 +1077,7 @@ func (c *coordinator) isAnthropicThinking(model config.SelectedModel) bool {
 }
 
 func (c *coordinator) buildProvider(providerCfg config.ProviderConfig, model config.SelectedModel) (fantasy.Provider, error) {
-	headers := maps.Clone(providerCfg.ExtraHeaders)
-	if headers == nil {
-		headers = make(map[string]string)
-	}
+	headers := providerCfg.ResolvedHeaders(c.cfg.Resolver())
 
 	// handle special headers for anthropic
 	if providerCfg.Type == anthropic.Name && c.isAnthropicThinking(model) {
@@ -731,6 +1089,10 @@ func (c *coordinator) buildProvider(providerCfg config.ProviderConfig, model con
 	}
 
 	apiKey, _ := c.cfg.Resolve(providerCfg.APIKey)
+	if providerCfg.AuthHeader != "" {
+		// The key is already in its header.
+		apiKey = ""
+	}
 	baseURL, _ := c.cfg.Resolve(providerCfg.BaseURL)
 
 	switch providerCfg.Type {
@@ -741,15 +1103,15 @@ func (c *coordinator) buildProvider(providerCfg config.ProviderConfig, model con
 	case openrouter.Name:
 		return c.buildOpenrouterProvider(baseURL, apiKey, headers)
 	case azure.Name:
-		return c.buildAzureProvider(baseURL, apiKey, headers, providerCfg.ExtraParams)
+		return c.buildAzureProvider(baseURL, apiKey, headers, providerCfg.ExtraParams, providerCfg.Azure)
 	case bedrock.Name:
-		return c.buildBedrockProvider(headers)
+		return c.buildBedrockProvider(providerCfg, headers)
 	case google.Name:
 		return c.buildGoogleProvider(baseURL, apiKey, headers)
 	case "google-vertex":
 		return c.buildGoogleVertexProvider(headers, providerCfg.ExtraParams)
 	case openaicompat.Name:
-		return c.buildOpenaiCompatProvider(baseURL, apiKey, headers, providerCfg.ExtraBody)
+		return c.buildOpenaiCompatProvider(baseURL, apiKey, headers, providerCfg.ExtraBody, providerCfg.OmitToolChoice)
 	case "github-copilot":
 		return c.buildCopilotProvider(providerCfg)
 	default:
@@ -774,6 +1136,15 @@ func (c *coordinator) Cancel(sessionID string) {
 
 func (c *coordinator) CancelAll() {
 	c.currentAgent.CancelAll()
+	c.background.cancelAll()
+}
+
+func (c *coordinator) BackgroundTasks() []BackgroundTask {
+	return c.background.list()
+}
+
+func (c *coordinator) CancelBackgroundTask(id string) bool {
+	return c.background.cancel(id)
 }
 
 func (c *coordinator) ClearQueue(sessionID string) {
@@ -824,3 +1195,7 @@ func (c *coordinator) Summarize(ctx context.Context, sessionID string) error {
 	}
 	return c.currentAgent.Summarize(ctx, sessionID, getProviderOptions(c.currentAgent.Model(), providerCfg))
 }
+
+func (c *coordinator) GenerateCommitMessage(ctx context.Context, diff string) (string, error) {
+	return c.currentAgent.GenerateCommitMessage(ctx, diff)
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProviderOptionsThinkingBudget(t *testing.T) {
+	t.Parallel()
+
+	anthropicBudget := func(t *testing.T, providerType catwalk.Type, modelID string, modelCfg config.SelectedModel) int64 {
+		t.Helper()
+		options := getProviderOptions(
+			Model{CatwalkCfg: catwalk.Model{ID: modelID}, ModelCfg: modelCfg},
+			config.ProviderConfig{Type: providerType},
+		)
+		parsed, ok := options[anthropic.Name].(*anthropic.ProviderOptions)
+		require.True(t, ok, "anthropic options should be set")
+		if parsed.Thinking == nil {
+			return 0
+		}
+		return parsed.Thinking.BudgetTokens
+	}
+
+	require.Equal(t, int64(8000), anthropicBudget(t, catwalk.TypeAnthropic, "claude-sonnet-4", config.SelectedModel{Think: true, ThinkingBudget: 8000}))
+	require.Equal(t, int64(config.DefaultThinkingBudget), anthropicBudget(t, catwalk.TypeBedrock, "anthropic.claude-sonnet-4", config.SelectedModel{Think: true}))
+	require.Equal(t, int64(4096), anthropicBudget(t, catwalk.TypeVertexAI, "claude-sonnet-4@20250514", config.SelectedModel{Think: true, ThinkingBudget: 4096}))
+	require.Zero(t, anthropicBudget(t, catwalk.TypeAnthropic, "claude-sonnet-4", config.SelectedModel{ThinkingBudget: 8000}))
+
+	options := getProviderOptions(
+		Model{CatwalkCfg: catwalk.Model{ID: "gemini-2.5-pro"}, ModelCfg: config.SelectedModel{ThinkingBudget: 16000}},
+		config.ProviderConfig{Type: catwalk.TypeVertexAI},
+	)
+	parsed, ok := options[google.Name].(*google.ProviderOptions)
+	require.True(t, ok, "google options should be set")
+	require.Equal(t, int64(16000), *parsed.ThinkingConfig.ThinkingBudget)
+}
+
+func TestMergeCallOptionsSampling(t *testing.T) {
+	t.Parallel()
+
+	providerCfg := config.ProviderConfig{
+		ID:   "openai",
+		Type: catwalk.TypeOpenAI,
+		ModelOptions: map[string]config.SamplingOptions{
+			"gpt-4o": {Temperature: ptr(0.2), TopP: ptr(0.8), PresencePenalty: ptr(0.5)},
+		},
+	}
+	model := Model{
+		CatwalkCfg: catwalk.Model{ID: "gpt-4o", Options: catwalk.ModelOptions{Temperature: ptr(1.0), FrequencyPenalty: ptr(0.1)}},
+		ModelCfg:   config.SelectedModel{TopP: ptr(0.9)},
+	}
+	_, temp, topP, _, freqPenalty, presPenalty := mergeCallOptions(model, providerCfg)
+	require.Equal(t, 0.2, *temp)
+	require.Equal(t, 0.9, *topP, "the selected model takes precedence")
+	require.Equal(t, 0.1, *freqPenalty, "the catwalk defaults apply last")
+	require.Equal(t, 0.5, *presPenalty)
+
+	model.CatwalkCfg.ID = "gpt-4.1"
+	_, temp, _, _, _, _ = mergeCallOptions(model, providerCfg)
+	require.Equal(t, 1.0, *temp)
+
+	providerCfg.ModelOptions["gpt-4.1"] = config.SamplingOptions{NoTemperature: true}
+	_, temp, _, _, _, _ = mergeCallOptions(model, providerCfg)
+	require.Nil(t, temp, "models rejecting a temperature are never sent one")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestCopilotContentExclusionsFailClosed(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{}
+	cfg.SetWorkingDir(t.TempDir())
+	c := &coordinator{cfg: cfg}
+	providerCfg := config.ProviderConfig{ID: copilot.ProviderID, OAuthToken: &oauth.Token{RefreshToken: "gho_token"}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	exclusions, err := c.copilotContentExclusions(ctx, providerCfg)
+	require.ErrorIs(t, err, ErrContentExclusionsUnavailable)
+	require.Nil(t, exclusions)
+	require.Nil(t, c.contentExclusions, "failures are fetched again on the next run")
+
+	exclusions, err = c.copilotContentExclusions(ctx, config.ProviderConfig{ID: copilot.ProviderID})
+	require.NoError(t, err, "providers without OAuth have no exclusions")
+	require.Nil(t, exclusions)
+}
@@ -6,10 +6,15 @@ import (
 )
 
 var (
-	ErrRequestCancelled = errors.New("request canceled by user")
-	ErrSessionBusy      = errors.New("session is currently processing another request")
-	ErrEmptyPrompt      = errors.New("prompt is empty")
-	ErrSessionMissing   = errors.New("session id is missing")
+	ErrRequestCancelled             = errors.New("request canceled by user")
+	ErrSessionBusy                  = errors.New("session is currently processing another request")
+	ErrEmptyPrompt                  = errors.New("prompt is empty")
+	ErrSessionMissing               = errors.New("session id is missing")
+	ErrEmptyDiff                    = errors.New("diff is empty")
+	ErrBudgetExceeded               = errors.New("budget exceeded")
+	ErrImagesNotSupported           = errors.New("the model doesn't support images, remove the image attachments or switch to a model that does")
+	ErrAttachmentExcluded           = errors.New("the attachment is excluded by the content exclusion policy")
+	ErrContentExclusionsUnavailable = errors.New("the Copilot content exclusion policy couldn't be loaded, try again")
 )
 
 func isCancelledErr(err error) bool {
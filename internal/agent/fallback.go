@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+)
+
+// fallbackPrompt asks the fallback model to go on with a response the failed
+// model had started.
+const fallbackPrompt = "Your previous response was cut off by an error of the provider. Continue it where it stopped, without repeating what you already did."
+
+// isFallbackErr reports whether err is the provider of the model refusing
+// the request or being unavailable, rather than the request being wrong:
+// its auth, quota or availability errors.
+func isFallbackErr(err error) bool {
+	if err == nil || isCancelledErr(err) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if copilot.RequiresReauth(err) || isStreamInterrupted(err) {
+		return true
+	}
+	var providerErr *fantasy.ProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden,
+			http.StatusNotFound, http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return providerErr.StatusCode >= http.StatusInternalServerError
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr)
+}
+
+// nextFallback returns the fallback model to switch to from the models
+// tried: the first one configured after the last tried, with its provider
+// enabled, that wasn't tried yet.
+func (c *coordinator) nextFallback(tried []config.SelectedModel) (config.SelectedModel, bool) {
+	isTried := func(m config.SelectedModel) bool {
+		for _, t := range tried {
+			if t.Provider == m.Provider && t.Model == m.Model {
+				return true
+			}
+		}
+		return false
+	}
+
+	fallbacks := c.cfg.Options.FallbackModels
+	last := tried[len(tried)-1]
+	start := 0
+	for i, m := range fallbacks {
+		if m.Provider == last.Provider && m.Model == last.Model {
+			start = i + 1
+			break
+		}
+	}
+	for _, m := range fallbacks[start:] {
+		if isTried(m) {
+			continue
+		}
+		providerCfg, ok := c.cfg.Providers.Get(m.Provider)
+		if !ok || providerCfg.Disable || c.cfg.GetModel(m.Provider, m.Model) == nil {
+			slog.Warn("Skipping fallback model that isn't available", "provider", m.Provider, "model", m.Model)
+			continue
+		}
+		return m, true
+	}
+	return config.SelectedModel{}, false
+}
+
+// fallBack returns the next fallback model to send the prompt to after the
+// models tried failed with err, and notes the switch on the failed response
+// of the session. The large model of the agent stays as it is, for the other
+// sessions and the next prompts.
+func (c *coordinator) fallBack(ctx context.Context, sessionID string, tried []config.SelectedModel, err error) (Model, bool) {
+	failed := tried[len(tried)-1]
+	for {
+		fallback, ok := c.nextFallback(tried)
+		if !ok {
+			return Model{}, false
+		}
+		tried = append(tried, fallback)
+
+		model, buildErr := c.buildModel(ctx, config.SelectedModelTypeLarge, fallback, fallback)
+		if buildErr != nil {
+			slog.Error("Failed to build fallback model", "provider", fallback.Provider, "model", fallback.Model, "error", buildErr)
+			continue
+		}
+		slog.Warn("Model failed, switching to fallback model", "provider", failed.Provider, "model", failed.Model, "fallback_provider", fallback.Provider, "fallback_model", fallback.Model, "error", err)
+		c.noteFallback(ctx, sessionID, model)
+		return model, true
+	}
+}
+
+// noteFallback adds the switch to the fallback model to the details of the
+// last response of the session, when it failed.
+func (c *coordinator) noteFallback(ctx context.Context, sessionID string, fallback Model) {
+	msgs, err := c.messages.List(ctx, sessionID)
+	if err != nil || len(msgs) == 0 {
+		return
+	}
+	last := msgs[len(msgs)-1]
+	finish := last.FinishPart()
+	if last.Role != message.Assistant || finish == nil || finish.Reason != message.FinishReasonError {
+		return
+	}
+
+	name := cmp.Or(fallback.CatwalkCfg.Name, fallback.ModelCfg.Model)
+	note := fmt.Sprintf("Switched to %s (%s).", name, fallback.ModelCfg.Provider)
+	last.AddFinish(finish.Reason, finish.Message, strings.TrimSpace(finish.Details+"\n\n"+note))
+	if err := c.messages.Update(ctx, last); err != nil {
+		slog.Warn("Failed to note the switch to the fallback model", "session", sessionID, "error", err)
+	}
+}
+
+// retriedCall returns the history and the call to send a prompt again
+// with a fallback model, and whether the prompt is in the session already:
+// it isn't when it failed before reaching the provider. A response that
+// failed before writing anything is left out of the history, with its
+// prompt sent again; the fallback model is asked to go on with one that did.
+func retriedCall(msgs []message.Message, call SessionAgentCall) ([]message.Message, SessionAgentCall, bool) {
+	if len(msgs) == 0 {
+		return msgs, call, false
+	}
+	if last := msgs[len(msgs)-1]; last.Role != message.Assistant || last.FinishReason() != message.FinishReasonError {
+		return msgs, call, false
+	}
+
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role != message.User {
+			continue
+		}
+		if msgs[i].Content().Text != call.Prompt {
+			return msgs, call, false
+		}
+		for _, m := range msgs[i+1:] {
+			if m.Role != message.Assistant || m.Content().Text != "" || len(m.ToolCalls()) > 0 || m.ReasoningContent().String() != "" {
+				call.Prompt = fallbackPrompt
+				call.Attachments = nil
+				return msgs, call, true
+			}
+		}
+		return msgs[:i], call, true
+	}
+	return msgs, call, false
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsFallbackErr(t *testing.T) {
+	t.Parallel()
+
+	for _, err := range []error{
+		&fantasy.ProviderError{Title: "unauthorized", StatusCode: 401},
+		&fantasy.ProviderError{Title: "insufficient quota", StatusCode: 429},
+		&fantasy.RetryError{Errors: []error{&fantasy.ProviderError{Title: "overloaded", StatusCode: 529}}},
+		&fantasy.ProviderError{Title: "model not found", StatusCode: 404},
+		fmt.Errorf("refreshing token: %w", copilot.ErrAuthenticationFailed),
+		&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+		io.ErrUnexpectedEOF,
+	} {
+		require.True(t, isFallbackErr(err), err.Error())
+	}
+	for _, err := range []error{
+		nil,
+		context.Canceled,
+		ErrRequestCancelled,
+		&fantasy.ProviderError{Title: "bad request", Message: "prompt is too long", StatusCode: 400},
+		ErrImagesNotSupported,
+	} {
+		require.False(t, isFallbackErr(err))
+	}
+}
+
+func TestNextFallback(t *testing.T) {
+	t.Parallel()
+
+	provider := func(id string, disable bool, models ...string) config.ProviderConfig {
+		cfg := config.ProviderConfig{ID: id, Disable: disable}
+		for _, m := range models {
+			cfg.Models = append(cfg.Models, catwalk.Model{ID: m, Name: m})
+		}
+		return cfg
+	}
+	c := &coordinator{cfg: &config.Config{
+		Options: &config.Options{FallbackModels: []config.SelectedModel{
+			{Provider: "copilot", Model: "gpt-4.1"},
+			{Provider: "azure", Model: "gpt-4.1"},
+			{Provider: "openrouter", Model: "gone"},
+			{Provider: "openrouter", Model: "gpt-4.1"},
+			{Provider: "copilot", Model: "gpt-4.1"},
+			{Provider: "ollama", Model: "qwen3"},
+		}},
+		Providers: csync.NewMapFrom(map[string]config.ProviderConfig{
+			"copilot":    provider("copilot", false, "gpt-4.1", "claude-sonnet-4"),
+			"azure":      provider("azure", true, "gpt-4.1"),
+			"openrouter": provider("openrouter", false, "gpt-4.1"),
+			"ollama":     provider("ollama", false, "qwen3"),
+		}),
+	}}
+
+	next := func(tried ...config.SelectedModel) string {
+		m, ok := c.nextFallback(tried)
+		if !ok {
+			return ""
+		}
+		return m.Provider + "/" + m.Model
+	}
+	copilotSonnet := config.SelectedModel{Provider: "copilot", Model: "claude-sonnet-4"}
+	copilotGPT := config.SelectedModel{Provider: "copilot", Model: "gpt-4.1"}
+	openrouterGPT := config.SelectedModel{Provider: "openrouter", Model: "gpt-4.1"}
+	ollamaQwen := config.SelectedModel{Provider: "ollama", Model: "qwen3"}
+
+	require.Equal(t, "copilot/gpt-4.1", next(copilotSonnet), "models out of the chain start it")
+	require.Equal(t, "openrouter/gpt-4.1", next(copilotGPT), "disabled providers and unknown models are skipped")
+	require.Equal(t, "ollama/qwen3", next(copilotGPT, openrouterGPT), "tried models are skipped")
+	require.Empty(t, next(copilotGPT, openrouterGPT, ollamaQwen))
+
+	c.cfg.Options.FallbackModels = nil
+	require.Empty(t, next(copilotGPT))
+}
+
+func TestNoteFallback(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	c := &coordinator{messages: env.messages}
+	sess, err := env.sessions.Create(t.Context(), "Session")
+	require.NoError(t, err)
+	failed, err := env.messages.Create(t.Context(), sess.ID, message.CreateMessageParams{Role: message.Assistant})
+	require.NoError(t, err)
+	failed.AddFinish(message.FinishReasonError, "Rate limit exceeded", "You exceeded your quota.")
+	require.NoError(t, env.messages.Update(t.Context(), failed))
+
+	c.noteFallback(t.Context(), sess.ID, Model{
+		CatwalkCfg: catwalk.Model{ID: "openai/gpt-4.1", Name: "GPT-4.1"},
+		ModelCfg:   config.SelectedModel{Provider: "openrouter", Model: "openai/gpt-4.1"},
+	})
+	got, err := env.messages.Get(t.Context(), failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, message.FinishReasonError, got.FinishReason())
+	require.Equal(t, "Rate limit exceeded", got.FinishPart().Message)
+	require.Equal(t, "You exceeded your quota.\n\nSwitched to GPT-4.1 (openrouter).", got.FinishPart().Details)
+}
+
+func TestRetriedCall(t *testing.T) {
+	t.Parallel()
+
+	user := func(text string) message.Message {
+		return message.Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: text}}}
+	}
+	response := func(text string, reason message.FinishReason) message.Message {
+		msg := message.Message{Role: message.Assistant}
+		if text != "" {
+			msg.Parts = append(msg.Parts, message.TextContent{Text: text})
+		}
+		msg.AddFinish(reason, "", "")
+		return msg
+	}
+	attachments := []message.Attachment{{FileName: "main.go", MimeType: "text/plain", Content: []byte("package main")}}
+	call := SessionAgentCall{Prompt: "Fix the bug", Attachments: attachments, retry: true}
+
+	// A response that failed right away is left out, its prompt sent again.
+	msgs := []message.Message{
+		user("Hi"), response("Hello", message.FinishReasonEndTurn),
+		user("Fix the bug"), response("", message.FinishReasonError), response("", message.FinishReasonError),
+	}
+	history, retried, stored := retriedCall(msgs, call)
+	require.True(t, stored)
+	require.Equal(t, msgs[:2], history)
+	require.Equal(t, "Fix the bug", retried.Prompt)
+	require.Equal(t, attachments, retried.Attachments)
+
+	// A response that wrote something is gone on with.
+	msgs = []message.Message{user("Fix the bug"), response("Let me look", message.FinishReasonError)}
+	history, retried, stored = retriedCall(msgs, call)
+	require.True(t, stored)
+	require.Equal(t, msgs, history)
+	require.Equal(t, fallbackPrompt, retried.Prompt)
+	require.Empty(t, retried.Attachments)
+
+	// A prompt that failed before being sent is sent as usual.
+	msgs = []message.Message{user("Hi"), response("Hello", message.FinishReasonEndTurn)}
+	history, retried, stored = retriedCall(msgs, call)
+	require.False(t, stored)
+	require.Equal(t, msgs, history)
+	require.Equal(t, call, retried)
+
+	msgs = []message.Message{user("Hi"), response("", message.FinishReasonError)}
+	_, _, stored = retriedCall(msgs, call)
+	require.False(t, stored)
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+)
+
+// maxParallelToolCalls bounds how many tool calls of a step run at once.
+const maxParallelToolCalls = 4
+
+// parallelSafeTools are the tools without side effects, whose calls can run
+// concurrently with each other.
+var parallelSafeTools = map[string]bool{
+	tools.ViewToolName:           true,
+	tools.LSToolName:             true,
+	tools.GlobToolName:           true,
+	tools.GrepToolName:           true,
+	tools.SourcegraphToolName:    true,
+	tools.WebSearchToolName:      true,
+	tools.FetchToolName:          true,
+	tools.WebFetchToolName:       true,
+	tools.DiagnosticsToolName:    true,
+	tools.ReferencesToolName:     true,
+	tools.DefinitionToolName:     true,
+	tools.SymbolsToolName:        true,
+	tools.SemanticSearchToolName: true,
+	AgentOutputToolName:          true,
+}
+
+// toolPrefetcher runs the independent tool calls of a step concurrently.
+//
+// Tools are executed one by one once the model finishes streaming a step, so
+// instead, each call of a parallel safe tool is started in the background as
+// soon as it's streamed. When its turn comes, the wrapped tool returns the
+// result of that run. Calls following one with side effects in the same step
+// aren't started early, as they might depend on it.
+type toolPrefetcher struct {
+	sem chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]*prefetchedCall
+	blocked bool
+}
+
+type prefetchedCall struct {
+	input string
+	done  chan struct{}
+	resp  fantasy.ToolResponse
+	err   error
+}
+
+func newToolPrefetcher(workers int) *toolPrefetcher {
+	return &toolPrefetcher{
+		sem:     make(chan struct{}, workers),
+		pending: make(map[string]*prefetchedCall),
+	}
+}
+
+// wrap returns the tools, with the parallel safe ones returning the results
+// of their prefetched calls.
+func (p *toolPrefetcher) wrap(agentTools []fantasy.AgentTool) []fantasy.AgentTool {
+	wrapped := make([]fantasy.AgentTool, len(agentTools))
+	for i, tool := range agentTools {
+		if parallelSafeTools[tool.Info().Name] {
+			tool = &prefetchingTool{AgentTool: tool, prefetcher: p}
+		}
+		wrapped[i] = tool
+	}
+	return wrapped
+}
+
+// startStep forgets about the calls of the previous step.
+func (p *toolPrefetcher) startStep() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.pending)
+	p.blocked = false
+}
+
+// prefetch starts running a streamed tool call in the background, when it's
+// safe to do so.
+func (p *toolPrefetcher) prefetch(ctx context.Context, agentTools []fantasy.AgentTool, tc fantasy.ToolCallContent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.blocked || tc.Invalid || tc.ProviderExecuted {
+		return
+	}
+	if !parallelSafeTools[tc.ToolName] {
+		// Later calls may depend on its side effects.
+		p.blocked = true
+		return
+	}
+
+	var tool fantasy.AgentTool
+	for _, t := range agentTools {
+		if t.Info().Name == tc.ToolName {
+			tool = t
+			break
+		}
+	}
+	if tool == nil {
+		return
+	}
+	if pt, ok := tool.(*prefetchingTool); ok {
+		tool = pt.AgentTool
+	}
+
+	call := &prefetchedCall{input: tc.Input, done: make(chan struct{})}
+	p.pending[tc.ToolCallID] = call
+	go func() {
+		defer close(call.done)
+		select {
+		case p.sem <- struct{}{}:
+			defer func() { <-p.sem }()
+		case <-ctx.Done():
+			call.err = ctx.Err()
+			return
+		}
+		call.resp, call.err = tool.Run(ctx, fantasy.ToolCall{
+			ID:    tc.ToolCallID,
+			Name:  tc.ToolName,
+			Input: tc.Input,
+		})
+	}()
+}
+
+// take returns the prefetched run of a call, if any.
+func (p *toolPrefetcher) take(call fantasy.ToolCall) (*prefetchedCall, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc, ok := p.pending[call.ID]
+	if !ok || pc.input != call.Input {
+		return nil, false
+	}
+	delete(p.pending, call.ID)
+	return pc, true
+}
+
+// prefetchingTool returns the result of a prefetched call when there is one,
+// and runs the tool otherwise.
+type prefetchingTool struct {
+	fantasy.AgentTool
+	prefetcher *toolPrefetcher
+}
+
+func (t *prefetchingTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	pc, ok := t.prefetcher.take(call)
+	if !ok {
+		return t.AgentTool.Run(ctx, call)
+	}
+	select {
+	case <-pc.done:
+		return pc.resp, pc.err
+	case <-ctx.Done():
+		return fantasy.ToolResponse{}, ctx.Err()
+	}
+}
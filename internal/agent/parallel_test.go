@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeToolInput struct {
+	Path string `json:"path"`
+}
+
+func TestToolPrefetcher(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning, runs atomic.Int32
+	release := make(chan struct{})
+	newTool := func(name string) fantasy.AgentTool {
+		return fantasy.NewAgentTool(name, name, func(ctx context.Context, input fakeToolInput, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			runs.Add(1)
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			<-release
+			return fantasy.NewTextResponse(name + ":" + input.Path), nil
+		})
+	}
+
+	p := newToolPrefetcher(2)
+	agentTools := p.wrap([]fantasy.AgentTool{
+		newTool(tools.ViewToolName),
+		newTool(tools.GlobToolName),
+		newTool(tools.BashToolName),
+	})
+	require.IsType(t, &prefetchingTool{}, agentTools[0])
+	require.IsType(t, &prefetchingTool{}, agentTools[1])
+	_, isPrefetching := agentTools[2].(*prefetchingTool)
+	require.False(t, isPrefetching)
+
+	ctx := t.Context()
+	p.startStep()
+	for i, tc := range []fantasy.ToolCallContent{
+		{ToolCallID: "1", ToolName: tools.ViewToolName, Input: `{"path":"a"}`},
+		{ToolCallID: "2", ToolName: tools.GlobToolName, Input: `{"path":"b"}`},
+		{ToolCallID: "3", ToolName: tools.ViewToolName, Input: `{"path":"c"}`},
+		{ToolCallID: "4", ToolName: tools.BashToolName, Input: `{"path":"d"}`},
+		{ToolCallID: "5", ToolName: tools.ViewToolName, Input: `{"path":"e"}`},
+	} {
+		p.prefetch(ctx, agentTools, tc)
+		if i == 1 {
+			// The first two calls run at the same time.
+			require.Eventually(t, func() bool { return running.Load() == 2 }, time.Second, time.Millisecond)
+		}
+	}
+	close(release)
+
+	// Prefetched calls return their result.
+	resp, err := agentTools[0].Run(ctx, fantasy.ToolCall{ID: "1", Name: tools.ViewToolName, Input: `{"path":"a"}`})
+	require.NoError(t, err)
+	require.Equal(t, "view:a", resp.Content)
+	resp, err = agentTools[0].Run(ctx, fantasy.ToolCall{ID: "3", Name: tools.ViewToolName, Input: `{"path":"c"}`})
+	require.NoError(t, err)
+	require.Equal(t, "view:c", resp.Content)
+	require.EqualValues(t, 2, maxRunning.Load(), "no more than the workers run at once")
+
+	// Calls after the bash call weren't started early.
+	_, ok := p.take(fantasy.ToolCall{ID: "5", Input: `{"path":"e"}`})
+	require.False(t, ok)
+
+	// A call with a different input is run again.
+	_, ok = p.take(fantasy.ToolCall{ID: "2", Input: `{"path":"x"}`})
+	require.False(t, ok)
+	resp, err = agentTools[1].Run(ctx, fantasy.ToolCall{ID: "2", Name: tools.GlobToolName, Input: `{"path":"b"}`})
+	require.NoError(t, err)
+	require.Equal(t, "glob:b", resp.Content)
+	require.EqualValues(t, 3, runs.Load())
+}
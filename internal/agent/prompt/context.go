@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/tokens"
+)
+
+// LoadContextFiles returns the context files given to the model, in order:
+// the context files named in the global configuration directory, then the
+// ones in each directory from the root of the repository down to the working
+// directory, then the other context paths. Each of them is truncated to the
+// token limit of the configuration, and the ones .crushignore or exclude,
+// when not nil, excludes are left out.
+func LoadContextFiles(cfg config.Config, exclude func(path string) bool) []ContextFile {
+	var names, others []string
+	for _, pth := range cfg.Options.ContextPaths {
+		expanded := expandPath(pth, cfg)
+		if filepath.IsAbs(expanded) || strings.ContainsAny(expanded, `/\`) {
+			others = append(others, expanded)
+		} else {
+			names = append(names, expanded)
+		}
+	}
+
+	seen := map[string]bool{}
+	crushIgnore := fsext.NewCrushIgnore(cfg.WorkingDir())
+	var files []ContextFile
+	add := func(contextFiles []ContextFile) {
+		for _, file := range contextFiles {
+			if crushIgnore.Ignores(file.Path) || (exclude != nil && exclude(file.Path)) {
+				continue
+			}
+			// Files differing in case alone are the same on most systems.
+			key := strings.ToLower(file.Path)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			files = append(files, truncateContextFile(file, cfg.Options.ContextFileMaxTokens))
+		}
+	}
+	dirs := append([]string{filepath.Dir(config.GlobalConfig())}, projectDirs(cfg.WorkingDir())...)
+	for _, dir := range dirs {
+		for _, name := range names {
+			add(processContextPath(filepath.Join(dir, name), cfg))
+		}
+	}
+	for _, pth := range others {
+		add(processContextPath(pth, cfg))
+	}
+	return files
+}
+
+// projectDirs returns the directories from the root of the repository of dir
+// down to dir, or dir alone outside of a repository.
+func projectDirs(dir string) []string {
+	dirs := []string{dir}
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			slices.Reverse(dirs)
+			return dirs
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return []string{dir}
+		}
+		current = parent
+		dirs = append(dirs, current)
+	}
+}
+
+// truncateContextFile cuts the content of file to about maxTokens estimated
+// tokens, at the end of a line. Files aren't truncated when maxTokens isn't
+// positive.
+func truncateContextFile(file ContextFile, maxTokens int) ContextFile {
+	file.Tokens = int(tokens.Estimate(file.Content))
+	if maxTokens <= 0 || file.Tokens <= maxTokens {
+		return file
+	}
+	content := file.Content[:len(file.Content)*maxTokens/file.Tokens]
+	if i := strings.LastIndexByte(content, '\n'); i > 0 {
+		content = content[:i]
+	}
+	file.Content = fmt.Sprintf(
+		"%s\n\n[Truncated: only the first %d of about %d tokens of this file are included.]",
+		strings.ToValidUTF8(content, ""), maxTokens, file.Tokens,
+	)
+	file.Truncated = true
+	return file
+}
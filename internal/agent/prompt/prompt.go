@@ -3,7 +3,9 @@ package prompt
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -11,6 +13,7 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/home"
 	"github.com/charmbracelet/crush/internal/shell"
@@ -23,6 +26,11 @@ type Prompt struct {
 	now        func() time.Time
 	platform   string
 	workingDir string
+	// instructions are the user-defined part of the prompt.
+	instructions string
+	// fragments are the files of the user appended to the prompt.
+	fragments []string
+	tools     []string
 }
 
 type PromptDat struct {
@@ -35,11 +43,18 @@ type PromptDat struct {
 	Date         string
 	GitStatus    string
 	ContextFiles []ContextFile
+	Instructions string
+	ProjectName  string
+	Tools        []string
 }
 
 type ContextFile struct {
 	Path    string
 	Content string
+	// Tokens is the estimated number of tokens of the whole file.
+	Tokens int
+	// Truncated is set when only the start of the file is included.
+	Truncated bool
 }
 
 type Option func(*Prompt)
@@ -62,6 +77,29 @@ func WithWorkingDir(workingDir string) Option {
 	}
 }
 
+// WithInstructions sets user-defined instructions, which the template can
+// include as is.
+func WithInstructions(instructions string) Option {
+	return func(p *Prompt) {
+		p.instructions = instructions
+	}
+}
+
+// WithFragments sets the files appended to the prompt, templates with the
+// same data as the prompt. The missing ones are skipped.
+func WithFragments(paths ...string) Option {
+	return func(p *Prompt) {
+		p.fragments = paths
+	}
+}
+
+// WithTools sets the names of the tools available to the agent.
+func WithTools(tools []string) Option {
+	return func(p *Prompt) {
+		p.tools = tools
+	}
+}
+
 func NewPrompt(name, promptTemplate string, opts ...Option) (*Prompt, error) {
 	p := &Prompt{
 		name:     name,
@@ -87,10 +125,39 @@ func (p *Prompt) Build(ctx context.Context, provider, model string, cfg config.C
 	if err := t.Execute(&sb, d); err != nil {
 		return "", fmt.Errorf("executing template: %w", err)
 	}
+	for _, path := range p.fragments {
+		fragment, err := buildFragment(path, d)
+		if err != nil {
+			return "", err
+		}
+		if fragment != "" {
+			sb.WriteString("\n\n")
+			sb.WriteString(fragment)
+		}
+	}
 
 	return sb.String(), nil
 }
 
+func buildFragment(path string, d PromptDat) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading prompt fragment %s: %w", path, err)
+	}
+	t, err := template.New(filepath.Base(path)).Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("parsing prompt fragment %s: %w", path, err)
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, d); err != nil {
+		return "", fmt.Errorf("executing prompt fragment %s: %w", path, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
 func processFile(filePath string) *ContextFile {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
@@ -150,27 +217,18 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, cfg con
 	workingDir := cmp.Or(p.workingDir, cfg.WorkingDir())
 	platform := cmp.Or(p.platform, runtime.GOOS)
 
-	files := map[string][]ContextFile{}
-
-	for _, pth := range cfg.Options.ContextPaths {
-		expanded := expandPath(pth, cfg)
-		pathKey := strings.ToLower(expanded)
-		if _, ok := files[pathKey]; ok {
-			continue
-		}
-		content := processContextPath(expanded, cfg)
-		files[pathKey] = content
-	}
-
 	isGit := isGitRepo(cfg.WorkingDir())
 	data := PromptDat{
-		Provider:   provider,
-		Model:      model,
-		Config:     cfg,
-		WorkingDir: filepath.ToSlash(workingDir),
-		IsGitRepo:  isGit,
-		Platform:   platform,
-		Date:       p.now().Format("1/2/2006"),
+		Provider:     provider,
+		Model:        model,
+		Config:       cfg,
+		WorkingDir:   filepath.ToSlash(workingDir),
+		IsGitRepo:    isGit,
+		Platform:     platform,
+		Date:         p.now().Format("1/2/2006"),
+		Instructions: p.instructions,
+		ProjectName:  filepath.Base(workingDir),
+		Tools:        p.tools,
 	}
 	if isGit {
 		var err error
@@ -180,9 +238,9 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, cfg con
 		}
 	}
 
-	for _, contextFiles := range files {
-		data.ContextFiles = append(data.ContextFiles, contextFiles...)
-	}
+	data.ContextFiles = LoadContextFiles(cfg, func(path string) bool {
+		return tools.IsContentExcluded(ctx, cfg.WorkingDir(), path)
+	})
 	return data, nil
 }
 
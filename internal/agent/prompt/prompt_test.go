@@ -0,0 +1,66 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptFragments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.md")
+	project := filepath.Join(dir, "project.md")
+	require.NoError(t, os.WriteFile(global, []byte("Answer tersely.\n"), 0o644))
+	require.NoError(t, os.WriteFile(project, []byte("Working on {{.ProjectName}} for {{.Platform}} with {{range $i, $t := .Tools}}{{if $i}}, {{end}}{{$t}}{{end}}.\n"), 0o644))
+
+	p, err := NewPrompt(
+		"test",
+		"Built-in prompt.",
+		WithWorkingDir(filepath.Join(dir, "acme")),
+		WithPlatform("linux"),
+		WithTools([]string{"bash", "view"}),
+		WithFragments(global, filepath.Join(dir, "missing.md"), project),
+	)
+	require.NoError(t, err)
+	built, err := p.Build(t.Context(), "", "", config.Config{Options: &config.Options{}})
+	require.NoError(t, err)
+	require.Equal(t, "Built-in prompt.\n\nAnswer tersely.\n\nWorking on acme for linux with bash, view.", built)
+
+	require.NoError(t, os.WriteFile(project, []byte("{{.Unknown"), 0o644))
+	_, err = p.Build(t.Context(), "", "", config.Config{Options: &config.Options{}})
+	require.ErrorContains(t, err, "parsing prompt fragment "+project)
+}
+
+func TestLoadContextFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	repo := filepath.Join(dir, "repo")
+	workingDir := filepath.Join(repo, "sub")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(workingDir, 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config", "crush"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte("outside the repository"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "crush", "AGENTS.md"), []byte("global"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "AGENTS.md"), []byte("root"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "CRUSH.md"), []byte("first line\nsecond line\nthird line\n"), 0o644))
+
+	cfg := config.Config{Options: &config.Options{ContextPaths: []string{"AGENTS.md", "CRUSH.md"}, ContextFileMaxTokens: 5}}
+	cfg.SetWorkingDir(workingDir)
+	files := LoadContextFiles(cfg, nil)
+	require.Len(t, files, 3)
+	require.Equal(t, filepath.Join(dir, "config", "crush", "AGENTS.md"), files[0].Path)
+	require.Equal(t, filepath.Join(repo, "AGENTS.md"), files[1].Path)
+	require.Equal(t, filepath.Join(workingDir, "CRUSH.md"), files[2].Path)
+	require.False(t, files[1].Truncated)
+	require.True(t, files[2].Truncated)
+	require.Equal(t, "first line\n\n[Truncated: only the first 5 of about 10 tokens of this file are included.]", files[2].Content)
+
+	files = LoadContextFiles(cfg, func(path string) bool { return filepath.Base(path) == "CRUSH.md" })
+	require.Len(t, files, 2, "excluded files are left out")
+	require.Equal(t, filepath.Join(repo, "AGENTS.md"), files[1].Path)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	interruptedTitle   = "Interrupted"
+	interruptedDetails = "Crush exited before the response was finished."
+	// interruptedToolResult is the result of the tool calls crush didn't
+	// get to finish, which may or may not have run.
+	interruptedToolResult = "The tool call was interrupted because Crush exited, it may or may not have run"
+)
+
+// recoverInterrupted finishes the last response of the session when crush
+// exited while writing it, as after a crash or with the terminal closed.
+// What was streamed so far was saved as it came and is kept: the tool calls
+// without results get error results and the response an error finish, so
+// the session shows as idle and the conversation can go on. It's only safe
+// while the session isn't running.
+func recoverInterrupted(ctx context.Context, messages message.Service, sessionID string) error {
+	msgs, err := messages.List(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list session messages: %w", err)
+	}
+
+	// Any earlier response was recovered by the prompts sent after it.
+	results := make(map[string]bool)
+	var last *message.Message
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == message.Tool {
+			for _, tr := range msgs[i].ToolResults() {
+				results[tr.ToolCallID] = true
+			}
+			continue
+		}
+		if msgs[i].Role == message.Assistant {
+			last = &msgs[i]
+		}
+		break
+	}
+	if last == nil || last.IsFinished() {
+		return nil
+	}
+
+	last.FinishThinking()
+	for _, tc := range last.ToolCalls() {
+		if !tc.Finished {
+			tc.Finished = true
+			tc.Input = "{}"
+			last.AddToolCall(tc)
+		}
+		if results[tc.ID] {
+			continue
+		}
+		_, err = messages.Create(ctx, sessionID, message.CreateMessageParams{
+			Role: message.Tool,
+			Parts: []message.ContentPart{
+				message.ToolResult{
+					ToolCallID: tc.ID,
+					Name:       tc.Name,
+					Content:    interruptedToolResult,
+					IsError:    true,
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add the result of an interrupted tool call: %w", err)
+		}
+	}
+	last.AddFinish(message.FinishReasonError, interruptedTitle, interruptedDetails)
+	if err := messages.Update(ctx, *last); err != nil {
+		return fmt.Errorf("failed to finish the interrupted response: %w", err)
+	}
+	return nil
+}
+
+// RecoverSession implements Coordinator.
+func (c *coordinator) RecoverSession(ctx context.Context, sessionID string) error {
+	if c.IsSessionBusy(sessionID) {
+		return nil
+	}
+	for _, task := range c.BackgroundTasks() {
+		if task.SessionID == sessionID && !task.Done() {
+			return nil
+		}
+	}
+	return recoverInterrupted(ctx, c.messages, sessionID)
+}
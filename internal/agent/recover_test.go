@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverInterrupted(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	session, err := env.sessions.Create(t.Context(), "New Session")
+	require.NoError(t, err)
+
+	create := func(role message.MessageRole, parts ...message.ContentPart) {
+		t.Helper()
+		_, err := env.messages.Create(t.Context(), session.ID, message.CreateMessageParams{Role: role, Parts: parts})
+		require.NoError(t, err)
+	}
+	create(message.User, message.TextContent{Text: "Look around"})
+	// Crush exited while the first tool ran and the second was written.
+	create(message.Assistant,
+		message.TextContent{Text: "Let me look."},
+		message.ToolCall{ID: "ls", Name: "ls", Input: `{"path":"."}`, Finished: true},
+		message.ToolCall{ID: "view", Name: "view", Input: `{"file_`},
+		message.ToolCall{ID: "glob", Name: "glob", Input: `{"pattern":"*"}`, Finished: true},
+	)
+	create(message.Tool, message.ToolResult{ToolCallID: "glob", Name: "glob", Content: "main.go"})
+
+	require.NoError(t, recoverInterrupted(t.Context(), env.messages, session.ID))
+	msgs, err := env.messages.List(t.Context(), session.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 5)
+
+	assistant := msgs[1]
+	require.True(t, assistant.IsFinished())
+	require.Equal(t, message.FinishReasonError, assistant.FinishReason())
+	require.Equal(t, "Let me look.", assistant.Content().Text, "the streamed text is kept")
+	calls := assistant.ToolCalls()
+	require.Equal(t, `{"path":"."}`, calls[0].Input)
+	require.True(t, calls[1].Finished)
+	require.Equal(t, "{}", calls[1].Input)
+
+	var interrupted []string
+	for _, msg := range msgs[3:] {
+		for _, tr := range msg.ToolResults() {
+			require.True(t, tr.IsError)
+			interrupted = append(interrupted, tr.ToolCallID)
+		}
+	}
+	require.ElementsMatch(t, []string{"ls", "view"}, interrupted)
+
+	// A finished session is left as is.
+	require.NoError(t, recoverInterrupted(t.Context(), env.messages, session.ID))
+	again, err := env.messages.List(t.Context(), session.ID)
+	require.NoError(t, err)
+	require.Equal(t, msgs, again)
+}
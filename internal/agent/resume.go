@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/bedrock"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// maxStreamResumes bounds the number of times a response cut off by the
+// network is resumed.
+const maxStreamResumes = 2
+
+// resumePrompt asks the model to go on with a response that was cut off, for
+// providers that can't continue it from the partial text.
+const resumePrompt = "Your previous response was cut off by a network error. Continue it exactly where it stopped, without repeating what you already wrote."
+
+// interruptedStreamErrors are the messages of the errors of streams dropped
+// by the network, when they aren't wrapped in a way errors.Is can follow.
+var interruptedStreamErrors = []string{
+	"unexpected eof",
+	"connection reset",
+	"broken pipe",
+	"stream error",
+	"server sent goaway",
+}
+
+// isStreamInterrupted reports whether err is a response stream being
+// dropped by the network, rather than the request failing.
+func isStreamInterrupted(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range interruptedStreamErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// canResume reports whether the response msg, which failed with err, can be
+// resumed: its stream was dropped while writing text, not calling tools or
+// thinking.
+func canResume(err error, msg *message.Message) bool {
+	return isStreamInterrupted(err) && len(msg.ToolCalls()) == 0 && !msg.IsThinking()
+}
+
+// supportsPrefill reports whether the provider continues the last message
+// of a request when it's from the assistant.
+func supportsPrefill(provider string) bool {
+	return provider == anthropic.Name || provider == bedrock.Name
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsStreamInterrupted(t *testing.T) {
+	t.Parallel()
+
+	for _, err := range []error{
+		io.ErrUnexpectedEOF,
+		fmt.Errorf("reading stream: %w", syscall.ECONNRESET),
+		errors.New("stream error: stream ID 3; INTERNAL_ERROR; received from peer"),
+		&fantasy.ProviderError{Message: "unexpected EOF"},
+	} {
+		require.True(t, isStreamInterrupted(err), err.Error())
+	}
+	for _, err := range []error{
+		nil,
+		context.Canceled,
+		context.DeadlineExceeded,
+		errors.New("invalid api key"),
+		&fantasy.ProviderError{Message: "overloaded", StatusCode: 529},
+	} {
+		require.False(t, isStreamInterrupted(err))
+	}
+}
+
+func TestCanResume(t *testing.T) {
+	t.Parallel()
+
+	text := &message.Message{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "Here's the plan"}}}
+	require.True(t, canResume(io.ErrUnexpectedEOF, text))
+	require.False(t, canResume(context.Canceled, text))
+
+	calling := &message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "Let me look."},
+		message.ToolCall{ID: "call", Name: "ls"},
+	}}
+	require.False(t, canResume(io.ErrUnexpectedEOF, calling))
+
+	thinking := &message.Message{Role: message.Assistant, Parts: []message.ContentPart{message.ReasoningContent{Thinking: "Hmm"}}}
+	require.False(t, canResume(io.ErrUnexpectedEOF, thinking))
+}
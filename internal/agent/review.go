@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/agent/prompt"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+//go:embed templates/review.md.tpl
+var reviewPromptTmpl []byte
+
+// Review implements Coordinator. The review agent has the read-only tools
+// of the task agent.
+func (c *coordinator) Review(ctx context.Context, sessionID, request string) (string, error) {
+	if err := c.readyWg.Wait(); err != nil {
+		return "", err
+	}
+	if err := c.checkBudget(ctx, sessionID); err != nil {
+		return "", err
+	}
+
+	agentCfg, ok := c.cfg.Agents[config.AgentTask]
+	if !ok {
+		return "", errors.New("task agent not configured")
+	}
+	reviewPrompt, err := prompt.NewPrompt("review", string(reviewPromptTmpl), prompt.WithWorkingDir(c.cfg.WorkingDir()))
+	if err != nil {
+		return "", err
+	}
+	agent, err := c.buildTaskAgent(ctx, reviewPrompt, agentCfg, config.SelectedModelTypeLarge)
+	if err != nil {
+		return "", fmt.Errorf("error building agent: %w", err)
+	}
+	call, err := c.taskAgentCall(agent, sessionID, request)
+	if err != nil {
+		return "", err
+	}
+	result, err := agent.Run(ctx, call)
+	if err != nil {
+		return "", err
+	}
+	return result.Response.Content.Text(), nil
+}
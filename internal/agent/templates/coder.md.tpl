@@ -332,9 +332,10 @@ Git status (snapshot at conversation start - may be outdated):
 
 {{if gt (len .Config.LSP) 0}}
 <lsp>
-Diagnostics (lint/typecheck) included in tool output.
+Fresh diagnostics (lint/typecheck) are included in the output of the edit, multiedit and write tools.
 - Fix issues in files you changed
 - Ignore issues in files you didn't touch (unless user asks)
+- Navigate code with lsp_symbols, lsp_definition and lsp_references rather than grep when looking for a symbol
 </lsp>
 {{end}}
 
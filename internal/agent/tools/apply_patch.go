@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/filepathext"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+type ApplyPatchParams struct {
+	Patch string `json:"patch" description:"The unified diff to apply, with --- and +++ headers for each file followed by its @@ hunks. Paths are relative to the working directory, optionally prefixed with a/ and b/"`
+}
+
+type ApplyPatchPermissionsParams struct {
+	Patch string   `json:"patch"`
+	Files []string `json:"files"`
+}
+
+// PatchHunkResult is the outcome of applying a hunk of the patch.
+type PatchHunkResult struct {
+	FilePath string `json:"file_path"`
+	Hunk     int    `json:"hunk"`
+	Applied  bool   `json:"applied"`
+	Result   string `json:"result"`
+}
+
+type ApplyPatchResponseMetadata struct {
+	Additions int               `json:"additions"`
+	Removals  int               `json:"removals"`
+	Files     []string          `json:"files"`
+	Hunks     []PatchHunkResult `json:"hunks"`
+}
+
+const ApplyPatchToolName = "apply_patch"
+
+//go:embed apply_patch.md
+var applyPatchDescription []byte
+
+// patchedFile is the content of a file before and after the patch.
+type patchedFile struct {
+	path       string
+	oldContent string
+	newContent string
+	isCrlf     bool
+	// exists tells whether the file exists, and deleted whether the patch
+	// deletes it.
+	exists  bool
+	deleted bool
+}
+
+func NewApplyPatchTool(lspClients *csync.Map[string, *lsp.Client], permissions permission.Service, files history.Service, workingDir string) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		ApplyPatchToolName,
+		string(applyPatchDescription),
+		func(ctx context.Context, params ApplyPatchParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if strings.TrimSpace(params.Patch) == "" {
+				return fantasy.NewTextErrorResponse("patch is required"), nil
+			}
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for applying a patch")
+			}
+
+			patches, err := parsePatch(params.Patch)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid patch: %s", err)), nil
+			}
+
+			// Every hunk is checked against the files before any is written.
+			changed, results, err := patchFiles(ctx, workingDir, patches)
+			if err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			metadata := ApplyPatchResponseMetadata{Hunks: results}
+			report := formatHunkResults(workingDir, results)
+			for _, r := range results {
+				if !r.Applied {
+					return fantasy.WithResponseMetadata(
+						fantasy.NewTextErrorResponse("Patch not applied, no file was changed:\n"+report),
+						metadata,
+					), nil
+				}
+			}
+
+			for _, f := range changed {
+				_, additions, removals := diff.GenerateDiff(f.oldContent, f.newContent, strings.TrimPrefix(f.path, workingDir))
+				metadata.Additions += additions
+				metadata.Removals += removals
+				metadata.Files = append(metadata.Files, f.path)
+			}
+
+			p := permissions.Request(permission.CreatePermissionRequest{
+				SessionID:   sessionID,
+				Path:        workingDir,
+				ToolCallID:  call.ID,
+				ToolName:    ApplyPatchToolName,
+				Action:      "write",
+				Description: fmt.Sprintf("Apply patch to %d file(s)", len(changed)),
+				Params: ApplyPatchPermissionsParams{
+					Patch: params.Patch,
+					Files: metadata.Files,
+				},
+			})
+			if !p {
+				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+			}
+
+			if err := writePatchedFiles(changed); err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+
+			for _, f := range changed {
+				recordPatchHistory(ctx, files, sessionID, f)
+				if !f.deleted {
+					recordFileWrite(sessionID, f.path)
+					recordFileRead(sessionID, f.path)
+				}
+			}
+
+			var text strings.Builder
+			fmt.Fprintf(&text, "<result>\nPatch applied to %d file(s):\n%s\n</result>\n", len(changed), report)
+			for _, f := range changed {
+				if f.deleted {
+					continue
+				}
+				notifyLSPs(ctx, lspClients, f.path)
+				text.WriteString(getDiagnostics(f.path, lspClients, contentExcludeFunc(ctx, workingDir)))
+			}
+			return fantasy.WithResponseMetadata(fantasy.NewTextResponse(text.String()), metadata), nil
+		})
+}
+
+// patchFiles applies the patches to the contents of their files, without
+// writing them.
+func patchFiles(ctx context.Context, workingDir string, patches []filePatch) ([]*patchedFile, []PatchHunkResult, error) {
+	var changed []*patchedFile
+	byPath := make(map[string]*patchedFile)
+	var results []PatchHunkResult
+
+	for _, fp := range patches {
+		path := filepathext.SmartJoin(workingDir, fp.path())
+		// The permission is asked for the working directory, which the
+		// patched files can't leave.
+		var pathErr string
+		if rel, err := filepath.Rel(workingDir, path); err != nil || !filepath.IsLocal(rel) {
+			pathErr = "failed: the file is outside the working directory"
+		} else if IsContentExcluded(ctx, workingDir, path) {
+			pathErr = "failed: the file is excluded by the content exclusion policy"
+		}
+		if pathErr != "" {
+			for i := range fp.hunks {
+				results = append(results, PatchHunkResult{FilePath: path, Hunk: i + 1, Result: pathErr})
+			}
+			continue
+		}
+		f, ok := byPath[path]
+		if !ok {
+			f = &patchedFile{path: path}
+			content, err := os.ReadFile(path)
+			switch {
+			case err == nil:
+				f.exists = true
+				f.oldContent, f.isCrlf = fsext.ToUnixLineEndings(string(content))
+			case !os.IsNotExist(err):
+				return nil, nil, fmt.Errorf("failed to read file: %w", err)
+			}
+			f.newContent = f.oldContent
+			byPath[path] = f
+			changed = append(changed, f)
+		}
+
+		// A file patched several times exists when an earlier patch
+		// created it.
+		present := (f.exists || f.newContent != "") && !f.deleted
+		var fileErr string
+		switch {
+		case fp.isCreation() && present:
+			fileErr = "failed: the file to create already exists"
+		case !fp.isCreation() && !present:
+			fileErr = "failed: file not found"
+		}
+		if fileErr != "" {
+			for i := range fp.hunks {
+				results = append(results, PatchHunkResult{FilePath: path, Hunk: i + 1, Result: fileErr})
+			}
+			continue
+		}
+
+		newContent, hunkResults := applyHunks(f.newContent, fp.hunks)
+		for i, r := range hunkResults {
+			results = append(results, PatchHunkResult{
+				FilePath: path,
+				Hunk:     i + 1,
+				Applied:  r.err == nil,
+				Result:   r.String(),
+			})
+		}
+		f.newContent = newContent
+		f.deleted = fp.isDeletion()
+	}
+	return changed, results, nil
+}
+
+// writePatchedFiles writes the patched files, restoring the ones already
+// written when one fails.
+func writePatchedFiles(changed []*patchedFile) error {
+	for i, f := range changed {
+		if err := writePatchedFile(f); err != nil {
+			for _, written := range changed[:i] {
+				if rerr := restorePatchedFile(written); rerr != nil {
+					slog.Error("Failed to restore file after a failed patch", "file", written.path, "error", rerr)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func writePatchedFile(f *patchedFile) error {
+	if f.deleted {
+		if !f.exists {
+			return nil
+		}
+		if err := os.Remove(f.path); err != nil {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	content := f.newContent
+	if f.isCrlf {
+		content, _ = fsext.ToWindowsLineEndings(content)
+	}
+	if err := os.WriteFile(f.path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func restorePatchedFile(f *patchedFile) error {
+	if !f.exists {
+		return os.Remove(f.path)
+	}
+	content := f.oldContent
+	if f.isCrlf {
+		content, _ = fsext.ToWindowsLineEndings(content)
+	}
+	return os.WriteFile(f.path, []byte(content), 0o644)
+}
+
+func recordPatchHistory(ctx context.Context, files history.Service, sessionID string, f *patchedFile) {
+	file, err := files.GetByPathAndSession(ctx, f.path, sessionID)
+	if err != nil {
+		if _, err := files.Create(ctx, sessionID, f.path, f.oldContent); err != nil {
+			slog.Error("Error creating file history", "error", err)
+			return
+		}
+	} else if file.Content != f.oldContent {
+		// The file was changed outside of Crush, keep that version too.
+		if _, err := files.CreateVersion(ctx, sessionID, f.path, f.oldContent); err != nil {
+			slog.Error("Error creating file history version", "error", err)
+		}
+	}
+	if _, err := files.CreateVersion(ctx, sessionID, f.path, f.newContent); err != nil {
+		slog.Error("Error creating file history version", "error", err)
+	}
+}
+
+// formatHunkResults lists the outcome of every hunk, file by file.
+func formatHunkResults(workingDir string, results []PatchHunkResult) string {
+	var sb strings.Builder
+	for _, r := range results {
+		path, err := filepath.Rel(workingDir, r.FilePath)
+		if err != nil {
+			path = r.FilePath
+		}
+		fmt.Fprintf(&sb, "%s: hunk %d %s\n", filepath.ToSlash(path), r.Hunk, r.Result)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -2,9 +2,9 @@ package tools
 
 import (
 	"bytes"
-	"cmp"
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
@@ -24,6 +24,7 @@ type BashParams struct {
 	Command         string `json:"command" description:"The command to execute"`
 	WorkingDir      string `json:"working_dir,omitempty" description:"The working directory to execute the command in (defaults to current directory)"`
 	RunInBackground bool   `json:"run_in_background,omitempty" description:"Set to true (boolean) to run this command in the background. Use job_output to read the output later."`
+	ResetShell      bool   `json:"reset_shell,omitempty" description:"Set to true (boolean) to start over from a fresh shell, in the default working directory and environment, before running the command. The command can be left empty to only reset the shell."`
 }
 
 type BashPermissionsParams struct {
@@ -31,6 +32,7 @@ type BashPermissionsParams struct {
 	Command         string `json:"command"`
 	WorkingDir      string `json:"working_dir"`
 	RunInBackground bool   `json:"run_in_background"`
+	ResetShell      bool   `json:"reset_shell"`
 }
 
 type BashResponseMetadata struct {
@@ -47,7 +49,6 @@ const (
 	BashToolName = "bash"
 
 	AutoBackgroundThreshold = 1 * time.Minute // Commands taking longer automatically become background jobs
-	MaxOutputLength         = 30000
 	BashNoOutput            = "no output"
 )
 
@@ -62,6 +63,7 @@ var bashDescriptionTpl = template.Must(
 type bashDescriptionData struct {
 	BannedCommands  string
 	MaxOutputLength int
+	Timeout         time.Duration
 	Attribution     config.Attribution
 	ModelName       string
 }
@@ -139,12 +141,19 @@ var bannedCommands = []string{
 	"ufw",
 }
 
-func bashDescription(attribution *config.Attribution, modelName string) string {
+// outputLimits bounds the command output returned to the model.
+type outputLimits struct {
+	maxBytes   int
+	truncation config.BashTruncation
+}
+
+func bashDescription(attribution *config.Attribution, modelName string, maxOutputLength int, timeout time.Duration) string {
 	bannedCommandsStr := strings.Join(bannedCommands, ", ")
 	var out bytes.Buffer
 	if err := bashDescriptionTpl.Execute(&out, bashDescriptionData{
 		BannedCommands:  bannedCommandsStr,
-		MaxOutputLength: MaxOutputLength,
+		MaxOutputLength: maxOutputLength,
+		Timeout:         timeout,
 		Attribution:     *attribution,
 		ModelName:       modelName,
 	}); err != nil {
@@ -186,17 +195,42 @@ func blockFuncs() []shell.BlockFunc {
 	}
 }
 
-func NewBashTool(permissions permission.Service, workingDir string, attribution *config.Attribution, modelName string) fantasy.AgentTool {
+func NewBashTool(permissions permission.Service, workingDir string, attribution *config.Attribution, modelName string, bashConfig config.ToolBash) fantasy.AgentTool {
+	commandTimeout, maxOutputBytes, truncation := bashConfig.Limits()
+	limits := outputLimits{maxBytes: maxOutputBytes, truncation: truncation}
 	return fantasy.NewAgentTool(
 		BashToolName,
-		string(bashDescription(attribution, modelName)),
+		string(bashDescription(attribution, modelName, maxOutputBytes, commandTimeout)),
 		func(ctx context.Context, params BashParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for executing shell command")
+			}
+
+			sessionShells := shell.GetSessionShells()
+			if params.ResetShell {
+				sessionShells.Reset(sessionID)
+			}
 			if params.Command == "" {
+				if params.ResetShell {
+					return fantasy.NewTextResponse(fmt.Sprintf("Shell reset.\n\n<cwd>%s</cwd>", normalizeWorkingDir(workingDir))), nil
+				}
 				return fantasy.NewTextErrorResponse("missing command"), nil
 			}
 
-			// Determine working directory
-			execWorkingDir := cmp.Or(params.WorkingDir, workingDir)
+			// Commands run in a fork of the session shell, whose state is
+			// kept once they complete.
+			sessionShell := sessionShells.Get(sessionID, workingDir, blockFuncs())
+			execShell := sessionShell.Fork()
+			if params.WorkingDir != "" {
+				if err := execShell.SetWorkingDir(params.WorkingDir); err != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid working directory: %s", err)), nil
+				}
+			}
+			if exclude := contentExcludeFunc(ctx, workingDir); exclude != nil {
+				execShell.SetExcludeFunc(exclude)
+			}
+			execWorkingDir := execShell.GetWorkingDir()
 
 			isSafeReadOnly := false
 			cmdLower := strings.ToLower(params.Command)
@@ -210,10 +244,6 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 				}
 			}
 
-			sessionID := GetSessionFromContext(ctx)
-			if sessionID == "" {
-				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for executing shell command")
-			}
 			if !isSafeReadOnly {
 				p := permissions.Request(
 					permission.CreatePermissionRequest{
@@ -224,6 +254,7 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 						Action:      "execute",
 						Description: fmt.Sprintf("Execute command: %s", params.Command),
 						Params:      BashPermissionsParams(params),
+						Command:     params.Command,
 					},
 				)
 				if !p {
@@ -237,7 +268,7 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 				bgManager := shell.GetBackgroundShellManager()
 				bgManager.Cleanup()
 				// Use background context so it continues after tool returns
-				bgShell, err := bgManager.Start(context.Background(), execWorkingDir, blockFuncs(), params.Command, params.Description)
+				bgShell, err := bgManager.StartShell(context.Background(), execShell, params.Command, params.Description)
 				if err != nil {
 					return fantasy.ToolResponse{}, fmt.Errorf("error starting background shell: %w", err)
 				}
@@ -256,7 +287,7 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 						return fantasy.ToolResponse{}, fmt.Errorf("[Job %s] error executing command: %w", bgShell.ID, execErr)
 					}
 
-					stdout = formatOutput(stdout, stderr, execErr)
+					stdout = formatOutput(stdout, stderr, execErr, limits)
 
 					metadata := BashResponseMetadata{
 						StartTime:        startTime.UnixMilli(),
@@ -290,12 +321,21 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 			startTime := time.Now()
 
 			// Start with detached context so it can survive if moved to background
+			runCtx, cancel := context.WithCancel(context.Background())
+			if commandTimeout > 0 {
+				runCtx, cancel = context.WithTimeout(context.Background(), commandTimeout)
+			}
 			bgManager := shell.GetBackgroundShellManager()
 			bgManager.Cleanup()
-			bgShell, err := bgManager.Start(context.Background(), execWorkingDir, blockFuncs(), params.Command, params.Description)
+			bgShell, err := bgManager.StartShell(runCtx, execShell, params.Command, params.Description)
 			if err != nil {
+				cancel()
 				return fantasy.ToolResponse{}, fmt.Errorf("error starting shell: %w", err)
 			}
+			go func() {
+				bgShell.Wait()
+				cancel()
+			}()
 
 			// Wait for either completion, auto-background threshold, or context cancellation
 			ticker := time.NewTicker(100 * time.Millisecond)
@@ -305,6 +345,7 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 			var stdout, stderr string
 			var done bool
 			var execErr error
+			var streamed string
 
 		waitLoop:
 			for {
@@ -314,6 +355,11 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 					if done {
 						break waitLoop
 					}
+					// Stream the output while the command runs.
+					if output := liveOutput(stdout, stderr); output != streamed {
+						streamed = output
+						publishToolOutput(sessionID, call.ID, output)
+					}
 				case <-timeout:
 					stdout, stderr, done, execErr = bgShell.GetOutput()
 					break waitLoop
@@ -331,13 +377,23 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 				// Don't call Kill() as it cancels the context and corrupts the exit code
 				bgManager.Remove(bgShell.ID)
 
+				if errors.Is(execErr, shell.ErrShellCrashed) {
+					// Start over from a fresh shell on the next command.
+					sessionShells.Reset(sessionID)
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("%s\n\nThe shell was reset to %s with the default environment.", execErr, normalizeWorkingDir(workingDir))), nil
+				}
+				sessionShell.Adopt(bgShell.Shell)
+				if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+					execErr = fmt.Errorf("command timed out after %s: %w", commandTimeout, context.DeadlineExceeded)
+				}
+
 				interrupted := shell.IsInterrupt(execErr)
 				exitCode := shell.ExitCode(execErr)
 				if exitCode == 0 && !interrupted && execErr != nil {
 					return fantasy.ToolResponse{}, fmt.Errorf("[Job %s] error executing command: %w", bgShell.ID, execErr)
 				}
 
-				stdout = formatOutput(stdout, stderr, execErr)
+				stdout = formatOutput(stdout, stderr, execErr, limits)
 
 				metadata := BashResponseMetadata{
 					StartTime:        startTime.UnixMilli(),
@@ -345,12 +401,12 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 					Output:           stdout,
 					Description:      params.Description,
 					Background:       params.RunInBackground,
-					WorkingDirectory: bgShell.WorkingDir,
+					WorkingDirectory: sessionShell.GetWorkingDir(),
 				}
 				if stdout == "" {
 					return fantasy.WithResponseMetadata(fantasy.NewTextResponse(BashNoOutput), metadata), nil
 				}
-				stdout += fmt.Sprintf("\n\n<cwd>%s</cwd>", normalizeWorkingDir(bgShell.WorkingDir))
+				stdout += fmt.Sprintf("\n\n<cwd>%s</cwd>", normalizeWorkingDir(sessionShell.GetWorkingDir()))
 				return fantasy.WithResponseMetadata(fantasy.NewTextResponse(stdout), metadata), nil
 			}
 
@@ -369,12 +425,12 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 }
 
 // formatOutput formats the output of a completed command with error handling
-func formatOutput(stdout, stderr string, execErr error) string {
+func formatOutput(stdout, stderr string, execErr error, limits outputLimits) string {
 	interrupted := shell.IsInterrupt(execErr)
 	exitCode := shell.ExitCode(execErr)
 
-	stdout = truncateOutput(stdout)
-	stderr = truncateOutput(stderr)
+	stdout = truncateOutput(stdout, limits)
+	stderr = truncateOutput(stderr, limits)
 
 	errorMessage := stderr
 	if errorMessage == "" && execErr != nil {
@@ -385,7 +441,11 @@ func formatOutput(stdout, stderr string, execErr error) string {
 		if errorMessage != "" {
 			errorMessage += "\n"
 		}
-		errorMessage += "Command was aborted before completion"
+		if errors.Is(execErr, context.DeadlineExceeded) {
+			errorMessage += "Command timed out before completion"
+		} else {
+			errorMessage += "Command was aborted before completion"
+		}
 	} else if exitCode != 0 {
 		if errorMessage != "" {
 			errorMessage += "\n"
@@ -406,12 +466,34 @@ func formatOutput(stdout, stderr string, execErr error) string {
 	return stdout
 }
 
-func truncateOutput(content string) string {
-	if len(content) <= MaxOutputLength {
+// liveOutput returns the output of a running command, its standard error
+// following its standard output.
+func liveOutput(stdout, stderr string) string {
+	if stdout != "" && stderr != "" {
+		return stdout + "\n" + stderr
+	}
+	return stdout + stderr
+}
+
+// truncateOutput keeps the part of content chosen by the limits, telling
+// the model how much was left out.
+func truncateOutput(content string, limits outputLimits) string {
+	if len(content) <= limits.maxBytes {
 		return content
 	}
 
-	halfLength := MaxOutputLength / 2
+	switch limits.truncation {
+	case config.BashTruncationHead:
+		start := content[:limits.maxBytes]
+		truncatedLinesCount := countLines(content[limits.maxBytes:])
+		return fmt.Sprintf("%s\n\n... [output truncated, %d more lines not shown] ...", start, truncatedLinesCount)
+	case config.BashTruncationTail:
+		end := content[len(content)-limits.maxBytes:]
+		truncatedLinesCount := countLines(content[:len(content)-limits.maxBytes])
+		return fmt.Sprintf("... [output truncated, %d earlier lines not shown] ...\n\n%s", truncatedLinesCount, end)
+	}
+
+	halfLength := limits.maxBytes / 2
 	start := content[:halfLength]
 	end := content[len(content)-halfLength:]
 
@@ -16,11 +16,15 @@ Common shell builtins and core utils available on Windows.
 </execution_steps>
 
 <usage_notes>
-- Command required, working_dir optional (defaults to current directory)
+- Command required, working_dir optional (defaults to the directory the previous command ended in)
 - IMPORTANT: Use Grep/Glob/Agent tools instead of 'find'/'grep'. Use View/LS tools instead of 'cat'/'head'/'tail'/'ls'
 - Chain with ';' or '&&', avoid newlines except in quoted strings
-- Each command runs in independent shell (no state persistence between calls)
+- The shell persists across calls in a session: the working directory, exported variables and activated virtualenvs carry over to the next command. Background commands don't change it
 - Prefer absolute paths over 'cd' (use 'cd' only if user explicitly requests)
+- Set reset_shell=true to start over from the project directory and default environment, e.g. when the shell state is broken. If the shell crashes, it's reset automatically
+{{- if .Timeout }}
+- Commands not started in the background are stopped after {{ .Timeout }}, so run long tasks with run_in_background=true
+{{- end }}
 </usage_notes>
 
 <background_execution>
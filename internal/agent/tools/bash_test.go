@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateOutput(t *testing.T) {
+	t.Parallel()
+
+	content := "one\ntwo\nthree\nfour\nfive"
+
+	require.Equal(t, content, truncateOutput(content, outputLimits{maxBytes: len(content)}))
+	require.Equal(t,
+		"one\ntw\n\n... [output truncated, 4 more lines not shown] ...",
+		truncateOutput(content, outputLimits{maxBytes: 6, truncation: config.BashTruncationHead}),
+	)
+	require.Equal(t,
+		"... [output truncated, 4 earlier lines not shown] ...\n\nr\nfive",
+		truncateOutput(content, outputLimits{maxBytes: 6, truncation: config.BashTruncationTail}),
+	)
+	require.Equal(t,
+		"one\nt\n\n... [3 lines truncated] ...\n\n\nfive",
+		truncateOutput(content, outputLimits{maxBytes: 10, truncation: config.BashTruncationHeadTail}),
+	)
+}
+
+func TestFormatOutputTimeout(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("command timed out: %w", context.DeadlineExceeded)
+	output := formatOutput("partial", "", err, outputLimits{maxBytes: 100})
+	require.True(t, strings.HasPrefix(output, "partial\n"))
+	require.Contains(t, output, "Command timed out before completion")
+}
+
+func TestPublishToolOutput(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	events := SubscribeToolOutput(ctx)
+
+	publishToolOutput("session", "call", liveOutput("building", "warning"))
+	event := <-events
+	require.Equal(t, ToolOutput{SessionID: "session", ToolCallID: "call", Output: "building\nwarning"}, event.Payload)
+
+	// Only the end of long outputs is sent, from the start of a line.
+	long := strings.Repeat("line\n", maxStreamedOutput/5) + "last\n"
+	publishToolOutput("session", "call", long)
+	event = <-events
+	require.LessOrEqual(t, len(event.Payload.Output), maxStreamedOutput)
+	require.True(t, strings.HasPrefix(event.Payload.Output, "line\n"))
+	require.True(t, strings.HasSuffix(event.Payload.Output, "last\n"))
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type DefinitionParams struct {
+	Symbol string `json:"symbol" description:"The symbol name to find the definition of (e.g., function name, variable name, type name)"`
+	Path   string `json:"path,omitempty" description:"The directory to search in. Use a directory/file to narrow down the symbol search. Defaults to the current working directory."`
+}
+
+const DefinitionToolName = "lsp_definition"
+
+//go:embed definition.md
+var definitionDescription []byte
+
+func NewDefinitionTool(lspClients *csync.Map[string, *lsp.Client], workingDir string) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		DefinitionToolName,
+		string(definitionDescription),
+		func(ctx context.Context, params DefinitionParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if params.Symbol == "" {
+				return fantasy.NewTextErrorResponse("symbol is required"), nil
+			}
+
+			if lspClients.Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			searchPath := cmp.Or(params.Path, ".")
+
+			matches, err := symbolMatches(ctx, params.Symbol, searchPath, workingDir)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to search for symbol: %s", err)), nil
+			}
+
+			if len(matches) == 0 {
+				return fantasy.NewTextResponse(fmt.Sprintf("Symbol '%s' not found", params.Symbol)), nil
+			}
+
+			var allErrs error
+			for _, match := range matches {
+				locations, err := findDefinitions(ctx, lspClients, params.Symbol, match)
+				if err != nil {
+					if strings.Contains(err.Error(), "no identifier found") {
+						// grep probably matched a comment, string value, or something else that's irrelevant
+						continue
+					}
+					slog.Error("Failed to find definition", "error", err, "symbol", params.Symbol, "path", match.path, "line", match.lineNum, "char", match.charNum)
+					allErrs = errors.Join(allErrs, err)
+					continue
+				}
+				locations = excludeLocations(ctx, workingDir, locations)
+				if len(locations) > 0 {
+					// Every use of the symbol leads to the same definition.
+					output := formatLocations(cleanupLocations(locations), "definition(s)")
+					return fantasy.NewTextResponse(output), nil
+				}
+			}
+
+			if allErrs != nil {
+				return fantasy.NewTextErrorResponse(allErrs.Error()), nil
+			}
+			return fantasy.NewTextResponse(fmt.Sprintf("No definition found for symbol '%s'", params.Symbol)), nil
+		})
+}
+
+func findDefinitions(ctx context.Context, lspClients *csync.Map[string, *lsp.Client], symbol string, match grepMatch) ([]protocol.Location, error) {
+	absPath, client, err := clientForMatch(lspClients, match)
+	if client == nil || err != nil {
+		return nil, err
+	}
+	return client.FindDefinitions(
+		ctx,
+		absPath,
+		match.lineNum,
+		match.charNum+getSymbolOffset(symbol),
+	)
+}
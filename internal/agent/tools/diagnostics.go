@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"charm.land/fantasy"
@@ -24,7 +25,7 @@ const DiagnosticsToolName = "lsp_diagnostics"
 //go:embed diagnostics.md
 var diagnosticsDescription []byte
 
-func NewDiagnosticsTool(lspClients *csync.Map[string, *lsp.Client]) fantasy.AgentTool {
+func NewDiagnosticsTool(lspClients *csync.Map[string, *lsp.Client], workingDir string) fantasy.AgentTool {
 	return fantasy.NewAgentTool(
 		DiagnosticsToolName,
 		string(diagnosticsDescription),
@@ -32,27 +33,43 @@ func NewDiagnosticsTool(lspClients *csync.Map[string, *lsp.Client]) fantasy.Agen
 			if lspClients.Len() == 0 {
 				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
 			}
+			if params.FilePath != "" && IsContentExcluded(ctx, workingDir, params.FilePath) {
+				return contentExcludedResponse(params.FilePath), nil
+			}
 			notifyLSPs(ctx, lspClients, params.FilePath)
-			output := getDiagnostics(params.FilePath, lspClients)
+			output := getDiagnostics(params.FilePath, lspClients, contentExcludeFunc(ctx, workingDir))
 			return fantasy.NewTextResponse(output), nil
 		})
 }
 
+// diagnosticsTimeout is how long to wait for a server to publish the
+// diagnostics of a changed file.
+const diagnosticsTimeout = 5 * time.Second
+
+// notifyLSPs tells the servers handling the file about its new content, and
+// waits for them to publish its diagnostics.
 func notifyLSPs(ctx context.Context, lsps *csync.Map[string, *lsp.Client], filepath string) {
 	if filepath == "" {
 		return
 	}
+	var wg sync.WaitGroup
 	for client := range lsps.Seq() {
-		if !client.HandlesFile(filepath) {
+		if !client.HandlesFile(filepath) || client.GetServerState() != lsp.StateReady {
 			continue
 		}
-		_ = client.OpenFileOnDemand(ctx, filepath)
-		_ = client.NotifyChange(ctx, filepath)
-		client.WaitForDiagnostics(ctx, 5*time.Second)
+		wg.Go(func() {
+			version := client.DiagnosticsVersion()
+			_ = client.OpenFileOnDemand(ctx, filepath)
+			_ = client.NotifyChange(ctx, filepath)
+			client.WaitForFileDiagnostics(ctx, filepath, version, diagnosticsTimeout)
+		})
 	}
+	wg.Wait()
 }
 
-func getDiagnostics(filePath string, lsps *csync.Map[string, *lsp.Client]) string {
+// getDiagnostics lists the diagnostics of the file and of the project,
+// without those of the files exclude tells, when set.
+func getDiagnostics(filePath string, lsps *csync.Map[string, *lsp.Client], exclude func(path string) bool) string {
 	fileDiagnostics := []string{}
 	projectDiagnostics := []string{}
 
@@ -63,6 +80,9 @@ func getDiagnostics(filePath string, lsps *csync.Map[string, *lsp.Client]) strin
 				slog.Error("Failed to convert diagnostic location URI to path", "uri", location, "error", err)
 				continue
 			}
+			if exclude != nil && exclude(path) {
+				continue
+			}
 			isCurrentFile := path == filePath
 			for _, diag := range diags {
 				formattedDiag := formatDiagnostic(path, diag, lspName)
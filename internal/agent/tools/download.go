@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/filepathext"
 	"github.com/charmbracelet/crush/internal/permission"
 )
@@ -34,7 +36,7 @@ const DownloadToolName = "download"
 //go:embed download.md
 var downloadDescription []byte
 
-func NewDownloadTool(permissions permission.Service, workingDir string, client *http.Client) fantasy.AgentTool {
+func NewDownloadTool(permissions permission.Service, workingDir string, client *http.Client, fetchConfig config.ToolFetch) fantasy.AgentTool {
 	if client == nil {
 		client = &http.Client{
 			Timeout: 5 * time.Minute, // Default 5 minute timeout for downloads
@@ -45,6 +47,9 @@ func NewDownloadTool(permissions permission.Service, workingDir string, client *
 			},
 		}
 	}
+
+	client = restrictRedirects(client, fetchConfig)
+
 	return fantasy.NewAgentTool(
 		DownloadToolName,
 		string(downloadDescription),
@@ -61,7 +66,14 @@ func NewDownloadTool(permissions permission.Service, workingDir string, client *
 				return fantasy.NewTextErrorResponse("URL must start with http:// or https://"), nil
 			}
 
+			if err := CheckFetchURL(params.URL, fetchConfig); err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+
 			filePath := filepathext.SmartJoin(workingDir, params.FilePath)
+			if IsContentExcluded(ctx, workingDir, filePath) {
+				return contentExcludedResponse(filePath), nil
+			}
 			relPath, _ := filepath.Rel(workingDir, filePath)
 			relPath = filepath.ToSlash(cmp.Or(relPath, filePath))
 
@@ -105,6 +117,9 @@ func NewDownloadTool(permissions permission.Service, workingDir string, client *
 			req.Header.Set("User-Agent", "crush/1.0")
 
 			resp, err := client.Do(req)
+			if errors.Is(err, ErrFetchNotAllowed) {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("failed to download from URL: %w", err)
 			}
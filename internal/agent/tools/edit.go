@@ -39,6 +39,8 @@ type EditResponseMetadata struct {
 	Removals   int    `json:"removals"`
 	OldContent string `json:"old_content,omitempty"`
 	NewContent string `json:"new_content,omitempty"`
+	// MatchStrategy is how old_string was found in the file.
+	MatchStrategy string `json:"match_strategy,omitempty"`
 }
 
 const EditToolName = "edit"
@@ -63,6 +65,9 @@ func NewEditTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 			}
 
 			params.FilePath = filepathext.SmartJoin(workingDir, params.FilePath)
+			if IsContentExcluded(ctx, workingDir, params.FilePath) {
+				return contentExcludedResponse(params.FilePath), nil
+			}
 
 			var response fantasy.ToolResponse
 			var err error
@@ -96,7 +101,7 @@ func NewEditTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 			notifyLSPs(ctx, lspClients, params.FilePath)
 
 			text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-			text += getDiagnostics(params.FilePath, lspClients)
+			text += getDiagnostics(params.FilePath, lspClients, contentExcludeFunc(ctx, workingDir))
 			response.Content = text
 			return response, nil
 		})
@@ -166,8 +171,8 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 		slog.Error("Error creating file history version", "error", err)
 	}
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(sessionID, filePath)
+	recordFileRead(sessionID, filePath)
 
 	return fantasy.WithResponseMetadata(
 		fantasy.NewTextResponse("File created: "+filePath),
@@ -193,12 +198,12 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
 	}
 
-	if getLastReadTime(filePath).IsZero() {
+	if getLastReadTime(GetSessionFromContext(edit.ctx), filePath).IsZero() {
 		return fantasy.NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
 	}
 
 	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
+	lastRead := getLastReadTime(GetSessionFromContext(edit.ctx), filePath)
 	if modTime.After(lastRead) {
 		return fantasy.NewTextErrorResponse(
 			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
@@ -215,25 +220,21 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 
 	var newContent string
 	var deletionCount int
+	match := editMatch{strategy: editMatchExact}
 
 	if replaceAll {
 		newContent = strings.ReplaceAll(oldContent, oldString, "")
 		deletionCount = strings.Count(oldContent, oldString)
 		if deletionCount == 0 {
-			return fantasy.NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
+			return fantasy.NewTextErrorResponse(errEditMatchNotFound.Error()), nil
 		}
 	} else {
-		index := strings.Index(oldContent, oldString)
-		if index == -1 {
-			return fantasy.NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-		}
-
-		lastIndex := strings.LastIndex(oldContent, oldString)
-		if index != lastIndex {
-			return fantasy.NewTextErrorResponse("old_string appears multiple times in the file. Please provide more context to ensure a unique match, or set replace_all to true"), nil
+		match, err = findEditMatch(oldContent, oldString)
+		if err != nil {
+			return fantasy.NewTextErrorResponse(err.Error()), nil
 		}
 
-		newContent = oldContent[:index] + oldContent[index+len(oldString):]
+		newContent = match.replace(oldContent, "")
 		deletionCount = 1
 	}
 
@@ -299,16 +300,17 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 		slog.Error("Error creating file history version", "error", err)
 	}
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(sessionID, filePath)
+	recordFileRead(sessionID, filePath)
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse("Content deleted from file: "+filePath),
+		fantasy.NewTextResponse(withMatchNote("Content deleted from file: "+filePath, match)),
 		EditResponseMetadata{
-			OldContent: oldContent,
-			NewContent: newContent,
-			Additions:  additions,
-			Removals:   removals,
+			OldContent:    oldContent,
+			NewContent:    newContent,
+			Additions:     additions,
+			Removals:      removals,
+			MatchStrategy: string(match.strategy),
 		},
 	), nil
 }
@@ -326,12 +328,12 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
 	}
 
-	if getLastReadTime(filePath).IsZero() {
+	if getLastReadTime(GetSessionFromContext(edit.ctx), filePath).IsZero() {
 		return fantasy.NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
 	}
 
 	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
+	lastRead := getLastReadTime(GetSessionFromContext(edit.ctx), filePath)
 	if modTime.After(lastRead) {
 		return fantasy.NewTextErrorResponse(
 			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
@@ -348,25 +350,21 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 
 	var newContent string
 	var replacementCount int
+	match := editMatch{strategy: editMatchExact}
 
 	if replaceAll {
 		newContent = strings.ReplaceAll(oldContent, oldString, newString)
 		replacementCount = strings.Count(oldContent, oldString)
 		if replacementCount == 0 {
-			return fantasy.NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
+			return fantasy.NewTextErrorResponse(errEditMatchNotFound.Error()), nil
 		}
 	} else {
-		index := strings.Index(oldContent, oldString)
-		if index == -1 {
-			return fantasy.NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-		}
-
-		lastIndex := strings.LastIndex(oldContent, oldString)
-		if index != lastIndex {
-			return fantasy.NewTextErrorResponse("old_string appears multiple times in the file. Please provide more context to ensure a unique match, or set replace_all to true"), nil
+		match, err = findEditMatch(oldContent, oldString)
+		if err != nil {
+			return fantasy.NewTextErrorResponse(err.Error()), nil
 		}
 
-		newContent = oldContent[:index] + newString + oldContent[index+len(oldString):]
+		newContent = match.replace(oldContent, newString)
 		replacementCount = 1
 	}
 
@@ -434,15 +432,25 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 		slog.Error("Error creating file history version", "error", err)
 	}
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(sessionID, filePath)
+	recordFileRead(sessionID, filePath)
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse("Content replaced in file: "+filePath),
+		fantasy.NewTextResponse(withMatchNote("Content replaced in file: "+filePath, match)),
 		EditResponseMetadata{
-			OldContent: oldContent,
-			NewContent: newContent,
-			Additions:  additions,
-			Removals:   removals,
+			OldContent:    oldContent,
+			NewContent:    newContent,
+			Additions:     additions,
+			Removals:      removals,
+			MatchStrategy: string(match.strategy),
 		}), nil
 }
+
+// withMatchNote appends to message how old_string was found when it didn't
+// match exactly.
+func withMatchNote(message string, match editMatch) string {
+	if note := match.describe(); note != "" {
+		return message + "\n" + note
+	}
+	return message
+}
@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// editMatchStrategy is how old_string was found in the file being edited.
+type editMatchStrategy string
+
+const (
+	editMatchExact editMatchStrategy = "exact"
+	// editMatchWhitespace matches lines differing only in whitespace, like
+	// indentation.
+	editMatchWhitespace editMatchStrategy = "whitespace"
+	// editMatchFuzzy matches lines whose tokens are similar enough.
+	editMatchFuzzy editMatchStrategy = "fuzzy"
+)
+
+const (
+	// fuzzyMatchThreshold is the minimum similarity, between 0 and 1, of the
+	// lines matched by the fuzzy strategy to old_string.
+	fuzzyMatchThreshold = 0.9
+	// maxFuzzyMatchTokens bounds the size of the old_string the fuzzy
+	// strategy is tried with, as it's quadratic.
+	maxFuzzyMatchTokens = 1000
+)
+
+var (
+	errEditMatchNotFound  = errors.New("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks")
+	errEditMatchAmbiguous = errors.New("old_string appears multiple times in the file. Please provide more context to ensure a unique match, or set replace_all to true")
+)
+
+var tokenRe = regexp.MustCompile(`\w+|[^\w\s]`)
+
+// editMatch is the part of the content matching old_string.
+type editMatch struct {
+	start, end int
+	// startLine and endLine are the 1-based lines matched by the line
+	// strategies.
+	startLine, endLine int
+	strategy           editMatchStrategy
+	similarity         float64
+	// indent is the indentation of the matched lines, replacing the one of
+	// old_string in the new string.
+	indent, oldIndent string
+}
+
+// describe tells the model how old_string was found when it didn't match
+// exactly, as the text replaced differs from it.
+func (m editMatch) describe() string {
+	switch m.strategy {
+	case editMatchWhitespace:
+		return fmt.Sprintf("old_string matched lines %d-%d ignoring whitespace differences", m.startLine, m.endLine)
+	case editMatchFuzzy:
+		return fmt.Sprintf("old_string matched lines %d-%d approximately (%d%% similar), check the result", m.startLine, m.endLine, int(m.similarity*100))
+	}
+	return ""
+}
+
+// replace returns content with the match replaced by newString, indented
+// like the matched lines.
+func (m editMatch) replace(content, newString string) string {
+	if m.indent != m.oldIndent && newString != "" {
+		lines := strings.Split(newString, "\n")
+		for i, line := range lines {
+			if rest, ok := strings.CutPrefix(line, m.oldIndent); ok && line != "" {
+				lines[i] = m.indent + m.convertIndent(rest)
+			}
+		}
+		newString = strings.Join(lines, "\n")
+	}
+	return content[:m.start] + newString + content[m.end:]
+}
+
+// convertIndent converts the leading spaces of line to tabs, or the other way
+// around, when old_string and the file indent differently.
+func (m editMatch) convertIndent(line string) string {
+	lead := leadingWhitespace(line)
+	rest := line[len(lead):]
+	tabs, spaces := strings.Count(m.indent, "\t"), strings.Count(m.oldIndent, " ")
+	switch {
+	case tabs == len(m.indent) && spaces == len(m.oldIndent) && tabs > 0 && spaces%tabs == 0:
+		lead = strings.ReplaceAll(lead, strings.Repeat(" ", spaces/tabs), "\t")
+	case strings.Count(m.oldIndent, "\t") == len(m.oldIndent) && strings.Count(m.indent, " ") == len(m.indent) &&
+		len(m.oldIndent) > 0 && len(m.indent)%len(m.oldIndent) == 0:
+		lead = strings.ReplaceAll(lead, "\t", strings.Repeat(" ", len(m.indent)/len(m.oldIndent)))
+	}
+	return lead + rest
+}
+
+type lineSpan struct {
+	start, end int
+}
+
+// findEditMatch finds the unique occurrence of oldString in content. When
+// there's no exact match, it falls back to lines differing only in
+// whitespace, and then to lines similar enough to oldString.
+func findEditMatch(content, oldString string) (editMatch, error) {
+	if index := strings.Index(content, oldString); index != -1 {
+		if strings.LastIndex(content, oldString) != index {
+			return editMatch{}, errEditMatchAmbiguous
+		}
+		return editMatch{
+			start:      index,
+			end:        index + len(oldString),
+			strategy:   editMatchExact,
+			similarity: 1,
+		}, nil
+	}
+
+	oldLines := strings.Split(strings.TrimSuffix(oldString, "\n"), "\n")
+	if strings.TrimSpace(oldString) == "" {
+		return editMatch{}, errEditMatchNotFound
+	}
+
+	var spans []lineSpan
+	var lines []string
+	for start := 0; start <= len(content); {
+		end := strings.IndexByte(content[start:], '\n')
+		if end == -1 {
+			end = len(content)
+		} else {
+			end += start
+		}
+		spans = append(spans, lineSpan{start, end})
+		lines = append(lines, content[start:end])
+		start = end + 1
+	}
+	if len(oldLines) > len(lines) {
+		return editMatch{}, errEditMatchNotFound
+	}
+
+	newMatch := func(first int, strategy editMatchStrategy, similarity float64) editMatch {
+		last := first + len(oldLines) - 1
+		m := editMatch{
+			start:      spans[first].start,
+			end:        spans[last].end,
+			startLine:  first + 1,
+			endLine:    last + 1,
+			strategy:   strategy,
+			similarity: similarity,
+		}
+		if strings.HasSuffix(oldString, "\n") && m.end < len(content) {
+			m.end++
+		}
+		for i, line := range oldLines {
+			if strings.TrimSpace(line) != "" {
+				m.oldIndent = leadingWhitespace(line)
+				m.indent = leadingWhitespace(lines[first+i])
+				break
+			}
+		}
+		return m
+	}
+
+	normalizedOld := make([]string, len(oldLines))
+	for i, line := range oldLines {
+		normalizedOld[i] = normalizeWhitespace(line)
+	}
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = normalizeWhitespace(line)
+	}
+	var matches []int
+	for i := 0; i+len(oldLines) <= len(lines); i++ {
+		if slices.Equal(normalized[i:i+len(oldLines)], normalizedOld) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+	case 1:
+		return newMatch(matches[0], editMatchWhitespace, 1), nil
+	default:
+		return editMatch{}, errEditMatchAmbiguous
+	}
+
+	oldTokens := tokenRe.FindAllString(oldString, -1)
+	if len(oldTokens) == 0 || len(oldTokens) > maxFuzzyMatchTokens {
+		return editMatch{}, errEditMatchNotFound
+	}
+	lineTokens := make([][]string, len(lines))
+	for i, line := range lines {
+		lineTokens[i] = tokenRe.FindAllString(line, -1)
+	}
+	best, bestSimilarity := -1, 0.0
+	var candidates []int
+	for i := 0; i+len(oldLines) <= len(lines); i++ {
+		var tokens []string
+		for _, lt := range lineTokens[i : i+len(oldLines)] {
+			tokens = append(tokens, lt...)
+		}
+		// Counting common tokens is cheap, and bounds the similarity.
+		if bagSimilarity(oldTokens, tokens) < fuzzyMatchThreshold {
+			continue
+		}
+		similarity := sequenceSimilarity(oldTokens, tokens)
+		if similarity < fuzzyMatchThreshold {
+			continue
+		}
+		candidates = append(candidates, i)
+		if similarity > bestSimilarity {
+			best, bestSimilarity = i, similarity
+		}
+	}
+	if best == -1 {
+		return editMatch{}, errEditMatchNotFound
+	}
+	// Windows overlapping the best one are the same place, shifted.
+	for _, i := range candidates {
+		if i <= best-len(oldLines) || i >= best+len(oldLines) {
+			return editMatch{}, errEditMatchAmbiguous
+		}
+	}
+	return newMatch(best, editMatchFuzzy, bestSimilarity), nil
+}
+
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func leadingWhitespace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// bagSimilarity is the Dice coefficient of the tokens, regardless of their
+// order. It's never lower than their sequenceSimilarity.
+func bagSimilarity(a, b []string) float64 {
+	counts := make(map[string]int, len(a))
+	for _, t := range a {
+		counts[t]++
+	}
+	common := 0
+	for _, t := range b {
+		if counts[t] > 0 {
+			counts[t]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// sequenceSimilarity is the Dice coefficient of the longest common
+// subsequence of the tokens.
+func sequenceSimilarity(a, b []string) float64 {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(b)]) / float64(len(a)+len(b))
+}
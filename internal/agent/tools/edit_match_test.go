@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindEditMatch(t *testing.T) {
+	t.Parallel()
+
+	content := "func main() {\n\tif ok {\n\t\tfmt.Println(\"hello\")\n\t}\n}\n"
+
+	t.Run("exact", func(t *testing.T) {
+		t.Parallel()
+		match, err := findEditMatch(content, "fmt.Println(\"hello\")")
+		require.NoError(t, err)
+		require.Equal(t, editMatchExact, match.strategy)
+		require.Empty(t, match.describe())
+		require.Equal(t, "func main() {\n\tif ok {\n\t\tfmt.Println(\"bye\")\n\t}\n}\n", match.replace(content, "fmt.Println(\"bye\")"))
+	})
+
+	t.Run("whitespace", func(t *testing.T) {
+		t.Parallel()
+		match, err := findEditMatch(content, "    if ok {\n        fmt.Println(\"hello\")\n    }\n")
+		require.NoError(t, err)
+		require.Equal(t, editMatchWhitespace, match.strategy)
+		require.Equal(t, "old_string matched lines 2-4 ignoring whitespace differences", match.describe())
+		// The new string is indented like the file.
+		require.Equal(t,
+			"func main() {\n\tif !ok {\n\t\tfmt.Println(\"bye\")\n\t}\n}\n",
+			match.replace(content, "    if !ok {\n        fmt.Println(\"bye\")\n    }\n"),
+		)
+	})
+
+	t.Run("fuzzy", func(t *testing.T) {
+		t.Parallel()
+		content := "func greet() {\n\tname := \"world\"\n\tfmt.Printf(\"hello, %s and all the others around here\\n\", name)\n\treturn\n}\n"
+		match, err := findEditMatch(content, "\tname := \"world\"\n\tfmt.Printf(\"hello, %s and all the others around there\\n\", name)\n")
+		require.NoError(t, err)
+		require.Equal(t, editMatchFuzzy, match.strategy)
+		require.Equal(t, 2, match.startLine)
+		require.Equal(t, 3, match.endLine)
+		require.GreaterOrEqual(t, match.similarity, fuzzyMatchThreshold)
+		require.Equal(t, "func greet() {\n\tname := \"you\"\n\treturn\n}\n", match.replace(content, "\tname := \"you\"\n"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		_, err := findEditMatch(content, "something else entirely")
+		require.ErrorIs(t, err, errEditMatchNotFound)
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		t.Parallel()
+		_, err := findEditMatch("a()\n  b()\na()\n\tb()\n", "a()\nb()")
+		require.ErrorIs(t, err, errEditMatchAmbiguous)
+	})
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"charm.land/fantasy"
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
@@ -21,7 +23,7 @@ const FetchToolName = "fetch"
 //go:embed fetch.md
 var fetchDescription []byte
 
-func NewFetchTool(permissions permission.Service, workingDir string, client *http.Client) fantasy.AgentTool {
+func NewFetchTool(permissions permission.Service, workingDir string, client *http.Client, fetchConfig config.ToolFetch) fantasy.AgentTool {
 	if client == nil {
 		client = &http.Client{
 			Timeout: 30 * time.Second,
@@ -33,6 +35,8 @@ func NewFetchTool(permissions permission.Service, workingDir string, client *htt
 		}
 	}
 
+	client = restrictRedirects(client, fetchConfig)
+
 	return fantasy.NewAgentTool(
 		FetchToolName,
 		string(fetchDescription),
@@ -50,6 +54,10 @@ func NewFetchTool(permissions permission.Service, workingDir string, client *htt
 				return fantasy.NewTextErrorResponse("URL must start with http:// or https://"), nil
 			}
 
+			if err := CheckFetchURL(params.URL, fetchConfig); err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+
 			sessionID := GetSessionFromContext(ctx)
 			if sessionID == "" {
 				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for creating a new file")
@@ -91,6 +99,9 @@ func NewFetchTool(permissions permission.Service, workingDir string, client *htt
 			req.Header.Set("User-Agent", "crush/1.0")
 
 			resp, err := client.Do(req)
+			if errors.Is(err, ErrFetchNotAllowed) {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("failed to fetch URL: %w", err)
 			}
@@ -117,7 +128,11 @@ func NewFetchTool(permissions permission.Service, workingDir string, client *htt
 			switch format {
 			case "text":
 				if strings.Contains(contentType, "text/html") {
-					text, err := extractTextFromHTML(content)
+					readable, err := readableHTML(content)
+					if err != nil {
+						return fantasy.NewTextErrorResponse("Failed to parse HTML: " + err.Error()), nil
+					}
+					text, err := extractTextFromHTML(readable)
 					if err != nil {
 						return fantasy.NewTextErrorResponse("Failed to extract text from HTML: " + err.Error()), nil
 					}
@@ -126,15 +141,17 @@ func NewFetchTool(permissions permission.Service, workingDir string, client *htt
 
 			case "markdown":
 				if strings.Contains(contentType, "text/html") {
-					markdown, err := convertHTMLToMarkdown(content)
+					readable, err := readableHTML(content)
+					if err != nil {
+						return fantasy.NewTextErrorResponse("Failed to parse HTML: " + err.Error()), nil
+					}
+					markdown, err := convertHTMLToMarkdown(readable)
 					if err != nil {
 						return fantasy.NewTextErrorResponse("Failed to convert HTML to Markdown: " + err.Error()), nil
 					}
 					content = markdown
 				}
 
-				content = "```\n" + content + "\n```"
-
 			case "html":
 				// return only the body of the HTML document
 				if strings.Contains(contentType, "text/html") {
@@ -152,11 +169,10 @@ func NewFetchTool(permissions permission.Service, workingDir string, client *htt
 					content = "<html>\n<body>\n" + body + "\n</body>\n</html>"
 				}
 			}
-			// calculate byte size of content
-			contentSize := int64(len(content))
-			if contentSize > MaxReadSize {
-				content = content[:MaxReadSize]
-				content += fmt.Sprintf("\n\n[Content truncated to %d bytes]", MaxReadSize)
+
+			content = truncateToTokens(content, fetchConfig.MaxTokensOrDefault())
+			if format == "markdown" {
+				content = "```\n" + content + "\n```"
 			}
 
 			return fantasy.NewTextResponse(content), nil
@@ -8,14 +8,66 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"unicode/utf8"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/crush/internal/config"
 )
 
-// FetchURLAndConvert fetches a URL and converts HTML content to markdown.
-func FetchURLAndConvert(ctx context.Context, client *http.Client, url string) (string, error) {
+// boilerplateSelector matches the parts of a page around its content, like
+// scripts, navigation and forms.
+const boilerplateSelector = "script, style, noscript, template, iframe, svg, canvas, form, nav, aside, " +
+	"[role=navigation], [role=banner], [role=contentinfo], [role=complementary], [aria-hidden=true]"
+
+// ErrFetchNotAllowed is returned for the hosts the configuration doesn't
+// allow fetching from.
+var ErrFetchNotAllowed = errors.New("not allowed by the configuration")
+
+// CheckFetchURL returns an error when the fetch tools may not access rawURL.
+func CheckFetchURL(rawURL string, fetchConfig config.ToolFetch) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if !fetchConfig.AllowsHost(u.Hostname()) {
+		return fmt.Errorf("fetching from %s is %w", u.Hostname(), ErrFetchNotAllowed)
+	}
+	return nil
+}
+
+// maxRedirects is the number of redirects followed, like the default of
+// net/http.
+const maxRedirects = 10
+
+// restrictRedirects returns a copy of client that refuses to follow the
+// redirects to the hosts fetchConfig doesn't allow, before requesting them.
+func restrictRedirects(client *http.Client, fetchConfig config.ToolFetch) *http.Client {
+	restricted := *client
+	restricted.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := CheckFetchURL(req.URL.String(), fetchConfig); err != nil {
+			return err
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+	return &restricted
+}
+
+// FetchURLAndConvert fetches a URL and converts HTML content to markdown,
+// leaving out the boilerplate around the main content.
+func FetchURLAndConvert(ctx context.Context, client *http.Client, url string, fetchConfig config.ToolFetch) (string, error) {
+	if err := CheckFetchURL(url, fetchConfig); err != nil {
+		return "", err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
@@ -23,7 +75,7 @@ func FetchURLAndConvert(ctx context.Context, client *http.Client, url string) (s
 
 	req.Header.Set("User-Agent", "crush/1.0")
 
-	resp, err := client.Do(req)
+	resp, err := restrictRedirects(client, fetchConfig).Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch URL: %w", err)
 	}
@@ -49,7 +101,11 @@ func FetchURLAndConvert(ctx context.Context, client *http.Client, url string) (s
 
 	// Convert HTML to markdown for better AI processing.
 	if strings.Contains(contentType, "text/html") {
-		markdown, err := ConvertHTMLToMarkdown(content)
+		readable, err := readableHTML(content)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		markdown, err := ConvertHTMLToMarkdown(readable)
 		if err != nil {
 			return "", fmt.Errorf("failed to convert HTML to markdown: %w", err)
 		}
@@ -66,6 +122,49 @@ func FetchURLAndConvert(ctx context.Context, client *http.Client, url string) (s
 	return content, nil
 }
 
+// readableHTML strips the boilerplate of an HTML page, returning the HTML of
+// its main content: the main or article element when there is one, the body
+// otherwise.
+func readableHTML(html string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", err
+	}
+	doc.Find(boilerplateSelector).Remove()
+	// Headers and footers of the page, unlike those of an article, are
+	// boilerplate too.
+	doc.Find("header, footer").Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("main, article, [role=main]").Length() == 0 {
+			s.Remove()
+		}
+	})
+
+	content := doc.Find("main, [role=main]").First()
+	if content.Length() == 0 {
+		content = doc.Find("article").First()
+	}
+	if content.Length() == 0 {
+		content = doc.Find("body")
+	}
+	return content.Html()
+}
+
+// truncateToTokens cuts content to about maxTokens tokens, at a line break
+// when there is one near the cut.
+func truncateToTokens(content string, maxTokens int) string {
+	// Roughly 4 characters per token.
+	maxBytes := maxTokens * 4
+	if len(content) <= maxBytes {
+		return content
+	}
+	cut := content[:maxBytes]
+	if i := strings.LastIndexByte(cut, '\n'); i > maxBytes/2 {
+		cut = cut[:i]
+	}
+	cut = strings.ToValidUTF8(cut, "")
+	return cut + fmt.Sprintf("\n\n[Content truncated to about %d tokens]", maxTokens)
+}
+
 // ConvertHTMLToMarkdown converts HTML content to markdown format.
 func ConvertHTMLToMarkdown(html string) (string, error) {
 	converter := md.NewConverter("", true, nil)
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFetchURL(t *testing.T) {
+	t.Parallel()
+
+	fetchConfig := config.ToolFetch{
+		AllowedDomains: []string{"go.dev", "github.com"},
+		BlockedDomains: []string{"gist.github.com"},
+	}
+	require.NoError(t, CheckFetchURL("https://go.dev/doc", fetchConfig))
+	require.NoError(t, CheckFetchURL("https://pkg.go.dev/net/http", fetchConfig))
+	require.NoError(t, CheckFetchURL("https://GitHub.com/charmbracelet", fetchConfig))
+	require.Error(t, CheckFetchURL("https://gist.github.com/someone", fetchConfig))
+	require.Error(t, CheckFetchURL("https://notgo.dev", fetchConfig))
+	require.Error(t, CheckFetchURL("https://example.com", fetchConfig))
+
+	require.NoError(t, CheckFetchURL("https://example.com", config.ToolFetch{}))
+}
+
+func TestReadableHTML(t *testing.T) {
+	t.Parallel()
+
+	page := `<html><head><style>body {}</style></head><body>
+<header><nav><a href="/">Home</a></nav></header>
+<main><article><header><h1>Title</h1></header><p>Content</p></article></main>
+<aside>Related</aside>
+<footer>Copyright</footer>
+<script>track()</script>
+</body></html>`
+	readable, err := readableHTML(page)
+	require.NoError(t, err)
+	require.Contains(t, readable, "<h1>Title</h1>")
+	require.Contains(t, readable, "<p>Content</p>")
+	for _, boilerplate := range []string{"Home", "Related", "Copyright", "track()"} {
+		require.NotContains(t, readable, boilerplate)
+	}
+
+	readable, err = readableHTML(`<html><body><nav>Menu</nav><p>Text</p></body></html>`)
+	require.NoError(t, err)
+	require.Equal(t, "<p>Text</p>", readable)
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "short", truncateToTokens("short", 10))
+
+	content := strings.Repeat("line of text\n", 10)
+	truncated := truncateToTokens(content, 10)
+	require.True(t, strings.HasPrefix(truncated, "line of text\nline of text\nline of text"))
+	require.True(t, strings.HasSuffix(truncated, "line of text\n\n[Content truncated to about 10 tokens]"))
+	require.Less(t, len(truncated), len(content))
+}
+
+func TestFetchRedirectToBlockedHost(t *testing.T) {
+	t.Parallel()
+
+	var reached atomic.Bool
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached.Store(true)
+	}))
+	defer blocked.Close()
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(blocked.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	fetchConfig := config.ToolFetch{BlockedDomains: []string{"localhost"}}
+	_, err := FetchURLAndConvert(t.Context(), redirecting.Client(), redirecting.URL, fetchConfig)
+	require.ErrorIs(t, err, ErrFetchNotAllowed)
+	require.False(t, reached.Load(), "the blocked host must never be requested")
+}
+
+func TestDownloadBlockedHost(t *testing.T) {
+	t.Parallel()
+
+	var reached atomic.Bool
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached.Store(true)
+	}))
+	defer blocked.Close()
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(blocked.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	workingDir := t.TempDir()
+	permissions := &mockPermissionService{Broker: pubsub.NewBroker[permission.PermissionRequest]()}
+	fetchConfig := config.ToolFetch{BlockedDomains: []string{"localhost"}}
+	tool := NewDownloadTool(permissions, workingDir, redirecting.Client(), fetchConfig)
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+
+	for _, url := range []string{
+		strings.Replace(blocked.URL, "127.0.0.1", "localhost", 1),
+		redirecting.URL,
+	} {
+		input, err := json.Marshal(DownloadParams{URL: url, FilePath: "file"})
+		require.NoError(t, err)
+		resp, err := tool.Run(ctx, fantasy.ToolCall{ID: "call", Name: DownloadToolName, Input: string(input)})
+		require.NoError(t, err)
+		require.True(t, resp.IsError, url)
+		require.Contains(t, resp.Content, "not allowed by the configuration")
+	}
+	require.False(t, reached.Load(), "the blocked host must never be requested")
+	require.NoFileExists(t, filepath.Join(workingDir, "file"))
+}
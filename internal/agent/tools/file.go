@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -10,44 +11,63 @@ type fileRecord struct {
 	path      string
 	readTime  time.Time
 	writeTime time.Time
+	// changeTime is when the file watcher last saw the file change outside
+	// of Crush.
+	changeTime time.Time
 }
 
+// fileRecords are the files read and written by the tools, by session, so
+// each session is told only about the files it read going stale.
 var (
-	fileRecords     = make(map[string]fileRecord)
+	fileRecords     = make(map[string]map[string]fileRecord)
 	fileRecordMutex sync.RWMutex
 )
 
-func recordFileRead(path string) {
-	fileRecordMutex.Lock()
-	defer fileRecordMutex.Unlock()
-
-	record, exists := fileRecords[path]
-	if !exists {
-		record = fileRecord{path: path}
-	}
-	record.readTime = time.Now()
-	fileRecords[path] = record
+func recordFileRead(sessionID, path string) {
+	updateFileRecord(sessionID, path, func(record *fileRecord) {
+		record.readTime = time.Now()
+	})
 }
 
-func getLastReadTime(path string) time.Time {
+func getLastReadTime(sessionID, path string) time.Time {
 	fileRecordMutex.RLock()
 	defer fileRecordMutex.RUnlock()
 
-	record, exists := fileRecords[path]
+	record, exists := fileRecords[sessionID][filepath.Clean(path)]
 	if !exists {
 		return time.Time{}
 	}
 	return record.readTime
 }
 
-func recordFileWrite(path string) {
-	fileRecordMutex.Lock()
-	defer fileRecordMutex.Unlock()
+func recordFileWrite(sessionID, path string) {
+	updateFileRecord(sessionID, path, func(record *fileRecord) {
+		record.writeTime = time.Now()
+	})
+}
 
-	record, exists := fileRecords[path]
+func updateFileRecord(sessionID, path string, update func(record *fileRecord)) {
+	path = filepath.Clean(path)
+	fileRecordMutex.Lock()
+	records, ok := fileRecords[sessionID]
+	if !ok {
+		records = make(map[string]fileRecord)
+		fileRecords[sessionID] = records
+	}
+	record, exists := records[path]
 	if !exists {
 		record = fileRecord{path: path}
 	}
-	record.writeTime = time.Now()
-	fileRecords[path] = record
+	update(&record)
+	records[path] = record
+	fileRecordMutex.Unlock()
+
+	watchFileDir(path)
+}
+
+// ForgetFiles forgets the files read and written in a session.
+func ForgetFiles(sessionID string) {
+	fileRecordMutex.Lock()
+	defer fileRecordMutex.Unlock()
+	delete(fileRecords, sessionID)
 }
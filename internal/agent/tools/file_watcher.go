@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	fileWatcher   *fsnotify.Watcher
+	watchedDirs   = make(map[string]bool)
+	fileWatcherMu sync.Mutex
+)
+
+// StartFileWatcher watches the directories of the files read by the tools, to
+// notice when they change outside of Crush. onChange is called with every
+// such file. The returned function stops the watcher.
+func StartFileWatcher(onChange func(path string)) (func() error, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	fileWatcherMu.Lock()
+	fileWatcher = w
+	clear(watchedDirs)
+	fileWatcherMu.Unlock()
+
+	// Watch the files read before the watcher started.
+	fileRecordMutex.RLock()
+	var paths []string
+	for _, records := range fileRecords {
+		paths = append(paths, slices.Collect(maps.Keys(records))...)
+	}
+	fileRecordMutex.RUnlock()
+	for _, path := range paths {
+		watchFileDir(path)
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				path := filepath.Clean(event.Name)
+				if markFileChanged(path) && onChange != nil {
+					onChange(path)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("File watcher error", "error", err)
+			}
+		}
+	}()
+
+	return func() error {
+		fileWatcherMu.Lock()
+		defer fileWatcherMu.Unlock()
+		if fileWatcher == w {
+			fileWatcher = nil
+		}
+		return w.Close()
+	}, nil
+}
+
+// watchFileDir watches the directory of a file, as editors often replace
+// files instead of writing them.
+func watchFileDir(path string) {
+	fileWatcherMu.Lock()
+	defer fileWatcherMu.Unlock()
+	if fileWatcher == nil {
+		return
+	}
+	dir := filepath.Dir(path)
+	if watchedDirs[dir] {
+		return
+	}
+	if err := fileWatcher.Add(dir); err != nil {
+		slog.Debug("Failed to watch directory", "dir", dir, "error", err)
+		return
+	}
+	watchedDirs[dir] = true
+}
+
+// markFileChanged records that a file read or written by the tools changed,
+// in every session but the ones whose writes the change is. It reports
+// whether it did in any.
+func markFileChanged(path string) bool {
+	fileRecordMutex.Lock()
+	defer fileRecordMutex.Unlock()
+
+	info, statErr := os.Stat(path)
+	changed := false
+	for _, records := range fileRecords {
+		record, exists := records[path]
+		if !exists {
+			continue
+		}
+		lastSeen := record.readTime
+		if record.writeTime.After(lastSeen) {
+			lastSeen = record.writeTime
+		}
+		if statErr == nil && !info.ModTime().After(lastSeen) {
+			continue
+		}
+		record.changeTime = time.Now()
+		records[path] = record
+		changed = true
+	}
+	return changed
+}
+
+// StaleFiles returns the files that changed outside of the session since its
+// tools last read or wrote them.
+func StaleFiles(sessionID string) []string {
+	fileRecordMutex.RLock()
+	defer fileRecordMutex.RUnlock()
+
+	var stale []string
+	for path, record := range fileRecords[sessionID] {
+		if record.readTime.IsZero() {
+			continue
+		}
+		if record.changeTime.After(record.readTime) && record.changeTime.After(record.writeTime) {
+			stale = append(stale, path)
+		}
+	}
+	slices.Sort(stale)
+	return stale
+}
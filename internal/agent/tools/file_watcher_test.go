@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWatcher(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	readFile := filepath.Join(dir, "read.txt")
+	writtenFile := filepath.Join(dir, "written.txt")
+	for _, path := range []string{readFile, writtenFile} {
+		require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+	}
+
+	changed := make(chan string, 100)
+	stop, err := StartFileWatcher(func(path string) {
+		select {
+		case changed <- path:
+		default:
+		}
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stop() })
+
+	const session, other = "file-watcher", "file-watcher-other"
+	t.Cleanup(func() {
+		ForgetFiles(session)
+		ForgetFiles(other)
+	})
+	recordFileRead(session, readFile)
+	recordFileRead(session, writtenFile)
+	recordFileRead(other, writtenFile)
+	require.NotContains(t, StaleFiles(session), readFile)
+
+	// Make sure the modification times are past the reads.
+	time.Sleep(10 * time.Millisecond)
+
+	// Writes by the tools don't make the file stale.
+	require.NoError(t, os.WriteFile(writtenFile, []byte("new\n"), 0o644))
+	recordFileWrite(session, writtenFile)
+
+	require.NoError(t, os.WriteFile(readFile, []byte("new\n"), 0o644))
+
+	require.Eventually(t, func() bool {
+		return slices.Contains(StaleFiles(session), readFile)
+	}, 5*time.Second, 10*time.Millisecond)
+	for path := range changed {
+		if path == readFile {
+			break
+		}
+	}
+	require.NotContains(t, StaleFiles(session), writtenFile)
+	require.Eventually(t, func() bool {
+		return slices.Contains(StaleFiles(other), writtenFile)
+	}, 5*time.Second, 10*time.Millisecond, "the writes of a session are changes for the others")
+	require.NotContains(t, StaleFiles(other), readFile, "only the files a session read go stale for it")
+
+	// Reading the file again makes it fresh.
+	recordFileRead(session, readFile)
+	require.NotContains(t, StaleFiles(session), readFile)
+
+	ForgetFiles(session)
+	require.Empty(t, StaleFiles(session))
+}
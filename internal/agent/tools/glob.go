@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -48,6 +49,10 @@ func NewGlobTool(workingDir string) fantasy.AgentTool {
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error finding files: %w", err)
 			}
+			crushIgnore := fsext.NewCrushIgnore(workingDir)
+			files = slices.DeleteFunc(files, func(path string) bool {
+				return IsContentExcluded(ctx, workingDir, path) || crushIgnore.Ignores(path)
+			})
 
 			var output string
 			if len(files) == 0 {
@@ -13,6 +13,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -73,10 +74,11 @@ var (
 )
 
 type GrepParams struct {
-	Pattern     string `json:"pattern" description:"The regex pattern to search for in file contents"`
-	Path        string `json:"path,omitempty" description:"The directory to search in. Defaults to the current working directory."`
-	Include     string `json:"include,omitempty" description:"File pattern to include in the search (e.g. \"*.js\", \"*.{ts,tsx}\")"`
-	LiteralText bool   `json:"literal_text,omitempty" description:"If true, the pattern will be treated as literal text with special regex characters escaped. Default is false."`
+	Pattern      string `json:"pattern" description:"The regex pattern to search for in file contents"`
+	Path         string `json:"path,omitempty" description:"The directory to search in. Defaults to the current working directory."`
+	Include      string `json:"include,omitempty" description:"File pattern to include in the search (e.g. \"*.js\", \"*.{ts,tsx}\")"`
+	LiteralText  bool   `json:"literal_text,omitempty" description:"If true, the pattern will be treated as literal text with special regex characters escaped. Default is false."`
+	ContextLines int    `json:"context_lines,omitempty" description:"Number of lines to show before and after each match (max 10). Default is 0."`
 }
 
 type grepMatch struct {
@@ -85,6 +87,9 @@ type grepMatch struct {
 	lineNum  int
 	charNum  int
 	lineText string
+	// before and after are the context lines around the match, which may
+	// include other matches.
+	before, after []string
 }
 
 type GrepResponseMetadata struct {
@@ -95,6 +100,7 @@ type GrepResponseMetadata struct {
 const (
 	GrepToolName        = "grep"
 	maxGrepContentWidth = 500
+	maxGrepContextLines = 10
 )
 
 //go:embed grep.md
@@ -132,10 +138,15 @@ func NewGrepTool(workingDir string) fantasy.AgentTool {
 				searchPath = workingDir
 			}
 
-			matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, 100)
+			contextLines := min(max(params.ContextLines, 0), maxGrepContextLines)
+			matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, contextLines, 100)
 			if err != nil {
 				return fantasy.NewTextErrorResponse(fmt.Sprintf("error searching files: %v", err)), nil
 			}
+			crushIgnore := fsext.NewCrushIgnore(workingDir)
+			matches = slices.DeleteFunc(matches, func(m grepMatch) bool {
+				return IsContentExcluded(ctx, workingDir, m.path) || crushIgnore.Ignores(m.path)
+			})
 
 			var output strings.Builder
 			if len(matches) == 0 {
@@ -144,24 +155,43 @@ func NewGrepTool(workingDir string) fantasy.AgentTool {
 				fmt.Fprintf(&output, "Found %d matches\n", len(matches))
 
 				currentFile := ""
-				for _, match := range matches {
+				// lastLine is the last line of the current file written, so
+				// that overlapping context isn't repeated.
+				lastLine := 0
+				for i, match := range matches {
 					if currentFile != match.path {
 						if currentFile != "" {
 							output.WriteString("\n")
 						}
 						currentFile = match.path
+						lastLine = 0
 						fmt.Fprintf(&output, "%s:\n", filepath.ToSlash(match.path))
 					}
 					if match.lineNum > 0 {
-						lineText := match.lineText
-						if len(lineText) > maxGrepContentWidth {
-							lineText = lineText[:maxGrepContentWidth] + "..."
+						first := match.lineNum - len(match.before)
+						if contextLines > 0 && lastLine > 0 && first > lastLine+1 {
+							output.WriteString("  --\n")
+						}
+						for j, text := range match.before {
+							if first+j > lastLine {
+								fmt.Fprintf(&output, "  Line %d- %s\n", first+j, formatGrepLine(text, contextLines))
+							}
 						}
+						lineText := formatGrepLine(match.lineText, contextLines)
 						if match.charNum > 0 {
 							fmt.Fprintf(&output, "  Line %d, Char %d: %s\n", match.lineNum, match.charNum, lineText)
 						} else {
 							fmt.Fprintf(&output, "  Line %d: %s\n", match.lineNum, lineText)
 						}
+						lastLine = match.lineNum
+						for j, text := range match.after {
+							// The next match of the file writes its own line.
+							if i+1 < len(matches) && matches[i+1].path == match.path && matches[i+1].lineNum <= lastLine+1 {
+								break
+							}
+							fmt.Fprintf(&output, "  Line %d- %s\n", match.lineNum+1+j, formatGrepLine(text, contextLines))
+							lastLine = match.lineNum + 1 + j
+						}
 					} else {
 						fmt.Fprintf(&output, "  %s\n", match.path)
 					}
@@ -182,16 +212,31 @@ func NewGrepTool(workingDir string) fantasy.AgentTool {
 		})
 }
 
-func searchFiles(ctx context.Context, pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
-	matches, err := searchWithRipgrep(ctx, pattern, rootPath, include)
+// formatGrepLine trims a matched or context line for the output. Indentation
+// is kept when there's context, as it shows the structure of the code.
+func formatGrepLine(text string, contextLines int) string {
+	if contextLines > 0 {
+		text = strings.TrimRight(text, " \t\r\n")
+	} else {
+		text = strings.TrimSpace(text)
+	}
+	if len(text) > maxGrepContentWidth {
+		text = text[:maxGrepContentWidth] + "..."
+	}
+	return text
+}
+
+func searchFiles(ctx context.Context, pattern, rootPath, include string, contextLines, limit int) ([]grepMatch, bool, error) {
+	matches, err := searchWithRipgrep(ctx, pattern, rootPath, include, contextLines)
 	if err != nil {
-		matches, err = searchFilesWithRegex(pattern, rootPath, include)
+		matches, err = searchFilesWithRegex(pattern, rootPath, include, contextLines)
 		if err != nil {
 			return nil, false, err
 		}
 	}
 
-	sort.Slice(matches, func(i, j int) bool {
+	// Matches of the same file stay in line order.
+	sort.SliceStable(matches, func(i, j int) bool {
 		return matches[i].modTime.After(matches[j].modTime)
 	})
 
@@ -203,8 +248,8 @@ func searchFiles(ctx context.Context, pattern, rootPath, include string, limit i
 	return matches, truncated, nil
 }
 
-func searchWithRipgrep(ctx context.Context, pattern, path, include string) ([]grepMatch, error) {
-	cmd := getRgSearchCmd(ctx, pattern, path, include)
+func searchWithRipgrep(ctx context.Context, pattern, path, include string, contextLines int) ([]grepMatch, error) {
+	cmd := getRgSearchCmd(ctx, pattern, path, include, contextLines)
 	if cmd == nil {
 		return nil, fmt.Errorf("ripgrep not found in $PATH")
 	}
@@ -226,6 +271,10 @@ func searchWithRipgrep(ctx context.Context, pattern, path, include string) ([]gr
 	}
 
 	var matches []grepMatch
+	// fileLines holds the matched and context lines of the current file, by
+	// line number.
+	fileLines := make(map[int]string)
+	fileStart := 0
 	for line := range bytes.SplitSeq(bytes.TrimSpace(output), []byte{'\n'}) {
 		if len(line) == 0 {
 			continue
@@ -234,9 +283,21 @@ func searchWithRipgrep(ctx context.Context, pattern, path, include string) ([]gr
 		if err := json.Unmarshal(line, &match); err != nil {
 			continue
 		}
+		switch match.Type {
+		case "begin":
+			clear(fileLines)
+			fileStart = len(matches)
+		case "context":
+			fileLines[match.Data.LineNumber] = match.Data.Lines.Text
+		case "end":
+			if contextLines > 0 {
+				addContextLines(matches[fileStart:], fileLines, contextLines)
+			}
+		}
 		if match.Type != "match" {
 			continue
 		}
+		fileLines[match.Data.LineNumber] = match.Data.Lines.Text
 		for _, m := range match.Data.Submatches {
 			fi, err := os.Stat(match.Data.Path.Text)
 			if err != nil {
@@ -247,7 +308,7 @@ func searchWithRipgrep(ctx context.Context, pattern, path, include string) ([]gr
 				modTime:  fi.ModTime(),
 				lineNum:  match.Data.LineNumber,
 				charNum:  m.Start + 1, // ensure 1-based
-				lineText: strings.TrimSpace(match.Data.Lines.Text),
+				lineText: strings.TrimRight(match.Data.Lines.Text, "\r\n"),
 			})
 			// only get the first match of each line
 			break
@@ -256,6 +317,33 @@ func searchWithRipgrep(ctx context.Context, pattern, path, include string) ([]gr
 	return matches, nil
 }
 
+// addContextLines sets the lines around the matches of a file, from the
+// lines known by number.
+func addContextLines(matches []grepMatch, lines map[int]string, contextLines int) {
+	for i := range matches {
+		m := &matches[i]
+		first := m.lineNum
+		for first > max(1, m.lineNum-contextLines) {
+			if _, ok := lines[first-1]; !ok {
+				break
+			}
+			first--
+		}
+		m.before = nil
+		for n := first; n < m.lineNum; n++ {
+			m.before = append(m.before, strings.TrimRight(lines[n], "\r\n"))
+		}
+		m.after = nil
+		for n := m.lineNum + 1; n <= m.lineNum+contextLines; n++ {
+			text, ok := lines[n]
+			if !ok {
+				break
+			}
+			m.after = append(m.after, strings.TrimRight(text, "\r\n"))
+		}
+	}
+}
+
 type ripgrepMatch struct {
 	Type string `json:"type"`
 	Data struct {
@@ -272,7 +360,7 @@ type ripgrepMatch struct {
 	} `json:"data"`
 }
 
-func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error) {
+func searchFilesWithRegex(pattern, rootPath, include string, contextLines int) ([]grepMatch, error) {
 	matches := []grepMatch{}
 
 	// Use cached regex compilation
@@ -321,23 +409,16 @@ func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error
 			return nil
 		}
 
-		match, lineNum, charNum, lineText, err := fileContainsPattern(path, regex)
+		fileMatches, err := fileMatchesPattern(path, regex, contextLines)
 		if err != nil {
 			return nil // Skip files we can't read
 		}
-
-		if match {
-			matches = append(matches, grepMatch{
-				path:     path,
-				modTime:  info.ModTime(),
-				lineNum:  lineNum,
-				charNum:  charNum,
-				lineText: lineText,
-			})
-
-			if len(matches) >= 200 {
-				return filepath.SkipAll
-			}
+		for _, m := range fileMatches {
+			m.modTime = info.ModTime()
+			matches = append(matches, m)
+		}
+		if len(matches) >= 200 {
+			return filepath.SkipAll
 		}
 
 		return nil
@@ -349,30 +430,47 @@ func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error
 	return matches, nil
 }
 
-func fileContainsPattern(filePath string, pattern *regexp.Regexp) (bool, int, int, string, error) {
+// fileMatchesPattern returns the lines of the file matching the pattern,
+// like ripgrep does.
+func fileMatchesPattern(filePath string, pattern *regexp.Regexp, contextLines int) ([]grepMatch, error) {
 	// Only search text files.
 	if !isTextFile(filePath) {
-		return false, 0, 0, "", nil
+		return nil, nil
 	}
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return false, 0, 0, "", err
+		return nil, err
 	}
 	defer file.Close()
 
+	var matches []grepMatch
+	// lines holds the lines that may be the context of a match.
+	lines := make(map[int]string)
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
+		if contextLines > 0 {
+			lines[lineNum] = line
+		}
 		if loc := pattern.FindStringIndex(line); loc != nil {
-			charNum := loc[0] + 1
-			return true, lineNum, charNum, line, nil
+			matches = append(matches, grepMatch{
+				path:     filePath,
+				lineNum:  lineNum,
+				charNum:  loc[0] + 1,
+				lineText: line,
+			})
 		}
 	}
-
-	return false, 0, 0, "", scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if contextLines > 0 {
+		addContextLines(matches, lines, contextLines)
+	}
+	return matches, nil
 }
 
 // isTextFile checks if a file is a text file by examining its MIME type.
@@ -1,11 +1,13 @@
 package tools
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"regexp"
 	"testing"
 
+	"charm.land/fantasy"
 	"github.com/stretchr/testify/require"
 )
 
@@ -85,9 +87,11 @@ func TestGrepWithIgnoreFiles(t *testing.T) {
 
 	// Test both implementations
 	for name, fn := range map[string]func(pattern, path, include string) ([]grepMatch, error){
-		"regex": searchFilesWithRegex,
+		"regex": func(pattern, path, include string) ([]grepMatch, error) {
+			return searchFilesWithRegex(pattern, path, include, 0)
+		},
 		"rg": func(pattern, path, include string) ([]grepMatch, error) {
-			return searchWithRipgrep(t.Context(), pattern, path, include)
+			return searchWithRipgrep(t.Context(), pattern, path, include, 0)
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -145,9 +149,11 @@ func TestSearchImplementations(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".crushignore"), []byte("file5.txt\n"), 0o644))
 
 	for name, fn := range map[string]func(pattern, path, include string) ([]grepMatch, error){
-		"regex": searchFilesWithRegex,
+		"regex": func(pattern, path, include string) ([]grepMatch, error) {
+			return searchFilesWithRegex(pattern, path, include, 0)
+		},
 		"rg": func(pattern, path, include string) ([]grepMatch, error) {
-			return searchWithRipgrep(t.Context(), pattern, path, include)
+			return searchWithRipgrep(t.Context(), pattern, path, include, 0)
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -396,9 +402,11 @@ func TestColumnMatch(t *testing.T) {
 
 	// Test both implementations
 	for name, fn := range map[string]func(pattern, path, include string) ([]grepMatch, error){
-		"regex": searchFilesWithRegex,
+		"regex": func(pattern, path, include string) ([]grepMatch, error) {
+			return searchFilesWithRegex(pattern, path, include, 0)
+		},
 		"rg": func(pattern, path, include string) ([]grepMatch, error) {
-			return searchWithRipgrep(t.Context(), pattern, path, include)
+			return searchWithRipgrep(t.Context(), pattern, path, include, 0)
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -419,3 +427,74 @@ func TestColumnMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchContextLines(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	content := "one\ntwo\nmatch three\nfour\nmatch five\nsix\nseven\neight\nnine\nmatch ten\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte(content), 0o644))
+
+	for name, fn := range map[string]func(pattern, path, include string, contextLines int) ([]grepMatch, error){
+		"regex": searchFilesWithRegex,
+		"rg": func(pattern, path, include string, contextLines int) ([]grepMatch, error) {
+			return searchWithRipgrep(t.Context(), pattern, path, include, contextLines)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if name == "rg" && getRg() == "" {
+				t.Skip("rg is not in $PATH")
+			}
+
+			matches, err := fn("match", tempDir, "", 1)
+			require.NoError(t, err)
+			require.Len(t, matches, 3)
+			require.Equal(t, []string{"two"}, matches[0].before)
+			require.Equal(t, []string{"four"}, matches[0].after)
+			require.Equal(t, []string{"four"}, matches[1].before)
+			require.Equal(t, []string{"six"}, matches[1].after)
+			require.Equal(t, []string{"nine"}, matches[2].before)
+			require.Empty(t, matches[2].after)
+		})
+	}
+}
+
+func TestGrepToolContextLines(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	content := "one\ntwo\nmatch three\nfour\nmatch five\nsix\nseven\neight\nnine\nmatch ten\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte(content), 0o644))
+
+	input, err := json.Marshal(GrepParams{Pattern: "match", ContextLines: 1})
+	require.NoError(t, err)
+	resp, err := NewGrepTool(tempDir).Run(t.Context(), fantasy.ToolCall{Input: string(input)})
+	require.NoError(t, err)
+	require.Equal(t, "Found 3 matches\n"+filepath.ToSlash(filepath.Join(tempDir, "file.txt"))+":\n"+
+		"  Line 2- two\n"+
+		"  Line 3, Char 1: match three\n"+
+		"  Line 4- four\n"+
+		"  Line 5, Char 1: match five\n"+
+		"  Line 6- six\n"+
+		"  --\n"+
+		"  Line 9- nine\n"+
+		"  Line 10, Char 1: match ten\n",
+		resp.Content,
+	)
+}
+
+func TestGrepToolNestedCrushIgnore(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "gen", "api"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "gen", ".crushignore"), []byte("api/\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "gen", "api", "client.go"), []byte("match generated\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("match main\n"), 0o644))
+
+	input, err := json.Marshal(GrepParams{Pattern: "match"})
+	require.NoError(t, err)
+	resp, err := NewGrepTool(tempDir).Run(t.Context(), fantasy.ToolCall{Input: string(input)})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "match main")
+	require.NotContains(t, resp.Content, "match generated")
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"charm.land/fantasy"
@@ -96,7 +97,7 @@ func NewLsTool(permissions permission.Service, workingDir string, lsConfig confi
 				}
 			}
 
-			output, metadata, err := ListDirectoryTree(searchPath, params, lsConfig)
+			output, metadata, err := ListDirectoryTree(ctx, workingDir, searchPath, params, lsConfig)
 			if err != nil {
 				return fantasy.NewTextErrorResponse(err.Error()), err
 			}
@@ -108,7 +109,9 @@ func NewLsTool(permissions permission.Service, workingDir string, lsConfig confi
 		})
 }
 
-func ListDirectoryTree(searchPath string, params LSParams, lsConfig config.ToolLs) (string, LSResponseMetadata, error) {
+// ListDirectoryTree renders the tree of searchPath, without the paths the
+// .crushignore files of workingDir or the content excluder of ctx exclude.
+func ListDirectoryTree(ctx context.Context, workingDir, searchPath string, params LSParams, lsConfig config.ToolLs) (string, LSResponseMetadata, error) {
 	if _, err := os.Stat(searchPath); os.IsNotExist(err) {
 		return "", LSResponseMetadata{}, fmt.Errorf("path does not exist: %s", searchPath)
 	}
@@ -124,6 +127,10 @@ func ListDirectoryTree(searchPath string, params LSParams, lsConfig config.ToolL
 	if err != nil {
 		return "", LSResponseMetadata{}, fmt.Errorf("error listing directory: %w", err)
 	}
+	crushIgnore := fsext.NewCrushIgnore(workingDir)
+	files = slices.DeleteFunc(files, func(path string) bool {
+		return IsContentExcluded(ctx, workingDir, path) || crushIgnore.Ignores(path)
+	})
 
 	metadata := LSResponseMetadata{
 		NumberOfFiles: len(files),
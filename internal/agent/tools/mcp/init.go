@@ -11,15 +11,19 @@ import (
 	"log/slog"
 	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/version"
@@ -30,6 +34,14 @@ var (
 	sessions = csync.NewMap[string, *mcp.ClientSession]()
 	states   = csync.NewMap[string, ClientInfo]()
 	broker   = pubsub.NewBroker[Event]()
+	// tokens are the OAuth tokens of the remote servers.
+	tokens = csync.NewMap[string, *oauth.Token]()
+
+	// closing is set on shutdown, so exiting servers aren't restarted.
+	closing atomic.Bool
+	// appCtx is the context of the application, which the servers
+	// reconnected for a tool call run in: they outlive the call.
+	appCtx atomic.Pointer[context.Context]
 )
 
 // State represents the current state of an MCP client
@@ -64,6 +76,7 @@ const (
 	EventStateChanged EventType = iota
 	EventToolsListChanged
 	EventPromptsListChanged
+	EventResourcesListChanged
 )
 
 // Event represents an event in the MCP system
@@ -77,8 +90,9 @@ type Event struct {
 
 // Counts number of available tools, prompts, etc.
 type Counts struct {
-	Tools   int
-	Prompts int
+	Tools     int
+	Prompts   int
+	Resources int
 }
 
 // ClientInfo holds information about an MCP client's state
@@ -108,6 +122,7 @@ func GetState(name string) (ClientInfo, bool) {
 
 // Close closes all MCP clients. This should be called during application shutdown.
 func Close() error {
+	closing.Store(true)
 	var errs []error
 	for name, session := range sessions.Seq2() {
 		if err := session.Close(); err != nil &&
@@ -123,81 +138,130 @@ func Close() error {
 
 // Initialize initializes MCP clients based on the provided configuration.
 func Initialize(ctx context.Context, permissions permission.Service, cfg *config.Config) {
+	appCtx.Store(&ctx)
 	var wg sync.WaitGroup
+	for name, token := range cfg.MCPTokens {
+		if token != nil {
+			tokens.Set(name, token)
+		}
+	}
 	// Initialize states for all configured MCPs
 	for name, m := range cfg.MCP {
-		if m.Disabled {
-			updateState(name, StateDisabled, nil, nil, Counts{})
-			slog.Debug("skipping disabled mcp", "name", name)
-			continue
-		}
+		wg.Go(func() {
+			initialize(ctx, cfg, name, m)
+		})
+	}
+	wg.Wait()
+}
 
-		// Set initial starting state
-		updateState(name, StateStarting, nil, nil, Counts{})
-
-		wg.Add(1)
-		go func(name string, m config.MCPConfig) {
-			defer func() {
-				wg.Done()
-				if r := recover(); r != nil {
-					var err error
-					switch v := r.(type) {
-					case error:
-						err = v
-					case string:
-						err = fmt.Errorf("panic: %s", v)
-					default:
-						err = fmt.Errorf("panic: %v", v)
-					}
-					updateState(name, StateError, err, nil, Counts{})
-					slog.Error("panic in mcp client initialization", "error", err, "name", name)
-				}
-			}()
-
-			// createSession handles its own timeout internally.
-			session, err := createSession(ctx, name, m, cfg.Resolver())
-			if err != nil {
-				return
-			}
+// Start connects to the MCP server name of cfg in the background, for the
+// servers added to the configuration after Initialize.
+func Start(ctx context.Context, cfg *config.Config, name string) {
+	m, ok := cfg.GetMCP(name)
+	if !ok {
+		return
+	}
+	go initialize(ctx, cfg, name, m)
+}
 
-			tools, err := getTools(ctx, session)
-			if err != nil {
-				slog.Error("error listing tools", "error", err)
-				updateState(name, StateError, err, nil, Counts{})
-				session.Close()
-				return
-			}
+func initialize(ctx context.Context, cfg *config.Config, name string, m config.MCPConfig) {
+	if m.Disabled {
+		updateState(name, StateDisabled, nil, nil, Counts{})
+		slog.Debug("skipping disabled mcp", "name", name)
+		return
+	}
 
-			prompts, err := getPrompts(ctx, session)
-			if err != nil {
-				slog.Error("error listing prompts", "error", err)
-				updateState(name, StateError, err, nil, Counts{})
-				session.Close()
-				return
+	// Set initial starting state
+	updateState(name, StateStarting, nil, nil, Counts{})
+
+	defer func() {
+		if r := recover(); r != nil {
+			var err error
+			switch v := r.(type) {
+			case error:
+				err = v
+			case string:
+				err = fmt.Errorf("panic: %s", v)
+			default:
+				err = fmt.Errorf("panic: %v", v)
 			}
+			updateState(name, StateError, err, nil, Counts{})
+			slog.Error("panic in mcp client initialization", "error", err, "name", name)
+		}
+	}()
 
-			updateTools(name, tools)
-			updatePrompts(name, prompts)
-			sessions.Set(name, session)
+	connect(ctx, name, m, cfg.Resolver(), 0)
+}
 
-			updateState(name, StateConnected, nil, session, Counts{
-				Tools:   len(tools),
-				Prompts: len(prompts),
-			})
-		}(name, m)
+func connect(ctx context.Context, name string, m config.MCPConfig, resolver config.VariableResolver, restarts int) (*mcp.ClientSession, error) {
+	// createSession handles its own timeout internally.
+	session, err := createSession(ctx, name, m, resolver)
+	if err != nil {
+		return nil, err
 	}
-	wg.Wait()
+
+	counts, err := register(ctx, name, session)
+	if err != nil {
+		updateState(name, StateError, err, nil, Counts{})
+		session.Close()
+		return nil, err
+	}
+	updateState(name, StateConnected, nil, session, counts)
+	go watchSession(ctx, name, session, restarts)
+	return session, nil
 }
 
-func getOrRenewClient(ctx context.Context, name string) (*mcp.ClientSession, error) {
-	sess, ok := sessions.Get(name)
-	if !ok {
-		return nil, fmt.Errorf("mcp '%s' not available", name)
+// register lists the tools, prompts and resources of the MCP server name
+// connected in session, making them available. The resources are optional:
+// the server is used without them when they can't be listed.
+func register(ctx context.Context, name string, session *mcp.ClientSession) (Counts, error) {
+	tools, err := getTools(ctx, session)
+	if err != nil {
+		slog.Error("error listing tools", "error", err)
+		return Counts{}, err
 	}
 
+	prompts, err := getPrompts(ctx, session)
+	if err != nil {
+		slog.Error("error listing prompts", "error", err)
+		return Counts{}, err
+	}
+
+	resources, err := getResources(ctx, session)
+	if err != nil {
+		slog.Warn("error listing resources, going on without them", "name", name, "error", err)
+	}
+
+	updateTools(name, tools)
+	updatePrompts(name, prompts)
+	updateResources(name, resources)
+	sessions.Set(name, session)
+	return Counts{
+		Tools:     len(tools),
+		Prompts:   len(prompts),
+		Resources: len(resources),
+	}, nil
+}
+
+func getOrRenewClient(ctx context.Context, name string) (*mcp.ClientSession, error) {
 	cfg := config.Get()
-	m := cfg.MCP[name]
+	m, ok := cfg.GetMCP(name)
+	if !ok || m.Disabled {
+		return nil, fmt.Errorf("mcp '%s' not available", name)
+	}
+	connectCtx := context.Background()
+	if c := appCtx.Load(); c != nil {
+		connectCtx = *c
+	}
 	state, _ := states.Get(name)
+	sess, ok := sessions.Get(name)
+	if !ok {
+		if state.State == StateStarting {
+			return nil, fmt.Errorf("mcp '%s' is restarting, try again shortly", name)
+		}
+		// The server crashed and couldn't be restarted, try again.
+		return connect(connectCtx, name, m, cfg.Resolver(), 0)
+	}
 
 	timeout := mcpTimeout(m)
 	pingCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -207,15 +271,9 @@ func getOrRenewClient(ctx context.Context, name string) (*mcp.ClientSession, err
 		return sess, nil
 	}
 	updateState(name, StateError, maybeTimeoutErr(err, timeout), nil, state.Counts)
+	sess.Close()
 
-	sess, err = createSession(ctx, name, m, cfg.Resolver())
-	if err != nil {
-		return nil, err
-	}
-
-	updateState(name, StateConnected, nil, sess, state.Counts)
-	sessions.Set(name, sess)
-	return sess, nil
+	return connect(connectCtx, name, m, cfg.Resolver(), 0)
 }
 
 // updateState updates the state of an MCP client and publishes an event
@@ -250,7 +308,7 @@ func createSession(ctx context.Context, name string, m config.MCPConfig, resolve
 	mcpCtx, cancel := context.WithCancel(ctx)
 	cancelTimer := time.AfterFunc(timeout, cancel)
 
-	transport, err := createTransport(mcpCtx, m, resolver)
+	transport, err := createTransport(mcpCtx, name, m, resolver)
 	if err != nil {
 		updateState(name, StateError, err, nil, Counts{})
 		slog.Error("error creating mcp client", "error", err, "name", name)
@@ -278,6 +336,12 @@ func createSession(ctx context.Context, name string, m config.MCPConfig, resolve
 					Name: name,
 				})
 			},
+			ResourceListChangedHandler: func(context.Context, *mcp.ResourceListChangedRequest) {
+				broker.Publish(pubsub.UpdatedEvent, Event{
+					Type: EventResourcesListChanged,
+					Name: name,
+				})
+			},
 			LoggingMessageHandler: func(_ context.Context, req *mcp.LoggingMessageRequest) {
 				slog.Info("mcp log", "name", name, "data", req.Params.Data)
 			},
@@ -328,7 +392,7 @@ func maybeTimeoutErr(err error, timeout time.Duration) error {
 	return err
 }
 
-func createTransport(ctx context.Context, m config.MCPConfig, resolver config.VariableResolver) (mcp.Transport, error) {
+func createTransport(ctx context.Context, name string, m config.MCPConfig, resolver config.VariableResolver) (mcp.Transport, error) {
 	switch m.Type {
 	case config.MCPStdio:
 		command, err := resolver.ResolveValue(m.Command)
@@ -344,51 +408,93 @@ func createTransport(ctx context.Context, m config.MCPConfig, resolver config.Va
 			Command: cmd,
 		}, nil
 	case config.MCPHttp:
-		if strings.TrimSpace(m.URL) == "" {
-			return nil, fmt.Errorf("mcp http config requires a non-empty 'url' field")
-		}
-		client := &http.Client{
-			Transport: &headerRoundTripper{
-				headers: m.ResolvedHeaders(),
-			},
+		endpoint, err := resolveURL(m, resolver)
+		if err != nil {
+			return nil, err
 		}
 		return &mcp.StreamableClientTransport{
-			Endpoint:   m.URL,
-			HTTPClient: client,
+			Endpoint:   endpoint,
+			HTTPClient: httpClient(name, m),
 		}, nil
 	case config.MCPSSE:
-		if strings.TrimSpace(m.URL) == "" {
-			return nil, fmt.Errorf("mcp sse config requires a non-empty 'url' field")
-		}
-		client := &http.Client{
-			Transport: &headerRoundTripper{
-				headers: m.ResolvedHeaders(),
-			},
+		endpoint, err := resolveURL(m, resolver)
+		if err != nil {
+			return nil, err
 		}
 		return &mcp.SSEClientTransport{
-			Endpoint:   m.URL,
-			HTTPClient: client,
+			Endpoint:   endpoint,
+			HTTPClient: httpClient(name, m),
 		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported mcp type: %s", m.Type)
 	}
 }
 
+// resolveURL returns the endpoint of a remote MCP server, which may
+// reference environment variables.
+func resolveURL(m config.MCPConfig, resolver config.VariableResolver) (string, error) {
+	if strings.TrimSpace(m.URL) == "" {
+		return "", fmt.Errorf("mcp %s config requires a non-empty 'url' field", m.Type)
+	}
+	endpoint, err := resolver.ResolveValue(m.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid mcp url: %w", err)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid mcp url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid mcp url %q: scheme must be http or https", endpoint)
+	}
+	return endpoint, nil
+}
+
+// httpClient returns the client used to talk to a remote MCP server, sending
+// its configured headers and OAuth token with every request.
+func httpClient(name string, m config.MCPConfig) *http.Client {
+	return &http.Client{
+		Transport: &headerRoundTripper{
+			headers: m.ResolvedHeaders(),
+			base: mcpauth.NewTransport(
+				name,
+				func() *oauth.Token {
+					token, _ := tokens.Get(name)
+					return token
+				},
+				func(token *oauth.Token) error {
+					tokens.Set(name, token)
+					return config.Get().SetMCPToken(name, token)
+				},
+				http.DefaultTransport,
+			),
+		},
+	}
+}
+
 type headerRoundTripper struct {
 	headers map[string]string
+	base    http.RoundTripper
 }
 
 func (rt headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it's given.
+	req = req.Clone(req.Context())
 	for k, v := range rt.headers {
 		req.Header.Set(k, v)
 	}
-	return http.DefaultTransport.RoundTrip(req)
+	return rt.base.RoundTrip(req)
 }
 
 func mcpTimeout(m config.MCPConfig) time.Duration {
 	return time.Duration(cmp.Or(m.Timeout, 15)) * time.Second
 }
 
+// toolTimeout returns how long a tool call can take, zero meaning no limit.
+func toolTimeout(m config.MCPConfig) time.Duration {
+	return time.Duration(max(m.ToolTimeout, 0)) * time.Second
+}
+
 func stdioCheck(old *exec.Cmd) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type Resource = mcp.Resource
+
+var allResources = csync.NewMap[string, []*Resource]()
+
+// Resources returns all available MCP resources.
+func Resources() iter.Seq2[string, []*Resource] {
+	return allResources.Seq2()
+}
+
+// ResourceContent is the content of an MCP resource.
+type ResourceContent struct {
+	URI      string
+	MIMEType string
+	Data     []byte
+}
+
+// ReadResource retrieves the contents of an MCP resource.
+func ReadResource(ctx context.Context, clientName, uri string) ([]ResourceContent, error) {
+	c, err := getOrRenewClient(ctx, clientName)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []ResourceContent
+	for _, content := range result.Contents {
+		if content == nil {
+			continue
+		}
+		rc := ResourceContent{
+			URI:      cmp.Or(content.URI, uri),
+			MIMEType: content.MIMEType,
+			Data:     content.Blob,
+		}
+		if content.Blob == nil {
+			rc.MIMEType = cmp.Or(rc.MIMEType, "text/plain")
+			rc.Data = []byte(content.Text)
+		}
+		if rc.MIMEType == "" {
+			return nil, fmt.Errorf("resource %s has binary content of unknown type", rc.URI)
+		}
+		contents = append(contents, rc)
+	}
+	if len(contents) == 0 {
+		return nil, errors.New("resource has no content")
+	}
+	return contents, nil
+}
+
+// RefreshResources gets the updated list of resources from the MCP and
+// updates the global state.
+func RefreshResources(ctx context.Context, name string) {
+	session, ok := sessions.Get(name)
+	if !ok {
+		slog.Warn("refresh resources: no session", "name", name)
+		return
+	}
+
+	// The server stays usable without its resources.
+	resources, err := getResources(ctx, session)
+	if err != nil {
+		slog.Warn("refresh resources: error listing resources", "name", name, "error", err)
+		return
+	}
+
+	updateResources(name, resources)
+
+	prev, _ := states.Get(name)
+	prev.Counts.Resources = len(resources)
+	updateState(name, StateConnected, nil, session, prev.Counts)
+}
+
+func getResources(ctx context.Context, c *mcp.ClientSession) ([]*Resource, error) {
+	if c.InitializeResult().Capabilities.Resources == nil {
+		return nil, nil
+	}
+	var resources []*Resource
+	for resource, err := range c.Resources(ctx, &mcp.ListResourcesParams{}) {
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// updateResources updates the resources available from an MCP.
+func updateResources(mcpName string, resources []*Resource) {
+	if len(resources) == 0 {
+		allResources.Del(mcpName)
+		return
+	}
+	allResources.Set(mcpName, resources)
+}
@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// connectServer connects a client to server over memory, closing both when
+// the test ends.
+func connectServer(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { serverSession.Close() })
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "crush"}, nil).Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestRegisterWithoutResources(t *testing.T) {
+	t.Parallel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "search"}, func(context.Context, *mcp.CallToolRequest, struct{}) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	server.AddResource(&mcp.Resource{URI: "file:///README.md", Name: "README"}, func(context.Context, *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{}, nil
+	})
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method == "resources/list" {
+				return nil, errors.New("resources are unavailable")
+			}
+			return next(ctx, method, req)
+		}
+	})
+
+	const name = "test-register-without-resources"
+	t.Cleanup(func() {
+		updateTools(name, nil)
+		sessions.Del(name)
+	})
+	counts, err := register(t.Context(), name, connectServer(t, server))
+	require.NoError(t, err, "failing resources leave the tools usable")
+	require.Equal(t, Counts{Tools: 1}, counts)
+	tools, ok := allTools.Get(name)
+	require.True(t, ok)
+	require.Equal(t, "search", tools[0].Name)
+	_, ok = allResources.Get(name)
+	require.False(t, ok)
+}
+
+func TestGetResources(t *testing.T) {
+	t.Parallel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs"}, nil)
+	resources, err := getResources(t.Context(), connectServer(t, server))
+	require.NoError(t, err)
+	require.Empty(t, resources, "servers without resources aren't asked for them")
+
+	server.AddResource(&mcp.Resource{URI: "file:///README.md", Name: "README"}, func(context.Context, *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{}, nil
+	})
+	resources, err = getResources(t.Context(), connectServer(t, server))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	require.Equal(t, "file:///README.md", resources[0].URI)
+}
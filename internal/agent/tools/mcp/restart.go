@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// maxRestarts is how many times in a row a crashing server is restarted
+	// before giving up. The next tool call tries again.
+	maxRestarts = 3
+	// restartBackoff is the delay before the first restart, doubled on each
+	// following one.
+	restartBackoff = time.Second
+	// stableAfter is how long a session must have run for its crash not to
+	// count as a failed restart.
+	stableAfter = time.Minute
+)
+
+// watchSession waits for the session to end, and restarts the server when it
+// exited on its own.
+func watchSession(ctx context.Context, name string, session *mcp.ClientSession, restarts int) {
+	started := time.Now()
+	err := session.Wait()
+	if closing.Load() || ctx.Err() != nil {
+		return
+	}
+	if current, ok := sessions.Get(name); !ok || current != session {
+		// Closed or replaced on purpose.
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	slog.Warn("MCP server exited", "name", name, "error", err)
+	state, _ := states.Get(name)
+	updateState(name, StateError, fmt.Errorf("server exited: %w", err), nil, state.Counts)
+
+	if time.Since(started) > stableAfter {
+		restarts = 0
+	}
+	restart(ctx, name, restarts)
+}
+
+// restart reconnects to a server that exited, backing off between attempts.
+func restart(ctx context.Context, name string, restarts int) {
+	for ; restarts < maxRestarts; restarts++ {
+		select {
+		case <-time.After(restartBackoff << restarts):
+		case <-ctx.Done():
+			return
+		}
+		if closing.Load() {
+			return
+		}
+
+		cfg := config.Get()
+		m, ok := cfg.GetMCP(name)
+		if !ok || m.Disabled {
+			return
+		}
+		updateState(name, StateStarting, nil, nil, Counts{})
+		if _, err := connect(ctx, name, m, cfg.Resolver(), restarts+1); err != nil {
+			slog.Warn("Failed to restart MCP server", "name", name, "attempt", restarts+1, "error", err)
+			continue
+		}
+		slog.Info("Restarted MCP server", "name", name, "attempt", restarts+1)
+		// Its tools may have changed while it was down.
+		broker.Publish(pubsub.UpdatedEvent, Event{
+			Type: EventToolsListChanged,
+			Name: name,
+		})
+		return
+	}
+	slog.Error("Giving up restarting MCP server", "name", name, "attempts", maxRestarts)
+}
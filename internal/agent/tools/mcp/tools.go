@@ -3,11 +3,13 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 	"log/slog"
 	"strings"
 
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -32,10 +34,20 @@ func RunTool(ctx context.Context, name, toolName string, input string) (string,
 	if err != nil {
 		return "", err
 	}
+	m, _ := config.Get().GetMCP(name)
+	timeout := toolTimeout(m)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	result, err := c.CallTool(ctx, &mcp.CallToolParams{
 		Name:      toolName,
 		Arguments: args,
 	})
+	if timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("mcp tool %s timed out after %s", toolName, timeout)
+	}
 	if err != nil {
 		return "", err
 	}
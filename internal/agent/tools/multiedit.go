@@ -71,6 +71,9 @@ func NewMultiEditTool(lspClients *csync.Map[string, *lsp.Client], permissions pe
 			}
 
 			params.FilePath = filepathext.SmartJoin(workingDir, params.FilePath)
+			if IsContentExcluded(ctx, workingDir, params.FilePath) {
+				return contentExcludedResponse(params.FilePath), nil
+			}
 
 			// Validate all edits before applying any
 			if err := validateEdits(params.Edits); err != nil {
@@ -101,7 +104,7 @@ func NewMultiEditTool(lspClients *csync.Map[string, *lsp.Client], permissions pe
 
 			// Wait for LSP diagnostics and add them to the response
 			text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-			text += getDiagnostics(params.FilePath, lspClients)
+			text += getDiagnostics(params.FilePath, lspClients, contentExcludeFunc(ctx, workingDir))
 			response.Content = text
 			return response, nil
 		})
@@ -199,8 +202,8 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 		slog.Error("Error creating file history version", "error", err)
 	}
 
-	recordFileWrite(params.FilePath)
-	recordFileRead(params.FilePath)
+	recordFileWrite(sessionID, params.FilePath)
+	recordFileRead(sessionID, params.FilePath)
 
 	editsApplied := len(params.Edits) - len(failedEdits)
 	var message string
@@ -238,13 +241,13 @@ func processMultiEditExistingFile(edit editContext, params MultiEditParams, call
 	}
 
 	// Check if file was read before editing
-	if getLastReadTime(params.FilePath).IsZero() {
+	if getLastReadTime(GetSessionFromContext(edit.ctx), params.FilePath).IsZero() {
 		return fantasy.NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
 	}
 
 	// Check if file was modified since last read
 	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(params.FilePath)
+	lastRead := getLastReadTime(GetSessionFromContext(edit.ctx), params.FilePath)
 	if modTime.After(lastRead) {
 		return fantasy.NewTextErrorResponse(
 			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
@@ -348,8 +351,8 @@ func processMultiEditExistingFile(edit editContext, params MultiEditParams, call
 		slog.Error("Error creating file history version", "error", err)
 	}
 
-	recordFileWrite(params.FilePath)
-	recordFileRead(params.FilePath)
+	recordFileWrite(sessionID, params.FilePath)
+	recordFileRead(sessionID, params.FilePath)
 
 	editsApplied := len(params.Edits) - len(failedEdits)
 	var message string
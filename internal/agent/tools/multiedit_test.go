@@ -22,16 +22,26 @@ func (m *mockPermissionService) Request(req permission.CreatePermissionRequest)
 	return true
 }
 
+func (m *mockPermissionService) Confirm(req permission.CreatePermissionRequest) bool {
+	return true
+}
+
 func (m *mockPermissionService) Grant(req permission.PermissionRequest) {}
 
 func (m *mockPermissionService) Deny(req permission.PermissionRequest) {}
 
 func (m *mockPermissionService) GrantPersistent(req permission.PermissionRequest) {}
 
+func (m *mockPermissionService) GrantCommandPrefix(req permission.PermissionRequest) {}
+
 func (m *mockPermissionService) AutoApproveSession(sessionID string) {}
 
 func (m *mockPermissionService) SetSkipRequests(skip bool) {}
 
+func (m *mockPermissionService) SetSessionSkipRequests(sessionID string, skip bool) {}
+
+func (m *mockPermissionService) SessionSkipRequests(sessionID string) bool { return false }
+
 func (m *mockPermissionService) SkipRequests() bool {
 	return false
 }
@@ -119,7 +129,7 @@ func TestMultiEditSequentialApplication(t *testing.T) {
 	_ = NewMultiEditTool(lspClients, permissions, files, tmpDir)
 
 	// Simulate reading the file first.
-	recordFileRead(testFile)
+	recordFileRead("", testFile)
 
 	// Manually test the sequential application logic.
 	currentContent := content
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/outline"
+	"github.com/charmbracelet/crush/internal/tokens"
+)
+
+// maxOutlineSize is the size of the largest file outlined, and of the
+// largest one whose tokens are counted.
+const maxOutlineSize = 10 * 1024 * 1024
+
+// exceedsReadBudget reports whether a file of size bytes has more tokens
+// than can be read at once. A file has fewer tokens than bytes, so only the
+// files larger than the budget are read to count them.
+func exceedsReadBudget(filePath string, size int64) bool {
+	if size <= MaxReadTokens {
+		return false
+	}
+	if size > maxOutlineSize {
+		return true
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return true
+	}
+	return tokens.Estimate(string(content)) > MaxReadTokens
+}
+
+// largeFileOutline introduces a file too large to read whole with its
+// outline.
+func largeFileOutline(filePath string, size int64, lineCount int) string {
+	var fileOutline string
+	if size <= maxOutlineSize {
+		if content, err := os.ReadFile(filePath); err == nil {
+			fileOutline = outline.Render(filePath, content)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("<outline>\n")
+	fmt.Fprintf(&sb, "File is too large to read whole (%d bytes, %d lines). ", size, lineCount)
+	if fileOutline == "" {
+		sb.WriteString("Use offset and limit to read the lines you need.\n")
+	} else {
+		sb.WriteString("Its outline follows. Use offset and limit to read the lines you need.\n")
+		sb.WriteString(fileOutline)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</outline>\n\n")
+	return sb.String()
+}
+
+// clipLines keeps the first lines of content fitting in budget tokens.
+func clipLines(content string, budget int64) string {
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		budget -= tokens.Estimate(line)
+		if budget < 0 {
+			return strings.TrimSuffix(strings.Join(lines[:i], ""), "\n")
+		}
+	}
+	return content
+}
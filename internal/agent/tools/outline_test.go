@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLargeFileOutline(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "large.go")
+	var sb strings.Builder
+	sb.WriteString("package large\n")
+	for i := range 3 {
+		sb.WriteString("\nfunc F" + strings.Repeat("x", i) + "() {}\n")
+	}
+	require.NoError(t, os.WriteFile(path, []byte(sb.String()), 0o644))
+
+	outline := largeFileOutline(path, int64(sb.Len()), 7)
+	require.Contains(t, outline, "File is too large to read whole")
+	require.Contains(t, outline, "     5|func Fx()")
+}
+
+func TestExceedsReadBudget(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) (string, int64) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path, int64(len(content))
+	}
+
+	path, size := write("small.go", "package small\n")
+	require.False(t, exceedsReadBudget(path, size))
+	path, size = write("words.txt", strings.Repeat("word ", MaxReadTokens))
+	require.False(t, exceedsReadBudget(path, size), "the budget is in tokens, not bytes")
+	path, size = write("symbols.txt", strings.Repeat("{}", MaxReadTokens))
+	require.True(t, exceedsReadBudget(path, size))
+}
+
+func TestClipLines(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "one two\nthree", clipLines("one two\nthree\nfour five", 5))
+	require.Equal(t, "one two", clipLines("one two", 3))
+	require.Empty(t, clipLines("one two", 1))
+}
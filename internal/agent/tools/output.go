@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// maxStreamedOutput is the number of bytes of the end of the output of a
+// running tool sent to the subscribers.
+const maxStreamedOutput = 16 * 1024
+
+// ToolOutput is the output a tool produced so far while it runs.
+type ToolOutput struct {
+	SessionID  string
+	ToolCallID string
+	Output     string
+}
+
+var toolOutputBroker = pubsub.NewBroker[ToolOutput]()
+
+// SubscribeToolOutput returns a channel receiving the output of the running
+// tools as it grows.
+func SubscribeToolOutput(ctx context.Context) <-chan pubsub.Event[ToolOutput] {
+	return toolOutputBroker.Subscribe(ctx)
+}
+
+// publishToolOutput sends the end of the output of a running tool to the
+// subscribers, from the start of a line.
+func publishToolOutput(sessionID, toolCallID, output string) {
+	if len(output) > maxStreamedOutput {
+		output = output[len(output)-maxStreamedOutput:]
+		if i := strings.IndexByte(output, '\n'); i >= 0 {
+			output = output[i+1:]
+		}
+	}
+	toolOutputBroker.Publish(pubsub.UpdatedEvent, ToolOutput{
+		SessionID:  sessionID,
+		ToolCallID: toolCallID,
+		Output:     output,
+	})
+}
@@ -0,0 +1,254 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// devNull is the path of the missing side of a created or deleted file.
+const devNull = "/dev/null"
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// filePatch holds the hunks of a unified diff changing a single file.
+type filePatch struct {
+	oldPath, newPath string
+	hunks            []hunk
+}
+
+// path is the file changed by the patch.
+func (p filePatch) path() string {
+	if p.newPath == devNull {
+		return p.oldPath
+	}
+	return p.newPath
+}
+
+func (p filePatch) isCreation() bool { return p.oldPath == devNull }
+func (p filePatch) isDeletion() bool { return p.newPath == devNull }
+
+// hunk is a change to a range of lines. oldStart is 1-based, and 0 when the
+// header has no line numbers.
+type hunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+	// oldNoNewline and newNoNewline tell the last line of each side has no
+	// trailing newline.
+	oldNoNewline, newNoNewline bool
+}
+
+// parsePatch parses a unified diff, possibly covering several files.
+func parsePatch(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var patches []filePatch
+	var current *filePatch
+	var h *hunk
+	// prev is the kind of the last hunk line, for "\ No newline" markers.
+	var prev byte
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			patches = append(patches, filePatch{
+				oldPath: patchPath(line[4:]),
+				newPath: patchPath(lines[i+1][4:]),
+			})
+			current, h = &patches[len(patches)-1], nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("hunk at line %d has no --- and +++ file headers", i+1)
+			}
+			current.hunks = append(current.hunks, hunk{})
+			h = &current.hunks[len(current.hunks)-1]
+			if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+				h.oldStart, _ = strconv.Atoi(m[1])
+			}
+		case h == nil:
+			// Lines outside hunks, like "diff --git" or "index" ones.
+		case strings.HasPrefix(line, `\`):
+			switch prev {
+			case '-':
+				h.oldNoNewline = true
+			case '+':
+				h.newNoNewline = true
+			default:
+				h.oldNoNewline, h.newNoNewline = true, true
+			}
+		case strings.HasPrefix(line, "-"):
+			h.oldLines = append(h.oldLines, line[1:])
+			prev = '-'
+		case strings.HasPrefix(line, "+"):
+			h.newLines = append(h.newLines, line[1:])
+			prev = '+'
+		case strings.HasPrefix(line, " "), line == "" && i < len(lines)-1:
+			// Blank context lines often lose their leading space.
+			text := strings.TrimPrefix(line, " ")
+			h.oldLines = append(h.oldLines, text)
+			h.newLines = append(h.newLines, text)
+			prev = ' '
+		case line == "":
+		default:
+			// Anything else ends the hunk, like the header of the next file.
+			h = nil
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, errors.New("no file headers found, the patch must be a unified diff with --- and +++ lines")
+	}
+	for _, p := range patches {
+		if p.oldPath != p.newPath && !p.isCreation() && !p.isDeletion() {
+			return nil, fmt.Errorf("renaming %s to %s is not supported", p.oldPath, p.newPath)
+		}
+		if len(p.hunks) == 0 {
+			return nil, fmt.Errorf("no hunks found for %s", p.path())
+		}
+	}
+	return patches, nil
+}
+
+// patchPath returns the path of a file header, without its a/ or b/ prefix
+// or timestamp.
+func patchPath(header string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == devNull {
+		return path
+	}
+	if len(path) > 2 && (path[:2] == "a/" || path[:2] == "b/") {
+		return path[2:]
+	}
+	return path
+}
+
+// hunkResult is the outcome of applying a hunk.
+type hunkResult struct {
+	// line is where the hunk was applied, offset how far it is from where
+	// the hunk said, and fuzzy whether whitespace differences were ignored.
+	line   int
+	offset int
+	fuzzy  bool
+	err    error
+}
+
+func (r hunkResult) String() string {
+	if r.err != nil {
+		return "failed: " + r.err.Error()
+	}
+	s := fmt.Sprintf("applied at line %d", r.line)
+	if r.offset != 0 {
+		s += fmt.Sprintf(" (offset %+d lines)", r.offset)
+	}
+	if r.fuzzy {
+		s += " ignoring whitespace differences"
+	}
+	return s
+}
+
+// applyHunks applies the hunks to content, returning the new content and the
+// outcome of each hunk. Hunks that fail are skipped.
+func applyHunks(content string, hunks []hunk) (string, []hunkResult) {
+	var lines []string
+	endsWithNewline := true
+	if content != "" {
+		endsWithNewline = strings.HasSuffix(content, "\n")
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	results := make([]hunkResult, len(hunks))
+	// delta is how many lines the applied hunks added.
+	delta := 0
+	for i, h := range hunks {
+		expected := max(0, h.oldStart-1) + delta
+		if h.oldStart == 0 && len(h.oldLines) > 0 {
+			expected = -1
+		}
+		at, fuzzy, err := locateHunk(lines, h.oldLines, expected)
+		if err != nil {
+			results[i] = hunkResult{err: err}
+			continue
+		}
+		results[i] = hunkResult{line: at + 1, fuzzy: fuzzy}
+		if expected >= 0 {
+			results[i].offset = at - expected
+		}
+
+		newLines := make([]string, 0, len(lines)-len(h.oldLines)+len(h.newLines))
+		newLines = append(newLines, lines[:at]...)
+		newLines = append(newLines, h.newLines...)
+		newLines = append(newLines, lines[at+len(h.oldLines):]...)
+		if at+len(h.oldLines) == len(lines) {
+			switch {
+			case h.newNoNewline:
+				endsWithNewline = false
+			case h.oldNoNewline:
+				endsWithNewline = true
+			}
+		}
+		lines = newLines
+		delta += len(h.newLines) - len(h.oldLines)
+	}
+
+	newContent := strings.Join(lines, "\n")
+	if endsWithNewline && len(lines) > 0 {
+		newContent += "\n"
+	}
+	return newContent, results
+}
+
+// locateHunk finds where the old lines of a hunk are, preferring the
+// expected line, then the closest exact match, then the closest one ignoring
+// whitespace. An expected line of -1 requires a unique match.
+func locateHunk(lines, oldLines []string, expected int) (int, bool, error) {
+	if len(oldLines) == 0 {
+		if expected < 0 || expected > len(lines) {
+			return 0, false, fmt.Errorf("line %d is past the end of the file (%d lines)", expected+1, len(lines))
+		}
+		return expected, false, nil
+	}
+	for _, normalize := range []func(string) string{nil, normalizeWhitespace} {
+		at := -1
+		matches := 0
+		for i := 0; i+len(oldLines) <= len(lines); i++ {
+			if !linesMatch(lines[i:i+len(oldLines)], oldLines, normalize) {
+				continue
+			}
+			matches++
+			if at == -1 || abs(i-expected) < abs(at-expected) {
+				at = i
+			}
+		}
+		if expected < 0 && matches > 1 {
+			return 0, false, errors.New("the lines to change appear several times, add line numbers to the hunk header or more context")
+		}
+		if at != -1 {
+			return at, normalize != nil, nil
+		}
+	}
+	return 0, false, errors.New("the context and removed lines don't match the file")
+}
+
+func linesMatch(a, b []string, normalize func(string) string) bool {
+	for i := range a {
+		if normalize == nil && a[i] != b[i] {
+			return false
+		}
+		if normalize != nil && normalize(a[i]) != normalize(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePatch(t *testing.T) {
+	t.Parallel()
+
+	patches, err := parsePatch(`diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var a = 1
++var a = 2
+
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+\ No newline at end of file
+--- a/old.txt	2024-01-01 00:00:00
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`)
+	require.NoError(t, err)
+	require.Len(t, patches, 3)
+
+	require.Equal(t, "main.go", patches[0].path())
+	require.Len(t, patches[0].hunks, 1)
+	require.Equal(t, 1, patches[0].hunks[0].oldStart)
+	// The blank context line lost its leading space.
+	require.Equal(t, []string{"package main", "var a = 1", ""}, patches[0].hunks[0].oldLines)
+	require.Equal(t, []string{"package main", "var a = 2", ""}, patches[0].hunks[0].newLines)
+
+	require.True(t, patches[1].isCreation())
+	require.Equal(t, "new.txt", patches[1].path())
+	require.Equal(t, []string{"hello", "world"}, patches[1].hunks[0].newLines)
+	require.True(t, patches[1].hunks[0].newNoNewline)
+	require.False(t, patches[1].hunks[0].oldNoNewline)
+
+	require.True(t, patches[2].isDeletion())
+	require.Equal(t, "old.txt", patches[2].path())
+
+	_, err = parsePatch("just some text")
+	require.Error(t, err)
+	_, err = parsePatch("--- a/a.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-a\n+b\n")
+	require.ErrorContains(t, err, "renaming")
+}
+
+func TestApplyHunks(t *testing.T) {
+	t.Parallel()
+
+	content := "one\ntwo\nthree\nfour\nfive\nsix\n"
+
+	t.Run("offset", func(t *testing.T) {
+		t.Parallel()
+		patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n one\n-two\n+2\n@@ -3,2 +3,2 @@\n five\n-six\n+6\n")
+		require.NoError(t, err)
+		newContent, results := applyHunks(content, patches[0].hunks)
+		require.Equal(t, "one\n2\nthree\nfour\nfive\n6\n", newContent)
+		require.NoError(t, results[0].err)
+		require.Equal(t, "applied at line 1", results[0].String())
+		require.Equal(t, "applied at line 5 (offset +2 lines)", results[1].String())
+	})
+
+	t.Run("whitespace", func(t *testing.T) {
+		t.Parallel()
+		patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -3,1 +3,1 @@\n-  three  \n+3\n")
+		require.NoError(t, err)
+		newContent, results := applyHunks(content, patches[0].hunks)
+		require.Equal(t, "one\ntwo\n3\nfour\nfive\nsix\n", newContent)
+		require.Equal(t, "applied at line 3 ignoring whitespace differences", results[0].String())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+		patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -1,1 +1,1 @@\n-seven\n+7\n@@ -2,1 +2,1 @@\n-two\n+2\n")
+		require.NoError(t, err)
+		newContent, results := applyHunks(content, patches[0].hunks)
+		require.Equal(t, "one\n2\nthree\nfour\nfive\nsix\n", newContent)
+		require.Error(t, results[0].err)
+		require.Equal(t, "failed: the context and removed lines don't match the file", results[0].String())
+		require.NoError(t, results[1].err)
+	})
+
+	t.Run("no line numbers", func(t *testing.T) {
+		t.Parallel()
+		patches, err := parsePatch("--- a/f\n+++ b/f\n@@ @@\n four\n-five\n+5\n")
+		require.NoError(t, err)
+		newContent, results := applyHunks(content, patches[0].hunks)
+		require.NoError(t, results[0].err)
+		require.Equal(t, "one\ntwo\nthree\nfour\n5\nsix\n", newContent)
+
+		_, results = applyHunks("x\nx\n", []hunk{{oldLines: []string{"x"}, newLines: []string{"y"}}})
+		require.ErrorContains(t, results[0].err, "several times")
+	})
+
+	t.Run("no newline at end of file", func(t *testing.T) {
+		t.Parallel()
+		patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -6 +6 @@\n-six\n+6\n\\ No newline at end of file\n")
+		require.NoError(t, err)
+		newContent, _ := applyHunks(content, patches[0].hunks)
+		require.Equal(t, "one\ntwo\nthree\nfour\nfive\n6", newContent)
+	})
+}
+
+func TestPatchFiles(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create and delete", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("bye\n"), 0o644))
+
+		patches, err := parsePatch("--- /dev/null\n+++ b/sub/new.txt\n@@ -0,0 +1 @@\n+hello\n--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n")
+		require.NoError(t, err)
+		changed, results, err := patchFiles(t.Context(), dir, patches)
+		require.NoError(t, err)
+		for _, r := range results {
+			require.True(t, r.Applied, r.Result)
+		}
+		require.NoError(t, writePatchedFiles(changed))
+
+		content, err := os.ReadFile(filepath.Join(dir, "sub", "new.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "hello\n", string(content))
+		require.NoFileExists(t, filepath.Join(dir, "old.txt"))
+	})
+
+	t.Run("failed hunk reported per file", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644))
+
+		patches, err := parsePatch("--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+b\n--- a/missing.txt\n+++ b/missing.txt\n@@ -1 +1 @@\n-a\n+b\n")
+		require.NoError(t, err)
+		_, results, err := patchFiles(t.Context(), dir, patches)
+		require.NoError(t, err)
+		require.Equal(t,
+			"a.txt: hunk 1 applied at line 1\nmissing.txt: hunk 1 failed: file not found",
+			formatHunkResults(dir, results),
+		)
+	})
+
+	t.Run("files outside the working directory", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		outside := filepath.Join(t.TempDir(), "outside.txt")
+
+		patches, err := parsePatch("--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+hello\n--- /dev/null\n+++ " + outside + "\n@@ -0,0 +1 @@\n+hello\n")
+		require.NoError(t, err)
+		changed, results, err := patchFiles(t.Context(), dir, patches)
+		require.NoError(t, err)
+		require.Empty(t, changed)
+		require.Len(t, results, 2)
+		for _, r := range results {
+			require.False(t, r.Applied)
+			require.Equal(t, "failed: the file is outside the working directory", r.Result)
+		}
+	})
+
+	t.Run("crlf", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "win.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a\r\nb\r\n"), 0o644))
+
+		patches, err := parsePatch("--- a/win.txt\n+++ b/win.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n")
+		require.NoError(t, err)
+		changed, _, err := patchFiles(t.Context(), dir, patches)
+		require.NoError(t, err)
+		require.NoError(t, writePatchedFiles(changed))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "a\r\nc\r\n", string(content))
+	})
+}
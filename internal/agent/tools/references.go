@@ -7,11 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"maps"
+	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
-	"sort"
 	"strings"
 
 	"charm.land/fantasy"
@@ -34,7 +33,7 @@ const ReferencesToolName = "lsp_references"
 //go:embed references.md
 var referencesDescription []byte
 
-func NewReferencesTool(lspClients *csync.Map[string, *lsp.Client]) fantasy.AgentTool {
+func NewReferencesTool(lspClients *csync.Map[string, *lsp.Client], workingDir string) fantasy.AgentTool {
 	return fantasy.NewAgentTool(
 		ReferencesToolName,
 		string(referencesDescription),
@@ -47,9 +46,9 @@ func NewReferencesTool(lspClients *csync.Map[string, *lsp.Client]) fantasy.Agent
 				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
 			}
 
-			workingDir := cmp.Or(params.Path, ".")
+			searchPath := cmp.Or(params.Path, ".")
 
-			matches, _, err := searchFiles(ctx, regexp.QuoteMeta(params.Symbol), workingDir, "", 100)
+			matches, err := symbolMatches(ctx, params.Symbol, searchPath, workingDir)
 			if err != nil {
 				return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to search for symbol: %s", err)), nil
 			}
@@ -75,6 +74,7 @@ func NewReferencesTool(lspClients *csync.Map[string, *lsp.Client]) fantasy.Agent
 				// XXX: should we break here or look for all results?
 			}
 
+			allLocations = excludeLocations(ctx, workingDir, allLocations)
 			if len(allLocations) > 0 {
 				output := formatReferences(cleanupLocations(allLocations))
 				return fantasy.NewTextResponse(output), nil
@@ -91,25 +91,36 @@ func (r *referencesTool) Name() string {
 	return ReferencesToolName
 }
 
-func find(ctx context.Context, lspClients *csync.Map[string, *lsp.Client], symbol string, match grepMatch) ([]protocol.Location, error) {
-	absPath, err := filepath.Abs(match.path)
+// symbolMatches returns the first match of symbol in each file under
+// searchPath that isn't excluded from the content, to ask the LSP servers
+// about: any use of the symbol in a file leads them to the same results.
+func symbolMatches(ctx context.Context, symbol, searchPath, workingDir string) ([]grepMatch, error) {
+	matches, _, err := searchFiles(ctx, regexp.QuoteMeta(symbol), searchPath, "", 0, 100)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %s", err)
+		return nil, err
 	}
+	return firstPerFile(slices.DeleteFunc(matches, func(m grepMatch) bool {
+		return IsContentExcluded(ctx, workingDir, m.path)
+	})), nil
+}
 
-	var client *lsp.Client
-	for c := range lspClients.Seq() {
-		if c.HandlesFile(absPath) {
-			client = c
-			break
+// firstPerFile returns the first of the matches of each file, in order.
+func firstPerFile(matches []grepMatch) []grepMatch {
+	seen := make(map[string]bool, len(matches))
+	return slices.DeleteFunc(matches, func(m grepMatch) bool {
+		if seen[m.path] {
+			return true
 		}
-	}
+		seen[m.path] = true
+		return false
+	})
+}
 
-	if client == nil {
-		slog.Warn("No LSP clients to handle", "path", match.path)
-		return nil, nil
+func find(ctx context.Context, lspClients *csync.Map[string, *lsp.Client], symbol string, match grepMatch) ([]protocol.Location, error) {
+	absPath, client, err := clientForMatch(lspClients, match)
+	if client == nil || err != nil {
+		return nil, err
 	}
-
 	return client.FindReferences(
 		ctx,
 		absPath,
@@ -119,6 +130,24 @@ func find(ctx context.Context, lspClients *csync.Map[string, *lsp.Client], symbo
 	)
 }
 
+// clientForMatch returns the absolute path of the file of a match, and the
+// LSP client handling it, if any.
+func clientForMatch(lspClients *csync.Map[string, *lsp.Client], match grepMatch) (string, *lsp.Client, error) {
+	absPath, err := filepath.Abs(match.path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get absolute path: %s", err)
+	}
+
+	for c := range lspClients.Seq() {
+		if c.HandlesFile(absPath) {
+			return absPath, c, nil
+		}
+	}
+
+	slog.Warn("No LSP clients to handle", "path", match.path)
+	return absPath, nil, nil
+}
+
 // getSymbolOffset returns the character offset to the actual symbol name
 // in a qualified symbol (e.g., "Bar" in "foo.Bar" or "method" in "Class::method").
 func getSymbolOffset(symbol string) int {
@@ -154,37 +183,43 @@ func cleanupLocations(locations []protocol.Location) []protocol.Location {
 	})
 }
 
-func groupByFilename(locations []protocol.Location) map[string][]protocol.Location {
-	files := make(map[string][]protocol.Location)
-	for _, loc := range locations {
+// excludeLocations removes the locations in files excluded by the content
+// excluder of ctx, whose lines mustn't be shown.
+func excludeLocations(ctx context.Context, workingDir string, locations []protocol.Location) []protocol.Location {
+	return slices.DeleteFunc(locations, func(loc protocol.Location) bool {
 		path, err := loc.URI.Path()
-		if err != nil {
-			slog.Error("Failed to convert location URI to path", "uri", loc.URI, "error", err)
-			continue
-		}
-		files[path] = append(files[path], loc)
-	}
-	return files
+		return err == nil && IsContentExcluded(ctx, workingDir, path)
+	})
 }
 
 func formatReferences(locations []protocol.Location) string {
-	fileRefs := groupByFilename(locations)
-	files := slices.Collect(maps.Keys(fileRefs))
-	sort.Strings(files)
+	return formatLocations(locations, "reference(s)")
+}
 
+// formatLocations lists the locations as file:line:column, followed by the
+// source line they point to.
+func formatLocations(locations []protocol.Location, noun string) string {
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("Found %d reference(s) in %d file(s):\n\n", len(locations), len(files)))
-
-	for _, file := range files {
-		refs := fileRefs[file]
-		output.WriteString(fmt.Sprintf("%s (%d reference(s)):\n", file, len(refs)))
-		for _, ref := range refs {
-			line := ref.Range.Start.Line + 1
-			char := ref.Range.Start.Character + 1
-			output.WriteString(fmt.Sprintf("  Line %d, Column %d\n", line, char))
+	files := map[string][]string{}
+	for _, loc := range locations {
+		path, err := loc.URI.Path()
+		if err != nil {
+			slog.Error("Failed to convert location URI to path", "uri", loc.URI, "error", err)
+			continue
+		}
+		lines, ok := files[path]
+		if !ok {
+			if content, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+			files[path] = lines
+		}
+		line := int(loc.Range.Start.Line)
+		fmt.Fprintf(&output, "%s:%d:%d", path, line+1, loc.Range.Start.Character+1)
+		if line < len(lines) {
+			output.WriteString(": " + strings.TrimSpace(lines[line]))
 		}
 		output.WriteString("\n")
 	}
-
-	return output.String()
+	return fmt.Sprintf("Found %d %s in %d file(s):\n\n", len(locations), noun, len(files)) + output.String()
 }
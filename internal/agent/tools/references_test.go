@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatLocations(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {\n\thello()\n}\n"), 0o644))
+
+	location := func(path string, line, char uint32) protocol.Location {
+		return protocol.Location{
+			URI:   protocol.URIFromPath(path),
+			Range: protocol.Range{Start: protocol.Position{Line: line, Character: char}},
+		}
+	}
+	missing := filepath.Join(dir, "missing.go")
+	output := formatLocations([]protocol.Location{
+		location(path, 2, 5),
+		location(path, 3, 1),
+		location(missing, 0, 0),
+	}, "reference(s)")
+
+	require.Equal(t, "Found 3 reference(s) in 2 file(s):\n\n"+
+		path+":3:6: func main() {\n"+
+		path+":4:2: hello()\n"+
+		missing+":1:1\n", output)
+}
+
+func TestFirstPerFile(t *testing.T) {
+	t.Parallel()
+
+	matches := []grepMatch{
+		{path: "a.go", lineNum: 3},
+		{path: "b.go", lineNum: 1},
+		{path: "a.go", lineNum: 7},
+		{path: "b.go", lineNum: 9},
+		{path: "c.go", lineNum: 2},
+	}
+	require.Equal(t, []grepMatch{
+		{path: "a.go", lineNum: 3},
+		{path: "b.go", lineNum: 1},
+		{path: "c.go", lineNum: 2},
+	}, firstPerFile(matches))
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -37,7 +38,7 @@ func getRgCmd(ctx context.Context, globPattern string) *exec.Cmd {
 	return exec.CommandContext(ctx, name, args...)
 }
 
-func getRgSearchCmd(ctx context.Context, pattern, path, include string) *exec.Cmd {
+func getRgSearchCmd(ctx context.Context, pattern, path, include string, contextLines int) *exec.Cmd {
 	name := getRg()
 	if name == "" {
 		return nil
@@ -47,6 +48,9 @@ func getRgSearchCmd(ctx context.Context, pattern, path, include string) *exec.Cm
 	if include != "" {
 		args = append(args, "--glob", include)
 	}
+	if contextLines > 0 {
+		args = append(args, "--context", strconv.Itoa(contextLines))
+	}
 	args = append(args, path)
 
 	return exec.CommandContext(ctx, name, args...)
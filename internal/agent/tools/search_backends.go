@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// SearchResult is a web page found by a search.
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchBackend looks up web pages matching a query.
+type SearchBackend interface {
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// NewSearchBackend returns the backend of provider. All providers but
+// DuckDuckGo need an API key.
+func NewSearchBackend(provider config.SearchProvider, apiKey string, client *http.Client) (SearchBackend, error) {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		}
+	}
+
+	var backend SearchBackend
+	switch provider {
+	case config.SearchProviderDuckDuckGo, "":
+		return &duckDuckGoSearch{client: client, baseURL: "https://html.duckduckgo.com/html/"}, nil
+	case config.SearchProviderBrave:
+		backend = &braveSearch{client: client, apiKey: apiKey, baseURL: "https://api.search.brave.com/res/v1/web/search"}
+	case config.SearchProviderTavily:
+		backend = &tavilySearch{client: client, apiKey: apiKey, baseURL: "https://api.tavily.com/search"}
+	case config.SearchProviderExa:
+		backend = &exaSearch{client: client, apiKey: apiKey, baseURL: "https://api.exa.ai/search"}
+	default:
+		return nil, fmt.Errorf("unknown search provider: %s", provider)
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("the %s search provider needs an API key", provider)
+	}
+	return backend, nil
+}
+
+// duckDuckGoSearch scrapes the results of the HTML version of DuckDuckGo,
+// which needs no API key.
+type duckDuckGoSearch struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (d *duckDuckGoSearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	form := url.Values{"q": {query}}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.baseURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "crush/1.0")
+
+	body, err := doSearchRequest(d.client, req)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	var results []SearchResult
+	doc.Find(".result").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if s.HasClass("result--ad") {
+			return true
+		}
+		link := s.Find("a.result__a").First()
+		href, ok := link.Attr("href")
+		if !ok {
+			return true
+		}
+		results = append(results, SearchResult{
+			Title:   strings.TrimSpace(link.Text()),
+			URL:     duckDuckGoTarget(href),
+			Snippet: strings.TrimSpace(s.Find(".result__snippet").Text()),
+		})
+		return len(results) < limit
+	})
+	return results, nil
+}
+
+// duckDuckGoTarget returns the page a DuckDuckGo result links to, through
+// its redirect.
+func duckDuckGoTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+type braveSearch struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+func (b *braveSearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(limit)}}
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doSearchJSONRequest(b.client, req, &response); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(response.Web.Results))
+	for _, r := range response.Web.Results {
+		results = append(results, SearchResult{Title: stripTags(r.Title), URL: r.URL, Snippet: stripTags(r.Description)})
+	}
+	return results, nil
+}
+
+type tavilySearch struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+func (t *tavilySearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	req, err := newSearchJSONRequest(ctx, t.baseURL, map[string]any{
+		"query":       query,
+		"max_results": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	var response struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearchJSONRequest(t.client, req, &response); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(response.Results))
+	for _, r := range response.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+type exaSearch struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+func (e *exaSearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	req, err := newSearchJSONRequest(ctx, e.baseURL, map[string]any{
+		"query":      query,
+		"numResults": limit,
+		"contents":   map[string]any{"highlights": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", e.apiKey)
+
+	var response struct {
+		Results []struct {
+			Title      string   `json:"title"`
+			URL        string   `json:"url"`
+			Highlights []string `json:"highlights"`
+		} `json:"results"`
+	}
+	if err := doSearchJSONRequest(e.client, req, &response); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(response.Results))
+	for _, r := range response.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: strings.Join(r.Highlights, " … ")})
+	}
+	return results, nil
+}
+
+func newSearchJSONRequest(ctx context.Context, endpoint string, body any) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal search request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func doSearchJSONRequest(client *http.Client, req *http.Request, out any) error {
+	body, err := doSearchRequest(client, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse search results: %w", err)
+	}
+	return nil
+}
+
+func doSearchRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	maxSize := int64(5 * 1024 * 1024) // 5MB
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if msg := strings.TrimSpace(string(body)); msg != "" && len(msg) < 500 {
+			return nil, fmt.Errorf("search failed with status code %d: %s", resp.StatusCode, msg)
+		}
+		return nil, fmt.Errorf("search failed with status code %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags removes the HTML markup highlighting the query in results.
+func stripTags(s string) string {
+	return html.UnescapeString(tagPattern.ReplaceAllString(s, ""))
+}
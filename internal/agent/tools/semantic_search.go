@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/semindex"
+)
+
+type SemanticSearchParams struct {
+	Query string `json:"query" description:"What the code to find does, in natural language"`
+	Limit int    `json:"limit,omitempty" description:"The number of chunks to return (default 10, max 30)"`
+}
+
+const (
+	SemanticSearchToolName = "semantic_search"
+	defaultSemanticResults = 10
+	maxSemanticResults     = 30
+)
+
+//go:embed semantic_search.md
+var semanticSearchDescription []byte
+
+func NewSemanticSearchTool(index *semindex.Index, workingDir string) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		SemanticSearchToolName,
+		string(semanticSearchDescription),
+		func(ctx context.Context, params SemanticSearchParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if strings.TrimSpace(params.Query) == "" {
+				return fantasy.NewTextErrorResponse("query is required"), nil
+			}
+			limit := params.Limit
+			if limit <= 0 {
+				limit = defaultSemanticResults
+			}
+			limit = min(limit, maxSemanticResults)
+
+			results, err := index.Search(ctx, params.Query, limit, contentExcludeFunc(ctx, workingDir))
+			if err != nil {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to search the project: %s", err)), nil
+			}
+			if len(results) == 0 {
+				return fantasy.NewTextResponse("No files indexed"), nil
+			}
+			return fantasy.NewTextResponse(formatSemanticResults(results)), nil
+		})
+}
+
+func formatSemanticResults(results []semindex.Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s:%d-%d\n", r.Path, r.StartLine, r.EndLine)
+		sb.WriteString(addLineNumbers(r.Content, r.StartLine))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type SymbolsParams struct {
+	Query string `json:"query,omitempty" description:"The name, or part of the name, of the symbols to find. Required unless path is a file."`
+	Path  string `json:"path,omitempty" description:"A file to list the symbols of, or a directory to narrow down the search to. Defaults to the whole workspace."`
+}
+
+const SymbolsToolName = "lsp_symbols"
+
+//go:embed symbols.md
+var symbolsDescription []byte
+
+func NewSymbolsTool(lspClients *csync.Map[string, *lsp.Client], workingDir string) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		SymbolsToolName,
+		string(symbolsDescription),
+		func(ctx context.Context, params SymbolsParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if lspClients.Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			if params.Path != "" {
+				if info, err := os.Stat(params.Path); err == nil && !info.IsDir() {
+					return documentSymbols(ctx, lspClients, workingDir, params)
+				}
+			}
+			if params.Query == "" {
+				return fantasy.NewTextErrorResponse("query is required unless path is a file"), nil
+			}
+
+			var symbols []protocol.SymbolInformation
+			var allErrs error
+			for client := range lspClients.Seq() {
+				found, err := client.WorkspaceSymbols(ctx, params.Query)
+				if err != nil {
+					slog.Error("Failed to find workspace symbols", "error", err, "lsp", client.GetName(), "query", params.Query)
+					allErrs = errors.Join(allErrs, err)
+					continue
+				}
+				symbols = append(symbols, found...)
+			}
+			if params.Path != "" {
+				dir, err := filepath.Abs(params.Path)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to get absolute path: %s", err)), nil
+				}
+				symbols = slices.DeleteFunc(symbols, func(sym protocol.SymbolInformation) bool {
+					path, err := sym.Location.URI.Path()
+					return err != nil || !strings.HasPrefix(path, dir+string(filepath.Separator))
+				})
+			}
+			symbols = excludeSymbols(ctx, workingDir, symbols)
+
+			if len(symbols) > 0 {
+				return fantasy.NewTextResponse(formatSymbols(symbols)), nil
+			}
+			if allErrs != nil {
+				return fantasy.NewTextErrorResponse(allErrs.Error()), nil
+			}
+			return fantasy.NewTextResponse(fmt.Sprintf("No symbols found for '%s'", params.Query)), nil
+		})
+}
+
+// documentSymbols lists the symbols of the file of params whose name contains
+// its query, if any.
+func documentSymbols(ctx context.Context, lspClients *csync.Map[string, *lsp.Client], workingDir string, params SymbolsParams) (fantasy.ToolResponse, error) {
+	absPath, client, err := clientForMatch(lspClients, grepMatch{path: params.Path})
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	if client == nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("no LSP client handles %s", params.Path)), nil
+	}
+	if IsContentExcluded(ctx, workingDir, absPath) {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("%s is excluded from the content", params.Path)), nil
+	}
+
+	symbols, err := client.DocumentSymbols(ctx, absPath)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to list symbols: %s", err)), nil
+	}
+	if params.Query != "" {
+		query := strings.ToLower(params.Query)
+		symbols = slices.DeleteFunc(symbols, func(sym protocol.SymbolInformation) bool {
+			return !strings.Contains(strings.ToLower(sym.Name), query)
+		})
+	}
+	if len(symbols) == 0 {
+		return fantasy.NewTextResponse(fmt.Sprintf("No symbols found in %s", params.Path)), nil
+	}
+	return fantasy.NewTextResponse(formatSymbols(symbols)), nil
+}
+
+// excludeSymbols removes the symbols in files excluded by the content
+// excluder of ctx.
+func excludeSymbols(ctx context.Context, workingDir string, symbols []protocol.SymbolInformation) []protocol.SymbolInformation {
+	return slices.DeleteFunc(symbols, func(sym protocol.SymbolInformation) bool {
+		path, err := sym.Location.URI.Path()
+		return err == nil && IsContentExcluded(ctx, workingDir, path)
+	})
+}
+
+// formatSymbols lists the symbols as file:line:column, followed by their
+// kind and name, and the symbol they're declared in.
+func formatSymbols(symbols []protocol.SymbolInformation) string {
+	var output strings.Builder
+	files := map[string]bool{}
+	for _, sym := range symbols {
+		path, err := sym.Location.URI.Path()
+		if err != nil {
+			slog.Error("Failed to convert symbol URI to path", "uri", sym.Location.URI, "error", err)
+			continue
+		}
+		files[path] = true
+		start := sym.Location.Range.Start
+		kind := protocol.TableKindMap[sym.Kind]
+		if kind == "" {
+			kind = "Symbol"
+		}
+		fmt.Fprintf(&output, "%s:%d:%d: %s %s", path, start.Line+1, start.Character+1, kind, sym.Name)
+		if sym.ContainerName != "" {
+			fmt.Fprintf(&output, " (in %s)", sym.ContainerName)
+		}
+		output.WriteString("\n")
+	}
+	return fmt.Sprintf("Found %d symbol(s) in %d file(s):\n\n", len(symbols), len(files)) + output.String()
+}
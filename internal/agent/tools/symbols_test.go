@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSymbols(t *testing.T) {
+	t.Parallel()
+
+	symbol := func(name string, kind protocol.SymbolKind, container string, line uint32) protocol.SymbolInformation {
+		return protocol.SymbolInformation{
+			Name:          name,
+			Kind:          kind,
+			ContainerName: container,
+			Location: protocol.Location{
+				URI:   protocol.URIFromPath("/src/server.go"),
+				Range: protocol.Range{Start: protocol.Position{Line: line, Character: 5}},
+			},
+		}
+	}
+
+	output := formatSymbols([]protocol.SymbolInformation{
+		symbol("Server", protocol.Struct, "", 9),
+		symbol("Start", protocol.Method, "Server", 14),
+	})
+	require.Equal(t, "Found 2 symbol(s) in 1 file(s):\n\n"+
+		"/src/server.go:10:6: Struct Server\n"+
+		"/src/server.go:15:6: Method Start (in Server)\n", output)
+}
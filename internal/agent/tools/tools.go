@@ -2,18 +2,32 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"charm.land/fantasy"
 )
 
 type (
-	sessionIDContextKey string
-	messageIDContextKey string
+	sessionIDContextKey       string
+	messageIDContextKey       string
+	contentExcluderContextKey string
 )
 
 const (
-	SessionIDContextKey sessionIDContextKey = "session_id"
-	MessageIDContextKey messageIDContextKey = "message_id"
+	SessionIDContextKey       sessionIDContextKey       = "session_id"
+	MessageIDContextKey       messageIDContextKey       = "message_id"
+	ContentExcluderContextKey contentExcluderContextKey = "content_excluder"
 )
 
+// ContentExcluder decides whether a file must never be sent to the model,
+// e.g. because of a provider content exclusion policy.
+type ContentExcluder interface {
+	// IsExcluded receives a path relative to the working directory.
+	IsExcluded(relPath string) bool
+}
+
 func GetSessionFromContext(ctx context.Context) string {
 	sessionID := ctx.Value(SessionIDContextKey)
 	if sessionID == nil {
@@ -37,3 +51,46 @@ func GetMessageFromContext(ctx context.Context) string {
 	}
 	return s
 }
+
+func GetContentExcluderFromContext(ctx context.Context) ContentExcluder {
+	excluder, ok := ctx.Value(ContentExcluderContextKey).(ContentExcluder)
+	if !ok {
+		return nil
+	}
+	return excluder
+}
+
+// IsContentExcluded reports whether the file at path is excluded by the
+// content excluder in the context, if any. Files outside the working
+// directory are never excluded.
+func IsContentExcluded(ctx context.Context, workingDir, path string) bool {
+	excluder := GetContentExcluderFromContext(ctx)
+	if excluder == nil {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	relPath, err := filepath.Rel(workingDir, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+	return excluder.IsExcluded(relPath)
+}
+
+// contentExcludeFunc returns the function telling whether an absolute path
+// is excluded by the content excluder in the context, or nil without one.
+func contentExcludeFunc(ctx context.Context, workingDir string) func(path string) bool {
+	if GetContentExcluderFromContext(ctx) == nil {
+		return nil
+	}
+	return func(path string) bool {
+		return IsContentExcluded(ctx, workingDir, path)
+	}
+}
+
+// contentExcludedResponse is the response of the tools refusing to touch
+// the file at path because of the content exclusion policy.
+func contentExcludedResponse(path string) fantasy.ToolResponse {
+	return fantasy.NewTextErrorResponse(fmt.Sprintf("File %s is excluded by the content exclusion policy and cannot be accessed", path))
+}
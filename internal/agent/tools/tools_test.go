@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type excludeNamed string
+
+func (e excludeNamed) IsExcluded(relPath string) bool {
+	return filepath.Base(relPath) == string(e)
+}
+
+func TestIsContentExcluded(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	ctx := context.WithValue(context.Background(), ContentExcluderContextKey, excludeNamed("secret.txt"))
+
+	require.True(t, IsContentExcluded(ctx, workingDir, filepath.Join(workingDir, "secret.txt")))
+	require.True(t, IsContentExcluded(ctx, workingDir, "nested/secret.txt"))
+	require.False(t, IsContentExcluded(ctx, workingDir, filepath.Join(workingDir, "public.txt")))
+	require.False(t, IsContentExcluded(ctx, workingDir, filepath.Join(filepath.Dir(workingDir), "secret.txt")))
+	require.False(t, IsContentExcluded(context.Background(), workingDir, filepath.Join(workingDir, "secret.txt")))
+}
+
+func TestContentExcludedTools(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "secret.txt"), []byte("match secret\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "public.txt"), []byte("match public\n"), 0o644))
+	ctx := context.WithValue(t.Context(), ContentExcluderContextKey, excludeNamed("secret.txt"))
+
+	run := func(tool fantasy.AgentTool, params any) fantasy.ToolResponse {
+		t.Helper()
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		resp, err := tool.Run(ctx, fantasy.ToolCall{Input: string(input)})
+		require.NoError(t, err)
+		return resp
+	}
+
+	for _, resp := range []fantasy.ToolResponse{
+		run(NewGlobTool(workingDir), GlobParams{Pattern: "*.txt"}),
+		run(NewGrepTool(workingDir), GrepParams{Pattern: "match"}),
+	} {
+		require.Contains(t, resp.Content, "public")
+		require.NotContains(t, resp.Content, "secret")
+	}
+
+	output, _, err := ListDirectoryTree(ctx, workingDir, workingDir, LSParams{}, config.ToolLs{})
+	require.NoError(t, err)
+	require.Contains(t, output, "public.txt")
+	require.NotContains(t, output, "secret.txt")
+
+	resp := run(NewWriteTool(nil, nil, nil, workingDir), WriteParams{FilePath: "secret.txt", Content: "leak"})
+	require.True(t, resp.IsError)
+	content, err := os.ReadFile(filepath.Join(workingDir, "secret.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "match secret\n", string(content))
+
+	patches, err := parsePatch("--- a/secret.txt\n+++ b/secret.txt\n@@ -1 +1 @@\n-match secret\n+leak\n")
+	require.NoError(t, err)
+	changed, results, err := patchFiles(ctx, workingDir, patches)
+	require.NoError(t, err)
+	require.Empty(t, changed)
+	require.Equal(t, "failed: the file is excluded by the content exclusion policy", results[0].Result)
+}
@@ -14,8 +14,10 @@ import (
 	"charm.land/fantasy"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/filepathext"
+	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/tokens"
 )
 
 //go:embed view.md
@@ -46,7 +48,7 @@ type ViewResponseMetadata struct {
 
 const (
 	ViewToolName     = "view"
-	MaxReadSize      = 250 * 1024
+	MaxReadTokens    = 60_000
 	DefaultReadLimit = 2000
 	MaxLineLength    = 2000
 )
@@ -99,6 +101,13 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 				}
 			}
 
+			if IsContentExcluded(ctx, absWorkingDir, absFilePath) {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("File %s is excluded by the content exclusion policy and cannot be read", params.FilePath)), nil
+			}
+			if fsext.NewCrushIgnore(absWorkingDir).Ignores(absFilePath) {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("File %s is excluded by .crushignore and cannot be read", params.FilePath)), nil
+			}
+
 			// Check if file exists
 			fileInfo, err := os.Stat(filePath)
 			if err != nil {
@@ -136,11 +145,9 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 				return fantasy.NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 			}
 
-			// Check file size
-			if fileInfo.Size() > MaxReadSize {
-				return fantasy.NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes",
-					fileInfo.Size(), MaxReadSize)), nil
-			}
+			// Files too large to read whole are outlined, and only the
+			// requested lines fitting in the rest of the budget are read.
+			oversized := exceedsReadBudget(filePath, fileInfo.Size())
 
 			// Set default limit if not provided
 			if params.Limit <= 0 {
@@ -165,7 +172,12 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 			}
 
 			notifyLSPs(ctx, lspClients, filePath)
-			output := "<file>\n"
+			output := ""
+			if oversized {
+				output = largeFileOutline(filePath, fileInfo.Size(), lineCount)
+				content = clipLines(content, MaxReadTokens-tokens.Estimate(output))
+			}
+			output += "<file>\n"
 			// Format the output with line numbers
 			output += addLineNumbers(content, params.Offset+1)
 
@@ -175,8 +187,8 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 					params.Offset+len(strings.Split(content, "\n")))
 			}
 			output += "\n</file>\n"
-			output += getDiagnostics(filePath, lspClients)
-			recordFileRead(filePath)
+			output += getDiagnostics(filePath, lspClients, contentExcludeFunc(ctx, workingDir))
+			recordFileRead(GetSessionFromContext(ctx), filePath)
 			return fantasy.WithResponseMetadata(
 				fantasy.NewTextResponse(output),
 				ViewResponseMetadata{
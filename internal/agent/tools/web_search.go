@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+type WebSearchParams struct {
+	Query      string `json:"query" description:"The search query"`
+	MaxResults int    `json:"max_results,omitempty" description:"Optional number of results to return (max 20)"`
+}
+
+type WebSearchResponseMetadata struct {
+	Results []SearchResult `json:"results"`
+}
+
+const (
+	WebSearchToolName = "web_search"
+
+	maxWebSearchResults = 20
+)
+
+//go:embed web_search.md
+var webSearchDescription []byte
+
+// NewWebSearchTool creates a tool searching the web with backend, returning
+// maxResults results unless the model asks for another number.
+func NewWebSearchTool(backend SearchBackend, maxResults int) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		WebSearchToolName,
+		string(webSearchDescription),
+		func(ctx context.Context, params WebSearchParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			query := strings.TrimSpace(params.Query)
+			if query == "" {
+				return fantasy.NewTextErrorResponse("query parameter is required"), nil
+			}
+			limit := maxResults
+			if params.MaxResults > 0 {
+				limit = params.MaxResults
+			}
+			limit = min(limit, maxWebSearchResults)
+
+			requestCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			results, err := backend.Search(requestCtx, query, limit)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if len(results) > limit {
+				results = results[:limit]
+			}
+			if len(results) == 0 {
+				return fantasy.NewTextResponse("No results found"), nil
+			}
+
+			return fantasy.WithResponseMetadata(
+				fantasy.NewTextResponse(formatSearchResults(results)),
+				WebSearchResponseMetadata{Results: results},
+			), nil
+		})
+}
+
+func formatSearchResults(results []SearchResult) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d results:\n", len(results))
+	for i, r := range results {
+		fmt.Fprintf(&output, "\n%d. %s\n   %s\n", i+1, r.Title, r.URL)
+		if snippet := strings.Join(strings.Fields(r.Snippet), " "); snippet != "" {
+			fmt.Fprintf(&output, "   %s\n", snippet)
+		}
+	}
+	output.WriteString("\nUse the fetch tool to read the pages that look relevant.")
+	return output.String()
+}
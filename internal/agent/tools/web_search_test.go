@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchBackends(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		provider config.SearchProvider
+		handler  func(t *testing.T, w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			provider: config.SearchProviderDuckDuckGo,
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				require.Equal(t, "golang context", r.PostForm.Get("q"))
+				io.WriteString(w, `<html><body>
+<div class="result result--ad"><a class="result__a" href="https://ads.example.com">Ad</a></div>
+<div class="result"><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fpkg.go.dev%2Fcontext&rut=abc">Package context</a>
+<a class="result__snippet">Package context defines
+the Context type.</a></div>
+<div class="result"><a class="result__a" href="https://go.dev/blog/context">Go Concurrency Patterns: Context</a></div>
+</body></html>`)
+			},
+		},
+		{
+			provider: config.SearchProviderBrave,
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "key", r.Header.Get("X-Subscription-Token"))
+				require.Equal(t, "golang context", r.URL.Query().Get("q"))
+				io.WriteString(w, `{"web":{"results":[
+{"title":"Package context","url":"https://pkg.go.dev/context","description":"Package <strong>context</strong> defines the Context type."},
+{"title":"Go Concurrency Patterns: Context","url":"https://go.dev/blog/context","description":""}]}}`)
+			},
+		},
+		{
+			provider: config.SearchProviderTavily,
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+				var body map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				require.Equal(t, "golang context", body["query"])
+				io.WriteString(w, `{"results":[
+{"title":"Package context","url":"https://pkg.go.dev/context","content":"Package context defines the Context type."},
+{"title":"Go Concurrency Patterns: Context","url":"https://go.dev/blog/context","content":""}]}`)
+			},
+		},
+		{
+			provider: config.SearchProviderExa,
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "key", r.Header.Get("x-api-key"))
+				io.WriteString(w, `{"results":[
+{"title":"Package context","url":"https://pkg.go.dev/context","highlights":["Package context defines the Context type."]},
+{"title":"Go Concurrency Patterns: Context","url":"https://go.dev/blog/context"}]}`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(t, w, r)
+			}))
+			defer server.Close()
+
+			backend, err := NewSearchBackend(tt.provider, "key", server.Client())
+			require.NoError(t, err)
+			switch b := backend.(type) {
+			case *duckDuckGoSearch:
+				b.baseURL = server.URL
+			case *braveSearch:
+				b.baseURL = server.URL
+			case *tavilySearch:
+				b.baseURL = server.URL
+			case *exaSearch:
+				b.baseURL = server.URL
+			}
+
+			tool := NewWebSearchTool(backend, 5)
+			resp, err := tool.Run(t.Context(), fantasy.ToolCall{ID: "1", Name: WebSearchToolName, Input: `{"query":"golang context"}`})
+			require.NoError(t, err)
+			require.False(t, resp.IsError, resp.Content)
+			require.Equal(t, "Found 2 results:\n\n"+
+				"1. Package context\n   https://pkg.go.dev/context\n   Package context defines the Context type.\n\n"+
+				"2. Go Concurrency Patterns: Context\n   https://go.dev/blog/context\n\n"+
+				"Use the fetch tool to read the pages that look relevant.", resp.Content)
+		})
+	}
+}
+
+func TestNewSearchBackendNeedsAPIKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSearchBackend(config.SearchProviderBrave, "", nil)
+	require.Error(t, err)
+	_, err = NewSearchBackend("bing", "key", nil)
+	require.Error(t, err)
+	_, err = NewSearchBackend(config.SearchProviderDuckDuckGo, "", nil)
+	require.NoError(t, err)
+}
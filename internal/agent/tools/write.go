@@ -64,6 +64,9 @@ func NewWriteTool(lspClients *csync.Map[string, *lsp.Client], permissions permis
 			}
 
 			filePath := filepathext.SmartJoin(workingDir, params.FilePath)
+			if IsContentExcluded(ctx, workingDir, filePath) {
+				return contentExcludedResponse(filePath), nil
+			}
 
 			fileInfo, err := os.Stat(filePath)
 			if err == nil {
@@ -72,7 +75,7 @@ func NewWriteTool(lspClients *csync.Map[string, *lsp.Client], permissions permis
 				}
 
 				modTime := fileInfo.ModTime()
-				lastRead := getLastReadTime(filePath)
+				lastRead := getLastReadTime(GetSessionFromContext(ctx), filePath)
 				if modTime.After(lastRead) {
 					return fantasy.NewTextErrorResponse(fmt.Sprintf("File %s has been modified since it was last read.\nLast modification: %s\nLast read: %s\n\nPlease read the file again before modifying it.",
 						filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339))), nil
@@ -156,14 +159,14 @@ func NewWriteTool(lspClients *csync.Map[string, *lsp.Client], permissions permis
 				slog.Error("Error creating file history version", "error", err)
 			}
 
-			recordFileWrite(filePath)
-			recordFileRead(filePath)
+			recordFileWrite(sessionID, filePath)
+			recordFileRead(sessionID, filePath)
 
-			notifyLSPs(ctx, lspClients, params.FilePath)
+			notifyLSPs(ctx, lspClients, filePath)
 
 			result := fmt.Sprintf("File successfully written: %s", filePath)
 			result = fmt.Sprintf("<result>\n%s\n</result>", result)
-			result += getDiagnostics(filePath, lspClients)
+			result += getDiagnostics(filePath, lspClients, contentExcludeFunc(ctx, workingDir))
 			return fantasy.WithResponseMetadata(fantasy.NewTextResponse(result),
 				WriteResponseMetadata{
 					Diff:      diff,
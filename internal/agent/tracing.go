@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the spans, after the OpenTelemetry conventions for
+// generative AI.
+const (
+	attrSessionID     = attribute.Key("session.id")
+	attrProvider      = attribute.Key("gen_ai.system")
+	attrModel         = attribute.Key("gen_ai.request.model")
+	attrInputTokens   = attribute.Key("gen_ai.usage.input_tokens")
+	attrOutputTokens  = attribute.Key("gen_ai.usage.output_tokens")
+	attrFinishReasons = attribute.Key("gen_ai.response.finish_reasons")
+	attrToolName      = attribute.Key("gen_ai.tool.name")
+	attrToolCallID    = attribute.Key("gen_ai.tool.call.id")
+)
+
+// requestSpan traces the requests of a run to the model, one at a time.
+type requestSpan struct {
+	span trace.Span
+}
+
+// start starts the span of a request to model.
+func (r *requestSpan) start(ctx context.Context, model Model) {
+	_, r.span = tracing.Start(ctx, "chat "+model.ModelCfg.Model,
+		attrProvider.String(model.ModelCfg.Provider),
+		attrModel.String(model.ModelCfg.Model),
+	)
+}
+
+// finish ends the span of the request with its usage.
+func (r *requestSpan) finish(usage fantasy.Usage, reason fantasy.FinishReason) {
+	if r.span == nil {
+		return
+	}
+	r.span.SetAttributes(
+		attrInputTokens.Int64(usage.InputTokens+usage.CacheCreationTokens+usage.CacheReadTokens),
+		attrOutputTokens.Int64(usage.OutputTokens),
+		attrFinishReasons.StringSlice([]string{string(reason)}),
+	)
+	r.end(nil)
+}
+
+// end ends the span of the request, if not ended yet, as failed with err if
+// not nil.
+func (r *requestSpan) end(err error) {
+	if r.span == nil {
+		return
+	}
+	tracing.End(r.span, err)
+	r.span = nil
+}
+
+var errToolFailed = errors.New("the tool returned an error")
+
+// tracedTool traces the runs of a tool.
+type tracedTool struct {
+	fantasy.AgentTool
+}
+
+// traceTools wraps the tools to trace their runs.
+func traceTools(agentTools []fantasy.AgentTool) []fantasy.AgentTool {
+	traced := make([]fantasy.AgentTool, len(agentTools))
+	for i, tool := range agentTools {
+		traced[i] = &tracedTool{AgentTool: tool}
+	}
+	return traced
+}
+
+func (t *tracedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	ctx, span := tracing.Start(ctx, "execute_tool "+call.Name,
+		attrToolName.String(call.Name),
+		attrToolCallID.String(call.ID),
+	)
+	resp, err := t.AgentTool.Run(ctx, call)
+	spanErr := err
+	if spanErr == nil && resp.IsError {
+		// The model is told about the error, it's a failure all the same.
+		// Its output is left out, like that of the other tool calls.
+		spanErr = errToolFailed
+	}
+	tracing.End(span, spanErr)
+	return resp, err
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -17,7 +18,9 @@ import (
 	"charm.land/fantasy"
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/db"
@@ -26,11 +29,14 @@ import (
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/shell"
 	"github.com/charmbracelet/crush/internal/term"
+	"github.com/charmbracelet/crush/internal/tracing"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/update"
@@ -44,6 +50,8 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	// Checkpoints is nil when checkpoints are disabled.
+	Checkpoints checkpoint.Service
 
 	AgentCoordinator agent.Coordinator
 
@@ -64,7 +72,7 @@ type App struct {
 // New initializes a new applcation instance.
 func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	q := db.New(conn)
-	sessions := session.NewService(q)
+	sessions := session.NewService(q, cfg.WorkingDir())
 	messages := message.NewService(q)
 	files := history.NewService(q, conn)
 	skipPermissionsRequests := cfg.Permissions != nil && cfg.Permissions.SkipRequests
@@ -73,11 +81,15 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		allowedTools = cfg.Permissions.AllowedTools
 	}
 
+	// Command prefixes the user always allows are kept with the project data.
+	allowedCommandsPath := filepath.Join(cfg.Options.DataDirectory, permission.AllowedCommandsFile)
+	permissions := permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools, allowedCommandsPath)
+
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
-		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
+		Permissions: permissions,
 		LSPClients:  csync.NewMap[string, *lsp.Client](),
 
 		globalCtx: ctx,
@@ -89,6 +101,10 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		tuiWG:           &sync.WaitGroup{},
 	}
 
+	if !cfg.Options.DisableCheckpoints {
+		app.Checkpoints = checkpoint.NewService(filepath.Join(cfg.Options.DataDirectory, "checkpoints"), cfg.WorkingDir())
+	}
+
 	app.setupEvents()
 
 	// Initialize LSP clients in the background.
@@ -105,6 +121,27 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	// cleanup database upon app shutdown
 	app.cleanupFuncs = append(app.cleanupFuncs, conn.Close, mcp.Close)
 
+	// Export traces of the agent runs when a collector is configured.
+	if err := app.setupTracing(ctx); err != nil {
+		slog.Warn("Failed to set up tracing", "error", err)
+	}
+
+	// Watch the files the agent reads, to tell it when they change.
+	if stopWatcher, err := tools.StartFileWatcher(func(path string) {
+		app.notifyLSPFileChanged(ctx, path)
+	}); err != nil {
+		slog.Warn("Failed to start file watcher", "error", err)
+	} else {
+		app.cleanupFuncs = append(app.cleanupFuncs, stopWatcher)
+	}
+	go func() {
+		for event := range app.Sessions.Subscribe(ctx) {
+			if event.Type == pubsub.DeletedEvent {
+				tools.ForgetFiles(event.Payload.ID)
+			}
+		}
+	}()
+
 	// TODO: remove the concept of agent config, most likely.
 	if !cfg.IsConfigured() {
 		slog.Warn("No agent configuration found")
@@ -122,13 +159,16 @@ func (app *App) Config() *config.Config {
 }
 
 // RunNonInteractive runs the application in non-interactive mode with the
-// given prompt, printing to stdout.
-func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt string, quiet bool) error {
+// given prompt, printing to output in the given format.
+func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt string, outputFormat OutputFormat, quiet bool) error {
 	slog.Info("Running in non-interactive mode")
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// The spinner would get in the way of the JSON output formats.
+	quiet = quiet || outputFormat != OutputFormatText
+
 	var spinner *format.Spinner
 	if !quiet {
 		t := styles.CurrentTheme()
@@ -183,38 +223,63 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt
 	// session.
 	app.Permissions.AutoApproveSession(sess.ID)
 
+	printer := newRunPrinter(outputFormat, output, sess.ID)
+	model, _ := app.config.GetSelectedModel(config.SelectedModelTypeLarge)
+	if err := printer.start(model.Model, model.Provider); err != nil {
+		return err
+	}
+
 	type response struct {
 		result *fantasy.AgentResult
 		err    error
 	}
 	done := make(chan response, 1)
 
+	// Subscribe before starting the run so that no message is missed.
+	messageEvents := app.Messages.Subscribe(ctx)
+
 	go func(ctx context.Context, sessionID, prompt string) {
-		result, err := app.AgentCoordinator.Run(ctx, sess.ID, prompt)
+		result, err := app.AgentCoordinator.Run(ctx, sessionID, prompt)
 		if err != nil {
 			done <- response{
 				err: fmt.Errorf("failed to start agent processing stream: %w", err),
 			}
+			return
 		}
 		done <- response{
 			result: result,
 		}
 	}(ctx, sess.ID, prompt)
 
-	messageEvents := app.Messages.Subscribe(ctx)
-	messageReadBytes := make(map[string]int)
 	supportsProgressBar := term.SupportsProgressBar()
 
 	defer func() {
 		if supportsProgressBar {
 			_, _ = fmt.Fprintf(os.Stderr, ansi.ResetProgressBar)
 		}
-
-		// Always print a newline at the end. If output is a TTY this will
-		// prevent the prompt from overwriting the last line of output.
-		_, _ = fmt.Fprintln(output)
 	}()
 
+	// finish prints what's left of the run along with its summary, reading
+	// the messages from the database since the last events may not have
+	// been received yet.
+	finish := func(runErr error) error {
+		stopSpinner()
+		finishCtx := context.WithoutCancel(ctx)
+		messages, err := app.Messages.List(finishCtx, sess.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		for _, msg := range messages {
+			if err := printer.message(msg); err != nil {
+				return err
+			}
+		}
+		if updated, err := app.Sessions.Get(finishCtx, sess.ID); err == nil {
+			sess = updated
+		}
+		return printer.result(sess, messages, runErr)
+	}
+
 	for {
 		if supportsProgressBar {
 			// HACK: Reinitialize the terminal progress bar on every iteration so
@@ -224,32 +289,27 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt
 
 		select {
 		case result := <-done:
-			stopSpinner()
 			if result.err != nil {
 				if errors.Is(result.err, context.Canceled) || errors.Is(result.err, agent.ErrRequestCancelled) {
 					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID)
-					return nil
+					return finish(result.err)
 				}
-				return fmt.Errorf("agent processing failed: %w", result.err)
+				err := fmt.Errorf("agent processing failed: %w", result.err)
+				if printErr := finish(err); printErr != nil {
+					return errors.Join(err, printErr)
+				}
+				return err
 			}
-			return nil
+			return finish(nil)
 
 		case event := <-messageEvents:
 			msg := event.Payload
 			if msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
 				stopSpinner()
-
-				content := msg.Content().String()
-				readBytes := messageReadBytes[msg.ID]
-
-				if len(content) < readBytes {
-					slog.Error("Non-interactive: message content is shorter than read bytes", "message_length", len(content), "read_bytes", readBytes)
-					return fmt.Errorf("message content is shorter than read bytes: %d < %d", len(content), readBytes)
-				}
-
-				part := content[readBytes:]
-				fmt.Fprint(output, part)
-				messageReadBytes[msg.ID] = len(content)
+			}
+			if err := printer.message(msg); err != nil {
+				slog.Error("Non-interactive: failed to print message", "error", err)
+				return err
 			}
 
 		case <-ctx.Done():
@@ -273,6 +333,10 @@ func (app *App) setupEvents() {
 	setupSubscriber(ctx, app.serviceEventsWG, "history", app.History.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "mcp", mcp.SubscribeEvents, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "copilot", copilot.SubscribeEvents, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "oauth", oauth.SubscribeTokenEvents, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "background-agents", agent.SubscribeBackgroundTasks, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "tool-output", tools.SubscribeToolOutput, app.events)
 	cleanupFunc := func() error {
 		cancel()
 		app.serviceEventsWG.Wait()
@@ -327,6 +391,7 @@ func (app *App) InitCoderAgent(ctx context.Context) error {
 		app.Messages,
 		app.Permissions,
 		app.History,
+		app.Checkpoints,
 		app.LSPClients,
 	)
 	if err != nil {
@@ -353,6 +418,8 @@ func (app *App) Subscribe(program *tea.Program) {
 	})
 	defer app.tuiWG.Done()
 
+	app.watchConfig(tuiCtx)
+
 	for {
 		select {
 		case <-tuiCtx.Done():
@@ -368,6 +435,26 @@ func (app *App) Subscribe(program *tea.Program) {
 	}
 }
 
+// setupTracing starts exporting traces, sending the spans left on shutdown.
+func (app *App) setupTracing(ctx context.Context) error {
+	var endpoint string
+	var headers map[string]string
+	if opts := app.config.Options.Tracing; opts != nil {
+		endpoint = opts.Endpoint
+		headers = opts.ResolvedHeaders(app.config.Resolver())
+	}
+	shutdown, err := tracing.Setup(ctx, endpoint, headers, version.Version)
+	if err != nil {
+		return err
+	}
+	app.cleanupFuncs = append(app.cleanupFuncs, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdown(ctx)
+	})
+	return nil
+}
+
 // Shutdown performs a graceful shutdown of the application.
 func (app *App) Shutdown() {
 	if app.AgentCoordinator != nil {
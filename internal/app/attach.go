@@ -0,0 +1,268 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxAttachedInputWidth is the width the input of the tool calls is cut to
+// when printed by attached instances.
+const maxAttachedInputWidth = 80
+
+// Attach follows the session worked on last in the instance of Crush
+// serving socket, printing its responses to output as they're written, and
+// queues each line read from input as a prompt in it. It returns once input
+// is closed or the instance exits.
+func Attach(ctx context.Context, socket string, input io.Reader, output io.Writer) error {
+	conn, events, ready, err := dialInstance(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	printer := &attachPrinter{output: output, sessionID: ready.SessionID}
+	if _, err := fmt.Fprintf(output, "Attached to Crush in %s. Type a prompt and press enter to queue it, ctrl+d to detach.\n", ready.WorkingDir); err != nil {
+		return err
+	}
+
+	var detached atomic.Bool
+	go func() {
+		commands := json.NewEncoder(conn)
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			if err := commands.Encode(StreamCommand{Type: "prompt", SessionID: printer.session(), Text: text, Queue: true}); err != nil {
+				break
+			}
+		}
+		detached.Store(true)
+		conn.Close()
+	}()
+
+	for {
+		var event StreamEvent
+		if err := events.Decode(&event); err != nil {
+			if detached.Load() || ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, io.EOF) {
+				return printer.println("Crush exited.")
+			}
+			return fmt.Errorf("failed to read event: %w", err)
+		}
+		if err := printer.print(event); err != nil {
+			return err
+		}
+	}
+}
+
+// RunAttached runs prompt in a new session of the instance of Crush serving
+// socket, printing its response to output as it's written. It returns once
+// the run is over, with its error when it failed.
+func RunAttached(ctx context.Context, socket, prompt string, output io.Writer) error {
+	conn, events, _, err := dialInstance(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(StreamCommand{Type: "prompt", Text: prompt}); err != nil {
+		return fmt.Errorf("failed to send prompt: %w", err)
+	}
+	printer := &attachPrinter{output: output}
+	for {
+		var event StreamEvent
+		if err := events.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read event: %w", err)
+		}
+		if printer.session() == "" {
+			// The events before the prompt's are the other sessions'.
+			if event.Type == "prompt" && event.Text == prompt {
+				printer.follow(event.SessionID)
+			} else if event.Type == "error" {
+				return errors.New(event.Error)
+			}
+			continue
+		}
+		if err := printer.print(event); err != nil {
+			return err
+		}
+		if event.Type == "done" && event.SessionID == printer.session() {
+			switch event.Reason {
+			case "error":
+				return errors.New(event.Error)
+			case "canceled":
+				return context.Canceled
+			}
+			return nil
+		}
+	}
+}
+
+// QueuePrompt queues text in the session worked on last in the instance of
+// Crush serving socket, or runs it right away when the session is idle. It
+// returns the session.
+func QueuePrompt(ctx context.Context, socket, text string) (string, error) {
+	conn, events, ready, err := dialInstance(ctx, socket)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(StreamCommand{Type: "prompt", SessionID: ready.SessionID, Text: text, Queue: true}); err != nil {
+		return "", fmt.Errorf("failed to send prompt: %w", err)
+	}
+	for {
+		var event StreamEvent
+		if err := events.Decode(&event); err != nil {
+			return "", fmt.Errorf("failed to read event: %w", err)
+		}
+		switch event.Type {
+		case "prompt", "queued":
+			if event.Text == text {
+				return event.SessionID, nil
+			}
+		case "error":
+			return "", errors.New(event.Error)
+		}
+	}
+}
+
+// dialInstance connects to the instance of Crush serving socket, returning
+// the connection, the decoder of its events and the ready event it starts
+// with.
+func dialInstance(ctx context.Context, socket string) (net.Conn, *json.Decoder, StreamEvent, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, nil, StreamEvent{}, fmt.Errorf("failed to attach: %w", err)
+	}
+	events := json.NewDecoder(conn)
+	var ready StreamEvent
+	if err := events.Decode(&ready); err != nil {
+		conn.Close()
+		return nil, nil, StreamEvent{}, fmt.Errorf("failed to attach: %w", err)
+	}
+	if ready.Type != "ready" {
+		conn.Close()
+		return nil, nil, StreamEvent{}, fmt.Errorf("failed to attach: unexpected %s event", ready.Type)
+	}
+	return conn, events, ready, nil
+}
+
+// attachPrinter prints the events of the session an attached instance
+// follows as plain text.
+type attachPrinter struct {
+	output io.Writer
+
+	mu        sync.Mutex
+	sessionID string
+	// midLine reports whether the text printed last didn't end its line.
+	midLine bool
+}
+
+func (p *attachPrinter) session() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sessionID
+}
+
+// follow makes the printer follow the session.
+func (p *attachPrinter) follow(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessionID = sessionID
+}
+
+// println prints s on a line of its own.
+func (p *attachPrinter) println(s string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.line(s)
+}
+
+func (p *attachPrinter) print(event StreamEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Without a session to follow, the first prompt starts one.
+	if p.sessionID == "" && (event.Type == "prompt" || event.Type == "queued") {
+		p.sessionID = event.SessionID
+	}
+	if event.SessionID != "" && event.SessionID != p.sessionID {
+		return nil
+	}
+
+	switch event.Type {
+	case "prompt":
+		return p.line("> " + event.Text)
+	case "queued":
+		return p.line("Queued: " + event.Text)
+	case "text_delta":
+		if _, err := io.WriteString(p.output, event.Text); err != nil {
+			return err
+		}
+		p.midLine = !strings.HasSuffix(event.Text, "\n")
+	case "tool_start":
+		input := strings.Join(strings.Fields(string(event.Input)), " ")
+		return p.line(fmt.Sprintf("→ %s %s", event.Name, ansi.Truncate(input, maxAttachedInputWidth, "…")))
+	case "tool_finish":
+		if event.IsError {
+			content, _, _ := strings.Cut(strings.TrimSpace(event.Content), "\n")
+			return p.line(fmt.Sprintf("✗ %s: %s", event.Name, content))
+		}
+	case "message_finish":
+		if event.Error != "" {
+			return p.line("Error: " + event.Error)
+		}
+		return p.endLine()
+	case "done":
+		return p.endLine()
+	case "permission_request":
+		return p.line(fmt.Sprintf("Waiting for permission to use %s in the other window", event.Permission.ToolName))
+	case "error":
+		return p.line("Error: " + event.Error)
+	}
+	return nil
+}
+
+// line prints s on a line of its own. It's called with p.mu held.
+func (p *attachPrinter) line(s string) error {
+	if err := p.endLine(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(p.output, s)
+	return err
+}
+
+// endLine ends the line the text printed last left open. It's called with
+// p.mu held.
+func (p *attachPrinter) endLine() error {
+	if !p.midLine {
+		return nil
+	}
+	p.midLine = false
+	_, err := fmt.Fprintln(p.output)
+	return err
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachPrinter(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	printer := &attachPrinter{output: &output}
+	for _, event := range []StreamEvent{
+		{Type: "text_delta", SessionID: "other", Text: "Not followed"},
+		{Type: "queued", SessionID: "sess", Text: "Look around"},
+		{Type: "text_delta", SessionID: "sess", Text: "Let me"},
+		{Type: "text_delta", SessionID: "sess", Text: " look."},
+		{Type: "tool_start", SessionID: "sess", Name: "ls", Input: json.RawMessage("{\n  \"path\": \".\"\n}")},
+		{Type: "permission_request", SessionID: "sess", Permission: &permission.PermissionRequest{ToolName: "bash"}},
+		{Type: "tool_finish", SessionID: "sess", Name: "bash", Content: "denied\nby user", IsError: true},
+		{Type: "text_delta", SessionID: "sess", Text: "Done."},
+		{Type: "message_finish", SessionID: "sess", Reason: "end_turn"},
+		{Type: "error", Error: "prompt text is required"},
+	} {
+		require.NoError(t, printer.print(event))
+	}
+	require.Equal(t, "sess", printer.session(), "the first prompt's session is followed")
+	require.Equal(t, `Queued: Look around
+Let me look.
+→ ls { "path": "." }
+Waiting for permission to use bash in the other window
+✗ bash: denied
+Done.
+Error: prompt text is required
+`, output.String())
+}
+
+func TestRunAttached(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "crush.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	serve := func(reason, errMsg string) {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		events := json.NewEncoder(conn)
+		_ = events.Encode(StreamEvent{Type: "ready", SessionID: "last"})
+		var cmd StreamCommand
+		if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&cmd); err != nil {
+			return
+		}
+		for _, event := range []StreamEvent{
+			{Type: "text_delta", SessionID: "last", Text: "Not followed"},
+			{Type: "prompt", SessionID: "other", Text: "Something else"},
+			{Type: "prompt", SessionID: "new", Text: cmd.Text},
+			{Type: "text_delta", SessionID: "other", Text: "Not followed either"},
+			{Type: "text_delta", SessionID: "new", Text: "All good"},
+			{Type: "done", SessionID: "other", Reason: "completed"},
+			{Type: "done", SessionID: "new", Reason: reason, Error: errMsg},
+		} {
+			_ = events.Encode(event)
+		}
+	}
+
+	go serve("completed", "")
+	var output bytes.Buffer
+	require.NoError(t, RunAttached(t.Context(), socket, "Check the tests", &output))
+	require.Equal(t, "All good\n", output.String())
+
+	go serve("error", "rate limited")
+	output.Reset()
+	require.EqualError(t, RunAttached(t.Context(), socket, "Check the tests", &output), "rate limited")
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// ConfigReloadMsg is sent to the TUI when the config files change, with the
+// configuration loaded from them again.
+type ConfigReloadMsg struct {
+	Config *config.Config
+	Err    error
+}
+
+// watchConfig sends a ConfigReloadMsg whenever the config files change,
+// until ctx is done.
+func (app *App) watchConfig(ctx context.Context) {
+	stop, err := config.Watch(app.config.WorkingDir(), func() {
+		fresh, err := app.config.Reload()
+		select {
+		case app.events <- ConfigReloadMsg{Config: fresh, Err: err}:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		slog.Warn("Failed to watch the config files", "error", err)
+		return
+	}
+	app.cleanupFuncs = append(app.cleanupFuncs, stop)
+}
+
+// ApplyConfig applies the settings of fresh that can change while running
+// and starts the MCP servers added, for as long as the app runs. The agent
+// picks up their tools once they connect, but needs UpdateAgentModel for the
+// models selected.
+func (app *App) ApplyConfig(fresh *config.Config) config.Reloaded {
+	reloaded := app.config.Apply(fresh)
+	for _, name := range reloaded.MCP {
+		mcp.Start(app.globalCtx, app.config, name)
+	}
+	return reloaded
+}
@@ -0,0 +1,528 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/instance"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/version"
+)
+
+// StreamEvent is a line of the event stream read by the frontends driving
+// Crush. Type is one of ready, session, prompt, queued, text_delta,
+// reasoning_delta, tool_start, tool_finish, message_finish,
+// permission_request, done and error.
+type StreamEvent struct {
+	Type       string                        `json:"type"`
+	SessionID  string                        `json:"session_id,omitempty"`
+	MessageID  string                        `json:"message_id,omitempty"`
+	Text       string                        `json:"text,omitempty"`
+	ID         string                        `json:"id,omitempty"`
+	ToolCallID string                        `json:"tool_call_id,omitempty"`
+	Name       string                        `json:"name,omitempty"`
+	Input      json.RawMessage               `json:"input,omitempty"`
+	Content    string                        `json:"content,omitempty"`
+	IsError    bool                          `json:"is_error,omitempty"`
+	Reason     string                        `json:"reason,omitempty"`
+	Title      string                        `json:"title,omitempty"`
+	Usage      *RunUsage                     `json:"usage,omitempty"`
+	CostUSD    float64                       `json:"cost_usd,omitempty"`
+	Permission *permission.PermissionRequest `json:"permission,omitempty"`
+	Version    string                        `json:"version,omitempty"`
+	Model      string                        `json:"model,omitempty"`
+	Provider   string                        `json:"provider,omitempty"`
+	WorkingDir string                        `json:"working_dir,omitempty"`
+	Error      string                        `json:"error,omitempty"`
+}
+
+// StreamCommand is a line written by the frontends driving Crush. Type is
+// one of prompt, permission and cancel.
+type StreamCommand struct {
+	Type string `json:"type"`
+	// SessionID is the session to prompt or cancel. Prompts without one
+	// start a new session, cancels without one cancel every session.
+	SessionID string `json:"session_id,omitempty"`
+	// Text is the prompt.
+	Text string `json:"text,omitempty"`
+	// Queue runs the prompt once the session is done with the ones before
+	// it, instead of failing when it's busy.
+	Queue bool `json:"queue,omitempty"`
+	// ID is the permission request to answer with Action: allow,
+	// allow_session or deny.
+	ID     string `json:"id,omitempty"`
+	Action string `json:"action,omitempty"`
+}
+
+// eventStream forwards the events of the app to the frontends connected,
+// and runs their commands.
+type eventStream struct {
+	app *App
+
+	clientsMu sync.Mutex
+	clients   map[*streamClient]struct{}
+
+	// pending holds the permission requests waiting for an answer, by ID.
+	pending *csync.Map[string, permission.PermissionRequest]
+	prompts sync.WaitGroup
+
+	// sentMu guards what was already sent of the messages: the length of
+	// their text and reasoning, and the tool calls, results and finishes.
+	sentMu         sync.Mutex
+	textBytes      map[string]int
+	reasoningBytes map[string]int
+	sent           map[string]bool
+}
+
+func newEventStream(app *App) *eventStream {
+	return &eventStream{
+		app:            app,
+		clients:        make(map[*streamClient]struct{}),
+		pending:        csync.NewMap[string, permission.PermissionRequest](),
+		textBytes:      make(map[string]int),
+		reasoningBytes: make(map[string]int),
+		sent:           make(map[string]bool),
+	}
+}
+
+// streamClient is a frontend connected to the event stream.
+type streamClient struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newStreamClient(w io.Writer) *streamClient {
+	return &streamClient{w: w}
+}
+
+// write writes an encoded event, after the ones written before.
+func (c *streamClient) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.w.Write(data)
+	return err
+}
+
+// ServeEvents streams the events of the app to output as JSON lines, and
+// runs the commands read from input as JSON lines. Once input is closed, it
+// returns when the prompts running are done.
+func (app *App) ServeEvents(ctx context.Context, input io.Reader, output io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream := newEventStream(app)
+	stream.forward(ctx)
+	client := stream.attach(ctx, output)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- stream.handle(ctx, input, client)
+	}()
+
+	select {
+	case err := <-errc:
+		stream.prompts.Wait()
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ServeEventsSocket listens on the unix socket at path, streaming the
+// events of the app to every client connected and running their commands,
+// until ctx is done.
+func (app *App) ServeEventsSocket(ctx context.Context, path string) error {
+	// Remove the socket left by a previous run.
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on socket: %w", err)
+	}
+	return app.serveEventsListener(ctx, listener)
+}
+
+// ServeInstance lets the instances of Crush started in the project after
+// this one attach to it through the socket of lock, which is released on
+// shutdown.
+func (app *App) ServeInstance(lock *instance.Lock) {
+	ctx, cancel := context.WithCancel(app.globalCtx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := app.serveEventsListener(ctx, lock.Listener()); err != nil {
+			slog.Error("Failed to serve the instances attached", "error", err)
+		}
+	}()
+	app.cleanupFuncs = append(app.cleanupFuncs, func() error {
+		cancel()
+		<-done
+		return lock.Release()
+	})
+}
+
+// serveEventsListener streams the events of the app to every client
+// connecting to listener and runs their commands, until ctx is done.
+func (app *App) serveEventsListener(ctx context.Context, listener net.Listener) error {
+	defer listener.Close()
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	stream := newEventStream(app)
+	stream.forward(ctx)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go func() {
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+
+			client := stream.attach(ctx, conn)
+			defer stream.detach(client)
+			if err := stream.handle(ctx, conn, client); err != nil && ctx.Err() == nil {
+				slog.Debug("Event stream client disconnected", "error", err)
+			}
+		}()
+	}
+}
+
+// attach adds a client writing the events to w, sending it the ready event,
+// with the session worked on last, and the permission requests waiting for
+// an answer.
+func (s *eventStream) attach(ctx context.Context, w io.Writer) *streamClient {
+	client := newStreamClient(w)
+	model, _ := s.app.config.GetSelectedModel(config.SelectedModelTypeLarge)
+	ready := StreamEvent{
+		Type:       "ready",
+		Version:    version.Version,
+		Model:      model.Model,
+		Provider:   model.Provider,
+		WorkingDir: s.app.config.WorkingDir(),
+	}
+	if sessions, err := s.app.Sessions.List(ctx); err == nil {
+		var updatedAt int64
+		for _, sess := range sessions {
+			if sess.UpdatedAt > updatedAt {
+				ready.SessionID, updatedAt = sess.ID, sess.UpdatedAt
+			}
+		}
+	}
+	s.send(client, ready)
+	for req := range s.pending.Seq() {
+		s.send(client, StreamEvent{Type: "permission_request", SessionID: req.SessionID, Permission: &req})
+	}
+
+	s.clientsMu.Lock()
+	s.clients[client] = struct{}{}
+	s.clientsMu.Unlock()
+	return client
+}
+
+func (s *eventStream) detach(client *streamClient) {
+	s.clientsMu.Lock()
+	delete(s.clients, client)
+	s.clientsMu.Unlock()
+}
+
+func (s *eventStream) send(client *streamClient, event StreamEvent) {
+	data, err := encodeEvent(event)
+	if err != nil {
+		return
+	}
+	if err := client.write(data); err != nil {
+		slog.Debug("Failed to send event", "type", event.Type, "error", err)
+	}
+}
+
+// broadcast sends event to every client, encoding it once. A slow client
+// only holds up the events sent to it.
+func (s *eventStream) broadcast(event StreamEvent) {
+	data, err := encodeEvent(event)
+	if err != nil {
+		return
+	}
+	s.clientsMu.Lock()
+	clients := slices.Collect(maps.Keys(s.clients))
+	s.clientsMu.Unlock()
+	for _, client := range clients {
+		if err := client.write(data); err != nil {
+			slog.Debug("Failed to send event", "type", event.Type, "error", err)
+		}
+	}
+}
+
+// encodeEvent encodes event as a JSON line.
+func encodeEvent(event StreamEvent) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to encode event", "type", event.Type, "error", err)
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// forward subscribes to the services of the app and broadcasts their
+// events until ctx is done.
+func (s *eventStream) forward(ctx context.Context) {
+	messages := s.app.Messages.Subscribe(ctx)
+	sessions := s.app.Sessions.Subscribe(ctx)
+	permissions := s.app.Permissions.Subscribe(ctx)
+	notifications := s.app.Permissions.SubscribeNotifications(ctx)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-messages:
+				s.message(event.Payload)
+			case event := <-sessions:
+				if event.Type != pubsub.DeletedEvent {
+					s.session(event.Payload)
+				}
+			case event := <-permissions:
+				req := event.Payload
+				s.pending.Set(req.ID, req)
+				s.broadcast(StreamEvent{Type: "permission_request", SessionID: req.SessionID, Permission: &req})
+			case event := <-notifications:
+				if n := event.Payload; n.Granted || n.Denied {
+					s.resolved(n.ToolCallID)
+				}
+			}
+		}
+	}()
+}
+
+// resolved forgets the permission requests of the tool call answered, by a
+// client or elsewhere, so that they aren't sent to the next clients.
+func (s *eventStream) resolved(toolCallID string) {
+	for req := range s.pending.Seq() {
+		if req.ToolCallID == toolCallID {
+			s.pending.Del(req.ID)
+		}
+	}
+}
+
+func (s *eventStream) session(sess session.Session) {
+	s.broadcast(StreamEvent{
+		Type:      "session",
+		SessionID: sess.ID,
+		Title:     sess.Title,
+		Usage: &RunUsage{
+			InputTokens:      sess.TotalPromptTokens,
+			OutputTokens:     sess.TotalCompletionTokens,
+			CacheReadTokens:  sess.TotalCacheReadTokens,
+			CacheWriteTokens: sess.TotalCacheWriteTokens,
+		},
+		CostUSD: sess.Cost,
+	})
+}
+
+// message broadcasts what's new in msg since it was last sent.
+func (s *eventStream) message(msg message.Message) {
+	s.sentMu.Lock()
+	defer s.sentMu.Unlock()
+
+	switch msg.Role {
+	case message.Assistant:
+		s.delta("reasoning_delta", msg, msg.ReasoningContent().Thinking, s.reasoningBytes)
+		s.delta("text_delta", msg, msg.Content().Text, s.textBytes)
+		for _, call := range msg.ToolCalls() {
+			if !call.Finished || s.sent[call.ID] {
+				continue
+			}
+			s.sent[call.ID] = true
+			s.broadcast(StreamEvent{
+				Type:      "tool_start",
+				SessionID: msg.SessionID,
+				MessageID: msg.ID,
+				ID:        call.ID,
+				Name:      call.Name,
+				Input:     rawInput(call.Input),
+			})
+		}
+		if finish := msg.FinishPart(); finish != nil && !s.sent[msg.ID] {
+			s.sent[msg.ID] = true
+			s.broadcast(StreamEvent{
+				Type:      "message_finish",
+				SessionID: msg.SessionID,
+				MessageID: msg.ID,
+				Reason:    string(finish.Reason),
+				Error:     finish.Message,
+			})
+		}
+	case message.Tool:
+		for _, result := range msg.ToolResults() {
+			if s.sent["result:"+result.ToolCallID] {
+				continue
+			}
+			s.sent["result:"+result.ToolCallID] = true
+			s.broadcast(StreamEvent{
+				Type:       "tool_finish",
+				SessionID:  msg.SessionID,
+				ToolCallID: result.ToolCallID,
+				Name:       result.Name,
+				Content:    result.Content,
+				IsError:    result.IsError,
+			})
+		}
+	}
+}
+
+// delta broadcasts the part of content of msg added since it was last sent,
+// as counted by readBytes.
+func (s *eventStream) delta(eventType string, msg message.Message, content string, readBytes map[string]int) {
+	read := readBytes[msg.ID]
+	if len(content) <= read {
+		return
+	}
+	readBytes[msg.ID] = len(content)
+	s.broadcast(StreamEvent{
+		Type:      eventType,
+		SessionID: msg.SessionID,
+		MessageID: msg.ID,
+		Text:      content[read:],
+	})
+}
+
+// handle runs the commands read from input, sending the errors to client,
+// until input is closed or ctx is done.
+func (s *eventStream) handle(ctx context.Context, input io.Reader, client *streamClient) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var cmd StreamCommand
+		if err := json.Unmarshal(line, &cmd); err != nil {
+			s.send(client, StreamEvent{Type: "error", Error: fmt.Sprintf("invalid command: %v", err)})
+			continue
+		}
+		if err := s.run(ctx, cmd); err != nil {
+			s.send(client, StreamEvent{Type: "error", SessionID: cmd.SessionID, Error: err.Error()})
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *eventStream) run(ctx context.Context, cmd StreamCommand) error {
+	switch cmd.Type {
+	case "prompt":
+		_, _, err := s.prompt(ctx, cmd.SessionID, cmd.Text, cmd.Queue)
+		return err
+	case "permission":
+		req, ok := s.pending.Take(cmd.ID)
+		if !ok {
+			return fmt.Errorf("no permission request %q waiting for an answer", cmd.ID)
+		}
+		switch cmd.Action {
+		case "allow":
+			s.app.Permissions.Grant(req)
+		case "allow_session":
+			s.app.Permissions.GrantPersistent(req)
+		case "deny":
+			s.app.Permissions.Deny(req)
+		default:
+			s.pending.Set(req.ID, req)
+			return fmt.Errorf("unknown permission action %q, expected allow, allow_session or deny", cmd.Action)
+		}
+		return nil
+	case "cancel":
+		if cmd.SessionID == "" {
+			s.app.AgentCoordinator.CancelAll()
+		} else {
+			s.app.AgentCoordinator.Cancel(cmd.SessionID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, expected prompt, permission or cancel", cmd.Type)
+	}
+}
+
+// prompt runs text in the session, or a new one, broadcasting a done event
+// once the run is over. A prompt queued in a busy session is broadcast as
+// queued, and its run is followed by the one running the session. It
+// returns the session and whether the prompt was queued.
+func (s *eventStream) prompt(ctx context.Context, sessionID, text string, queue bool) (string, bool, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", false, agent.ErrEmptyPrompt
+	}
+	queued := false
+	if sessionID == "" {
+		sess, err := s.app.Sessions.Create(ctx, "New Session")
+		if err != nil {
+			return "", false, fmt.Errorf("failed to create session: %w", err)
+		}
+		sessionID = sess.ID
+	} else if s.app.AgentCoordinator.IsSessionBusy(sessionID) {
+		if !queue {
+			return "", false, agent.ErrSessionBusy
+		}
+		queued = true
+	}
+	if queued {
+		s.broadcast(StreamEvent{Type: "queued", SessionID: sessionID, Text: text})
+	} else {
+		s.broadcast(StreamEvent{Type: "prompt", SessionID: sessionID, Text: text})
+	}
+
+	s.prompts.Add(1)
+	go func() {
+		defer s.prompts.Done()
+		result, err := s.app.AgentCoordinator.Run(ctx, sessionID, text)
+		if queued && result == nil && err == nil {
+			return
+		}
+
+		// The last events may have been dropped by slow subscribers, send
+		// what's left from the database.
+		if messages, listErr := s.app.Messages.List(context.WithoutCancel(ctx), sessionID); listErr == nil {
+			for _, msg := range messages {
+				s.message(msg)
+			}
+		}
+
+		done := StreamEvent{Type: "done", SessionID: sessionID}
+		switch {
+		case errors.Is(err, context.Canceled) || errors.Is(err, agent.ErrRequestCancelled):
+			done.Reason = "canceled"
+		case err != nil:
+			done.Reason = "error"
+			done.Error = err.Error()
+		default:
+			done.Reason = "completed"
+		}
+		s.broadcast(done)
+	}()
+	return sessionID, queued, nil
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func streamEvents(t *testing.T, output *bytes.Buffer) []StreamEvent {
+	t.Helper()
+	var events []StreamEvent
+	for line := range strings.SplitSeq(strings.TrimSpace(output.String()), "\n") {
+		var event StreamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestEventStreamMessages(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	stream := newEventStream(&App{})
+	stream.clients[newStreamClient(&output)] = struct{}{}
+
+	msgs := runMessages()
+	partial := msgs[1]
+	partial.Parts = []message.ContentPart{
+		message.ReasoningContent{Thinking: "Hmm"},
+		message.TextContent{Text: "Let me"},
+		message.ToolCall{ID: "call", Name: "ls"},
+	}
+	stream.message(partial)
+	msgs[3].AddFinish(message.FinishReasonEndTurn, "", "")
+	for range 2 {
+		for _, msg := range msgs {
+			stream.message(msg)
+		}
+	}
+
+	var types, texts []string
+	for _, event := range streamEvents(t, &output) {
+		types = append(types, event.Type)
+		if event.Text != "" {
+			texts = append(texts, event.Text)
+		}
+	}
+	require.Equal(t, []string{
+		"reasoning_delta", "text_delta", "text_delta", "tool_start", "tool_finish", "text_delta", "message_finish",
+	}, types)
+	require.Equal(t, []string{"Hmm", "Let me", " look.", "There's main.go."}, texts)
+}
+
+func TestEventStreamCommands(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	stream := newEventStream(&App{})
+	stream.pending.Set("req", permission.PermissionRequest{ID: "req"})
+
+	input := strings.Join([]string{
+		`not json`,
+		`{"type":"dance"}`,
+		`{"type":"prompt","text":"  "}`,
+		`{"type":"permission","id":"other","action":"allow"}`,
+		`{"type":"permission","id":"req","action":"maybe"}`,
+	}, "\n")
+	require.NoError(t, stream.handle(t.Context(), strings.NewReader(input), newStreamClient(&output)))
+
+	events := streamEvents(t, &output)
+	require.Len(t, events, 5)
+	for _, event := range events {
+		require.Equal(t, "error", event.Type)
+		require.NotEmpty(t, event.Error)
+	}
+	_, ok := stream.pending.Get("req")
+	require.True(t, ok, "unanswered requests must stay pending")
+}
+
+func TestEventStreamResolved(t *testing.T) {
+	t.Parallel()
+
+	stream := newEventStream(&App{})
+	stream.pending.Set("req", permission.PermissionRequest{ID: "req", ToolCallID: "call"})
+	stream.pending.Set("other", permission.PermissionRequest{ID: "other", ToolCallID: "other-call"})
+
+	stream.resolved("call")
+	_, ok := stream.pending.Get("req")
+	require.False(t, ok, "requests answered elsewhere are no longer pending")
+	_, ok = stream.pending.Get("other")
+	require.True(t, ok)
+}
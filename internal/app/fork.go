@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// ErrNotForked is returned when comparing a session that isn't a fork.
+var ErrNotForked = errors.New("session isn't a fork")
+
+// ForkSession creates a session with the messages of the session up to
+// messageID, to explore another approach from there. The results of the
+// tool calls of messageID are kept along with it.
+func (app *App) ForkSession(ctx context.Context, sessionID, messageID string) (session.Session, error) {
+	parent, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	messages, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	end := forkEnd(messages, messageID)
+	if end == 0 {
+		return session.Session{}, fmt.Errorf("message %s not found in the session", messageID)
+	}
+	return app.fork(ctx, parent, messages[:end], messageID)
+}
+
+// fork creates a session with a copy of messages, the ones of parent up to
+// messageID.
+func (app *App) fork(ctx context.Context, parent session.Session, messages []message.Message, messageID string) (session.Session, error) {
+	fork, err := app.Sessions.Create(ctx, parent.Title+" (fork)")
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	// Copies get new IDs, the summaries of the session must point to them.
+	ids := make(map[string]string, len(messages))
+	for _, msg := range messages {
+		parts := msg.Parts
+		if msg.Role != message.Assistant {
+			// The finish part of other messages is added when creating them.
+			parts = make([]message.ContentPart, 0, len(msg.Parts))
+			for _, part := range msg.Parts {
+				if _, ok := part.(message.Finish); !ok {
+					parts = append(parts, part)
+				}
+			}
+		}
+		copied, err := app.Messages.Create(ctx, fork.ID, message.CreateMessageParams{
+			Role:             msg.Role,
+			Parts:            parts,
+			Model:            msg.Model,
+			Provider:         msg.Provider,
+			IsSummaryMessage: msg.IsSummaryMessage,
+		})
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to copy message: %w", err)
+		}
+		ids[msg.ID] = copied.ID
+
+		if msg.Role == message.Assistant {
+			copied.Cost = msg.Cost
+			copied.PromptTokens = msg.PromptTokens
+			copied.CompletionTokens = msg.CompletionTokens
+			if err := app.Messages.Update(ctx, copied); err != nil {
+				return session.Session{}, fmt.Errorf("failed to copy message: %w", err)
+			}
+			// The context of the fork is the one of its last request.
+			fork.PromptTokens = msg.PromptTokens
+			fork.CompletionTokens = msg.CompletionTokens
+		}
+	}
+
+	fork.ForkedFromID = parent.ID
+	fork.ForkedAtMessageID = messageID
+	fork.SummaryMessageID = ids[parent.SummaryMessageID]
+	fork.SummaryKeepFromID = ids[parent.SummaryKeepFromID]
+	fork.AccountProvider = parent.AccountProvider
+	fork.Account = parent.Account
+	fork.Tags = parent.Tags
+	fork, err = app.Sessions.Save(ctx, fork)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to save session: %w", err)
+	}
+	return fork, nil
+}
+
+// CompareFork returns how the fork sessionID and the session it was forked
+// from went on since the fork, as a unified diff of their transcripts.
+func (app *App) CompareFork(ctx context.Context, sessionID string) (string, error) {
+	fork, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if fork.ForkedFromID == "" {
+		return "", ErrNotForked
+	}
+	original, err := app.Sessions.Get(ctx, fork.ForkedFromID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the original session: %w", err)
+	}
+	originalMessages, err := app.Messages.List(ctx, original.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+	forkMessages, err := app.Messages.List(ctx, fork.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	// Both sessions hold the same messages up to the fork, none when it was
+	// forked before the first prompt.
+	end := 0
+	if fork.ForkedAtMessageID != "" {
+		end = forkEnd(originalMessages, fork.ForkedAtMessageID)
+		if end == 0 {
+			return "", fmt.Errorf("message %s not found in the original session", fork.ForkedAtMessageID)
+		}
+	}
+	forkStart := min(end, len(forkMessages))
+	return udiff.Unified(
+		original.Title,
+		fork.Title,
+		export.Markdown(original, originalMessages[end:]),
+		export.Markdown(fork, forkMessages[forkStart:]),
+	), nil
+}
+
+// RegenerateFrom readies the session to regenerate the conversation from the
+// user message messageID, edited: it deletes the message and the ones after
+// it, or forks the session right before it with the branch_on_edit option.
+// It returns the session to send the edited message to, along with the
+// attachments of the message.
+func (app *App) RegenerateFrom(ctx context.Context, sessionID, messageID string) (session.Session, []message.Attachment, error) {
+	if app.AgentCoordinator != nil && app.AgentCoordinator.IsSessionBusy(sessionID) {
+		return session.Session{}, nil, agent.ErrSessionBusy
+	}
+	sess, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	messages, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	i := slices.IndexFunc(messages, func(msg message.Message) bool { return msg.ID == messageID })
+	if i < 0 || messages[i].Role != message.User {
+		return session.Session{}, nil, fmt.Errorf("user message %s not found in the session", messageID)
+	}
+
+	var attachments []message.Attachment
+	for _, file := range messages[i].BinaryContent() {
+		attachments = append(attachments, message.Attachment{
+			FilePath: file.Path,
+			FileName: filepath.Base(file.Path),
+			MimeType: file.MIMEType,
+			Content:  file.Data,
+		})
+	}
+
+	if app.config.Options.BranchOnEdit {
+		// The fork is at the last message before the edited one that isn't
+		// a tool result, which forks keep anyway.
+		forkedAt := ""
+		for j := i - 1; j >= 0; j-- {
+			if messages[j].Role != message.Tool {
+				forkedAt = messages[j].ID
+				break
+			}
+		}
+		fork, err := app.fork(ctx, sess, messages[:i], forkedAt)
+		return fork, attachments, err
+	}
+
+	for j := len(messages) - 1; j >= i; j-- {
+		if err := app.Messages.Delete(ctx, messages[j].ID); err != nil {
+			return session.Session{}, nil, fmt.Errorf("failed to delete message: %w", err)
+		}
+		if messages[j].ID == sess.SummaryMessageID || messages[j].ID == sess.SummaryKeepFromID {
+			sess.SummaryMessageID = ""
+			sess.SummaryKeepFromID = ""
+		}
+	}
+	// The context of the session is the one of its last request left.
+	sess.PromptTokens, sess.CompletionTokens = 0, 0
+	for _, msg := range slices.Backward(messages[:i]) {
+		if msg.Role == message.Assistant {
+			sess.PromptTokens, sess.CompletionTokens = msg.PromptTokens, msg.CompletionTokens
+			break
+		}
+	}
+	sess, err = app.Sessions.Save(ctx, sess)
+	if err != nil {
+		return session.Session{}, nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return sess, attachments, nil
+}
+
+// forkEnd returns the number of messages a fork at messageID keeps: up to
+// messageID and the results of its tool calls. It returns 0 when messageID
+// isn't one of messages.
+func forkEnd(messages []message.Message, messageID string) int {
+	for i, msg := range messages {
+		if msg.ID != messageID {
+			continue
+		}
+		end := i + 1
+		for end < len(messages) && messages[end].Role == message.Tool {
+			end++
+		}
+		return end
+	}
+	return 0
+}
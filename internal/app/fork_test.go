@@ -0,0 +1,129 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkSession(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	app := &App{Sessions: session.NewService(q, ""), Messages: message.NewService(q)}
+	ctx := t.Context()
+
+	original, err := app.Sessions.Create(ctx, "Parser")
+	require.NoError(t, err)
+	var ids []string
+	for _, msg := range runMessages() {
+		created, err := app.Messages.Create(ctx, original.ID, message.CreateMessageParams{Role: msg.Role, Parts: msg.Parts})
+		require.NoError(t, err)
+		ids = append(ids, created.ID)
+	}
+
+	// Forking at the tool call keeps its results.
+	fork, err := app.ForkSession(ctx, original.ID, ids[1])
+	require.NoError(t, err)
+	require.Equal(t, "Parser (fork)", fork.Title)
+	require.Equal(t, original.ID, fork.ForkedFromID)
+	require.Equal(t, ids[1], fork.ForkedAtMessageID)
+
+	messages, err := app.Messages.List(ctx, fork.ID)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	require.Equal(t, "List the files", messages[0].Content().Text)
+	require.Len(t, messages[0].Parts, 2, "the finish part must not be duplicated")
+	require.Len(t, messages[1].ToolCalls(), 1)
+	require.Equal(t, "- main.go", messages[2].ToolResults()[0].Content)
+
+	_, err = app.Messages.Create(ctx, fork.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "There's only main.go."}},
+	})
+	require.NoError(t, err)
+	diff, err := app.CompareFork(ctx, fork.ID)
+	require.NoError(t, err)
+	require.Contains(t, diff, "-There's main.go.")
+	require.Contains(t, diff, "+There's only main.go.")
+	require.NotContains(t, diff, "List the files")
+
+	_, err = app.CompareFork(ctx, original.ID)
+	require.ErrorIs(t, err, ErrNotForked)
+	_, err = app.ForkSession(ctx, original.ID, "missing")
+	require.Error(t, err)
+}
+
+func TestRegenerateFrom(t *testing.T) {
+	t.Parallel()
+
+	for name, branch := range map[string]bool{"prune": false, "branch": true} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := db.Connect(t.Context(), t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+			q := db.New(conn)
+			app := &App{
+				Sessions: session.NewService(q, ""),
+				Messages: message.NewService(q),
+				config:   &config.Config{Options: &config.Options{BranchOnEdit: branch}},
+			}
+			ctx := t.Context()
+
+			original, err := app.Sessions.Create(ctx, "Parser")
+			require.NoError(t, err)
+			msgs := append(runMessages(),
+				message.Message{Role: message.User, Parts: []message.ContentPart{
+					message.TextContent{Text: "Read main.go"},
+					message.BinaryContent{Path: "shot.png", MIMEType: "image/png", Data: []byte("png")},
+				}},
+				message.Message{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "It's empty."}}},
+			)
+			var ids []string
+			for _, msg := range msgs {
+				created, err := app.Messages.Create(ctx, original.ID, message.CreateMessageParams{Role: msg.Role, Parts: msg.Parts})
+				require.NoError(t, err)
+				ids = append(ids, created.ID)
+			}
+
+			_, _, err = app.RegenerateFrom(ctx, original.ID, ids[1])
+			require.Error(t, err, "only user messages can be edited")
+
+			sess, attachments, err := app.RegenerateFrom(ctx, original.ID, ids[4])
+			require.NoError(t, err)
+			require.Equal(t, []message.Attachment{{FilePath: "shot.png", FileName: "shot.png", MimeType: "image/png", Content: []byte("png")}}, attachments)
+
+			kept, err := app.Messages.List(ctx, sess.ID)
+			require.NoError(t, err)
+			require.Len(t, kept, 4)
+			require.Equal(t, "There's main.go.", kept[3].Content().Text)
+
+			originalMessages, err := app.Messages.List(ctx, original.ID)
+			require.NoError(t, err)
+			if branch {
+				require.NotEqual(t, original.ID, sess.ID)
+				require.Equal(t, ids[3], sess.ForkedAtMessageID)
+				require.Len(t, originalMessages, 6)
+			} else {
+				require.Equal(t, original.ID, sess.ID)
+				require.Len(t, originalMessages, 4)
+			}
+
+			// Editing the first prompt starts over.
+			sess, _, err = app.RegenerateFrom(ctx, original.ID, ids[0])
+			require.NoError(t, err)
+			kept, err = app.Messages.List(ctx, sess.ID)
+			require.NoError(t, err)
+			require.Empty(t, kept)
+		})
+	}
+}
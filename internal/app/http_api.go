@@ -0,0 +1,451 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// maxRequestBody is the size the bodies of the requests to the HTTP API are
+// limited to.
+const maxRequestBody = 10 << 20
+
+// ErrAPITokenRequired is returned when serving the HTTP API without a token
+// on an address other machines can reach.
+var ErrAPITokenRequired = errors.New("serving the HTTP API on other addresses than the loopback ones needs a token")
+
+// APIOptions configures the HTTP API.
+type APIOptions struct {
+	// Token, when set, is required from every request as a bearer token.
+	Token string
+	// AllowedOrigins are the origins of the web frontends allowed to call
+	// the API from a browser.
+	AllowedOrigins []string
+}
+
+// APISession is a session as returned by the HTTP API.
+type APISession struct {
+	ID              string   `json:"id"`
+	ParentSessionID string   `json:"parent_session_id,omitempty"`
+	Title           string   `json:"title"`
+	MessageCount    int64    `json:"message_count"`
+	Usage           RunUsage `json:"usage"`
+	CostUSD         float64  `json:"cost_usd"`
+	Busy            bool     `json:"busy"`
+	QueuedPrompts   int      `json:"queued_prompts"`
+	CreatedAt       int64    `json:"created_at"`
+	UpdatedAt       int64    `json:"updated_at"`
+}
+
+// APIMessage is a message as returned by the HTTP API.
+type APIMessage struct {
+	ID           string          `json:"id"`
+	SessionID    string          `json:"session_id"`
+	Role         string          `json:"role"`
+	Text         string          `json:"text,omitempty"`
+	Reasoning    string          `json:"reasoning,omitempty"`
+	ToolCalls    []APIToolCall   `json:"tool_calls,omitempty"`
+	ToolResults  []APIToolResult `json:"tool_results,omitempty"`
+	FinishReason string          `json:"finish_reason,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Model        string          `json:"model,omitempty"`
+	Provider     string          `json:"provider,omitempty"`
+	CreatedAt    int64           `json:"created_at"`
+}
+
+// APIToolCall is a tool call of a message returned by the HTTP API.
+type APIToolCall struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Input    json.RawMessage `json:"input"`
+	Finished bool            `json:"finished"`
+}
+
+// APIToolResult is a tool result of a message returned by the HTTP API.
+type APIToolResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+	IsError    bool   `json:"is_error"`
+}
+
+// ServeAPI serves the HTTP API of the app on addr, a host and port or the
+// path of a unix socket, until ctx is done. Its events are streamed with
+// server-sent events, as the lines of ServeEvents.
+func (app *App) ServeAPI(ctx context.Context, addr string, opts APIOptions) error {
+	network := "tcp"
+	if strings.ContainsAny(addr, `/\`) {
+		network = "unix"
+		// Remove the socket left by a previous run.
+		if err := os.Remove(addr); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	} else if opts.Token == "" && !isLoopbackHost(addr) {
+		return fmt.Errorf("%s: %w", addr, ErrAPITokenRequired)
+	}
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	stream := newEventStream(app)
+	stream.forward(ctx)
+	server := &http.Server{
+		Handler:           newAPIHandler(ctx, stream, opts, network == "tcp" && opts.Token == ""),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx) //nolint:errcheck
+	})
+	defer stop()
+
+	slog.Info("Serving the HTTP API", "addr", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	stream.prompts.Wait()
+	return nil
+}
+
+// apiHandler serves the HTTP API from the services of an app.
+type apiHandler struct {
+	ctx    context.Context
+	app    *App
+	stream *eventStream
+	opts   APIOptions
+	// checkHost rejects the requests from or for other hosts than the
+	// loopback ones, so that neither other machines nor web pages
+	// rebinding their domain to it can reach an API without a token.
+	checkHost bool
+}
+
+func newAPIHandler(ctx context.Context, stream *eventStream, opts APIOptions, checkHost bool) http.Handler {
+	h := &apiHandler{ctx: ctx, app: stream.app, stream: stream, opts: opts, checkHost: checkHost}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/sessions", h.listSessions)
+	mux.HandleFunc("POST /v1/sessions", h.createSession)
+	mux.HandleFunc("GET /v1/sessions/{id}", h.getSession)
+	mux.HandleFunc("GET /v1/sessions/{id}/messages", h.listMessages)
+	mux.HandleFunc("POST /v1/sessions/{id}/prompt", h.prompt)
+	mux.HandleFunc("POST /v1/sessions/{id}/cancel", h.cancel)
+	mux.HandleFunc("GET /v1/permissions", h.listPermissions)
+	mux.HandleFunc("POST /v1/permissions/{id}", h.answerPermission)
+	mux.HandleFunc("GET /v1/events", h.events)
+	return h.guard(mux)
+}
+
+// guard checks the host, the origin and the token of the requests before
+// passing them to next.
+func (h *apiHandler) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.checkHost && (!isLoopbackHost(r.Host) || !isLoopbackHost(r.RemoteAddr)) {
+			writeAPIError(w, http.StatusForbidden, errors.New("host not allowed"))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !slices.Contains(h.opts.AllowedOrigins, origin) {
+				writeAPIError(w, http.StatusForbidden, errors.New("origin not allowed"))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		if h.opts.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) != 1 {
+				writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func (h *apiHandler) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.app.Sessions.List(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	result := make([]APISession, 0, len(sessions))
+	for _, sess := range sessions {
+		result = append(result, h.session(sess))
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+func (h *apiHandler) createSession(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title string `json:"title"`
+	}
+	if !readAPIBody(w, r, &body) {
+		return
+	}
+	sess, err := h.app.Sessions.Create(r.Context(), cmp.Or(strings.TrimSpace(body.Title), "New Session"))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, h.session(sess))
+}
+
+func (h *apiHandler) getSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.findSession(w, r)
+	if !ok {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, h.session(sess))
+}
+
+func (h *apiHandler) listMessages(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.findSession(w, r)
+	if !ok {
+		return
+	}
+	messages, err := h.app.Messages.List(r.Context(), sess.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	result := make([]APIMessage, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, apiMessage(msg))
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+func (h *apiHandler) prompt(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.findSession(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Text  string `json:"text"`
+		Queue bool   `json:"queue"`
+	}
+	if !readAPIBody(w, r, &body) {
+		return
+	}
+	// The run outlives the request.
+	sessionID, queued, err := h.stream.prompt(h.ctx, sess.ID, body.Text, body.Queue)
+	switch {
+	case errors.Is(err, agent.ErrEmptyPrompt):
+		writeAPIError(w, http.StatusBadRequest, err)
+	case errors.Is(err, agent.ErrSessionBusy):
+		writeAPIError(w, http.StatusConflict, err)
+	case err != nil:
+		writeAPIError(w, http.StatusInternalServerError, err)
+	default:
+		writeAPIJSON(w, http.StatusAccepted, struct {
+			SessionID string `json:"session_id"`
+			Queued    bool   `json:"queued"`
+		}{sessionID, queued})
+	}
+}
+
+func (h *apiHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.findSession(w, r)
+	if !ok {
+		return
+	}
+	h.app.AgentCoordinator.Cancel(sess.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *apiHandler) listPermissions(w http.ResponseWriter, r *http.Request) {
+	result := []permission.PermissionRequest{}
+	for req := range h.stream.pending.Seq() {
+		result = append(result, req)
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+func (h *apiHandler) answerPermission(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Action string `json:"action"`
+	}
+	if !readAPIBody(w, r, &body) {
+		return
+	}
+	id := r.PathValue("id")
+	if _, ok := h.stream.pending.Get(id); !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no permission request %q waiting for an answer", id))
+		return
+	}
+	if err := h.stream.run(r.Context(), StreamCommand{Type: "permission", ID: id, Action: body.Action}); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// events streams the events of the app as server-sent events, until the
+// client disconnects.
+func (h *apiHandler) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	client := h.stream.attach(r.Context(), &sseWriter{w: w, flusher: flusher})
+	defer h.stream.detach(client)
+	<-r.Context().Done()
+}
+
+// sseWriter writes the lines of the event stream as server-sent events.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	for line := range bytes.Lines(p) {
+		if _, err := fmt.Fprintf(s.w, "data: %s\n\n", bytes.TrimSuffix(line, []byte("\n"))); err != nil {
+			return 0, err
+		}
+	}
+	s.flusher.Flush()
+	return len(p), nil
+}
+
+// findSession returns the session of the path, writing a not found error
+// when there's none.
+func (h *apiHandler) findSession(w http.ResponseWriter, r *http.Request) (session.Session, bool) {
+	sess, err := h.app.Sessions.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("session %s not found", r.PathValue("id")))
+		return session.Session{}, false
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return session.Session{}, false
+	}
+	return sess, true
+}
+
+func (h *apiHandler) session(sess session.Session) APISession {
+	result := APISession{
+		ID:              sess.ID,
+		ParentSessionID: sess.ParentSessionID,
+		Title:           sess.Title,
+		MessageCount:    sess.MessageCount,
+		Usage: RunUsage{
+			InputTokens:      sess.TotalPromptTokens,
+			OutputTokens:     sess.TotalCompletionTokens,
+			CacheReadTokens:  sess.TotalCacheReadTokens,
+			CacheWriteTokens: sess.TotalCacheWriteTokens,
+		},
+		CostUSD:   sess.Cost,
+		CreatedAt: sess.CreatedAt,
+		UpdatedAt: sess.UpdatedAt,
+	}
+	if h.app.AgentCoordinator != nil {
+		result.Busy = h.app.AgentCoordinator.IsSessionBusy(sess.ID)
+		result.QueuedPrompts = h.app.AgentCoordinator.QueuedPrompts(sess.ID)
+	}
+	return result
+}
+
+func apiMessage(msg message.Message) APIMessage {
+	result := APIMessage{
+		ID:        msg.ID,
+		SessionID: msg.SessionID,
+		Role:      string(msg.Role),
+		Text:      msg.Content().Text,
+		Reasoning: msg.ReasoningContent().Thinking,
+		Model:     msg.Model,
+		Provider:  msg.Provider,
+		CreatedAt: msg.CreatedAt,
+	}
+	for _, call := range msg.ToolCalls() {
+		result.ToolCalls = append(result.ToolCalls, APIToolCall{
+			ID:       call.ID,
+			Name:     call.Name,
+			Input:    rawInput(call.Input),
+			Finished: call.Finished,
+		})
+	}
+	for _, tr := range msg.ToolResults() {
+		result.ToolResults = append(result.ToolResults, APIToolResult{
+			ToolCallID: tr.ToolCallID,
+			Name:       tr.Name,
+			Content:    tr.Content,
+			IsError:    tr.IsError,
+		})
+	}
+	if finish := msg.FinishPart(); finish != nil {
+		result.FinishReason = string(finish.Reason)
+		result.Error = finish.Message
+	}
+	return result
+}
+
+// readAPIBody decodes the JSON body of r into v, if any, writing a bad
+// request error when it can't.
+func readAPIBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write response", "error", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	app := &App{Sessions: session.NewService(q, ""), Messages: message.NewService(q), config: &config.Config{}}
+	stream := newEventStream(app)
+	stream.pending.Set("req", permission.PermissionRequest{ID: "req", ToolName: "bash"})
+	server := httptest.NewServer(newAPIHandler(t.Context(), stream, APIOptions{}, true))
+	t.Cleanup(server.Close)
+
+	call := func(method, path, body string, v any) int {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var created APISession
+	require.Equal(t, http.StatusCreated, call("POST", "/v1/sessions", `{"title":"Parser"}`, &created))
+	require.Equal(t, "Parser", created.Title)
+	for _, msg := range runMessages() {
+		_, err := app.Messages.Create(t.Context(), created.ID, message.CreateMessageParams{Role: msg.Role, Parts: msg.Parts})
+		require.NoError(t, err)
+	}
+
+	var sessions []APISession
+	require.Equal(t, http.StatusOK, call("GET", "/v1/sessions", "", &sessions))
+	require.Len(t, sessions, 1)
+	var messages []APIMessage
+	require.Equal(t, http.StatusOK, call("GET", "/v1/sessions/"+created.ID+"/messages", "", &messages))
+	require.Len(t, messages, 4)
+	require.Equal(t, "List the files", messages[0].Text)
+	require.JSONEq(t, `{"path":"."}`, string(messages[1].ToolCalls[0].Input))
+	require.Equal(t, "- main.go", messages[2].ToolResults[0].Content)
+
+	require.Equal(t, http.StatusNotFound, call("GET", "/v1/sessions/missing", "", nil))
+	require.Equal(t, http.StatusBadRequest, call("POST", "/v1/sessions/"+created.ID+"/prompt", `{"text":" "}`, nil))
+	require.Equal(t, http.StatusBadRequest, call("POST", "/v1/sessions/"+created.ID+"/prompt", `not json`, nil))
+
+	var pending []permission.PermissionRequest
+	require.Equal(t, http.StatusOK, call("GET", "/v1/permissions", "", &pending))
+	require.Len(t, pending, 1)
+	require.Equal(t, http.StatusNotFound, call("POST", "/v1/permissions/other", `{"action":"allow"}`, nil))
+	require.Equal(t, http.StatusBadRequest, call("POST", "/v1/permissions/req", `{"action":"maybe"}`, nil))
+
+	req, err := http.NewRequestWithContext(t.Context(), "GET", server.URL+"/v1/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	var ready StreamEvent
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ready))
+	require.Equal(t, "ready", ready.Type)
+	require.Equal(t, created.ID, ready.SessionID)
+}
+
+func TestAPIGuard(t *testing.T) {
+	t.Parallel()
+
+	handler := newAPIHandler(t.Context(), newEventStream(&App{}), APIOptions{Token: "secret", AllowedOrigins: []string{"http://localhost:3000"}}, true)
+	status := func(host, origin, token string) int {
+		req := httptest.NewRequest("GET", "/v1/permissions", nil)
+		req.Host = host
+		req.RemoteAddr = "127.0.0.1:54321"
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, status("127.0.0.1:7777", "", "secret"))
+	require.Equal(t, http.StatusOK, status("localhost:7777", "http://localhost:3000", "secret"))
+	require.Equal(t, http.StatusForbidden, status("evil.example:7777", "", "secret"), "rebound domains are rejected")
+	require.Equal(t, http.StatusForbidden, status("[::1]:7777", "http://evil.example", "secret"))
+	require.Equal(t, http.StatusUnauthorized, status("127.0.0.1:7777", "", ""))
+	require.Equal(t, http.StatusUnauthorized, status("127.0.0.1:7777", "", "guess"))
+
+	req := httptest.NewRequest("GET", "/v1/permissions", nil)
+	req.Host = "localhost:7777"
+	req.RemoteAddr = "192.0.2.1:54321"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code, "other machines are rejected whatever the host they ask for")
+}
+
+func TestServeAPITokenRequired(t *testing.T) {
+	t.Parallel()
+
+	app := &App{}
+	require.ErrorIs(t, app.ServeAPI(t.Context(), ":0", APIOptions{}), ErrAPITokenRequired)
+	require.ErrorIs(t, app.ServeAPI(t.Context(), "0.0.0.0:0", APIOptions{}), ErrAPITokenRequired)
+}
@@ -78,3 +78,16 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, config
 	// Add to map with mutex protection before starting goroutine
 	app.LSPClients.Set(name, lspClient)
 }
+
+// notifyLSPFileChanged tells the LSP clients with the file open that it
+// changed outside of Crush, so they don't work with its old content.
+func (app *App) notifyLSPFileChanged(ctx context.Context, path string) {
+	for name, client := range app.LSPClients.Seq2() {
+		if !client.IsFileOpen(path) {
+			continue
+		}
+		if err := client.NotifyChange(ctx, path); err != nil {
+			slog.Debug("Failed to notify LSP client of file change", "name", name, "file", path, "error", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRequest(t *testing.T) {
+	t.Parallel()
+
+	request := planRequest(&github.Issue{
+		Repo:     "charmbracelet/crush",
+		Number:   7,
+		Title:    "Crash on empty config",
+		Body:     "It panics.",
+		Author:   "alice",
+		Labels:   []string{"bug", "config"},
+		Comments: []github.Comment{{Author: "bob", Body: "Same here.\n"}},
+	})
+	require.Contains(t, request, "Plan the implementation of issue charmbracelet/crush#7 by alice, labeled bug, config.")
+	require.Contains(t, request, "<description>\nIt panics.\n</description>")
+	require.Contains(t, request, "<comments>\nbob:\nSame here.\n\n</comments>")
+	require.Contains(t, request, "Markdown checklist")
+}
+
+func TestIssueFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "load.go"), []byte("package config\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo.png"), []byte("\x89PNG\r\n\x1a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("secret\n"), 0o644))
+
+	attachments := issueFiles(dir, &github.Issue{
+		Title: "Crash in config/load.go",
+		Body:  "See ./config/load.go and logo.png, e.g. ../secret.txt or missing.go.",
+		Comments: []github.Comment{
+			{Body: "Also main.go."},
+		},
+	})
+	var names []string
+	for _, attachment := range attachments {
+		require.True(t, attachment.IsText())
+		names = append(names, attachment.FileName)
+	}
+	require.Equal(t, []string{filepath.Join("config", "load.go"), "main.go"}, names)
+	require.Equal(t, "package config\n", string(attachments[0].Content))
+}
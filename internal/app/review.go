@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/github"
+)
+
+// maxReviewDiff is the size of the diffs past which they are truncated
+// before being reviewed.
+const maxReviewDiff = 256 * 1024
+
+// ReviewPullRequest runs the review agent over a pull request in a session of
+// its own and returns its review. The agent can only read files, and the
+// permissions its tools request are denied, as nobody is there to answer
+// them.
+func (app *App) ReviewPullRequest(ctx context.Context, pr *github.PullRequest) (github.Review, error) {
+	sess, err := app.Sessions.Create(ctx, fmt.Sprintf("Review of %s#%d", pr.Repo, pr.Number))
+	if err != nil {
+		return github.Review{}, fmt.Errorf("failed to create session for the review: %w", err)
+	}
+	slog.Info("Created session for the review", "session_id", sess.ID, "pull_request", pr.URL)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	permissions := app.Permissions.Subscribe(ctx)
+	go func() {
+		for event := range permissions {
+			if event.Payload.SessionID == sess.ID {
+				slog.Warn("Denied a permission request of the review agent", "tool", event.Payload.ToolName)
+				app.Permissions.Deny(event.Payload)
+			}
+		}
+	}()
+
+	response, err := app.AgentCoordinator.Review(ctx, sess.ID, reviewRequest(pr))
+	if err != nil {
+		return github.Review{}, fmt.Errorf("failed to review the pull request: %w", err)
+	}
+	return parseReview(response), nil
+}
+
+// reviewRequest returns the prompt asking to review pr.
+func reviewRequest(pr *github.PullRequest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Review pull request %s#%d by %s, merging %s into %s.\n\n", pr.Repo, pr.Number, pr.Author, pr.HeadRef, pr.BaseRef)
+	fmt.Fprintf(&sb, "<title>\n%s\n</title>\n\n", pr.Title)
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		fmt.Fprintf(&sb, "<description>\n%s\n</description>\n\n", body)
+	}
+	if len(pr.Comments) > 0 {
+		sb.WriteString("<comments>\n")
+		for _, comment := range pr.Comments {
+			if comment.Path != "" {
+				fmt.Fprintf(&sb, "%s on %s:%d:\n%s\n\n", comment.Author, comment.Path, comment.Line, strings.TrimSpace(comment.Body))
+			} else {
+				fmt.Fprintf(&sb, "%s:\n%s\n\n", comment.Author, strings.TrimSpace(comment.Body))
+			}
+		}
+		sb.WriteString("</comments>\n\n")
+	}
+	diff := pr.Diff
+	if len(diff) > maxReviewDiff {
+		diff = diff[:maxReviewDiff] + "\n[diff truncated]\n"
+	}
+	fmt.Fprintf(&sb, "<diff>\n%s</diff>\n", diff)
+	return sb.String()
+}
+
+// parseReview parses the review at the end of the response of the review
+// agent, keeping the whole response as the body of the review when it has
+// none.
+func parseReview(response string) github.Review {
+	const fence = "```json"
+	start := strings.LastIndex(response, fence)
+	if start >= 0 {
+		block := response[start+len(fence):]
+		if end := strings.Index(block, "```"); end >= 0 {
+			var review github.Review
+			if err := json.Unmarshal([]byte(block[:end]), &review); err == nil {
+				review.Body = strings.TrimSpace(review.Body)
+				return review
+			}
+		}
+	}
+	slog.Warn("The review agent didn't answer with a review, using its response as is")
+	return github.Review{Body: strings.TrimSpace(response)}
+}
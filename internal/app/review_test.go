@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReview(t *testing.T) {
+	t.Parallel()
+
+	review := parseReview("I read the parser.\n\n```json\n{\"body\": \"One issue.\", \"comments\": [{\"path\": \"parse.go\", \"line\": 12, \"body\": \"Check for EOF.\"}]}\n```\n")
+	require.Equal(t, github.Review{
+		Body:     "One issue.",
+		Comments: []github.ReviewComment{{Path: "parse.go", Line: 12, Body: "Check for EOF."}},
+	}, review)
+
+	require.Equal(t, github.Review{Body: "Looks good to me."}, parseReview("Looks good to me.\n"))
+	require.Equal(t, github.Review{Body: "```json\n{oops}\n```"}, parseReview("```json\n{oops}\n```"))
+}
+
+func TestReviewRequest(t *testing.T) {
+	t.Parallel()
+
+	request := reviewRequest(&github.PullRequest{
+		Repo:     "charmbracelet/crush",
+		Number:   42,
+		Title:    "Add a parser",
+		Author:   "alice",
+		BaseRef:  "main",
+		HeadRef:  "parser",
+		Diff:     "diff --git a/parse.go b/parse.go\n",
+		Comments: []github.Comment{{Author: "bob", Body: "Typo.", Path: "parse.go", Line: 3}},
+	})
+	require.Contains(t, request, "Review pull request charmbracelet/crush#42 by alice, merging parser into main.")
+	require.Contains(t, request, "<title>\nAdd a parser\n</title>")
+	require.NotContains(t, request, "<description>")
+	require.Contains(t, request, "bob on parse.go:3:\nTypo.")
+	require.Contains(t, request, "<diff>\ndiff --git a/parse.go b/parse.go\n</diff>")
+}
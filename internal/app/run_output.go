@@ -0,0 +1,242 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// OutputFormat is the format of what non-interactive runs print.
+type OutputFormat string
+
+const (
+	// OutputFormatText streams the answer as plain text.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON prints a single JSON object once the run is over.
+	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatStreamJSON prints a JSON object per line as the run goes,
+	// ending with the same object as OutputFormatJSON.
+	OutputFormatStreamJSON OutputFormat = "stream-json"
+)
+
+// ParseOutputFormat returns the output format named s.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch format := OutputFormat(s); format {
+	case OutputFormatText, OutputFormatJSON, OutputFormatStreamJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q, expected text, json or stream-json", s)
+	}
+}
+
+// RunToolCall is a tool called during a non-interactive run.
+type RunToolCall struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Input   json.RawMessage `json:"input"`
+	Result  string          `json:"result"`
+	IsError bool            `json:"is_error"`
+}
+
+// RunUsage adds up the tokens used by a non-interactive run.
+type RunUsage struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+}
+
+// RunResult sums up a non-interactive run, printed last by the JSON output
+// formats.
+type RunResult struct {
+	Type       string        `json:"type"`
+	SessionID  string        `json:"session_id"`
+	Result     string        `json:"result"`
+	IsError    bool          `json:"is_error"`
+	Error      string        `json:"error,omitempty"`
+	NumTurns   int           `json:"num_turns"`
+	ToolCalls  []RunToolCall `json:"tool_calls"`
+	Usage      RunUsage      `json:"usage"`
+	CostUSD    float64       `json:"cost_usd"`
+	DurationMS int64         `json:"duration_ms"`
+}
+
+// runEvent is a line printed by the stream-json output format.
+type runEvent struct {
+	Type       string          `json:"type"`
+	SessionID  string          `json:"session_id,omitempty"`
+	MessageID  string          `json:"message_id,omitempty"`
+	Text       string          `json:"text,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Input      json.RawMessage `json:"input,omitempty"`
+	Content    string          `json:"content,omitempty"`
+	IsError    bool            `json:"is_error,omitempty"`
+}
+
+// runPrinter prints the messages of a non-interactive run as they're
+// updated, in an output format.
+type runPrinter struct {
+	format    OutputFormat
+	output    io.Writer
+	encoder   *json.Encoder
+	sessionID string
+	started   time.Time
+
+	// readBytes is the length of the text already printed by message, and
+	// printed the tool calls and results already printed.
+	readBytes map[string]int
+	printed   map[string]bool
+}
+
+func newRunPrinter(format OutputFormat, output io.Writer, sessionID string) *runPrinter {
+	return &runPrinter{
+		format:    format,
+		output:    output,
+		encoder:   json.NewEncoder(output),
+		sessionID: sessionID,
+		started:   time.Now(),
+		readBytes: make(map[string]int),
+		printed:   make(map[string]bool),
+	}
+}
+
+// start prints the event starting a run.
+func (p *runPrinter) start(model, provider string) error {
+	if p.format != OutputFormatStreamJSON {
+		return nil
+	}
+	return p.encoder.Encode(struct {
+		Type      string `json:"type"`
+		SessionID string `json:"session_id"`
+		Model     string `json:"model"`
+		Provider  string `json:"provider"`
+	}{"init", p.sessionID, model, provider})
+}
+
+// message prints what's new in msg since it was last printed.
+func (p *runPrinter) message(msg message.Message) error {
+	if msg.SessionID != p.sessionID || p.format == OutputFormatJSON {
+		return nil
+	}
+	switch msg.Role {
+	case message.Assistant:
+		content := msg.Content().Text
+		readBytes := p.readBytes[msg.ID]
+		if len(content) < readBytes {
+			return fmt.Errorf("message content is shorter than read bytes: %d < %d", len(content), readBytes)
+		}
+		if part := content[readBytes:]; part != "" {
+			p.readBytes[msg.ID] = len(content)
+			if p.format == OutputFormatText {
+				if _, err := fmt.Fprint(p.output, part); err != nil {
+					return err
+				}
+			} else if err := p.encoder.Encode(runEvent{Type: "text", MessageID: msg.ID, Text: part}); err != nil {
+				return err
+			}
+		}
+		if p.format != OutputFormatStreamJSON {
+			return nil
+		}
+		for _, call := range msg.ToolCalls() {
+			if !call.Finished || p.printed[call.ID] {
+				continue
+			}
+			p.printed[call.ID] = true
+			if err := p.encoder.Encode(runEvent{Type: "tool_call", ID: call.ID, Name: call.Name, Input: rawInput(call.Input)}); err != nil {
+				return err
+			}
+		}
+	case message.Tool:
+		if p.format != OutputFormatStreamJSON {
+			return nil
+		}
+		for _, result := range msg.ToolResults() {
+			if p.printed["result:"+result.ToolCallID] {
+				continue
+			}
+			p.printed["result:"+result.ToolCallID] = true
+			if err := p.encoder.Encode(runEvent{
+				Type:       "tool_result",
+				ToolCallID: result.ToolCallID,
+				Name:       result.Name,
+				Content:    result.Content,
+				IsError:    result.IsError,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// result prints the summary of the run, made of its messages, for the JSON
+// output formats.
+func (p *runPrinter) result(sess session.Session, messages []message.Message, runErr error) error {
+	if p.format == OutputFormatText {
+		// Always print a newline at the end. If output is a TTY this will
+		// prevent the prompt from overwriting the last line of output.
+		_, err := fmt.Fprintln(p.output)
+		return err
+	}
+
+	result := RunResult{
+		Type:      "result",
+		SessionID: p.sessionID,
+		ToolCalls: []RunToolCall{},
+		Usage: RunUsage{
+			InputTokens:      sess.TotalPromptTokens,
+			OutputTokens:     sess.TotalCompletionTokens,
+			CacheReadTokens:  sess.TotalCacheReadTokens,
+			CacheWriteTokens: sess.TotalCacheWriteTokens,
+		},
+		CostUSD:    sess.Cost,
+		DurationMS: time.Since(p.started).Milliseconds(),
+	}
+	if runErr != nil {
+		result.IsError = true
+		result.Error = runErr.Error()
+	}
+
+	calls := make(map[string]int)
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.Assistant:
+			result.NumTurns++
+			if text := msg.Content().Text; text != "" {
+				result.Result = text
+			}
+			for _, call := range msg.ToolCalls() {
+				calls[call.ID] = len(result.ToolCalls)
+				result.ToolCalls = append(result.ToolCalls, RunToolCall{ID: call.ID, Name: call.Name, Input: rawInput(call.Input)})
+			}
+		case message.Tool:
+			for _, r := range msg.ToolResults() {
+				if i, ok := calls[r.ToolCallID]; ok {
+					result.ToolCalls[i].Result = r.Content
+					result.ToolCalls[i].IsError = r.IsError
+				}
+			}
+		}
+	}
+	return p.encoder.Encode(result)
+}
+
+// rawInput returns the input of a tool call as JSON, quoting it when it
+// isn't valid JSON, like when the call was interrupted.
+func rawInput(input string) json.RawMessage {
+	if input == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(input)) {
+		return json.RawMessage(input)
+	}
+	quoted, _ := json.Marshal(input)
+	return quoted
+}
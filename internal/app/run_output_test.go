@@ -0,0 +1,117 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func runMessages() []message.Message {
+	return []message.Message{
+		{ID: "1", SessionID: "s", Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "List the files"}}},
+		{ID: "2", SessionID: "s", Role: message.Assistant, Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look."},
+			message.ToolCall{ID: "call", Name: "ls", Input: `{"path":"."}`, Finished: true},
+		}},
+		{ID: "3", SessionID: "s", Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call", Name: "ls", Content: "- main.go"},
+		}},
+		{ID: "4", SessionID: "s", Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "There's main.go."}}},
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	format, err := ParseOutputFormat("stream-json")
+	require.NoError(t, err)
+	require.Equal(t, OutputFormatStreamJSON, format)
+
+	_, err = ParseOutputFormat("yaml")
+	require.Error(t, err)
+}
+
+func TestRunPrinterText(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	printer := newRunPrinter(OutputFormatText, &output, "s")
+	msgs := runMessages()
+	partial := msgs[3]
+	partial.Parts = []message.ContentPart{message.TextContent{Text: "There's"}}
+	for _, msg := range append(msgs[:3:3], partial) {
+		require.NoError(t, printer.message(msg))
+	}
+	for _, msg := range msgs {
+		require.NoError(t, printer.message(msg))
+	}
+	require.NoError(t, printer.result(session.Session{}, msgs, nil))
+	require.Equal(t, "Let me look.There's main.go.\n", output.String())
+}
+
+func TestRunPrinterStreamJSON(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	printer := newRunPrinter(OutputFormatStreamJSON, &output, "s")
+	require.NoError(t, printer.start("model", "provider"))
+	msgs := runMessages()
+	for _, msg := range msgs {
+		require.NoError(t, printer.message(msg))
+	}
+	// Messages are printed again once the run is over.
+	for _, msg := range msgs {
+		require.NoError(t, printer.message(msg))
+	}
+	sess := session.Session{TotalPromptTokens: 100, TotalCompletionTokens: 20, Cost: 0.5}
+	require.NoError(t, printer.result(sess, msgs, errors.New("boom")))
+
+	var types []string
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	for _, line := range lines {
+		var event struct {
+			Type string `json:"type"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []string{"init", "text", "tool_call", "tool_result", "text", "result"}, types)
+
+	var result RunResult
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &result))
+	require.Equal(t, "There's main.go.", result.Result)
+	require.True(t, result.IsError)
+	require.Equal(t, "boom", result.Error)
+	require.Equal(t, 2, result.NumTurns)
+	require.Equal(t, int64(100), result.Usage.InputTokens)
+	require.Equal(t, 0.5, result.CostUSD)
+	require.Len(t, result.ToolCalls, 1)
+	require.Equal(t, "ls", result.ToolCalls[0].Name)
+	require.JSONEq(t, `{"path":"."}`, string(result.ToolCalls[0].Input))
+	require.Equal(t, "- main.go", result.ToolCalls[0].Result)
+}
+
+func TestRunPrinterJSON(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	printer := newRunPrinter(OutputFormatJSON, &output, "s")
+	require.NoError(t, printer.start("model", "provider"))
+	msgs := runMessages()
+	for _, msg := range msgs {
+		require.NoError(t, printer.message(msg))
+	}
+	require.Empty(t, output.String())
+	require.NoError(t, printer.result(session.Session{}, msgs, nil))
+
+	var result RunResult
+	require.NoError(t, json.Unmarshal(output.Bytes(), &result))
+	require.Equal(t, "result", result.Type)
+	require.False(t, result.IsError)
+}
@@ -0,0 +1,284 @@
+// Package checkpoint snapshots the working directory before the agent
+// changes it, so that its changes can be reverted.
+//
+// Snapshots are commits of a shadow git repository kept with the project
+// data, separate from the user's own repository, with one ref per session.
+// Files ignored by the project's .gitignore are neither saved nor restored.
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/git"
+)
+
+// ErrGitNotFound is returned when git isn't installed.
+var ErrGitNotFound = errors.New("git not found in $PATH, checkpoints need it")
+
+// ErrTooManyFiles is returned by Create when the working directory holds
+// more files than a checkpoint saves.
+var ErrTooManyFiles = fmt.Errorf("more than %d files in the working directory, too many to checkpoint", maxFiles)
+
+const (
+	// maxDescriptionLength bounds the description stored with a checkpoint.
+	maxDescriptionLength = 200
+	// maxFiles is the number of files past which the working directory isn't
+	// snapshotted, as hashing them all would hold up every prompt.
+	maxFiles = 20_000
+	// maxFileSize is the size past which the files not saved yet are left
+	// out of checkpoints, as they're mostly build outputs and data sets.
+	maxFileSize = 10 << 20
+	// createTimeout bounds the time taken to snapshot the working directory.
+	createTimeout = time.Minute
+)
+
+// Checkpoint is a snapshot of the working directory.
+type Checkpoint struct {
+	// ID is the hash of the shadow commit.
+	ID          string
+	SessionID   string
+	Description string
+	CreatedAt   int64
+}
+
+// Service creates and restores the checkpoints of sessions.
+type Service interface {
+	// Create snapshots the working directory for the session.
+	Create(ctx context.Context, sessionID, description string) (Checkpoint, error)
+	// List returns the checkpoints of the session, newest first.
+	List(ctx context.Context, sessionID string) ([]Checkpoint, error)
+	// Restore brings the working directory back to the checkpoint. The
+	// current state is saved as a checkpoint first, so restoring can be
+	// undone.
+	Restore(ctx context.Context, sessionID, id string) error
+}
+
+type service struct {
+	gitDir     string
+	workingDir string
+	// mu serializes the git commands, as they share the shadow index.
+	mu sync.Mutex
+}
+
+// NewService returns a service keeping the checkpoints of workingDir in the
+// shadow repository at gitDir, which is created when needed.
+func NewService(gitDir, workingDir string) Service {
+	return &service{
+		gitDir:     gitDir,
+		workingDir: workingDir,
+	}
+}
+
+func (s *service) Create(ctx context.Context, sessionID, description string) (Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(ctx, sessionID, description)
+}
+
+func (s *service) create(ctx context.Context, sessionID, description string) (Checkpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	if err := s.init(ctx); err != nil {
+		return Checkpoint{}, err
+	}
+	if err := s.add(ctx); err != nil {
+		return Checkpoint{}, err
+	}
+	tree, err := s.git(ctx, "write-tree")
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	args := []string{"commit-tree", tree, "-m", truncate(description)}
+	if parent, err := s.git(ctx, "rev-parse", "--verify", "--quiet", sessionRef(sessionID)); err == nil {
+		args = append(args, "-p", parent)
+	}
+	id, err := s.git(ctx, args...)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if _, err := s.git(ctx, "update-ref", sessionRef(sessionID), id); err != nil {
+		return Checkpoint{}, err
+	}
+	return Checkpoint{
+		ID:          id,
+		SessionID:   sessionID,
+		Description: truncate(description),
+		CreatedAt:   time.Now().Unix(),
+	}, nil
+}
+
+func (s *service) List(ctx context.Context, sessionID string) ([]Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.gitDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	if _, err := s.git(ctx, "rev-parse", "--verify", "--quiet", sessionRef(sessionID)); err != nil {
+		return nil, nil
+	}
+	out, err := s.git(ctx, "log", "--format=%H%x00%ct%x00%s", sessionRef(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for line := range strings.SplitSeq(out, "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		createdAt, _ := strconv.ParseInt(fields[1], 10, 64)
+		checkpoints = append(checkpoints, Checkpoint{
+			ID:          fields[0],
+			SessionID:   sessionID,
+			Description: fields[2],
+			CreatedAt:   createdAt,
+		})
+	}
+	return checkpoints, nil
+}
+
+func (s *service) Restore(ctx context.Context, sessionID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.git(ctx, "cat-file", "-e", id+"^{commit}"); err != nil {
+		return fmt.Errorf("checkpoint %s not found", shortID(id))
+	}
+	// This also leaves the current state in the index, so that files created
+	// since the checkpoint are removed.
+	if _, err := s.create(ctx, sessionID, "Before restoring checkpoint "+shortID(id)); err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "read-tree", "--reset", "-u", id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// add stages the files of the working directory in the shadow index: the
+// ones saved before, changed or deleted since, and the new ones not ignored
+// and not too large.
+func (s *service) add(ctx context.Context) error {
+	saved, err := s.git(ctx, "ls-files", "-z", "--cached")
+	if err != nil {
+		return err
+	}
+	added, err := s.git(ctx, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return err
+	}
+	paths := splitPaths(saved)
+	others := splitPaths(added)
+	if len(paths)+len(others) > maxFiles {
+		return ErrTooManyFiles
+	}
+	for _, path := range others {
+		info, err := os.Lstat(filepath.Join(s.workingDir, path))
+		if err != nil || info.Size() > maxFileSize {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// The paths go through a file, as they may not fit on the command line.
+	pathspecs := filepath.Join(s.gitDir, "crush-add")
+	if err := os.WriteFile(pathspecs, []byte(strings.Join(paths, "\x00")), 0o644); err != nil {
+		return fmt.Errorf("failed to list the files to checkpoint: %w", err)
+	}
+	defer os.Remove(pathspecs)
+	_, err = s.git(ctx, "add", "--all", "--pathspec-from-file="+pathspecs, "--pathspec-file-nul")
+	return err
+}
+
+func splitPaths(out string) []string {
+	var paths []string
+	for path := range strings.SplitSeq(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// init creates the shadow repository, ignoring the data directory holding it.
+func (s *service) init(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.gitDir, "HEAD")); err == nil {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return ErrGitNotFound
+	}
+	if _, err := s.git(ctx, "init", "--quiet"); err != nil {
+		return err
+	}
+	exclude := ".git\n"
+	if rel, err := filepath.Rel(s.workingDir, filepath.Dir(s.gitDir)); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+		exclude += "/" + filepath.ToSlash(rel) + "/\n"
+	}
+	if err := os.MkdirAll(filepath.Join(s.gitDir, "info"), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint repository: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.gitDir, "info", "exclude"), []byte(exclude), 0o644); err != nil {
+		return fmt.Errorf("failed to create checkpoint repository: %w", err)
+	}
+	return nil
+}
+
+// git runs a git command on the shadow repository, returning its trimmed
+// output.
+func (s *service) git(ctx context.Context, args ...string) (string, error) {
+	env := append([]string{
+		"GIT_DIR=" + s.gitDir,
+		"GIT_WORK_TREE=" + s.workingDir,
+		"GIT_LITERAL_PATHSPECS=1",
+	}, git.Config(
+		"user.name", "Crush",
+		"user.email", "crush@charm.land",
+		"core.autocrlf", "false",
+		"core.safecrlf", "false",
+		"gc.auto", "0",
+	)...)
+	return git.RunEnv(ctx, s.workingDir, env, args...)
+}
+
+func sessionRef(sessionID string) string {
+	return "refs/crush/sessions/" + sessionID
+}
+
+// ShortID is the abbreviated ID of the checkpoint, for display.
+func (c Checkpoint) ShortID() string {
+	return shortID(c.ID)
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func truncate(description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	if description == "" {
+		return "Checkpoint"
+	}
+	if runes := []rune(description); len(runes) > maxDescriptionLength {
+		return string(runes[:maxDescriptionLength-1]) + "…"
+	}
+	return description
+}
@@ -0,0 +1,97 @@
+package checkpoint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoints(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not in $PATH")
+	}
+
+	workingDir := t.TempDir()
+	dataDir := filepath.Join(workingDir, ".crush")
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(workingDir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(workingDir, name), []byte(content), 0o644))
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(workingDir, name))
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	write(".gitignore", "build/\n")
+	write("main.go", "package main\n")
+	write("build/out", "binary")
+	write(".crush/crush.db", "data")
+
+	s := NewService(filepath.Join(dataDir, "checkpoints"), workingDir)
+	first, err := s.Create(t.Context(), "session", "First prompt")
+	require.NoError(t, err)
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("sub/new.go", "package sub\n")
+	write("build/out", "new binary")
+	_, err = s.Create(t.Context(), "session", "Second prompt")
+	require.NoError(t, err)
+
+	other, err := s.List(t.Context(), "other")
+	require.NoError(t, err)
+	require.Empty(t, other)
+
+	checkpoints, err := s.List(t.Context(), "session")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	require.Equal(t, "Second prompt", checkpoints[0].Description)
+	require.Equal(t, first.ID, checkpoints[1].ID)
+
+	require.NoError(t, s.Restore(t.Context(), "session", first.ID))
+	require.Equal(t, "package main\n", read("main.go"))
+	require.NoFileExists(t, filepath.Join(workingDir, "sub", "new.go"))
+	// Ignored files and the data directory are left alone.
+	require.Equal(t, "new binary", read("build/out"))
+	require.Equal(t, "data", read(".crush/crush.db"))
+
+	// Restoring saved the state before it, so it can be undone.
+	checkpoints, err = s.List(t.Context(), "session")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 3)
+	require.NoError(t, s.Restore(t.Context(), "session", checkpoints[0].ID))
+	require.Equal(t, "package main\n\nfunc main() {}\n", read("main.go"))
+	require.Equal(t, "package sub\n", read("sub/new.go"))
+
+	require.Error(t, s.Restore(t.Context(), "session", "0123456789abcdef"))
+}
+
+func TestCheckpointsSkipLargeFiles(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not in $PATH")
+	}
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workingDir, ".crush"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "data.bin"), make([]byte, maxFileSize+1), 0o644))
+
+	s := NewService(filepath.Join(workingDir, ".crush", "checkpoints"), workingDir)
+	first, err := s.Create(t.Context(), "session", "First prompt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package other\n"), 0o644))
+	require.NoError(t, s.Restore(t.Context(), "session", first.ID))
+
+	content, err := os.ReadFile(filepath.Join(workingDir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", string(content))
+	require.FileExists(t, filepath.Join(workingDir, "data.bin"), "files left out are left alone")
+	files, err := s.(*service).git(t.Context(), "ls-tree", "--name-only", first.ID)
+	require.NoError(t, err)
+	require.Equal(t, "main.go", files)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/acp"
+	"github.com/spf13/cobra"
+)
+
+var acpCmd = &cobra.Command{
+	Use:   "acp",
+	Short: "Serve Crush as an agent over the Agent Client Protocol",
+	Long: `Serve Crush as an agent over the Agent Client Protocol, on stdin and stdout,
+for editors such as Zed to use it as their agent. The sessions the editor
+creates are Crush sessions, and the permissions the tools request are asked
+in the editor.`,
+	Example: `
+# In the settings of Zed
+{
+  "agent_servers": {
+    "Crush": {
+      "command": "crush",
+      "args": ["acp"]
+    }
+  }
+}
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+		return acp.Serve(cmd.Context(), app, os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	acpCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/instance"
+	"github.com/charmbracelet/crush/internal/stringext"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [prompt...]",
+	Short: "Attach to the Crush running in the project",
+	Long: `Attach to the Crush already running in the project as a second view,
+following the session worked on last. Each line typed is queued as a prompt,
+run once the session is done with the ones before it.
+
+Given a prompt, queue it and exit.`,
+	Example: `
+# Follow the session of the Crush running in the project
+crush attach
+
+# Queue a prompt in it
+crush attach "Also update the changelog"
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cfg, err := config.Load(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %v", err)
+		}
+		running, err := instance.Find(cfg.Options.DataDirectory)
+		if err != nil {
+			return err
+		}
+
+		if len(args) > 0 {
+			sessionID, err := app.QueuePrompt(cmd.Context(), running.Socket, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Sent to session %s\n", sessionID)
+			return nil
+		}
+		return app.Attach(cmd.Context(), running.Socket, os.Stdin, cmd.OutOrStdout())
+	},
+}
+
+// offerAttach asks whether to attach to the instance already running in the
+// project, when there's a terminal to ask on, instead of sharing its
+// database.
+func offerAttach(cmd *cobra.Command, running *instance.RunningError) error {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("%w, use crush attach to follow it", running)
+	}
+	fmt.Fprintf(os.Stderr, "%s. Attach to it? [Y/n] ", stringext.Capitalize(running.Error()))
+	input := bufio.NewReader(os.Stdin)
+	answer, err := input.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return app.Attach(cmd.Context(), running.Socket, input, os.Stdout)
+	default:
+		return running
+	}
+}
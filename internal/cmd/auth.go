@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
+	"github.com/pkg/browser"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage provider authentication",
+	Long:  `Inspect the credentials Crush uses to authenticate with providers.`,
+}
+
+var printTokenCmd = &cobra.Command{
+	Use:   "print-token [provider]",
+	Short: "Print a provider access token",
+	Long: `Print the access token Crush currently uses for a provider to stdout, so it can
+be reused in scripts. For GitHub Copilot this is the short-lived Copilot API token.
+Expired tokens are refreshed first. Defaults to the provider of the large model.
+
+The token grants access to your account: since it will be printed in plain text,
+the --yes flag is required.`,
+	Example: `
+# Print the Copilot API token
+crush auth print-token copilot --yes
+
+# Use it with curl
+curl -H "Authorization: Bearer $(crush auth print-token copilot --yes)" https://api.githubcopilot.com/models
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			return errors.New("refusing to print a secret token without --yes")
+		}
+
+		// Keep stdout clean for scripts.
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cfg, err := config.Init(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		var providerID string
+		if len(args) > 0 {
+			providerID = args[0]
+		} else {
+			providerID = cfg.Models[config.SelectedModelTypeLarge].Provider
+		}
+		if providerID == "" {
+			return errors.New("no provider given and no large model configured")
+		}
+
+		token, err := cfg.ProviderAccessToken(cmd.Context(), providerID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), token)
+		return nil
+	},
+}
+
+var mcpLoginCmd = &cobra.Command{
+	Use:   "mcp <server>",
+	Short: "Log in to a remote MCP server",
+	Long: `Authorize Crush with a remote MCP server that requires OAuth. The authorization
+page opens in your browser, and the token is stored in the data directory config
+and refreshed automatically.
+
+Crush registers itself with the authorization server unless a client ID is set in
+the oauth settings of the server.`,
+	Example: `
+# Log in to the "linear" MCP server
+crush auth mcp linear
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cfg, err := config.Init(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		name := args[0]
+		m, ok := cfg.MCP[name]
+		if !ok {
+			return fmt.Errorf("mcp server %s not found", name)
+		}
+		if m.Type != config.MCPHttp && m.Type != config.MCPSSE {
+			return fmt.Errorf("mcp server %s is not an http or sse server", name)
+		}
+		serverURL, err := cfg.Resolve(m.URL)
+		if err != nil {
+			return fmt.Errorf("invalid mcp url: %w", err)
+		}
+
+		var oauthCfg config.MCPOAuthConfig
+		if m.OAuth != nil {
+			oauthCfg = *m.OAuth
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+		defer cancel()
+		token, err := mcpauth.Login(ctx, mcpauth.LoginOptions{
+			Name:         name,
+			ServerURL:    serverURL,
+			ClientID:     oauthCfg.ClientID,
+			ClientSecret: oauthCfg.ClientSecret,
+			Scopes:       oauthCfg.Scopes,
+			OpenURL: func(url string) error {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Opening the authorization page in your browser. If it doesn't open, visit:\n\n%s\n\n", url)
+				_ = browser.OpenURL(url)
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetMCPToken(name, token); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Logged in to %s.\n", name)
+		return nil
+	},
+}
+
+func init() {
+	printTokenCmd.Flags().Bool("yes", false, "Confirm printing the token in plain text")
+	authCmd.AddCommand(printTokenCmd, mcpLoginCmd)
+}
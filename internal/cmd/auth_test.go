@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintTokenRequiresYes(t *testing.T) {
+	var b bytes.Buffer
+	printTokenCmd.SetOut(&b)
+	printTokenCmd.SetErr(&b)
+	err := printTokenCmd.RunE(printTokenCmd, []string{"copilot"})
+	require.ErrorContains(t, err, "--yes")
+	require.Empty(t, b.String())
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var commitMessageCmd = &cobra.Command{
+	Use:   "commit-message",
+	Short: "Write a commit message for the staged changes",
+	Long: `Write a commit message for the changes staged in git, using the small model.
+A diff can also be piped from stdin instead.`,
+	Example: `
+# Write a commit message for the staged changes
+crush commit-message
+
+# Commit with the generated message
+git commit -m "$(crush commit-message)"
+
+# Write a commit message for a diff
+git diff HEAD~1 | crush commit-message
+  `,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{sharesProjectAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		diff, err := MaybePrependStdin("")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(diff) == "" {
+			if diff, err = stagedDiff(cmd, app.Config().WorkingDir()); err != nil {
+				return err
+			}
+		}
+
+		msg, err := app.AgentCoordinator.GenerateCommitMessage(cmd.Context(), diff)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), msg)
+		return nil
+	},
+}
+
+// stagedDiff returns the diff of the changes staged in the git repository at
+// dir.
+func stagedDiff(cmd *cobra.Command, dir string) (string, error) {
+	var stderr bytes.Buffer
+	git := exec.CommandContext(cmd.Context(), "git", "diff", "--cached")
+	git.Dir = dir
+	git.Stderr = &stderr
+	out, err := git.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged changes: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the Crush configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration files",
+	Long: `Validate the configuration files found for the current directory against the
+configuration schema, reporting unknown keys and values of the wrong type. When
+they're valid, also report the providers missing their credentials.`,
+	Example: `
+# Validate the configuration of the current project
+crush config validate
+
+# Validate the configuration of another project
+crush config validate -c /path/to/project
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+
+		problems, err := config.Validate(cwd, dataDir)
+		if err != nil {
+			return fmt.Errorf("failed to validate the configuration: %w", err)
+		}
+		if len(problems) == 0 {
+			cmd.Println("The configuration is valid.")
+			return nil
+		}
+		for _, problem := range problems {
+			cmd.PrintErrln(problem)
+		}
+		if len(problems) == 1 {
+			return errors.New("found a problem in the configuration")
+		}
+		return fmt.Errorf("found %d problems in the configuration", len(problems))
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long: `Show the configuration of the files found for the current directory, each
+merged over the ones of lower priority, with the secrets in it redacted. The
+files are listed on the standard error, from the lowest priority to the
+highest.`,
+	Example: `
+# Show the effective configuration of the current project
+crush config show
+
+# Show only the files it's merged from
+crush config show --files
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+
+		files := config.Files(cwd)
+		if onlyFiles, _ := cmd.Flags().GetBool("files"); onlyFiles {
+			for _, file := range files {
+				cmd.Println(file)
+			}
+			return nil
+		}
+		for _, file := range files {
+			cmd.PrintErrln("Merged from " + file)
+		}
+
+		merged, err := config.Merged(cwd)
+		if err != nil {
+			return err
+		}
+		bts, err := json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal the configuration: %w", err)
+		}
+		fmt.Println(string(bts))
+		return nil
+	},
+}
+
+func init() {
+	configShowCmd.Flags().Bool("files", false, "Only list the configuration files, from the lowest priority to the highest")
+	configCmd.AddCommand(configValidateCmd, configShowCmd)
+}
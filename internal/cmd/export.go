@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [session-id]",
+	Short: "Export a session to Markdown or HTML",
+	Long: `Export a session, the latest one by default, to a self-contained Markdown
+or HTML file with its messages, tool calls, diffs and costs, for sharing and
+archival.`,
+	Example: `
+# Export the latest session to Markdown
+crush export
+
+# Export a session to an HTML file
+crush export 4f9a1c2e --format html --output session.html
+
+# Print the latest session as Markdown
+crush export --output -
+  `,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{sharesProjectAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		formatFlag, _ := cmd.Flags().GetString("format")
+
+		if formatFlag == "" {
+			formatFlag = "markdown"
+			if ext := filepath.Ext(output); ext != "" {
+				formatFlag = strings.TrimPrefix(ext, ".")
+			}
+		}
+		format, err := export.ParseFormat(formatFlag)
+		if err != nil {
+			return err
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		ctx := cmd.Context()
+		var sess session.Session
+		if len(args) == 1 {
+			if sess, err = app.Sessions.Get(ctx, args[0]); err != nil {
+				return fmt.Errorf("session %s not found: %w", args[0], err)
+			}
+		} else {
+			sessions, err := app.Sessions.List(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			if len(sessions) == 0 {
+				return fmt.Errorf("no sessions to export")
+			}
+			sess = sessions[0]
+		}
+		messages, err := app.Messages.List(ctx, sess.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		if output == "-" {
+			content, err := export.Render(sess, messages, format)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(content)
+			return err
+		}
+		if output == "" {
+			output = export.FileName(sess, format)
+		}
+		if err := export.WriteFile(output, sess, messages, format); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Exported session to", output)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "", "File to write, or - for stdout (default: named after the session)")
+	exportCmd.Flags().StringP("format", "f", "", "Export format: markdown or html (default: from the output extension, or markdown)")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/github"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// issuePlan is the session planning the implementation of an issue, with
+// the prompt to start it with.
+type issuePlan struct {
+	session     session.Session
+	prompt      string
+	attachments []message.Attachment
+}
+
+// planIssue fetches the issue given with --issue and creates the session
+// planning its implementation.
+func planIssue(cmd *cobra.Command, app *app.App, ref string) (issuePlan, error) {
+	repo, number, err := github.ParseRef(ref)
+	if err != nil {
+		return issuePlan{}, err
+	}
+	cfg := app.Config()
+	if !cfg.IsConfigured() {
+		return issuePlan{}, fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+	}
+	if repo == "" {
+		if repo, err = originRepo(cmd, cfg.WorkingDir()); err != nil {
+			return issuePlan{}, err
+		}
+	}
+	token, err := githubToken(cfg)
+	if err != nil {
+		return issuePlan{}, err
+	}
+
+	ctx := cmd.Context()
+	issue, err := github.NewClient(token).Issue(ctx, repo, number)
+	if err != nil {
+		return issuePlan{}, err
+	}
+	sess, prompt, attachments, err := app.PlanIssue(ctx, issue)
+	if err != nil {
+		return issuePlan{}, err
+	}
+	return issuePlan{session: sess, prompt: prompt, attachments: attachments}, nil
+}
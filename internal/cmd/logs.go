@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
@@ -45,6 +46,17 @@ var logsCmd = &cobra.Command{
 			return fmt.Errorf("failed to get tail flag: %v", err)
 		}
 
+		levelFlag, err := cmd.Flags().GetString("level")
+		if err != nil {
+			return fmt.Errorf("failed to get level flag: %v", err)
+		}
+		minLevel := slog.LevelDebug
+		if levelFlag != "" {
+			if err := minLevel.UnmarshalText([]byte(levelFlag)); err != nil {
+				return fmt.Errorf("invalid level %q, use debug, info, warn or error", levelFlag)
+			}
+		}
+
 		log.SetLevel(log.DebugLevel)
 		log.SetOutput(os.Stdout)
 		if !term.IsTerminal(os.Stdout.Fd()) {
@@ -63,19 +75,36 @@ var logsCmd = &cobra.Command{
 		}
 
 		if follow {
-			return followLogs(cmd.Context(), logsFile, tailLines)
+			return followLogs(cmd.Context(), logsFile, tailLines, minLevel)
 		}
 
-		return showLogs(logsFile, tailLines)
+		return showLogs(logsFile, tailLines, minLevel)
 	},
 }
 
 func init() {
 	logsCmd.Flags().BoolP("follow", "f", false, "Follow log output")
 	logsCmd.Flags().IntP("tail", "t", defaultTailLines, "Show only the last N lines default: 1000 for performance")
+	logsCmd.Flags().StringP("level", "l", "", "Show only the entries of this level and above: debug, info, warn or error")
+}
+
+// hasLevel reports whether a log line is of minLevel or above. Lines that
+// aren't log entries are left out.
+func hasLevel(lineText string, minLevel slog.Level) bool {
+	var entry struct {
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(lineText), &entry); err != nil {
+		return false
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(entry.Level)); err != nil {
+		return minLevel <= slog.LevelInfo
+	}
+	return level >= minLevel
 }
 
-func followLogs(ctx context.Context, logsFile string, tailLines int) error {
+func followLogs(ctx context.Context, logsFile string, tailLines int, minLevel slog.Level) error {
 	t, err := tail.TailFile(logsFile, tail.Config{
 		Follow: false,
 		ReOpen: false,
@@ -87,7 +116,7 @@ func followLogs(ctx context.Context, logsFile string, tailLines int) error {
 
 	var lines []string
 	for line := range t.Lines {
-		if line.Err != nil {
+		if line.Err != nil || !hasLevel(line.Text, minLevel) {
 			continue
 		}
 		lines = append(lines, line.Text)
@@ -120,7 +149,7 @@ func followLogs(ctx context.Context, logsFile string, tailLines int) error {
 	for {
 		select {
 		case line := <-t.Lines:
-			if line.Err != nil {
+			if line.Err != nil || !hasLevel(line.Text, minLevel) {
 				continue
 			}
 			printLogLine(line.Text)
@@ -130,7 +159,7 @@ func followLogs(ctx context.Context, logsFile string, tailLines int) error {
 	}
 }
 
-func showLogs(logsFile string, tailLines int) error {
+func showLogs(logsFile string, tailLines int, minLevel slog.Level) error {
 	t, err := tail.TailFile(logsFile, tail.Config{
 		Follow:      false,
 		ReOpen:      false,
@@ -144,7 +173,7 @@ func showLogs(logsFile string, tailLines int) error {
 
 	var lines []string
 	for line := range t.Lines {
-		if line.Err != nil {
+		if line.Err != nil || !hasLevel(line.Text, minLevel) {
 			continue
 		}
 		lines = append(lines, line.Text)
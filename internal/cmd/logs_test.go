@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasLevel(t *testing.T) {
+	t.Parallel()
+
+	warn := `{"time":"2026-10-16T10:00:00Z","level":"WARN","msg":"slow"}`
+	require.True(t, hasLevel(warn, slog.LevelDebug))
+	require.True(t, hasLevel(warn, slog.LevelWarn))
+	require.False(t, hasLevel(warn, slog.LevelError))
+
+	// Levels in between, as slog writes them.
+	require.True(t, hasLevel(`{"level":"ERROR+2","msg":"worse"}`, slog.LevelError))
+
+	require.False(t, hasLevel("not json", slog.LevelDebug))
+}
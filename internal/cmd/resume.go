@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// resumeSession returns the session to start the TUI with: the one given
+// with --resume, by its ID or a prefix of it, or the last one worked on with
+// --continue. It returns false when there's none to resume.
+func resumeSession(ctx context.Context, cmd *cobra.Command, sessions session.Service) (session.Session, bool, error) {
+	id, _ := cmd.Flags().GetString("resume")
+	cont, _ := cmd.Flags().GetBool("continue")
+	if id == "" && !cont {
+		return session.Session{}, false, nil
+	}
+
+	if id != "" {
+		if sess, err := sessions.Get(ctx, id); err == nil {
+			return sess, true, nil
+		}
+	}
+	list, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, false, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if id != "" {
+		sess, err := findSession(list, id)
+		return sess, err == nil, err
+	}
+	sess, ok := latestSession(list)
+	return sess, ok, nil
+}
+
+// findSession returns the session whose ID starts with prefix, when a single
+// one does.
+func findSession(sessions []session.Session, prefix string) (session.Session, error) {
+	var found []session.Session
+	for _, sess := range sessions {
+		if strings.HasPrefix(sess.ID, prefix) {
+			found = append(found, sess)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return session.Session{}, fmt.Errorf("session %s not found", prefix)
+	case 1:
+		return found[0], nil
+	default:
+		return session.Session{}, fmt.Errorf("session ID %s is ambiguous, %d sessions start with it", prefix, len(found))
+	}
+}
+
+// latestSession returns the session updated last.
+func latestSession(sessions []session.Session) (session.Session, bool) {
+	var latest session.Session
+	for _, sess := range sessions {
+		if latest.ID == "" || sess.UpdatedAt > latest.UpdatedAt {
+			latest = sess
+		}
+	}
+	return latest, latest.ID != ""
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSession(t *testing.T) {
+	t.Parallel()
+
+	sessions := []session.Session{
+		{ID: "4f9a1c2e-aaaa"},
+		{ID: "4f9b0000-bbbb"},
+	}
+
+	sess, err := findSession(sessions, "4f9a")
+	require.NoError(t, err)
+	require.Equal(t, "4f9a1c2e-aaaa", sess.ID)
+
+	_, err = findSession(sessions, "4f9")
+	require.ErrorContains(t, err, "ambiguous")
+
+	_, err = findSession(sessions, "ffff")
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestLatestSession(t *testing.T) {
+	t.Parallel()
+
+	_, ok := latestSession(nil)
+	require.False(t, ok)
+
+	sess, ok := latestSession([]session.Session{
+		{ID: "old", UpdatedAt: 100},
+		{ID: "new", UpdatedAt: 300},
+		{ID: "mid", UpdatedAt: 200},
+	})
+	require.True(t, ok)
+	require.Equal(t, "new", sess.ID)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/github"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review <pull-request>",
+	Short: "Review a GitHub pull request",
+	Long: `Review a GitHub pull request: fetch its diff and comments, run a review agent
+over them and print its review. The agent can read the files of the working
+directory for context, but not change them nor run commands.
+
+The pull request is given by its number, in the repository of the origin
+remote, as owner/name#number or by its URL. With --post, the review is posted
+on the pull request as comments.
+
+GitHub is called with the GH_TOKEN or GITHUB_TOKEN environment variable, or
+else with the token of your GitHub Copilot login. That token can only read
+public repositories, so --post needs GH_TOKEN or GITHUB_TOKEN set to a token
+allowed to write pull requests.`,
+	Example: `
+# Review a pull request of the current repository
+crush review 42
+
+# Review a pull request and post the review on it
+crush review https://github.com/charmbracelet/crush/pull/42 --post
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		post, _ := cmd.Flags().GetBool("post")
+
+		repo, number, err := github.ParseRef(args[0])
+		if err != nil {
+			return err
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		cfg := app.Config()
+		if !cfg.IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+		if repo == "" {
+			if repo, err = originRepo(cmd, cfg.WorkingDir()); err != nil {
+				return err
+			}
+		}
+		token, err := githubToken(cfg)
+		if err != nil {
+			return err
+		}
+		if post && !hasEnvGitHubToken() {
+			return errors.New("--post needs GH_TOKEN or GITHUB_TOKEN set to a token allowed to write pull requests: the GitHub Copilot login can't post reviews")
+		}
+
+		ctx := cmd.Context()
+		client := github.NewClient(token)
+		pr, err := client.PullRequest(ctx, repo, number)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Reviewing %s: %s\n", pr.URL, pr.Title)
+
+		review, err := app.ReviewPullRequest(ctx, pr)
+		if err != nil {
+			return err
+		}
+		printReview(cmd.OutOrStdout(), review)
+
+		if !post {
+			return nil
+		}
+		url, err := client.CreateReview(ctx, pr, review.OnDiff(pr.Diff))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Posted the review: %s\n", url)
+		return nil
+	},
+}
+
+func init() {
+	reviewCmd.Flags().Bool("post", false, "Post the review on the pull request")
+}
+
+// originRepo returns the GitHub repository of the origin remote of the git
+// repository at dir, in owner/name form.
+func originRepo(cmd *cobra.Command, dir string) (string, error) {
+	git := exec.CommandContext(cmd.Context(), "git", "remote", "get-url", "origin")
+	git.Dir = dir
+	out, err := git.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the origin remote, give it as owner/name#number: %w", err)
+	}
+	repo := copilot.RepoFromRemoteURL(string(out))
+	if repo == "" {
+		return "", errors.New("the origin remote isn't on GitHub, give it as owner/name#number")
+	}
+	return repo, nil
+}
+
+// hasEnvGitHubToken reports whether a GitHub token is set in the
+// environment.
+func hasEnvGitHubToken() bool {
+	return cmp.Or(os.Getenv("GH_TOKEN"), os.Getenv("GITHUB_TOKEN")) != ""
+}
+
+// githubToken returns the token to call GitHub with: the one of the
+// environment, or else the GitHub OAuth token of the Copilot login, which
+// only has the read:user scope.
+func githubToken(cfg *config.Config) (string, error) {
+	if token := cmp.Or(os.Getenv("GH_TOKEN"), os.Getenv("GITHUB_TOKEN")); token != "" {
+		return token, nil
+	}
+	// The GitHub OAuth token of Copilot is stored as the refresh token.
+	if providerCfg, ok := cfg.Providers.Get(copilot.ProviderID); ok && providerCfg.OAuthToken != nil && providerCfg.OAuthToken.RefreshToken != "" {
+		return providerCfg.OAuthToken.RefreshToken, nil
+	}
+	return "", errors.New("no GitHub token: log in to GitHub Copilot in crush, or set GITHUB_TOKEN")
+}
+
+// printReview prints a review as Markdown.
+func printReview(w io.Writer, review github.Review) {
+	if review.Body != "" {
+		fmt.Fprintln(w, review.Body)
+	}
+	for _, comment := range review.Comments {
+		fmt.Fprintf(w, "\n**%s:%d**\n\n%s\n", comment.Path, comment.Line, comment.Body)
+	}
+}
@@ -19,9 +19,11 @@ import (
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/db"
 	"github.com/charmbracelet/crush/internal/event"
+	"github.com/charmbracelet/crush/internal/instance"
 	termutil "github.com/charmbracelet/crush/internal/term"
 	"github.com/charmbracelet/crush/internal/tui"
 	"github.com/charmbracelet/crush/internal/version"
+	"github.com/charmbracelet/crush/internal/worktree"
 	"github.com/charmbracelet/fang"
 	uv "github.com/charmbracelet/ultraviolet"
 	"github.com/charmbracelet/x/ansi"
@@ -36,6 +38,11 @@ func init() {
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Debug")
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	rootCmd.Flags().BoolP("worktree", "w", false, "Work in a new git worktree and branch, merged back after review")
+	rootCmd.Flags().Bool("continue", false, "Continue the last session worked on")
+	rootCmd.Flags().StringP("resume", "r", "", "Resume the session with the given ID, or a prefix of it")
+	rootCmd.Flags().String("issue", "", "Plan the implementation of a GitHub issue, given by its URL, owner/name#number or number, in a new session")
+	rootCmd.MarkFlagsMutuallyExclusive("continue", "resume", "issue")
 
 	rootCmd.AddCommand(
 		runCmd,
@@ -43,6 +50,14 @@ func init() {
 		updateProvidersCmd,
 		logsCmd,
 		schemaCmd,
+		configCmd,
+		authCmd,
+		commitMessageCmd,
+		serveCmd,
+		exportCmd,
+		attachCmd,
+		acpCmd,
+		reviewCmd,
 	)
 }
 
@@ -73,20 +88,62 @@ crush run "Explain the use of context in Go"
 
 # Run in dangerous mode (auto-accept all permissions)
 crush -y
+
+# Work in a new git worktree, leaving the checked out branch untouched
+crush -w
+
+# Continue the last session worked on
+crush --continue
+
+# Resume a session by its ID
+crush --resume 4f9a1c2e
+
+# Plan the implementation of an issue
+crush --issue https://github.com/charmbracelet/crush/issues/42
+
+# Follow the Crush already running in the project
+crush attach
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		app, err := setupAppWithProgressBar(cmd)
+		var running *instance.RunningError
+		if errors.As(err, &running) {
+			return offerAttach(cmd, running)
+		}
 		if err != nil {
 			return err
 		}
-		defer app.Shutdown()
+		defer func() {
+			app.Shutdown()
+			finishWorktree(cmd.Context())
+		}()
+
+		sess, resume, err := resumeSession(cmd.Context(), cmd, app.Sessions)
+		if err != nil {
+			return err
+		}
+		var plan issuePlan
+		if ref, _ := cmd.Flags().GetString("issue"); ref != "" {
+			if plan, err = planIssue(cmd, app, ref); err != nil {
+				return err
+			}
+			sess, resume = plan.session, true
+		}
 
 		event.AppInitialized()
 
 		// Set up the TUI.
+		if err := tui.ValidateKeys(app.Config().Options.TUI.Keys); err != nil {
+			return err
+		}
 		var env uv.Environ = os.Environ()
 		ui := tui.New(app)
 		ui.QueryVersion = shouldQueryTerminalVersion(env)
+		if resume {
+			ui.InitialSession = &sess
+			ui.InitialPrompt = plan.prompt
+			ui.InitialAttachments = plan.attachments
+		}
 
 		program := tea.NewProgram(
 			ui,
@@ -150,6 +207,10 @@ func Execute() {
 	}
 }
 
+// sharesProjectAnnotation marks the commands that can run along the
+// instance holding the project, as they don't change its sessions.
+const sharesProjectAnnotation = "shares-project"
+
 func setupAppWithProgressBar(cmd *cobra.Command) (*app.App, error) {
 	if termutil.SupportsProgressBar() {
 		_, _ = fmt.Fprintf(os.Stderr, ansi.SetIndeterminateProgressBar)
@@ -161,7 +222,7 @@ func setupAppWithProgressBar(cmd *cobra.Command) (*app.App, error) {
 
 // setupApp handles the common setup logic for both interactive and non-interactive modes.
 // It returns the app instance, config, cleanup function, and any error.
-func setupApp(cmd *cobra.Command) (*app.App, error) {
+func setupApp(cmd *cobra.Command) (_ *app.App, err error) {
 	debug, _ := cmd.Flags().GetBool("debug")
 	yolo, _ := cmd.Flags().GetBool("yolo")
 	dataDir, _ := cmd.Flags().GetString("data-dir")
@@ -177,6 +238,34 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		return nil, err
 	}
 
+	// The first instance holds the project: the ones started after it attach
+	// to it instead of sharing its database, but for the commands only
+	// reading it.
+	var lock *instance.Lock
+	if cmd.Annotations[sharesProjectAnnotation] == "" {
+		if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
+			return nil, err
+		}
+		if lock, err = instance.Acquire(cfg.Options.DataDirectory); err != nil {
+			var running *instance.RunningError
+			if errors.As(err, &running) {
+				return nil, fmt.Errorf("%w, use crush attach to follow it", running)
+			}
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				lock.Release()
+			}
+		}()
+	}
+
+	if useWorktree, _ := cmd.Flags().GetBool("worktree"); useWorktree {
+		if err := setupWorktree(ctx, cfg, cwd); err != nil {
+			return nil, err
+		}
+	}
+
 	if cfg.Permissions == nil {
 		cfg.Permissions = &config.Permissions{}
 	}
@@ -197,6 +286,9 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		slog.Error("Failed to create app instance", "error", err)
 		return nil, err
 	}
+	if lock != nil {
+		appInstance.ServeInstance(lock)
+	}
 
 	if shouldEnableMetrics() {
 		event.Init()
@@ -205,6 +297,46 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 	return appInstance, nil
 }
 
+// setupWorktree moves Crush to a new worktree of the project, keeping its
+// data in the project.
+func setupWorktree(ctx context.Context, cfg *config.Config, cwd string) error {
+	dataDir, err := filepath.Abs(cfg.Options.DataDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+	if err := createDotCrushDir(dataDir); err != nil {
+		return err
+	}
+	wt, err := worktree.Create(ctx, cwd, filepath.Join(dataDir, "worktrees"))
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(wt.WorkingDir); err != nil {
+		return fmt.Errorf("failed to change directory: %v", err)
+	}
+	cfg.Options.DataDirectory = dataDir
+	cfg.SetWorkingDir(wt.WorkingDir)
+	worktree.SetCurrent(wt)
+	slog.Info("Working in worktree", "dir", wt.Dir, "branch", wt.Branch)
+	return nil
+}
+
+// finishWorktree removes the worktree when its changes were merged, and
+// tells the user where they are otherwise.
+func finishWorktree(ctx context.Context) {
+	wt := worktree.Current()
+	if wt == nil {
+		return
+	}
+	if wt.Merged(ctx) {
+		if err := wt.Remove(ctx); err != nil {
+			slog.Error("Failed to remove worktree", "dir", wt.Dir, "error", err)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Changes kept in worktree %s on branch %s\n", wt.Dir, wt.Branch)
+}
+
 func shouldEnableMetrics() bool {
 	if v, _ := strconv.ParseBool(os.Getenv("CRUSH_DISABLE_METRICS")); v {
 		return false
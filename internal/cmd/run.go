@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"strings"
 
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/instance"
 	"github.com/spf13/cobra"
 )
 
@@ -13,7 +16,15 @@ var runCmd = &cobra.Command{
 	Use:   "run [prompt...]",
 	Short: "Run a single non-interactive prompt",
 	Long: `Run a single prompt in non-interactive mode and exit.
-The prompt can be provided as arguments or piped from stdin.`,
+The prompt can be provided with --prompt, as arguments or piped from stdin.
+
+With --output-format json, a single JSON object with the final answer, the
+tool calls, the token usage and the cost is printed once the run is over.
+With --output-format stream-json, a JSON object is printed per line as the
+run goes, for text, tool calls and tool results, ending with the same object.
+
+With Crush already running in the project, a text prompt runs in a new
+session of it.`,
 	Example: `
 # Run a simple prompt
 crush run Explain the use of context in Go
@@ -26,21 +37,21 @@ crush run "What is this code doing?" <<< prrr.go
 
 # Run in quiet mode (hide the spinner)
 crush run --quiet "Generate a README for this project"
+
+# Get the answer, tool calls and cost as JSON, in scripts and CI
+crush run -p "Fix the failing tests" --output-format json | jq .result
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		flagPrompt, _ := cmd.Flags().GetString("prompt")
+		formatFlag, _ := cmd.Flags().GetString("output-format")
 
-		app, err := setupApp(cmd)
+		outputFormat, err := app.ParseOutputFormat(formatFlag)
 		if err != nil {
 			return err
 		}
-		defer app.Shutdown()
 
-		if !app.Config().IsConfigured() {
-			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
-		}
-
-		prompt := strings.Join(args, " ")
+		prompt := strings.TrimSpace(flagPrompt + " " + strings.Join(args, " "))
 
 		prompt, err = MaybePrependStdin(prompt)
 		if err != nil {
@@ -52,16 +63,33 @@ crush run --quiet "Generate a README for this project"
 			return fmt.Errorf("no prompt provided")
 		}
 
+		appInstance, err := setupApp(cmd)
+		var running *instance.RunningError
+		if errors.As(err, &running) && outputFormat == app.OutputFormatText {
+			// Run in the instance holding the project rather than failing.
+			return app.RunAttached(cmd.Context(), running.Socket, prompt, os.Stdout)
+		}
+		if err != nil {
+			return err
+		}
+		defer appInstance.Shutdown()
+
+		if !appInstance.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
 		// TODO: Make this work when redirected to something other than stdout.
 		// For example:
 		//     crush run "Do something fancy" > output.txt
 		//     echo "Do something fancy" | crush run > output.txt
 		//
 		// TODO: We currently need to press ^c twice to cancel. Fix that.
-		return app.RunNonInteractive(cmd.Context(), os.Stdout, prompt, quiet)
+		return appInstance.RunNonInteractive(cmd.Context(), os.Stdout, prompt, outputFormat, quiet)
 	},
 }
 
 func init() {
 	runCmd.Flags().BoolP("quiet", "q", false, "Hide spinner")
+	runCmd.Flags().StringP("prompt", "p", "", "Prompt to run, prepended to the arguments")
+	runCmd.Flags().String("output-format", string(app.OutputFormatText), "Output format: text, json or stream-json")
 }
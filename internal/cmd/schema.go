@@ -5,18 +5,15 @@ import (
 	"fmt"
 
 	"github.com/charmbracelet/crush/internal/config"
-	"github.com/invopop/jsonschema"
 	"github.com/spf13/cobra"
 )
 
 var schemaCmd = &cobra.Command{
-	Use:    "schema",
-	Short:  "Generate JSON schema for configuration",
-	Long:   "Generate JSON schema for the crush configuration file",
-	Hidden: true,
+	Use:   "schema",
+	Short: "Generate JSON schema for configuration",
+	Long:  "Generate JSON schema for the crush configuration file",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reflector := new(jsonschema.Reflector)
-		bts, err := json.MarshalIndent(reflector.Reflect(&config.Config{}), "", "  ")
+		bts, err := json.MarshalIndent(config.Schema(), "", "  ")
 		if err != nil {
 			return fmt.Errorf("failed to marshal schema: %w", err)
 		}
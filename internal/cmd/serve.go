@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/app"
+
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Drive Crush from another program with JSON events",
+	Long: `Stream the events of Crush as JSON lines, for editor plugins and other
+frontends to drive it: text and reasoning deltas, tool starts and finishes,
+permission requests, token usage and costs.
+
+Frontends write commands as JSON lines back:
+  {"type":"prompt","text":"...","session_id":"..."}
+  {"type":"permission","id":"...","action":"allow|allow_session|deny"}
+  {"type":"cancel","session_id":"..."}
+
+Prompts sent with "queue":true wait for a busy session to be done with the
+ones before them, instead of failing.
+
+Events are written to stdout and commands read from stdin, unless --socket
+is given, to serve every client connecting to a unix socket instead.
+
+With --http, Crush serves an HTTP API instead, on a host and port or the
+path of a unix socket:
+  GET  /v1/sessions                 list the sessions
+  POST /v1/sessions                 create one, {"title":"..."}
+  GET  /v1/sessions/{id}            get a session
+  GET  /v1/sessions/{id}/messages   list its messages
+  POST /v1/sessions/{id}/prompt     prompt it, {"text":"...","queue":true}
+  POST /v1/sessions/{id}/cancel     cancel its run
+  GET  /v1/permissions              list the permission requests waiting
+  POST /v1/permissions/{id}         answer one, {"action":"allow|allow_session|deny"}
+  GET  /v1/events                   stream the events as server-sent events
+
+Requests must carry the token given with --token, or CRUSH_SERVE_TOKEN, as a
+bearer token when one is set. Without a token, the API is only served on
+loopback addresses. Browsers can only call the API from the origins given
+with --allow-origin.`,
+	Example: `
+# Drive Crush through stdin and stdout
+echo '{"type":"prompt","text":"Explain this project"}' | crush serve
+
+# Serve the clients of a unix socket
+crush serve --socket /tmp/crush.sock
+
+# Serve the HTTP API on a local port
+crush serve --http 127.0.0.1:7777 --token "$(openssl rand -hex 16)"
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socket, _ := cmd.Flags().GetString("socket")
+		httpAddr, _ := cmd.Flags().GetString("http")
+		token, _ := cmd.Flags().GetString("token")
+		allowedOrigins, _ := cmd.Flags().GetStringSlice("allow-origin")
+		apiOpts := app.APIOptions{
+			Token:          cmp.Or(token, os.Getenv("CRUSH_SERVE_TOKEN")),
+			AllowedOrigins: allowedOrigins,
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		if httpAddr != "" {
+			return app.ServeAPI(cmd.Context(), httpAddr, apiOpts)
+		}
+		if socket != "" {
+			return app.ServeEventsSocket(cmd.Context(), socket)
+		}
+		return app.ServeEvents(cmd.Context(), os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("socket", "", "Path of a unix socket to serve instead of stdin and stdout")
+	serveCmd.Flags().String("http", "", "Address to serve the HTTP API on, a host and port or the path of a unix socket")
+	serveCmd.Flags().String("token", "", "Bearer token the HTTP API requires, defaults to $CRUSH_SERVE_TOKEN")
+	serveCmd.Flags().StringSlice("allow-origin", nil, "Origin of a web frontend allowed to call the HTTP API")
+	serveCmd.MarkFlagsMutuallyExclusive("socket", "http")
+	serveCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+}
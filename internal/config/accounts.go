@@ -0,0 +1,116 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/oauth"
+)
+
+// ProviderAccount is a named set of credentials for a provider. Storing
+// several accounts lets the user switch identities without logging in again.
+type ProviderAccount struct {
+	// The account name, usually the user name or email of the identity.
+	Name string `json:"name" jsonschema:"description=Name identifying the account,example=octocat"`
+	// The account's API key, for providers that don't use OAuth.
+	APIKey string `json:"api_key,omitempty" jsonschema:"description=API key for the account"`
+	// The account's OAuth token, stored in the tokens of the config.
+	OAuthToken *oauth.Token `json:"-"`
+}
+
+// Account returns the stored account with the given name.
+func (pc *ProviderConfig) Account(name string) (ProviderAccount, bool) {
+	idx := slices.IndexFunc(pc.Accounts, func(a ProviderAccount) bool {
+		return a.Name == name
+	})
+	if idx == -1 {
+		return ProviderAccount{}, false
+	}
+	return pc.Accounts[idx], true
+}
+
+// currentAccount returns the provider's credentials in use as an account.
+func (pc *ProviderConfig) currentAccount(name string) ProviderAccount {
+	account := ProviderAccount{Name: name, OAuthToken: pc.OAuthToken}
+	if pc.OAuthToken == nil {
+		account.APIKey = pc.APIKey
+	}
+	return account
+}
+
+// setAccount adds the account, replacing any account with the same name.
+func (pc *ProviderConfig) setAccount(account ProviderAccount) {
+	idx := slices.IndexFunc(pc.Accounts, func(a ProviderAccount) bool {
+		return a.Name == account.Name
+	})
+	if idx == -1 {
+		pc.Accounts = append(pc.Accounts, account)
+		return
+	}
+	pc.Accounts[idx] = account
+}
+
+// syncActiveAccount copies the credentials in use into the active account,
+// e.g. after a token refresh. It reports whether an account was updated.
+func (pc *ProviderConfig) syncActiveAccount() bool {
+	if _, ok := pc.Account(pc.ActiveAccount); !ok {
+		return false
+	}
+	pc.setAccount(pc.currentAccount(pc.ActiveAccount))
+	return true
+}
+
+// SaveProviderAccount stores the credentials the provider currently uses
+// under the given account name and makes it the active account.
+func (c *Config) SaveProviderAccount(providerID, name string) error {
+	providerConfig, ok := c.Providers.Get(providerID)
+	if !ok {
+		return fmt.Errorf("provider %s not found", providerID)
+	}
+	if name == "" {
+		return fmt.Errorf("account name is required")
+	}
+
+	providerConfig.setAccount(providerConfig.currentAccount(name))
+	providerConfig.ActiveAccount = name
+	c.Providers.Set(providerID, providerConfig)
+
+	if err := cmp.Or(
+		c.saveAccounts(providerID, providerConfig.Accounts),
+		c.SetConfigField(fmt.Sprintf("providers.%s.active_account", providerID), name),
+	); err != nil {
+		return fmt.Errorf("failed to save account %s: %w", name, err)
+	}
+	return nil
+}
+
+// SwitchProviderAccount makes a stored account the active one for the
+// provider, replacing the credentials in use with the account's.
+func (c *Config) SwitchProviderAccount(providerID, name string) error {
+	providerConfig, ok := c.Providers.Get(providerID)
+	if !ok {
+		return fmt.Errorf("provider %s not found", providerID)
+	}
+	account, ok := providerConfig.Account(name)
+	if !ok {
+		return fmt.Errorf("account %s not found for provider %s", name, providerID)
+	}
+
+	var credentials any = account.APIKey
+	if account.OAuthToken != nil {
+		credentials = account.OAuthToken
+	}
+	if err := c.SetProviderAPIKey(providerID, credentials); err != nil {
+		return fmt.Errorf("failed to switch to account %s: %w", name, err)
+	}
+
+	providerConfig, _ = c.Providers.Get(providerID)
+	providerConfig.ActiveAccount = name
+	c.Providers.Set(providerID, providerConfig)
+
+	if err := c.SetConfigField(fmt.Sprintf("providers.%s.active_account", providerID), name); err != nil {
+		return fmt.Errorf("failed to save active account: %w", err)
+	}
+	return nil
+}
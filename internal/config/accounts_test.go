@@ -0,0 +1,91 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderAccounts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := &Config{}
+	cfg.setDefaults(dir, "")
+	cfg.dataConfigDir = filepath.Join(dir, "config.json")
+	cfg.Providers = csync.NewMap[string, ProviderConfig]()
+	cfg.Providers.Set("openai", ProviderConfig{ID: "openai", APIKey: "key-work"})
+
+	require.NoError(t, cfg.SaveProviderAccount("openai", "work"))
+
+	require.NoError(t, cfg.SetProviderAPIKey("openai", "key-personal"))
+	require.NoError(t, cfg.SaveProviderAccount("openai", "personal"))
+
+	pc, ok := cfg.Providers.Get("openai")
+	require.True(t, ok)
+	require.Len(t, pc.Accounts, 2)
+	require.Equal(t, "personal", pc.ActiveAccount)
+
+	require.NoError(t, cfg.SwitchProviderAccount("openai", "work"))
+	pc, _ = cfg.Providers.Get("openai")
+	require.Equal(t, "key-work", pc.APIKey)
+	require.Equal(t, "work", pc.ActiveAccount)
+
+	// persisted state
+	out := readConfigJSON(t, cfg.dataConfigDir)
+	providers := out["providers"].(map[string]any)
+	openai := providers["openai"].(map[string]any)
+	require.Equal(t, "work", openai["active_account"])
+	require.Equal(t, "key-work", openai["api_key"])
+	require.Len(t, openai["accounts"], 2)
+
+	require.Error(t, cfg.SwitchProviderAccount("openai", "missing"))
+	require.Error(t, cfg.SaveProviderAccount("missing", "work"))
+}
+
+func TestProviderAccessToken(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{}
+	cfg.setDefaults(t.TempDir(), "")
+	cfg.resolver = NewEnvironmentVariableResolver(env.NewFromMap(map[string]string{
+		"OPENAI_API_KEY": "sk-openai",
+	}))
+	cfg.Providers = csync.NewMap[string, ProviderConfig]()
+	cfg.Providers.Set("openai", ProviderConfig{ID: "openai", APIKey: "$OPENAI_API_KEY"})
+	cfg.Providers.Set("empty", ProviderConfig{ID: "empty"})
+	cfg.Providers.Set(copilot.ProviderID, ProviderConfig{
+		ID: copilot.ProviderID,
+		OAuthToken: &oauth.Token{
+			RefreshToken:     "gho_github",
+			CopilotToken:     "tid=copilot",
+			CopilotExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	})
+	cfg.Providers.Set("anthropic", ProviderConfig{
+		ID:         "anthropic",
+		OAuthToken: &oauth.Token{AccessToken: "sk-ant-oat", ExpiresIn: 3600, ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+
+	ctx := t.Context()
+	for provider, want := range map[string]string{
+		"openai":           "sk-openai",
+		copilot.ProviderID: "tid=copilot",
+		"anthropic":        "sk-ant-oat",
+	} {
+		token, err := cfg.ProviderAccessToken(ctx, provider)
+		require.NoError(t, err)
+		require.Equal(t, want, token)
+	}
+
+	_, err := cfg.ProviderAccessToken(ctx, "empty")
+	require.Error(t, err)
+	_, err = cfg.ProviderAccessToken(ctx, "missing")
+	require.Error(t, err)
+}
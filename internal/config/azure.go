@@ -0,0 +1,44 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+)
+
+// azureDeploymentModels returns the models of the Azure deployments, named
+// after the deployments and with the metadata of the models they serve,
+// followed by the models configured by hand.
+func azureDeploymentModels(deployments map[string]string, known, configured []catwalk.Model) []catwalk.Model {
+	models := make([]catwalk.Model, 0, len(deployments)+len(configured))
+	for _, deployment := range slices.Sorted(maps.Keys(deployments)) {
+		modelID := deployments[deployment]
+		idx := slices.IndexFunc(known, func(m catwalk.Model) bool { return m.ID == modelID })
+		if idx == -1 {
+			slog.Warn("Unknown model of Azure deployment, its metadata is missing", "deployment", deployment, "model", modelID)
+			models = append(models, catwalk.Model{ID: deployment, Name: deployment})
+			continue
+		}
+		model := known[idx]
+		model.ID = deployment
+		model.Name = fmt.Sprintf("%s (%s)", deployment, known[idx].Name)
+		models = append(models, model)
+	}
+	for _, model := range configured {
+		if !slices.ContainsFunc(models, func(m catwalk.Model) bool { return m.ID == model.ID }) {
+			model.Name = cmp.Or(model.Name, model.ID)
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// hasAzureDeployments reports whether the deployments of the Azure provider
+// are configured.
+func (pc ProviderConfig) hasAzureDeployments() bool {
+	return pc.Azure != nil && len(pc.Azure.Deployments) > 0
+}
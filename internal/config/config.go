@@ -3,13 +3,16 @@ package config
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
@@ -18,6 +21,7 @@ import (
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/claude"
 	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/tracing"
 	"github.com/invopop/jsonschema"
 	"github.com/tidwall/sjson"
 )
@@ -26,6 +30,12 @@ const (
 	appName              = "crush"
 	defaultDataDirectory = ".crush"
 	defaultInitializeAs  = "AGENTS.md"
+	promptFragmentFile   = "prompt.md"
+
+	defaultBashMaxOutputBytes = 30000
+	defaultFetchMaxTokens     = 25000
+	defaultWebSearchResults   = 8
+	defaultContextFileTokens  = 8000
 )
 
 var defaultContextPaths = []string{
@@ -73,6 +83,9 @@ type SelectedModel struct {
 	// Used by anthropic models that can reason to indicate if the model should think.
 	Think bool `json:"think,omitempty" jsonschema:"description=Enable thinking mode for Anthropic models that support reasoning"`
 
+	// The tokens anthropic models think with, and gemini ones.
+	ThinkingBudget int64 `json:"thinking_budget,omitempty" jsonschema:"description=Tokens Anthropic and Gemini models may think with,minimum=1024,example=8000"`
+
 	// Overrides the default model configuration.
 	MaxTokens        int64    `json:"max_tokens,omitempty" jsonschema:"description=Maximum number of tokens for model responses,minimum=1,maximum=200000,example=4096"`
 	Temperature      *float64 `json:"temperature,omitempty" jsonschema:"description=Sampling temperature,minimum=0,maximum=1,example=0.7"`
@@ -85,6 +98,61 @@ type SelectedModel struct {
 	ProviderOptions map[string]any `json:"provider_options,omitempty" jsonschema:"description=Additional provider-specific options for the model"`
 }
 
+// SamplingOptions override the sampling parameters of a model, unless the
+// selected model sets them.
+type SamplingOptions struct {
+	Temperature      *float64 `json:"temperature,omitempty" jsonschema:"description=Sampling temperature,minimum=0,maximum=2,example=0.7"`
+	TopP             *float64 `json:"top_p,omitempty" jsonschema:"description=Top-p (nucleus) sampling parameter,minimum=0,maximum=1,example=0.9"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty" jsonschema:"description=Frequency penalty to reduce repetition"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty" jsonschema:"description=Presence penalty to increase topic diversity"`
+	// Whether the model rejects the temperature parameter, like some
+	// Copilot models.
+	NoTemperature bool `json:"no_temperature,omitempty" jsonschema:"description=Never send the temperature parameter, for the models that reject it,default=false"`
+}
+
+// SupportsTemperature reports whether the model of the provider accepts a
+// sampling temperature.
+func (pc *ProviderConfig) SupportsTemperature(modelID string) bool {
+	return !pc.ModelOptions[modelID].NoTemperature
+}
+
+// setCopilotModels sets the Copilot models of the provider, marking the ones
+// rejecting the temperature parameter.
+func (pc *ProviderConfig) setCopilotModels(ctx context.Context, includePreview bool) {
+	models, noTemperature := copilot.GetModels(ctx, includePreview)
+	pc.Models = models
+	if len(noTemperature) > 0 && pc.ModelOptions == nil {
+		pc.ModelOptions = make(map[string]SamplingOptions, len(noTemperature))
+	}
+	for _, id := range noTemperature {
+		options := pc.ModelOptions[id]
+		options.NoTemperature = true
+		pc.ModelOptions[id] = options
+	}
+}
+
+// DefaultThinkingBudget is the thinking budget of the models not setting one.
+const DefaultThinkingBudget = 2000
+
+// Budget returns the tokens the model may think with.
+func (m SelectedModel) Budget() int64 {
+	return cmp.Or(m.ThinkingBudget, DefaultThinkingBudget)
+}
+
+// ThinksWithToggle reports whether thinking is switched on and off for the
+// model, with a budget, rather than set with a reasoning effort. That's the
+// case of the Anthropic models, wherever they're served.
+func ThinksWithToggle(providerType catwalk.Type, modelID string) bool {
+	switch providerType {
+	case catwalk.TypeAnthropic, catwalk.TypeBedrock:
+		return true
+	case catwalk.TypeVertexAI:
+		return strings.Contains(modelID, "claude") || strings.Contains(modelID, "anthropic")
+	default:
+		return false
+	}
+}
+
 type ProviderConfig struct {
 	// The provider's id.
 	ID string `json:"id,omitempty" jsonschema:"description=Unique identifier for the provider,example=openai"`
@@ -98,19 +166,38 @@ type ProviderConfig struct {
 	APIKey string `json:"api_key,omitempty" jsonschema:"description=API key for authentication with the provider,example=$OPENAI_API_KEY"`
 	// OAuthToken for providers that use OAuth2 authentication.
 	OAuthToken *oauth.Token `json:"oauth,omitempty" jsonschema:"description=OAuth2 token for authentication with the provider"`
+	// Stored accounts that can be switched between.
+	Accounts []ProviderAccount `json:"accounts,omitempty" jsonschema:"description=Stored accounts for this provider that can be switched between"`
+	// The name of the account whose credentials are currently in use.
+	ActiveAccount string `json:"active_account,omitempty" jsonschema:"description=Name of the account currently in use"`
 	// Marks the provider as disabled.
 	Disable bool `json:"disable,omitempty" jsonschema:"description=Whether this provider is disabled,default=false"`
 
 	// Custom system prompt prefix.
 	SystemPromptPrefix string `json:"system_prompt_prefix,omitempty" jsonschema:"description=Custom prefix to add to system prompts for this provider"`
 
+	// The header to send the API key in, as is, instead of the usual
+	// authentication of the provider type.
+	AuthHeader string `json:"auth_header,omitempty" jsonschema:"description=HTTP header to send the API key in instead of the default authentication of the provider type,example=X-API-Key"`
+
 	// Extra headers to send with each request to the provider.
 	ExtraHeaders map[string]string `json:"extra_headers,omitempty" jsonschema:"description=Additional HTTP headers to send with requests"`
 	// Extra body
 	ExtraBody map[string]any `json:"extra_body,omitempty" jsonschema:"description=Additional fields to include in request bodies, only works with openai-compatible providers"`
+	// Whether to leave out the tool_choice parameter, for the servers that
+	// reject it.
+	OmitToolChoice bool `json:"omit_tool_choice,omitempty" jsonschema:"description=Leave out the tool_choice parameter for servers that reject it. Only works with openai-compatible providers,default=false"`
 
 	ProviderOptions map[string]any `json:"provider_options,omitempty" jsonschema:"description=Additional provider-specific options for this provider"`
 
+	// Sampling parameters by model ID.
+	ModelOptions map[string]SamplingOptions `json:"model_options,omitempty" jsonschema:"description=Sampling parameters of the models of the provider by model ID"`
+
+	// Azure OpenAI settings.
+	Azure *AzureOptions `json:"azure,omitempty" jsonschema:"description=Azure OpenAI settings of the azure provider"`
+	// Vertex AI settings.
+	Vertex *VertexOptions `json:"vertex,omitempty" jsonschema:"description=Vertex AI settings of the vertexai provider"`
+
 	// Used to pass extra parameters to the provider.
 	ExtraParams map[string]string `json:"-"`
 
@@ -118,6 +205,65 @@ type ProviderConfig struct {
 	Models []catwalk.Model `json:"models,omitempty" jsonschema:"description=List of models available from this provider"`
 }
 
+// ResolvedHeaders returns the extra headers with their variables resolved,
+// and the resolved API key in AuthHeader if set. Without a resolver, the
+// values are used as they are.
+func (pc *ProviderConfig) ResolvedHeaders(resolver VariableResolver) map[string]string {
+	resolve := func(value string) string {
+		if resolver == nil {
+			return value
+		}
+		resolved, err := resolver.ResolveValue(value)
+		if err != nil {
+			slog.Error("error resolving provider header", "error", err, "provider", pc.ID)
+			return value
+		}
+		return resolved
+	}
+	headers := make(map[string]string, len(pc.ExtraHeaders)+1)
+	for k, v := range pc.ExtraHeaders {
+		headers[k] = resolve(v)
+	}
+	if pc.AuthHeader != "" {
+		headers[pc.AuthHeader] = resolve(pc.APIKey)
+	}
+	return headers
+}
+
+const (
+	AzureAuthAPIKey  = "api-key"
+	AzureAuthEntraID = "entra-id"
+)
+
+// AzureOptions configure the Azure OpenAI provider.
+type AzureOptions struct {
+	// Auth selects the API key or Microsoft Entra ID, with the credentials
+	// found by the Azure SDK, to authenticate.
+	Auth string `json:"auth,omitempty" jsonschema:"description=How to authenticate: with the API key or with Microsoft Entra ID,enum=api-key,enum=entra-id,default=api-key"`
+	// APIVersion is sent as the api-version of the requests, for the ones
+	// not using the v1 API.
+	APIVersion string `json:"api_version,omitempty" jsonschema:"description=The api-version parameter of the requests. Not needed with the v1 API,example=preview"`
+	// Deployments map the names of the deployments to the IDs of the models
+	// they serve, which their metadata comes from.
+	Deployments map[string]string `json:"deployments,omitempty" jsonschema:"description=The deployments to use mapped to the IDs of the models they serve"`
+}
+
+// UsesEntraID reports whether the provider authenticates with Microsoft
+// Entra ID rather than an API key.
+func (o *AzureOptions) UsesEntraID() bool {
+	return o != nil && o.Auth == AzureAuthEntraID
+}
+
+// VertexOptions configure the Vertex AI provider, authenticated with the
+// Application Default Credentials.
+type VertexOptions struct {
+	// Project is the Google Cloud project billed for the requests.
+	Project string `json:"project,omitempty" jsonschema:"description=The Google Cloud project to use,example=my-project"`
+	// Location is the region whose endpoint the requests are sent to, or
+	// global.
+	Location string `json:"location,omitempty" jsonschema:"description=The region whose endpoint to use or global,example=us-east5,default=global"`
+}
+
 func (pc *ProviderConfig) SetupClaudeCode() {
 	pc.APIKey = fmt.Sprintf("Bearer %s", pc.OAuthToken.AccessToken)
 	pc.SystemPromptPrefix = "You are Claude Code, Anthropic's official CLI for Claude."
@@ -165,9 +311,22 @@ type MCPConfig struct {
 	URL      string            `json:"url,omitempty" jsonschema:"description=URL for HTTP or SSE MCP servers,format=uri,example=http://localhost:3000/mcp"`
 	Disabled bool              `json:"disabled,omitempty" jsonschema:"description=Whether this MCP server is disabled,default=false"`
 	Timeout  int               `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds for MCP server connections,default=15,example=30,example=60,example=120"`
+	// ToolTimeout bounds each tool call, which can take longer than
+	// connecting.
+	ToolTimeout int `json:"tool_timeout,omitempty" jsonschema:"description=Timeout in seconds for MCP tool calls. No limit by default,example=60,example=300"`
 
 	// TODO: maybe make it possible to get the value from the env
 	Headers map[string]string `json:"headers,omitempty" jsonschema:"description=HTTP headers for HTTP/SSE MCP servers"`
+
+	OAuth *MCPOAuthConfig `json:"oauth,omitempty" jsonschema:"description=OAuth client settings for HTTP/SSE MCP servers requiring authorization"`
+}
+
+// MCPOAuthConfig configures how Crush logs in to a remote MCP server. Crush
+// registers itself with the authorization server when no client ID is set.
+type MCPOAuthConfig struct {
+	ClientID     string   `json:"client_id,omitempty" jsonschema:"description=OAuth client ID registered with the authorization server"`
+	ClientSecret string   `json:"client_secret,omitempty" jsonschema:"description=OAuth client secret, for confidential clients"`
+	Scopes       []string `json:"scopes,omitempty" jsonschema:"description=Scopes to request, defaulting to the ones the server supports"`
 }
 
 type LSPConfig struct {
@@ -184,10 +343,73 @@ type LSPConfig struct {
 type TUIOptions struct {
 	CompactMode bool   `json:"compact_mode,omitempty" jsonschema:"description=Enable compact mode for the TUI interface,default=false"`
 	DiffMode    string `json:"diff_mode,omitempty" jsonschema:"description=Diff mode for the TUI interface,enum=unified,enum=split"`
+	// Accessible renders dialogs as plain text, without animations, for
+	// screen readers and dumb terminals.
+	Accessible bool `json:"accessible,omitempty" jsonschema:"description=Render dialogs as plain text without spinners or borders for screen readers,default=false"`
+	// NativeSelection leaves the mouse to the terminal, for its own text
+	// selection, instead of scrolling and selecting messages with it.
+	NativeSelection bool `json:"native_selection,omitempty" jsonschema:"description=Leave the mouse to the terminal for its native text selection instead of using it to scroll and select messages,default=false"`
+	// VimMode enables the vim key bindings in the prompt editor.
+	VimMode bool `json:"vim_mode,omitempty" jsonschema:"description=Edit prompts with vim key bindings in normal and insert modes,default=false"`
+	// Theme is the name of the theme of the TUI.
+	Theme string `json:"theme,omitempty" jsonschema:"description=Name of the TUI theme: charmtone or one defined in the themes directory of the config,default=charmtone"`
+	// ToolVerbosity is how much of the tool calls the transcript shows.
+	ToolVerbosity string `json:"tool_verbosity,omitempty" jsonschema:"description=How much of the tool calls the transcript shows: quiet collapses them all to one line and normal the finished ones while debug expands them with their input,enum=quiet,enum=normal,enum=debug,default=normal"`
+	// HideThinking leaves the thinking of reasoning models out of the
+	// transcript.
+	HideThinking bool `json:"hide_thinking,omitempty" jsonschema:"description=Leave the thinking of reasoning models out of the transcript,default=false"`
+	// Keys replaces the keys triggering actions of the TUI, by action name.
+	Keys map[string][]string `json:"keys,omitempty" jsonschema:"description=Keys triggering the actions of the TUI by action name"`
 	// Here we can add themes later or any TUI related options
 	//
 
 	Completions Completions `json:"completions,omitzero" jsonschema:"description=Completions UI options"`
+	// Notifications configures the notifications sent while the terminal
+	// isn't focused.
+	Notifications Notifications `json:"notifications,omitzero" jsonschema:"description=Notifications sent while the terminal is not focused"`
+}
+
+// Tool verbosity levels, telling how much of the tool calls the transcript
+// shows.
+const (
+	ToolVerbosityQuiet  = "quiet"
+	ToolVerbosityNormal = "normal"
+	ToolVerbosityDebug  = "debug"
+)
+
+// Notification kinds, telling how an event is notified.
+const (
+	NotificationDesktop = "desktop"
+	NotificationBell    = "bell"
+	NotificationOff     = "off"
+)
+
+// Notifications defines how the TUI notifies events while the terminal isn't
+// focused.
+type Notifications struct {
+	// Finished notifies the agent finishing a run.
+	Finished string `json:"finished,omitempty" jsonschema:"description=How to notify the agent finishing a run,enum=desktop,enum=bell,enum=off,default=desktop"`
+	// Permission notifies the agent asking for permission.
+	Permission string `json:"permission,omitempty" jsonschema:"description=How to notify the agent asking for permission,enum=desktop,enum=bell,enum=off,default=desktop"`
+	// MinDuration is the number of seconds a run must last to be notified
+	// when it finishes.
+	MinDuration *int `json:"min_duration,omitempty" jsonschema:"description=Minimum duration in seconds of a run to notify it finishing,default=10,example=30"`
+}
+
+// FinishedKind returns how to notify the agent finishing a run.
+func (n Notifications) FinishedKind() string {
+	return cmp.Or(n.Finished, NotificationDesktop)
+}
+
+// PermissionKind returns how to notify the agent asking for permission.
+func (n Notifications) PermissionKind() string {
+	return cmp.Or(n.Permission, NotificationDesktop)
+}
+
+// MinRunDuration returns how long a run must last to be notified when it
+// finishes.
+func (n Notifications) MinRunDuration() time.Duration {
+	return time.Duration(max(0, ptrValOr(n.MinDuration, 10))) * time.Second
 }
 
 // Completions defines options for the completions UI.
@@ -229,17 +451,36 @@ func (Attribution) JSONSchemaExtend(schema *jsonschema.Schema) {
 }
 
 type Options struct {
-	ContextPaths              []string     `json:"context_paths,omitempty" jsonschema:"description=Paths to files containing context information for the AI,example=.cursorrules,example=CRUSH.md"`
-	TUI                       *TUIOptions  `json:"tui,omitempty" jsonschema:"description=Terminal user interface options"`
-	Debug                     bool         `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
-	DebugLSP                  bool         `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
-	DisableAutoSummarize      bool         `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
-	DataDirectory             string       `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
-	DisabledTools             []string     `json:"disabled_tools" jsonschema:"description=Tools to disable"`
-	DisableProviderAutoUpdate bool         `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
-	Attribution               *Attribution `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
-	DisableMetrics            bool         `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
-	InitializeAs              string       `json:"initialize_as,omitempty" jsonschema:"description=Name of the context file to create/update during project initialization,default=AGENTS.md,example=AGENTS.md,example=CRUSH.md,example=CLAUDE.md,example=docs/LLMs.md"`
+	ContextPaths              []string        `json:"context_paths,omitempty" jsonschema:"description=Paths to files containing context information for the AI,example=.cursorrules,example=CRUSH.md"`
+	ContextFileMaxTokens      int             `json:"context_file_max_tokens,omitempty" jsonschema:"description=Estimated number of tokens each context file is truncated to (-1 includes them whole),default=8000,example=2000"`
+	RepoMapTokens             int             `json:"repo_map_tokens,omitempty" jsonschema:"description=Approximate number of tokens of the map of the repository added to the system prompt (0 leaves it out),example=1024"`
+	TUI                       *TUIOptions     `json:"tui,omitempty" jsonschema:"description=Terminal user interface options"`
+	Debug                     bool            `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
+	DebugLSP                  bool            `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
+	DisableAutoSummarize      bool            `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
+	AutoCompactThreshold      float64         `json:"auto_compact_threshold,omitempty" jsonschema:"description=Fraction of the context window at which the conversation is compacted automatically (defaults to leaving 20% of the window free),minimum=0,maximum=1,example=0.8"`
+	CompactKeepTurns          int             `json:"compact_keep_turns,omitempty" jsonschema:"description=Number of recent turns kept verbatim when the conversation is compacted automatically (-1 summarizes everything),default=2,example=-1"`
+	DataDirectory             string          `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
+	DisabledTools             []string        `json:"disabled_tools" jsonschema:"description=Tools to disable"`
+	DisableProviderAutoUpdate bool            `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
+	Attribution               *Attribution    `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
+	DisableMetrics            bool            `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
+	DisableCheckpoints        bool            `json:"disable_checkpoints,omitempty" jsonschema:"description=Disable the snapshots of the working directory taken before each prompt to revert agent changes,default=false"`
+	AutoCommit                bool            `json:"auto_commit,omitempty" jsonschema:"description=Offer to commit the changes of each completed prompt with a message written by the small model,default=false"`
+	InitializeAs              string          `json:"initialize_as,omitempty" jsonschema:"description=Name of the context file to create/update during project initialization,default=AGENTS.md,example=AGENTS.md,example=CRUSH.md,example=CLAUDE.md,example=docs/LLMs.md"`
+	PreviewModels             bool            `json:"preview_models,omitempty" jsonschema:"description=Include preview and beta models in the model list,default=false"`
+	DeviceFlowNoExpiry        bool            `json:"device_flow_no_expiry,omitempty" jsonschema:"description=Keep waiting for device login approval by requesting a new code whenever the current one expires,default=false"`
+	BranchOnEdit              bool            `json:"branch_on_edit,omitempty" jsonschema:"description=Fork the session when editing a previous message instead of deleting the messages after it,default=false"`
+	MaxSessionCost            float64         `json:"max_session_cost,omitempty" jsonschema:"description=Cost in US dollars of a session after which new prompts need confirming (0 for no limit),minimum=0,example=5"`
+	MaxMonthlyCost            float64         `json:"max_monthly_cost,omitempty" jsonschema:"description=Cost in US dollars of all the sessions of a calendar month after which new prompts need confirming (0 for no limit),minimum=0,example=100"`
+	Tracing                   *Tracing        `json:"tracing,omitempty" jsonschema:"description=Export of traces of the agent runs with OpenTelemetry"`
+	FallbackModels            []SelectedModel `json:"fallback_models,omitempty" jsonschema:"description=Models to switch to in order when the provider of the large model rejects the credentials or runs out of quota or is unavailable"`
+}
+
+// Tracing configures the export of traces to an OpenTelemetry collector.
+type Tracing struct {
+	Endpoint string            `json:"endpoint,omitempty" jsonschema:"description=URL of the OTLP collector receiving the traces over HTTP (the OTEL_EXPORTER_OTLP_* variables apply when empty),example=http://localhost:4318"`
+	Headers  map[string]string `json:"headers,omitempty" jsonschema:"description=HTTP headers sent to the collector (supports variables)"`
 }
 
 type MCPs map[string]MCPConfig
@@ -292,17 +533,35 @@ func (m MCPConfig) ResolvedEnv() []string {
 	return resolveEnvs(m.Env)
 }
 
+// ResolvedHeaders returns the headers with their variables resolved, leaving
+// the configured ones untouched so secrets aren't written back to the config.
 func (m MCPConfig) ResolvedHeaders() map[string]string {
 	resolver := NewShellVariableResolver(env.New())
+	headers := make(map[string]string, len(m.Headers))
 	for e, v := range m.Headers {
-		var err error
-		m.Headers[e], err = resolver.ResolveValue(v)
+		resolved, err := resolver.ResolveValue(v)
 		if err != nil {
 			slog.Error("error resolving header variable", "error", err, "variable", e, "value", v)
-			continue
+			resolved = v
 		}
+		headers[e] = resolved
 	}
-	return m.Headers
+	return headers
+}
+
+// ResolvedHeaders returns the headers with their variables resolved, leaving
+// the ones that don't resolve as they are.
+func (t *Tracing) ResolvedHeaders(resolver VariableResolver) map[string]string {
+	headers := make(map[string]string, len(t.Headers))
+	for k, v := range t.Headers {
+		resolved, err := resolver.ResolveValue(v)
+		if err != nil {
+			slog.Error("error resolving header variable", "error", err, "variable", k, "value", v)
+			resolved = v
+		}
+		headers[k] = resolved
+	}
+	return headers
 }
 
 type Agent struct {
@@ -326,10 +585,35 @@ type Agent struct {
 
 	// Overrides the context paths for this agent
 	ContextPaths []string `json:"context_paths,omitempty"`
+
+	// Instructions replace the built-in system prompt, for user-defined
+	// subagents.
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// Subagent is a user-defined agent the main agent can delegate tasks to.
+type Subagent struct {
+	Description string `json:"description" jsonschema:"required,description=What the subagent does and when the main agent should delegate to it,example=Reviews diffs for bugs and style issues"`
+	Prompt      string `json:"prompt" jsonschema:"required,description=System prompt of the subagent,example=You are a meticulous code reviewer."`
+
+	Model SelectedModelType `json:"model,omitempty" jsonschema:"description=The model type the subagent runs with,enum=large,enum=small,default=large"`
+
+	// The available tools for the subagent
+	//  if this is nil, only the read-only tools are available
+	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=Tools the subagent can use. Defaults to the read-only tools,example=view,example=grep,example=bash"`
+
+	// The MCPs available to the subagent, in the same format as the agents
+	//  if this is nil, no MCPs are available
+	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty" jsonschema:"description=MCP servers the subagent can use, mapped to their allowed tools or an empty list for all of them"`
+
+	Disabled bool `json:"disabled,omitempty" jsonschema:"description=Whether this subagent is disabled,default=false"`
 }
 
 type Tools struct {
-	Ls ToolLs `json:"ls,omitzero"`
+	Ls        ToolLs        `json:"ls,omitzero"`
+	Bash      ToolBash      `json:"bash,omitzero"`
+	Fetch     ToolFetch     `json:"fetch,omitzero"`
+	WebSearch ToolWebSearch `json:"web_search,omitzero"`
 }
 
 type ToolLs struct {
@@ -341,6 +625,109 @@ func (t ToolLs) Limits() (depth, items int) {
 	return ptrValOr(t.MaxDepth, 0), ptrValOr(t.MaxItems, 0)
 }
 
+// BashTruncation is the part of a long command output kept for the model.
+type BashTruncation string
+
+const (
+	BashTruncationHeadTail BashTruncation = "head_tail"
+	BashTruncationHead     BashTruncation = "head"
+	BashTruncationTail     BashTruncation = "tail"
+)
+
+type ToolBash struct {
+	Timeout        *int           `json:"timeout,omitempty" jsonschema:"description=Seconds after which a command is stopped unless it was started in the background; 0 means no limit,default=0,example=300"`
+	MaxOutputBytes *int           `json:"max_output_bytes,omitempty" jsonschema:"description=Maximum number of bytes of stdout and stderr each returned to the model,default=30000,example=60000"`
+	Truncation     BashTruncation `json:"truncation,omitempty" jsonschema:"description=Part of the output kept when it exceeds max_output_bytes,enum=head_tail,enum=head,enum=tail,default=head_tail"`
+}
+
+// Limits returns the timeout of commands, zero when there is none, the
+// maximum number of bytes of output, and how output is truncated.
+func (t ToolBash) Limits() (timeout time.Duration, maxOutputBytes int, truncation BashTruncation) {
+	timeout = time.Duration(max(0, ptrValOr(t.Timeout, 0))) * time.Second
+	maxOutputBytes = ptrValOr(t.MaxOutputBytes, 0)
+	if maxOutputBytes <= 0 {
+		maxOutputBytes = defaultBashMaxOutputBytes
+	}
+	switch t.Truncation {
+	case BashTruncationHead, BashTruncationTail:
+		truncation = t.Truncation
+	default:
+		truncation = BashTruncationHeadTail
+	}
+	return timeout, maxOutputBytes, truncation
+}
+
+type ToolFetch struct {
+	MaxTokens      *int     `json:"max_tokens,omitempty" jsonschema:"description=Approximate number of tokens of a fetched page returned to the model,default=25000,example=10000"`
+	AllowedDomains []string `json:"allowed_domains,omitempty" jsonschema:"description=Domains the fetch and download tools may access including their subdomains; all domains are allowed when empty,example=go.dev,example=github.com"`
+	BlockedDomains []string `json:"blocked_domains,omitempty" jsonschema:"description=Domains the fetch and download tools may never access including their subdomains,example=example.com"`
+}
+
+// MaxTokensOrDefault returns the approximate number of tokens of a fetched
+// page returned to the model.
+func (t ToolFetch) MaxTokensOrDefault() int {
+	if maxTokens := ptrValOr(t.MaxTokens, 0); maxTokens > 0 {
+		return maxTokens
+	}
+	return defaultFetchMaxTokens
+}
+
+// AllowsHost reports whether the fetch tools may access host. Blocked domains
+// win over allowed ones.
+func (t ToolFetch) AllowsHost(host string) bool {
+	if slices.ContainsFunc(t.BlockedDomains, func(domain string) bool { return matchesDomain(host, domain) }) {
+		return false
+	}
+	return len(t.AllowedDomains) == 0 ||
+		slices.ContainsFunc(t.AllowedDomains, func(domain string) bool { return matchesDomain(host, domain) })
+}
+
+// matchesDomain reports whether host is domain or one of its subdomains.
+func matchesDomain(host, domain string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	domain = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), "."), "*.")
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
+
+// SearchProvider is the backend the web_search tool queries.
+type SearchProvider string
+
+const (
+	SearchProviderDuckDuckGo SearchProvider = "duckduckgo"
+	SearchProviderBrave      SearchProvider = "brave"
+	SearchProviderTavily     SearchProvider = "tavily"
+	SearchProviderExa        SearchProvider = "exa"
+)
+
+type ToolWebSearch struct {
+	Provider   SearchProvider `json:"provider,omitempty" jsonschema:"description=Backend queried by the web_search tool; all but duckduckgo need an API key,enum=duckduckgo,enum=brave,enum=tavily,enum=exa,default=duckduckgo"`
+	APIKey     string         `json:"api_key,omitempty" jsonschema:"description=API key of the search provider read from <PROVIDER>_API_KEY when unset,example=$BRAVE_API_KEY"`
+	MaxResults *int           `json:"max_results,omitempty" jsonschema:"description=Number of results returned by a search unless the model asks for another,default=8,example=5"`
+}
+
+// ProviderOrDefault returns the backend queried by the web_search tool.
+func (t ToolWebSearch) ProviderOrDefault() SearchProvider {
+	return cmp.Or(t.Provider, SearchProviderDuckDuckGo)
+}
+
+// APIKeyOrDefault returns the API key of the search provider, unresolved,
+// defaulting to the <PROVIDER>_API_KEY environment variable.
+func (t ToolWebSearch) APIKeyOrDefault() string {
+	if t.APIKey != "" {
+		return t.APIKey
+	}
+	return "$" + strings.ToUpper(string(t.ProviderOrDefault())) + "_API_KEY"
+}
+
+// MaxResultsOrDefault returns the number of results returned by a search
+// unless the model asks for another.
+func (t ToolWebSearch) MaxResultsOrDefault() int {
+	if maxResults := ptrValOr(t.MaxResults, 0); maxResults > 0 {
+		return maxResults
+	}
+	return defaultWebSearchResults
+}
+
 // Config holds the configuration for crush.
 type Config struct {
 	Schema string `json:"$schema,omitempty"`
@@ -354,6 +741,11 @@ type Config struct {
 	Providers *csync.Map[string, ProviderConfig] `json:"providers,omitempty" jsonschema:"description=AI provider configurations"`
 
 	MCP MCPs `json:"mcp,omitempty" jsonschema:"description=Model Context Protocol server configurations"`
+	// OAuth tokens of remote MCP servers stored in the data directory config.
+	MCPTokens map[string]*oauth.Token `json:"mcp_tokens,omitempty" jsonschema:"description=OAuth tokens of remote MCP servers, stored by crush auth mcp"`
+	// OAuth tokens of the providers and their accounts stored in the data
+	// directory config, keyed by provider or by provider/account.
+	Tokens map[string]*oauth.Token `json:"tokens,omitempty" jsonschema:"description=OAuth tokens of the providers and their accounts, keyed by provider or by provider/account"`
 
 	LSP LSPs `json:"lsp,omitempty" jsonschema:"description=Language Server Protocol configurations"`
 
@@ -363,6 +755,8 @@ type Config struct {
 
 	Tools Tools `json:"tools,omitzero" jsonschema:"description=Tool configurations"`
 
+	Subagents map[string]Subagent `json:"subagents,omitempty" jsonschema:"description=Named subagents the main agent can delegate tasks to"`
+
 	Agents map[string]Agent `json:"-"`
 
 	// Internal
@@ -377,6 +771,21 @@ func (c *Config) WorkingDir() string {
 	return c.workingDir
 }
 
+// SetWorkingDir moves the project to dir, like a worktree of it.
+func (c *Config) SetWorkingDir(dir string) {
+	c.workingDir = dir
+}
+
+// PromptFragments returns the files appended to the system prompt of the
+// coder agent, the global one first and then the one of the project.
+func (c *Config) PromptFragments() []string {
+	fragments := []string{filepath.Join(filepath.Dir(GlobalConfig()), promptFragmentFile)}
+	if c.Options != nil && c.Options.DataDirectory != "" {
+		fragments = append(fragments, filepath.Join(c.Options.DataDirectory, promptFragmentFile))
+	}
+	return fragments
+}
+
 func (c *Config) EnabledProviders() []ProviderConfig {
 	var enabled []ProviderConfig
 	for p := range c.Providers.Seq() {
@@ -403,8 +812,29 @@ func (c *Config) GetModel(provider, model string) *catwalk.Model {
 	return nil
 }
 
-func (c *Config) GetProviderForModel(modelType SelectedModelType) *ProviderConfig {
+// selectionMu guards the settings of the configs that change while running,
+// from the TUI, while the agent reads them: the selected models and the MCP
+// servers.
+var selectionMu sync.RWMutex
+
+// GetSelectedModel returns the model selected as modelType.
+func (c *Config) GetSelectedModel(modelType SelectedModelType) (SelectedModel, bool) {
+	selectionMu.RLock()
+	defer selectionMu.RUnlock()
 	model, ok := c.Models[modelType]
+	return model, ok
+}
+
+// GetMCP returns the config of the MCP server name.
+func (c *Config) GetMCP(name string) (MCPConfig, bool) {
+	selectionMu.RLock()
+	defer selectionMu.RUnlock()
+	m, ok := c.MCP[name]
+	return m, ok
+}
+
+func (c *Config) GetProviderForModel(modelType SelectedModelType) *ProviderConfig {
+	model, ok := c.GetSelectedModel(modelType)
 	if !ok {
 		return nil
 	}
@@ -415,7 +845,7 @@ func (c *Config) GetProviderForModel(modelType SelectedModelType) *ProviderConfi
 }
 
 func (c *Config) GetModelByType(modelType SelectedModelType) *catwalk.Model {
-	model, ok := c.Models[modelType]
+	model, ok := c.GetSelectedModel(modelType)
 	if !ok {
 		return nil
 	}
@@ -423,7 +853,7 @@ func (c *Config) GetModelByType(modelType SelectedModelType) *catwalk.Model {
 }
 
 func (c *Config) LargeModel() *catwalk.Model {
-	model, ok := c.Models[SelectedModelTypeLarge]
+	model, ok := c.GetSelectedModel(SelectedModelTypeLarge)
 	if !ok {
 		return nil
 	}
@@ -431,7 +861,7 @@ func (c *Config) LargeModel() *catwalk.Model {
 }
 
 func (c *Config) SmallModel() *catwalk.Model {
-	model, ok := c.Models[SelectedModelTypeSmall]
+	model, ok := c.GetSelectedModel(SelectedModelTypeSmall)
 	if !ok {
 		return nil
 	}
@@ -446,6 +876,46 @@ func (c *Config) SetCompactMode(enabled bool) error {
 	return c.SetConfigField("options.tui.compact_mode", enabled)
 }
 
+// SetNativeSelection toggles leaving the mouse to the terminal and saves it.
+func (c *Config) SetNativeSelection(enabled bool) error {
+	if c.Options == nil {
+		c.Options = &Options{}
+	}
+	c.Options.TUI.NativeSelection = enabled
+	return c.SetConfigField("options.tui.native_selection", enabled)
+}
+
+// SetTheme sets the theme of the TUI and saves it.
+func (c *Config) SetTheme(name string) error {
+	if c.Options == nil {
+		c.Options = &Options{}
+	}
+	c.Options.TUI.Theme = name
+	return c.SetConfigField("options.tui.theme", name)
+}
+
+func (c *Config) previewModelsEnabled() bool {
+	return c.Options != nil && c.Options.PreviewModels
+}
+
+// SetPreviewModels toggles the visibility of preview models and refreshes
+// the model list of providers that fetch their models dynamically.
+func (c *Config) SetPreviewModels(ctx context.Context, enabled bool) error {
+	if c.Options == nil {
+		c.Options = &Options{}
+	}
+	c.Options.PreviewModels = enabled
+	if err := c.SetConfigField("options.preview_models", enabled); err != nil {
+		return err
+	}
+
+	if providerConfig, ok := c.Providers.Get(copilot.ProviderID); ok {
+		providerConfig.setCopilotModels(ctx, enabled)
+		c.Providers.Set(copilot.ProviderID, providerConfig)
+	}
+	return nil
+}
+
 func (c *Config) Resolve(key string) (string, error) {
 	if c.resolver == nil {
 		return "", fmt.Errorf("no variable resolver configured")
@@ -454,7 +924,9 @@ func (c *Config) Resolve(key string) (string, error) {
 }
 
 func (c *Config) UpdatePreferredModel(modelType SelectedModelType, model SelectedModel) error {
+	selectionMu.Lock()
 	c.Models[modelType] = model
+	selectionMu.Unlock()
 	if err := c.SetConfigField(fmt.Sprintf("models.%s", modelType), model); err != nil {
 		return fmt.Errorf("failed to update preferred model: %w", err)
 	}
@@ -485,7 +957,69 @@ func (c *Config) SetConfigField(key string, value any) error {
 	return nil
 }
 
-func (c *Config) RefreshOAuthToken(ctx context.Context, providerID string) error {
+// ProviderAccessToken returns the credential crush currently uses to talk to
+// the given provider: the short-lived Copilot API token, the OAuth access
+// token, or the resolved API key. Expired tokens are refreshed and persisted
+// first.
+func (c *Config) ProviderAccessToken(ctx context.Context, providerID string) (string, error) {
+	providerConfig, exists := c.Providers.Get(providerID)
+	if !exists {
+		return "", fmt.Errorf("provider %s not found", providerID)
+	}
+
+	token := providerConfig.OAuthToken
+	switch {
+	case providerID == copilot.ProviderID:
+		if token == nil || token.RefreshToken == "" {
+			return "", fmt.Errorf("provider %s is not logged in", providerID)
+		}
+		if !token.IsCopilotTokenExpired() {
+			return token.CopilotToken, nil
+		}
+		copilotToken, err := copilot.ExchangeForCopilotToken(ctx, token.RefreshToken)
+		if err != nil {
+			return "", err
+		}
+		token.CopilotToken = copilotToken.Token
+		token.CopilotExpiresAt = copilotToken.ExpiresAt
+		c.Providers.Set(providerID, providerConfig)
+		if err := c.SetProviderToken(oauth.TokenKey{ProviderID: providerID}, token); err != nil {
+			return "", fmt.Errorf("failed to persist copilot token: %w", err)
+		}
+		oauth.PublishTokenRefreshed(providerID, token)
+		return copilotToken.Token, nil
+	case token != nil:
+		if token.IsExpired() {
+			if err := c.RefreshOAuthToken(ctx, providerID); err != nil {
+				return "", err
+			}
+			providerConfig, _ = c.Providers.Get(providerID)
+		}
+		return providerConfig.OAuthToken.AccessToken, nil
+	}
+
+	apiKey, err := c.Resolve(providerConfig.APIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve api key for provider %s: %w", providerID, err)
+	}
+	if apiKey == "" {
+		return "", fmt.Errorf("provider %s has no credentials", providerID)
+	}
+	return apiKey, nil
+}
+
+// SetMCPToken persists the OAuth token of the MCP server name.
+func (c *Config) SetMCPToken(name string, token *oauth.Token) error {
+	if err := c.SetConfigField("mcp_tokens."+escapePathKey(name), token); err != nil {
+		return fmt.Errorf("failed to save mcp token: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) RefreshOAuthToken(ctx context.Context, providerID string) (err error) {
+	ctx, span := tracing.StartTokenRefresh(ctx, providerID)
+	defer func() { tracing.End(span, err) }()
+
 	providerConfig, exists := c.Providers.Get(providerID)
 	if !exists {
 		return fmt.Errorf("provider %s not found", providerID)
@@ -501,28 +1035,47 @@ func (c *Config) RefreshOAuthToken(ctx context.Context, providerID string) error
 	}
 
 	newToken, err := claude.RefreshToken(ctx, providerConfig.OAuthToken.RefreshToken)
+	if errors.Is(err, oauth.ErrTokenInvalid) {
+		oauth.PublishTokenInvalidated(providerID, err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to refresh OAuth token for provider %s: %w", providerID, err)
 	}
 
 	slog.Info("Successfully refreshed OAuth token in background", "provider", providerID)
+	newToken.InheritMetadata(providerConfig.OAuthToken)
 	providerConfig.OAuthToken = newToken
 	providerConfig.APIKey = fmt.Sprintf("Bearer %s", newToken.AccessToken)
 	providerConfig.SetupClaudeCode()
+	accountsChanged := providerConfig.syncActiveAccount()
 
 	c.Providers.Set(providerID, providerConfig)
 
 	if err := cmp.Or(
 		c.SetConfigField(fmt.Sprintf("providers.%s.api_key", providerID), newToken.AccessToken),
-		c.SetConfigField(fmt.Sprintf("providers.%s.oauth", providerID), newToken),
+		c.SetProviderToken(oauth.TokenKey{ProviderID: providerID}, newToken),
 	); err != nil {
 		return fmt.Errorf("failed to persist refreshed token: %w", err)
 	}
+	if accountsChanged {
+		if err := c.saveAccounts(providerID, providerConfig.Accounts); err != nil {
+			return fmt.Errorf("failed to persist refreshed account: %w", err)
+		}
+	}
 
+	oauth.PublishTokenRefreshed(providerID, newToken)
 	return nil
 }
 
-func (c *Config) SetProviderAPIKey(providerID string, apiKey any) error {
+func (c *Config) SetProviderAPIKey(providerID string, apiKey any) (err error) {
+	if token, ok := apiKey.(*oauth.Token); ok {
+		defer func() {
+			if err == nil {
+				oauth.PublishTokenSaved(providerID, token)
+			}
+		}()
+	}
+
 	var providerConfig ProviderConfig
 	var exists bool
 	var setKeyOrToken func()
@@ -536,7 +1089,7 @@ func (c *Config) SetProviderAPIKey(providerID string, apiKey any) error {
 	case *oauth.Token:
 		// For Copilot, the GitHub OAuth token is stored in RefreshToken.
 		if providerID == copilot.ProviderID {
-			if err := c.SetConfigField(fmt.Sprintf("providers.%s.oauth", providerID), v); err != nil {
+			if err := c.SetProviderToken(oauth.TokenKey{ProviderID: providerID}, v); err != nil {
 				return err
 			}
 			setKeyOrToken = func() {
@@ -546,7 +1099,7 @@ func (c *Config) SetProviderAPIKey(providerID string, apiKey any) error {
 		} else {
 			if err := cmp.Or(
 				c.SetConfigField(fmt.Sprintf("providers.%s.api_key", providerID), v.AccessToken),
-				c.SetConfigField(fmt.Sprintf("providers.%s.oauth", providerID), v),
+				c.SetProviderToken(oauth.TokenKey{ProviderID: providerID}, v),
 			); err != nil {
 				return err
 			}
@@ -575,8 +1128,8 @@ func (c *Config) SetProviderAPIKey(providerID string, apiKey any) error {
 			Disable:      false,
 			ExtraHeaders: make(map[string]string),
 			ExtraParams:  make(map[string]string),
-			Models:       copilot.GetModels(context.Background()),
 		}
+		providerConfig.setCopilotModels(context.Background(), c.previewModelsEnabled())
 		setKeyOrToken()
 		c.Providers.Set(providerID, providerConfig)
 		return nil
@@ -657,20 +1210,26 @@ func (c *Config) recordRecentModel(modelType SelectedModelType, model SelectedMo
 func allToolNames() []string {
 	return []string{
 		"agent",
+		"agent_output",
 		"bash",
 		"job_output",
 		"job_kill",
 		"download",
 		"edit",
 		"multiedit",
+		"apply_patch",
 		"lsp_diagnostics",
 		"lsp_references",
+		"lsp_definition",
+		"lsp_symbols",
 		"fetch",
 		"agentic_fetch",
 		"glob",
 		"grep",
+		"semantic_search",
 		"ls",
 		"sourcegraph",
+		"web_search",
 		"view",
 		"write",
 	}
@@ -685,7 +1244,7 @@ func resolveAllowedTools(allTools []string, disabledTools []string) []string {
 }
 
 func resolveReadOnlyTools(tools []string) []string {
-	readOnlyTools := []string{"glob", "grep", "ls", "sourcegraph", "view"}
+	readOnlyTools := []string{"glob", "grep", "semantic_search", "ls", "sourcegraph", "web_search", "view"}
 	// filter to only include tools that are in allowedtools (include mode)
 	return filterSlice(tools, readOnlyTools, true)
 }
@@ -726,9 +1285,61 @@ func (c *Config) SetupAgents() {
 			AllowedMCP: map[string][]string{},
 		},
 	}
+	for name, subagent := range c.Subagents {
+		if subagent.Disabled {
+			continue
+		}
+		if _, ok := agents[name]; ok {
+			slog.Warn("Ignoring subagent with a reserved name", "name", name)
+			continue
+		}
+		if strings.TrimSpace(subagent.Prompt) == "" {
+			slog.Warn("Ignoring subagent without a prompt", "name", name)
+			continue
+		}
+		agents[name] = subagentConfig(name, subagent, allowedTools)
+	}
 	c.Agents = agents
 }
 
+// subagentConfig returns the agent of a user-defined subagent, restricted to
+// the allowed tools.
+func subagentConfig(name string, subagent Subagent, allowedTools []string) Agent {
+	tools := resolveReadOnlyTools(allowedTools)
+	if subagent.AllowedTools != nil {
+		tools = filterSlice(allowedTools, subagent.AllowedTools, true)
+	}
+	// Subagents can't delegate further.
+	tools = filterSlice(tools, []string{"agent", "agent_output"}, false)
+
+	allowedMCP := subagent.AllowedMCP
+	if allowedMCP == nil {
+		allowedMCP = map[string][]string{}
+	}
+	return Agent{
+		ID:           name,
+		Name:         name,
+		Description:  subagent.Description,
+		Model:        cmp.Or(subagent.Model, SelectedModelTypeLarge),
+		AllowedTools: tools,
+		AllowedMCP:   allowedMCP,
+		Instructions: subagent.Prompt,
+	}
+}
+
+// SubagentNames returns the names of the enabled user-defined subagents,
+// sorted.
+func (c *Config) SubagentNames() []string {
+	var names []string
+	for name, agent := range c.Agents {
+		if agent.Instructions != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
 func (c *Config) Resolver() VariableResolver {
 	return c.resolver
 }
@@ -748,7 +1359,9 @@ func (c *ProviderConfig) TestConnection(resolver VariableResolver) error {
 		} else {
 			testURL = baseURL + "/models"
 		}
-		headers["Authorization"] = "Bearer " + apiKey
+		if c.AuthHeader == "" {
+			headers["Authorization"] = "Bearer " + apiKey
+		}
 	case catwalk.TypeAnthropic:
 		baseURL, _ := resolver.ResolveValue(c.BaseURL)
 		if baseURL == "" {
@@ -778,7 +1391,7 @@ func (c *ProviderConfig) TestConnection(resolver VariableResolver) error {
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	for k, v := range c.ExtraHeaders {
+	for k, v := range c.ResolvedHeaders(resolver) {
 		req.Header.Set(k, v)
 	}
 	b, err := client.Do(req)
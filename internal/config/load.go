@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -25,7 +26,6 @@ import (
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/claude"
-	"github.com/charmbracelet/crush/internal/oauth/copilot"
 	powernapConfig "github.com/charmbracelet/x/powernap/pkg/config"
 )
 
@@ -48,6 +48,11 @@ func LoadReader(fd io.Reader) (*Config, error) {
 
 // Load loads the configuration from the default paths.
 func Load(workingDir, dataDir string, debug bool) (*Config, error) {
+	// Tokens are only ever written to the data config.
+	if err := migrateTokenFile(GlobalConfigData()); err != nil {
+		slog.Warn("Failed to migrate stored OAuth tokens", "error", err)
+	}
+
 	configPaths := lookupConfigs(workingDir)
 
 	cfg, err := loadFromConfigPaths(configPaths)
@@ -69,6 +74,8 @@ func Load(workingDir, dataDir string, debug bool) (*Config, error) {
 		cfg.Options.Debug,
 	)
 
+	cfg.applyTokens()
+
 	if !isInsideWorktree() {
 		const depth = 2
 		const items = 100
@@ -87,12 +94,23 @@ func Load(workingDir, dataDir string, debug bool) (*Config, error) {
 	cfg.knownProviders = providers
 
 	env := env.New()
+	if err := checkRequiredVariables(cfg, env); err != nil {
+		return nil, err
+	}
+
 	// Configure providers
 	valueResolver := NewShellVariableResolver(env)
 	cfg.resolver = valueResolver
+	local := cfg.takeLocalServers()
 	if err := cfg.configureProviders(env, valueResolver, cfg.knownProviders); err != nil {
 		return nil, fmt.Errorf("failed to configure providers: %w", err)
 	}
+	// Local servers are looked for in the background, unless the selected
+	// models need them, or there would be no provider to start with.
+	waitLocalServers := cfg.discoverLocalServers(local, env, valueResolver)
+	if !cfg.IsConfigured() || cfg.selectsLocalServer(local) {
+		waitLocalServers()
+	}
 
 	if !cfg.IsConfigured() {
 		slog.Warn("No providers configured")
@@ -192,11 +210,15 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			BaseURL:            p.APIEndpoint,
 			APIKey:             p.APIKey,
 			OAuthToken:         config.OAuthToken,
+			Accounts:           config.Accounts,
+			ActiveAccount:      config.ActiveAccount,
 			Type:               p.Type,
 			Disable:            config.Disable,
 			SystemPromptPrefix: config.SystemPromptPrefix,
 			ExtraHeaders:       headers,
 			ExtraBody:          config.ExtraBody,
+			Azure:              config.Azure,
+			Vertex:             config.Vertex,
 			ExtraParams:        make(map[string]string),
 			Models:             p.Models,
 		}
@@ -206,17 +228,26 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 				newToken, err := claude.RefreshToken(context.TODO(), config.OAuthToken.RefreshToken)
 				if err == nil {
 					slog.Info("Successfully refreshed Anthropic OAuth token")
+					newToken.InheritMetadata(config.OAuthToken)
 					config.OAuthToken = newToken
 					prepared.OAuthToken = newToken
 					if err := cmp.Or(
 						c.SetConfigField("providers.anthropic.api_key", newToken.AccessToken),
-						c.SetConfigField("providers.anthropic.oauth", newToken),
+						c.SetProviderToken(oauth.TokenKey{ProviderID: string(p.ID)}, newToken),
 					); err != nil {
 						return err
 					}
+					if prepared.syncActiveAccount() {
+						if err := c.saveAccounts(string(p.ID), prepared.Accounts); err != nil {
+							return err
+						}
+					}
 				} else {
 					slog.Error("Failed to refresh Anthropic OAuth token", "error", err)
 					event.Error(err)
+					if errors.Is(err, oauth.ErrTokenInvalid) {
+						oauth.PublishTokenInvalidated(string(p.ID), err)
+					}
 				}
 			} else {
 				slog.Info("Using existing non-expired Anthropic OAuth token")
@@ -227,15 +258,17 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 		switch p.ID {
 		// Handle specific providers that require additional configuration
 		case catwalk.InferenceProviderVertexAI:
-			if !hasVertexCredentials(env) {
+			project, location := vertexProjectLocation(config.Vertex, env, resolver)
+			if project == "" {
 				if configExists {
 					slog.Warn("Skipping Vertex AI provider due to missing credentials")
 					c.Providers.Del(string(p.ID))
 				}
 				continue
 			}
-			prepared.ExtraParams["project"] = env.Get("VERTEXAI_PROJECT")
-			prepared.ExtraParams["location"] = env.Get("VERTEXAI_LOCATION")
+			prepared.ExtraParams["project"] = project
+			prepared.ExtraParams["location"] = location
+			prepared.Models = vertexModels(prepared.Models, knownProviders)
 		case catwalk.InferenceProviderAzure:
 			endpoint, err := resolver.ResolveValue(p.APIEndpoint)
 			if err != nil || endpoint == "" {
@@ -247,6 +280,12 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			}
 			prepared.BaseURL = endpoint
 			prepared.ExtraParams["apiVersion"] = env.Get("AZURE_OPENAI_API_VERSION")
+			if prepared.Azure != nil && prepared.Azure.APIVersion != "" {
+				prepared.ExtraParams["apiVersion"] = prepared.Azure.APIVersion
+			}
+			if prepared.Azure != nil && len(prepared.Azure.Deployments) > 0 {
+				prepared.Models = azureDeploymentModels(prepared.Azure.Deployments, p.Models, config.Models)
+			}
 		case catwalk.InferenceProviderBedrock:
 			if !hasAWSCredentials(env) {
 				if configExists {
@@ -259,11 +298,6 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			if prepared.ExtraParams["region"] == "" {
 				prepared.ExtraParams["region"] = env.Get("AWS_DEFAULT_REGION")
 			}
-			for _, model := range p.Models {
-				if !strings.HasPrefix(model.ID, "anthropic.") {
-					return fmt.Errorf("bedrock provider only supports anthropic models for now, found: %s", model.ID)
-				}
-			}
 		default:
 			// if the provider api or endpoint are missing we skip them
 			v, err := resolver.ResolveValue(p.APIKey)
@@ -338,6 +372,12 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			c.Providers.Del(id)
 			continue
 		}
+		// The model picker shows the models by name.
+		for i, model := range providerConfig.Models {
+			if model.Name == "" {
+				providerConfig.Models[i].Name = model.ID
+			}
+		}
 
 		c.Providers.Set(id, providerConfig)
 	}
@@ -387,10 +427,18 @@ func (c *Config) setDefaults(workingDir, dataDir string) {
 	c.Options.ContextPaths = append(defaultContextPaths, c.Options.ContextPaths...)
 	slices.Sort(c.Options.ContextPaths)
 	c.Options.ContextPaths = slices.Compact(c.Options.ContextPaths)
+	if c.Options.ContextFileMaxTokens == 0 {
+		c.Options.ContextFileMaxTokens = defaultContextFileTokens
+	}
 
 	if str, ok := os.LookupEnv("CRUSH_DISABLE_PROVIDER_AUTO_UPDATE"); ok {
 		c.Options.DisableProviderAutoUpdate, _ = strconv.ParseBool(str)
 	}
+	if str, ok := os.LookupEnv("CRUSH_ACCESSIBLE"); ok {
+		c.Options.TUI.Accessible, _ = strconv.ParseBool(str)
+	} else if os.Getenv("TERM") == "dumb" {
+		c.Options.TUI.Accessible = true
+	}
 
 	if c.Options.Attribution == nil {
 		c.Options.Attribution = &Attribution{
@@ -456,6 +504,24 @@ func (c *Config) applyLSPDefaults() {
 	}
 }
 
+// defaultModel returns the model of the provider with the given ID. Azure
+// deployments are named after something else, the first deployment serving
+// the model is used then, if any.
+func (c *Config) defaultModel(providerConfig ProviderConfig, modelID string) *catwalk.Model {
+	if model := c.GetModel(providerConfig.ID, modelID); model != nil {
+		return model
+	}
+	if !providerConfig.hasAzureDeployments() {
+		return nil
+	}
+	for _, deployment := range slices.Sorted(maps.Keys(providerConfig.Azure.Deployments)) {
+		if providerConfig.Azure.Deployments[deployment] == modelID {
+			return c.GetModel(providerConfig.ID, deployment)
+		}
+	}
+	return nil
+}
+
 func (c *Config) defaultModelSelection(knownProviders []catwalk.Provider) (largeModel SelectedModel, smallModel SelectedModel, err error) {
 	if len(knownProviders) == 0 && c.Providers.Len() == 0 {
 		err = fmt.Errorf("no providers configured, please configure at least one provider")
@@ -469,28 +535,34 @@ func (c *Config) defaultModelSelection(knownProviders []catwalk.Provider) (large
 		if !ok || providerConfig.Disable {
 			continue
 		}
-		defaultLargeModel := c.GetModel(string(p.ID), p.DefaultLargeModelID)
-		if defaultLargeModel == nil {
+		// The default models no Azure deployment serves are left unset, to
+		// be selected.
+		defaultLargeModel := c.defaultModel(providerConfig, p.DefaultLargeModelID)
+		if defaultLargeModel == nil && !providerConfig.hasAzureDeployments() {
 			err = fmt.Errorf("default large model %s not found for provider %s", p.DefaultLargeModelID, p.ID)
 			return largeModel, smallModel, err
 		}
-		largeModel = SelectedModel{
-			Provider:        string(p.ID),
-			Model:           defaultLargeModel.ID,
-			MaxTokens:       defaultLargeModel.DefaultMaxTokens,
-			ReasoningEffort: defaultLargeModel.DefaultReasoningEffort,
+		if defaultLargeModel != nil {
+			largeModel = SelectedModel{
+				Provider:        string(p.ID),
+				Model:           defaultLargeModel.ID,
+				MaxTokens:       defaultLargeModel.DefaultMaxTokens,
+				ReasoningEffort: defaultLargeModel.DefaultReasoningEffort,
+			}
 		}
 
-		defaultSmallModel := c.GetModel(string(p.ID), p.DefaultSmallModelID)
-		if defaultSmallModel == nil {
+		defaultSmallModel := c.defaultModel(providerConfig, p.DefaultSmallModelID)
+		if defaultSmallModel == nil && !providerConfig.hasAzureDeployments() {
 			err = fmt.Errorf("default small model %s not found for provider %s", p.DefaultSmallModelID, p.ID)
 			return largeModel, smallModel, err
 		}
-		smallModel = SelectedModel{
-			Provider:        string(p.ID),
-			Model:           defaultSmallModel.ID,
-			MaxTokens:       defaultSmallModel.DefaultMaxTokens,
-			ReasoningEffort: defaultSmallModel.DefaultReasoningEffort,
+		if defaultSmallModel != nil {
+			smallModel = SelectedModel{
+				Provider:        string(p.ID),
+				Model:           defaultSmallModel.ID,
+				MaxTokens:       defaultSmallModel.DefaultMaxTokens,
+				ReasoningEffort: defaultSmallModel.DefaultReasoningEffort,
+			}
 		}
 		return largeModel, smallModel, err
 	}
@@ -532,99 +604,88 @@ func (c *Config) configureSelectedModels(knownProviders []catwalk.Provider) erro
 	}
 	large, small := defaultLarge, defaultSmall
 
-	largeModelSelected, largeModelConfigured := c.Models[SelectedModelTypeLarge]
-	if largeModelConfigured {
-		if largeModelSelected.Model != "" {
-			large.Model = largeModelSelected.Model
-		}
-		if largeModelSelected.Provider != "" {
-			large.Provider = largeModelSelected.Provider
-		}
-		model := c.GetModel(large.Provider, large.Model)
-		if model == nil {
-			large = defaultLarge
+	if selected, ok := c.Models[SelectedModelTypeLarge]; ok {
+		var found bool
+		if large, found = c.configureSelectedModel(selected, defaultLarge); !found && large.Model != "" {
 			// override the model type to large
 			err := c.UpdatePreferredModel(SelectedModelTypeLarge, large)
 			if err != nil {
 				return fmt.Errorf("failed to update preferred large model: %w", err)
 			}
-		} else {
-			if largeModelSelected.MaxTokens > 0 {
-				large.MaxTokens = largeModelSelected.MaxTokens
-			} else {
-				large.MaxTokens = model.DefaultMaxTokens
-			}
-			if largeModelSelected.ReasoningEffort != "" {
-				large.ReasoningEffort = largeModelSelected.ReasoningEffort
-			}
-			large.Think = largeModelSelected.Think
-			if largeModelSelected.Temperature != nil {
-				large.Temperature = largeModelSelected.Temperature
-			}
-			if largeModelSelected.TopP != nil {
-				large.TopP = largeModelSelected.TopP
-			}
-			if largeModelSelected.TopK != nil {
-				large.TopK = largeModelSelected.TopK
-			}
-			if largeModelSelected.FrequencyPenalty != nil {
-				large.FrequencyPenalty = largeModelSelected.FrequencyPenalty
-			}
-			if largeModelSelected.PresencePenalty != nil {
-				large.PresencePenalty = largeModelSelected.PresencePenalty
-			}
 		}
 	}
-	smallModelSelected, smallModelConfigured := c.Models[SelectedModelTypeSmall]
-	if smallModelConfigured {
-		if smallModelSelected.Model != "" {
-			small.Model = smallModelSelected.Model
-		}
-		if smallModelSelected.Provider != "" {
-			small.Provider = smallModelSelected.Provider
-		}
-
-		model := c.GetModel(small.Provider, small.Model)
-		if model == nil {
-			small = defaultSmall
+	if selected, ok := c.Models[SelectedModelTypeSmall]; ok {
+		var found bool
+		if small, found = c.configureSelectedModel(selected, defaultSmall); !found && small.Model != "" {
 			// override the model type to small
 			err := c.UpdatePreferredModel(SelectedModelTypeSmall, small)
 			if err != nil {
 				return fmt.Errorf("failed to update preferred small model: %w", err)
 			}
-		} else {
-			if smallModelSelected.MaxTokens > 0 {
-				small.MaxTokens = smallModelSelected.MaxTokens
-			} else {
-				small.MaxTokens = model.DefaultMaxTokens
-			}
-			if smallModelSelected.ReasoningEffort != "" {
-				small.ReasoningEffort = smallModelSelected.ReasoningEffort
-			}
-			if smallModelSelected.Temperature != nil {
-				small.Temperature = smallModelSelected.Temperature
-			}
-			if smallModelSelected.TopP != nil {
-				small.TopP = smallModelSelected.TopP
-			}
-			if smallModelSelected.TopK != nil {
-				small.TopK = smallModelSelected.TopK
-			}
-			if smallModelSelected.FrequencyPenalty != nil {
-				small.FrequencyPenalty = smallModelSelected.FrequencyPenalty
-			}
-			if smallModelSelected.PresencePenalty != nil {
-				small.PresencePenalty = smallModelSelected.PresencePenalty
-			}
-			small.Think = smallModelSelected.Think
 		}
 	}
+	if large.Model == "" {
+		return errors.New("no deployment serves the default large model: select the large model under models")
+	}
+	if small.Model == "" {
+		return errors.New("no deployment serves the default small model: select the small model under models")
+	}
 	c.Models[SelectedModelTypeLarge] = large
 	c.Models[SelectedModelTypeSmall] = small
 	return nil
 }
 
+// configureSelectedModel returns the model selected, with the settings it
+// leaves out taken from defaults and from the model itself. It returns
+// defaults when the model selected isn't known.
+func (c *Config) configureSelectedModel(selected, defaults SelectedModel) (SelectedModel, bool) {
+	configured := defaults
+	if selected.Model != "" {
+		configured.Model = selected.Model
+	}
+	if selected.Provider != "" {
+		configured.Provider = selected.Provider
+	}
+	model := c.GetModel(configured.Provider, configured.Model)
+	if model == nil {
+		return defaults, false
+	}
+	if selected.MaxTokens > 0 {
+		configured.MaxTokens = selected.MaxTokens
+	} else {
+		configured.MaxTokens = model.DefaultMaxTokens
+	}
+	if selected.ReasoningEffort != "" {
+		configured.ReasoningEffort = selected.ReasoningEffort
+	}
+	configured.Think = selected.Think
+	if selected.Temperature != nil {
+		configured.Temperature = selected.Temperature
+	}
+	if selected.TopP != nil {
+		configured.TopP = selected.TopP
+	}
+	if selected.TopK != nil {
+		configured.TopK = selected.TopK
+	}
+	if selected.FrequencyPenalty != nil {
+		configured.FrequencyPenalty = selected.FrequencyPenalty
+	}
+	if selected.PresencePenalty != nil {
+		configured.PresencePenalty = selected.PresencePenalty
+	}
+	return configured, true
+}
+
 // lookupConfigs searches config files recursively from CWD up to FS root
+// configNames are the names of the project configs. Within a directory, the
+// ones listed last have the lowest priority.
+var configNames = []string{
+	appName + ".json",
+	"." + appName + ".json",
+	filepath.Join(defaultDataDirectory, "config.json"),
+}
+
 func lookupConfigs(cwd string) []string {
 	// prepend default config paths
 	configPaths := []string{
@@ -632,8 +693,6 @@ func lookupConfigs(cwd string) []string {
 		GlobalConfigData(),
 	}
 
-	configNames := []string{appName + ".json", "." + appName + ".json"}
-
 	foundConfigs, err := fsext.Lookup(cwd, configNames...)
 	if err != nil {
 		// returns at least default configs
@@ -678,12 +737,6 @@ func loadFromReaders(readers []io.Reader) (*Config, error) {
 	return LoadReader(merged)
 }
 
-func hasVertexCredentials(env env.Env) bool {
-	hasProject := env.Get("VERTEXAI_PROJECT") != ""
-	hasLocation := env.Get("VERTEXAI_LOCATION") != ""
-	return hasProject && hasLocation
-}
-
 func hasAWSCredentials(env env.Env) bool {
 	if env.Get("AWS_BEARER_TOKEN_BEDROCK") != "" {
 		return true
@@ -710,6 +763,11 @@ func hasAWSCredentials(env env.Env) bool {
 		return true
 	}
 
+	// Profiles signing in with SSO live in the config file alone.
+	if _, err := os.Stat(filepath.Join(home.Dir(), ".aws/config")); err == nil {
+		return true
+	}
+
 	return false
 }
 
@@ -800,7 +858,7 @@ func (c *Config) configureGitHubCopilot(env env.Env, resolver VariableResolver,
 
 	// Fetch models from models.dev API if not configured.
 	if len(providerConfig.Models) == 0 {
-		providerConfig.Models = copilot.GetModels(context.Background())
+		providerConfig.setCopilotModels(context.Background(), c.previewModelsEnabled())
 	}
 
 	// Set up Copilot-specific headers.
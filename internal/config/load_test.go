@@ -57,6 +57,31 @@ func TestConfig_setDefaults(t *testing.T) {
 	require.Equal(t, "/tmp", cfg.workingDir)
 }
 
+func TestConfig_setDefaultsAccessible(t *testing.T) {
+	t.Run("dumb terminal", func(t *testing.T) {
+		t.Setenv("TERM", "dumb")
+		cfg := &Config{}
+		cfg.setDefaults("/tmp", "")
+		require.True(t, cfg.Options.TUI.Accessible)
+	})
+
+	t.Run("env override", func(t *testing.T) {
+		t.Setenv("TERM", "dumb")
+		t.Setenv("CRUSH_ACCESSIBLE", "false")
+		cfg := &Config{}
+		cfg.setDefaults("/tmp", "")
+		require.False(t, cfg.Options.TUI.Accessible)
+	})
+
+	t.Run("env enable", func(t *testing.T) {
+		t.Setenv("TERM", "xterm-256color")
+		t.Setenv("CRUSH_ACCESSIBLE", "1")
+		cfg := &Config{}
+		cfg.setDefaults("/tmp", "")
+		require.True(t, cfg.Options.TUI.Accessible)
+	})
+}
+
 func TestConfig_configureProviders(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
@@ -228,14 +253,14 @@ func TestConfig_configureProvidersBedrockWithoutCredentials(t *testing.T) {
 	require.Equal(t, cfg.Providers.Len(), 0)
 }
 
-func TestConfig_configureProvidersBedrockWithoutUnsupportedModel(t *testing.T) {
+func TestConfig_configureProvidersBedrockWithOtherModels(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
 			ID:          catwalk.InferenceProviderBedrock,
 			APIKey:      "",
 			APIEndpoint: "",
 			Models: []catwalk.Model{{
-				ID: "some-random-model",
+				ID: "meta.llama3-70b-instruct-v1:0",
 			}},
 		},
 	}
@@ -248,7 +273,47 @@ func TestConfig_configureProvidersBedrockWithoutUnsupportedModel(t *testing.T) {
 	})
 	resolver := NewEnvironmentVariableResolver(env)
 	err := cfg.configureProviders(env, resolver, knownProviders)
-	require.Error(t, err)
+	require.NoError(t, err)
+	// Models other than Anthropic's go through the Converse API.
+	_, ok := cfg.Providers.Get("bedrock")
+	require.True(t, ok)
+}
+
+func TestConfig_configureProvidersBedrockInferenceProfiles(t *testing.T) {
+	knownProviders := []catwalk.Provider{
+		{
+			ID: catwalk.InferenceProviderBedrock,
+			Models: []catwalk.Model{{
+				ID: "anthropic.claude-sonnet-4-20250514-v1:0",
+			}},
+		},
+	}
+
+	cfg := &Config{
+		Providers: csync.NewMapFrom(map[string]ProviderConfig{
+			"bedrock": {
+				Models: []catwalk.Model{
+					{ID: "eu.anthropic.claude-sonnet-4-20250514-v1:0"},
+					{ID: "arn:aws:bedrock:eu-west-1:123456789012:inference-profile/eu.anthropic.claude-sonnet-4-20250514-v1:0"},
+					{ID: "arn:aws:bedrock:eu-west-1:123456789012:application-inference-profile/a1b2c3"},
+					{ID: "eu.amazon.nova-pro-v1:0"},
+				},
+			},
+		}),
+	}
+	cfg.setDefaults("/tmp", "")
+	env := env.NewFromMap(map[string]string{
+		"AWS_PROFILE":        "sso",
+		"AWS_DEFAULT_REGION": "eu-west-1",
+	})
+	resolver := NewEnvironmentVariableResolver(env)
+	err := cfg.configureProviders(env, resolver, knownProviders)
+	require.NoError(t, err)
+
+	bedrockProvider, ok := cfg.Providers.Get("bedrock")
+	require.True(t, ok, "Bedrock provider should be present")
+	require.Len(t, bedrockProvider.Models, 5)
+	require.Equal(t, "eu-west-1", bedrockProvider.ExtraParams["region"])
 }
 
 func TestConfig_configureProvidersVertexAIWithCredentials(t *testing.T) {
@@ -333,6 +398,117 @@ func TestConfig_configureProvidersVertexAIMissingProject(t *testing.T) {
 	require.Equal(t, cfg.Providers.Len(), 0)
 }
 
+func TestConfig_configureProvidersVertexAIFromConfig(t *testing.T) {
+	knownProviders := []catwalk.Provider{
+		{
+			ID: catwalk.InferenceProviderAnthropic,
+			Models: []catwalk.Model{{
+				ID:               "claude-sonnet-4-5-20250929",
+				Name:             "Claude Sonnet 4.5",
+				ContextWindow:    200000,
+				DefaultMaxTokens: 50000,
+				CanReason:        true,
+			}},
+		},
+		{
+			ID: catwalk.InferenceProviderVertexAI,
+			Models: []catwalk.Model{{
+				ID:            "gemini-2.5-pro",
+				ContextWindow: 1048576,
+			}},
+		},
+	}
+
+	cfg := &Config{
+		Providers: csync.NewMapFrom(map[string]ProviderConfig{
+			"vertexai": {
+				Vertex: &VertexOptions{Project: "$PROJECT"},
+				Models: []catwalk.Model{{ID: "claude-sonnet-4-5@20250929"}},
+			},
+		}),
+	}
+	cfg.setDefaults("/tmp", "")
+	env := env.NewFromMap(map[string]string{
+		"PROJECT":           "test-project",
+		"ANTHROPIC_API_KEY": "",
+	})
+	resolver := NewEnvironmentVariableResolver(env)
+	err := cfg.configureProviders(env, resolver, knownProviders)
+	require.NoError(t, err)
+
+	vertexProvider, ok := cfg.Providers.Get("vertexai")
+	require.True(t, ok, "VertexAI provider should be present")
+	require.Equal(t, "test-project", vertexProvider.ExtraParams["project"])
+	require.Equal(t, "global", vertexProvider.ExtraParams["location"])
+	require.Equal(t, []catwalk.Model{
+		{ID: "claude-sonnet-4-5@20250929", Name: "Claude Sonnet 4.5", ContextWindow: 200000, DefaultMaxTokens: 50000, CanReason: true},
+		{ID: "gemini-2.5-pro", Name: "gemini-2.5-pro", ContextWindow: 1048576},
+	}, vertexProvider.Models)
+}
+
+func TestConfig_configureProvidersAzureDeployments(t *testing.T) {
+	knownProviders := []catwalk.Provider{
+		{
+			ID:                  catwalk.InferenceProviderAzure,
+			APIKey:              "$AZURE_OPENAI_API_KEY",
+			APIEndpoint:         "$AZURE_OPENAI_API_ENDPOINT",
+			DefaultLargeModelID: "gpt-5",
+			DefaultSmallModelID: "gpt-5-mini",
+			Models: []catwalk.Model{
+				{ID: "gpt-5", Name: "GPT-5", ContextWindow: 400000, CanReason: true},
+				{ID: "gpt-5-mini", Name: "GPT-5 Mini", ContextWindow: 400000},
+			},
+		},
+	}
+
+	cfg := &Config{
+		Providers: csync.NewMapFrom(map[string]ProviderConfig{
+			"azure": {
+				Azure: &AzureOptions{
+					Auth:       AzureAuthEntraID,
+					APIVersion: "preview",
+					Deployments: map[string]string{
+						"prod-gpt": "gpt-5",
+						"custom":   "my-fine-tune",
+					},
+				},
+			},
+		}),
+	}
+	cfg.setDefaults("/tmp", "")
+	env := env.NewFromMap(map[string]string{
+		"AZURE_OPENAI_API_ENDPOINT": "https://example.openai.azure.com",
+	})
+	resolver := NewEnvironmentVariableResolver(env)
+	err := cfg.configureProviders(env, resolver, knownProviders)
+	require.NoError(t, err)
+
+	azureProvider, ok := cfg.Providers.Get("azure")
+	require.True(t, ok, "Azure provider should be present")
+	require.True(t, azureProvider.Azure.UsesEntraID())
+	require.Equal(t, "preview", azureProvider.ExtraParams["apiVersion"])
+	require.Equal(t, []catwalk.Model{
+		{ID: "custom", Name: "custom"},
+		{ID: "prod-gpt", Name: "prod-gpt (GPT-5)", ContextWindow: 400000, CanReason: true},
+	}, azureProvider.Models)
+
+	large, small, err := cfg.defaultModelSelection(knownProviders)
+	require.NoError(t, err)
+	require.Equal(t, "prod-gpt", large.Model)
+	require.Empty(t, small.Model, "no deployment serves the default small model")
+
+	cfg.Models = map[SelectedModelType]SelectedModel{}
+	err = cfg.configureSelectedModels(knownProviders)
+	require.ErrorContains(t, err, "select the small model under models")
+
+	cfg.Models = map[SelectedModelType]SelectedModel{
+		SelectedModelTypeSmall: {Provider: "azure", Model: "custom"},
+	}
+	require.NoError(t, cfg.configureSelectedModels(knownProviders))
+	require.Equal(t, "prod-gpt", cfg.Models[SelectedModelTypeLarge].Model)
+	require.Equal(t, "custom", cfg.Models[SelectedModelTypeSmall].Model)
+}
+
 func TestConfig_configureProvidersSetProviderID(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
@@ -469,7 +645,7 @@ func TestConfig_setupAgentsWithNoDisabledTools(t *testing.T) {
 
 	taskAgent, ok := cfg.Agents[AgentTask]
 	require.True(t, ok)
-	assert.Equal(t, []string{"glob", "grep", "ls", "sourcegraph", "view"}, taskAgent.AllowedTools)
+	assert.Equal(t, []string{"glob", "grep", "semantic_search", "ls", "sourcegraph", "web_search", "view"}, taskAgent.AllowedTools)
 }
 
 func TestConfig_setupAgentsWithDisabledTools(t *testing.T) {
@@ -487,11 +663,11 @@ func TestConfig_setupAgentsWithDisabledTools(t *testing.T) {
 	coderAgent, ok := cfg.Agents[AgentCoder]
 	require.True(t, ok)
 
-	assert.Equal(t, []string{"agent", "bash", "job_output", "job_kill", "multiedit", "lsp_diagnostics", "lsp_references", "fetch", "agentic_fetch", "glob", "ls", "sourcegraph", "view", "write"}, coderAgent.AllowedTools)
+	assert.Equal(t, []string{"agent", "agent_output", "bash", "job_output", "job_kill", "multiedit", "apply_patch", "lsp_diagnostics", "lsp_references", "lsp_definition", "lsp_symbols", "fetch", "agentic_fetch", "glob", "semantic_search", "ls", "sourcegraph", "web_search", "view", "write"}, coderAgent.AllowedTools)
 
 	taskAgent, ok := cfg.Agents[AgentTask]
 	require.True(t, ok)
-	assert.Equal(t, []string{"glob", "ls", "sourcegraph", "view"}, taskAgent.AllowedTools)
+	assert.Equal(t, []string{"glob", "semantic_search", "ls", "sourcegraph", "web_search", "view"}, taskAgent.AllowedTools)
 }
 
 func TestConfig_setupAgentsWithEveryReadOnlyToolDisabled(t *testing.T) {
@@ -500,8 +676,10 @@ func TestConfig_setupAgentsWithEveryReadOnlyToolDisabled(t *testing.T) {
 			DisabledTools: []string{
 				"glob",
 				"grep",
+				"semantic_search",
 				"ls",
 				"sourcegraph",
+				"web_search",
 				"view",
 			},
 		},
@@ -510,13 +688,60 @@ func TestConfig_setupAgentsWithEveryReadOnlyToolDisabled(t *testing.T) {
 	cfg.SetupAgents()
 	coderAgent, ok := cfg.Agents[AgentCoder]
 	require.True(t, ok)
-	assert.Equal(t, []string{"agent", "bash", "job_output", "job_kill", "download", "edit", "multiedit", "lsp_diagnostics", "lsp_references", "fetch", "agentic_fetch", "write"}, coderAgent.AllowedTools)
+	assert.Equal(t, []string{"agent", "agent_output", "bash", "job_output", "job_kill", "download", "edit", "multiedit", "apply_patch", "lsp_diagnostics", "lsp_references", "lsp_definition", "lsp_symbols", "fetch", "agentic_fetch", "write"}, coderAgent.AllowedTools)
 
 	taskAgent, ok := cfg.Agents[AgentTask]
 	require.True(t, ok)
 	assert.Equal(t, []string{}, taskAgent.AllowedTools)
 }
 
+func TestConfig_setupAgentsWithSubagents(t *testing.T) {
+	cfg := &Config{
+		Options: &Options{
+			DisabledTools: []string{"bash"},
+		},
+		Subagents: map[string]Subagent{
+			"researcher": {
+				Description: "Researches things",
+				Prompt:      "You research things.",
+			},
+			"reviewer": {
+				Description:  "Reviews code",
+				Prompt:       "You review code.",
+				Model:        SelectedModelTypeSmall,
+				AllowedTools: []string{"view", "bash", "agent", "write"},
+				AllowedMCP:   map[string][]string{"docs": nil},
+			},
+			"disabled": {
+				Prompt:   "You do nothing.",
+				Disabled: true,
+			},
+			"empty": {
+				Description: "Has no prompt",
+			},
+			AgentTask: {
+				Prompt: "You replace the task agent.",
+			},
+		},
+	}
+
+	cfg.SetupAgents()
+	require.Equal(t, []string{"researcher", "reviewer"}, cfg.SubagentNames())
+
+	researcher := cfg.Agents["researcher"]
+	assert.Equal(t, SelectedModelTypeLarge, researcher.Model)
+	assert.Equal(t, "You research things.", researcher.Instructions)
+	assert.Equal(t, []string{"glob", "grep", "semantic_search", "ls", "sourcegraph", "web_search", "view"}, researcher.AllowedTools)
+	assert.Equal(t, map[string][]string{}, researcher.AllowedMCP)
+
+	reviewer := cfg.Agents["reviewer"]
+	assert.Equal(t, SelectedModelTypeSmall, reviewer.Model)
+	assert.Equal(t, []string{"view", "write"}, reviewer.AllowedTools)
+	assert.Equal(t, map[string][]string{"docs": nil}, reviewer.AllowedMCP)
+
+	assert.Empty(t, cfg.Agents[AgentTask].Instructions)
+}
+
 func TestConfig_configureProvidersWithDisabledProvider(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
@@ -574,8 +799,9 @@ func TestConfig_configureProvidersCustomProviderValidation(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, cfg.Providers.Len(), 1)
-		_, exists := cfg.Providers.Get("custom")
+		custom, exists := cfg.Providers.Get("custom")
 		require.True(t, exists)
+		require.Equal(t, "test-model", custom.Models[0].Name)
 	})
 
 	t.Run("custom provider with missing BaseURL is removed", func(t *testing.T) {
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/crush/internal/llamacpp"
+	"github.com/charmbracelet/crush/internal/lmstudio"
+	"github.com/charmbracelet/crush/internal/ollama"
+)
+
+// localDiscoveryTimeout bounds the time spent looking for a local server.
+const localDiscoveryTimeout = 3 * time.Second
+
+// localServer is a server running models locally, set up as a provider when
+// found running.
+type localServer struct {
+	id   string
+	name string
+	// host returns the address the server is looked for at, unless a
+	// base URL is configured.
+	host  func(env env.Env) string
+	fetch func(ctx context.Context, host string) ([]catwalk.Model, error)
+	// omitToolChoice is set for the servers rejecting the tool_choice
+	// parameter.
+	omitToolChoice bool
+}
+
+var localServers = []localServer{
+	{
+		id:    ollama.ProviderID,
+		name:  "Ollama",
+		host:  func(env env.Env) string { return ollama.Host(env.Get("OLLAMA_HOST")) },
+		fetch: ollama.FetchModels,
+	},
+	{
+		id:             lmstudio.ProviderID,
+		name:           "LM Studio",
+		host:           func(env.Env) string { return lmstudio.DefaultHost },
+		fetch:          lmstudio.FetchModels,
+		omitToolChoice: true,
+	},
+	{
+		id:             llamacpp.ProviderID,
+		name:           "llama.cpp",
+		host:           func(env.Env) string { return llamacpp.DefaultHost },
+		fetch:          llamacpp.FetchModels,
+		omitToolChoice: true,
+	},
+}
+
+// takeLocalServers removes the providers of the local servers to look for
+// from the configured ones, returning them: the ones not disabled, with no
+// models configured. They're set up again once found running.
+func (c *Config) takeLocalServers() map[string]ProviderConfig {
+	local := make(map[string]ProviderConfig)
+	for _, server := range localServers {
+		providerConfig, _ := c.Providers.Get(server.id)
+		if providerConfig.Disable || len(providerConfig.Models) > 0 {
+			continue
+		}
+		local[server.id] = providerConfig
+		c.Providers.Del(server.id)
+	}
+	return local
+}
+
+// discoverLocalServers looks for the local servers in the background,
+// setting up their providers as they're found. The returned function waits
+// for them all.
+func (c *Config) discoverLocalServers(local map[string]ProviderConfig, env env.Env, resolver VariableResolver) (wait func()) {
+	var wg sync.WaitGroup
+	for _, server := range localServers {
+		providerConfig, ok := local[server.id]
+		if !ok {
+			continue
+		}
+		wg.Go(func() {
+			c.discoverLocalServer(server, providerConfig, env, resolver)
+		})
+	}
+	return wg.Wait
+}
+
+// selectsLocalServer reports whether a selected model is served by one of
+// the local servers.
+func (c *Config) selectsLocalServer(local map[string]ProviderConfig) bool {
+	for _, model := range c.Models {
+		if _, ok := local[model.Provider]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverLocalServer sets up the provider of server with the models it
+// offers. The server is looked for at the configured base URL, or its usual
+// address.
+func (c *Config) discoverLocalServer(server localServer, providerConfig ProviderConfig, env env.Env, resolver VariableResolver) {
+	host := server.host(env)
+	if providerConfig.BaseURL != "" {
+		baseURL, err := resolver.ResolveValue(providerConfig.BaseURL)
+		if err != nil {
+			slog.Warn("Not looking for a local server, its base URL doesn't resolve", "provider", server.id, "error", err)
+			return
+		}
+		host = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), localDiscoveryTimeout)
+	defer cancel()
+	models, err := server.fetch(ctx, host)
+	if err != nil {
+		slog.Debug("Local server not found", "provider", server.id, "host", host, "error", err)
+		return
+	}
+	if len(models) == 0 {
+		slog.Info("Local server has no models to offer", "provider", server.id, "host", host)
+		return
+	}
+
+	providerConfig.ID = server.id
+	providerConfig.Name = cmp.Or(providerConfig.Name, server.name)
+	providerConfig.Type = cmp.Or(providerConfig.Type, catwalk.TypeOpenAICompat)
+	providerConfig.BaseURL = cmp.Or(providerConfig.BaseURL, host+"/v1")
+	providerConfig.OmitToolChoice = providerConfig.OmitToolChoice || server.omitToolChoice
+	providerConfig.Models = models
+	c.Providers.Set(server.id, providerConfig)
+	slog.Info("Found local server", "provider", server.id, "host", host, "models", len(models))
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverLocalServer(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models": [{"name": "qwen3:30b"}]}`))
+		case "/api/show":
+			_, _ = w.Write([]byte(`{"model_info": {"qwen3moe.context_length": 262144}, "capabilities": ["completion", "tools"]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	resolver := NewEnvironmentVariableResolver(env.NewFromMap(nil))
+	discover := func(providers map[string]ProviderConfig) (ProviderConfig, bool) {
+		cfg := &Config{Providers: csync.NewMapFrom(providers)}
+		cfg.setDefaults("/tmp", "")
+		local := cfg.takeLocalServers()
+		cfg.discoverLocalServers(local, env.NewFromMap(map[string]string{"OLLAMA_HOST": server.URL}), resolver)()
+		return cfg.Providers.Get("ollama")
+	}
+
+	ollama, ok := discover(map[string]ProviderConfig{})
+	require.True(t, ok)
+	require.Equal(t, "Ollama", ollama.Name)
+	require.Equal(t, catwalk.TypeOpenAICompat, ollama.Type)
+	require.Equal(t, server.URL+"/v1", ollama.BaseURL)
+	require.Equal(t, []catwalk.Model{{ID: "qwen3:30b", Name: "qwen3:30b", ContextWindow: 262144}}, ollama.Models)
+
+	ollama, _ = discover(map[string]ProviderConfig{"ollama": {Disable: true}})
+	require.Empty(t, ollama.Models)
+
+	configured := []catwalk.Model{{ID: "llama3.2:3b"}}
+	ollama, _ = discover(map[string]ProviderConfig{"ollama": {Models: configured}})
+	require.Equal(t, configured, ollama.Models)
+
+	// The configured base URL wins over OLLAMA_HOST, and the provider is
+	// left out until it's found.
+	_, ok = discover(map[string]ProviderConfig{"ollama": {BaseURL: "http://127.0.0.1:1/v1"}})
+	require.False(t, ok)
+}
+
+func TestSelectsLocalServer(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Providers: csync.NewMapFrom(map[string]ProviderConfig{"ollama": {}, "openai": {}}),
+		Models:    map[SelectedModelType]SelectedModel{SelectedModelTypeLarge: {Provider: "openai", Model: "gpt-4.1"}},
+	}
+	local := cfg.takeLocalServers()
+	require.Contains(t, local, "ollama")
+	_, ok := cfg.Providers.Get("ollama")
+	require.False(t, ok)
+	require.False(t, cfg.selectsLocalServer(local))
+
+	cfg.Models[SelectedModelTypeSmall] = SelectedModel{Provider: "ollama", Model: "qwen3:30b"}
+	require.True(t, cfg.selectsLocalServer(local))
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPConfig_ResolvedHeaders(t *testing.T) {
+	t.Setenv("MCP_TEST_TOKEN", "secret")
+
+	m := MCPConfig{
+		Type: MCPHttp,
+		Headers: map[string]string{
+			"Authorization": "Bearer $MCP_TEST_TOKEN",
+			"X-Static":      "value",
+		},
+	}
+	require.Equal(t, map[string]string{
+		"Authorization": "Bearer secret",
+		"X-Static":      "value",
+	}, m.ResolvedHeaders())
+	// The configured headers keep their variables.
+	require.Equal(t, "Bearer $MCP_TEST_TOKEN", m.Headers["Authorization"])
+}
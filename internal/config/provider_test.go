@@ -3,10 +3,13 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/env"
 	"github.com/stretchr/testify/require"
 )
 
@@ -71,3 +74,46 @@ func TestProvider_loadProvidersWithIssuesAndNoCache(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, providers, "Expected nil providers when loading fails and no cache exists")
 }
+
+func TestProviderConfig_ResolvedHeaders(t *testing.T) {
+	t.Parallel()
+
+	resolver := NewShellVariableResolver(env.NewFromMap(map[string]string{
+		"GATEWAY_KEY":  "key",
+		"GATEWAY_TEAM": "crush",
+	}))
+	pc := ProviderConfig{
+		ID:           "gateway",
+		APIKey:       "$GATEWAY_KEY",
+		AuthHeader:   "X-API-Key",
+		ExtraHeaders: map[string]string{"X-Team": "${GATEWAY_TEAM}"},
+	}
+	require.Equal(t, map[string]string{
+		"X-API-Key": "key",
+		"X-Team":    "crush",
+	}, pc.ResolvedHeaders(resolver))
+	// The configured values are kept, so secrets aren't written back.
+	require.Equal(t, "${GATEWAY_TEAM}", pc.ExtraHeaders["X-Team"])
+}
+
+func TestProviderConfig_TestConnectionAuthHeader(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("X-API-Key") != "key" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	pc := ProviderConfig{
+		ID:         "gateway",
+		Type:       catwalk.TypeOpenAICompat,
+		BaseURL:    server.URL + "/v1",
+		APIKey:     "key",
+		AuthHeader: "X-API-Key",
+	}
+	require.NoError(t, pc.TestConnection(NewEnvironmentVariableResolver(env.NewFromMap(nil))))
+}
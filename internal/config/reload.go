@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the changes editors make to the config files when
+// saving them.
+const reloadDelay = 200 * time.Millisecond
+
+// Reloaded are the settings changed in the config files that Reload applied.
+type Reloaded struct {
+	Theme  bool
+	Keys   bool
+	Models []SelectedModelType
+	// MCP are the MCP servers added.
+	MCP []string
+}
+
+// IsZero reports whether nothing was reloaded.
+func (r Reloaded) IsZero() bool {
+	return !r.Theme && !r.Keys && len(r.Models) == 0 && len(r.MCP) == 0
+}
+
+func (r Reloaded) String() string {
+	var parts []string
+	if r.Theme {
+		parts = append(parts, "theme")
+	}
+	if r.Keys {
+		parts = append(parts, "key bindings")
+	}
+	for _, t := range r.Models {
+		parts = append(parts, string(t)+" model")
+	}
+	if len(r.MCP) > 0 {
+		parts = append(parts, "MCP "+strings.Join(r.MCP, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Reload parses the config files again, without setting up the providers,
+// the logs or anything else Load does: only the settings Apply copies are
+// read from the result.
+func (c *Config) Reload() (*Config, error) {
+	configPaths := lookupConfigs(c.WorkingDir())
+	fresh, err := loadFromConfigPaths(configPaths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config from paths %v: %w", configPaths, err)
+	}
+	return fresh, nil
+}
+
+// Apply copies the settings of fresh, parsed by Reload, that can change
+// without restarting: the theme, the key bindings, the selected models and
+// the MCP servers added. Other changes take effect on the next start.
+func (c *Config) Apply(fresh *Config) Reloaded {
+	var r Reloaded
+	if c.Options == nil {
+		c.Options = &Options{}
+	}
+	if c.Options.TUI == nil {
+		c.Options.TUI = &TUIOptions{}
+	}
+	// Settings removed from the files go back to their defaults.
+	var theme string
+	var keys map[string][]string
+	if fresh.Options != nil && fresh.Options.TUI != nil {
+		theme, keys = fresh.Options.TUI.Theme, fresh.Options.TUI.Keys
+	}
+	if theme != c.Options.TUI.Theme {
+		c.Options.TUI.Theme = theme
+		r.Theme = true
+	}
+	if !maps.EqualFunc(keys, c.Options.TUI.Keys, slices.Equal) {
+		c.Options.TUI.Keys = keys
+		r.Keys = true
+	}
+
+	// The defaults only fill in the settings the models selected leave out,
+	// so they don't matter without providers.
+	defaultLarge, defaultSmall, _ := c.defaultModelSelection(c.knownProviders)
+	defaults := map[SelectedModelType]SelectedModel{
+		SelectedModelTypeLarge: defaultLarge,
+		SelectedModelTypeSmall: defaultSmall,
+	}
+	for _, t := range []SelectedModelType{SelectedModelTypeLarge, SelectedModelTypeSmall} {
+		selected, ok := fresh.Models[t]
+		if !ok {
+			continue
+		}
+		// Models of providers set up since the start need a restart.
+		model, ok := c.configureSelectedModel(selected, defaults[t])
+		if !ok {
+			slog.Warn("Not reloading the model of an unknown provider", "type", t, "provider", selected.Provider, "model", selected.Model)
+			continue
+		}
+		if current, _ := c.GetSelectedModel(t); reflect.DeepEqual(model, current) {
+			continue
+		}
+		selectionMu.Lock()
+		if c.Models == nil {
+			c.Models = make(map[SelectedModelType]SelectedModel)
+		}
+		c.Models[t] = model
+		selectionMu.Unlock()
+		r.Models = append(r.Models, t)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(fresh.MCP)) {
+		if _, ok := c.GetMCP(name); ok {
+			continue
+		}
+		selectionMu.Lock()
+		if c.MCP == nil {
+			c.MCP = make(MCPs)
+		}
+		c.MCP[name] = fresh.MCP[name]
+		selectionMu.Unlock()
+		r.MCP = append(r.MCP, name)
+	}
+	return r
+}
+
+// Watch calls onChange when the config files found from workingDir change,
+// including the project configs of workingDir created since. The returned
+// function stops watching.
+func Watch(workingDir string, onChange func()) (func() error, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	// Editors often replace the files instead of writing them, so watch
+	// their directories, including the ones of the project configs not
+	// created yet.
+	candidates := lookupConfigs(workingDir)
+	for _, name := range configNames {
+		candidates = append(candidates, filepath.Join(workingDir, name))
+	}
+	paths := make(map[string]bool)
+	for _, path := range candidates {
+		path = filepath.Clean(path)
+		if paths[path] {
+			continue
+		}
+		paths[path] = true
+		dir := filepath.Dir(path)
+		if err := w.Add(dir); err != nil {
+			slog.Debug("Failed to watch config directory", "dir", dir, "error", err)
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+	go func() {
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod || !paths[filepath.Clean(event.Name)] {
+					continue
+				}
+				mu.Lock()
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, onChange)
+				mu.Unlock()
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Config watcher error", "error", err)
+			}
+		}
+	}()
+
+	return func() error {
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+		return w.Close()
+	}, nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Options: &Options{TUI: &TUIOptions{Theme: "charmtone"}},
+		Models: map[SelectedModelType]SelectedModel{
+			SelectedModelTypeLarge: {Provider: "openai", Model: "gpt-4o"},
+			SelectedModelTypeSmall: {Provider: "openai", Model: "gpt-4o-mini"},
+		},
+		MCP: MCPs{"github": {Type: MCPHttp, URL: "https://api.githubcopilot.com/mcp/"}},
+	}
+	cfg.setDefaults("/tmp", "")
+	cfg.Providers.Set("openai", ProviderConfig{ID: "openai", Models: []catwalk.Model{{ID: "gpt-4o"}, {ID: "gpt-4o-mini"}, {ID: "o3"}}})
+
+	fresh := &Config{
+		Options: &Options{TUI: &TUIOptions{Theme: "dracula", Keys: map[string][]string{"models": {"ctrl+o"}}}},
+		Models: map[SelectedModelType]SelectedModel{
+			SelectedModelTypeLarge: {Provider: "openai", Model: "o3"},
+			SelectedModelTypeSmall: {Provider: "other", Model: "small"},
+		},
+		MCP: MCPs{
+			"github": {Type: MCPHttp, URL: "https://example.com/mcp/"},
+			"docs":   {Type: MCPStdio, Command: "docs-mcp"},
+		},
+	}
+
+	reloaded := cfg.Apply(fresh)
+	require.Equal(t, Reloaded{
+		Theme:  true,
+		Keys:   true,
+		Models: []SelectedModelType{SelectedModelTypeLarge},
+		MCP:    []string{"docs"},
+	}, reloaded)
+	require.Equal(t, "theme, key bindings, large model, MCP docs", reloaded.String())
+	require.Equal(t, "dracula", cfg.Options.TUI.Theme)
+	require.Equal(t, "o3", cfg.Models[SelectedModelTypeLarge].Model)
+	require.Equal(t, "gpt-4o-mini", cfg.Models[SelectedModelTypeSmall].Model)
+	// Changes to the servers already running need a restart.
+	require.Equal(t, "https://api.githubcopilot.com/mcp/", cfg.MCP["github"].URL)
+
+	require.True(t, cfg.Apply(fresh).IsZero())
+
+	// Removing the theme goes back to the default one.
+	fresh.Options = nil
+	reloaded = cfg.Apply(fresh)
+	require.Equal(t, Reloaded{Theme: true, Keys: true}, reloaded)
+	require.Empty(t, cfg.Options.TUI.Theme)
+	require.Empty(t, cfg.Options.TUI.Keys)
+}
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crush.json"), []byte(`{"options":{"tui":{"theme":"dracula"}}}`), 0o644))
+	cfg := &Config{workingDir: dir}
+
+	fresh, err := cfg.Reload()
+	require.NoError(t, err)
+	require.Equal(t, "dracula", fresh.Options.TUI.Theme)
+	require.Nil(t, fresh.Providers, "providers aren't set up")
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	changed := make(chan struct{}, 1)
+	stop, err := Watch(dir, func() {
+		changed <- struct{}{}
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stop() })
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crush.json"), []byte("{}"), 0o644))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not noticed")
+	}
+	select {
+	case <-changed:
+		t.Fatal("config change noticed twice")
+	case <-time.After(2 * reloadDelay):
+	}
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -38,6 +42,9 @@ func NewShellVariableResolver(env env.Env) VariableResolver {
 // it will resolve shell-like variable substitution anywhere in the string, including:
 // - $(command) for command substitution
 // - $VAR or ${VAR} for environment variables
+// - ${VAR:-default} for a default when VAR is unset or empty
+// - ${VAR:?message} for a required variable, failing with message otherwise
+// - $$ for a literal $
 func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 	// Special case: lone $ is an error (backward compatibility)
 	if value == "$" {
@@ -49,105 +56,20 @@ func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 		return value, nil
 	}
 
-	result := value
-
-	// Handle command substitution: $(command)
-	for {
-		start := strings.Index(result, "$(")
-		if start == -1 {
-			break
-		}
-
-		// Find matching closing parenthesis
-		depth := 0
-		end := -1
-		for i := start + 2; i < len(result); i++ {
-			if result[i] == '(' {
-				depth++
-			} else if result[i] == ')' {
-				if depth == 0 {
-					end = i
-					break
-				}
-				depth--
-			}
-		}
-
-		if end == -1 {
-			return "", fmt.Errorf("unmatched $( in value: %s", value)
-		}
-
-		command := result[start+2 : end]
+	// Commands run as written, and neither their output nor the escaped
+	// dollars are expanded further.
+	result, err := expandVariables(value, r.env.Get, func(command string) (string, error) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-
+		defer cancel()
 		stdout, _, err := r.shell.Exec(ctx, command)
-		cancel()
 		if err != nil {
 			return "", fmt.Errorf("command execution failed for '%s': %w", command, err)
 		}
-
-		// Replace the $(command) with the output
-		replacement := strings.TrimSpace(stdout)
-		result = result[:start] + replacement + result[end+1:]
+		return strings.TrimSpace(stdout), nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w in value: %s", err, value)
 	}
-
-	// Handle environment variables: $VAR and ${VAR}
-	searchStart := 0
-	for {
-		start := strings.Index(result[searchStart:], "$")
-		if start == -1 {
-			break
-		}
-		start += searchStart // Adjust for the offset
-
-		// Skip if this is part of $( which we already handled
-		if start+1 < len(result) && result[start+1] == '(' {
-			// Skip past this $(...)
-			searchStart = start + 1
-			continue
-		}
-		var varName string
-		var end int
-
-		if start+1 < len(result) && result[start+1] == '{' {
-			// Handle ${VAR} format
-			closeIdx := strings.Index(result[start+2:], "}")
-			if closeIdx == -1 {
-				return "", fmt.Errorf("unmatched ${ in value: %s", value)
-			}
-			varName = result[start+2 : start+2+closeIdx]
-			end = start + 2 + closeIdx + 1
-		} else {
-			// Handle $VAR format - variable names must start with letter or underscore
-			if start+1 >= len(result) {
-				return "", fmt.Errorf("incomplete variable reference at end of string: %s", value)
-			}
-
-			if result[start+1] != '_' &&
-				(result[start+1] < 'a' || result[start+1] > 'z') &&
-				(result[start+1] < 'A' || result[start+1] > 'Z') {
-				return "", fmt.Errorf("invalid variable name starting with '%c' in: %s", result[start+1], value)
-			}
-
-			end = start + 1
-			for end < len(result) && (result[end] == '_' ||
-				(result[end] >= 'a' && result[end] <= 'z') ||
-				(result[end] >= 'A' && result[end] <= 'Z') ||
-				(result[end] >= '0' && result[end] <= '9')) {
-				end++
-			}
-			varName = result[start+1 : end]
-		}
-
-		envValue := r.env.Get(varName)
-		if envValue == "" {
-			return "", fmt.Errorf("environment variable %q not set", varName)
-		}
-
-		result = result[:start] + envValue + result[end:]
-		searchStart = start + len(envValue) // Continue searching after the replacement
-	}
-
 	return result, nil
 }
 
@@ -166,6 +88,9 @@ func (r *environmentVariableResolver) ResolveValue(value string) (string, error)
 	if !strings.HasPrefix(value, "$") {
 		return value, nil
 	}
+	if strings.HasPrefix(value, "$$") {
+		return value[1:], nil
+	}
 
 	varName := strings.TrimPrefix(value, "$")
 	resolvedValue := r.env.Get(varName)
@@ -174,3 +99,168 @@ func (r *environmentVariableResolver) ResolveValue(value string) (string, error)
 	}
 	return resolvedValue, nil
 }
+
+// RequiredVariableError is returned for a variable referenced as
+// ${VAR:?message} that isn't set.
+type RequiredVariableError struct {
+	Name    string
+	Message string
+}
+
+func (e *RequiredVariableError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Name, e.Message)
+	}
+	return fmt.Sprintf("required environment variable %q not set", e.Name)
+}
+
+// expandVariables replaces the references to the variables in value, $VAR,
+// ${VAR}, ${VAR:-default} and ${VAR:?message}, with their value from lookup,
+// and the command substitutions, $(command), with the output of run. A $$
+// stands for a literal $. Without run, command substitutions are left as is.
+func expandVariables(value string, lookup func(string) string, run func(command string) (string, error)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+1 >= len(value) {
+			return "", fmt.Errorf("incomplete variable reference at end of string")
+		}
+
+		var name, expansion string
+		switch next := value[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case next == '(':
+			end := matchingParen(value, i+2)
+			if end == -1 {
+				return "", fmt.Errorf("unmatched $(")
+			}
+			if run == nil {
+				b.WriteString(value[i : end+1])
+			} else {
+				output, err := run(value[i+2 : end])
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(output)
+			}
+			i = end
+			continue
+		case next == '{':
+			closeIdx := strings.IndexByte(value[i+2:], '}')
+			if closeIdx == -1 {
+				return "", fmt.Errorf("unmatched ${")
+			}
+			name = value[i+2 : i+2+closeIdx]
+			if idx := strings.IndexByte(name, ':'); idx != -1 {
+				name, expansion = name[:idx], name[idx:]
+			}
+			i += 2 + closeIdx
+		case isVariableStart(next):
+			end := i + 2
+			for end < len(value) && (isVariableStart(value[end]) || (value[end] >= '0' && value[end] <= '9')) {
+				end++
+			}
+			name = value[i+1 : end]
+			i = end - 1
+		default:
+			return "", fmt.Errorf("invalid variable name starting with '%c'", next)
+		}
+
+		v := lookup(name)
+		switch {
+		case v != "":
+		case strings.HasPrefix(expansion, ":-"):
+			v = expansion[2:]
+		case strings.HasPrefix(expansion, ":?"):
+			return "", &RequiredVariableError{Name: name, Message: expansion[2:]}
+		default:
+			return "", fmt.Errorf("environment variable %q not set", name)
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+// matchingParen returns the index of the parenthesis closing the one opened
+// before start in value, or -1 when there's none.
+func matchingParen(value string, start int) int {
+	depth := 0
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func isVariableStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// disabledKeys are the keys of the config holding entries that can be
+// disabled, by the field disabling them.
+var disabledKeys = map[string]string{
+	"providers": "disable",
+	"mcp":       "disabled",
+	"lsp":       "disabled",
+}
+
+// checkRequiredVariables returns an error for the first variable referenced
+// as required, ${VAR:?message}, in the values of the config that env doesn't
+// set, so that it fails to load rather than when the value is used. The
+// disabled providers, MCP and LSP servers are left out, as they're never
+// used.
+func checkRequiredVariables(cfg *Config, env env.Env) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var value map[string]any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	for key, field := range disabledKeys {
+		entries, _ := value[key].(map[string]any)
+		for name, entry := range entries {
+			if entry, ok := entry.(map[string]any); ok && entry[field] == true {
+				delete(entries, name)
+			}
+		}
+	}
+	return checkRequiredValue("", value, env)
+}
+
+func checkRequiredValue(key string, value any, env env.Env) error {
+	switch value := value.(type) {
+	case string:
+		var required *RequiredVariableError
+		if _, err := expandVariables(value, env.Get, nil); errors.As(err, &required) {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case map[string]any:
+		for _, name := range slices.Sorted(maps.Keys(value)) {
+			if err := checkRequiredValue(joinKey(key, name), value[name], env); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range value {
+			if err := checkRequiredValue(fmt.Sprintf("%s[%d]", key, i), item, env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/charmbracelet/crush/internal/env"
@@ -237,6 +238,49 @@ func TestShellVariableResolver_EnhancedResolveValue(t *testing.T) {
 			value:       "$1$2$3",
 			expectError: true,
 		},
+		{
+			name:     "escaped dollars",
+			value:    "pa$$word $$(not a command) $${TOKEN} $$$TOKEN",
+			envVars:  map[string]string{"TOKEN": "sk-123"},
+			expected: "pa$word $(not a command) ${TOKEN} $sk-123",
+		},
+		{
+			name:  "escaped dollars after commands",
+			value: "$(echo $$PPID)-$$HOME-$(printf '%s' '$HOME')",
+			shellFunc: func(ctx context.Context, command string) (stdout, stderr string, err error) {
+				switch command {
+				case "echo $$PPID":
+					return "42\n", "", nil
+				case "printf '%s' '$HOME'":
+					return "$HOME", "", nil
+				}
+				return "", "", errors.New("unexpected command")
+			},
+			envVars:  map[string]string{"HOME": "/home/user"},
+			expected: "42-$HOME-$HOME",
+		},
+		{
+			name:     "default for unset variable",
+			value:    "${BASE_URL:-http://localhost:11434}/v1",
+			expected: "http://localhost:11434/v1",
+		},
+		{
+			name:     "default for set variable",
+			value:    "${BASE_URL:-http://localhost:11434}/v1",
+			envVars:  map[string]string{"BASE_URL": "https://example.com"},
+			expected: "https://example.com/v1",
+		},
+		{
+			name:        "required variable unset",
+			value:       "Bearer ${TOKEN:?set TOKEN to your key}",
+			expectError: true,
+		},
+		{
+			name:     "required variable set",
+			value:    "Bearer ${TOKEN:?set TOKEN to your key}",
+			envVars:  map[string]string{"TOKEN": "sk-123"},
+			expected: "Bearer sk-123",
+		},
 	}
 
 	for _, tt := range tests {
@@ -330,3 +374,30 @@ func TestNewEnvironmentVariableResolver(t *testing.T) {
 	require.NotNil(t, resolver)
 	require.Implements(t, (*VariableResolver)(nil), resolver)
 }
+
+func TestCheckRequiredVariables(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := LoadReader(strings.NewReader(`{
+		"providers": {"local": {"base_url": "${LOCAL_URL:-http://localhost}", "api_key": "$$LITERAL"}},
+		"mcp": {
+			"github": {"type": "http", "url": "https://api.githubcopilot.com/mcp/", "headers": {"Authorization": "Bearer ${GITHUB_TOKEN:?create a token at https://github.com/settings/tokens}"}},
+			"linear": {"type": "http", "url": "https://mcp.linear.app/mcp", "disabled": true, "headers": {"Authorization": "Bearer ${LINEAR_TOKEN:?}"}}
+		}
+	}`))
+	require.NoError(t, err)
+
+	err = checkRequiredVariables(cfg, env.NewFromMap(map[string]string{}))
+	var required *RequiredVariableError
+	require.ErrorAs(t, err, &required)
+	require.Equal(t, "GITHUB_TOKEN", required.Name)
+	require.EqualError(t, err, "mcp.github.headers.Authorization: GITHUB_TOKEN: create a token at https://github.com/settings/tokens")
+
+	require.NoError(t, checkRequiredVariables(cfg, env.NewFromMap(map[string]string{"GITHUB_TOKEN": "token"})), "disabled servers are left out")
+
+	cfg, err = LoadReader(strings.NewReader(`{
+		"providers": {"deepseek": {"disable": true, "api_key": "${DEEPSEEK_API_KEY:?}"}}
+	}`))
+	require.NoError(t, err)
+	require.NoError(t, checkRequiredVariables(cfg, env.NewFromMap(map[string]string{})), "disabled providers are left out")
+}
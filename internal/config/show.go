@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// redacted replaces the secrets in the configuration shown.
+const redacted = "********"
+
+// Files returns the config files found from workingDir, from the lowest
+// priority to the highest: the global config, the data config, and the
+// project configs from the root of the file system down to workingDir.
+func Files(workingDir string) []string {
+	var files []string
+	for _, path := range lookupConfigs(workingDir) {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Merged returns the configuration of the files found from workingDir, each
+// merged over the ones of lower priority, with the secrets in it redacted.
+func Merged(workingDir string) (map[string]any, error) {
+	var readers []io.Reader
+	for _, path := range Files(workingDir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		readers = append(readers, bytes.NewReader(data))
+	}
+	merged := map[string]any{}
+	if len(readers) == 0 {
+		return merged, nil
+	}
+	r, err := Merge(readers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge configuration files: %w", err)
+	}
+	if err := json.NewDecoder(r).Decode(&merged); err != nil {
+		return nil, fmt.Errorf("failed to decode the merged configuration: %w", err)
+	}
+	redactSecrets(merged)
+	return merged, nil
+}
+
+// redactSecrets replaces the values of the keys, tokens and secrets in the
+// configuration, except the references to environment variables.
+func redactSecrets(value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		for k, v := range value {
+			if s, ok := v.(string); ok && isSecretKey(k) && s != "" && !strings.HasPrefix(s, "$") {
+				value[k] = redacted
+				continue
+			}
+			// OAuth tokens are stored as objects.
+			if _, ok := v.(map[string]any); ok && (k == "oauth" || k == "mcp_tokens" || k == "tokens") {
+				value[k] = redacted
+				continue
+			}
+			redactSecrets(v)
+		}
+	case []any:
+		for _, v := range value {
+			redactSecrets(v)
+		}
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"key", "token", "secret", "password", "authorization"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(project, ".crush"), 0o755))
+	for _, path := range []string{
+		filepath.Join(root, "crush.json"),
+		filepath.Join(project, ".crush", "config.json"),
+		filepath.Join(project, ".crush.json"),
+		filepath.Join(project, "crush.json"),
+	} {
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	}
+
+	// The global configs may exist outside of the test directory.
+	files := slices.DeleteFunc(Files(project), func(path string) bool {
+		return !strings.HasPrefix(path, root)
+	})
+	require.Equal(t, []string{
+		filepath.Join(root, "crush.json"),
+		filepath.Join(project, ".crush", "config.json"),
+		filepath.Join(project, ".crush.json"),
+		filepath.Join(project, "crush.json"),
+	}, files)
+}
+
+func TestRedactSecrets(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"providers": map[string]any{
+			"openai": map[string]any{"api_key": "sk-123", "base_url": "https://api.openai.com/v1"},
+			"other":  map[string]any{"api_key": "$OTHER_API_KEY", "oauth": map[string]any{"access_token": "token"}},
+		},
+		"mcp": map[string]any{
+			"github": map[string]any{"headers": map[string]any{"Authorization": "Bearer token"}},
+		},
+		"options": map[string]any{
+			"tui": map[string]any{"keys": map[string]any{"sessions": []any{"ctrl+o"}}},
+		},
+	}
+	redactSecrets(config)
+	require.Equal(t, map[string]any{
+		"providers": map[string]any{
+			"openai": map[string]any{"api_key": redacted, "base_url": "https://api.openai.com/v1"},
+			"other":  map[string]any{"api_key": "$OTHER_API_KEY", "oauth": redacted},
+		},
+		"mcp": map[string]any{
+			"github": map[string]any{"headers": map[string]any{"Authorization": redacted}},
+		},
+		"options": map[string]any{
+			"tui": map[string]any{"keys": map[string]any{"sessions": []any{"ctrl+o"}}},
+		},
+	}, config)
+}
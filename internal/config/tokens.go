@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/tidwall/sjson"
+)
+
+// migrateToken upgrades a token stored under key to the current schema. It
+// reports whether the token changed and needs to be persisted again.
+func migrateToken(key oauth.TokenKey, t *oauth.Token) bool {
+	if t == nil || t.Version >= oauth.TokenSchemaVersion {
+		return false
+	}
+	if t.ProviderID == "" {
+		t.ProviderID = key.ProviderID
+	}
+	if t.ProviderID != copilot.ProviderID {
+		// Only Copilot derives short-lived API tokens.
+		t.CopilotToken = ""
+		t.CopilotExpiresAt = 0
+	}
+	t.Version = oauth.TokenSchemaVersion
+	return true
+}
+
+// tokenPath returns the path of the record of key in the config file.
+func tokenPath(key oauth.TokenKey) string {
+	return "tokens." + escapePathKey(key.String())
+}
+
+// escapePathKey returns key as a single key of an sjson path, with the
+// characters sjson gives a meaning to escaped, as in the dots of emails.
+func escapePathKey(key string) string {
+	var path strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`.*?|#@\`, r) {
+			path.WriteRune('\\')
+		}
+		path.WriteRune(r)
+	}
+	return path.String()
+}
+
+// parseTokenKey returns the key a token is stored under in the config file.
+func parseTokenKey(s string) oauth.TokenKey {
+	providerID, account, _ := strings.Cut(s, "/")
+	return oauth.TokenKey{ProviderID: providerID, Account: account}
+}
+
+// storedTokens is the part of a config file holding OAuth tokens.
+type storedTokens struct {
+	Tokens    map[string]*oauth.Token `json:"tokens"`
+	Providers map[string]struct {
+		OAuthToken *oauth.Token `json:"oauth"`
+		Accounts   []struct {
+			Name       string       `json:"name"`
+			OAuthToken *oauth.Token `json:"oauth"`
+		} `json:"accounts"`
+	} `json:"providers"`
+}
+
+// migrateTokenFile upgrades the tokens stored in the config file at path to
+// the current schema, moving the ones kept in the providers and their
+// accounts by older versions to their own records. The rest of the file is
+// left untouched.
+func migrateTokenFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var stored storedTokens
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	content := string(data)
+	migrated := 0
+	move := func(field string, key oauth.TokenKey, token *oauth.Token) error {
+		if token == nil {
+			return nil
+		}
+		// A record written since takes precedence over the legacy token.
+		if _, ok := stored.Tokens[key.String()]; !ok {
+			migrateToken(key, token)
+			if content, err = sjson.Set(content, tokenPath(key), token); err != nil {
+				return fmt.Errorf("failed to migrate token %s: %w", key, err)
+			}
+		}
+		if content, err = sjson.Delete(content, field); err != nil {
+			return fmt.Errorf("failed to migrate token %s: %w", key, err)
+		}
+		migrated++
+		return nil
+	}
+	for k, token := range stored.Tokens {
+		key := parseTokenKey(k)
+		if !migrateToken(key, token) {
+			continue
+		}
+		if content, err = sjson.Set(content, tokenPath(key), token); err != nil {
+			return fmt.Errorf("failed to migrate token %s: %w", key, err)
+		}
+		migrated++
+	}
+	for providerID, provider := range stored.Providers {
+		if err := move(fmt.Sprintf("providers.%s.oauth", providerID), oauth.TokenKey{ProviderID: providerID}, provider.OAuthToken); err != nil {
+			return err
+		}
+		for i, account := range provider.Accounts {
+			key := oauth.TokenKey{ProviderID: providerID, Account: account.Name}
+			if err := move(fmt.Sprintf("providers.%s.accounts.%d.oauth", providerID, i), key, account.OAuthToken); err != nil {
+				return err
+			}
+		}
+	}
+	if migrated == 0 {
+		return nil
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	slog.Info("Migrated stored OAuth tokens", "path", path, "count", migrated, "version", oauth.TokenSchemaVersion)
+	return nil
+}
+
+// applyTokens gives the providers and their accounts the tokens stored for
+// them, and upgrades the tokens set in the providers by hand to the current
+// schema.
+func (c *Config) applyTokens() {
+	for providerID, providerConfig := range c.Providers.Seq2() {
+		migrateToken(oauth.TokenKey{ProviderID: providerID}, providerConfig.OAuthToken)
+	}
+	for k, token := range c.Tokens {
+		key := parseTokenKey(k)
+		migrateToken(key, token)
+		// Logging in to Copilot only stores the token.
+		providerConfig, _ := c.Providers.Get(key.ProviderID)
+		if key.Account == "" {
+			providerConfig.OAuthToken = token
+		} else {
+			account, ok := providerConfig.Account(key.Account)
+			if !ok {
+				continue
+			}
+			account.OAuthToken = token
+			providerConfig.setAccount(account)
+		}
+		c.Providers.Set(key.ProviderID, providerConfig)
+	}
+}
+
+// SetProviderToken persists the OAuth token of the provider or one of its
+// accounts in the record of key.
+func (c *Config) SetProviderToken(key oauth.TokenKey, token *oauth.Token) error {
+	if err := c.SetConfigField(tokenPath(key), token); err != nil {
+		return fmt.Errorf("failed to save token %s: %w", key, err)
+	}
+	return nil
+}
+
+// saveAccounts persists the accounts of the provider, with their tokens in
+// records of their own.
+func (c *Config) saveAccounts(providerID string, accounts []ProviderAccount) error {
+	if err := c.SetConfigField(fmt.Sprintf("providers.%s.accounts", providerID), accounts); err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if account.OAuthToken == nil {
+			continue
+		}
+		if err := c.SetProviderToken(oauth.TokenKey{ProviderID: providerID, Account: account.Name}, account.OAuthToken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProviderToken returns the OAuth token stored for the provider and account.
+// An empty account returns the token the provider currently uses.
+func (c *Config) ProviderToken(key oauth.TokenKey) (*oauth.Token, bool) {
+	providerConfig, ok := c.Providers.Get(key.ProviderID)
+	if !ok {
+		return nil, false
+	}
+	if key.Account == "" {
+		return providerConfig.OAuthToken, providerConfig.OAuthToken != nil
+	}
+	account, ok := providerConfig.Account(key.Account)
+	if !ok || account.OAuthToken == nil {
+		return nil, false
+	}
+	return account.OAuthToken, true
+}
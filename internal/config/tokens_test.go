@@ -0,0 +1,188 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/sjson"
+)
+
+func TestMigrateToken(t *testing.T) {
+	t.Parallel()
+
+	t.Run("legacy claude token", func(t *testing.T) {
+		t.Parallel()
+
+		token := &oauth.Token{AccessToken: "a", CopilotToken: "stale", CopilotExpiresAt: 1}
+		require.True(t, migrateToken(oauth.TokenKey{ProviderID: "anthropic"}, token))
+		require.Equal(t, "anthropic", token.ProviderID)
+		require.Equal(t, oauth.TokenSchemaVersion, token.Version)
+		require.Empty(t, token.CopilotToken)
+		require.Zero(t, token.CopilotExpiresAt)
+	})
+
+	t.Run("legacy copilot token", func(t *testing.T) {
+		t.Parallel()
+
+		token := &oauth.Token{RefreshToken: "gho", CopilotToken: "tid", CopilotExpiresAt: 1}
+		require.True(t, migrateToken(oauth.TokenKey{ProviderID: "github-copilot", Account: "octocat"}, token))
+		require.Equal(t, "github-copilot", token.ProviderID)
+		require.Equal(t, "tid", token.CopilotToken)
+	})
+
+	t.Run("current token", func(t *testing.T) {
+		t.Parallel()
+
+		token := &oauth.Token{ProviderID: "anthropic", Version: oauth.TokenSchemaVersion}
+		require.False(t, migrateToken(oauth.TokenKey{ProviderID: "anthropic"}, token))
+		require.False(t, migrateToken(oauth.TokenKey{ProviderID: "anthropic"}, nil))
+	})
+}
+
+func TestMigrateTokenFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "crush.json")
+	legacy := `{
+  "options": {"debug": true},
+  "tokens": {"github-copilot": {"refresh_token": "gho"}},
+  "providers": {
+    "anthropic": {
+      "api_key": "a",
+      "oauth": {"access_token": "a", "refresh_token": "r", "expires_in": 3600, "expires_at": 1, "copilot_token": "stale"},
+      "accounts": [{"name": "me@example.com", "oauth": {"access_token": "b", "refresh_token": "r", "expires_in": 3600, "expires_at": 1}}]
+    },
+    "openai": {"api_key": "sk"}
+  }
+}`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0o600))
+	require.NoError(t, migrateTokenFile(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var cfg Config
+	require.NoError(t, json.Unmarshal(data, &cfg))
+	require.True(t, cfg.Options.Debug, "unrelated fields must be kept")
+
+	// Tokens are moved out of the providers into records of their own.
+	pc, ok := cfg.Providers.Get("anthropic")
+	require.True(t, ok)
+	require.Nil(t, pc.OAuthToken)
+	require.Equal(t, "a", pc.APIKey)
+	require.Equal(t, "me@example.com", pc.Accounts[0].Name)
+	require.NotContains(t, string(data), `"copilot_token"`)
+
+	require.Len(t, cfg.Tokens, 3)
+	token := cfg.Tokens["anthropic"]
+	require.Equal(t, oauth.TokenSchemaVersion, token.Version)
+	require.Equal(t, "anthropic", token.ProviderID)
+	require.Empty(t, token.CopilotToken)
+	require.Equal(t, "r", token.RefreshToken)
+	require.Equal(t, "b", cfg.Tokens["anthropic/me@example.com"].AccessToken)
+	require.Equal(t, oauth.TokenSchemaVersion, cfg.Tokens["anthropic/me@example.com"].Version)
+	require.Equal(t, "github-copilot", cfg.Tokens["github-copilot"].ProviderID)
+
+	// Migrating again is a no-op.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, migrateTokenFile(path))
+	again, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, info.ModTime(), again.ModTime())
+
+	require.NoError(t, migrateTokenFile(filepath.Join(t.TempDir(), "missing.json")))
+}
+
+func TestApplyTokens(t *testing.T) {
+	t.Parallel()
+
+	inline := &oauth.Token{AccessToken: "inline"}
+	cfg := &Config{
+		Providers: csync.NewMapFrom(map[string]ProviderConfig{
+			"anthropic": {ID: "anthropic", Accounts: []ProviderAccount{{Name: "work"}}},
+			"openai":    {ID: "openai", OAuthToken: inline},
+		}),
+		Tokens: map[string]*oauth.Token{
+			"anthropic":      {AccessToken: "active", Version: oauth.TokenSchemaVersion},
+			"anthropic/work": {AccessToken: "work", Version: oauth.TokenSchemaVersion},
+			"anthropic/gone": {AccessToken: "gone", Version: oauth.TokenSchemaVersion},
+			"github-copilot": {RefreshToken: "gho"},
+		},
+	}
+	cfg.applyTokens()
+
+	token, ok := cfg.ProviderToken(oauth.TokenKey{ProviderID: "anthropic"})
+	require.True(t, ok)
+	require.Equal(t, "active", token.AccessToken)
+	token, ok = cfg.ProviderToken(oauth.TokenKey{ProviderID: "anthropic", Account: "work"})
+	require.True(t, ok)
+	require.Equal(t, "work", token.AccessToken)
+	_, ok = cfg.ProviderToken(oauth.TokenKey{ProviderID: "anthropic", Account: "gone"})
+	require.False(t, ok, "tokens of accounts no longer stored are left out")
+
+	token, ok = cfg.ProviderToken(oauth.TokenKey{ProviderID: "github-copilot"})
+	require.True(t, ok, "providers only logged in to are added")
+	require.Equal(t, oauth.TokenSchemaVersion, token.Version)
+	require.Equal(t, oauth.TokenSchemaVersion, inline.Version)
+}
+
+func TestTokenPath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "tokens.anthropic", tokenPath(oauth.TokenKey{ProviderID: "anthropic"}))
+	require.Equal(t, `tokens.anthropic/me\@example\.com`, tokenPath(oauth.TokenKey{ProviderID: "anthropic", Account: "me@example.com"}))
+
+	content, err := sjson.Set("{}", tokenPath(oauth.TokenKey{ProviderID: "anthropic", Account: "me@example.com"}), "x")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"tokens": {"anthropic/me@example.com": "x"}}`, content)
+}
+
+func TestConfig_SetMCPToken(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := &Config{dataConfigDir: filepath.Join(dir, "config.json")}
+	require.NoError(t, cfg.SetMCPToken("docs.example", &oauth.Token{AccessToken: "access"}))
+	require.NoError(t, cfg.SetMCPToken("a*b?#c", &oauth.Token{AccessToken: "other"}))
+
+	data, err := os.ReadFile(cfg.dataConfigDir)
+	require.NoError(t, err)
+	var stored struct {
+		MCPTokens map[string]*oauth.Token `json:"mcp_tokens"`
+	}
+	require.NoError(t, json.Unmarshal(data, &stored))
+	require.Len(t, stored.MCPTokens, 2)
+	require.Equal(t, "access", stored.MCPTokens["docs.example"].AccessToken)
+	require.Equal(t, "other", stored.MCPTokens["a*b?#c"].AccessToken)
+}
+
+func TestConfig_ProviderToken(t *testing.T) {
+	t.Parallel()
+
+	active := &oauth.Token{AccessToken: "active"}
+	stored := &oauth.Token{AccessToken: "stored"}
+	cfg := &Config{Providers: csync.NewMap[string, ProviderConfig]()}
+	cfg.Providers.Set("anthropic", ProviderConfig{
+		ID:         "anthropic",
+		OAuthToken: active,
+		Accounts:   []ProviderAccount{{Name: "work", OAuthToken: stored}},
+	})
+
+	token, ok := cfg.ProviderToken(oauth.TokenKey{ProviderID: "anthropic"})
+	require.True(t, ok)
+	require.Same(t, active, token)
+
+	token, ok = cfg.ProviderToken(oauth.TokenKey{ProviderID: "anthropic", Account: "work"})
+	require.True(t, ok)
+	require.Same(t, stored, token)
+
+	_, ok = cfg.ProviderToken(oauth.TokenKey{ProviderID: "anthropic", Account: "home"})
+	require.False(t, ok)
+	_, ok = cfg.ProviderToken(oauth.TokenKey{ProviderID: "openai"})
+	require.False(t, ok)
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/invopop/jsonschema"
+)
+
+// Problem is something wrong found validating the configuration.
+type Problem struct {
+	// Path is the config file at fault, empty for the merged configuration.
+	Path string
+	// Key is the dotted path of the value at fault, like options.tui.
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	var parts []string
+	if p.Path != "" {
+		parts = append(parts, p.Path)
+	}
+	if p.Key != "" {
+		parts = append(parts, p.Key)
+	}
+	return strings.Join(append(parts, p.Message), ": ")
+}
+
+// Schema returns the JSON schema of the config files.
+func Schema() *jsonschema.Schema {
+	reflector := new(jsonschema.Reflector)
+	return reflector.Reflect(&Config{})
+}
+
+// Validate checks the config files found from workingDir for unknown keys
+// and values of the wrong type, and when they're valid that the providers
+// they configure have the credentials they need.
+func Validate(workingDir, dataDir string) ([]Problem, error) {
+	paths := lookupConfigs(workingDir)
+	var problems []Problem
+	for _, path := range paths {
+		fileProblems, err := ValidateFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	configured, err := loadFromConfigPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(workingDir, dataDir, false)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.missingCredentials(configured), nil
+}
+
+// ValidateFile checks the config file at path for unknown keys and values of
+// the wrong type.
+func ValidateFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []Problem{{Path: path, Message: fmt.Sprintf("invalid JSON: %v", err)}}, nil
+	}
+	schema := Schema()
+	problems := validateValue(schema.Definitions, schema, "", value)
+	for i := range problems {
+		problems[i].Path = path
+	}
+	return problems, nil
+}
+
+// validateValue checks value, found at key, against the schema s.
+func validateValue(defs jsonschema.Definitions, s *jsonschema.Schema, key string, value any) []Problem {
+	if s == nil || s == jsonschema.TrueSchema {
+		return nil
+	}
+	if s.Ref != "" {
+		def, ok := defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if !ok {
+			return nil
+		}
+		return validateValue(defs, def, key, value)
+	}
+
+	if kind := jsonType(value); s.Type != "" && kind != s.Type && (s.Type != "number" || kind != "integer") {
+		return []Problem{{Key: key, Message: fmt.Sprintf("expected %s, got %s", withArticle(s.Type), withArticle(kind))}}
+	}
+	if str, ok := value.(string); ok && len(s.Enum) > 0 && !slices.Contains(s.Enum, any(str)) {
+		var allowed []string
+		for _, v := range s.Enum {
+			allowed = append(allowed, fmt.Sprintf("%q", v))
+		}
+		return []Problem{{Key: key, Message: fmt.Sprintf("invalid value %q, expected one of %s", str, strings.Join(allowed, ", "))}}
+	}
+
+	var problems []Problem
+	switch value := value.(type) {
+	case map[string]any:
+		for _, name := range slices.Sorted(maps.Keys(value)) {
+			property := joinKey(key, name)
+			if s.Properties != nil {
+				if ps, ok := s.Properties.Get(name); ok {
+					problems = append(problems, validateValue(defs, ps, property, value[name])...)
+					continue
+				}
+			}
+			if s.AdditionalProperties == jsonschema.FalseSchema {
+				problems = append(problems, Problem{Key: property, Message: "unknown key"})
+				continue
+			}
+			problems = append(problems, validateValue(defs, s.AdditionalProperties, property, value[name])...)
+		}
+	case []any:
+		for i, item := range value {
+			problems = append(problems, validateValue(defs, s.Items, fmt.Sprintf("%s[%d]", key, i), item)...)
+		}
+	}
+	return problems
+}
+
+// jsonType returns the JSON schema type of a decoded JSON value.
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func withArticle(kind string) string {
+	switch kind {
+	case "null":
+		return kind
+	case "array", "integer", "object":
+		return "an " + kind
+	}
+	return "a " + kind
+}
+
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+// missingCredentials returns the providers of the configured ones that c
+// skipped when loading, because they lack their credentials or endpoint.
+func (c *Config) missingCredentials(configured *Config) []Problem {
+	if configured.Providers == nil {
+		return nil
+	}
+	known := make(map[string]catwalk.Provider)
+	for _, p := range c.knownProviders {
+		known[string(p.ID)] = p
+	}
+
+	var problems []Problem
+	for _, id := range slices.Sorted(maps.Keys(maps.Collect(configured.Providers.Seq2()))) {
+		p, _ := configured.Providers.Get(id)
+		if p.Disable {
+			continue
+		}
+		if _, ok := c.Providers.Get(id); ok {
+			continue
+		}
+		message := c.missingCredential(p, known[id])
+		if id == "github-copilot" {
+			message = "missing GitHub token, log in from the models dialog or set CRUSH_GITHUB_COPILOT_TOKEN"
+		}
+		problems = append(problems, Problem{Key: "providers." + id, Message: message})
+	}
+	return problems
+}
+
+// missingCredential tells why the provider p, configured over the known one,
+// couldn't be set up.
+func (c *Config) missingCredential(p ProviderConfig, known catwalk.Provider) string {
+	switch known.ID {
+	case catwalk.InferenceProviderVertexAI:
+		return "missing project, set VERTEXAI_PROJECT or providers.vertexai.vertex.project"
+	case catwalk.InferenceProviderBedrock:
+		return "missing AWS credentials"
+	case catwalk.InferenceProviderAzure:
+		return missingValue("API endpoint", cmp.Or(p.BaseURL, known.APIEndpoint), c.resolver)
+	case "":
+		// Custom providers need an endpoint and models.
+		if _, err := c.resolver.ResolveValue(p.BaseURL); p.BaseURL == "" || err != nil {
+			return missingValue("API endpoint", p.BaseURL, c.resolver)
+		}
+		if len(p.Models) == 0 {
+			return "no models configured"
+		}
+		return "unsupported provider type " + string(p.Type)
+	}
+	return missingValue("API key", cmp.Or(p.APIKey, known.APIKey), c.resolver)
+}
+
+// missingValue describes the value named what, unset or resolving to
+// nothing.
+func missingValue(what, value string, resolver VariableResolver) string {
+	if value == "" {
+		return "missing " + what
+	}
+	if _, err := resolver.ResolveValue(value); err != nil {
+		return fmt.Sprintf("missing %s: %v", what, err)
+	}
+	return fmt.Sprintf("missing %s: %s is empty", what, value)
+}
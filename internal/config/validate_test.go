@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "crush.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"$schema": "https://charm.land/crush.json",
+		"options": {"tui": {"compact_mode": "yes", "diff_mode": "sideways", "bogus": 1}},
+		"providers": {"custom": {"base_url": "http://localhost", "models": [{"id": "m", "context_window": 1.5}]}},
+		"lsp": {"go": {"command": "gopls", "args": ["serve", 2]}}
+	}`), 0o644))
+
+	problems, err := ValidateFile(path)
+	require.NoError(t, err)
+	var messages []string
+	for _, p := range problems {
+		require.Equal(t, path, p.Path)
+		messages = append(messages, p.Key+": "+p.Message)
+	}
+	require.Equal(t, []string{
+		"lsp.go.args[1]: expected a string, got an integer",
+		"options.tui.bogus: unknown key",
+		"options.tui.compact_mode: expected a boolean, got a string",
+		`options.tui.diff_mode: invalid value "sideways", expected one of "unified", "split"`,
+		"providers.custom.models[0].context_window: expected an integer, got a number",
+	}, messages)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"options": {`), 0o644))
+	problems, err = ValidateFile(path)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.True(t, strings.HasPrefix(problems[0].Message, "invalid JSON"))
+
+	_, err = ValidateFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestMissingCredentials(t *testing.T) {
+	t.Parallel()
+
+	configured, err := LoadReader(strings.NewReader(`{"providers": {
+		"openai": {},
+		"anthropic": {"api_key": "$ANTHROPIC_API_KEY"},
+		"disabled": {"disable": true},
+		"local": {"base_url": "$LOCAL_URL", "models": [{"id": "m"}]}
+	}}`))
+	require.NoError(t, err)
+
+	cfg := &Config{}
+	cfg.setDefaults("/tmp", "")
+	cfg.knownProviders = []catwalk.Provider{
+		{ID: "openai", APIKey: "$OPENAI_API_KEY"},
+		{ID: "anthropic", APIKey: "$ANTHROPIC_API_KEY"},
+	}
+	cfg.resolver = NewEnvironmentVariableResolver(env.NewFromMap(map[string]string{
+		"ANTHROPIC_API_KEY": "key",
+	}))
+	cfg.Providers.Set("anthropic", ProviderConfig{ID: "anthropic"})
+
+	problems := cfg.missingCredentials(configured)
+	require.Len(t, problems, 2)
+	require.Equal(t, "providers.local", problems[0].Key)
+	require.Contains(t, problems[0].Message, "missing API endpoint")
+	require.Equal(t, "providers.openai", problems[1].Key)
+	require.Contains(t, problems[1].Message, "missing API key")
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/env"
+)
+
+// vertexDefaultLocation is the endpoint used when no region is set, serving
+// both the Gemini and the Claude models.
+const vertexDefaultLocation = "global"
+
+// vertexProjectLocation returns the project and the location of Vertex AI,
+// from the configuration or the environment. The Google Cloud variables are
+// only used when the Gen AI SDK is told to use Vertex AI.
+func vertexProjectLocation(opts *VertexOptions, env env.Env, resolver VariableResolver) (project, location string) {
+	if opts != nil {
+		var err error
+		if project, err = resolver.ResolveValue(opts.Project); err != nil {
+			slog.Warn("Vertex AI project doesn't resolve", "error", err)
+			project = ""
+		}
+		if location, err = resolver.ResolveValue(opts.Location); err != nil {
+			slog.Warn("Vertex AI location doesn't resolve", "error", err)
+			location = ""
+		}
+	}
+	project = cmp.Or(project, env.Get("VERTEXAI_PROJECT"))
+	location = cmp.Or(location, env.Get("VERTEXAI_LOCATION"))
+	if strings.EqualFold(env.Get("GOOGLE_GENAI_USE_VERTEXAI"), "true") {
+		project = cmp.Or(project, env.Get("GOOGLE_CLOUD_PROJECT"))
+		location = cmp.Or(location, env.Get("GOOGLE_CLOUD_LOCATION"))
+	}
+	return project, cmp.Or(location, vertexDefaultLocation)
+}
+
+// vertexModels fills in the metadata the models of Vertex AI lack, usually
+// the ones configured by hand, from the same models of Anthropic and Gemini.
+// Vertex AI names the versions of the Claude models after an @.
+func vertexModels(models []catwalk.Model, knownProviders []catwalk.Provider) []catwalk.Model {
+	models = slices.Clone(models)
+	for i, model := range models {
+		if model.ContextWindow != 0 {
+			continue
+		}
+		known, ok := findVertexModel(model.ID, knownProviders)
+		if !ok {
+			continue
+		}
+		known.ID = model.ID
+		if model.Name != "" && model.Name != model.ID {
+			known.Name = model.Name
+		}
+		known.DefaultMaxTokens = cmp.Or(model.DefaultMaxTokens, known.DefaultMaxTokens)
+		models[i] = known
+	}
+	return models
+}
+
+func findVertexModel(modelID string, knownProviders []catwalk.Provider) (catwalk.Model, bool) {
+	candidates := []string{modelID, strings.ReplaceAll(modelID, "@", "-")}
+	for _, p := range knownProviders {
+		if p.ID != catwalk.InferenceProviderAnthropic && p.ID != catwalk.InferenceProviderGemini {
+			continue
+		}
+		for _, m := range p.Models {
+			if slices.Contains(candidates, m.ID) {
+				return m, true
+			}
+		}
+	}
+	return catwalk.Model{}, false
+}
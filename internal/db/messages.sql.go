@@ -24,7 +24,7 @@ INSERT INTO messages (
 ) VALUES (
     ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
 )
-RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message, cost, prompt_tokens, completion_tokens
 `
 
 type CreateMessageParams struct {
@@ -59,6 +59,9 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.FinishedAt,
 		&i.Provider,
 		&i.IsSummaryMessage,
+		&i.Cost,
+		&i.PromptTokens,
+		&i.CompletionTokens,
 	)
 	return i, err
 }
@@ -83,8 +86,21 @@ func (q *Queries) DeleteSessionMessages(ctx context.Context, sessionID string) e
 	return err
 }
 
+const getCostSince = `-- name: GetCostSince :one
+SELECT CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS cost
+FROM messages
+WHERE created_at >= ?
+`
+
+func (q *Queries) GetCostSince(ctx context.Context, createdAt int64) (float64, error) {
+	row := q.queryRow(ctx, q.getCostSinceStmt, getCostSince, createdAt)
+	var cost float64
+	err := row.Scan(&cost)
+	return cost, err
+}
+
 const getMessage = `-- name: GetMessage :one
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message, cost, prompt_tokens, completion_tokens
 FROM messages
 WHERE id = ? LIMIT 1
 `
@@ -103,12 +119,15 @@ func (q *Queries) GetMessage(ctx context.Context, id string) (Message, error) {
 		&i.FinishedAt,
 		&i.Provider,
 		&i.IsSummaryMessage,
+		&i.Cost,
+		&i.PromptTokens,
+		&i.CompletionTokens,
 	)
 	return i, err
 }
 
 const listMessagesBySession = `-- name: ListMessagesBySession :many
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message, cost, prompt_tokens, completion_tokens
 FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC
@@ -134,6 +153,9 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 			&i.FinishedAt,
 			&i.Provider,
 			&i.IsSummaryMessage,
+			&i.Cost,
+			&i.PromptTokens,
+			&i.CompletionTokens,
 		); err != nil {
 			return nil, err
 		}
@@ -153,17 +175,30 @@ UPDATE messages
 SET
     parts = ?,
     finished_at = ?,
+    cost = ?,
+    prompt_tokens = ?,
+    completion_tokens = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?
 `
 
 type UpdateMessageParams struct {
-	Parts      string        `json:"parts"`
-	FinishedAt sql.NullInt64 `json:"finished_at"`
-	ID         string        `json:"id"`
+	Parts            string        `json:"parts"`
+	FinishedAt       sql.NullInt64 `json:"finished_at"`
+	Cost             float64       `json:"cost"`
+	PromptTokens     int64         `json:"prompt_tokens"`
+	CompletionTokens int64         `json:"completion_tokens"`
+	ID               string        `json:"id"`
 }
 
 func (q *Queries) UpdateMessage(ctx context.Context, arg UpdateMessageParams) error {
-	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage, arg.Parts, arg.FinishedAt, arg.ID)
+	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage,
+		arg.Parts,
+		arg.FinishedAt,
+		arg.Cost,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.ID,
+	)
 	return err
 }
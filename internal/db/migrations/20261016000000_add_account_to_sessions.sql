@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add the provider account a session was created with
+ALTER TABLE sessions ADD COLUMN account_provider TEXT;
+ALTER TABLE sessions ADD COLUMN account TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove the provider account from sessions table
+ALTER TABLE sessions DROP COLUMN account;
+ALTER TABLE sessions DROP COLUMN account_provider;
+-- +goose StatementEnd
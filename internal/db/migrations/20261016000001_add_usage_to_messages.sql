@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add the cost and token usage of each assistant message
+ALTER TABLE messages ADD COLUMN cost REAL NOT NULL DEFAULT 0.0;
+ALTER TABLE messages ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+-- Add the token usage accumulated over every request of a session
+ALTER TABLE sessions ADD COLUMN total_prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN total_completion_tokens INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove the token usage from sessions and messages tables
+ALTER TABLE sessions DROP COLUMN total_completion_tokens;
+ALTER TABLE sessions DROP COLUMN total_prompt_tokens;
+ALTER TABLE messages DROP COLUMN completion_tokens;
+ALTER TABLE messages DROP COLUMN prompt_tokens;
+ALTER TABLE messages DROP COLUMN cost;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add the oldest message kept verbatim after a compaction summary
+ALTER TABLE sessions ADD COLUMN summary_keep_from_id TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove the kept message from sessions table
+ALTER TABLE sessions DROP COLUMN summary_keep_from_id;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add the prompt cache usage accumulated over every request of a session
+ALTER TABLE sessions ADD COLUMN total_cache_read_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN total_cache_write_tokens INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove the prompt cache usage from sessions table
+ALTER TABLE sessions DROP COLUMN total_cache_write_tokens;
+ALTER TABLE sessions DROP COLUMN total_cache_read_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add the session and message sessions were forked from
+ALTER TABLE sessions ADD COLUMN forked_from_session_id TEXT;
+ALTER TABLE sessions ADD COLUMN forked_at_message_id TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove the fork origin from sessions table
+ALTER TABLE sessions DROP COLUMN forked_at_message_id;
+ALTER TABLE sessions DROP COLUMN forked_from_session_id;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add the tags of sessions, as a JSON array, and the project they were created in
+ALTER TABLE sessions ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE sessions ADD COLUMN project TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove the tags and project from sessions table
+ALTER TABLE sessions DROP COLUMN project;
+ALTER TABLE sessions DROP COLUMN tags;
+-- +goose StatementEnd
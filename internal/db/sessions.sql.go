@@ -10,6 +10,63 @@ import (
 	"database/sql"
 )
 
+const addSessionUsage = `-- name: AddSessionUsage :one
+UPDATE sessions
+SET
+    cost = cost + ?,
+    total_prompt_tokens = total_prompt_tokens + ?,
+    total_completion_tokens = total_completion_tokens + ?,
+    total_cache_read_tokens = total_cache_read_tokens + ?,
+    total_cache_write_tokens = total_cache_write_tokens + ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, account_provider, account, total_prompt_tokens, total_completion_tokens, summary_keep_from_id, total_cache_read_tokens, total_cache_write_tokens, forked_from_session_id, forked_at_message_id, tags, project
+`
+
+type AddSessionUsageParams struct {
+	Cost                  float64 `json:"cost"`
+	TotalPromptTokens     int64   `json:"total_prompt_tokens"`
+	TotalCompletionTokens int64   `json:"total_completion_tokens"`
+	TotalCacheReadTokens  int64   `json:"total_cache_read_tokens"`
+	TotalCacheWriteTokens int64   `json:"total_cache_write_tokens"`
+	ID                    string  `json:"id"`
+}
+
+func (q *Queries) AddSessionUsage(ctx context.Context, arg AddSessionUsageParams) (Session, error) {
+	row := q.queryRow(ctx, q.addSessionUsageStmt, addSessionUsage,
+		arg.Cost,
+		arg.TotalPromptTokens,
+		arg.TotalCompletionTokens,
+		arg.TotalCacheReadTokens,
+		arg.TotalCacheWriteTokens,
+		arg.ID,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.AccountProvider,
+		&i.Account,
+		&i.TotalPromptTokens,
+		&i.TotalCompletionTokens,
+		&i.SummaryKeepFromID,
+		&i.TotalCacheReadTokens,
+		&i.TotalCacheWriteTokens,
+		&i.ForkedFromSessionID,
+		&i.ForkedAtMessageID,
+		&i.Tags,
+		&i.Project,
+	)
+	return i, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
     id,
@@ -20,6 +77,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    project,
     updated_at,
     created_at
 ) VALUES (
@@ -31,9 +89,10 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, account_provider, account, total_prompt_tokens, total_completion_tokens, summary_keep_from_id, total_cache_read_tokens, total_cache_write_tokens, forked_from_session_id, forked_at_message_id, tags, project
 `
 
 type CreateSessionParams struct {
@@ -44,6 +103,7 @@ type CreateSessionParams struct {
 	PromptTokens     int64          `json:"prompt_tokens"`
 	CompletionTokens int64          `json:"completion_tokens"`
 	Cost             float64        `json:"cost"`
+	Project          string         `json:"project"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -55,6 +115,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.Cost,
+		arg.Project,
 	)
 	var i Session
 	err := row.Scan(
@@ -68,6 +129,17 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.AccountProvider,
+		&i.Account,
+		&i.TotalPromptTokens,
+		&i.TotalCompletionTokens,
+		&i.SummaryKeepFromID,
+		&i.TotalCacheReadTokens,
+		&i.TotalCacheWriteTokens,
+		&i.ForkedFromSessionID,
+		&i.ForkedAtMessageID,
+		&i.Tags,
+		&i.Project,
 	)
 	return i, err
 }
@@ -83,7 +155,7 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 }
 
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, account_provider, account, total_prompt_tokens, total_completion_tokens, summary_keep_from_id, total_cache_read_tokens, total_cache_write_tokens, forked_from_session_id, forked_at_message_id, tags, project
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,12 +174,23 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.AccountProvider,
+		&i.Account,
+		&i.TotalPromptTokens,
+		&i.TotalCompletionTokens,
+		&i.SummaryKeepFromID,
+		&i.TotalCacheReadTokens,
+		&i.TotalCacheWriteTokens,
+		&i.ForkedFromSessionID,
+		&i.ForkedAtMessageID,
+		&i.Tags,
+		&i.Project,
 	)
 	return i, err
 }
 
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, account_provider, account, total_prompt_tokens, total_completion_tokens, summary_keep_from_id, total_cache_read_tokens, total_cache_write_tokens, forked_from_session_id, forked_at_message_id, tags, project
 FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC
@@ -133,6 +216,17 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.AccountProvider,
+			&i.Account,
+			&i.TotalPromptTokens,
+			&i.TotalCompletionTokens,
+			&i.SummaryKeepFromID,
+			&i.TotalCacheReadTokens,
+			&i.TotalCacheWriteTokens,
+			&i.ForkedFromSessionID,
+			&i.ForkedAtMessageID,
+			&i.Tags,
+			&i.Project,
 		); err != nil {
 			return nil, err
 		}
@@ -154,18 +248,28 @@ SET
     prompt_tokens = ?,
     completion_tokens = ?,
     summary_message_id = ?,
-    cost = ?
+    account_provider = ?,
+    account = ?,
+    summary_keep_from_id = ?,
+    forked_from_session_id = ?,
+    forked_at_message_id = ?,
+    tags = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, account_provider, account, total_prompt_tokens, total_completion_tokens, summary_keep_from_id, total_cache_read_tokens, total_cache_write_tokens, forked_from_session_id, forked_at_message_id, tags, project
 `
 
 type UpdateSessionParams struct {
-	Title            string         `json:"title"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
-	Cost             float64        `json:"cost"`
-	ID               string         `json:"id"`
+	Title               string         `json:"title"`
+	PromptTokens        int64          `json:"prompt_tokens"`
+	CompletionTokens    int64          `json:"completion_tokens"`
+	SummaryMessageID    sql.NullString `json:"summary_message_id"`
+	AccountProvider     sql.NullString `json:"account_provider"`
+	Account             sql.NullString `json:"account"`
+	SummaryKeepFromID   sql.NullString `json:"summary_keep_from_id"`
+	ForkedFromSessionID sql.NullString `json:"forked_from_session_id"`
+	ForkedAtMessageID   sql.NullString `json:"forked_at_message_id"`
+	Tags                string         `json:"tags"`
+	ID                  string         `json:"id"`
 }
 
 func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error) {
@@ -174,7 +278,12 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.SummaryMessageID,
-		arg.Cost,
+		arg.AccountProvider,
+		arg.Account,
+		arg.SummaryKeepFromID,
+		arg.ForkedFromSessionID,
+		arg.ForkedAtMessageID,
+		arg.Tags,
 		arg.ID,
 	)
 	var i Session
@@ -189,6 +298,17 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.AccountProvider,
+		&i.Account,
+		&i.TotalPromptTokens,
+		&i.TotalCompletionTokens,
+		&i.SummaryKeepFromID,
+		&i.TotalCacheReadTokens,
+		&i.TotalCacheWriteTokens,
+		&i.ForkedFromSessionID,
+		&i.ForkedAtMessageID,
+		&i.Tags,
+		&i.Project,
 	)
 	return i, err
 }
@@ -9,6 +9,11 @@ FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC;
 
+-- name: GetCostSince :one
+SELECT CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS cost
+FROM messages
+WHERE created_at >= ?;
+
 -- name: CreateMessage :one
 INSERT INTO messages (
     id,
@@ -30,6 +35,9 @@ UPDATE messages
 SET
     parts = ?,
     finished_at = ?,
+    cost = ?,
+    prompt_tokens = ?,
+    completion_tokens = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?;
 
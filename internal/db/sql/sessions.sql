@@ -8,6 +8,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    project,
     updated_at,
     created_at
 ) VALUES (
@@ -19,6 +20,7 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
@@ -41,10 +43,25 @@ SET
     prompt_tokens = ?,
     completion_tokens = ?,
     summary_message_id = ?,
-    cost = ?
+    account_provider = ?,
+    account = ?,
+    summary_keep_from_id = ?,
+    forked_from_session_id = ?,
+    forked_at_message_id = ?,
+    tags = ?
 WHERE id = ?
 RETURNING *;
 
+-- name: AddSessionUsage :one
+UPDATE sessions
+SET
+    cost = cost + ?,
+    total_prompt_tokens = total_prompt_tokens + ?,
+    total_completion_tokens = total_completion_tokens + ?,
+    total_cache_read_tokens = total_cache_read_tokens + ?,
+    total_cache_write_tokens = total_cache_write_tokens + ?
+WHERE id = ?
+RETURNING *;
 
 -- name: DeleteSession :exec
 DELETE FROM sessions
@@ -0,0 +1,276 @@
+// Package export renders sessions to Markdown and HTML files, for sharing
+// and archival.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Format is the format sessions are exported to.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+// maxResultLength is the length tool results are cut to, the diffs of the
+// files edited being kept whole.
+const maxResultLength = 4000
+
+// ParseFormat returns the format named s, or by its file extension.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q, expected markdown or html", s)
+	}
+}
+
+// Ext returns the file extension of the format.
+func (f Format) Ext() string {
+	if f == FormatHTML {
+		return ".html"
+	}
+	return ".md"
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FileName returns the name of the file sess is exported to by default.
+func FileName(sess session.Session, format Format) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(sess.Title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	if slug == "" {
+		slug = "session"
+	}
+	return fmt.Sprintf("crush-%s-%s%s", slug, time.Unix(sess.CreatedAt, 0).Format("20060102-150405"), format.Ext())
+}
+
+// Render renders sess and its messages in format.
+func Render(sess session.Session, messages []message.Message, format Format) ([]byte, error) {
+	md := Markdown(sess, messages)
+	if format == FormatMarkdown {
+		return []byte(md), nil
+	}
+	return HTML(sess, md)
+}
+
+// WriteFile exports sess and its messages to path, in format.
+func WriteFile(path string, sess session.Session, messages []message.Message, format Format) error {
+	content, err := Render(sess, messages, format)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// Markdown renders sess and its messages: the prompts, the answers, the
+// tool calls with their results or the diffs of the files they edited, and
+// the usage and cost of the session.
+func Markdown(sess session.Session, messages []message.Message) string {
+	var b strings.Builder
+	title := sess.Title
+	if title == "" {
+		title = "Untitled Session"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- **Date:** %s\n", time.Unix(sess.CreatedAt, 0).Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- **Messages:** %d\n", len(messages))
+	fmt.Fprintf(&b, "- **Tokens:** %d input, %d output\n", sess.TotalPromptTokens, sess.TotalCompletionTokens)
+	fmt.Fprintf(&b, "- **Cost:** $%.4f\n", sess.Cost)
+
+	results := make(map[string]message.ToolResult)
+	for _, msg := range messages {
+		for _, result := range msg.ToolResults() {
+			results[result.ToolCallID] = result
+		}
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			b.WriteString("\n## User\n\n")
+			if msg.IsSummaryMessage {
+				b.WriteString("*Summary of the conversation so far:*\n\n")
+			}
+			b.WriteString(strings.TrimSpace(msg.Content().Text))
+			b.WriteString("\n")
+			for _, file := range msg.BinaryContent() {
+				fmt.Fprintf(&b, "\n*Attached %s*\n", file.Path)
+			}
+		case message.Assistant:
+			text := strings.TrimSpace(msg.Content().Text)
+			calls := msg.ToolCalls()
+			if text == "" && len(calls) == 0 {
+				continue
+			}
+			b.WriteString("\n## Assistant")
+			if msg.Model != "" {
+				fmt.Fprintf(&b, " (%s)", msg.Model)
+			}
+			b.WriteString("\n")
+			if text != "" {
+				fmt.Fprintf(&b, "\n%s\n", text)
+			}
+			for _, call := range calls {
+				writeToolCall(&b, call, results[call.ID])
+			}
+			if finish := msg.FinishPart(); finish != nil && finish.Reason == message.FinishReasonError {
+				fmt.Fprintf(&b, "\n> **Error:** %s\n", strings.TrimSpace(finish.Message+" "+finish.Details))
+			}
+		}
+	}
+	return b.String()
+}
+
+func writeToolCall(b *strings.Builder, call message.ToolCall, result message.ToolResult) {
+	fmt.Fprintf(b, "\n### Tool: %s\n\n", call.Name)
+	input := call.Input
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, []byte(input), "", "  ") == nil {
+		input = pretty.String()
+	}
+	b.WriteString(fence("json", input))
+
+	if result.ToolCallID == "" {
+		b.WriteString("\n*No result.*\n")
+		return
+	}
+	if d := resultDiff(call, result); d != "" && !result.IsError {
+		b.WriteString("\n")
+		b.WriteString(fence("diff", d))
+		return
+	}
+	content := result.Content
+	if len(content) > maxResultLength {
+		content = content[:maxResultLength] + "\n[output truncated]"
+	}
+	if result.IsError {
+		b.WriteString("\n**Error:**\n\n")
+	} else {
+		b.WriteString("\n**Result:**\n\n")
+	}
+	b.WriteString(fence("", content))
+}
+
+// resultDiff returns the diff of the file edited by call, when it did.
+func resultDiff(call message.ToolCall, result message.ToolResult) string {
+	var metadata struct {
+		Diff       string `json:"diff"`
+		OldContent string `json:"old_content"`
+		NewContent string `json:"new_content"`
+	}
+	if result.Metadata == "" || json.Unmarshal([]byte(result.Metadata), &metadata) != nil {
+		return ""
+	}
+	if metadata.Diff != "" {
+		return metadata.Diff
+	}
+	if metadata.OldContent == metadata.NewContent {
+		return ""
+	}
+	var params struct {
+		FilePath string `json:"file_path"`
+	}
+	_ = json.Unmarshal([]byte(call.Input), &params)
+	d, _, _ := diff.GenerateDiff(metadata.OldContent, metadata.NewContent, params.FilePath)
+	return d
+}
+
+// fence wraps content in a code block, with a fence longer than the
+// backticks it holds.
+func fence(lang, content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	ticks := strings.Repeat("`", max(3, longest+1))
+	return fmt.Sprintf("%s%s\n%s\n%s\n", ticks, lang, strings.TrimRight(content, "\n"), ticks)
+}
+
+var page = template.Must(template.New("session").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="Crush">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 56rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.5 system-ui, sans-serif; color: #1f1d2b; background: #fbfaff; }
+h1 { color: #6b50ff; }
+h2 { margin-top: 2.5rem; padding-top: 1rem; border-top: 1px solid #e0ddf0; }
+h3 { font-size: 1rem; color: #6b50ff; }
+pre { overflow-x: auto; padding: 0.75rem 1rem; border-radius: 6px; background: #201f26; color: #dfdbdd; font-size: 0.85rem; }
+code { font-family: ui-monospace, monospace; }
+:not(pre) > code { padding: 0.1rem 0.3rem; border-radius: 4px; background: #ece9f8; }
+blockquote { margin: 1rem 0; padding: 0.5rem 1rem; border-left: 4px solid #ff577d; background: #fff0f3; }
+table { border-collapse: collapse; }
+th, td { padding: 0.25rem 0.75rem; border: 1px solid #e0ddf0; }
+@media (prefers-color-scheme: dark) {
+  body { color: #dfdbdd; background: #16151c; }
+  h2 { border-color: #3a3943; }
+  :not(pre) > code { background: #2d2c35; }
+  blockquote { background: #2b1a20; }
+  th, td { border-color: #3a3943; }
+}
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
+
+// HTML renders the Markdown export of sess to a self-contained page.
+func HTML(sess session.Session, markdown string) ([]byte, error) {
+	var body bytes.Buffer
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := md.Convert([]byte(markdown), &body); err != nil {
+		return nil, fmt.Errorf("failed to render markdown: %w", err)
+	}
+	title := sess.Title
+	if title == "" {
+		title = "Untitled Session"
+	}
+	// The Markdown renderer escapes the HTML of the session.
+	safeBody := template.HTML(body.String()) //nolint:gosec
+	var out bytes.Buffer
+	if err := page.Execute(&out, struct {
+		Title string
+		Body  template.HTML
+	}{title, safeBody}); err != nil {
+		return nil, fmt.Errorf("failed to render html: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func testSession() (session.Session, []message.Message) {
+	sess := session.Session{
+		ID:                    "s",
+		Title:                 "Fix the <parser> bug!",
+		CreatedAt:             time.Date(2025, 3, 4, 10, 30, 0, 0, time.Local).Unix(),
+		TotalPromptTokens:     1200,
+		TotalCompletionTokens: 300,
+		Cost:                  0.0123,
+	}
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Fix the parser"}}},
+		{Role: message.Assistant, Model: "claude", Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look at it."},
+			message.ToolCall{ID: "1", Name: "bash", Input: `{"command":"cat parser.go"}`, Finished: true},
+			message.ToolCall{ID: "2", Name: "edit", Input: `{"file_path":"parser.go"}`, Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "1", Name: "bash", Content: "package parser\n```go\n```"},
+			message.ToolResult{ToolCallID: "2", Name: "edit", Content: "edited", Metadata: `{"old_content":"a\n","new_content":"b\n"}`},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "Fixed <script>alert(1)</script> it."}}},
+	}
+	return sess, messages
+}
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	sess, messages := testSession()
+	md := Markdown(sess, messages)
+	require.Contains(t, md, "# Fix the <parser> bug!\n")
+	require.Contains(t, md, "- **Tokens:** 1200 input, 300 output\n")
+	require.Contains(t, md, "- **Cost:** $0.0123\n")
+	require.Contains(t, md, "## Assistant (claude)\n\nLet me look at it.\n")
+	require.Contains(t, md, "### Tool: bash\n\n```json\n{\n  \"command\": \"cat parser.go\"\n}\n```\n")
+	require.Contains(t, md, "````\npackage parser\n```go\n```\n````\n", "fences must be longer than the backticks of the content")
+	require.Contains(t, md, "```diff\n")
+	require.Contains(t, md, "-a\n+b\n")
+	require.NotContains(t, md, "edited")
+}
+
+func TestHTML(t *testing.T) {
+	t.Parallel()
+
+	sess, messages := testSession()
+	out, err := Render(sess, messages, FormatHTML)
+	require.NoError(t, err)
+	page := string(out)
+	require.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	require.Contains(t, page, "<title>Fix the &lt;parser&gt; bug!</title>")
+	require.Contains(t, page, "<h3>Tool: bash</h3>")
+	require.NotContains(t, page, "<script>")
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	sess, messages := testSession()
+	name := FileName(sess, FormatMarkdown)
+	require.Equal(t, "crush-fix-the-parser-bug-20250304-103000.md", name)
+
+	path := filepath.Join(t.TempDir(), "exports", name)
+	require.NoError(t, WriteFile(path, sess, messages, FormatMarkdown))
+	require.FileExists(t, path)
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	format, err := ParseFormat("md")
+	require.NoError(t, err)
+	require.Equal(t, FormatMarkdown, format)
+	format, err = ParseFormat("HTML")
+	require.NoError(t, err)
+	require.Equal(t, FormatHTML, format)
+	_, err = ParseFormat("pdf")
+	require.Error(t, err)
+}
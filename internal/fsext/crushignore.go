@@ -0,0 +1,139 @@
+package fsext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/home"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// globalIgnoreCheckInterval is how often the global ignore file is checked
+// for changes, at most.
+const globalIgnoreCheckInterval = time.Second
+
+// globalCrushIgnore holds the patterns of the ignore file applying to every
+// project.
+var globalCrushIgnore = &globalIgnore{path: globalCrushIgnorePath}
+
+// globalCrushIgnorePath returns the path of the ignore file applying to
+// every project: crush/ignore in $XDG_CONFIG_HOME, or else in ~/.config.
+func globalCrushIgnorePath() string {
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+		return filepath.Join(xdgConfigHome, "crush", "ignore")
+	}
+	return filepath.Join(home.Dir(), ".config", "crush", "ignore")
+}
+
+// globalIgnore holds the patterns of an ignore file, read again when it
+// changes.
+type globalIgnore struct {
+	path func() string
+
+	mu      sync.Mutex
+	parser  ignore.IgnoreParser
+	checked time.Time
+	modTime time.Time
+	size    int64
+}
+
+// get returns the patterns of the file, reading it again when it changed
+// since it was last checked.
+func (g *globalIgnore) get() ignore.IgnoreParser {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if g.parser != nil && now.Sub(g.checked) < globalIgnoreCheckInterval {
+		return g.parser
+	}
+	g.checked = now
+
+	path := g.path()
+	info, err := os.Stat(path)
+	if err != nil {
+		g.parser, g.modTime, g.size = ignore.CompileIgnoreLines(), time.Time{}, 0
+		return g.parser
+	}
+	if g.parser != nil && info.ModTime().Equal(g.modTime) && info.Size() == g.size {
+		return g.parser
+	}
+	g.modTime, g.size = info.ModTime(), info.Size()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		g.parser = ignore.CompileIgnoreLines()
+		return g.parser
+	}
+	g.parser = ignore.CompileIgnoreLines(strings.Split(string(content), "\n")...)
+	return g.parser
+}
+
+// CrushIgnore tells the paths excluded by the .crushignore files from a root
+// directory down, and by the global ignore file, so they never reach the
+// model. Unlike the listings, it doesn't follow the .gitignore files, which
+// often exclude files the model is asked to read, like local configurations.
+type CrushIgnore struct {
+	root    string
+	parsers *csync.Map[string, ignore.IgnoreParser]
+}
+
+// NewCrushIgnore returns the CrushIgnore of root. The .crushignore files are
+// read once, when first needed.
+func NewCrushIgnore(root string) *CrushIgnore {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &CrushIgnore{
+		root:    root,
+		parsers: csync.NewMap[string, ignore.IgnoreParser](),
+	}
+}
+
+// Ignores reports whether path, or one of its parent directories, is
+// excluded. Paths ending with a separator are directories. Paths outside of
+// the root are never excluded.
+func (c *CrushIgnore) Ignores(path string) bool {
+	dir := strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.root, path)
+	}
+	relPath, err := filepath.Rel(c.root, path)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for end := 1; end <= len(parts); end++ {
+		candidate := strings.Join(parts[:end], "/")
+		isDir := end < len(parts) || dir
+		if matches(globalCrushIgnore.get(), candidate, isDir) {
+			return true
+		}
+		// Each .crushignore applies to the paths below its directory.
+		for start := range end {
+			parser := c.parser(filepath.Join(c.root, filepath.Join(parts[:start]...)))
+			if matches(parser, strings.Join(parts[start:end], "/"), isDir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *CrushIgnore) parser(dir string) ignore.IgnoreParser {
+	return c.parsers.GetOrSet(dir, func() ignore.IgnoreParser {
+		content, err := os.ReadFile(filepath.Join(dir, ".crushignore"))
+		if err != nil {
+			return ignore.CompileIgnoreLines()
+		}
+		return ignore.CompileIgnoreLines(strings.Split(string(content), "\n")...)
+	})
+}
+
+func matches(parser ignore.IgnoreParser, path string, isDir bool) bool {
+	return parser.MatchesPath(path) || (isDir && parser.MatchesPath(path+"/"))
+}
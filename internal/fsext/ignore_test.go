@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -120,3 +121,45 @@ func TestShouldExcludeFileCommonPatterns(t *testing.T) {
 		require.True(t, ShouldExcludeFile(tempDir, dir), "Expected %s to be ignored by common patterns", filepath.Base(dir))
 	}
 }
+
+func TestCrushIgnoreIgnores(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg", "api"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".crushignore"), []byte("vendor/\n*.pb.go\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", ".crushignore"), []byte("/api\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.env\n"), 0o644))
+
+	ci := NewCrushIgnore(root)
+	require.True(t, ci.Ignores(filepath.Join(root, "vendor", "github.com", "lib", "lib.go")))
+	require.True(t, ci.Ignores("pkg/service.pb.go"))
+	require.True(t, ci.Ignores(filepath.Join(root, "pkg", "api", "handler.go")))
+	require.False(t, ci.Ignores(filepath.Join(root, "api", "handler.go")), "patterns anchored to a nested .crushignore apply below it only")
+	require.False(t, ci.Ignores(filepath.Join(root, "pkg", "service.go")))
+	require.False(t, ci.Ignores(filepath.Join(root, "local.env")), ".gitignore patterns don't apply")
+	require.False(t, ci.Ignores(filepath.Join(filepath.Dir(root), "vendor", "lib.go")), "paths outside the root are never ignored")
+}
+
+func TestGlobalIgnore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ignore")
+	g := &globalIgnore{path: func() string { return path }}
+	require.False(t, g.get().MatchesPath("secrets.txt"), "a missing file ignores nothing")
+
+	require.NoError(t, os.WriteFile(path, []byte("secrets.txt\n"), 0o644))
+	require.False(t, g.get().MatchesPath("secrets.txt"), "the file isn't checked again right away")
+	g.checked = time.Time{}
+	require.True(t, g.get().MatchesPath("secrets.txt"))
+
+	require.NoError(t, os.WriteFile(path, []byte("*.key\n"), 0o644))
+	g.checked = time.Time{}
+	require.False(t, g.get().MatchesPath("secrets.txt"))
+	require.True(t, g.get().MatchesPath("server.key"), "changes are read again")
+}
+
+func TestGlobalCrushIgnorePath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/etc/xdg")
+	require.Equal(t, filepath.Join("/etc/xdg", "crush", "ignore"), globalCrushIgnorePath())
+}
@@ -0,0 +1,71 @@
+package github
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiffLines returns the lines of the new versions of the files of a unified
+// diff, by path, that review comments can be made on: the added lines and
+// the context lines around them.
+func DiffLines(diff string) map[string]map[int]bool {
+	lines := make(map[string]map[int]bool)
+	var path string
+	var line int
+	inHunk := false
+	for text := range strings.Lines(diff) {
+		text = strings.TrimRight(text, "\r\n")
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			path, inHunk = "", false
+		case !inHunk && strings.HasPrefix(text, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			if path == "/dev/null" {
+				path = ""
+			}
+		case strings.HasPrefix(text, "@@ "):
+			line, inHunk = hunkStart(text), true
+		case !inHunk || path == "":
+		case strings.HasPrefix(text, "+"), strings.HasPrefix(text, " "), text == "":
+			if lines[path] == nil {
+				lines[path] = make(map[int]bool)
+			}
+			lines[path][line] = true
+			line++
+		}
+	}
+	return lines
+}
+
+// hunkStart returns the first line of the new version of a file in a hunk
+// header such as "@@ -1,4 +1,6 @@".
+func hunkStart(header string) int {
+	for field := range strings.FieldsSeq(header) {
+		if rest, ok := strings.CutPrefix(field, "+"); ok {
+			start, _, _ := strings.Cut(rest, ",")
+			n, _ := strconv.Atoi(start)
+			return n
+		}
+	}
+	return 0
+}
+
+// OnDiff returns the review with its comments on lines outside of diff
+// moved to its body, as GitHub rejects the reviews with such comments.
+func (r Review) OnDiff(diff string) Review {
+	lines := DiffLines(diff)
+	result := Review{Body: r.Body}
+	var outside []string
+	for _, comment := range r.Comments {
+		if lines[comment.Path][comment.Line] {
+			result.Comments = append(result.Comments, comment)
+			continue
+		}
+		outside = append(outside, fmt.Sprintf("- `%s:%d`: %s", comment.Path, comment.Line, comment.Body))
+	}
+	if len(outside) > 0 {
+		result.Body = strings.TrimSpace(result.Body + "\n\n" + strings.Join(outside, "\n"))
+	}
+	return result
+}
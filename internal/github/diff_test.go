@@ -0,0 +1,52 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+-import "fmt"
++import (
++	"fmt"
++)
+ func main() {
+@@ -20,2 +21,2 @@ func run() {
+-	return nil
++	return err
+ }
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package old
+`
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, map[string]map[int]bool{
+		"main.go": {1: true, 2: true, 3: true, 4: true, 5: true, 21: true, 22: true},
+	}, DiffLines(testDiff))
+}
+
+func TestReviewOnDiff(t *testing.T) {
+	t.Parallel()
+
+	review := Review{Body: "Mostly fine.", Comments: []ReviewComment{
+		{Path: "main.go", Line: 21, Body: "Wrap the error."},
+		{Path: "main.go", Line: 10, Body: "Unrelated."},
+		{Path: "old.go", Line: 1, Body: "Still used?"},
+	}}
+	require.Equal(t, Review{
+		Body:     "Mostly fine.\n\n- `main.go:10`: Unrelated.\n- `old.go:1`: Still used?",
+		Comments: []ReviewComment{{Path: "main.go", Line: 21, Body: "Wrap the error."}},
+	}, review.OnDiff(testDiff))
+}
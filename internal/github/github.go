@@ -0,0 +1,347 @@
+// Package github is a small client of the GitHub REST API, to fetch issues
+// and pull requests and post reviews of them.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiURL is the base URL of the GitHub REST API.
+const apiURL = "https://api.github.com"
+
+// ErrNoAccess is returned when GitHub refuses a request, or answers as if
+// what it asks for doesn't exist, which it does for the private repositories
+// the token can't see.
+var ErrNoAccess = errors.New("the token can't access it, or it doesn't exist: set GH_TOKEN to a token with access to the repository")
+
+// Client calls the GitHub REST API with an OAuth or personal access token.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client authenticating with token.
+func NewClient(token string) *Client {
+	return &Client{
+		baseURL: apiURL,
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// PullRequest is a pull request with its diff and the comments made on it
+// so far.
+type PullRequest struct {
+	Repo     string
+	Number   int
+	URL      string
+	Title    string
+	Body     string
+	Author   string
+	BaseRef  string
+	HeadRef  string
+	HeadSHA  string
+	Diff     string
+	Comments []Comment
+}
+
+// Issue is an issue with the comments made on it so far.
+type Issue struct {
+	Repo     string
+	Number   int
+	URL      string
+	Title    string
+	Body     string
+	Author   string
+	State    string
+	Labels   []string
+	Comments []Comment
+}
+
+// Comment is a comment on an issue or a pull request. Path and Line are set for review
+// comments on the diff.
+type Comment struct {
+	Author string
+	Body   string
+	Path   string
+	Line   int
+}
+
+// Review is a review to post on a pull request.
+type Review struct {
+	Body     string          `json:"body"`
+	Comments []ReviewComment `json:"comments"`
+}
+
+// ReviewComment is a comment of a review on a line of the new version of a
+// file.
+type ReviewComment struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Body string `json:"body"`
+}
+
+var refPattern = regexp.MustCompile(`^(?:(?:https?://)?github\.com/)?(?:([\w.-]+/[\w.-]+)(?:#|/pull/|/issues/))?(\d+)(?:/[\w/]*)?$`)
+
+// ParseRef parses a reference to an issue or a pull request: its number,
+// owner/name#number or its URL. The repository is empty when only the number
+// is given.
+func ParseRef(ref string) (repo string, number int, err error) {
+	m := refPattern.FindStringSubmatch(strings.TrimSpace(ref))
+	if m == nil {
+		return "", 0, fmt.Errorf("invalid reference %q, expected a number, owner/name#number or a URL", ref)
+	}
+	number, err = strconv.Atoi(m[2])
+	if err != nil || number <= 0 {
+		return "", 0, fmt.Errorf("invalid issue or pull request number %q", m[2])
+	}
+	return m[1], number, nil
+}
+
+// Issue fetches an issue of repo, in owner/name form, with its comments.
+func (c *Client) Issue(ctx context.Context, repo string, number int) (*Issue, error) {
+	var issue struct {
+		HTMLURL string `json:"html_url"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		User    user   `json:"user"`
+		State   string `json:"state"`
+		Labels  []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	if err := c.do(ctx, "GET", fmt.Sprintf("/repos/%s/issues/%d", repo, number), "", nil, &issue); err != nil {
+		return nil, fmt.Errorf("failed to fetch issue: %w", err)
+	}
+	comments, err := c.issueComments(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Issue{
+		Repo:     repo,
+		Number:   number,
+		URL:      issue.HTMLURL,
+		Title:    issue.Title,
+		Body:     issue.Body,
+		Author:   issue.User.Login,
+		State:    issue.State,
+		Comments: comments,
+	}
+	for _, label := range issue.Labels {
+		result.Labels = append(result.Labels, label.Name)
+	}
+	return result, nil
+}
+
+// issueComments fetches the comments of an issue or a pull request, outside
+// of reviews.
+func (c *Client) issueComments(ctx context.Context, repo string, number int) ([]Comment, error) {
+	comments, err := list[struct {
+		User user   `json:"user"`
+		Body string `json:"body"`
+	}](ctx, c, fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %w", err)
+	}
+	var result []Comment
+	for _, comment := range comments {
+		result = append(result, Comment{Author: comment.User.Login, Body: comment.Body})
+	}
+	return result, nil
+}
+
+// PullRequest fetches a pull request of repo, in owner/name form, with its
+// diff and comments.
+func (c *Client) PullRequest(ctx context.Context, repo string, number int) (*PullRequest, error) {
+	path := fmt.Sprintf("/repos/%s/pulls/%d", repo, number)
+
+	var pr struct {
+		HTMLURL string `json:"html_url"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		User    user   `json:"user"`
+		Base    struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := c.do(ctx, "GET", path, "", nil, &pr); err != nil {
+		return nil, fmt.Errorf("failed to fetch pull request: %w", err)
+	}
+
+	var diff bytes.Buffer
+	if err := c.do(ctx, "GET", path, "application/vnd.github.diff", nil, &diff); err != nil {
+		return nil, fmt.Errorf("failed to fetch pull request diff: %w", err)
+	}
+
+	comments, err := c.issueComments(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	reviewComments, err := list[struct {
+		User user   `json:"user"`
+		Body string `json:"body"`
+		Path string `json:"path"`
+		Line int    `json:"line"`
+	}](ctx, c, path+"/comments")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pull request review comments: %w", err)
+	}
+
+	result := &PullRequest{
+		Repo:     repo,
+		Number:   number,
+		URL:      pr.HTMLURL,
+		Title:    pr.Title,
+		Body:     pr.Body,
+		Author:   pr.User.Login,
+		BaseRef:  pr.Base.Ref,
+		HeadRef:  pr.Head.Ref,
+		HeadSHA:  pr.Head.SHA,
+		Diff:     diff.String(),
+		Comments: comments,
+	}
+	for _, comment := range reviewComments {
+		result.Comments = append(result.Comments, Comment{
+			Author: comment.User.Login,
+			Body:   comment.Body,
+			Path:   comment.Path,
+			Line:   comment.Line,
+		})
+	}
+	return result, nil
+}
+
+// CreateReview posts review as a comment-only review of the head commit of
+// pr, returning the URL of the review.
+func (c *Client) CreateReview(ctx context.Context, pr *PullRequest, review Review) (string, error) {
+	body := struct {
+		CommitID string          `json:"commit_id,omitempty"`
+		Event    string          `json:"event"`
+		Body     string          `json:"body"`
+		Comments []ReviewComment `json:"comments,omitempty"`
+	}{
+		CommitID: pr.HeadSHA,
+		Event:    "COMMENT",
+		Body:     review.Body,
+		Comments: review.Comments,
+	}
+	var result struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(ctx, "POST", fmt.Sprintf("/repos/%s/pulls/%d/reviews", pr.Repo, pr.Number), "", body, &result); err != nil {
+		return "", fmt.Errorf("failed to post review: %w", err)
+	}
+	return result.HTMLURL, nil
+}
+
+type user struct {
+	Login string `json:"login"`
+}
+
+// nextLink matches the URL of the next page in a Link header.
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// list fetches every page of the list at path, 100 items at a time.
+func list[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	var items []T
+	url := c.baseURL + path + "?per_page=100"
+	for url != "" {
+		resp, err := c.send(ctx, "GET", url, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		var page []T
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		url = ""
+		if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			url = m[1]
+		}
+	}
+	return items, nil
+}
+
+// do sends a request to the API and decodes the response into result, or
+// copies it as is when result is a *bytes.Buffer.
+func (c *Client) do(ctx context.Context, method, path, accept string, body, result any) error {
+	resp, err := c.send(ctx, method, c.baseURL+path, accept, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if buf, ok := result.(*bytes.Buffer); ok {
+		_, err = buf.ReadFrom(resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// send sends a request to url, returning the response when it succeeded.
+func (c *Client) send(ctx context.Context, method, url, accept string, body any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return nil, fmt.Errorf("github returned %s: %s: %w", resp.Status, apiErr.Message, ErrNoAccess)
+	}
+	return nil, fmt.Errorf("github returned %s: %s", resp.Status, apiErr.Message)
+}
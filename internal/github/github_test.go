@@ -0,0 +1,127 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRef(t *testing.T) {
+	t.Parallel()
+
+	for ref, want := range map[string]struct {
+		repo   string
+		number int
+	}{
+		"42":                     {"", 42},
+		"#42":                    {},
+		"charmbracelet/crush#42": {"charmbracelet/crush", 42},
+		"https://github.com/charmbracelet/crush/pull/42":  {"charmbracelet/crush", 42},
+		"github.com/charmbracelet/crush/pull/42/files":    {"charmbracelet/crush", 42},
+		"https://github.com/charmbracelet/crush/issues/7": {"charmbracelet/crush", 7},
+		"charmbracelet/crush":                             {},
+		"0":                                               {},
+	} {
+		repo, number, err := ParseRef(ref)
+		if want.number == 0 {
+			require.Error(t, err, ref)
+			continue
+		}
+		require.NoError(t, err, ref)
+		require.Equal(t, want.repo, repo, ref)
+		require.Equal(t, want.number, number, ref)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/charmbracelet/crush/issues/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"html_url":"https://github.com/charmbracelet/crush/issues/7","title":"Crash on empty config","body":"It panics.","user":{"login":"alice"},"state":"open","labels":[{"name":"bug"}]}`))
+	})
+	var server *httptest.Server
+	mux.HandleFunc("GET /repos/charmbracelet/crush/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "100", r.URL.Query().Get("per_page"))
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"user":{"login":"carol"},"body":"Fixed in main."}]`))
+			return
+		}
+		w.Header().Set("Link", `<`+server.URL+`/repos/charmbracelet/crush/issues/7/comments?per_page=100&page=2>; rel="next", <`+server.URL+`/repos/charmbracelet/crush/issues/7/comments?per_page=100&page=2>; rel="last"`)
+		w.Write([]byte(`[{"user":{"login":"bob"},"body":"In config/load.go."}]`))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	client := &Client{baseURL: server.URL, http: server.Client()}
+
+	issue, err := client.Issue(t.Context(), "charmbracelet/crush", 7)
+	require.NoError(t, err)
+	require.Equal(t, &Issue{
+		Repo:     "charmbracelet/crush",
+		Number:   7,
+		URL:      "https://github.com/charmbracelet/crush/issues/7",
+		Title:    "Crash on empty config",
+		Body:     "It panics.",
+		Author:   "alice",
+		State:    "open",
+		Labels:   []string{"bug"},
+		Comments: []Comment{{Author: "bob", Body: "In config/load.go."}, {Author: "carol", Body: "Fixed in main."}},
+	}, issue)
+}
+
+func TestPullRequest(t *testing.T) {
+	t.Parallel()
+
+	var posted map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/charmbracelet/crush/pulls/42", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer gho_token", r.Header.Get("Authorization"))
+		if r.Header.Get("Accept") == "application/vnd.github.diff" {
+			w.Write([]byte("diff --git a/main.go b/main.go\n"))
+			return
+		}
+		w.Write([]byte(`{"html_url":"https://github.com/charmbracelet/crush/pull/42","title":"Add a parser","body":"Parses.","user":{"login":"alice"},"base":{"ref":"main"},"head":{"ref":"parser","sha":"abc123"}}`))
+	})
+	mux.HandleFunc("GET /repos/charmbracelet/crush/issues/42/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"user":{"login":"bob"},"body":"Nice."}]`))
+	})
+	mux.HandleFunc("GET /repos/charmbracelet/crush/pulls/42/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"user":{"login":"bob"},"body":"Typo.","path":"main.go","line":3}]`))
+	})
+	mux.HandleFunc("POST /repos/charmbracelet/crush/pulls/42/reviews", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+		w.Write([]byte(`{"html_url":"https://github.com/charmbracelet/crush/pull/42#pullrequestreview-1"}`))
+	})
+	mux.HandleFunc("GET /repos/charmbracelet/crush/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	client := &Client{baseURL: server.URL, token: "gho_token", http: server.Client()}
+
+	pr, err := client.PullRequest(t.Context(), "charmbracelet/crush", 42)
+	require.NoError(t, err)
+	require.Equal(t, "Add a parser", pr.Title)
+	require.Equal(t, "alice", pr.Author)
+	require.Equal(t, "abc123", pr.HeadSHA)
+	require.Equal(t, "diff --git a/main.go b/main.go\n", pr.Diff)
+	require.Equal(t, []Comment{
+		{Author: "bob", Body: "Nice."},
+		{Author: "bob", Body: "Typo.", Path: "main.go", Line: 3},
+	}, pr.Comments)
+
+	url, err := client.CreateReview(t.Context(), pr, Review{Body: "Looks good.", Comments: []ReviewComment{{Path: "main.go", Line: 3, Body: "Check the error."}}})
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/charmbracelet/crush/pull/42#pullrequestreview-1", url)
+	require.Equal(t, "COMMENT", posted["event"])
+	require.Equal(t, "abc123", posted["commit_id"])
+	require.Len(t, posted["comments"], 1)
+
+	_, err = client.PullRequest(t.Context(), "charmbracelet/crush", 7)
+	require.ErrorContains(t, err, "404 Not Found: Not Found")
+	require.ErrorIs(t, err, ErrNoAccess)
+}
@@ -0,0 +1,50 @@
+// Package httpext queries the JSON APIs of the servers crush talks to
+// outside of the model providers.
+package httpext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DoJSON sends a request to url with body encoded as JSON, when set, and
+// decodes the JSON response into result. name is the name of the server,
+// for the errors.
+func DoJSON(ctx context.Context, name, method, url string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s request: %w", name, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", name, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to query %s: %s", name, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", name, err)
+	}
+	return nil
+}
+
+// GetJSON gets url and decodes its JSON response into result.
+func GetJSON(ctx context.Context, name, url string, result any) error {
+	return DoJSON(ctx, name, http.MethodGet, url, nil, result)
+}
@@ -0,0 +1,31 @@
+package httpext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoJSON(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/echo" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(map[string]string{"method": r.Method, "model": body["model"]})
+	}))
+	t.Cleanup(server.Close)
+
+	var result map[string]string
+	require.NoError(t, DoJSON(t.Context(), "echo", http.MethodPost, server.URL+"/echo", map[string]string{"model": "qwen3"}, &result))
+	require.Equal(t, map[string]string{"method": "POST", "model": "qwen3"}, result)
+
+	require.EqualError(t, GetJSON(t.Context(), "echo", server.URL+"/missing", &result), "failed to query echo: 404 Not Found")
+}
@@ -0,0 +1,172 @@
+// Package instance keeps a single Crush working on the database of a
+// project: the first one started holds a lock file and listens on a unix
+// socket, which the ones started after it attach to.
+package instance
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// LockFile is the name of the lock file in the data directory.
+const LockFile = "crush.lock"
+
+// dialTimeout is how long the socket of the instance holding the lock has
+// to answer before the lock is deemed stale.
+const dialTimeout = time.Second
+
+// ErrNotRunning is returned by Find when no instance runs in the project.
+var ErrNotRunning = errors.New("crush isn't running in this project")
+
+// Info describes the instance holding the lock of a project.
+type Info struct {
+	PID       int       `json:"pid"`
+	Socket    string    `json:"socket"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// RunningError is returned by Acquire when another instance holds the lock.
+type RunningError struct {
+	Info
+}
+
+func (e *RunningError) Error() string {
+	return fmt.Sprintf("crush is already running in this project (pid %d)", e.PID)
+}
+
+// Lock is the lock of a project, held until released.
+type Lock struct {
+	Info
+	path     string
+	listener net.Listener
+}
+
+// Listener returns the listener of the socket the other instances attach
+// to.
+func (l *Lock) Listener() net.Listener {
+	return l.listener
+}
+
+// Acquire takes the lock of the project whose data is in dataDir and
+// listens on its socket. It returns a *RunningError when another instance
+// holds it, and takes over the locks left by instances that exited without
+// releasing them.
+func Acquire(dataDir string) (*Lock, error) {
+	dataDir, err := filepath.Abs(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+	path := filepath.Join(dataDir, LockFile)
+	socket := socketPath(dataDir)
+	info := Info{PID: os.Getpid(), Socket: socket, StartedAt: time.Now()}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lock: %w", err)
+	}
+
+	// The lock is written aside and linked in place, so that it's never
+	// seen half written.
+	tmp := path + "." + strconv.Itoa(info.PID)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	linked := false
+	for range 2 {
+		err := os.Link(tmp, path)
+		if err == nil {
+			linked = true
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+		held, err := Find(dataDir)
+		if err == nil {
+			return nil, &RunningError{held}
+		}
+		if !errors.Is(err, ErrNotRunning) {
+			return nil, err
+		}
+		// The instance holding it exited without releasing it.
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+		}
+	}
+	if !linked {
+		return nil, errors.New("failed to create lock file: another instance is starting")
+	}
+
+	// Remove the socket left by an instance that exited.
+	if err := os.Remove(socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to listen on socket: %w", err)
+	}
+	return &Lock{Info: info, path: path, listener: listener}, nil
+}
+
+// Release closes the socket and removes the lock file, unless it was taken
+// over in the meantime.
+func (l *Lock) Release() error {
+	err := l.listener.Close()
+	if held, readErr := read(l.path); readErr == nil && held.PID == l.PID && held.StartedAt.Equal(l.StartedAt) {
+		if removeErr := os.Remove(l.path); removeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to remove lock file: %w", removeErr))
+		}
+	}
+	return err
+}
+
+// Find returns the instance running in the project whose data is in
+// dataDir, or ErrNotRunning when there's none: no lock file, or a lock file
+// whose socket doesn't answer.
+func Find(dataDir string) (Info, error) {
+	info, err := read(filepath.Join(dataDir, LockFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, ErrNotRunning
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	conn, err := net.DialTimeout("unix", info.Socket, dialTimeout)
+	if err != nil {
+		return Info{}, ErrNotRunning
+	}
+	conn.Close()
+	return info, nil
+}
+
+func read(path string) (Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		// Not written by Acquire, it can only be stale.
+		return Info{}, ErrNotRunning
+	}
+	return info, nil
+}
+
+// socketPath returns the path of the socket of the project whose data is in
+// the absolute dataDir. It's kept in the temporary directory, as the paths
+// of unix sockets are limited to about a hundred bytes.
+func socketPath(dataDir string) string {
+	sum := sha256.Sum256([]byte(dataDir))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("crush-%x.sock", sum[:8]))
+}
@@ -0,0 +1,55 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	_, err := Find(dataDir)
+	require.ErrorIs(t, err, ErrNotRunning)
+
+	lock, err := Acquire(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), lock.PID)
+
+	_, err = Acquire(dataDir)
+	var running *RunningError
+	require.True(t, errors.As(err, &running), "the lock is held")
+	require.Equal(t, lock.Info.Socket, running.Socket)
+	found, err := Find(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, lock.PID, found.PID)
+
+	require.NoError(t, lock.Release())
+	_, err = Find(dataDir)
+	require.ErrorIs(t, err, ErrNotRunning)
+	require.NoFileExists(t, filepath.Join(dataDir, LockFile))
+}
+
+func TestAcquireStale(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	// Left by an instance that crashed.
+	data, err := json.Marshal(Info{PID: 1, Socket: filepath.Join(dataDir, "gone.sock")})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, LockFile), data, 0o600))
+	_, err = Find(dataDir)
+	require.ErrorIs(t, err, ErrNotRunning)
+
+	lock, err := Acquire(dataDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { lock.Release() })
+	found, err := Find(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), found.PID)
+}
@@ -0,0 +1,60 @@
+// Package llamacpp discovers the model served by a running llama.cpp server.
+package llamacpp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/httpext"
+)
+
+const (
+	// ProviderID is the identifier of the llama.cpp provider.
+	ProviderID = "llamacpp"
+	// DefaultHost is the address llama-server listens on by default.
+	DefaultHost = "http://localhost:8080"
+)
+
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+type propsResponse struct {
+	DefaultGenerationSettings *struct {
+		NCtx int64 `json:"n_ctx"`
+	} `json:"default_generation_settings"`
+	Modalities struct {
+		Vision bool `json:"vision"`
+	} `json:"modalities"`
+}
+
+// FetchModels returns the model served by the llama.cpp server at host, with
+// the context size it was started with.
+func FetchModels(ctx context.Context, host string) ([]catwalk.Model, error) {
+	// Other servers listen on the same port, the properties tell them apart.
+	var props propsResponse
+	if err := httpext.GetJSON(ctx, "llama.cpp", host+"/props", &props); err != nil {
+		return nil, err
+	}
+	if props.DefaultGenerationSettings == nil {
+		return nil, fmt.Errorf("not a llama.cpp server: %s", host)
+	}
+	var models modelsResponse
+	if err := httpext.GetJSON(ctx, "llama.cpp", host+"/v1/models", &models); err != nil {
+		return nil, err
+	}
+
+	var result []catwalk.Model
+	for _, m := range models.Data {
+		result = append(result, catwalk.Model{
+			ID:             m.ID,
+			Name:           m.ID,
+			ContextWindow:  props.DefaultGenerationSettings.NCtx,
+			SupportsImages: props.Modalities.Vision,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package llamacpp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchModels(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/props":
+			_, _ = w.Write([]byte(`{"default_generation_settings": {"n_ctx": 32768}, "modalities": {"vision": false}}`))
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"object": "list", "data": [{"id": "Qwen3-Coder-30B-A3B-Instruct-Q4_K_M.gguf", "object": "model"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	models, err := FetchModels(t.Context(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, []catwalk.Model{{
+		ID:            "Qwen3-Coder-30B-A3B-Instruct-Q4_K_M.gguf",
+		Name:          "Qwen3-Coder-30B-A3B-Instruct-Q4_K_M.gguf",
+		ContextWindow: 32768,
+	}}, models)
+
+	// Other servers on the same port aren't taken for llama.cpp.
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name": "dev server"}`))
+	}))
+	t.Cleanup(other.Close)
+	_, err = FetchModels(t.Context(), other.URL)
+	require.Error(t, err)
+}
@@ -0,0 +1,54 @@
+// Package lmstudio discovers the models loaded in a running LM Studio server.
+package lmstudio
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/httpext"
+)
+
+const (
+	// ProviderID is the identifier of the LM Studio provider.
+	ProviderID = "lmstudio"
+	// DefaultHost is the address LM Studio serves on by default.
+	DefaultHost = "http://localhost:1234"
+)
+
+type modelsResponse struct {
+	Data []struct {
+		ID               string   `json:"id"`
+		Type             string   `json:"type"`
+		State            string   `json:"state"`
+		MaxContextLength int64    `json:"max_context_length"`
+		Capabilities     []string `json:"capabilities"`
+	} `json:"data"`
+}
+
+// FetchModels returns the language models loaded in the LM Studio server at
+// host, with their context lengths.
+func FetchModels(ctx context.Context, host string) ([]catwalk.Model, error) {
+	var models modelsResponse
+	if err := httpext.GetJSON(ctx, "lm studio", host+"/api/v0/models", &models); err != nil {
+		return nil, err
+	}
+
+	var result []catwalk.Model
+	for _, m := range models.Data {
+		// Embedding models can't chat, and the others are loaded on
+		// demand, which takes longer than Crush waits for an answer.
+		if m.State != "loaded" || (m.Type != "llm" && m.Type != "vlm") {
+			continue
+		}
+		result = append(result, catwalk.Model{
+			ID:             m.ID,
+			Name:           m.ID,
+			ContextWindow:  m.MaxContextLength,
+			SupportsImages: m.Type == "vlm",
+		})
+	}
+	slices.SortFunc(result, func(a, b catwalk.Model) int { return strings.Compare(a.ID, b.ID) })
+	return result, nil
+}
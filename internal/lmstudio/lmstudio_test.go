@@ -0,0 +1,35 @@
+package lmstudio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchModels(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v0/models" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [
+			{"id": "qwen3-coder-30b", "type": "llm", "state": "loaded", "max_context_length": 262144},
+			{"id": "gemma-3-12b", "type": "vlm", "state": "loaded", "max_context_length": 131072},
+			{"id": "devstral-small", "type": "llm", "state": "not-loaded", "max_context_length": 131072},
+			{"id": "text-embedding-nomic", "type": "embeddings", "state": "loaded", "max_context_length": 2048}
+		]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	models, err := FetchModels(t.Context(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, []catwalk.Model{
+		{ID: "gemma-3-12b", Name: "gemma-3-12b", ContextWindow: 131072, SupportsImages: true},
+		{ID: "qwen3-coder-30b", Name: "qwen3-coder-30b", ContextWindow: 262144},
+	}, models)
+}
@@ -8,9 +8,11 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
@@ -36,6 +38,9 @@ type Client struct {
 
 	// Diagnostic cache
 	diagnostics *csync.VersionedMap[protocol.DocumentURI, []protocol.Diagnostic]
+	// Version of the diagnostics cache when each file's diagnostics were last
+	// published.
+	diagnosticsVersions *csync.Map[protocol.DocumentURI, uint64]
 
 	// Files are currently opened by the LSP
 	openFiles *csync.Map[string, *OpenFileInfo]
@@ -86,12 +91,13 @@ func New(ctx context.Context, name string, config config.LSPConfig, resolver con
 	}
 
 	client := &Client{
-		client:      powernapClient,
-		name:        name,
-		fileTypes:   config.FileTypes,
-		diagnostics: csync.NewVersionedMap[protocol.DocumentURI, []protocol.Diagnostic](),
-		openFiles:   csync.NewMap[string, *OpenFileInfo](),
-		config:      config,
+		client:              powernapClient,
+		name:                name,
+		fileTypes:           config.FileTypes,
+		diagnostics:         csync.NewVersionedMap[protocol.DocumentURI, []protocol.Diagnostic](),
+		diagnosticsVersions: csync.NewMap[protocol.DocumentURI, uint64](),
+		openFiles:           csync.NewMap[string, *OpenFileInfo](),
+		config:              config,
 	}
 
 	// Initialize server state
@@ -425,22 +431,29 @@ func (c *Client) openKeyConfigFiles(ctx context.Context) {
 	}
 }
 
-// WaitForDiagnostics waits until diagnostics change or the timeout is reached.
-func (c *Client) WaitForDiagnostics(ctx context.Context, d time.Duration) {
-	ticker := time.NewTicker(200 * time.Millisecond)
+// DiagnosticsVersion returns the version of the diagnostics, which changes
+// every time the server publishes some.
+func (c *Client) DiagnosticsVersion() uint64 {
+	return c.diagnostics.Version()
+}
+
+// WaitForFileDiagnostics waits until the server publishes diagnostics for the
+// file after the given diagnostics version, or the timeout is reached.
+func (c *Client) WaitForFileDiagnostics(ctx context.Context, filepath string, since uint64, d time.Duration) {
+	uri := protocol.URIFromPath(filepath)
+	ticker := time.NewTicker(50 * time.Millisecond)
 	defer ticker.Stop()
 	timeout := time.After(d)
-	pv := c.diagnostics.Version()
 	for {
+		if v, ok := c.diagnosticsVersions.Get(uri); ok && v > since {
+			return
+		}
 		select {
 		case <-ctx.Done():
 			return
 		case <-timeout:
 			return
 		case <-ticker.C:
-			if pv != c.diagnostics.Version() {
-				return
-			}
 		}
 	}
 }
@@ -455,6 +468,140 @@ func (c *Client) FindReferences(ctx context.Context, filepath string, line, char
 	return c.client.FindReferences(ctx, filepath, line-1, character-1, includeDeclaration)
 }
 
+// FindDefinitions finds where the symbol at the given position is declared.
+func (c *Client) FindDefinitions(ctx context.Context, filepath string, line, character int) ([]protocol.Location, error) {
+	if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+		return nil, err
+	}
+	params := protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filepath)},
+			Position:     protocol.Position{Line: uint32(line - 1), Character: uint32(character - 1)},
+		},
+	}
+	var result json.RawMessage
+	if err := c.call(ctx, powernap.MethodTextDocumentDefinition, params, &result); err != nil {
+		return nil, fmt.Errorf("find definitions request failed: %w", err)
+	}
+	return parseLocations(result)
+}
+
+// WorkspaceSymbols finds the symbols of the workspace matching query.
+func (c *Client) WorkspaceSymbols(ctx context.Context, query string) ([]protocol.SymbolInformation, error) {
+	var result []protocol.SymbolInformation
+	if err := c.call(ctx, methodWorkspaceSymbol, protocol.WorkspaceSymbolParams{Query: query}, &result); err != nil {
+		return nil, fmt.Errorf("workspace symbols request failed: %w", err)
+	}
+	return result, nil
+}
+
+// DocumentSymbols lists the symbols declared in the file, the ones nested in
+// others having them as their container.
+func (c *Client) DocumentSymbols(ctx context.Context, filepath string) ([]protocol.SymbolInformation, error) {
+	if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+		return nil, err
+	}
+	uri := protocol.URIFromPath(filepath)
+	params := protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}
+	var result json.RawMessage
+	if err := c.call(ctx, methodTextDocumentDocumentSymbol, params, &result); err != nil {
+		return nil, fmt.Errorf("document symbols request failed: %w", err)
+	}
+	return parseDocumentSymbols(uri, result)
+}
+
+const (
+	methodWorkspaceSymbol            = "workspace/symbol"
+	methodTextDocumentDocumentSymbol = "textDocument/documentSymbol"
+)
+
+// call sends a request the powernap client has no method for on its
+// connection to the server.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	if !c.client.IsInitialized() {
+		return fmt.Errorf("client not initialized")
+	}
+	field := reflect.ValueOf(c.client).Elem().FieldByName("conn")
+	conn := *(**transport.Connection)(unsafe.Pointer(field.UnsafeAddr()))
+	if conn == nil {
+		return fmt.Errorf("client not connected")
+	}
+	return conn.Call(ctx, method, params, result)
+}
+
+// parseLocations decodes the result of a definition request: a location,
+// a list of them, or a list of links to them.
+func parseLocations(result json.RawMessage) ([]protocol.Location, error) {
+	if len(result) == 0 || string(result) == "null" {
+		return nil, nil
+	}
+	var location protocol.Location
+	if err := json.Unmarshal(result, &location); err == nil && location.URI != "" {
+		return []protocol.Location{location}, nil
+	}
+	var items []struct {
+		protocol.Location
+		TargetURI            protocol.DocumentURI `json:"targetUri"`
+		TargetSelectionRange protocol.Range       `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(result, &items); err != nil {
+		return nil, fmt.Errorf("unexpected definition result: %w", err)
+	}
+	locations := make([]protocol.Location, 0, len(items))
+	for _, item := range items {
+		if item.TargetURI != "" {
+			item.Location = protocol.Location{URI: item.TargetURI, Range: item.TargetSelectionRange}
+		}
+		locations = append(locations, item.Location)
+	}
+	return locations, nil
+}
+
+// parseDocumentSymbols decodes the result of a document symbol request,
+// flattening the tree of symbols it may be.
+func parseDocumentSymbols(uri protocol.DocumentURI, result json.RawMessage) ([]protocol.SymbolInformation, error) {
+	if len(result) == 0 || string(result) == "null" {
+		return nil, nil
+	}
+	var items []struct {
+		protocol.SymbolInformation
+		SelectionRange *protocol.Range           `json:"selectionRange"`
+		Children       []protocol.DocumentSymbol `json:"children"`
+	}
+	if err := json.Unmarshal(result, &items); err != nil {
+		return nil, fmt.Errorf("unexpected document symbol result: %w", err)
+	}
+
+	var symbols []protocol.SymbolInformation
+	var walk func(sym protocol.DocumentSymbol, container string)
+	walk = func(sym protocol.DocumentSymbol, container string) {
+		symbols = append(symbols, protocol.SymbolInformation{
+			Name:          sym.Name,
+			Kind:          sym.Kind,
+			ContainerName: container,
+			Location:      protocol.Location{URI: uri, Range: sym.SelectionRange},
+		})
+		for _, child := range sym.Children {
+			walk(child, sym.Name)
+		}
+	}
+	for _, item := range items {
+		if item.SelectionRange == nil {
+			symbols = append(symbols, item.SymbolInformation)
+			continue
+		}
+		walk(protocol.DocumentSymbol{
+			Name:           item.Name,
+			Kind:           item.Kind,
+			SelectionRange: *item.SelectionRange,
+			Children:       item.Children,
+		}, "")
+	}
+	return symbols, nil
+}
+
 // HasRootMarkers checks if any of the specified root marker patterns exist in the given directory.
 // Uses glob patterns to match files, allowing for more flexible matching.
 func HasRootMarkers(dir string, rootMarkers []string) bool {
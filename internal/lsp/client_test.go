@@ -1,11 +1,23 @@
 package lsp
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClient(t *testing.T) {
@@ -55,3 +67,146 @@ func TestClient(t *testing.T) {
 		t.Logf("Close failed as expected with dummy command: %v", err)
 	}
 }
+
+func TestClientWaitForFileDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{
+		diagnostics:         csync.NewVersionedMap[protocol.DocumentURI, []protocol.Diagnostic](),
+		diagnosticsVersions: csync.NewMap[protocol.DocumentURI, uint64](),
+	}
+	publish := func(path, message string) {
+		params, err := json.Marshal(protocol.PublishDiagnosticsParams{
+			URI:         protocol.URIFromPath(path),
+			Diagnostics: []protocol.Diagnostic{{Message: message}},
+		})
+		require.NoError(t, err)
+		HandleDiagnostics(client, params)
+	}
+
+	// Diagnostics published before the change don't count.
+	publish("/tmp/main.go", "stale")
+	version := client.DiagnosticsVersion()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		publish("/tmp/other.go", "other file")
+		time.Sleep(50 * time.Millisecond)
+		publish("/tmp/main.go", "fresh")
+	}()
+	client.WaitForFileDiagnostics(t.Context(), "/tmp/main.go", version, 5*time.Second)
+	diags := client.GetFileDiagnostics(protocol.URIFromPath("/tmp/main.go"))
+	require.Len(t, diags, 1)
+	require.Equal(t, "fresh", diags[0].Message)
+
+	// It gives up after the timeout when nothing is published.
+	start := time.Now()
+	client.WaitForFileDiagnostics(t.Context(), "/tmp/main.go", client.DiagnosticsVersion(), 100*time.Millisecond)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestClientNavigation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {\n\tgreet()\n}\n"), 0o644))
+
+	cfg := config.LSPConfig{
+		Command:   os.Args[0],
+		Args:      []string{"-test.run=^TestFakeServer$"},
+		FileTypes: []string{"go"},
+		Env:       map[string]string{"CRUSH_FAKE_LSP_SERVER": "1"},
+	}
+	client, err := New(t.Context(), "fake", cfg, config.NewEnvironmentVariableResolver(env.NewFromMap(nil)))
+	require.NoError(t, err)
+	_, err = client.Initialize(t.Context(), filepath.Dir(path))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close(context.Background()) })
+
+	uri := protocol.URIFromPath(path)
+	definitions, err := client.FindDefinitions(t.Context(), path, 4, 2)
+	require.NoError(t, err)
+	require.Equal(t, []protocol.Location{{URI: uri, Range: lineRange(6)}}, definitions)
+
+	symbols, err := client.WorkspaceSymbols(t.Context(), "greet")
+	require.NoError(t, err)
+	require.Len(t, symbols, 1)
+	require.Equal(t, "greet", symbols[0].Name)
+	require.Equal(t, protocol.Function, symbols[0].Kind)
+
+	symbols, err = client.DocumentSymbols(t.Context(), path)
+	require.NoError(t, err)
+	require.Len(t, symbols, 2)
+	require.Equal(t, "Server", symbols[0].Name)
+	require.Equal(t, "Start", symbols[1].Name)
+	require.Equal(t, "Server", symbols[1].ContainerName)
+	require.Equal(t, protocol.Location{URI: uri, Range: lineRange(9)}, symbols[1].Location)
+}
+
+// TestFakeServer is the language server TestClientNavigation starts.
+func TestFakeServer(t *testing.T) {
+	if os.Getenv("CRUSH_FAKE_LSP_SERVER") != "1" {
+		t.Skip("only run as a language server by TestClientNavigation")
+	}
+
+	r := bufio.NewReader(os.Stdin)
+	for {
+		var req struct {
+			ID     *json.RawMessage `json:"id"`
+			Method string           `json:"method"`
+			Params struct {
+				TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(readMessage(r), &req); err != nil || req.Method == "exit" {
+			os.Exit(0)
+		}
+		if req.ID == nil {
+			continue
+		}
+
+		uri := req.Params.TextDocument.URI
+		var result any
+		switch req.Method {
+		case "initialize":
+			result = map[string]any{"capabilities": map[string]any{}}
+		case "textDocument/definition":
+			result = []map[string]any{{"targetUri": uri, "targetRange": lineRange(5), "targetSelectionRange": lineRange(6)}}
+		case "workspace/symbol":
+			result = []protocol.SymbolInformation{{Name: "greet", Kind: protocol.Function, Location: protocol.Location{URI: "file:///main.go", Range: lineRange(6)}}}
+		case "textDocument/documentSymbol":
+			result = []protocol.DocumentSymbol{{
+				Name: "Server", Kind: protocol.Struct, Range: lineRange(7), SelectionRange: lineRange(7),
+				Children: []protocol.DocumentSymbol{{Name: "Start", Kind: protocol.Method, Range: lineRange(9), SelectionRange: lineRange(9)}},
+			}}
+		}
+		body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		fmt.Fprintf(os.Stdout, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+}
+
+func readMessage(r *bufio.Reader) []byte {
+	length := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+			length, _ = strconv.Atoi(v)
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil
+	}
+	return body
+}
+
+func lineRange(line uint32) protocol.Range {
+	return protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line, Character: 4}}
+}
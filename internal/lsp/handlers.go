@@ -112,6 +112,7 @@ func HandleDiagnostics(client *Client, params json.RawMessage) {
 	}
 
 	client.diagnostics.Set(diagParams.URI, diagParams.Diagnostics)
+	client.diagnosticsVersions.Set(diagParams.URI, client.diagnostics.Version())
 
 	// Calculate total diagnostic count
 	totalCount := 0
@@ -1,8 +1,42 @@
 package message
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Attachment struct {
 	FilePath string
 	FileName string
 	MimeType string
 	Content  []byte
 }
+
+// IsText reports whether the attachment holds text, such as an MCP resource,
+// which is sent to the model inline instead of as a file.
+func (a Attachment) IsText() bool {
+	return IsTextMIMEType(a.MimeType)
+}
+
+// IsTextMIMEType reports whether content of the given MIME type is text.
+func IsTextMIMEType(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	switch {
+	case strings.HasPrefix(mimeType, "text/"):
+		return true
+	case strings.HasSuffix(mimeType, "+json"), strings.HasSuffix(mimeType, "+xml"):
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/yaml",
+		"application/toml", "application/javascript", "application/x-sh":
+		return true
+	}
+	return false
+}
+
+// TextAttachment formats the content of a text attachment to be sent to the
+// model as part of the prompt.
+func TextAttachment(name string, content []byte) string {
+	return fmt.Sprintf("<attachment name=%q>\n%s\n</attachment>", name, content)
+}
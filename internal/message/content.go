@@ -134,6 +134,12 @@ type Message struct {
 	CreatedAt        int64
 	UpdatedAt        int64
 	IsSummaryMessage bool
+
+	// Cost is what generating the message cost, in USD, and PromptTokens and
+	// CompletionTokens the usage of the request.
+	Cost             float64
+	PromptTokens     int64
+	CompletionTokens int64
 }
 
 func (m *Message) Content() TextContent {
@@ -436,6 +442,10 @@ func (m *Message) ToAIMessage() []fantasy.Message {
 			parts = append(parts, fantasy.TextPart{Text: text})
 		}
 		for _, content := range m.BinaryContent() {
+			if IsTextMIMEType(content.MIMEType) {
+				parts = append(parts, fantasy.TextPart{Text: TextAttachment(content.Path, content.Data)})
+				continue
+			}
 			parts = append(parts, fantasy.FilePart{
 				Filename:  content.Path,
 				Data:      content.Data,
@@ -28,6 +28,9 @@ type Service interface {
 	List(ctx context.Context, sessionID string) ([]Message, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	// CostSince returns what the messages created since a time cost, in all
+	// the sessions.
+	CostSince(ctx context.Context, since time.Time) (float64, error)
 }
 
 type service struct {
@@ -116,9 +119,12 @@ func (s *service) Update(ctx context.Context, message Message) error {
 		finishedAt.Valid = true
 	}
 	err = s.q.UpdateMessage(ctx, db.UpdateMessageParams{
-		ID:         message.ID,
-		Parts:      string(parts),
-		FinishedAt: finishedAt,
+		ID:               message.ID,
+		Parts:            string(parts),
+		FinishedAt:       finishedAt,
+		Cost:             message.Cost,
+		PromptTokens:     message.PromptTokens,
+		CompletionTokens: message.CompletionTokens,
 	})
 	if err != nil {
 		return err
@@ -128,6 +134,10 @@ func (s *service) Update(ctx context.Context, message Message) error {
 	return nil
 }
 
+func (s *service) CostSince(ctx context.Context, since time.Time) (float64, error) {
+	return s.q.GetCostSince(ctx, since.Unix())
+}
+
 func (s *service) Get(ctx context.Context, id string) (Message, error) {
 	dbMessage, err := s.q.GetMessage(ctx, id)
 	if err != nil {
@@ -166,6 +176,9 @@ func (s *service) fromDBItem(item db.Message) (Message, error) {
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
 		IsSummaryMessage: item.IsSummaryMessage != 0,
+		Cost:             item.Cost,
+		PromptTokens:     item.PromptTokens,
+		CompletionTokens: item.CompletionTokens,
 	}, nil
 }
 
@@ -11,11 +11,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/oauth"
 )
 
 const clientId = "9d1c250a-e61b-44d9-88ed-5944d1962f5e"
 
+func init() {
+	oauth.RegisterProvider(oauth.Provider{
+		ID:   string(catwalk.InferenceProviderAnthropic),
+		Name: "Anthropic (Claude Code Max)",
+		Flow: oauth.FlowPKCE,
+	})
+}
+
 // AuthorizeURL returns the Claude Code Max OAuth2 authorization URL.
 func AuthorizeURL(verifier, challenge string) (string, error) {
 	u, err := url.Parse("https://claude.ai/oauth/authorize")
@@ -73,6 +82,9 @@ func ExchangeToken(ctx context.Context, code, verifier string) (*oauth.Token, er
 		return nil, err
 	}
 	token.SetExpiresAt()
+	token.SetObtainedAt()
+	token.ProviderID = string(catwalk.InferenceProviderAnthropic)
+	token.Version = oauth.TokenSchemaVersion
 	return &token, nil
 }
 
@@ -95,7 +107,11 @@ func RefreshToken(ctx context.Context, refreshToken string) (*oauth.Token, error
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return nil, fmt.Errorf("claude code max: failed to refresh token: %w: status %d body %q", oauth.ErrTokenInvalid, resp.StatusCode, string(body))
+	default:
 		return nil, fmt.Errorf("claude code max: failed to refresh token: status %d body %q", resp.StatusCode, string(body))
 	}
 
@@ -104,6 +120,9 @@ func RefreshToken(ctx context.Context, refreshToken string) (*oauth.Token, error
 		return nil, err
 	}
 	token.SetExpiresAt()
+	token.SetObtainedAt()
+	token.ProviderID = string(catwalk.InferenceProviderAnthropic)
+	token.Version = oauth.TokenSchemaVersion
 	return &token, nil
 }
 
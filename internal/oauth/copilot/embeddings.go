@@ -0,0 +1,111 @@
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultEmbeddingModel is the embedding model exposed by the Copilot API.
+const DefaultEmbeddingModel = "copilot-text-embedding-ada-002"
+
+// EmbeddingsClient creates embeddings through the Copilot API using the
+// user's existing Copilot subscription.
+type EmbeddingsClient struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewEmbeddingsClient creates a new EmbeddingsClient. The transport is
+// expected to handle Copilot authentication, usually a *Transport.
+func NewEmbeddingsClient(transport http.RoundTripper) *EmbeddingsClient {
+	return &EmbeddingsClient{
+		baseURL: CopilotAPIBaseURL,
+		model:   DefaultEmbeddingModel,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Minute,
+		},
+	}
+}
+
+// SetModel overrides the embedding model.
+func (c *EmbeddingsClient) SetModel(model string) {
+	c.model = model
+}
+
+// SetBaseURL overrides the API base URL. Useful for testing.
+func (c *EmbeddingsClient) SetBaseURL(baseURL string) {
+	c.baseURL = baseURL
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns one embedding vector per input, in the same order.
+func (c *EmbeddingsClient) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(embeddingsRequest{Model: c.model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result embeddingsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings response: %w", err)
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(embeddings) {
+			return nil, fmt.Errorf("embeddings response has out of range index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	for i, e := range embeddings {
+		if e == nil {
+			return nil, fmt.Errorf("embeddings response is missing input %d", i)
+		}
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,82 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingsClient_Embed(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns embeddings in input order", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "POST", r.Method)
+			require.Equal(t, "/embeddings", r.URL.Path)
+
+			var req embeddingsRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, DefaultEmbeddingModel, req.Model)
+			require.Equal(t, []string{"foo", "bar"}, req.Input)
+
+			_, _ = w.Write([]byte(`{"data":[
+				{"index":1,"embedding":[0.3,0.4]},
+				{"index":0,"embedding":[0.1,0.2]}
+			]}`))
+		}))
+		defer server.Close()
+
+		client := NewEmbeddingsClient(http.DefaultTransport)
+		client.SetBaseURL(server.URL)
+
+		embeddings, err := client.Embed(context.Background(), []string{"foo", "bar"})
+		require.NoError(t, err)
+		require.Equal(t, [][]float32{{0.1, 0.2}, {0.3, 0.4}}, embeddings)
+	})
+
+	t.Run("returns error on failure status", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		client := NewEmbeddingsClient(http.DefaultTransport)
+		client.SetBaseURL(server.URL)
+
+		_, err := client.Embed(context.Background(), []string{"foo"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 403")
+	})
+
+	t.Run("returns error on missing embeddings", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1]}]}`))
+		}))
+		defer server.Close()
+
+		client := NewEmbeddingsClient(http.DefaultTransport)
+		client.SetBaseURL(server.URL)
+
+		_, err := client.Embed(context.Background(), []string{"foo", "bar"})
+		require.Error(t, err)
+	})
+
+	t.Run("skips request for empty input", func(t *testing.T) {
+		t.Parallel()
+
+		client := NewEmbeddingsClient(http.DefaultTransport)
+		embeddings, err := client.Embed(context.Background(), nil)
+		require.NoError(t, err)
+		require.Nil(t, embeddings)
+	})
+}
@@ -0,0 +1,61 @@
+package copilot
+
+import (
+	"errors"
+	"net"
+	"net/url"
+)
+
+// ErrorKind categorizes errors of the device flow and token exchange, so the
+// UI can suggest what to do next.
+type ErrorKind int
+
+const (
+	// ErrorKindUnknown is any error not covered by the other kinds.
+	ErrorKindUnknown ErrorKind = iota
+	// ErrorKindNoSubscription means the GitHub account has no Copilot access.
+	ErrorKindNoSubscription
+	// ErrorKindAccessDenied means the user declined the authorization.
+	ErrorKindAccessDenied
+	// ErrorKindExpiredCode means the device code expired before the user
+	// confirmed it.
+	ErrorKindExpiredCode
+	// ErrorKindNetwork means GitHub could not be reached, e.g. because of a
+	// DNS, connection or proxy failure.
+	ErrorKindNetwork
+	// ErrorKindRateLimited means GitHub asked us to back off.
+	ErrorKindRateLimited
+)
+
+// ClassifyError returns the kind of err.
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorKindUnknown
+	}
+
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		switch oauthErr.Code {
+		case "access_denied":
+			return ErrorKindAccessDenied
+		case "expired_token":
+			return ErrorKindExpiredCode
+		case "slow_down":
+			return ErrorKindRateLimited
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrNoCopilotAccess):
+		return ErrorKindNoSubscription
+	case errors.Is(err, ErrRateLimited):
+		return ErrorKindRateLimited
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return ErrorKindNetwork
+	}
+	return ErrorKindUnknown
+}
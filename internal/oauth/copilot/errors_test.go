@@ -0,0 +1,43 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	proxyErr := &url.Error{
+		Op:  "Post",
+		URL: tokenURL,
+		Err: &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("connection refused")},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{"nil", nil, ErrorKindUnknown},
+		{"no subscription", fmt.Errorf("%w: no active subscription", ErrNoCopilotAccess), ErrorKindNoSubscription},
+		{"access denied", &OAuthError{Code: "access_denied"}, ErrorKindAccessDenied},
+		{"expired code", &OAuthError{Code: "expired_token"}, ErrorKindExpiredCode},
+		{"slow down", &OAuthError{Code: "slow_down"}, ErrorKindRateLimited},
+		{"rate limited", fmt.Errorf("%w: please wait", ErrRateLimited), ErrorKindRateLimited},
+		{"proxy failure", fmt.Errorf("failed to poll for token: %w", proxyErr), ErrorKindNetwork},
+		{"dns failure", &net.DNSError{Err: "no such host", Name: "github.com"}, ErrorKindNetwork},
+		{"other", errors.New("boom"), ErrorKindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.kind, ClassifyError(tt.err))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package copilot
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// EventType identifies the kind of Copilot authentication event.
+type EventType string
+
+const (
+	// EventTokenRefreshed is published when a Copilot API token becomes
+	// available, either from the persisted config or a fresh exchange.
+	EventTokenRefreshed EventType = "token_refreshed"
+	// EventAuthFailed is published when a Copilot API token could not be
+	// obtained.
+	EventAuthFailed EventType = "auth_failed"
+)
+
+// Event describes a change in the Copilot authentication state.
+type Event struct {
+	Type      EventType
+	ExpiresAt time.Time
+	Error     error
+}
+
+var broker = pubsub.NewBroker[Event]()
+
+// SubscribeEvents returns a channel for Copilot authentication events.
+func SubscribeEvents(ctx context.Context) <-chan pubsub.Event[Event] {
+	return broker.Subscribe(ctx)
+}
+
+func publishTokenRefreshed(expiresAt int64) {
+	broker.Publish(pubsub.UpdatedEvent, Event{
+		Type:      EventTokenRefreshed,
+		ExpiresAt: time.Unix(expiresAt, 0),
+	})
+}
+
+func publishAuthFailed(err error) {
+	broker.Publish(pubsub.UpdatedEvent, Event{
+		Type:  EventAuthFailed,
+		Error: err,
+	})
+}
@@ -0,0 +1,135 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// contentExclusionURL is the endpoint returning the content exclusion rules
+// configured by the user's organizations.
+const contentExclusionURL = "https://api.github.com/copilot_internal/content_exclusion"
+
+// ContentExclusions holds the path patterns that must never be sent to the
+// Copilot API.
+type ContentExclusions struct {
+	patterns []string
+}
+
+// NewContentExclusions creates ContentExclusions from gitignore-like glob
+// patterns relative to the repository root.
+func NewContentExclusions(patterns []string) *ContentExclusions {
+	return &ContentExclusions{patterns: patterns}
+}
+
+// Patterns returns the configured exclusion patterns.
+func (c *ContentExclusions) Patterns() []string {
+	if c == nil {
+		return nil
+	}
+	return c.patterns
+}
+
+// IsExcluded reports whether the given path, relative to the repository
+// root, matches any exclusion pattern.
+func (c *ContentExclusions) IsExcluded(relPath string) bool {
+	if c == nil || len(c.patterns) == 0 {
+		return false
+	}
+	relPath = strings.TrimPrefix(filepath.ToSlash(relPath), "/")
+	for _, pattern := range c.patterns {
+		pattern = strings.TrimPrefix(pattern, "/")
+		// Patterns without a slash match at any depth, like .gitignore.
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		if ok, _ := doublestar.Match(pattern, relPath); ok {
+			return true
+		}
+		// A matched directory excludes everything below it.
+		if ok, _ := doublestar.Match(strings.TrimSuffix(pattern, "/")+"/**", relPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type contentExclusionResponse []struct {
+	Rules []struct {
+		Paths []string `json:"paths"`
+	} `json:"rules"`
+}
+
+// FetchContentExclusions fetches the content exclusion rules that apply to
+// the given repositories (in owner/name form) for the user owning the GitHub
+// OAuth token.
+func FetchContentExclusions(ctx context.Context, githubToken string, repos []string) (*ContentExclusions, error) {
+	headers := maps.Clone(CopilotHeaders)
+	headers["Authorization"] = "Bearer " + githubToken
+
+	u := contentExclusionURL
+	if len(repos) > 0 {
+		u += "?repos=" + url.QueryEscape(strings.Join(repos, ","))
+	}
+
+	resp, err := doRequest(ctx, "GET", u, nil, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch content exclusions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content exclusions response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content exclusions request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return parseContentExclusions(body)
+}
+
+func parseContentExclusions(body []byte) (*ContentExclusions, error) {
+	var result contentExclusionResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse content exclusions response: %w", err)
+	}
+
+	var patterns []string
+	for _, scope := range result {
+		for _, rule := range scope.Rules {
+			patterns = append(patterns, rule.Paths...)
+		}
+	}
+	return NewContentExclusions(patterns), nil
+}
+
+// RepoFromRemoteURL extracts the owner/name of a GitHub repository from a
+// git remote URL. It returns an empty string for non-GitHub remotes.
+func RepoFromRemoteURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	remote = strings.TrimSuffix(remote, ".git")
+	for _, prefix := range []string{
+		"https://github.com/",
+		"http://github.com/",
+		"ssh://git@github.com/",
+		"git@github.com:",
+	} {
+		if rest, ok := strings.CutPrefix(remote, prefix); ok {
+			if strings.Count(rest, "/") == 1 {
+				return rest
+			}
+			return ""
+		}
+	}
+	return ""
+}
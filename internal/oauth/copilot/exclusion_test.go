@@ -0,0 +1,68 @@
+package copilot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentExclusions_IsExcluded(t *testing.T) {
+	t.Parallel()
+
+	exclusions := NewContentExclusions([]string{
+		"secrets.json",
+		"/internal/keys/",
+		"**/*.pem",
+		"config/prod/*.yaml",
+	})
+
+	tests := []struct {
+		path     string
+		excluded bool
+	}{
+		{"secrets.json", true},
+		{"nested/dir/secrets.json", true},
+		{"internal/keys/a.go", true},
+		{"internal/keys/nested/b.go", true},
+		{"certs/server.pem", true},
+		{"config/prod/db.yaml", true},
+		{"config/dev/db.yaml", false},
+		{"main.go", false},
+		{"internal/other/keys.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.excluded, exclusions.IsExcluded(tt.path))
+		})
+	}
+
+	t.Run("nil exclusions exclude nothing", func(t *testing.T) {
+		t.Parallel()
+		var nilExclusions *ContentExclusions
+		require.False(t, nilExclusions.IsExcluded("secrets.json"))
+	})
+}
+
+func TestParseContentExclusions(t *testing.T) {
+	t.Parallel()
+
+	body := `[
+		{"rules":[{"paths":["secrets.json","**/*.pem"],"source":{"name":"acme","type":"Organization"}}],"scope":"all"},
+		{"rules":[{"paths":["/vendor/"]}],"scope":"repo"}
+	]`
+
+	exclusions, err := parseContentExclusions([]byte(body))
+	require.NoError(t, err)
+	require.Equal(t, []string{"secrets.json", "**/*.pem", "/vendor/"}, exclusions.Patterns())
+}
+
+func TestRepoFromRemoteURL(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "charmbracelet/crush", RepoFromRemoteURL("https://github.com/charmbracelet/crush.git"))
+	require.Equal(t, "charmbracelet/crush", RepoFromRemoteURL("git@github.com:charmbracelet/crush.git"))
+	require.Equal(t, "charmbracelet/crush", RepoFromRemoteURL("ssh://git@github.com/charmbracelet/crush\n"))
+	require.Empty(t, RepoFromRemoteURL("https://gitlab.com/foo/bar.git"))
+	require.Empty(t, RepoFromRemoteURL(""))
+}
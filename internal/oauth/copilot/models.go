@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
@@ -44,8 +45,10 @@ type ModelsDevModel struct {
 	} `json:"modalities"`
 	OpenWeights bool `json:"open_weights"`
 	Cost        struct {
-		Input  float64 `json:"input"`
-		Output float64 `json:"output"`
+		Input      float64 `json:"input"`
+		Output     float64 `json:"output"`
+		CacheRead  float64 `json:"cache_read"`
+		CacheWrite float64 `json:"cache_write"`
 	} `json:"cost"`
 	Limit struct {
 		Context int64 `json:"context"`
@@ -54,8 +57,28 @@ type ModelsDevModel struct {
 	Status string `json:"status"`
 }
 
-// FetchModels fetches GitHub Copilot models from models.dev API.
-func FetchModels(ctx context.Context) ([]catwalk.Model, error) {
+// IsPreview reports whether models.dev marks the model as a preview, alpha
+// or beta release.
+func (m ModelsDevModel) IsPreview() bool {
+	switch m.Status {
+	case "alpha", "beta", "preview":
+		return true
+	}
+	return strings.Contains(strings.ToLower(m.Name), "preview")
+}
+
+// FetchModels fetches GitHub Copilot models from models.dev API. Preview
+// models are only included when includePreview is true.
+func FetchModels(ctx context.Context, includePreview bool) ([]catwalk.Model, error) {
+	models, err := fetchModelsDev(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return convertModels(models, includePreview), nil
+}
+
+// fetchModelsDev fetches the GitHub Copilot models of the models.dev API.
+func fetchModelsDev(ctx context.Context) (map[string]ModelsDevModel, error) {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -91,11 +114,24 @@ func FetchModels(ctx context.Context) ([]catwalk.Model, error) {
 		return nil, fmt.Errorf("github-copilot provider not found in models.dev API")
 	}
 
-	return convertModels(copilotProvider.Models), nil
+	return copilotProvider.Models, nil
+}
+
+// noTemperatureModels returns the IDs of the models models.dev lists without
+// temperature support, sorted.
+func noTemperatureModels(models map[string]ModelsDevModel) []string {
+	var ids []string
+	for _, m := range models {
+		if m.Status != "deprecated" && !m.Temperature {
+			ids = append(ids, m.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
 }
 
 // convertModels converts models.dev models to catwalk models.
-func convertModels(models map[string]ModelsDevModel) []catwalk.Model {
+func convertModels(models map[string]ModelsDevModel, includePreview bool) []catwalk.Model {
 	result := make([]catwalk.Model, 0, len(models))
 
 	for _, m := range models {
@@ -103,6 +139,9 @@ func convertModels(models map[string]ModelsDevModel) []catwalk.Model {
 		if m.Status == "deprecated" {
 			continue
 		}
+		if !includePreview && m.IsPreview() {
+			continue
+		}
 
 		model := catwalk.Model{
 			ID:               m.ID,
@@ -111,6 +150,12 @@ func convertModels(models map[string]ModelsDevModel) []catwalk.Model {
 			DefaultMaxTokens: m.Limit.Output,
 			ContextWindow:    m.Limit.Context,
 			CanReason:        m.Reasoning,
+			// Catwalk stores cache writes as "in cached" and cache reads as
+			// "out cached".
+			CostPer1MIn:        m.Cost.Input,
+			CostPer1MOut:       m.Cost.Output,
+			CostPer1MInCached:  m.Cost.CacheWrite,
+			CostPer1MOutCached: m.Cost.CacheRead,
 		}
 
 		// Set reasonable defaults if not provided.
@@ -127,6 +172,36 @@ func convertModels(models map[string]ModelsDevModel) []catwalk.Model {
 	return result
 }
 
+// PremiumRequestCost is the price in USD GitHub charges for a single premium
+// request beyond the plan allowance.
+const PremiumRequestCost = 0.04
+
+// premiumRequestMultipliers holds the premium request multipliers GitHub
+// applies to Copilot models. Models not listed count as one premium request.
+var premiumRequestMultipliers = map[string]float64{
+	"gpt-4.1":           0,
+	"gpt-4o":            0,
+	"gpt-5-mini":        0,
+	"grok-code-fast-1":  0,
+	"claude-haiku-4.5":  0.33,
+	"gemini-2.0-flash":  0.25,
+	"o3-mini":           0.33,
+	"o4-mini":           0.33,
+	"claude-opus-4":     10,
+	"claude-opus-41":    10,
+	"claude-3.7-sonnet": 1,
+}
+
+// PremiumRequestMultiplier returns how many premium requests a single
+// request to the given Copilot model consumes. Models included in the
+// subscription return 0 and unknown models return 1.
+func PremiumRequestMultiplier(modelID string) float64 {
+	if m, ok := premiumRequestMultipliers[modelID]; ok {
+		return m
+	}
+	return 1
+}
+
 func containsModality(modalities []string, target string) bool {
 	return slices.Contains(modalities, target)
 }
@@ -168,14 +243,16 @@ func DefaultModels() []catwalk.Model {
 	}
 }
 
-// GetModels returns Copilot models, falling back to defaults if API fetch fails.
-func GetModels(ctx context.Context) []catwalk.Model {
-	models, err := FetchModels(ctx)
+// GetModels returns Copilot models, falling back to defaults if API fetch
+// fails, with the IDs of the models rejecting the temperature parameter.
+func GetModels(ctx context.Context, includePreview bool) ([]catwalk.Model, []string) {
+	fetched, err := fetchModelsDev(ctx)
 	if err != nil {
-		return DefaultModels()
+		return DefaultModels(), nil
 	}
+	models := convertModels(fetched, includePreview)
 	if len(models) == 0 {
-		return DefaultModels()
+		return DefaultModels(), nil
 	}
-	return models
+	return models, noTemperatureModels(fetched)
 }
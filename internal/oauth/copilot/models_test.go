@@ -54,7 +54,7 @@ func TestGetModels_FallbackToDefaults(t *testing.T) {
 
 		// GetModels should return defaults if the API is unreachable.
 		// Since we can't easily mock the URL, we just verify it returns models.
-		models := GetModels(context.Background())
+		models, _ := GetModels(context.Background(), false)
 
 		require.NotEmpty(t, models)
 	})
@@ -141,7 +141,9 @@ func TestModelsDevModelParsing(t *testing.T) {
 			"open_weights": false,
 			"cost": {
 				"input": 2.5,
-				"output": 10.0
+				"output": 10.0,
+				"cache_read": 1.25,
+				"cache_write": 3.75
 			},
 			"limit": {
 				"context": 128000,
@@ -166,6 +168,10 @@ func TestModelsDevModelParsing(t *testing.T) {
 		require.Equal(t, "active", model.Status)
 		require.Contains(t, model.Modalities.Input, "text")
 		require.Contains(t, model.Modalities.Input, "image")
+		require.Equal(t, 2.5, model.Cost.Input)
+		require.Equal(t, 10.0, model.Cost.Output)
+		require.Equal(t, 1.25, model.Cost.CacheRead)
+		require.Equal(t, 3.75, model.Cost.CacheWrite)
 	})
 
 	t.Run("handles minimal model data", func(t *testing.T) {
@@ -255,7 +261,7 @@ func TestConvertModels(t *testing.T) {
 			},
 		}
 
-		result := convertModels(input)
+		result := convertModels(input, false)
 
 		require.Len(t, result, 2)
 
@@ -333,7 +339,7 @@ func TestConvertModels(t *testing.T) {
 			},
 		}
 
-		result := convertModels(input)
+		result := convertModels(input, false)
 
 		require.Len(t, result, 1)
 		require.Equal(t, "active-model", result[0].ID)
@@ -351,7 +357,7 @@ func TestConvertModels(t *testing.T) {
 			},
 		}
 
-		result := convertModels(input)
+		result := convertModels(input, false)
 
 		require.Len(t, result, 1)
 		require.Equal(t, int64(16384), result[0].DefaultMaxTokens)
@@ -376,11 +382,91 @@ func TestConvertModels(t *testing.T) {
 			},
 		}
 
-		result := convertModels(input)
+		result := convertModels(input, false)
 
 		require.Len(t, result, 1)
 		require.True(t, result[0].SupportsImages)
 	})
+
+	t.Run("carries cost data", func(t *testing.T) {
+		t.Parallel()
+
+		model := ModelsDevModel{
+			ID:     "priced-model",
+			Name:   "Priced Model",
+			Status: "active",
+		}
+		model.Cost.Input = 3
+		model.Cost.Output = 15
+		model.Cost.CacheRead = 0.3
+		model.Cost.CacheWrite = 3.75
+
+		result := convertModels(map[string]ModelsDevModel{model.ID: model}, false)
+
+		require.Len(t, result, 1)
+		require.Equal(t, 3.0, result[0].CostPer1MIn)
+		require.Equal(t, 15.0, result[0].CostPer1MOut)
+		require.Equal(t, 3.75, result[0].CostPer1MInCached)
+		require.Equal(t, 0.3, result[0].CostPer1MOutCached)
+	})
+}
+
+func TestNoTemperatureModels(t *testing.T) {
+	t.Parallel()
+
+	ids := noTemperatureModels(map[string]ModelsDevModel{
+		"temperature-model":      {ID: "temperature-model", Temperature: true},
+		"no-temperature-model":   {ID: "no-temperature-model"},
+		"a-no-temperature-model": {ID: "a-no-temperature-model", Status: "beta"},
+		"deprecated-model":       {ID: "deprecated-model", Status: "deprecated"},
+	})
+	require.Equal(t, []string{"a-no-temperature-model", "no-temperature-model"}, ids)
+}
+
+func TestConvertModels_Preview(t *testing.T) {
+	t.Parallel()
+
+	input := map[string]ModelsDevModel{
+		"stable-model": {
+			ID:     "stable-model",
+			Name:   "Stable Model",
+			Status: "active",
+		},
+		"beta-model": {
+			ID:     "beta-model",
+			Name:   "Beta Model",
+			Status: "beta",
+		},
+		"named-preview": {
+			ID:   "named-preview",
+			Name: "Gemini 2.5 Pro (Preview)",
+		},
+	}
+
+	t.Run("skips preview models by default", func(t *testing.T) {
+		t.Parallel()
+
+		result := convertModels(input, false)
+
+		require.Len(t, result, 1)
+		require.Equal(t, "stable-model", result[0].ID)
+	})
+
+	t.Run("includes preview models when opted in", func(t *testing.T) {
+		t.Parallel()
+
+		result := convertModels(input, true)
+
+		require.Len(t, result, 3)
+	})
+}
+
+func TestPremiumRequestMultiplier(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0.0, PremiumRequestMultiplier("gpt-4.1"))
+	require.Equal(t, 10.0, PremiumRequestMultiplier("claude-opus-4"))
+	require.Equal(t, 1.0, PremiumRequestMultiplier("unknown-model"))
 }
 
 func TestContainsModality(t *testing.T) {
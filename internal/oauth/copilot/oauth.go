@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,19 +12,42 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/charmbracelet/crush/internal/oauth"
 )
 
 // OAuth Client ID for GitHub Copilot Chat (same as VS Code extension).
 // This is a public client ID and safe to include in source code.
 const clientID = "Iv1.b507a08c87ecfe98"
 
+func init() {
+	oauth.RegisterProvider(oauth.Provider{
+		ID:   ProviderID,
+		Name: "GitHub Copilot",
+		Flow: oauth.FlowDeviceCode,
+	})
+}
+
 // API endpoints.
 const (
 	deviceCodeURL   = "https://github.com/login/device/code"
 	tokenURL        = "https://github.com/login/oauth/access_token"
 	copilotTokenURL = "https://api.github.com/copilot_internal/v2/token"
+	userURL         = "https://api.github.com/user"
+	copilotUserURL  = "https://api.github.com/copilot_internal/user"
+)
+
+// Errors returned when the GitHub token can no longer be exchanged for a
+// Copilot token. Both require the user to re-run the device flow.
+var (
+	ErrAuthenticationFailed = errors.New("github authentication failed")
+	ErrNoCopilotAccess      = errors.New("no copilot access")
 )
 
+// ErrRateLimited is returned when GitHub rejects a request because too many
+// were made.
+var ErrRateLimited = errors.New("rate limited")
+
 // CopilotHeaders are required headers to mimic VS Code's Copilot extension.
 var CopilotHeaders = map[string]string{
 	"User-Agent":             "GitHubCopilotChat/0.32.4",
@@ -37,14 +61,30 @@ type DeviceFlowResponse struct {
 	DeviceCode      string `json:"device_code"`
 	UserCode        string `json:"user_code"`
 	VerificationURI string `json:"verification_uri"`
-	ExpiresIn       int    `json:"expires_in"`
-	Interval        int    `json:"interval"`
+	// VerificationURIComplete embeds the user code so the user doesn't have
+	// to type it. GitHub may omit it.
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// BrowserURL returns the URL the user should open to authorize the device,
+// preferring the one with the user code already filled in.
+func (r *DeviceFlowResponse) BrowserURL() string {
+	if r.VerificationURIComplete != "" {
+		return r.VerificationURIComplete
+	}
+	return r.VerificationURI
 }
 
 // CopilotToken represents the short-lived Copilot API token.
 type CopilotToken struct {
 	Token     string `json:"token"`
 	ExpiresAt int64  `json:"expires_at"` // Unix timestamp in seconds
+
+	// Subscription metadata returned alongside the token.
+	SKU         string `json:"sku,omitempty"`
+	ChatEnabled bool   `json:"chat_enabled"`
 }
 
 // IsExpired checks if the token is expired or about to expire (within 60 seconds).
@@ -83,6 +123,9 @@ func StartDeviceFlow(ctx context.Context) (*DeviceFlowResponse, error) {
 		return nil, fmt.Errorf("failed to read device flow response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%w: device flow failed with status %d", ErrRateLimited, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("device flow failed with status %d: %s", resp.StatusCode, string(body))
 	}
@@ -214,11 +257,11 @@ func ExchangeForCopilotToken(ctx context.Context, githubToken string) (*CopilotT
 	case http.StatusOK:
 		// Success.
 	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("github authentication failed: invalid or expired token")
+		return nil, fmt.Errorf("%w: invalid or expired token", ErrAuthenticationFailed)
 	case http.StatusForbidden:
-		return nil, fmt.Errorf("no copilot access: your GitHub account doesn't have an active Copilot subscription")
+		return nil, fmt.Errorf("%w: your GitHub account doesn't have an active Copilot subscription", ErrNoCopilotAccess)
 	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("rate limited: please wait and try again")
+		return nil, fmt.Errorf("%w: please wait and try again", ErrRateLimited)
 	default:
 		return nil, fmt.Errorf("copilot token exchange failed with status %d: %s", resp.StatusCode, string(body))
 	}
@@ -237,6 +280,30 @@ func ValidateToken(ctx context.Context, githubToken string) error {
 	return err
 }
 
+// FetchUsername returns the login of the GitHub user owning the OAuth token.
+func FetchUsername(ctx context.Context, githubToken string) (string, error) {
+	headers := maps.Clone(CopilotHeaders)
+	headers["Authorization"] = "Bearer " + githubToken
+
+	resp, err := doRequest(ctx, "GET", userURL, nil, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch github user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("github user request failed with status %d", resp.StatusCode)
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", fmt.Errorf("failed to parse github user response: %w", err)
+	}
+	return user.Login, nil
+}
+
 // OAuthError represents an OAuth error response.
 type OAuthError struct {
 	Code        string
@@ -250,6 +317,16 @@ func (e *OAuthError) Error() string {
 	return e.Code
 }
 
+// RequiresReauth reports whether err means the stored GitHub token is missing,
+// revoked, or no longer grants Copilot access.
+func RequiresReauth(err error) bool {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) && oauthErr.Code == "no_token" {
+		return true
+	}
+	return errors.Is(err, ErrAuthenticationFailed) || errors.Is(err, ErrNoCopilotAccess)
+}
+
 func doRequest(ctx context.Context, method, url string, body any, headers map[string]string) (*http.Response, error) {
 	var reqBody io.Reader
 	if body != nil {
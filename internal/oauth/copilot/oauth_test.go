@@ -2,6 +2,8 @@ package copilot
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -91,6 +93,17 @@ func TestProviderID(t *testing.T) {
 	require.Equal(t, "github-copilot", ProviderID)
 }
 
+func TestRequiresReauth(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, RequiresReauth(fmt.Errorf("%w: invalid or expired token", ErrAuthenticationFailed)))
+	require.True(t, RequiresReauth(fmt.Errorf("request failed: %w", ErrNoCopilotAccess)))
+	require.True(t, RequiresReauth(&OAuthError{Code: "no_token"}))
+	require.False(t, RequiresReauth(&OAuthError{Code: "expired_token"}))
+	require.False(t, RequiresReauth(errors.New("rate limited")))
+	require.False(t, RequiresReauth(nil))
+}
+
 // TestDeviceFlowResponseParsing tests the device flow response JSON parsing.
 func TestDeviceFlowResponseParsing(t *testing.T) {
 	t.Parallel()
@@ -130,9 +143,29 @@ func TestDeviceFlowResponseParsing(t *testing.T) {
 		require.Equal(t, "abc123", resp.DeviceCode)
 		require.Equal(t, "TEST-1234", resp.UserCode)
 		require.Empty(t, resp.VerificationURI)
+		require.Empty(t, resp.VerificationURIComplete)
 		require.Equal(t, 0, resp.ExpiresIn)
 		require.Equal(t, 0, resp.Interval)
 	})
+
+	t.Run("prefers complete verification uri", func(t *testing.T) {
+		t.Parallel()
+
+		jsonData := `{
+			"device_code": "abc123",
+			"user_code": "TEST-1234",
+			"verification_uri": "https://github.com/login/device",
+			"verification_uri_complete": "https://github.com/login/device?user_code=TEST-1234"
+		}`
+
+		var resp DeviceFlowResponse
+		err := json.Unmarshal([]byte(jsonData), &resp)
+		require.NoError(t, err)
+		require.Equal(t, "https://github.com/login/device?user_code=TEST-1234", resp.BrowserURL())
+
+		resp.VerificationURIComplete = ""
+		require.Equal(t, "https://github.com/login/device", resp.BrowserURL())
+	})
 }
 
 // TestTokenResponseParsing tests the token response JSON parsing.
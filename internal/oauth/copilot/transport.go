@@ -1,12 +1,18 @@
 package copilot
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"sync"
 
 	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/tracing"
 )
 
 // CopilotAPIBaseURL is the base URL for the GitHub Copilot API.
@@ -65,9 +71,65 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	reqCopy.Header.Set("Openai-Intent", "conversation-edits")
 	reqCopy.Header.Set("X-Initiator", "user")
 
+	// Copilot rejects image content unless the request is flagged as a
+	// vision request.
+	hasImages, err := hasImageContent(reqCopy)
+	if err != nil {
+		return nil, err
+	}
+	if hasImages {
+		reqCopy.Header.Set("Copilot-Vision-Request", "true")
+	}
+
 	return t.base.RoundTrip(reqCopy)
 }
 
+// hasImageContent reports whether the messages of the chat request have
+// image parts. The body is buffered and restored so it can still be sent.
+func hasImageContent(req *http.Request) (bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return false, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	var chat chatRequest
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return false, nil
+	}
+	for _, msg := range chat.Messages {
+		// Text-only content is a string rather than a list of parts.
+		var parts []struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(msg.Content, &parts) != nil {
+			continue
+		}
+		for _, part := range parts {
+			if part.Type == "image_url" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// chatRequest is the part of a chat completions request looked at to tell
+// whether it has images.
+type chatRequest struct {
+	Messages []struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+}
+
 // getValidToken returns a valid Copilot API token, refreshing if necessary.
 func (t *Transport) getValidToken(ctx context.Context) (string, error) {
 	// Check if we have a valid cached token in memory.
@@ -88,6 +150,25 @@ func (t *Transport) getValidToken(ctx context.Context) (string, error) {
 		return t.copilotToken.Token, nil
 	}
 
+	refreshCtx, span := tracing.StartTokenRefresh(ctx, ProviderID)
+	token, err := t.refreshToken(refreshCtx)
+	tracing.End(span, err)
+	if err != nil {
+		if ctx.Err() == nil {
+			publishAuthFailed(err)
+		}
+		if errors.Is(err, ErrAuthenticationFailed) || errors.Is(err, ErrNoCopilotAccess) {
+			oauth.PublishTokenInvalidated(ProviderID, err)
+		}
+		return "", err
+	}
+	publishTokenRefreshed(t.copilotToken.ExpiresAt)
+	return token, nil
+}
+
+// refreshToken loads or exchanges a new Copilot API token. The caller must
+// hold the write lock.
+func (t *Transport) refreshToken(ctx context.Context) (string, error) {
 	// Get the GitHub OAuth token.
 	oauthToken, err := t.tokenProvider()
 	if err != nil {
@@ -116,6 +197,10 @@ func (t *Transport) getValidToken(ctx context.Context) (string, error) {
 	}
 
 	t.copilotToken = copilotToken
+	oauth.PublishTokenRefreshed(ProviderID, &oauth.Token{
+		CopilotToken:     copilotToken.Token,
+		CopilotExpiresAt: copilotToken.ExpiresAt,
+	})
 
 	// Persist the new Copilot token if a saver is configured.
 	if t.tokenSaver != nil {
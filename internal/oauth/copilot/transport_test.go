@@ -1,9 +1,12 @@
 package copilot
 
 import (
+	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -147,6 +150,68 @@ func TestTransport_RoundTrip(t *testing.T) {
 	})
 }
 
+func TestTransport_VisionRequest(t *testing.T) {
+	t.Parallel()
+
+	newTransport := func() *Transport {
+		return &Transport{
+			tokenProvider: func() (*oauth.Token, error) {
+				return &oauth.Token{RefreshToken: "ghu_test"}, nil
+			},
+			base: http.DefaultTransport,
+			copilotToken: &CopilotToken{
+				Token:     "cached-token",
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		}
+	}
+
+	t.Run("flags requests with images", func(t *testing.T) {
+		t.Parallel()
+
+		body := `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}]}`
+
+		var capturedHeader, capturedBody string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			capturedHeader = r.Header.Get("Copilot-Vision-Request")
+			data, _ := io.ReadAll(r.Body)
+			capturedBody = string(data)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		req, err := http.NewRequest("POST", server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+
+		resp, err := newTransport().RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, "true", capturedHeader)
+		require.Equal(t, body, capturedBody)
+	})
+
+	t.Run("does not flag text requests", func(t *testing.T) {
+		t.Parallel()
+
+		var capturedHeader string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			capturedHeader = r.Header.Get("Copilot-Vision-Request")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		req, err := http.NewRequest("POST", server.URL, strings.NewReader(`{"messages":[{"role":"user","content":"what does \"image_url\" mean?"},{"role":"user","content":[{"type":"text","text":"image_url"}]}]}`))
+		require.NoError(t, err)
+
+		resp, err := newTransport().RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Empty(t, capturedHeader)
+	})
+}
+
 func TestTransport_ClearCache(t *testing.T) {
 	t.Parallel()
 
@@ -334,3 +399,47 @@ func TestTransport_UsesPersistedCopilotToken(t *testing.T) {
 		require.Equal(t, "Bearer persisted-copilot-token", capturedAuth)
 	})
 }
+
+func TestTransport_PublishesEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := SubscribeEvents(ctx)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	expiresAt := time.Now().Add(42 * time.Minute).Unix()
+	transport := &Transport{
+		tokenProvider: func() (*oauth.Token, error) {
+			return &oauth.Token{
+				RefreshToken:     "ghu_github_token",
+				CopilotToken:     "persisted-copilot-token",
+				CopilotExpiresAt: expiresAt,
+			}, nil
+		},
+		base: http.DefaultTransport,
+	}
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Other tests share the broker, so look for our own event.
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Payload.Type == EventTokenRefreshed && event.Payload.ExpiresAt.Unix() == expiresAt {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected token refreshed event")
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// Plan is the Copilot subscription plan of the user.
+type Plan string
+
+const (
+	PlanIndividual Plan = "Individual"
+	PlanBusiness   Plan = "Business"
+	PlanEnterprise Plan = "Enterprise"
+	PlanUnknown    Plan = "Unknown"
+)
+
+// planFromSKU maps the SKU reported by the token exchange to a plan.
+func planFromSKU(sku string) Plan {
+	switch {
+	case sku == "":
+		return PlanUnknown
+	case strings.Contains(sku, "enterprise"):
+		return PlanEnterprise
+	case strings.Contains(sku, "business"):
+		return PlanBusiness
+	case strings.Contains(sku, "individual"), strings.Contains(sku, "free"), strings.Contains(sku, "pro"):
+		return PlanIndividual
+	}
+	return PlanUnknown
+}
+
+// Usage describes the Copilot subscription and premium request usage of the
+// user.
+type Usage struct {
+	Plan        Plan
+	SKU         string
+	ChatEnabled bool
+
+	// PremiumUsed and PremiumQuota count premium requests in the current
+	// billing period. PremiumUnlimited is true when the plan has no quota.
+	PremiumUsed      int
+	PremiumQuota     int
+	PremiumUnlimited bool
+	// ResetDate is when the premium request quota resets, if known.
+	ResetDate string
+}
+
+type copilotUserResponse struct {
+	QuotaResetDate string `json:"quota_reset_date"`
+	QuotaSnapshots struct {
+		PremiumInteractions *struct {
+			Entitlement float64 `json:"entitlement"`
+			Remaining   float64 `json:"remaining"`
+			Unlimited   bool    `json:"unlimited"`
+		} `json:"premium_interactions"`
+	} `json:"quota_snapshots"`
+}
+
+// FetchUsage returns the plan and entitlements from the token exchange
+// metadata, along with the premium request quota of the user owning the
+// GitHub OAuth token.
+func FetchUsage(ctx context.Context, githubToken string) (*Usage, error) {
+	token, err := ExchangeForCopilotToken(ctx, githubToken)
+	if err != nil {
+		return nil, err
+	}
+	usage := &Usage{
+		Plan:        planFromSKU(token.SKU),
+		SKU:         token.SKU,
+		ChatEnabled: token.ChatEnabled,
+	}
+
+	headers := maps.Clone(CopilotHeaders)
+	headers["Authorization"] = "Bearer " + githubToken
+
+	resp, err := doRequest(ctx, "GET", copilotUserURL, nil, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch copilot usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read copilot usage response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("copilot usage request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := parseUsage(body, usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func parseUsage(body []byte, usage *Usage) error {
+	var result copilotUserResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse copilot usage response: %w", err)
+	}
+
+	usage.ResetDate = result.QuotaResetDate
+	if premium := result.QuotaSnapshots.PremiumInteractions; premium != nil {
+		usage.PremiumUnlimited = premium.Unlimited
+		usage.PremiumQuota = int(premium.Entitlement)
+		usage.PremiumUsed = max(0, int(premium.Entitlement-premium.Remaining))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package copilot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanFromSKU(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, PlanIndividual, planFromSKU("copilot_for_individuals_subscriber"))
+	require.Equal(t, PlanIndividual, planFromSKU("free_limited_copilot"))
+	require.Equal(t, PlanBusiness, planFromSKU("copilot_for_business_seat"))
+	require.Equal(t, PlanEnterprise, planFromSKU("copilot_enterprise_seat"))
+	require.Equal(t, PlanUnknown, planFromSKU(""))
+	require.Equal(t, PlanUnknown, planFromSKU("something_else"))
+}
+
+func TestParseUsage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("premium quota", func(t *testing.T) {
+		t.Parallel()
+
+		body := `{
+			"quota_reset_date": "2026-11-01",
+			"quota_snapshots": {
+				"premium_interactions": {"entitlement": 300, "remaining": 120.5, "unlimited": false}
+			}
+		}`
+		var usage Usage
+		require.NoError(t, parseUsage([]byte(body), &usage))
+		require.Equal(t, 300, usage.PremiumQuota)
+		require.Equal(t, 179, usage.PremiumUsed)
+		require.False(t, usage.PremiumUnlimited)
+		require.Equal(t, "2026-11-01", usage.ResetDate)
+	})
+
+	t.Run("missing quota", func(t *testing.T) {
+		t.Parallel()
+
+		var usage Usage
+		require.NoError(t, parseUsage([]byte(`{}`), &usage))
+		require.Zero(t, usage.PremiumQuota)
+		require.Zero(t, usage.PremiumUsed)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		t.Parallel()
+
+		var usage Usage
+		require.Error(t, parseUsage([]byte(`nope`), &usage))
+	})
+}
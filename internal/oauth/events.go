@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// ErrTokenInvalid is returned when the provider rejected a token, so the user
+// has to log in again.
+var ErrTokenInvalid = errors.New("oauth token is no longer valid")
+
+// TokenEventType identifies what happened to a token.
+type TokenEventType string
+
+const (
+	// TokenSaved is published when a login stores a new token.
+	TokenSaved TokenEventType = "saved"
+	// TokenRefreshed is published when a token, or the short-lived API token
+	// derived from it, is renewed.
+	TokenRefreshed TokenEventType = "refreshed"
+	// TokenInvalidated is published when the provider rejected a token.
+	TokenInvalidated TokenEventType = "invalidated"
+)
+
+// TokenEvent describes a change to the token of a provider.
+type TokenEvent struct {
+	Type       TokenEventType
+	ProviderID string
+	// ExpiresAt is when the token in use expires, if known.
+	ExpiresAt time.Time
+	// Error is why the token was invalidated.
+	Error error
+}
+
+var tokenBroker = pubsub.NewBroker[TokenEvent]()
+
+// SubscribeTokenEvents returns a channel receiving token changes of every
+// provider.
+func SubscribeTokenEvents(ctx context.Context) <-chan pubsub.Event[TokenEvent] {
+	return tokenBroker.Subscribe(ctx)
+}
+
+// PublishTokenSaved notifies subscribers that a login stored token.
+func PublishTokenSaved(providerID string, token *Token) {
+	tokenBroker.Publish(pubsub.CreatedEvent, TokenEvent{
+		Type:       TokenSaved,
+		ProviderID: providerID,
+		ExpiresAt:  token.expiry(),
+	})
+}
+
+// PublishTokenRefreshed notifies subscribers that token was renewed.
+func PublishTokenRefreshed(providerID string, token *Token) {
+	tokenBroker.Publish(pubsub.UpdatedEvent, TokenEvent{
+		Type:       TokenRefreshed,
+		ProviderID: providerID,
+		ExpiresAt:  token.expiry(),
+	})
+}
+
+// PublishTokenInvalidated notifies subscribers that the token of a provider
+// was rejected.
+func PublishTokenInvalidated(providerID string, err error) {
+	tokenBroker.Publish(pubsub.DeletedEvent, TokenEvent{
+		Type:       TokenInvalidated,
+		ProviderID: providerID,
+		Error:      err,
+	})
+}
+
+// expiry returns when the token used for requests expires, preferring the
+// short-lived Copilot API token when there is one.
+func (t *Token) expiry() time.Time {
+	switch {
+	case t == nil:
+		return time.Time{}
+	case t.CopilotExpiresAt > 0:
+		return time.Unix(t.CopilotExpiresAt, 0)
+	case t.ExpiresAt > 0:
+		return time.Unix(t.ExpiresAt, 0)
+	}
+	return time.Time{}
+}
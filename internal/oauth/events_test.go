@@ -0,0 +1,51 @@
+package oauth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenEvents(t *testing.T) {
+	t.Parallel()
+
+	events := SubscribeTokenEvents(t.Context())
+	next := func(providerID string) pubsub.Event[TokenEvent] {
+		t.Helper()
+		for {
+			select {
+			case event := <-events:
+				// Other tests may publish concurrently.
+				if event.Payload.ProviderID == providerID {
+					return event
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for token event")
+			}
+		}
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	PublishTokenSaved("test-events", &Token{ExpiresAt: expiresAt})
+	event := next("test-events")
+	require.Equal(t, pubsub.CreatedEvent, event.Type)
+	require.Equal(t, TokenSaved, event.Payload.Type)
+	require.Equal(t, expiresAt, event.Payload.ExpiresAt.Unix())
+
+	copilotExpiresAt := time.Now().Add(30 * time.Minute).Unix()
+	PublishTokenRefreshed("test-events", &Token{ExpiresAt: expiresAt, CopilotExpiresAt: copilotExpiresAt})
+	event = next("test-events")
+	require.Equal(t, TokenRefreshed, event.Payload.Type)
+	require.Equal(t, copilotExpiresAt, event.Payload.ExpiresAt.Unix())
+
+	err := errors.New("revoked")
+	PublishTokenInvalidated("test-events", err)
+	event = next("test-events")
+	require.Equal(t, pubsub.DeletedEvent, event.Type)
+	require.Equal(t, TokenInvalidated, event.Payload.Type)
+	require.ErrorIs(t, event.Payload.Error, err)
+	require.True(t, event.Payload.ExpiresAt.IsZero())
+}
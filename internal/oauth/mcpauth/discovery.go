@@ -0,0 +1,144 @@
+// Package mcpauth authorizes Crush with remote MCP servers, following the
+// authorization flow of the MCP specification: the authorization server is
+// discovered from the MCP server, Crush registers itself as a client when no
+// client ID is configured, and the user logs in with the authorization code
+// flow with PKCE.
+package mcpauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ServerMetadata describes an OAuth authorization server (RFC 8414).
+type ServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// resourceMetadata describes a protected resource, the MCP server (RFC 9728).
+type resourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
+}
+
+var errNotFound = errors.New("metadata not found")
+
+// Discover finds the authorization server of the MCP server at serverURL.
+//
+// The protected resource metadata of the server points to its authorization
+// server. Servers without it are their own authorization server, and when
+// that one doesn't publish its metadata either, the default endpoints of the
+// MCP specification are used.
+func Discover(ctx context.Context, serverURL string) (*ServerMetadata, error) {
+	server, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mcp url: %w", err)
+	}
+
+	issuer := &url.URL{Scheme: server.Scheme, Host: server.Host}
+	var scopes []string
+	var resource resourceMetadata
+	err = fetchFirst(ctx, &resource, wellKnownURLs(server, "oauth-protected-resource")...)
+	switch {
+	case err == nil && len(resource.AuthorizationServers) > 0:
+		issuer, err = url.Parse(resource.AuthorizationServers[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid authorization server: %w", err)
+		}
+		scopes = resource.ScopesSupported
+	case err != nil && !errors.Is(err, errNotFound):
+		return nil, err
+	}
+
+	var meta ServerMetadata
+	err = fetchFirst(ctx, &meta, append(
+		wellKnownURLs(issuer, "oauth-authorization-server"),
+		wellKnownURLs(issuer, "openid-configuration")...,
+	)...)
+	switch {
+	case errors.Is(err, errNotFound):
+		base := issuer.Scheme + "://" + issuer.Host
+		meta = ServerMetadata{
+			Issuer:                base,
+			AuthorizationEndpoint: base + "/authorize",
+			TokenEndpoint:         base + "/token",
+			RegistrationEndpoint:  base + "/register",
+		}
+	case err != nil:
+		return nil, err
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		return nil, errors.New("authorization server metadata lacks the authorization or token endpoint")
+	}
+	if len(meta.ScopesSupported) == 0 {
+		meta.ScopesSupported = scopes
+	}
+	return &meta, nil
+}
+
+// wellKnownURLs returns where the metadata of u is published, with its path
+// inserted after the well-known suffix first, then at the root.
+func wellKnownURLs(u *url.URL, suffix string) []string {
+	base := u.Scheme + "://" + u.Host + "/.well-known/" + suffix
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		return []string{base}
+	}
+	return []string{base + path, base}
+}
+
+// fetchFirst decodes the first of the urls that exists into v.
+func fetchFirst(ctx context.Context, v any, urls ...string) error {
+	for _, u := range urls {
+		err := fetchJSON(ctx, u, v)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		return err
+	}
+	return errNotFound
+}
+
+func fetchJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return errNotFound
+	default:
+		return fmt.Errorf("fetching %s: status %d", u, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", u, err)
+	}
+	return nil
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
@@ -0,0 +1,325 @@
+package mcpauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/version"
+)
+
+// Keys of the values kept in oauth.Token.Extra to refresh the token without
+// discovering the authorization server again.
+const (
+	extraTokenEndpoint = "token_endpoint"
+	extraClientID      = "client_id"
+	extraClientSecret  = "client_secret"
+	extraResource      = "resource"
+)
+
+// ProviderID returns the ID tokens of the MCP server name are issued for.
+func ProviderID(name string) string {
+	return "mcp:" + name
+}
+
+// LoginOptions configures the authorization of an MCP server.
+type LoginOptions struct {
+	// Name is the name of the MCP server in the config.
+	Name string
+	// ServerURL is the endpoint of the MCP server.
+	ServerURL string
+	// ClientID and ClientSecret identify Crush with the authorization
+	// server. Crush registers itself when ClientID is empty.
+	ClientID     string
+	ClientSecret string
+	// Scopes to request, defaulting to the ones the server supports.
+	Scopes []string
+	// OpenURL sends the user to the authorization page.
+	OpenURL func(url string) error
+}
+
+// Login authorizes Crush with the MCP server with the authorization code flow
+// with PKCE, receiving the code on a loopback redirect.
+func Login(ctx context.Context, opts LoginOptions) (*oauth.Token, error) {
+	meta, err := Discover(ctx, opts.ServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discovering authorization server: %w", err)
+	}
+	if len(meta.CodeChallengeMethodsSupported) > 0 && !slices.Contains(meta.CodeChallengeMethodsSupported, "S256") {
+		return nil, errors.New("authorization server doesn't support PKCE with S256")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listening for the authorization callback: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr())
+
+	clientID, clientSecret := opts.ClientID, opts.ClientSecret
+	if clientID == "" {
+		clientID, clientSecret, err = register(ctx, meta, redirectURI)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	verifier, challenge, err := oauth.GetChallenge()
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	q.Set("state", state)
+	q.Set("resource", opts.ServerURL)
+	scopes := opts.Scopes
+	if len(scopes) == 0 {
+		scopes = meta.ScopesSupported
+	}
+	if len(scopes) > 0 {
+		q.Set("scope", strings.Join(scopes, " "))
+	}
+	authURL.RawQuery = q.Encode()
+
+	code, err := waitForCode(ctx, listener, state, func() error {
+		return opts.OpenURL(authURL.String())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {verifier},
+		"resource":      {opts.ServerURL},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	token, err := requestToken(ctx, meta.TokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	token.ProviderID = ProviderID(opts.Name)
+	token.Extra = map[string]string{
+		extraTokenEndpoint: meta.TokenEndpoint,
+		extraClientID:      clientID,
+		extraResource:      opts.ServerURL,
+	}
+	if clientSecret != "" {
+		token.Extra[extraClientSecret] = clientSecret
+	}
+	return token, nil
+}
+
+// RefreshToken renews token with its refresh token.
+func RefreshToken(ctx context.Context, token *oauth.Token) (*oauth.Token, error) {
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("%w: token expired and can't be refreshed", oauth.ErrTokenInvalid)
+	}
+	endpoint := token.Extra[extraTokenEndpoint]
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: unknown token endpoint", oauth.ErrTokenInvalid)
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+		"client_id":     {token.Extra[extraClientID]},
+	}
+	if secret := token.Extra[extraClientSecret]; secret != "" {
+		form.Set("client_secret", secret)
+	}
+	if resource := token.Extra[extraResource]; resource != "" {
+		form.Set("resource", resource)
+	}
+	newToken, err := requestToken(ctx, endpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	// Servers may keep the refresh token when they don't rotate it.
+	if newToken.RefreshToken == "" {
+		newToken.RefreshToken = token.RefreshToken
+	}
+	newToken.InheritMetadata(token)
+	return newToken, nil
+}
+
+// expired reports whether the token must be refreshed, tokens without a
+// lifetime never expiring.
+func expired(token *oauth.Token) bool {
+	return token.ExpiresIn > 0 && token.IsExpired()
+}
+
+// register registers Crush as a client of the authorization server
+// (RFC 7591), returning its client ID and secret.
+func register(ctx context.Context, meta *ServerMetadata, redirectURI string) (string, string, error) {
+	if meta.RegistrationEndpoint == "" {
+		return "", "", errors.New("authorization server doesn't support client registration, set oauth.client_id in the mcp config")
+	}
+	body, err := json.Marshal(map[string]any{
+		"client_name":                "Crush",
+		"client_uri":                 "https://github.com/charmbracelet/crush",
+		"software_id":                "crush",
+		"software_version":           version.Version,
+		"redirect_uris":              []string{redirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("registering client: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", "", fmt.Errorf("registering client: status %d body %q", resp.StatusCode, string(respBody))
+	}
+	var client struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.Unmarshal(respBody, &client); err != nil {
+		return "", "", fmt.Errorf("registering client: %w", err)
+	}
+	if client.ClientID == "" {
+		return "", "", errors.New("registering client: no client_id returned")
+	}
+	return client.ClientID, client.ClientSecret, nil
+}
+
+// waitForCode opens the authorization page and waits for the authorization
+// server to redirect back with the code.
+func waitForCode(ctx context.Context, listener net.Listener, state string, open func() error) (string, error) {
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("state") != state:
+			res.err = errors.New("authorization failed: state mismatch")
+		case q.Get("code") == "":
+			res.err = errors.New("authorization failed: no code returned")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Crush is now authorized, you can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	if err := open(); err != nil {
+		return "", fmt.Errorf("opening the authorization page: %w", err)
+	}
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// requestToken posts form to the token endpoint.
+func requestToken(ctx context.Context, endpoint string, form url.Values) (*oauth.Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: status %d body %q", oauth.ErrTokenInvalid, resp.StatusCode, string(body))
+	default:
+		return nil, fmt.Errorf("status %d body %q", resp.StatusCode, string(body))
+	}
+
+	var token oauth.Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("no access token returned")
+	}
+	if token.ExpiresIn > 0 {
+		token.SetExpiresAt()
+	}
+	token.SetObtainedAt()
+	token.Version = oauth.TokenSchemaVersion
+	return &token, nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,168 @@
+package mcpauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthServer returns a server acting as both the MCP server, at /mcp, and
+// its authorization server.
+func newAuthServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var refreshes atomic.Int32
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"resource":              srv.URL + "/mcp",
+			"authorization_servers": []string{srv.URL + "/auth"},
+			"scopes_supported":      []string{"read", "write"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/auth", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                           srv.URL + "/auth",
+			"authorization_endpoint":           srv.URL + "/auth/authorize",
+			"token_endpoint":                   srv.URL + "/auth/token",
+			"registration_endpoint":            srv.URL + "/auth/register",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]any{"client_id": "registered-client"})
+	})
+	mux.HandleFunc("/auth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != "registered-client" || q.Get("code_challenge") == "" || q.Get("scope") != "read write" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"the-code"}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "the-code" || r.Form.Get("code_verifier") == "" {
+				http.Error(w, "invalid_grant", http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, map[string]any{"access_token": "access-1", "refresh_token": "refresh-1", "expires_in": 3600, "token_type": "Bearer"})
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				http.Error(w, "invalid_grant", http.StatusBadRequest)
+				return
+			}
+			refreshes.Add(1)
+			writeJSON(w, map[string]any{"access_token": "access-2", "expires_in": 3600})
+		}
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return srv, &refreshes
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+	srv, refreshes := newAuthServer(t)
+
+	token, err := Login(t.Context(), LoginOptions{
+		Name:      "test",
+		ServerURL: srv.URL + "/mcp",
+		OpenURL: func(u string) error {
+			// Act as the browser, following the redirect to the callback.
+			go func() {
+				resp, err := http.Get(u)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "access-1", token.AccessToken)
+	require.Equal(t, "refresh-1", token.RefreshToken)
+	require.Equal(t, "mcp:test", token.ProviderID)
+	require.Equal(t, "registered-client", token.Extra[extraClientID])
+	require.Equal(t, srv.URL+"/auth/token", token.Extra[extraTokenEndpoint])
+
+	// An expired token is refreshed before the request is sent.
+	token.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	var saved *oauth.Token
+	client := &http.Client{Transport: NewTransport(
+		"test",
+		func() *oauth.Token {
+			if saved != nil {
+				return saved
+			}
+			return token
+		},
+		func(tok *oauth.Token) error {
+			saved = tok
+			return nil
+		},
+		http.DefaultTransport,
+	)}
+	resp, err := client.Get(srv.URL + "/mcp")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "access-2", saved.AccessToken)
+	require.Equal(t, "refresh-1", saved.RefreshToken, "the refresh token is kept when not rotated")
+	require.Equal(t, "registered-client", saved.Extra[extraClientID])
+
+	resp, err = client.Get(srv.URL + "/mcp")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 1, refreshes.Load())
+}
+
+func TestTransportUnauthorized(t *testing.T) {
+	t.Parallel()
+	srv, _ := newAuthServer(t)
+
+	client := &http.Client{Transport: NewTransport(
+		"test",
+		func() *oauth.Token { return nil },
+		func(*oauth.Token) error { return nil },
+		http.DefaultTransport,
+	)}
+	_, err := client.Get(srv.URL + "/mcp")
+	require.ErrorIs(t, err, ErrUnauthorized)
+	require.ErrorContains(t, err, "crush auth mcp test")
+}
+
+func TestDiscoverDefaults(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	meta, err := Discover(t.Context(), srv.URL+"/mcp")
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/authorize", meta.AuthorizationEndpoint)
+	require.Equal(t, srv.URL+"/token", meta.TokenEndpoint)
+	require.Equal(t, srv.URL+"/register", meta.RegistrationEndpoint)
+}
@@ -0,0 +1,91 @@
+package mcpauth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/tracing"
+)
+
+// ErrUnauthorized is returned when an MCP server requires the user to log in.
+var ErrUnauthorized = errors.New("mcp server requires authorization")
+
+// TokenProvider returns the token of the MCP server, nil when not logged in.
+type TokenProvider func() *oauth.Token
+
+// TokenSaver persists a refreshed token.
+type TokenSaver func(token *oauth.Token) error
+
+// Transport implements http.RoundTripper, authorizing the requests to an MCP
+// server with its OAuth token and refreshing it as needed. Requests are sent
+// as is when there's no token, in case the server doesn't need one.
+type Transport struct {
+	name          string
+	tokenProvider TokenProvider
+	tokenSaver    TokenSaver
+	base          http.RoundTripper
+
+	mu sync.Mutex
+}
+
+// NewTransport creates a new Transport for the MCP server name, wrapping
+// base.
+func NewTransport(name string, tokenProvider TokenProvider, tokenSaver TokenSaver, base http.RoundTripper) *Transport {
+	return &Transport{
+		name:          name,
+		tokenProvider: tokenProvider,
+		tokenSaver:    tokenSaver,
+		base:          base,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.validToken(req)
+	if err != nil {
+		return nil, err
+	}
+	if token != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	if token != nil {
+		return nil, fmt.Errorf("%w: the token was rejected, log in again with `crush auth mcp %s`", ErrUnauthorized, t.name)
+	}
+	return nil, fmt.Errorf("%w: log in with `crush auth mcp %s`", ErrUnauthorized, t.name)
+}
+
+// validToken returns the token to send, refreshing it first if it expired.
+func (t *Transport) validToken(req *http.Request) (*oauth.Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token := t.tokenProvider()
+	if token == nil || !expired(token) {
+		return token, nil
+	}
+	ctx, span := tracing.StartTokenRefresh(req.Context(), "mcp:"+t.name)
+	newToken, err := RefreshToken(ctx, token)
+	tracing.End(span, err)
+	if errors.Is(err, oauth.ErrTokenInvalid) {
+		return nil, fmt.Errorf("%w: %w, log in again with `crush auth mcp %s`", ErrUnauthorized, err, t.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.tokenSaver(newToken); err != nil {
+		slog.Warn("Failed to persist MCP token", "name", t.name, "error", err)
+		// Don't fail - token is still usable in memory.
+	}
+	return newToken, nil
+}
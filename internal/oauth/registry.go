@@ -0,0 +1,69 @@
+package oauth
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// FlowType identifies how a provider obtains OAuth tokens.
+type FlowType string
+
+const (
+	// FlowDeviceCode is the OAuth device authorization flow, where the user
+	// enters a code on the provider's website while we poll for a token.
+	FlowDeviceCode FlowType = "device_code"
+	// FlowPKCE is the authorization code flow with PKCE, where the user
+	// pastes the code returned by the provider after authorizing.
+	FlowPKCE FlowType = "pkce"
+)
+
+// Provider describes an OAuth-capable provider.
+type Provider struct {
+	// ID matches the provider ID used in the config.
+	ID string
+	// Name is the human-readable provider name.
+	Name string
+	// Flow is the OAuth flow the provider uses.
+	Flow FlowType
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Provider
+)
+
+// RegisterProvider adds a provider to the registry, replacing any existing
+// provider with the same ID.
+func RegisterProvider(p Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = slices.DeleteFunc(registry, func(existing Provider) bool {
+		return existing.ID == p.ID
+	})
+	registry = append(registry, p)
+}
+
+// Providers returns all registered providers, sorted by name.
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	providers := slices.Clone(registry)
+	slices.SortFunc(providers, func(a, b Provider) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return providers
+}
+
+// LookupProvider returns the registered provider with the given ID.
+func LookupProvider(id string) (Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	idx := slices.IndexFunc(registry, func(p Provider) bool {
+		return p.ID == id
+	})
+	if idx == -1 {
+		return Provider{}, false
+	}
+	return registry[idx], true
+}
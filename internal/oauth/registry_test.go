@@ -0,0 +1,30 @@
+package oauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	RegisterProvider(Provider{ID: "test-zeta", Name: "Zeta", Flow: FlowPKCE})
+	RegisterProvider(Provider{ID: "test-alpha", Name: "Alpha", Flow: FlowPKCE})
+	RegisterProvider(Provider{ID: "test-alpha", Name: "Alpha", Flow: FlowDeviceCode})
+
+	p, ok := LookupProvider("test-alpha")
+	require.True(t, ok)
+	require.Equal(t, FlowDeviceCode, p.Flow)
+
+	_, ok = LookupProvider("test-missing")
+	require.False(t, ok)
+
+	var names []string
+	for _, p := range Providers() {
+		if p.ID == "test-alpha" || p.ID == "test-zeta" {
+			names = append(names, p.Name)
+		}
+	}
+	require.Equal(t, []string{"Alpha", "Zeta"}, names)
+}
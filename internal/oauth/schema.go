@@ -0,0 +1,23 @@
+package oauth
+
+// TokenSchemaVersion is the current version of the persisted token format.
+//
+// Version 0 tokens mixed the fields of every provider in a single record.
+// Version 1 records know the provider that issued them and only carry the
+// fields that provider uses.
+const TokenSchemaVersion = 1
+
+// TokenKey identifies a stored token record.
+type TokenKey struct {
+	ProviderID string
+	// Account is the name of the stored account, or empty for the
+	// credentials the provider currently uses.
+	Account string
+}
+
+func (k TokenKey) String() string {
+	if k.Account == "" {
+		return k.ProviderID
+	}
+	return k.ProviderID + "/" + k.Account
+}
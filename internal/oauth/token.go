@@ -1,16 +1,33 @@
 package oauth
 
 import (
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
-// Token represents an OAuth2 token from Claude Code Max.
+// Token represents an OAuth2 token.
 type Token struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int    `json:"expires_in"`
 	ExpiresAt    int64  `json:"expires_at"`
 
+	// TokenType is the type of AccessToken, usually "Bearer".
+	TokenType string `json:"token_type,omitempty"`
+	// Scope is the space separated list of scopes granted to the token.
+	Scope string `json:"scope,omitempty"`
+	// ObtainedAt is the Unix timestamp when the token was issued or last
+	// refreshed.
+	ObtainedAt int64 `json:"obtained_at,omitempty"`
+	// ProviderID is the ID of the provider that issued the token.
+	ProviderID string `json:"provider_id,omitempty"`
+	// Extra holds provider specific values that don't fit any other field.
+	Extra map[string]string `json:"extra,omitempty"`
+	// Version is the schema version the token was stored with.
+	Version int `json:"version,omitempty"`
+
 	// CopilotToken stores the short-lived Copilot API token (tid=xxx).
 	// This is used by GitHub Copilot provider to cache the API token.
 	CopilotToken string `json:"copilot_token,omitempty"`
@@ -23,6 +40,43 @@ func (t *Token) SetExpiresAt() {
 	t.ExpiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second).Unix()
 }
 
+// SetObtainedAt records the current time as when the token was obtained.
+func (t *Token) SetObtainedAt() {
+	t.ObtainedAt = time.Now().Unix()
+}
+
+// Scopes returns the scopes granted to the token.
+func (t *Token) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// HasScope returns whether the token was granted the given scope.
+func (t *Token) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes(), scope)
+}
+
+// InheritMetadata copies the metadata of prev that t lacks, as refresh
+// responses usually omit the scope and never carry our own fields.
+func (t *Token) InheritMetadata(prev *Token) {
+	if prev == nil {
+		return
+	}
+	if t.TokenType == "" {
+		t.TokenType = prev.TokenType
+	}
+	if t.Scope == "" {
+		t.Scope = prev.Scope
+	}
+	if t.ProviderID == "" {
+		t.ProviderID = prev.ProviderID
+	}
+	if len(prev.Extra) > 0 {
+		extra := maps.Clone(prev.Extra)
+		maps.Copy(extra, t.Extra)
+		t.Extra = extra
+	}
+}
+
 // IsExpired checks if the token is expired or about to expire (within 10% of its lifetime).
 func (t *Token) IsExpired() bool {
 	return time.Now().Unix() >= (t.ExpiresAt - int64(t.ExpiresIn)/10)
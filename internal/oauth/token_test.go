@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -122,3 +123,53 @@ func TestToken_IsCopilotTokenExpired(t *testing.T) {
 		})
 	}
 }
+
+func TestToken_LegacyJSON(t *testing.T) {
+	t.Parallel()
+
+	legacy := `{"access_token":"a","refresh_token":"r","expires_in":3600,"expires_at":1700000000}`
+	var token Token
+	require.NoError(t, json.Unmarshal([]byte(legacy), &token))
+	require.Equal(t, "a", token.AccessToken)
+	require.Empty(t, token.ProviderID)
+	require.Nil(t, token.Extra)
+
+	data, err := json.Marshal(token)
+	require.NoError(t, err)
+	require.JSONEq(t, legacy, string(data))
+}
+
+func TestToken_Scopes(t *testing.T) {
+	t.Parallel()
+
+	token := &Token{Scope: "user:inference  user:profile"}
+	require.Equal(t, []string{"user:inference", "user:profile"}, token.Scopes())
+	require.True(t, token.HasScope("user:profile"))
+	require.False(t, token.HasScope("user"))
+	require.Empty(t, (&Token{}).Scopes())
+}
+
+func TestToken_InheritMetadata(t *testing.T) {
+	t.Parallel()
+
+	prev := &Token{
+		TokenType:  "Bearer",
+		Scope:      "user:inference",
+		ProviderID: "anthropic",
+		Extra:      map[string]string{"org": "acme", "tier": "max"},
+	}
+	token := &Token{
+		AccessToken: "new",
+		Extra:       map[string]string{"tier": "pro"},
+	}
+	token.InheritMetadata(prev)
+
+	require.Equal(t, "Bearer", token.TokenType)
+	require.Equal(t, "user:inference", token.Scope)
+	require.Equal(t, "anthropic", token.ProviderID)
+	require.Equal(t, map[string]string{"org": "acme", "tier": "pro"}, token.Extra)
+	require.Equal(t, "max", prev.Extra["tier"], "prev must not be modified")
+
+	token.InheritMetadata(nil)
+	require.Equal(t, "anthropic", token.ProviderID)
+}
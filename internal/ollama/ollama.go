@@ -0,0 +1,104 @@
+// Package ollama discovers the models installed in a running Ollama instance.
+package ollama
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/httpext"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// ProviderID is the identifier of the Ollama provider.
+	ProviderID = "ollama"
+	// DefaultHost is the address Ollama listens on by default.
+	DefaultHost = "http://localhost:11434"
+)
+
+type tagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+type showResponse struct {
+	ModelInfo    map[string]any `json:"model_info"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// Host returns the address of the Ollama API from the value of OLLAMA_HOST,
+// which like for Ollama itself may lack the scheme or the port.
+func Host(ollamaHost string) string {
+	host := strings.TrimSuffix(strings.TrimSpace(ollamaHost), "/")
+	if host == "" {
+		return DefaultHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	scheme, address, _ := strings.Cut(host, "://")
+	if !strings.Contains(address, ":") {
+		port := "11434"
+		if scheme == "https" {
+			port = "443"
+		}
+		address += ":" + port
+	}
+	return scheme + "://" + address
+}
+
+// FetchModels returns the models installed in the Ollama instance at host
+// that can call tools, with their context lengths. The models whose details
+// can't be fetched are left out.
+func FetchModels(ctx context.Context, host string) ([]catwalk.Model, error) {
+	var tags tagsResponse
+	if err := httpext.GetJSON(ctx, "ollama", host+"/api/tags", &tags); err != nil {
+		return nil, err
+	}
+
+	models := make([]catwalk.Model, len(tags.Models))
+	var group errgroup.Group
+	group.SetLimit(4)
+	for i, tag := range tags.Models {
+		group.Go(func() error {
+			var show showResponse
+			if err := httpext.DoJSON(ctx, "ollama", http.MethodPost, host+"/api/show", map[string]string{"model": tag.Name}, &show); err != nil {
+				slog.Warn("Leaving out Ollama model without details", "model", tag.Name, "error", err)
+				return nil
+			}
+			// Crush needs tools, which embedding models and older ones lack.
+			if !slices.Contains(show.Capabilities, "tools") {
+				return nil
+			}
+			models[i] = catwalk.Model{
+				ID:             tag.Name,
+				Name:           tag.Name,
+				ContextWindow:  contextLength(show.ModelInfo),
+				CanReason:      slices.Contains(show.Capabilities, "thinking"),
+				SupportsImages: slices.Contains(show.Capabilities, "vision"),
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	models = slices.DeleteFunc(models, func(m catwalk.Model) bool { return m.ID == "" })
+	slices.SortFunc(models, func(a, b catwalk.Model) int { return strings.Compare(a.ID, b.ID) })
+	return models, nil
+}
+
+// contextLength returns the context length the model was trained with, found
+// in its information under the key of its architecture.
+func contextLength(info map[string]any) int64 {
+	for k, v := range info {
+		if n, ok := v.(float64); ok && strings.HasSuffix(k, ".context_length") {
+			return int64(n)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+package ollama
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer serves the models of an Ollama instance.
+func newServer(t *testing.T) *httptest.Server {
+	shows := map[string]any{
+		"qwen3:30b": map[string]any{
+			"model_info":   map[string]any{"general.architecture": "qwen3moe", "qwen3moe.context_length": 262144},
+			"capabilities": []string{"completion", "tools", "thinking"},
+		},
+		"gemma3:4b": map[string]any{
+			"model_info":   map[string]any{"gemma3.context_length": 131072},
+			"capabilities": []string{"completion", "vision"},
+		},
+		"nomic-embed-text:latest": map[string]any{
+			"model_info":   map[string]any{"nomic-bert.context_length": 2048},
+			"capabilities": []string{"embedding"},
+		},
+		"llama3.2:3b": map[string]any{
+			"model_info":   map[string]any{"llama.context_length": 131072},
+			"capabilities": []string{"completion", "tools"},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			var models []map[string]string
+			for name := range shows {
+				models = append(models, map[string]string{"name": name})
+			}
+			models = append(models, map[string]string{"name": "broken:7b"})
+			_ = json.NewEncoder(w).Encode(map[string]any{"models": models})
+		case "/api/show":
+			var req struct {
+				Model string `json:"model"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			show, ok := shows[req.Model]
+			if !ok {
+				http.Error(w, "model is corrupted", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(show)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchModels(t *testing.T) {
+	t.Parallel()
+
+	models, err := FetchModels(t.Context(), newServer(t).URL)
+	require.NoError(t, err, "models without details are left out")
+	require.Equal(t, []catwalk.Model{
+		{ID: "llama3.2:3b", Name: "llama3.2:3b", ContextWindow: 131072},
+		{ID: "qwen3:30b", Name: "qwen3:30b", ContextWindow: 262144, CanReason: true},
+	}, models)
+
+	_, err = FetchModels(t.Context(), "http://127.0.0.1:1")
+	require.Error(t, err)
+}
+
+func TestHost(t *testing.T) {
+	t.Parallel()
+
+	for value, expected := range map[string]string{
+		"":                         DefaultHost,
+		"0.0.0.0":                  "http://0.0.0.0:11434",
+		"127.0.0.1:8080":           "http://127.0.0.1:8080",
+		"https://ollama.example/":  "https://ollama.example:443",
+		"http://192.168.1.2:11434": "http://192.168.1.2:11434",
+	} {
+		require.Equal(t, expected, Host(value), value)
+	}
+}
@@ -0,0 +1,151 @@
+// Package outline extracts the imports and the declarations of source files,
+// for the model to find its way in files it doesn't read whole.
+package outline
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxEntries caps the number of lines of a rendered outline.
+	maxEntries = 500
+	// maxEntryLength caps the length of each line of a rendered outline.
+	maxEntryLength = 200
+)
+
+// Entry is a line of the outline of a file.
+type Entry struct {
+	// Line is the line of the entry, starting at 1.
+	Line int
+	// Text is the signature of the declaration, or the import.
+	Text string
+	// Import is the path of the imported package or file, for imports.
+	Import string
+	// TopLevel is set for the entries that aren't nested in another
+	// declaration.
+	TopLevel bool
+}
+
+var (
+	// declarationPattern matches the lines that look like imports or
+	// declarations in most languages.
+	declarationPattern = regexp.MustCompile(`^\s*(?:export\s+(?:default\s+)?(?:const|let|var)\b|(?:export\s+)?(?:(?:pub(?:\([^)]*\))?|public|private|protected|internal|static|abstract|async|final|default|override|sealed|open|data|unsafe|extern)\s+)*(?:import|from|using|#include|require|use|package|module|namespace|func|function|def|fn|class|struct|enum|interface|trait|impl|type|record|object|protocol|extension|mod)\b)`)
+	// importPattern matches the imports of most languages, capturing the
+	// imported path.
+	importPattern = regexp.MustCompile(`^\s*(?:import|from|using|#include|require|use)\b\s*(?:[^'"<]*\bfrom\s+)?['"<]?([\w./@:-]+)`)
+)
+
+// Parse outlines a file. Go files are parsed, and the files of the other
+// languages are outlined by the lines that look like imports or
+// declarations.
+func Parse(filePath string, content []byte) []Entry {
+	if filepath.Ext(filePath) == ".go" {
+		if entries, ok := parseGo(filePath, content); ok {
+			return entries
+		}
+	}
+	return parsePattern(content)
+}
+
+// Render returns the outline of a file as its imports and the signatures
+// of its declarations, after their line numbers, or an empty string when
+// nothing was found.
+func Render(filePath string, content []byte) string {
+	entries := Parse(filePath, content)
+	var sb strings.Builder
+	for i, entry := range entries {
+		if i == maxEntries {
+			fmt.Fprintf(&sb, "(%d more declarations)\n", len(entries)-i)
+			break
+		}
+		text := entry.Text
+		if len(text) > maxEntryLength {
+			text = text[:maxEntryLength] + "..."
+		}
+		fmt.Fprintf(&sb, "%6d|%s\n", entry.Line, text)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func parseGo(filePath string, content []byte) ([]Entry, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+	source := func(from, to token.Pos) string {
+		return strings.Join(strings.Fields(string(content[fset.Position(from).Offset:fset.Position(to).Offset])), " ")
+	}
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line
+	}
+
+	entries := []Entry{{Line: line(file.Package), Text: "package " + file.Name.Name}}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			end := decl.End()
+			if decl.Body != nil {
+				end = decl.Body.Lbrace
+			}
+			entries = append(entries, Entry{Line: line(decl.Pos()), Text: source(decl.Pos(), end), TopLevel: true})
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				entry := Entry{Line: line(spec.Pos())}
+				switch spec := spec.(type) {
+				case *ast.ImportSpec:
+					entry.Text = "import " + source(spec.Pos(), spec.End())
+					entry.Import = strings.Trim(spec.Path.Value, "\"`")
+				case *ast.TypeSpec:
+					entry.TopLevel = true
+					switch spec.Type.(type) {
+					case *ast.StructType:
+						entry.Text = "type " + spec.Name.Name + " struct"
+					case *ast.InterfaceType:
+						entry.Text = "type " + spec.Name.Name + " interface"
+					default:
+						entry.Text = "type " + source(spec.Pos(), spec.End())
+					}
+				case *ast.ValueSpec:
+					entry.TopLevel = true
+					names := make([]string, len(spec.Names))
+					for i, name := range spec.Names {
+						names[i] = name.Name
+					}
+					entry.Text = decl.Tok.String() + " " + strings.Join(names, ", ")
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, true
+}
+
+func parsePattern(content []byte) []Entry {
+	var entries []Entry
+	for i, line := range bytes.Split(content, []byte{'\n'}) {
+		if !declarationPattern.Match(line) {
+			continue
+		}
+		entry := Entry{
+			Line:     i + 1,
+			Text:     strings.TrimRight(strings.TrimSuffix(strings.TrimRight(string(line), " \t\r"), "{}"), " \t{"),
+			TopLevel: len(line) > 0 && line[0] != ' ' && line[0] != '\t',
+		}
+		if match := importPattern.FindSubmatch(line); match != nil {
+			entry.Import = string(match[1])
+			entry.TopLevel = false
+		} else if bytes.HasPrefix(line, []byte("package ")) {
+			entry.TopLevel = false
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,60 @@
+package outline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	t.Run("go", func(t *testing.T) {
+		t.Parallel()
+		content := `package tools
+
+import (
+	"fmt"
+)
+
+const maxSize, minSize = 10, 1
+
+type Reader interface {
+	Read() error
+}
+
+type ID string
+
+func (r *reader) Read(
+	ctx context.Context,
+) error {
+	return nil
+}
+`
+		require.Equal(t, strings.Join([]string{
+			"     1|package tools",
+			"     4|import \"fmt\"",
+			"     7|const maxSize, minSize",
+			"     9|type Reader interface",
+			"    13|type ID string",
+			"    15|func (r *reader) Read( ctx context.Context, ) error",
+		}, "\n"), Render("reader.go", []byte(content)))
+	})
+
+	t.Run("other languages", func(t *testing.T) {
+		t.Parallel()
+		content := "import os\n\nclass Reader:\n    def read(self):\n        return 1\n\nasync def main():\n    pass\n"
+		require.Equal(t, strings.Join([]string{
+			"     1|import os",
+			"     3|class Reader:",
+			"     4|    def read(self):",
+			"     7|async def main():",
+		}, "\n"), Render("reader.py", []byte(content)))
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, Render("notes.txt", []byte("Some notes.\n")))
+	})
+}
@@ -0,0 +1,119 @@
+package permission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// AllowedCommandsFile is the name of the file, in the data directory of a
+// project, holding the command prefixes that are always allowed.
+const AllowedCommandsFile = "permissions.json"
+
+// subcommandRe matches the words that are subcommands, like "test" in "go
+// test", rather than arguments.
+var subcommandRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// unprefixablePrograms are the programs no prefix is allowed for, even with
+// what looks like a subcommand: interpreters and shells running any code they
+// are given, the ones running other commands, and destructive tools, whose
+// second word is a file rather than a subcommand.
+var unprefixablePrograms = []string{
+	"sh", "bash", "zsh", "fish", "dash", "ksh", "csh", "tcsh", "pwsh", "powershell", "cmd",
+	"python", "node", "deno", "bun", "ruby", "perl", "php", "lua", "tclsh", "osascript",
+	"env", "sudo", "doas", "su", "exec", "eval", "xargs", "nohup", "nice", "time", "timeout", "watch", "ssh",
+	"rm", "rmdir", "dd", "shred", "truncate", "mkfs", "chmod", "chown", "mv", "kill", "pkill", "killall", "find",
+}
+
+// shellOperators are the parts of a command that could chain another command
+// to an allowed one.
+var shellOperators = []string{";", "&", "|", "`", "$(", ">", "<", "\n"}
+
+type allowedCommands struct {
+	AllowedCommands []string `json:"allowed_commands"`
+}
+
+// CommandPrefix returns the prefix to always allow so that similar commands
+// are allowed too: the program followed by its subcommand. Commands without
+// a subcommand, like the ones starting with a flag or a path, have no prefix,
+// as the program alone would allow anything it runs, and neither do the
+// commands of unprefixablePrograms or chaining other commands.
+func CommandPrefix(command string) (string, bool) {
+	command = strings.TrimSpace(command)
+	if command == "" || hasShellOperator(command) {
+		return "", false
+	}
+	fields := strings.Fields(command)
+	if len(fields) < 2 || !subcommandRe.MatchString(fields[1]) || isUnprefixable(fields[0]) {
+		return "", false
+	}
+	return fields[0] + " " + fields[1], true
+}
+
+// isUnprefixable reports whether program is one of unprefixablePrograms,
+// whatever its directory and version, as in /usr/bin/python3.12.
+func isUnprefixable(program string) bool {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(program)), ".exe")
+	name = strings.TrimRight(name, "0123456789.")
+	return slices.Contains(unprefixablePrograms, name)
+}
+
+// matchesCommandPrefix reports whether command is one of the prefixes, or
+// starts with one of them.
+func matchesCommandPrefix(command string, prefixes []string) bool {
+	command = strings.TrimSpace(command)
+	if command == "" || hasShellOperator(command) {
+		return false
+	}
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return command == prefix || strings.HasPrefix(command, prefix+" ")
+	})
+}
+
+func hasShellOperator(command string) bool {
+	return slices.ContainsFunc(shellOperators, func(op string) bool {
+		return strings.Contains(command, op)
+	})
+}
+
+func loadAllowedCommands(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowed commands: %w", err)
+	}
+	var allowed allowedCommands
+	if err := json.Unmarshal(data, &allowed); err != nil {
+		return nil, fmt.Errorf("failed to parse allowed commands: %w", err)
+	}
+	// Prefixes saved by older versions, or by hand, that would allow any
+	// command of a program are left out.
+	return slices.DeleteFunc(allowed.AllowedCommands, func(prefix string) bool {
+		valid, ok := CommandPrefix(prefix)
+		return !ok || valid != prefix
+	}), nil
+}
+
+func saveAllowedCommands(path string, prefixes []string) error {
+	data, err := json.MarshalIndent(allowedCommands{AllowedCommands: prefixes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode allowed commands: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write allowed commands: %w", err)
+	}
+	return nil
+}
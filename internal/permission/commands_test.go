@@ -0,0 +1,91 @@
+package permission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandPrefix(t *testing.T) {
+	t.Parallel()
+
+	for command, want := range map[string]string{
+		"go test ./...":          "go test",
+		"  npm run build  ":      "npm run",
+		"git status":             "git status",
+		"./script.sh build":      "./script.sh build",
+		"ls -la":                 "",
+		"make":                   "",
+		"./script.sh --flag":     "",
+		"git -C dir status":      "",
+		"python3 script.py":      "",
+		"python3 manage":         "",
+		"/usr/bin/python3.12 x":  "",
+		"bash x.sh":              "",
+		"sh -c ls":               "",
+		"rm -rf build":           "",
+		"rm build":               "",
+		"sudo apt":               "",
+		"go test ./... && rm -f": "",
+		"cat file | grep foo":    "",
+		"echo $(whoami)":         "",
+		"make > out.log":         "",
+		"":                       "",
+	} {
+		prefix, ok := CommandPrefix(command)
+		require.Equal(t, want != "", ok, command)
+		require.Equal(t, want, prefix, command)
+	}
+}
+
+func TestPermissionService_GrantCommandPrefix(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), AllowedCommandsFile)
+	service := NewPermissionService("/tmp", false, []string{}, path)
+
+	request := func(command string) CreatePermissionRequest {
+		return CreatePermissionRequest{
+			SessionID: "session1",
+			ToolName:  "bash",
+			Action:    "execute",
+			Path:      "/tmp",
+			Command:   command,
+		}
+	}
+
+	events := service.Subscribe(t.Context())
+	granted := make(chan bool)
+	go func() { granted <- service.Request(request("go test ./...")) }()
+	event := <-events
+	service.GrantCommandPrefix(event.Payload)
+	require.True(t, <-granted)
+
+	// The prefix is persisted, so it's allowed in other sessions and runs.
+	service = NewPermissionService("/tmp", false, []string{}, path)
+	req := request("go test -run TestFoo ./internal/...")
+	req.SessionID = "session2"
+	require.True(t, service.Request(req))
+
+	// Other commands still need a permission, as do chained ones.
+	events = service.Subscribe(t.Context())
+	for _, command := range []string{"go build ./...", "go test ./... && rm -rf /tmp/x", "gofmt -l ."} {
+		go func() { granted <- service.Request(request(command)) }()
+		event := <-events
+		require.Equal(t, command, event.Payload.Command)
+		service.Deny(event.Payload)
+		require.False(t, <-granted)
+	}
+}
+
+func TestLoadAllowedCommands(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), AllowedCommandsFile)
+	require.NoError(t, os.WriteFile(path, []byte(`{"allowed_commands": ["go test", "rm", "python3", "bash", "git", "npm run"]}`), 0o600))
+	prefixes, err := loadAllowedCommands(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"go test", "npm run"}, prefixes, "prefixes allowing any command of a program are left out")
+}
@@ -3,6 +3,7 @@ package permission
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
@@ -23,6 +24,8 @@ type CreatePermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// Command is the shell command run by the tool, if any.
+	Command string `json:"command,omitempty"`
 }
 
 type PermissionNotification struct {
@@ -40,17 +43,25 @@ type PermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	Command     string `json:"command,omitempty"`
+	// Confirmation is set on the requests made with Confirm, which can't be
+	// granted for the rest of the session.
+	Confirmation bool `json:"confirmation,omitempty"`
 }
 
 type Service interface {
 	pubsub.Suscriber[PermissionRequest]
 	GrantPersistent(permission PermissionRequest)
+	GrantCommandPrefix(permission PermissionRequest)
 	Grant(permission PermissionRequest)
 	Deny(permission PermissionRequest)
 	Request(opts CreatePermissionRequest) bool
+	Confirm(opts CreatePermissionRequest) bool
 	AutoApproveSession(sessionID string)
 	SetSkipRequests(skip bool)
 	SkipRequests() bool
+	SetSessionSkipRequests(sessionID string, skip bool)
+	SessionSkipRequests(sessionID string) bool
 	SubscribeNotifications(ctx context.Context) <-chan pubsub.Event[PermissionNotification]
 }
 
@@ -65,7 +76,15 @@ type permissionService struct {
 	autoApproveSessions   map[string]bool
 	autoApproveSessionsMu sync.RWMutex
 	skip                  bool
-	allowedTools          []string
+	// sessionSkip overrides skip for the sessions YOLO mode was toggled in.
+	sessionSkip  *csync.Map[string, bool]
+	allowedTools []string
+
+	// allowedCommands are the command prefixes always allowed in the
+	// project, persisted to allowedCommandsPath.
+	allowedCommands     []string
+	allowedCommandsMu   sync.RWMutex
+	allowedCommandsPath string
 
 	// used to make sure we only process one request at a time
 	requestMu     sync.Mutex
@@ -91,6 +110,24 @@ func (s *permissionService) GrantPersistent(permission PermissionRequest) {
 	}
 }
 
+// GrantCommandPrefix grants the permission, and always allows the commands
+// starting like its command from now on.
+func (s *permissionService) GrantCommandPrefix(permission PermissionRequest) {
+	if prefix, ok := CommandPrefix(permission.Command); ok {
+		s.allowedCommandsMu.Lock()
+		if !slices.Contains(s.allowedCommands, prefix) {
+			s.allowedCommands = append(s.allowedCommands, prefix)
+			if s.allowedCommandsPath != "" {
+				if err := saveAllowedCommands(s.allowedCommandsPath, s.allowedCommands); err != nil {
+					slog.Warn("Failed to persist allowed command", "prefix", prefix, "error", err)
+				}
+			}
+		}
+		s.allowedCommandsMu.Unlock()
+	}
+	s.Grant(permission)
+}
+
 func (s *permissionService) Grant(permission PermissionRequest) {
 	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
 		ToolCallID: permission.ToolCallID,
@@ -123,7 +160,7 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 }
 
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
-	if s.skip {
+	if s.SessionSkipRequests(opts.SessionID) {
 		return true
 	}
 
@@ -148,6 +185,14 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		return true
 	}
 
+	s.allowedCommandsMu.RLock()
+	commandAllowed := opts.Command != "" && matchesCommandPrefix(opts.Command, s.allowedCommands)
+	s.allowedCommandsMu.RUnlock()
+
+	if commandAllowed {
+		return true
+	}
+
 	fileInfo, err := os.Stat(opts.Path)
 	dir := opts.Path
 	if err == nil {
@@ -170,6 +215,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		Description: opts.Description,
 		Action:      opts.Action,
 		Params:      opts.Params,
+		Command:     opts.Command,
 	}
 
 	s.sessionPermissionsMu.RLock()
@@ -190,6 +236,42 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	}
 	s.sessionPermissionsMu.RUnlock()
 
+	return s.ask(permission)
+}
+
+// Confirm asks the user for the permission whatever YOLO mode, the allowed
+// tools and the permissions granted for the session. It's denied in the
+// sessions approved automatically, as nobody is there to answer.
+func (s *permissionService) Confirm(opts CreatePermissionRequest) bool {
+	s.autoApproveSessionsMu.RLock()
+	autoApprove := s.autoApproveSessions[opts.SessionID]
+	s.autoApproveSessionsMu.RUnlock()
+	if autoApprove {
+		return false
+	}
+
+	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
+		ToolCallID: opts.ToolCallID,
+	})
+	s.requestMu.Lock()
+	defer s.requestMu.Unlock()
+
+	return s.ask(PermissionRequest{
+		ID:           uuid.New().String(),
+		Path:         opts.Path,
+		SessionID:    opts.SessionID,
+		ToolCallID:   opts.ToolCallID,
+		ToolName:     opts.ToolName,
+		Description:  opts.Description,
+		Action:       opts.Action,
+		Params:       opts.Params,
+		Command:      opts.Command,
+		Confirmation: true,
+	})
+}
+
+// ask publishes the permission request and waits for the answer.
+func (s *permissionService) ask(permission PermissionRequest) bool {
 	s.activeRequest = &permission
 
 	respCh := make(chan bool, 1)
@@ -220,7 +302,29 @@ func (s *permissionService) SkipRequests() bool {
 	return s.skip
 }
 
-func NewPermissionService(workingDir string, skip bool, allowedTools []string) Service {
+// SetSessionSkipRequests turns YOLO mode on or off for a single session,
+// whatever the default set with SetSkipRequests.
+func (s *permissionService) SetSessionSkipRequests(sessionID string, skip bool) {
+	s.sessionSkip.Set(sessionID, skip)
+}
+
+// SessionSkipRequests reports whether the permission requests of a session
+// are granted without asking.
+func (s *permissionService) SessionSkipRequests(sessionID string) bool {
+	if skip, ok := s.sessionSkip.Get(sessionID); ok {
+		return skip
+	}
+	return s.skip
+}
+
+// NewPermissionService creates the permission service. The command prefixes
+// the user always allows are persisted to allowedCommandsPath, unless it's
+// empty.
+func NewPermissionService(workingDir string, skip bool, allowedTools []string, allowedCommandsPath string) Service {
+	allowedCommands, err := loadAllowedCommands(allowedCommandsPath)
+	if err != nil {
+		slog.Warn("Failed to load allowed commands", "path", allowedCommandsPath, "error", err)
+	}
 	return &permissionService{
 		Broker:              pubsub.NewBroker[PermissionRequest](),
 		notificationBroker:  pubsub.NewBroker[PermissionNotification](),
@@ -228,7 +332,10 @@ func NewPermissionService(workingDir string, skip bool, allowedTools []string) S
 		sessionPermissions:  make([]PermissionRequest, 0),
 		autoApproveSessions: make(map[string]bool),
 		skip:                skip,
+		sessionSkip:         csync.NewMap[string, bool](),
 		allowedTools:        allowedTools,
 		pendingRequests:     csync.NewMap[string, chan bool](),
+		allowedCommands:     allowedCommands,
+		allowedCommandsPath: allowedCommandsPath,
 	}
 }
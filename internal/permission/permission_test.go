@@ -54,7 +54,7 @@ func TestPermissionService_AllowedCommands(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := NewPermissionService("/tmp", false, tt.allowedTools)
+			service := NewPermissionService("/tmp", false, tt.allowedTools, "")
 
 			// Create a channel to capture the permission request
 			// Since we're testing the allowlist logic, we need to simulate the request
@@ -79,7 +79,7 @@ func TestPermissionService_AllowedCommands(t *testing.T) {
 }
 
 func TestPermissionService_SkipMode(t *testing.T) {
-	service := NewPermissionService("/tmp", true, []string{})
+	service := NewPermissionService("/tmp", true, []string{}, "")
 
 	result := service.Request(CreatePermissionRequest{
 		SessionID:   "test-session",
@@ -94,9 +94,58 @@ func TestPermissionService_SkipMode(t *testing.T) {
 	}
 }
 
+func TestPermissionService_SessionSkipMode(t *testing.T) {
+	service := NewPermissionService("/tmp", false, []string{}, "")
+	service.SetSessionSkipRequests("yolo-session", true)
+
+	assert.True(t, service.SessionSkipRequests("yolo-session"))
+	assert.False(t, service.SessionSkipRequests("other-session"))
+	assert.True(t, service.Request(CreatePermissionRequest{
+		SessionID:   "yolo-session",
+		ToolName:    "bash",
+		Action:      "execute",
+		Description: "test command",
+		Path:        "/tmp",
+	}), "expected permission to be granted in the yolo session")
+
+	// Sessions without an override follow the default.
+	service.SetSkipRequests(true)
+	assert.True(t, service.SessionSkipRequests("other-session"))
+	service.SetSessionSkipRequests("yolo-session", false)
+	assert.False(t, service.SessionSkipRequests("yolo-session"))
+}
+
+func TestPermissionService_Confirm(t *testing.T) {
+	service := NewPermissionService("/tmp", true, []string{"auto_commit"}, "")
+	req := CreatePermissionRequest{
+		SessionID:   "session",
+		ToolName:    "auto_commit",
+		Action:      "commit",
+		Description: "Fix parser",
+		Path:        "/tmp",
+	}
+
+	// Neither YOLO mode nor the allowed tools answer confirmations.
+	events := service.Subscribe(t.Context())
+	var confirmed bool
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		confirmed = service.Confirm(req)
+	})
+	event := <-events
+	assert.True(t, event.Payload.Confirmation)
+	service.Deny(event.Payload)
+	wg.Wait()
+	assert.False(t, confirmed)
+
+	// Nobody answers in the sessions approved automatically.
+	service.AutoApproveSession("session")
+	assert.False(t, service.Confirm(req))
+}
+
 func TestPermissionService_SequentialProperties(t *testing.T) {
 	t.Run("Sequential permission requests with persistent grants", func(t *testing.T) {
-		service := NewPermissionService("/tmp", false, []string{})
+		service := NewPermissionService("/tmp", false, []string{}, "")
 
 		req1 := CreatePermissionRequest{
 			SessionID:   "session1",
@@ -140,7 +189,7 @@ func TestPermissionService_SequentialProperties(t *testing.T) {
 		assert.True(t, result2, "Second request should be auto-approved")
 	})
 	t.Run("Sequential requests with temporary grants", func(t *testing.T) {
-		service := NewPermissionService("/tmp", false, []string{})
+		service := NewPermissionService("/tmp", false, []string{}, "")
 
 		req := CreatePermissionRequest{
 			SessionID:   "session2",
@@ -180,7 +229,7 @@ func TestPermissionService_SequentialProperties(t *testing.T) {
 		assert.False(t, result2, "Second request should be denied")
 	})
 	t.Run("Concurrent requests with different outcomes", func(t *testing.T) {
-		service := NewPermissionService("/tmp", false, []string{})
+		service := NewPermissionService("/tmp", false, []string{}, "")
 
 		events := service.Subscribe(t.Context())
 
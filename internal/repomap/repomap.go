@@ -0,0 +1,369 @@
+// Package repomap maps a repository for the model: its directory tree with
+// the top-level symbols of each file, keeping the most important files when
+// it doesn't fit, as ranked by PageRank over the graph of their imports.
+package repomap
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/outline"
+)
+
+const (
+	// maxFiles caps the number of files and directories walked.
+	maxFiles = 5000
+	// maxFileSize is the size of the largest file mapped.
+	maxFileSize = 512 * 1024
+	// maxSymbols caps the number of symbols listed for each file.
+	maxSymbols = 20
+	// maxSymbolLength caps the length of each symbol.
+	maxSymbolLength = 120
+
+	// refreshInterval is how old the map gets before being built again.
+	refreshInterval = 30 * time.Second
+
+	damping    = 0.85
+	iterations = 20
+)
+
+// sourceExtensions are the extensions of the files mapped.
+var sourceExtensions = []string{
+	".go", ".py", ".js", ".jsx", ".mjs", ".ts", ".tsx", ".rs", ".java", ".kt",
+	".scala", ".rb", ".php", ".cs", ".swift", ".c", ".h", ".cc", ".cpp", ".hpp",
+	".lua", ".ex", ".exs", ".zig", ".dart", ".hs", ".ml", ".clj", ".sh",
+}
+
+// resolveSuffixes are tried in turn to find the file of a relative import.
+var resolveSuffixes = []string{
+	"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".py",
+	"/index.ts", "/index.tsx", "/index.js", "/__init__.py",
+}
+
+// Map is the map of a repository. It's safe for concurrent use.
+type Map struct {
+	root      string
+	maxTokens int
+
+	// refreshing is held while building the map, which a single goroutine
+	// does at a time.
+	refreshing sync.Mutex
+	// pending is set while the map is built in the background.
+	pending atomic.Bool
+	// files are the files mapped, by their slash-separated path relative to
+	// the root.
+	files  map[string]*file
+	module string
+	// exclude, when set, tells the files left out of the map.
+	exclude func(rel string) bool
+
+	mu        sync.Mutex
+	rendered  string
+	refreshed time.Time
+}
+
+type file struct {
+	modTime time.Time
+	size    int64
+	symbols []string
+	imports []string
+}
+
+// New returns the map of the repository at root, rendered in about
+// maxTokens tokens.
+func New(root string, maxTokens int) *Map {
+	return &Map{
+		root:      root,
+		maxTokens: maxTokens,
+		files:     make(map[string]*file),
+	}
+}
+
+// String returns the map as last built, which is empty until it first is,
+// and builds it again in the background when it's older than
+// refreshInterval.
+func (m *Map) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.refreshed) > refreshInterval && m.pending.CompareAndSwap(false, true) {
+		go func() {
+			defer m.pending.Store(false)
+			m.Refresh()
+		}()
+	}
+	return m.rendered
+}
+
+// SetExclude leaves out of the map the files for which exclude returns true,
+// given their slash-separated path relative to the root, and builds it again
+// without them before returning.
+func (m *Map) SetExclude(exclude func(rel string) bool) {
+	m.refreshing.Lock()
+	m.exclude = exclude
+	m.refreshing.Unlock()
+	m.Refresh()
+}
+
+// Refresh builds the map again, parsing only the files that changed since it
+// was last built.
+func (m *Map) Refresh() {
+	m.refreshing.Lock()
+	defer m.refreshing.Unlock()
+
+	changed := m.refresh()
+	var rendered string
+	if changed {
+		rendered = m.render()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if changed {
+		m.rendered = rendered
+	}
+	m.refreshed = time.Now()
+}
+
+// refresh parses the files added or changed, and forgets the ones removed
+// or excluded, reporting whether any was.
+func (m *Map) refresh() bool {
+	paths, _, err := fsext.ListDirectory(m.root, nil, 0, maxFiles)
+	if err != nil {
+		slog.Warn("Failed to map the repository", "error", err)
+		return false
+	}
+	m.module = goModule(m.root)
+	crushIgnore := fsext.NewCrushIgnore(m.root)
+
+	changed := false
+	seen := make(map[string]bool, len(m.files))
+	for _, path := range paths {
+		if !slices.Contains(sourceExtensions, filepath.Ext(path)) || crushIgnore.Ignores(path) {
+			continue
+		}
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if m.exclude != nil && m.exclude(rel) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() > maxFileSize {
+			continue
+		}
+		seen[rel] = true
+		if f, ok := m.files[rel]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			delete(seen, rel)
+			continue
+		}
+		m.files[rel] = parseFile(rel, content, info)
+		changed = true
+	}
+	for rel := range m.files {
+		if !seen[rel] {
+			delete(m.files, rel)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func parseFile(rel string, content []byte, info os.FileInfo) *file {
+	f := &file{modTime: info.ModTime(), size: info.Size()}
+	for _, entry := range outline.Parse(rel, content) {
+		if entry.Import != "" {
+			f.imports = append(f.imports, entry.Import)
+		}
+		if !entry.TopLevel || len(f.symbols) == maxSymbols {
+			continue
+		}
+		symbol := entry.Text
+		if len(symbol) > maxSymbolLength {
+			symbol = symbol[:maxSymbolLength] + "..."
+		}
+		f.symbols = append(f.symbols, symbol)
+	}
+	return f
+}
+
+// goModule returns the path of the Go module at root, if any.
+func goModule(root string) string {
+	content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`)
+		}
+	}
+	return ""
+}
+
+// render renders the most important files that fit in the token budget,
+// at four bytes each, as a tree.
+func (m *Map) render() string {
+	if len(m.files) == 0 {
+		return ""
+	}
+	nodes := slices.Sorted(maps.Keys(m.files))
+	rank := pageRank(nodes, m.links())
+	byRank := slices.Clone(nodes)
+	slices.SortStableFunc(byRank, func(a, b string) int {
+		return cmp.Compare(rank[b], rank[a])
+	})
+
+	budget := m.maxTokens * 4
+	var included []string
+	size := 0
+	for _, rel := range byRank {
+		cost := len(rel) + 1
+		for _, symbol := range m.files[rel].symbols {
+			cost += len(symbol) + 1
+		}
+		if size+cost > budget {
+			continue
+		}
+		size += cost
+		included = append(included, rel)
+	}
+	slices.Sort(included)
+
+	var sb strings.Builder
+	printed := make(map[string]bool)
+	for _, rel := range included {
+		var dirs []string
+		if dir := path.Dir(rel); dir != "." {
+			dirs = strings.Split(dir, "/")
+		}
+		for i := range dirs {
+			if dir := strings.Join(dirs[:i+1], "/"); !printed[dir] {
+				printed[dir] = true
+				fmt.Fprintf(&sb, "%s%s/\n", strings.Repeat("  ", i), dirs[i])
+			}
+		}
+		indent := strings.Repeat("  ", len(dirs))
+		fmt.Fprintf(&sb, "%s%s\n", indent, path.Base(rel))
+		for _, symbol := range m.files[rel].symbols {
+			fmt.Fprintf(&sb, "%s  %s\n", indent, symbol)
+		}
+	}
+	if left := len(nodes) - len(included); left > 0 {
+		fmt.Fprintf(&sb, "(%d fewer imported files left out)\n", left)
+	}
+	return sb.String()
+}
+
+// links returns the files each file imports.
+func (m *Map) links() map[string][]string {
+	goDirs := make(map[string][]string)
+	for rel := range m.files {
+		if path.Ext(rel) == ".go" && !strings.HasSuffix(rel, "_test.go") {
+			goDirs[path.Dir(rel)] = append(goDirs[path.Dir(rel)], rel)
+		}
+	}
+	links := make(map[string][]string)
+	for rel, f := range m.files {
+		var targets []string
+		for _, imp := range f.imports {
+			for _, target := range m.resolve(rel, imp, goDirs) {
+				if target != rel && !slices.Contains(targets, target) {
+					targets = append(targets, target)
+				}
+			}
+		}
+		links[rel] = targets
+	}
+	return links
+}
+
+// resolve returns the files of the repository an import of the file at rel
+// refers to: the files of the package of Go imports, and the file of the
+// relative imports of the other languages.
+func (m *Map) resolve(rel, imp string, goDirs map[string][]string) []string {
+	if path.Ext(rel) == ".go" {
+		if m.module == "" {
+			return nil
+		}
+		if imp == m.module {
+			return goDirs["."]
+		}
+		if pkg, ok := strings.CutPrefix(imp, m.module+"/"); ok {
+			return goDirs[pkg]
+		}
+		return nil
+	}
+
+	var base string
+	switch {
+	case strings.HasPrefix(imp, "./") || strings.HasPrefix(imp, "../"):
+		base = path.Join(path.Dir(rel), imp)
+	case path.Ext(rel) == ".py":
+		// Python modules are dotted, and relative ones start with a dot per
+		// level up.
+		dir := "."
+		if trimmed := strings.TrimLeft(imp, "."); trimmed != imp {
+			dir = path.Dir(rel)
+			for range len(imp) - len(trimmed) - 1 {
+				dir = path.Dir(dir)
+			}
+			imp = trimmed
+		}
+		base = path.Join(dir, strings.ReplaceAll(imp, ".", "/"))
+	default:
+		base = path.Join(path.Dir(rel), imp)
+	}
+	for _, suffix := range resolveSuffixes {
+		if _, ok := m.files[base+suffix]; ok {
+			return []string{base + suffix}
+		}
+	}
+	return nil
+}
+
+// pageRank ranks the nodes of a graph by how much they're linked to, by the
+// nodes ranked high themselves.
+func pageRank(nodes []string, links map[string][]string) map[string]float64 {
+	n := float64(len(nodes))
+	rank := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		rank[node] = 1 / n
+	}
+	for range iterations {
+		// The rank of the nodes without links is shared by all of them.
+		dangling := 0.0
+		for _, node := range nodes {
+			if len(links[node]) == 0 {
+				dangling += rank[node]
+			}
+		}
+		next := make(map[string]float64, len(nodes))
+		for _, node := range nodes {
+			next[node] += (1-damping)/n + damping*dangling/n
+			for _, target := range links[node] {
+				next[target] += damping * rank[node] / float64(len(links[node]))
+			}
+		}
+		rank = next
+	}
+	return rank
+}
@@ -0,0 +1,67 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(rel)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, rel), []byte(content), 0o644))
+	}
+	write("go.mod", "module example.com/app\n")
+	write("main.go", "package main\n\nimport \"example.com/app/util\"\n\nfunc main() { util.Run() }\n")
+	write("cmd/tool/tool.go", "package main\n\nimport \"example.com/app/util\"\n\nfunc main() { util.Run() }\n")
+	write("util/run.go", "package util\n\n// Run runs.\nfunc Run() {}\n\ntype Runner interface {\n\tRun()\n}\n")
+	write("web/app.ts", "import { api } from './api'\n\nexport function render(): void {}\n")
+	write("web/api.ts", "export const api = createAPI()\n")
+
+	m := New(root, 1000)
+	require.Empty(t, m.String(), "the map is built in the background")
+	m.Refresh()
+	require.Equal(t, `cmd/
+  tool/
+    tool.go
+      func main()
+main.go
+  func main()
+util/
+  run.go
+    func Run()
+    type Runner interface
+web/
+  api.ts
+    export const api = createAPI()
+  app.ts
+    export function render(): void
+`, m.String())
+
+	rank := pageRank(
+		[]string{"cmd/tool/tool.go", "main.go", "util/run.go", "web/api.ts", "web/app.ts"},
+		m.links(),
+	)
+	require.Greater(t, rank["util/run.go"], rank["main.go"])
+	require.Greater(t, rank["web/api.ts"], rank["web/app.ts"])
+
+	// Only the most imported files are kept when the map doesn't fit.
+	m.maxTokens = 12
+	require.Equal(t, "util/\n  run.go\n    func Run()\n    type Runner interface\n(4 fewer imported files left out)\n", m.render())
+
+	m.maxTokens = 1000
+	write("util/run.go", "package util\n\nfunc Start() {}\n")
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "web")))
+	m.Refresh()
+	require.Equal(t, "cmd/\n  tool/\n    tool.go\n      func main()\nmain.go\n  func main()\nutil/\n  run.go\n    func Start()\n", m.String())
+
+	// Excluded files are left out at once.
+	m.SetExclude(func(rel string) bool { return rel == "util/run.go" })
+	require.Equal(t, "cmd/\n  tool/\n    tool.go\n      func main()\nmain.go\n  func main()\n", m.String())
+}
@@ -0,0 +1,262 @@
+// Package semindex is a semantic index of a repository: its source files cut
+// in chunks of lines, each embedded as a vector, searched by how close their
+// vectors are to the one of a query.
+package semindex
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+)
+
+const (
+	// maxFiles caps the number of files and directories walked.
+	maxFiles = 5000
+	// maxFileSize is the size of the largest file indexed.
+	maxFileSize = 256 * 1024
+	// chunkLines is the number of lines of each chunk.
+	chunkLines = 40
+	// maxChunkSize caps the bytes of each chunk that are embedded.
+	maxChunkSize = 4000
+	// batchSize is the number of chunks embedded by each request, about.
+	batchSize = 32
+)
+
+// sourceExtensions are the extensions of the files indexed.
+var sourceExtensions = []string{
+	".go", ".py", ".js", ".jsx", ".mjs", ".ts", ".tsx", ".rs", ".java", ".kt",
+	".scala", ".rb", ".php", ".cs", ".swift", ".c", ".h", ".cc", ".cpp", ".hpp",
+	".lua", ".ex", ".exs", ".zig", ".dart", ".hs", ".ml", ".clj", ".sh", ".md",
+}
+
+// Embedder embeds texts as vectors, one for each input in the same order.
+type Embedder interface {
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// Result is a chunk of a file found by a search.
+type Result struct {
+	// Path is the path of the file, relative to the root of the index.
+	Path      string
+	StartLine int
+	EndLine   int
+	Content   string
+	// Score is the cosine similarity of the chunk to the query.
+	Score float64
+}
+
+// Index is the semantic index of a repository, kept in memory. It's safe for
+// concurrent use.
+type Index struct {
+	root     string
+	embedder Embedder
+
+	mu sync.Mutex
+	// files are the files indexed, by their slash-separated path relative to
+	// the root.
+	files map[string]*file
+}
+
+type file struct {
+	modTime time.Time
+	size    int64
+	chunks  []*chunk
+}
+
+type chunk struct {
+	startLine int
+	endLine   int
+	content   string
+	vector    []float32
+}
+
+// New returns the index of the repository at root, embedding its files with
+// embedder. Nothing is embedded until the first search.
+func New(root string, embedder Embedder) *Index {
+	return &Index{
+		root:     root,
+		embedder: embedder,
+		files:    make(map[string]*file),
+	}
+}
+
+// Search returns the limit chunks closest to query, most similar first,
+// embedding the files added or changed since the last search beforehand.
+// The files for which exclude, when not nil, returns true given their
+// absolute path are neither embedded nor returned.
+func (ix *Index) Search(ctx context.Context, query string, limit int, exclude func(path string) bool) ([]Result, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if err := ix.refresh(ctx, exclude); err != nil {
+		return nil, err
+	}
+	vectors, err := ix.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed the query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, errors.New("failed to embed the query: no embedding returned")
+	}
+
+	var results []Result
+	for rel, f := range ix.files {
+		for _, c := range f.chunks {
+			results = append(results, Result{
+				Path:      rel,
+				StartLine: c.startLine,
+				EndLine:   c.endLine,
+				Content:   c.content,
+				Score:     cosine(vectors[0], c.vector),
+			})
+		}
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			strings.Compare(a.Path, b.Path),
+			cmp.Compare(a.StartLine, b.StartLine),
+		)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// refresh embeds the files added or changed, and forgets the ones removed
+// or excluded. When embedding fails, the files embedded until then are kept.
+func (ix *Index) refresh(ctx context.Context, exclude func(path string) bool) error {
+	paths, _, err := fsext.ListDirectory(ix.root, nil, 0, maxFiles)
+	if err != nil {
+		return fmt.Errorf("failed to list the files to index: %w", err)
+	}
+	crushIgnore := fsext.NewCrushIgnore(ix.root)
+
+	seen := make(map[string]bool, len(ix.files))
+	var batch []*chunk
+	var inputs []string
+	pending := make(map[string]*file)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		vectors, err := ix.embedder.Embed(ctx, inputs)
+		if err != nil {
+			return fmt.Errorf("failed to embed the files: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("failed to embed the files: got %d embeddings for %d chunks", len(vectors), len(batch))
+		}
+		for i, c := range batch {
+			c.vector = vectors[i]
+		}
+		for rel, f := range pending {
+			ix.files[rel] = f
+		}
+		batch, inputs = nil, nil
+		clear(pending)
+		return nil
+	}
+
+	for _, path := range paths {
+		if !slices.Contains(sourceExtensions, filepath.Ext(path)) || crushIgnore.Ignores(path) {
+			continue
+		}
+		if exclude != nil && exclude(path) {
+			continue
+		}
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() > maxFileSize {
+			continue
+		}
+		seen[rel] = true
+		if f, ok := ix.files[rel]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			delete(seen, rel)
+			continue
+		}
+		f := &file{modTime: info.ModTime(), size: info.Size(), chunks: chunkFile(string(content))}
+		pending[rel] = f
+		for _, c := range f.chunks {
+			batch = append(batch, c)
+			inputs = append(inputs, embeddingInput(rel, c))
+		}
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	for rel := range ix.files {
+		if !seen[rel] {
+			delete(ix.files, rel)
+		}
+	}
+	return nil
+}
+
+// chunkFile cuts content in chunks of chunkLines lines, leaving out the
+// blank ones.
+func chunkFile(content string) []*chunk {
+	lines := strings.Split(content, "\n")
+	var chunks []*chunk
+	for start := 0; start < len(lines); start += chunkLines {
+		end := min(start+chunkLines, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		chunks = append(chunks, &chunk{
+			startLine: start + 1,
+			endLine:   end,
+			content:   text,
+		})
+	}
+	return chunks
+}
+
+// embeddingInput returns the text embedded for a chunk: its content headed
+// by the path of its file, for the path to count, cut to maxChunkSize.
+func embeddingInput(rel string, c *chunk) string {
+	input := rel + "\n" + c.content
+	if len(input) > maxChunkSize {
+		input = strings.ToValidUTF8(input[:maxChunkSize], "")
+	}
+	return input
+}
+
+// cosine returns the cosine similarity of a and b.
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
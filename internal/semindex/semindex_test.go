@@ -0,0 +1,96 @@
+package semindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// wordEmbedder embeds a text as the counts of a few words in it.
+type wordEmbedder struct {
+	embedded []string
+}
+
+var words = []string{"apple", "banana", "cherry"}
+
+func (e *wordEmbedder) Embed(_ context.Context, inputs []string) ([][]float32, error) {
+	e.embedded = append(e.embedded, inputs...)
+	vectors := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		for _, word := range words {
+			vectors[i] = append(vectors[i], float32(strings.Count(input, word)))
+		}
+	}
+	return vectors, nil
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(rel)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, rel), []byte(content), 0o644))
+	}
+	write("fruit/apple.go", "package fruit\n\n// apple apple\nfunc Apple() {}\n")
+	write("fruit/banana.go", "package fruit\n\n// banana\nfunc Banana() {}\n")
+	write("notes.txt", "apple\n")
+
+	embedder := &wordEmbedder{}
+	ix := New(root, embedder)
+	paths := func(results []Result) []string {
+		var paths []string
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+
+	results, err := ix.Search(t.Context(), "apple", 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fruit/apple.go", "fruit/banana.go"}, paths(results))
+	require.Equal(t, Result{
+		Path:      "fruit/apple.go",
+		StartLine: 1,
+		EndLine:   5,
+		Content:   "package fruit\n\n// apple apple\nfunc Apple() {}\n",
+		Score:     1,
+	}, results[0])
+	require.Len(t, embedder.embedded, 3, "two chunks and the query")
+
+	// Only the files changed are embedded again.
+	embedder.embedded = nil
+	write("fruit/banana.go", "package fruit\n\n// banana apple apple apple\nfunc Banana() {}\n")
+	results, err = ix.Search(t.Context(), "banana", 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fruit/banana.go"}, paths(results))
+	require.Equal(t, []string{"fruit/banana.go\npackage fruit\n\n// banana apple apple apple\nfunc Banana() {}\n", "banana"}, embedder.embedded)
+
+	// Removed and excluded files are forgotten.
+	require.NoError(t, os.Remove(filepath.Join(root, "fruit/apple.go")))
+	exclude := func(path string) bool { return strings.HasSuffix(path, "banana.go") }
+	results, err = ix.Search(t.Context(), "apple", 10, exclude)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestChunkFile(t *testing.T) {
+	t.Parallel()
+
+	lines := make([]string, chunkLines+10)
+	for i := range lines {
+		lines[i] = "x"
+	}
+	for i := chunkLines - 5; i < chunkLines+10; i++ {
+		lines[i] = ""
+	}
+	chunks := chunkFile(strings.Join(lines, "\n"))
+	require.Len(t, chunks, 1, "blank chunks are left out")
+	require.Equal(t, 1, chunks[0].startLine)
+	require.Equal(t, chunkLines, chunks[0].endLine)
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTags returns the tags written in s, separated by commas or spaces,
+// lowercased and without duplicates.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		tag := strings.ToLower(strings.TrimPrefix(field, "#"))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Filter selects sessions by tag, date and project.
+type Filter struct {
+	// Tags are the tags sessions must all have.
+	Tags []string
+	// Project is part of the name of the project of the sessions.
+	Project string
+	// After and Before bound the time the sessions were last updated.
+	After, Before time.Time
+}
+
+// ParseFilter reads the filters written in query, returning them and the
+// rest of the query. Filters are written like "#tag", "project:name",
+// "after:date" and "before:date", dates being like "2025-01-31", "today",
+// "yesterday" or a number of days or weeks ago like "3d" or "2w". Invalid
+// dates, like the ones being typed, are ignored.
+func ParseFilter(query string, now time.Time) (Filter, string) {
+	var (
+		filter Filter
+		rest   []string
+	)
+	for field := range strings.FieldsSeq(query) {
+		name, value, ok := strings.Cut(field, ":")
+		switch {
+		case strings.HasPrefix(field, "#") && len(field) > 1:
+			filter.Tags = append(filter.Tags, strings.ToLower(field[1:]))
+		case ok && name == "project":
+			filter.Project = strings.ToLower(value)
+		case ok && (name == "after" || name == "before"):
+			date, ok := parseDate(value, now)
+			if !ok {
+				continue
+			}
+			if name == "after" {
+				filter.After = date
+			} else {
+				filter.Before = date
+			}
+		default:
+			rest = append(rest, field)
+		}
+	}
+	return filter, strings.Join(rest, " ")
+}
+
+// parseDate returns the start of the day written in s.
+func parseDate(s string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+	if n, err := strconv.Atoi(strings.TrimRight(s, "dw")); err == nil && len(s) > 1 {
+		switch s[len(s)-1] {
+		case 'd':
+			return today.AddDate(0, 0, -n), true
+		case 'w':
+			return today.AddDate(0, 0, -7*n), true
+		}
+	}
+	date, err := time.ParseInLocation(time.DateOnly, s, now.Location())
+	return date, err == nil
+}
+
+// IsZero reports whether the filter selects every session.
+func (f Filter) IsZero() bool {
+	return len(f.Tags) == 0 && f.Project == "" && f.After.IsZero() && f.Before.IsZero()
+}
+
+// Match reports whether the filter selects s.
+func (f Filter) Match(s Session) bool {
+	for _, tag := range f.Tags {
+		if !slices.Contains(s.Tags, tag) {
+			return false
+		}
+	}
+	if f.Project != "" && !strings.Contains(strings.ToLower(filepath.Base(s.Project)), f.Project) {
+		return false
+	}
+	updated := time.Unix(s.UpdatedAt, 0)
+	if !f.After.IsZero() && updated.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !updated.Before(f.Before) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"bug", "auth"}, ParseTags("#Bug, auth bug"))
+	require.Nil(t, ParseTags(" , "))
+}
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 3, 10, 15, 4, 0, 0, time.UTC)
+
+	t.Run("filters and rest", func(t *testing.T) {
+		t.Parallel()
+
+		filter, rest := ParseFilter("login #Auth project:Crush fix after:2025-01-31 before:yesterday", now)
+		require.Equal(t, "login fix", rest)
+		require.Equal(t, []string{"auth"}, filter.Tags)
+		require.Equal(t, "crush", filter.Project)
+		require.Equal(t, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), filter.After)
+		require.Equal(t, time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC), filter.Before)
+	})
+
+	t.Run("relative dates", func(t *testing.T) {
+		t.Parallel()
+
+		filter, _ := ParseFilter("after:2w before:3d", now)
+		require.Equal(t, time.Date(2025, 2, 24, 0, 0, 0, 0, time.UTC), filter.After)
+		require.Equal(t, time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC), filter.Before)
+	})
+
+	t.Run("invalid dates are ignored", func(t *testing.T) {
+		t.Parallel()
+
+		filter, rest := ParseFilter("after:2025-0", now)
+		require.True(t, filter.IsZero())
+		require.Empty(t, rest)
+	})
+}
+
+func TestFilterMatch(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 3, 10, 15, 4, 0, 0, time.UTC)
+	sess := Session{
+		Tags:      []string{"bug", "auth"},
+		Project:   "/home/user/src/crush",
+		UpdatedAt: time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC).Unix(),
+	}
+
+	for query, want := range map[string]bool{
+		"":                          true,
+		"#bug":                      true,
+		"#bug #auth":                true,
+		"#bug #ui":                  false,
+		"project:cru":               true,
+		"project:src":               false,
+		"after:3d":                  true,
+		"after:today":               false,
+		"before:2025-03-08":         false,
+		"before:2025-03-09 #auth":   true,
+		"after:2025-03-09 #auth":    false,
+		"project:crush after:1w #b": false,
+	} {
+		filter, _ := ParseFilter(query, now)
+		require.Equal(t, want, filter.Match(sess), query)
+	}
+}
@@ -3,7 +3,9 @@ package session
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/charmbracelet/crush/internal/db"
@@ -23,6 +25,45 @@ type Session struct {
 	Cost             float64
 	CreatedAt        int64
 	UpdatedAt        int64
+
+	// AccountProvider and Account identify the provider account the session
+	// was created with, so resuming it uses the same identity.
+	AccountProvider string
+	Account         string
+
+	// TotalPromptTokens and TotalCompletionTokens add up the usage of every
+	// request, while PromptTokens and CompletionTokens only hold the latest
+	// request, i.e. the current context size.
+	TotalPromptTokens     int64
+	TotalCompletionTokens int64
+
+	// SummaryKeepFromID is the oldest message kept verbatim after the
+	// summary, when only older turns were compacted.
+	SummaryKeepFromID string
+
+	// TotalCacheReadTokens and TotalCacheWriteTokens add up the prompt
+	// tokens read from and written to the provider's prompt cache.
+	TotalCacheReadTokens  int64
+	TotalCacheWriteTokens int64
+
+	// ForkedFromID is the session this one was forked from, at the message
+	// ForkedAtMessageID of it, to explore another approach.
+	ForkedFromID      string
+	ForkedAtMessageID string
+
+	// Tags are the labels the user gave the session, and Project the
+	// working directory it was created in.
+	Tags    []string
+	Project string
+}
+
+// Usage is what requests add to the cost and the totals of a session.
+type Usage struct {
+	Cost             float64
+	PromptTokens     int64
+	CompletionTokens int64
+	CacheReadTokens  int64
+	CacheWriteTokens int64
 }
 
 type Service interface {
@@ -32,7 +73,13 @@ type Service interface {
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
+	// Save updates the session, but for its cost and totals, which only
+	// AddUsage changes.
 	Save(ctx context.Context, session Session) (Session, error)
+	// AddUsage adds usage to the cost and the totals of the session in a
+	// single update, so that the runs sharing it, such as the background
+	// agents of its parent, don't overwrite each other's.
+	AddUsage(ctx context.Context, id string, usage Usage) (Session, error)
 	Delete(ctx context.Context, id string) error
 
 	// Agent tool session management
@@ -43,13 +90,15 @@ type Service interface {
 
 type service struct {
 	*pubsub.Broker[Session]
-	q db.Querier
+	q       db.Querier
+	project string
 }
 
 func (s *service) Create(ctx context.Context, title string) (Session, error) {
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
-		ID:    uuid.New().String(),
-		Title: title,
+		ID:      uuid.New().String(),
+		Title:   title,
+		Project: s.project,
 	})
 	if err != nil {
 		return Session{}, err
@@ -111,6 +160,13 @@ func (s *service) Get(ctx context.Context, id string) (Session, error) {
 }
 
 func (s *service) Save(ctx context.Context, session Session) (Session, error) {
+	tags := []byte("[]")
+	if len(session.Tags) > 0 {
+		var err error
+		if tags, err = json.Marshal(session.Tags); err != nil {
+			return Session{}, err
+		}
+	}
 	dbSession, err := s.q.UpdateSession(ctx, db.UpdateSessionParams{
 		ID:               session.ID,
 		Title:            session.Title,
@@ -120,7 +176,27 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 			String: session.SummaryMessageID,
 			Valid:  session.SummaryMessageID != "",
 		},
-		Cost: session.Cost,
+		AccountProvider: sql.NullString{
+			String: session.AccountProvider,
+			Valid:  session.AccountProvider != "",
+		},
+		Account: sql.NullString{
+			String: session.Account,
+			Valid:  session.Account != "",
+		},
+		SummaryKeepFromID: sql.NullString{
+			String: session.SummaryKeepFromID,
+			Valid:  session.SummaryKeepFromID != "",
+		},
+		ForkedFromSessionID: sql.NullString{
+			String: session.ForkedFromID,
+			Valid:  session.ForkedFromID != "",
+		},
+		ForkedAtMessageID: sql.NullString{
+			String: session.ForkedAtMessageID,
+			Valid:  session.ForkedAtMessageID != "",
+		},
+		Tags: string(tags),
 	})
 	if err != nil {
 		return Session{}, err
@@ -130,6 +206,23 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 	return session, nil
 }
 
+func (s *service) AddUsage(ctx context.Context, id string, usage Usage) (Session, error) {
+	dbSession, err := s.q.AddSessionUsage(ctx, db.AddSessionUsageParams{
+		ID:                    id,
+		Cost:                  usage.Cost,
+		TotalPromptTokens:     usage.PromptTokens,
+		TotalCompletionTokens: usage.CompletionTokens,
+		TotalCacheReadTokens:  usage.CacheReadTokens,
+		TotalCacheWriteTokens: usage.CacheWriteTokens,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
 func (s *service) List(ctx context.Context) ([]Session, error) {
 	dbSessions, err := s.q.ListSessions(ctx)
 	if err != nil {
@@ -143,6 +236,10 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 }
 
 func (s service) fromDBItem(item db.Session) Session {
+	var tags []string
+	if err := json.Unmarshal([]byte(item.Tags), &tags); err != nil {
+		slog.Warn("Failed to parse session tags", "session_id", item.ID, "error", err)
+	}
 	return Session{
 		ID:               item.ID,
 		ParentSessionID:  item.ParentSessionID.String,
@@ -154,14 +251,28 @@ func (s service) fromDBItem(item db.Session) Session {
 		Cost:             item.Cost,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
+		AccountProvider:  item.AccountProvider.String,
+		Account:          item.Account.String,
+
+		TotalPromptTokens:     item.TotalPromptTokens,
+		TotalCompletionTokens: item.TotalCompletionTokens,
+		SummaryKeepFromID:     item.SummaryKeepFromID.String,
+		TotalCacheReadTokens:  item.TotalCacheReadTokens,
+		TotalCacheWriteTokens: item.TotalCacheWriteTokens,
+		ForkedFromID:          item.ForkedFromSessionID.String,
+		ForkedAtMessageID:     item.ForkedAtMessageID.String,
+		Tags:                  tags,
+		Project:               item.Project,
 	}
 }
 
-func NewService(q db.Querier) Service {
+// NewService creates the session service, creating sessions in project.
+func NewService(q db.Querier, project string) Service {
 	broker := pubsub.NewBroker[Session]()
 	return &service{
 		broker,
 		q,
+		project,
 	}
 }
 
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddUsage(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	s := NewService(db.New(conn), "")
+
+	sess, err := s.Create(t.Context(), "Session")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Go(func() {
+			_, err := s.AddUsage(t.Context(), sess.ID, Usage{Cost: 0.5, PromptTokens: 100, CompletionTokens: 10, CacheReadTokens: 2, CacheWriteTokens: 1})
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Saving a stale copy of the session keeps the usage added since.
+	sess.Title = "Renamed"
+	sess, err = s.Save(t.Context(), sess)
+	require.NoError(t, err)
+	require.Equal(t, "Renamed", sess.Title)
+	require.InDelta(t, 5.0, sess.Cost, 1e-9)
+	require.Equal(t, int64(1000), sess.TotalPromptTokens)
+	require.Equal(t, int64(100), sess.TotalCompletionTokens)
+	require.Equal(t, int64(20), sess.TotalCacheReadTokens)
+	require.Equal(t, int64(10), sess.TotalCacheWriteTokens)
+}
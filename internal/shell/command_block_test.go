@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -374,3 +376,37 @@ func TestSplitArgsFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestExcludeFunc(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "public.txt"), []byte("public"), 0o644))
+
+	shell := NewShell(&Options{WorkingDir: dir})
+	shell.SetExcludeFunc(func(path string) bool {
+		return path == filepath.Join(dir, "secret.txt")
+	})
+
+	for _, command := range []string{
+		"cat secret.txt",
+		"cat *.txt",
+		"cd sub && cat ../secret.txt",
+		"cat < secret.txt",
+		"echo leak > secret.txt",
+	} {
+		stdout, _, err := shell.Exec(t.Context(), command)
+		require.Error(t, err, command)
+		require.NotContains(t, stdout, "secret", command)
+		require.NoError(t, shell.SetWorkingDir(dir))
+	}
+
+	stdout, _, err := shell.Exec(t.Context(), "cat public.txt")
+	require.NoError(t, err)
+	require.Equal(t, "public", stdout)
+	content, err := os.ReadFile(filepath.Join(dir, "secret.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(content))
+}
@@ -0,0 +1,53 @@
+package shell
+
+import (
+	"os"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/csync"
+)
+
+// SessionShells keeps a shell per session, so that the working directory and
+// environment variables set by a command, like with cd, export or by
+// activating a virtualenv, carry over to the next commands of the session.
+type SessionShells struct {
+	shells *csync.Map[string, *Shell]
+}
+
+var (
+	sessionShells     *SessionShells
+	sessionShellsOnce sync.Once
+)
+
+// GetSessionShells returns the singleton keeping the shells of the sessions.
+func GetSessionShells() *SessionShells {
+	sessionShellsOnce.Do(func() {
+		sessionShells = &SessionShells{
+			shells: csync.NewMap[string, *Shell](),
+		}
+	})
+	return sessionShells
+}
+
+// Get returns the shell of a session, starting a new one in workingDir if
+// there is none. When the working directory of the shell was removed since,
+// it's moved back to workingDir.
+func (m *SessionShells) Get(sessionID, workingDir string, blockFuncs []BlockFunc) *Shell {
+	shell := m.shells.GetOrSet(sessionID, func() *Shell {
+		return NewShell(&Options{
+			WorkingDir: workingDir,
+			BlockFuncs: blockFuncs,
+		})
+	})
+	if _, err := os.Stat(shell.GetWorkingDir()); err != nil {
+		_ = shell.SetWorkingDir(workingDir)
+	}
+	return shell
+}
+
+// Reset forgets the shell of a session, so that its next command starts from
+// a new one. It reports whether the session had a shell.
+func (m *SessionShells) Reset(sessionID string) bool {
+	_, ok := m.shells.Take(sessionID)
+	return ok
+}
@@ -0,0 +1,62 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/csync"
+)
+
+func TestSessionShells(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	subDir := filepath.Join(workingDir, "sub")
+	if err := os.Mkdir(subDir, 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	shells := &SessionShells{shells: csync.NewMap[string, *Shell]()}
+	session := shells.Get("session", workingDir, nil)
+
+	// A fork runs the command, and the session adopts its state.
+	fork := session.Fork()
+	if _, _, err := fork.Exec(t.Context(), "cd sub && export FOO=bar"); err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if session.GetWorkingDir() != workingDir {
+		t.Fatalf("expected the fork not to change the session shell, got %s", session.GetWorkingDir())
+	}
+	session.Adopt(fork)
+
+	stdout, _, err := shells.Get("session", workingDir, nil).Exec(t.Context(), "pwd; echo $FOO")
+	if err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if got := strings.Fields(stdout); len(got) != 2 || got[0] != subDir || got[1] != "bar" {
+		t.Fatalf("expected the directory and variable to persist, got %q", stdout)
+	}
+
+	// Other sessions have their own shell.
+	if dir := shells.Get("other", workingDir, nil).GetWorkingDir(); dir != workingDir {
+		t.Fatalf("expected a new shell in %s, got %s", workingDir, dir)
+	}
+
+	// A removed working directory falls back to the default one.
+	if err := os.Remove(subDir); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+	if dir := shells.Get("session", workingDir, nil).GetWorkingDir(); dir != workingDir {
+		t.Fatalf("expected the shell to move back to %s, got %s", workingDir, dir)
+	}
+
+	if !shells.Reset("session") {
+		t.Fatal("expected the session to have a shell")
+	}
+	if env := shells.Get("session", workingDir, nil).GetEnv(); slices.Contains(env, "FOO=bar") {
+		t.Fatal("expected a reset shell to have the default environment")
+	}
+}
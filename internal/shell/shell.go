@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
@@ -46,9 +47,17 @@ type noopLogger struct{}
 
 func (noopLogger) InfoPersist(msg string, keysAndValues ...any) {}
 
+// ErrShellCrashed is returned when the interpreter panics while running a
+// command, leaving the state of the shell unknown.
+var ErrShellCrashed = errors.New("shell crashed")
+
 // BlockFunc is a function that determines if a command should be blocked
 type BlockFunc func(args []string) bool
 
+// ExcludeFunc reports whether commands must not touch the file at the
+// absolute path.
+type ExcludeFunc func(path string) bool
+
 // Shell provides cross-platform shell execution with optional state persistence
 type Shell struct {
 	env        []string
@@ -56,6 +65,7 @@ type Shell struct {
 	mu         sync.Mutex
 	logger     Logger
 	blockFuncs []BlockFunc
+	exclude    ExcludeFunc
 }
 
 // Options for creating a new shell
@@ -158,6 +168,32 @@ func (s *Shell) SetEnv(key, value string) {
 	s.env = append(s.env, keyPrefix+value)
 }
 
+// Fork returns a new shell starting with the working directory, environment
+// and block functions of s. Commands run in the fork don't change s.
+func (s *Shell) Fork() *Shell {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &Shell{
+		cwd:        s.cwd,
+		env:        slices.Clone(s.env),
+		logger:     s.logger,
+		blockFuncs: s.blockFuncs,
+		exclude:    s.exclude,
+	}
+}
+
+// Adopt replaces the working directory and environment of s with the ones of
+// other, usually a fork of s that ran a command.
+func (s *Shell) Adopt(other *Shell) {
+	cwd, env := other.GetWorkingDir(), other.GetEnv()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cwd = cwd
+	s.env = env
+}
+
 // SetBlockFuncs sets the command block functions for the shell
 func (s *Shell) SetBlockFuncs(blockFuncs []BlockFunc) {
 	s.mu.Lock()
@@ -165,6 +201,14 @@ func (s *Shell) SetBlockFuncs(blockFuncs []BlockFunc) {
 	s.blockFuncs = blockFuncs
 }
 
+// SetExcludeFunc sets the function telling the files that commands must not
+// touch, whether as arguments or in redirections.
+func (s *Shell) SetExcludeFunc(exclude ExcludeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exclude = exclude
+}
+
 // CommandsBlocker creates a BlockFunc that blocks exact command matches
 func CommandsBlocker(cmds []string) BlockFunc {
 	bannedSet := make(map[string]struct{})
@@ -230,12 +274,45 @@ func (s *Shell) blockHandler() func(next interp.ExecHandlerFunc) interp.ExecHand
 					return fmt.Errorf("command is not allowed for security reasons: %s", strings.Join(args, " "))
 				}
 			}
+			if s.exclude != nil {
+				dir := interp.HandlerCtx(ctx).Dir
+				for _, arg := range args[1:] {
+					// Flags may carry their path after an equal sign.
+					if strings.HasPrefix(arg, "-") {
+						_, arg, _ = strings.Cut(arg, "=")
+					}
+					if arg != "" && s.exclude(absPath(dir, arg)) {
+						return fmt.Errorf("command is not allowed to access excluded file: %s", arg)
+					}
+				}
+			}
 
 			return next(ctx, args)
 		}
 	}
 }
 
+// openHandler opens the files of redirections, unless they're excluded.
+func (s *Shell) openHandler() interp.OpenHandlerFunc {
+	open := interp.DefaultOpenHandler()
+	return func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+		if s.exclude != nil && path != "" && s.exclude(absPath(interp.HandlerCtx(ctx).Dir, path)) {
+			return nil, &os.PathError{Op: "open", Path: path, Err: errExcluded}
+		}
+		return open(ctx, path, flag, perm)
+	}
+}
+
+var errExcluded = errors.New("file is excluded")
+
+// absPath returns path as an absolute path, relative to dir when it isn't.
+func absPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
 // newInterp creates a new interpreter with the current shell state
 func (s *Shell) newInterp(stdout, stderr io.Writer) (*interp.Runner, error) {
 	return interp.New(
@@ -244,6 +321,7 @@ func (s *Shell) newInterp(stdout, stderr io.Writer) (*interp.Runner, error) {
 		interp.Env(expand.ListEnviron(s.env...)),
 		interp.Dir(s.cwd),
 		interp.ExecHandlers(s.execHandlers()...),
+		interp.OpenHandler(s.openHandler()),
 	)
 }
 
@@ -257,7 +335,14 @@ func (s *Shell) updateShellFromRunner(runner *interp.Runner) {
 }
 
 // execCommon is the shared implementation for executing commands
-func (s *Shell) execCommon(ctx context.Context, command string, stdout, stderr io.Writer) error {
+func (s *Shell) execCommon(ctx context.Context, command string, stdout, stderr io.Writer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.InfoPersist("shell crashed", "command", command, "panic", r)
+			err = fmt.Errorf("%w: %v", ErrShellCrashed, r)
+		}
+	}()
+
 	line, err := syntax.NewParser().Parse(strings.NewReader(command), "")
 	if err != nil {
 		return fmt.Errorf("could not parse command: %w", err)
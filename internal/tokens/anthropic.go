@@ -0,0 +1,168 @@
+package tokens
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	// countTimeout bounds the time taken to count the tokens of a request,
+	// as prompts wait for it.
+	countTimeout = 10 * time.Second
+)
+
+// AnthropicCounter counts the tokens of the requests with the token counting
+// endpoint of the Anthropic API, which is free and separately rate limited.
+type AnthropicCounter struct {
+	client  *http.Client
+	baseURL string
+	model   string
+	headers map[string]string
+	// unsupported is set once the API turns out not to have the endpoint,
+	// as with some of the Anthropic compatible ones.
+	unsupported atomic.Bool
+}
+
+// ErrUnsupported is returned when the API can't count tokens.
+var ErrUnsupported = errors.New("token counting isn't supported")
+
+// NewAnthropicCounter returns a counter for model, calling the API at baseURL
+// or the default one with the API key, sent as the Authorization header when
+// it starts with "Bearer ", and the extra headers.
+func NewAnthropicCounter(baseURL, apiKey, model string, headers map[string]string) *AnthropicCounter {
+	c := &AnthropicCounter{
+		client:  &http.Client{Timeout: countTimeout},
+		baseURL: strings.TrimSuffix(cmp.Or(baseURL, anthropicBaseURL), "/"),
+		model:   model,
+		headers: map[string]string{"anthropic-version": "2023-06-01"},
+	}
+	switch {
+	case strings.HasPrefix(apiKey, "Bearer "):
+		c.headers["Authorization"] = apiKey
+	case apiKey != "":
+		c.headers["x-api-key"] = apiKey
+	}
+	for k, v := range headers {
+		c.headers[k] = v
+	}
+	return c
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicCountRequest struct {
+	Model    string             `json:"model"`
+	System   string             `json:"system,omitempty"`
+	Messages []anthropicMessage `json:"messages"`
+	Tools    []anthropicTool    `json:"tools,omitempty"`
+}
+
+// Count implements Counter. Messages are sent as text, merged by role, and
+// files are estimated rather than uploaded.
+func (c *AnthropicCounter) Count(ctx context.Context, msgs []fantasy.Message, tools []fantasy.AgentTool) (int64, error) {
+	if c.unsupported.Load() {
+		return 0, ErrUnsupported
+	}
+	request := anthropicCountRequest{Model: c.model}
+	var system []string
+	var media int64
+	for _, msg := range msgs {
+		var texts []string
+		for _, part := range msg.Content {
+			text, isMedia := partText(part)
+			if isMedia {
+				media += mediaTokens
+				continue
+			}
+			if text != "" {
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		text := strings.Join(texts, "\n\n")
+		role := "user"
+		switch msg.Role {
+		case fantasy.MessageRoleSystem:
+			system = append(system, text)
+			continue
+		case fantasy.MessageRoleAssistant:
+			role = "assistant"
+		}
+		if n := len(request.Messages); n > 0 && request.Messages[n-1].Role == role {
+			request.Messages[n-1].Content += "\n\n" + text
+			continue
+		}
+		request.Messages = append(request.Messages, anthropicMessage{Role: role, Content: text})
+	}
+	if len(request.Messages) == 0 {
+		return media, nil
+	}
+	request.System = strings.Join(system, "\n\n")
+	for _, tool := range tools {
+		info := tool.Info()
+		schema := map[string]any{"type": "object", "properties": info.Parameters}
+		if len(info.Required) > 0 {
+			schema["required"] = info.Required
+		}
+		request.Tools = append(request.Tools, anthropicTool{Name: info.Name, Description: info.Description, InputSchema: schema})
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal token count request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages/count_tokens", bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token count request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read token count: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		c.unsupported.Store(true)
+		return 0, ErrUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to count tokens: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var response struct {
+		InputTokens int64 `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("failed to parse token count: %w", err)
+	}
+	return response.InputTokens + media, nil
+}
@@ -0,0 +1,124 @@
+// Package tokens counts the tokens of the requests sent to the models, for
+// the providers that don't report them.
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"unicode"
+
+	"charm.land/fantasy"
+)
+
+const (
+	// messageOverhead is the number of tokens framing each message.
+	messageOverhead = 4
+	// mediaTokens is the number of tokens counted for a file or an image.
+	mediaTokens = 1500
+)
+
+// Counter counts the tokens of a request.
+type Counter interface {
+	// Count returns the number of input tokens of a request sending msgs,
+	// system prompts included, and tools.
+	Count(ctx context.Context, msgs []fantasy.Message, tools []fantasy.AgentTool) (int64, error)
+}
+
+// Estimator estimates the tokens of the requests from their text, for the
+// models without a way to count them.
+type Estimator struct{}
+
+// Count implements Counter.
+func (Estimator) Count(_ context.Context, msgs []fantasy.Message, tools []fantasy.AgentTool) (int64, error) {
+	var count int64
+	for _, tool := range tools {
+		count += Estimate(toolText(tool))
+	}
+	for _, msg := range msgs {
+		count += messageOverhead
+		for _, part := range msg.Content {
+			text, media := partText(part)
+			if media {
+				count += mediaTokens
+				continue
+			}
+			count += Estimate(text)
+		}
+	}
+	return count, nil
+}
+
+// Estimate estimates the tokens of text as the byte pair encodings of the
+// models split it: a token per five letters of a word, per three digits of
+// a number and per four spaces of indentation, with a single space merged
+// into the word that follows it, and a token for every other symbol or
+// character of a non-Latin script.
+func Estimate(text string) int64 {
+	var count float64
+	letters, digits, spaces := 0, 0, 0
+	flush := func() {
+		count += math.Ceil(float64(letters)/5) + math.Ceil(float64(digits)/3)
+		if spaces > 1 {
+			count += math.Ceil(float64(spaces-1) / 4)
+		}
+		letters, digits, spaces = 0, 0, 0
+	}
+	for _, r := range text {
+		switch {
+		case r == ' ' || r == '\t':
+			if letters+digits > 0 {
+				flush()
+			}
+			spaces++
+		case unicode.Is(unicode.Latin, r):
+			if digits > 0 {
+				flush()
+			}
+			letters++
+		case unicode.IsDigit(r):
+			if letters > 0 {
+				flush()
+			}
+			digits++
+		default:
+			flush()
+			count++
+		}
+	}
+	flush()
+	return int64(count)
+}
+
+// partText returns the text of part, or whether it's a file or an image.
+func partText(part fantasy.MessagePart) (string, bool) {
+	switch part := part.(type) {
+	case fantasy.TextPart:
+		return part.Text, false
+	case fantasy.ReasoningPart:
+		return part.Text, false
+	case fantasy.FilePart:
+		return "", true
+	case fantasy.ToolCallPart:
+		return part.ToolName + " " + part.Input, false
+	case fantasy.ToolResultPart:
+		switch output := part.Output.(type) {
+		case fantasy.ToolResultOutputContentText:
+			return output.Text, false
+		case fantasy.ToolResultOutputContentError:
+			if output.Error != nil {
+				return output.Error.Error(), false
+			}
+		case fantasy.ToolResultOutputContentMedia:
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// toolText returns the definition of tool as the models read it.
+func toolText(tool fantasy.AgentTool) string {
+	info := tool.Info()
+	schema, _ := json.Marshal(info.Parameters)
+	return info.Name + "\n" + info.Description + "\n" + string(schema)
+}
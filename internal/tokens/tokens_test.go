@@ -0,0 +1,79 @@
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	t.Parallel()
+
+	require.Zero(t, Estimate(""))
+	require.Equal(t, int64(2), Estimate("Hello there"))
+	require.Equal(t, int64(4), Estimate("internationalization"), "long words take a token per five letters")
+	require.Equal(t, int64(2), Estimate("123456"))
+	require.Equal(t, int64(6), Estimate("a.b(c)"))
+	require.Equal(t, int64(4), Estimate("\n        x"), "indentation")
+	require.Equal(t, int64(3), Estimate("日本語"))
+}
+
+func TestEstimatorCount(t *testing.T) {
+	t.Parallel()
+
+	count, err := Estimator{}.Count(t.Context(), []fantasy.Message{
+		fantasy.NewSystemMessage("Be brief"),
+		fantasy.NewUserMessage("Hello there"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.ToolCallPart{ToolName: "view", Input: `{}`}}},
+	}, nil)
+	require.NoError(t, err)
+	// The overhead of 3 messages, "Be brief", "Hello there" and "view {}".
+	require.Equal(t, int64(3*messageOverhead+2+2+3), count)
+}
+
+func TestAnthropicCounter(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages/count_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"input_tokens": 42}`))
+	}))
+	t.Cleanup(server.Close)
+
+	counter := NewAnthropicCounter(server.URL, "key", "claude-sonnet-4", map[string]string{"X-Extra": "extra"})
+	count, err := counter.Count(t.Context(), []fantasy.Message{
+		fantasy.NewSystemMessage("Be brief"),
+		fantasy.NewUserMessage("Hello"),
+		fantasy.NewUserMessage("there"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "Hi"}}},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+	require.Equal(t, "key", header.Get("x-api-key"))
+	require.Equal(t, "2023-06-01", header.Get("anthropic-version"))
+	require.Equal(t, "extra", header.Get("X-Extra"))
+	var received anthropicCountRequest
+	require.NoError(t, json.Unmarshal(body, &received))
+	require.Equal(t, "claude-sonnet-4", received.Model)
+	require.Equal(t, "Be brief", received.System)
+	require.Equal(t, []anthropicMessage{{Role: "user", Content: "Hello\n\nthere"}, {Role: "assistant", Content: "Hi"}}, received.Messages)
+
+	// APIs without the endpoint aren't asked again.
+	counter = NewAnthropicCounter(server.URL+"/compatible", "key", "glm-4.6", nil)
+	_, err = counter.Count(t.Context(), []fantasy.Message{fantasy.NewUserMessage("Hello")}, nil)
+	require.True(t, errors.Is(err, ErrUnsupported))
+	require.True(t, counter.unsupported.Load())
+}
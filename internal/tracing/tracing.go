@@ -0,0 +1,89 @@
+// Package tracing exports traces of the agent runs with OTLP, with spans for
+// the requests to the models, the tool calls and the token refreshes, for
+// finding slow or failing steps of runs, in CI especially.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/charmbracelet/crush"
+	serviceName = "crush"
+	// tracesPath is where OTLP collectors receive traces over HTTP.
+	tracesPath = "/v1/traces"
+)
+
+// Setup exports the traces to endpoint, the URL of an OTLP collector receiving
+// them over HTTP, sending headers along. Without an endpoint, the standard
+// OTEL_EXPORTER_OTLP_* variables configure the export, and tracing stays off
+// when they don't set one either. The returned function sends the spans left
+// and stops the export.
+func Setup(ctx context.Context, endpoint string, headers map[string]string, version string) (func(context.Context) error, error) {
+	if endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid tracing endpoint %q", endpoint)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = tracesPath
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(u.String()))
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx, if any. Spans
+// are dropped when tracing is off.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartTokenRefresh starts the span of the refresh of the token of provider,
+// a provider of models or an MCP server.
+func StartTokenRefresh(ctx context.Context, provider string) (context.Context, trace.Span) {
+	return Start(ctx, "refresh_token", attribute.String("crush.token.provider", provider))
+}
+
+// End ends span, marking it as failed with err if not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := Setup(t.Context(), "", nil, "test")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(t.Context()), "tracing is off without an endpoint")
+
+	_, err = Setup(t.Context(), "localhost", nil, "test")
+	require.Error(t, err)
+
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	t.Cleanup(server.Close)
+
+	shutdown, err = Setup(t.Context(), server.URL, map[string]string{"Authorization": "Bearer token"}, "test")
+	require.NoError(t, err)
+	_, span := Start(t.Context(), "run")
+	End(span, errors.New("failed"))
+	require.NoError(t, shutdown(t.Context()))
+
+	r := <-received
+	require.Equal(t, "/v1/traces", r.URL.Path)
+	require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+}
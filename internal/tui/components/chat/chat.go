@@ -25,6 +25,8 @@ import (
 type SendMsg struct {
 	Text        string
 	Attachments []message.Attachment
+	// Plan sends the prompt with the read-only tools only.
+	Plan bool
 }
 
 type SessionSelectedMsg = session.Session
@@ -41,6 +43,15 @@ const (
 	NotFound = -1
 )
 
+// streamFrameInterval is how often the updates of the messages being
+// streamed are applied, at most: fast models send one per token, which would
+// render the messages again each time.
+const streamFrameInterval = time.Second / 30
+
+// streamFrameMsg applies the updates of the messages being streamed received
+// since the last frame.
+type streamFrameMsg struct{}
+
 // MessageListCmp represents a component that displays a list of chat messages
 // with support for real-time updates and session management.
 type MessageListCmp interface {
@@ -66,7 +77,10 @@ type messageListCmp struct {
 	previousSelected string // Last selected item index for restoring focus
 
 	lastUserMessageTime int64
-	defaultListKeyMap   list.KeyMap
+	// turnCosts holds the cost of each assistant message since the last
+	// user message.
+	turnCosts         map[string]float64
+	defaultListKeyMap list.KeyMap
 
 	// Click tracking for double/triple click detection
 	lastClickTime time.Time
@@ -74,6 +88,11 @@ type messageListCmp struct {
 	lastClickY    int
 	clickCount    int
 	promptQueue   int
+
+	// pendingUpdates are the latest updates of the assistant messages
+	// received since the last frame, in the order of pendingOrder.
+	pendingUpdates map[string]message.Message
+	pendingOrder   []string
 }
 
 // New creates a new message list component with custom keybindings
@@ -191,8 +210,16 @@ func (m *messageListCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			return m, tea.Batch(cmds...)
 		}
 	case pubsub.Event[permission.PermissionNotification]:
+		cmds = append(cmds, m.flushUpdates())
 		cmds = append(cmds, m.handlePermissionRequest(msg.Payload))
 		return m, tea.Batch(cmds...)
+	case pubsub.Event[tools.ToolOutput]:
+		cmds = append(cmds, m.flushUpdates())
+		cmds = append(cmds, m.handleToolOutput(msg.Payload))
+		return m, tea.Batch(cmds...)
+	case streamFrameMsg:
+		cmds = append(cmds, m.flushUpdates())
+		return m, tea.Batch(cmds...)
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
 			cmds = append(cmds, m.SetSession(msg))
@@ -200,6 +227,8 @@ func (m *messageListCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 	case SessionClearedMsg:
 		m.session = session.Session{}
+		clear(m.pendingUpdates)
+		m.pendingOrder = m.pendingOrder[:0]
 		cmds = append(cmds, m.listCmp.SetItems([]list.Item{}))
 		return m, tea.Batch(cmds...)
 
@@ -256,6 +285,20 @@ func (m *messageListCmp) handlePermissionRequest(permission permission.Permissio
 	return nil
 }
 
+// handleToolOutput shows the output of a running tool in its tool call.
+func (m *messageListCmp) handleToolOutput(output tools.ToolOutput) tea.Cmd {
+	if output.SessionID != m.session.ID {
+		return nil
+	}
+	items := m.listCmp.Items()
+	if toolCallIndex := m.findToolCallByID(items, output.ToolCallID); toolCallIndex != NotFound {
+		toolCall := items[toolCallIndex].(messages.ToolCallCmp)
+		toolCall.SetOutput(output.Output)
+		m.listCmp.UpdateItem(toolCall.ID(), toolCall)
+	}
+	return nil
+}
+
 // handleChildSession handles messages from child sessions (agent tools).
 func (m *messageListCmp) handleChildSession(event pubsub.Event[message.Message]) tea.Cmd {
 	var cmds []tea.Cmd
@@ -326,6 +369,53 @@ func (m *messageListCmp) handleChildSession(event pubsub.Event[message.Message])
 
 // handleMessageEvent processes different types of message events (created/updated).
 func (m *messageListCmp) handleMessageEvent(event pubsub.Event[message.Message]) tea.Cmd {
+	if event.Type == pubsub.UpdatedEvent &&
+		event.Payload.SessionID == m.session.ID &&
+		event.Payload.Role == message.Assistant &&
+		!event.Payload.IsFinished() {
+		return m.queueUpdate(event.Payload)
+	}
+	// Other events may depend on the pending updates, like tool results on
+	// the tool calls.
+	flush := m.flushUpdates()
+	return tea.Batch(flush, m.applyMessageEvent(event))
+}
+
+// queueUpdate keeps the update of an assistant message being streamed to
+// apply it on the next frame, scheduling one if needed.
+func (m *messageListCmp) queueUpdate(msg message.Message) tea.Cmd {
+	if m.pendingUpdates == nil {
+		m.pendingUpdates = make(map[string]message.Message)
+	}
+	scheduled := len(m.pendingOrder) > 0
+	if _, ok := m.pendingUpdates[msg.ID]; !ok {
+		m.pendingOrder = append(m.pendingOrder, msg.ID)
+	}
+	m.pendingUpdates[msg.ID] = msg
+	if scheduled {
+		return nil
+	}
+	return tea.Tick(streamFrameInterval, func(time.Time) tea.Msg {
+		return streamFrameMsg{}
+	})
+}
+
+// flushUpdates applies the pending updates of the messages being streamed.
+func (m *messageListCmp) flushUpdates() tea.Cmd {
+	if len(m.pendingOrder) == 0 {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, id := range m.pendingOrder {
+		cmds = append(cmds, m.handleUpdateAssistantMessage(m.pendingUpdates[id]))
+	}
+	clear(m.pendingUpdates)
+	m.pendingOrder = m.pendingOrder[:0]
+	return tea.Batch(cmds...)
+}
+
+// applyMessageEvent applies a message event to the list.
+func (m *messageListCmp) applyMessageEvent(event pubsub.Event[message.Message]) tea.Cmd {
 	switch event.Type {
 	case pubsub.CreatedEvent:
 		if event.Payload.SessionID != m.session.ID {
@@ -394,6 +484,7 @@ func (m *messageListCmp) handleNewMessage(msg message.Message) tea.Cmd {
 // handleNewUserMessage adds a new user message to the list and updates the timestamp.
 func (m *messageListCmp) handleNewUserMessage(msg message.Message) tea.Cmd {
 	m.lastUserMessageTime = msg.CreatedAt
+	clear(m.turnCosts)
 	return m.listCmp.AppendItem(messages.NewMessageCmp(msg))
 }
 
@@ -468,6 +559,7 @@ func (m *messageListCmp) findAssistantMessageAndToolCalls(items []list.Item, mes
 
 // updateAssistantMessageContent updates or removes the assistant message based on content.
 func (m *messageListCmp) updateAssistantMessageContent(msg message.Message, assistantIndex int) tea.Cmd {
+	m.trackTurnCost(msg)
 	if assistantIndex == NotFound {
 		return nil
 	}
@@ -489,6 +581,7 @@ func (m *messageListCmp) updateAssistantMessageContent(msg message.Message, assi
 				messages.NewAssistantSection(
 					msg,
 					time.Unix(m.lastUserMessageTime, 0),
+					m.turnCost(),
 				),
 			)
 		}
@@ -566,6 +659,8 @@ func (m *messageListCmp) SetSession(session session.Session) tea.Cmd {
 	}
 
 	m.session = session
+	clear(m.pendingUpdates)
+	m.pendingOrder = m.pendingOrder[:0]
 	sessionMessages, err := m.app.Messages.List(context.Background(), session.ID)
 	if err != nil {
 		return util.ReportError(err)
@@ -587,6 +682,24 @@ func (m *messageListCmp) SetSession(session session.Session) tea.Cmd {
 	return m.listCmp.SetItems(uiMessages)
 }
 
+// trackTurnCost records the latest cost of an assistant message of the
+// current turn.
+func (m *messageListCmp) trackTurnCost(msg message.Message) {
+	if m.turnCosts == nil {
+		m.turnCosts = make(map[string]float64)
+	}
+	m.turnCosts[msg.ID] = msg.Cost
+}
+
+// turnCost returns what the current turn cost so far.
+func (m *messageListCmp) turnCost() float64 {
+	var total float64
+	for _, cost := range m.turnCosts {
+		total += cost
+	}
+	return total
+}
+
 // buildToolResultMap creates a map of tool call ID to tool result for efficient lookup.
 func (m *messageListCmp) buildToolResultMap(messages []message.Message) map[string]message.ToolResult {
 	toolResultMap := make(map[string]message.ToolResult)
@@ -606,11 +719,13 @@ func (m *messageListCmp) convertMessagesToUI(sessionMessages []message.Message,
 		switch msg.Role {
 		case message.User:
 			m.lastUserMessageTime = msg.CreatedAt
+			clear(m.turnCosts)
 			uiMessages = append(uiMessages, messages.NewMessageCmp(msg))
 		case message.Assistant:
+			m.trackTurnCost(msg)
 			uiMessages = append(uiMessages, m.convertAssistantMessage(msg, toolResultMap)...)
 			if msg.FinishPart() != nil && msg.FinishPart().Reason == message.FinishReasonEndTurn {
-				uiMessages = append(uiMessages, messages.NewAssistantSection(msg, time.Unix(m.lastUserMessageTime, 0)))
+				uiMessages = append(uiMessages, messages.NewAssistantSection(msg, time.Unix(m.lastUserMessageTime, 0), m.turnCost()))
 			}
 		}
 	}
@@ -787,6 +902,14 @@ func (m *messageListCmp) CopySelectedText(clear bool) tea.Cmd {
 		defer func() { m.SelectionClear() }()
 	}
 
+	// Over SSH the native clipboard is the one of the remote host, so only
+	// OSC 52 reaches the user's terminal.
+	if util.IsRemoteSession() {
+		return tea.Sequence(
+			tea.SetClipboard(selectedText),
+			util.ReportInfo("Selected text copied to clipboard"),
+		)
+	}
 	return tea.Sequence(
 		// We use both OSC 52 and native clipboard for compatibility with different
 		// terminal emulators and environments.
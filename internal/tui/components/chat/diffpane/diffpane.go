@@ -0,0 +1,147 @@
+// Package diffpane shows the changes the agent made to the file it edited
+// last in the session, updating as it edits files.
+package diffpane
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+// splitWidth is the width from which the diff is shown side by side.
+const splitWidth = 100
+
+// DiffPane shows the diff of the file the agent edited last.
+type DiffPane interface {
+	util.Model
+	layout.Sizeable
+	SetSession(session.Session) tea.Cmd
+}
+
+type filesLoadedMsg struct {
+	sessionID string
+	files     []history.File
+}
+
+type diffPaneCmp struct {
+	width, height int
+	history       history.Service
+	session       session.Session
+
+	// initial holds the content of the files before the session edited
+	// them, by path.
+	initial map[string]history.File
+	// latest is the latest version of the file edited last.
+	latest history.File
+}
+
+// New creates a diff pane following the versions of the files edited in
+// the sessions, recorded in files.
+func New(files history.Service) DiffPane {
+	return &diffPaneCmp{
+		history: files,
+		initial: make(map[string]history.File),
+	}
+}
+
+func (d *diffPaneCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (d *diffPaneCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case filesLoadedMsg:
+		if msg.sessionID != d.session.ID {
+			return d, nil
+		}
+		for _, file := range msg.files {
+			d.add(file)
+		}
+	case pubsub.Event[history.File]:
+		if msg.Payload.SessionID == d.session.ID {
+			d.add(msg.Payload)
+		}
+	}
+	return d, nil
+}
+
+// add records a version of a file, showing it when it's the latest edit.
+func (d *diffPaneCmp) add(file history.File) {
+	if initial, ok := d.initial[file.Path]; !ok || file.Version < initial.Version {
+		d.initial[file.Path] = file
+	}
+	if file.Version > 0 && file.UpdatedAt >= d.latest.UpdatedAt {
+		d.latest = file
+	}
+}
+
+func (d *diffPaneCmp) SetSession(s session.Session) tea.Cmd {
+	d.session = s
+	d.initial = make(map[string]history.File)
+	d.latest = history.File{}
+	if s.ID == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		files, err := d.history.ListBySession(context.Background(), s.ID)
+		if err != nil {
+			return util.ReportError(fmt.Errorf("failed to load the session files: %w", err))()
+		}
+		return filesLoadedMsg{sessionID: s.ID, files: files}
+	}
+}
+
+func (d *diffPaneCmp) View() string {
+	t := styles.CurrentTheme()
+	width := d.width - 2 // 2 for the padding
+	style := t.S().Base.Width(d.width).Height(d.height).Padding(1, 1, 0, 1)
+
+	if d.latest.Path == "" {
+		return style.Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			core.Section("Diff", width),
+			"",
+			t.S().Muted.Render("No files edited in this session yet."),
+		))
+	}
+
+	path := fsext.PrettyPath(d.latest.Path)
+	before, _ := fsext.ToUnixLineEndings(d.initial[d.latest.Path].Content)
+	after, _ := fsext.ToUnixLineEndings(d.latest.Content)
+	height := max(0, d.height-3) // 3 for the padding and the title
+	formatter := core.DiffFormatter().
+		Before(path, before).
+		After(path, after).
+		Width(width).
+		Height(height)
+	if width >= splitWidth {
+		formatter = formatter.Split()
+	}
+	return style.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		core.Section("Diff "+t.S().Muted.Render(path), width),
+		"",
+		strings.TrimRight(formatter.String(), "\n"),
+	))
+}
+
+func (d *diffPaneCmp) SetSize(width, height int) tea.Cmd {
+	d.width = width
+	d.height = height
+	return nil
+}
+
+func (d *diffPaneCmp) GetSize() (int, int) {
+	return d.width, d.height
+}
@@ -0,0 +1,215 @@
+package editor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+// errNoClipboardImage is returned when the clipboard holds no image.
+var errNoClipboardImage = errors.New("no image in the clipboard")
+
+// imagesUnsupported returns an error when the current model can't see
+// images.
+func imagesUnsupported() error {
+	cfg := config.Get()
+	model := cfg.GetModelByType(cfg.Agents[config.AgentCoder].Model)
+	if model == nil {
+		return errors.New("no model selected")
+	}
+	if !model.SupportsImages {
+		return fmt.Errorf("%s doesn't support images, switch to a model that does to attach them", model.Name)
+	}
+	return nil
+}
+
+// isImagePath reports whether path has the extension of an image that can
+// be attached.
+func isImagePath(path string) bool {
+	return slices.Contains(filepicker.AllowedTypes, strings.ToLower(filepath.Ext(path)))
+}
+
+// imageAttachment reads the image at path to attach it.
+func imageAttachment(path string) (message.Attachment, error) {
+	tooBig, err := filepicker.IsFileTooBig(path, filepicker.MaxAttachmentSize)
+	if err != nil {
+		return message.Attachment{}, err
+	}
+	if tooBig {
+		return message.Attachment{}, fmt.Errorf("%s is larger than %d MB", filepath.Base(path), filepicker.MaxAttachmentSize/1024/1024)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return message.Attachment{}, err
+	}
+	return message.Attachment{
+		FilePath: path,
+		FileName: filepath.Base(path),
+		MimeType: http.DetectContentType(content[:min(512, len(content))]),
+		Content:  content,
+	}, nil
+}
+
+// droppedPaths returns the absolute paths of the files dropped on the
+// terminal, which pastes them as text: quoted, with escaped spaces or as
+// file:// URIs, separated by spaces or newlines. It returns nil unless the
+// whole content is made of paths to existing files.
+func droppedPaths(content string) []string {
+	var (
+		paths   []string
+		current strings.Builder
+		quote   rune
+		escaped bool
+		inPath  bool
+	)
+	flush := func() {
+		if inPath {
+			paths = append(paths, current.String())
+			current.Reset()
+			inPath = false
+		}
+	}
+	for _, r := range strings.TrimSpace(content) {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\\' && runtime.GOOS != "windows":
+			escaped, inPath = true, true
+		case r == '\'' || r == '"':
+			quote, inPath = r, true
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			current.WriteRune(r)
+			inPath = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil
+	}
+	flush()
+
+	for i, path := range paths {
+		if strings.HasPrefix(path, "file://") {
+			u, err := url.Parse(path)
+			if err != nil {
+				return nil
+			}
+			path = u.Path
+		}
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return nil
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return nil
+		}
+		paths[i] = path
+	}
+	return paths
+}
+
+// pasteClipboardImage attaches the image in the system clipboard.
+func pasteClipboardImage() tea.Msg {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	content, err := readClipboardImage(ctx)
+	if errors.Is(err, errNoClipboardImage) {
+		return util.ReportWarn("No image in the clipboard")()
+	}
+	if err != nil {
+		return util.ReportError(fmt.Errorf("failed to read the clipboard: %w", err))()
+	}
+	if int64(len(content)) > filepicker.MaxAttachmentSize {
+		return util.ReportError(fmt.Errorf("the image in the clipboard is larger than %d MB", filepicker.MaxAttachmentSize/1024/1024))()
+	}
+	return filepicker.FilePickedMsg{
+		Attachment: message.Attachment{
+			FileName: fmt.Sprintf("clipboard-%s.png", time.Now().Format("150405")),
+			MimeType: http.DetectContentType(content[:min(512, len(content))]),
+			Content:  content,
+		},
+	}
+}
+
+// readClipboardImage returns the PNG image in the system clipboard, using
+// the tools of the platform.
+func readClipboardImage(ctx context.Context) ([]byte, error) {
+	var content []byte
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		content, err = clipboardOutput(ctx, "osascript", "-e", "get the clipboard as «class PNGf»")
+		if err == nil {
+			// The image is printed as «data PNGf89504E47...».
+			data := strings.TrimSpace(string(content))
+			data = strings.TrimSuffix(strings.TrimPrefix(data, "«data PNGf"), "»")
+			content, err = hex.DecodeString(data)
+		}
+	case "windows":
+		script := `Add-Type -AssemblyName System.Windows.Forms; Add-Type -AssemblyName System.Drawing; ` +
+			`$img = [System.Windows.Forms.Clipboard]::GetImage(); ` +
+			`if ($img) { $ms = New-Object System.IO.MemoryStream; $img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png); [Convert]::ToBase64String($ms.ToArray()) }`
+		content, err = clipboardOutput(ctx, "powershell", "-NoProfile", "-STA", "-Command", script)
+		if err == nil {
+			content, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			if _, lookErr := exec.LookPath("wl-paste"); lookErr == nil {
+				content, err = clipboardOutput(ctx, "wl-paste", "--no-newline", "--type", "image/png")
+				break
+			}
+		}
+		content, err = clipboardOutput(ctx, "xclip", "-selection", "clipboard", "-target", "image/png", "-out")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 || http.DetectContentType(content[:min(512, len(content))]) != "image/png" {
+		return nil, errNoClipboardImage
+	}
+	return content, nil
+}
+
+func clipboardOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is needed to paste images", name)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	content, err := cmd.Output()
+	if err != nil {
+		// The tools fail when the clipboard doesn't hold an image.
+		if stderr.Len() > 0 {
+			return nil, errNoClipboardImage
+		}
+		return nil, err
+	}
+	return content, nil
+}
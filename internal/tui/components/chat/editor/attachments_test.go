@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDroppedPaths(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "screenshot.png")
+	spaced := filepath.Join(dir, "my screenshot.png")
+	for _, path := range []string{plain, spaced} {
+		require.NoError(t, os.WriteFile(path, []byte("png"), 0o644))
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{name: "plain", content: plain + "\n", expected: []string{plain}},
+		{name: "escaped", content: filepath.Join(dir, `my\ screenshot.png`), expected: []string{spaced}},
+		{name: "quoted", content: "'" + spaced + "' \"" + plain + "\"", expected: []string{spaced, plain}},
+		{name: "uri", content: "file://" + filepath.ToSlash(dir) + "/my%20screenshot.png", expected: []string{spaced}},
+		{name: "text", content: "look at " + plain, expected: nil},
+		{name: "directory", content: dir, expected: nil},
+		{name: "unterminated quote", content: "'" + plain, expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, droppedPaths(tt.content))
+		})
+	}
+}
@@ -4,10 +4,8 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
-	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
@@ -18,6 +16,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/session"
@@ -30,6 +29,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
+	"mvdan.cc/sh/v3/shell"
 )
 
 type Editor interface {
@@ -43,12 +43,21 @@ type Editor interface {
 	IsCompletionsOpen() bool
 	HasAttachments() bool
 	Cursor() *tea.Cursor
+	// LeaveInsertMode switches the vim key bindings to normal mode, for
+	// the esc handled elsewhere to still leave insert mode.
+	LeaveInsertMode()
 }
 
 type FileCompletionItem struct {
 	Path string // The file path
 }
 
+// CommandCompletionItem is a custom command completed after a "/" starting
+// the prompt.
+type CommandCompletionItem struct {
+	Name string
+}
+
 type editorCmp struct {
 	width              int
 	height             int
@@ -63,10 +72,15 @@ type editorCmp struct {
 
 	keyMap EditorKeyMap
 
-	// File path completions
+	// vim holds the state of the vim key bindings, nil when they're
+	// disabled.
+	vim *vim
+
+	// File path and custom command completions
 	currentQuery          string
 	completionsStartIndex int
 	isCompletionsOpen     bool
+	completingCommand     bool
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -93,15 +107,35 @@ type OpenEditorMsg struct {
 	Text string
 }
 
-func (m *editorCmp) openEditor(value string) tea.Cmd {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		// Use platform-appropriate default editor
-		if runtime.GOOS == "windows" {
-			editor = "notepad"
-		} else {
-			editor = "nvim"
+// externalEditor returns the command of the editor prompts are composed in:
+// $VISUAL or $EDITOR, which may have arguments like "code --wait", or a
+// platform-appropriate default.
+func externalEditor() ([]string, error) {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		value := strings.TrimSpace(os.Getenv(env))
+		if value == "" {
+			continue
 		}
+		fields, err := shell.Fields(value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid $%s: %w", env, err)
+		}
+		if len(fields) > 0 {
+			return fields, nil
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}, nil
+	}
+	return []string{"nvim"}, nil
+}
+
+// openEditor opens the draft in the external editor, suspending the TUI
+// until it exits, and puts the text written there back in the prompt.
+func (m *editorCmp) openEditor(value string) tea.Cmd {
+	editor, err := externalEditor()
+	if err != nil {
+		return util.ReportError(err)
 	}
 
 	tmpfile, err := os.CreateTemp("", "msg_*.md")
@@ -112,24 +146,25 @@ func (m *editorCmp) openEditor(value string) tea.Cmd {
 	if _, err := tmpfile.WriteString(value); err != nil {
 		return util.ReportError(err)
 	}
-	c := exec.CommandContext(context.TODO(), editor, tmpfile.Name())
+	c := exec.CommandContext(context.TODO(), editor[0], append(editor[1:], tmpfile.Name())...)
 	c.Stdin = os.Stdin
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
 	return tea.ExecProcess(c, func(err error) tea.Msg {
+		defer os.Remove(tmpfile.Name()) //nolint:errcheck
 		if err != nil {
-			return util.ReportError(err)
+			return util.ReportError(fmt.Errorf("failed to run %s: %w", editor[0], err))()
 		}
 		content, err := os.ReadFile(tmpfile.Name())
 		if err != nil {
-			return util.ReportError(err)
+			return util.ReportError(err)()
 		}
-		if len(content) == 0 {
-			return util.ReportWarn("Message is empty")
+		text := strings.TrimSpace(string(content))
+		if text == "" {
+			return util.ReportWarn("Message is empty")()
 		}
-		os.Remove(tmpfile.Name())
 		return OpenEditorMsg{
-			Text: strings.TrimSpace(string(content)),
+			Text: text,
 		}
 	})
 }
@@ -148,6 +183,13 @@ func (m *editorCmp) send() tea.Cmd {
 		return util.CmdHandler(dialogs.OpenDialogMsg{Model: quit.NewQuitDialog()})
 	}
 
+	// The model may have changed since the images were attached.
+	if slices.ContainsFunc(m.attachments, func(a message.Attachment) bool { return !a.IsText() }) {
+		if err := imagesUnsupported(); err != nil {
+			return util.ReportError(err)
+		}
+	}
+
 	m.textarea.Reset()
 	attachments := m.attachments
 
@@ -156,6 +198,16 @@ func (m *editorCmp) send() tea.Cmd {
 		return nil
 	}
 
+	// A prompt starting with /name runs the custom command of that name,
+	// with the rest of the prompt as its arguments.
+	if name, ok := strings.CutPrefix(value, "/"); ok {
+		name, arguments, _ := strings.Cut(name, " ")
+		customCommands, _ := commands.LoadCustomCommandTemplates()
+		if command, ok := commands.FindCustomCommand(customCommands, name); ok {
+			return command.Run(strings.TrimSpace(arguments), attachments)
+		}
+	}
+
 	// Change the placeholder when sending a new message.
 	m.randomizePlaceholders()
 
@@ -167,6 +219,15 @@ func (m *editorCmp) send() tea.Cmd {
 	)
 }
 
+// addAttachment attaches a file to the prompt, up to maxAttachments.
+func (m *editorCmp) addAttachment(attachment message.Attachment) tea.Cmd {
+	if len(m.attachments) >= maxAttachments {
+		return util.ReportError(fmt.Errorf("cannot add more than %d attachments", maxAttachments))
+	}
+	m.attachments = append(m.attachments, attachment)
+	return nil
+}
+
 func (m *editorCmp) repositionCompletions() tea.Msg {
 	x, y := m.completionsPosition()
 	return completions.RepositionCompletionsMsg{X: x, Y: y}
@@ -178,28 +239,51 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		return m, m.repositionCompletions
-	case filepicker.FilePickedMsg:
-		if len(m.attachments) >= maxAttachments {
-			return m, util.ReportError(fmt.Errorf("cannot add more than %d images", maxAttachments))
+	case config.Reloaded:
+		if msg.Keys {
+			m.keyMap = DefaultEditorKeyMap()
+			m.keyMap.Actions().Configure(m.app.Config().Options.TUI.Keys)
 		}
-		m.attachments = append(m.attachments, msg.Attachment)
 		return m, nil
+	case filepicker.FilePickedMsg:
+		return m, m.addAttachment(msg.Attachment)
 	case completions.CompletionsOpenedMsg:
 		m.isCompletionsOpen = true
 	case completions.CompletionsClosedMsg:
 		m.isCompletionsOpen = false
+		m.completingCommand = false
 		m.currentQuery = ""
 		m.completionsStartIndex = 0
 	case completions.SelectCompletionMsg:
 		if !m.isCompletionsOpen {
 			return m, nil
 		}
+		if item, ok := msg.Value.(CommandCompletionItem); ok {
+			m.textarea.SetValue("/" + item.Name + " ")
+			m.textarea.MoveToEnd()
+			if !msg.Insert {
+				m.isCompletionsOpen = false
+				m.completingCommand = false
+				m.currentQuery = ""
+				m.completionsStartIndex = 0
+			}
+		}
 		if item, ok := msg.Value.(FileCompletionItem); ok {
 			word := m.textarea.Word()
-			// If the selected item is a file, insert its path into the textarea
+			// If the selected item is a file, insert its path into the
+			// textarea, or attach it when it's an image the model can see.
+			inserted := item.Path
+			if isImagePath(item.Path) && imagesUnsupported() == nil {
+				inserted = ""
+				if attachment, err := imageAttachment(item.Path); err != nil {
+					cmds = append(cmds, util.ReportError(err))
+				} else {
+					cmds = append(cmds, m.addAttachment(attachment))
+				}
+			}
 			value := m.textarea.Value()
 			value = value[:m.completionsStartIndex] + // Remove the current query
-				item.Path + // Insert the file path
+				inserted + // Insert the file path
 				value[m.completionsStartIndex+len(word):] // Append the rest of the value
 			// XXX: This will always move the cursor to the end of the textarea.
 			m.textarea.SetValue(value)
@@ -212,63 +296,63 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		}
 
 	case commands.OpenExternalEditorMsg:
-		if m.app.AgentCoordinator.IsSessionBusy(m.session.ID) {
-			return m, util.ReportWarn("Agent is working, please wait...")
-		}
 		return m, m.openEditor(m.textarea.Value())
 	case OpenEditorMsg:
 		m.textarea.SetValue(msg.Text)
 		m.textarea.MoveToEnd()
 	case tea.PasteMsg:
-		path := strings.ReplaceAll(msg.Content, "\\ ", " ")
-		// try to get an image
-		path, err := filepath.Abs(strings.TrimSpace(path))
-		if err != nil {
-			m.textarea, cmd = m.textarea.Update(msg)
-			return m, cmd
-		}
-		isAllowedType := false
-		for _, ext := range filepicker.AllowedTypes {
-			if strings.HasSuffix(path, ext) {
-				isAllowedType = true
-				break
-			}
+		// Some terminals paste nothing when the clipboard holds an image.
+		if strings.TrimSpace(msg.Content) == "" && imagesUnsupported() == nil {
+			return m, pasteClipboardImage
 		}
-		if !isAllowedType {
+		// Files dropped on the terminal are pasted as their paths.
+		paths := droppedPaths(msg.Content)
+		if len(paths) == 0 || slices.ContainsFunc(paths, func(path string) bool { return !isImagePath(path) }) {
 			m.textarea, cmd = m.textarea.Update(msg)
 			return m, cmd
 		}
-		tooBig, _ := filepicker.IsFileTooBig(path, filepicker.MaxAttachmentSize)
-		if tooBig {
-			m.textarea, cmd = m.textarea.Update(msg)
-			return m, cmd
+		if err := imagesUnsupported(); err != nil {
+			return m, util.ReportError(err)
 		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			m.textarea, cmd = m.textarea.Update(msg)
-			return m, cmd
+		for _, path := range paths {
+			attachment, err := imageAttachment(path)
+			if err != nil {
+				return m, tea.Batch(append(cmds, util.ReportError(err))...)
+			}
+			cmds = append(cmds, m.addAttachment(attachment))
 		}
-		mimeBufferSize := min(512, len(content))
-		mimeType := http.DetectContentType(content[:mimeBufferSize])
-		fileName := filepath.Base(path)
-		attachment := message.Attachment{FilePath: path, FileName: fileName, MimeType: mimeType, Content: content}
-		return m, util.CmdHandler(filepicker.FilePickedMsg{
-			Attachment: attachment,
-		})
+		return m, tea.Batch(cmds...)
 
 	case commands.ToggleYoloModeMsg:
 		m.setEditorPrompt()
 		return m, nil
 	case tea.KeyPressMsg:
+		if m.vim != nil && !m.isCompletionsOpen && m.handleVim(msg) {
+			return m, nil
+		}
 		cur := m.textarea.Cursor()
 		curIdx := m.textarea.Width()*cur.Y + cur.X
 		switch {
-		// Open command palette when "/" is pressed on empty prompt
+		// Complete the custom commands when "/" is pressed on empty prompt,
+		// or open the command palette when there are none.
 		case msg.String() == "/" && len(strings.TrimSpace(m.textarea.Value())) == 0:
-			return m, util.CmdHandler(dialogs.OpenDialogMsg{
-				Model: commands.NewCommandDialog(m.session.ID),
-			})
+			customCommands, _ := commands.LoadCustomCommandTemplates()
+			if len(customCommands) == 0 {
+				// The command runs later, when the editor may be on another
+				// session.
+				sessionID, sessionsService := m.session.ID, m.app.Sessions
+				return m, func() tea.Msg {
+					sessions, _ := sessionsService.List(context.Background())
+					return dialogs.OpenDialogMsg{
+						Model: commands.NewCommandDialog(sessionID, sessions),
+					}
+				}
+			}
+			m.isCompletionsOpen = true
+			m.completingCommand = true
+			m.currentQuery = ""
+			m.completionsStartIndex = curIdx
+			cmds = append(cmds, m.startCommandCompletions(customCommands))
 		// Completions
 		case msg.String() == "@" && !m.isCompletionsOpen &&
 			// only show if beginning of prompt, or if previous char is a space or newline:
@@ -302,10 +386,13 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				return m, nil
 			}
 		}
-		if key.Matches(msg, m.keyMap.OpenEditor) {
-			if m.app.AgentCoordinator.IsSessionBusy(m.session.ID) {
-				return m, util.ReportWarn("Agent is working, please wait...")
+		if key.Matches(msg, m.keyMap.PasteImage) {
+			if err := imagesUnsupported(); err != nil {
+				return m, util.ReportError(err)
 			}
+			return m, pasteClipboardImage
+		}
+		if key.Matches(msg, m.keyMap.OpenEditor) {
 			return m, m.openEditor(m.textarea.Value())
 		}
 		if key.Matches(msg, DeleteKeyMaps.Escape) {
@@ -337,12 +424,13 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		if ok {
 			if kp.String() == "space" || m.textarea.Value() == "" {
 				m.isCompletionsOpen = false
+				m.completingCommand = false
 				m.currentQuery = ""
 				m.completionsStartIndex = 0
 				cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 			} else {
 				word := m.textarea.Word()
-				if strings.HasPrefix(word, "@") {
+				if strings.HasPrefix(word, "@") || (m.completingCommand && strings.HasPrefix(word, "/")) {
 					// XXX: wont' work if editing in the middle of the field.
 					m.completionsStartIndex = strings.LastIndex(m.textarea.Value(), word)
 					m.currentQuery = word[1:]
@@ -359,6 +447,7 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 					)
 				} else if m.isCompletionsOpen {
 					m.isCompletionsOpen = false
+					m.completingCommand = false
 					m.currentQuery = ""
 					m.completionsStartIndex = 0
 					cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
@@ -370,8 +459,41 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// handleVim applies the vim key bindings to the key pressed, and reports
+// whether they handled it. In normal mode, the keys that don't type text, like
+// enter, keep doing what they do in insert mode.
+func (m *editorCmp) handleVim(msg tea.KeyPressMsg) bool {
+	if m.vim.mode == vimInsert {
+		if m.deleteMode || !key.Matches(msg, m.keyMap.VimNormal) {
+			return false
+		}
+		m.vim.escape(bufferOf(&m.textarea)).apply(&m.textarea)
+		return true
+	}
+
+	k := msg.Text
+	switch {
+	case msg.String() == "backspace":
+		k = "h"
+	case k == "" || msg.Mod.Contains(tea.ModCtrl) || msg.Mod.Contains(tea.ModAlt):
+		if m.vim.pending == "" {
+			return false
+		}
+		k = msg.String()
+	}
+	m.vim.normal(bufferOf(&m.textarea), k).apply(&m.textarea)
+	return true
+}
+
+func (m *editorCmp) LeaveInsertMode() {
+	if m.vim == nil || m.vim.mode != vimInsert {
+		return
+	}
+	m.vim.escape(bufferOf(&m.textarea)).apply(&m.textarea)
+}
+
 func (m *editorCmp) setEditorPrompt() {
-	if m.app.Permissions.SkipRequests() {
+	if m.app.Permissions.SessionSkipRequests(m.session.ID) {
 		m.textarea.SetPromptFunc(4, yoloPromptFunc)
 		return
 	}
@@ -391,6 +513,9 @@ func (m *editorCmp) completionsPosition() (int, int) {
 func (m *editorCmp) Cursor() *tea.Cursor {
 	cursor := m.textarea.Cursor()
 	if cursor != nil {
+		if m.vim != nil && m.vim.mode == vimInsert {
+			cursor.Shape = tea.CursorBar
+		}
 		cursor.X = cursor.X + m.x + 1
 		cursor.Y = cursor.Y + m.y + 1 // adjust for padding
 	}
@@ -426,7 +551,7 @@ func (m *editorCmp) View() string {
 	} else {
 		m.textarea.Placeholder = m.readyPlaceholder
 	}
-	if m.app.Permissions.SkipRequests() {
+	if m.app.Permissions.SessionSkipRequests(m.session.ID) {
 		m.textarea.Placeholder = "Yolo mode!"
 	}
 	if len(m.attachments) == 0 {
@@ -510,6 +635,25 @@ func (m *editorCmp) startCompletions() tea.Msg {
 	}
 }
 
+// startCommandCompletions completes the names of the custom commands.
+func (m *editorCmp) startCommandCompletions(customCommands []commands.CustomCommand) tea.Cmd {
+	return func() tea.Msg {
+		completionItems := make([]completions.Completion, 0, len(customCommands))
+		for _, command := range customCommands {
+			completionItems = append(completionItems, completions.Completion{
+				Title: "/" + command.Name,
+				Value: CommandCompletionItem{Name: command.Name},
+			})
+		}
+		x, y := m.completionsPosition()
+		return completions.OpenCompletionsMsg{
+			Completions: completionItems,
+			X:           x,
+			Y:           y,
+		}
+	}
+}
+
 // Blur implements Container.
 func (c *editorCmp) Blur() tea.Cmd {
 	c.textarea.Blur()
@@ -528,13 +672,23 @@ func (c *editorCmp) IsFocused() bool {
 
 // Bindings implements Container.
 func (c *editorCmp) Bindings() []key.Binding {
-	return c.keyMap.KeyBindings()
+	bindings := c.keyMap.KeyBindings()
+	switch {
+	case c.vim == nil:
+	case c.vim.mode == vimInsert:
+		bindings = append(bindings, c.keyMap.VimNormal)
+	default:
+		bindings = append(bindings, c.keyMap.VimInsert)
+	}
+	return bindings
 }
 
 // TODO: most likely we do not need to have the session here
 // we need to move some functionality to the page level
 func (c *editorCmp) SetSession(session session.Session) tea.Cmd {
 	c.session = session
+	// YOLO mode is set per session.
+	c.setEditorPrompt()
 	return nil
 }
 
@@ -586,6 +740,10 @@ func New(app *app.App) Editor {
 		textarea: ta,
 		keyMap:   DefaultEditorKeyMap(),
 	}
+	e.keyMap.Actions().Configure(app.Config().Options.TUI.Keys)
+	if app.Config().Options.TUI.VimMode {
+		e.vim = &vim{mode: vimInsert}
+	}
 	e.setEditorPrompt()
 
 	e.randomizePlaceholders()
@@ -0,0 +1,24 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	editor, err := externalEditor()
+	require.NoError(t, err)
+	require.Equal(t, []string{"code", "--wait"}, editor)
+
+	t.Setenv("VISUAL", "'/opt/my editor/bin/edit' -f")
+	editor, err = externalEditor()
+	require.NoError(t, err)
+	require.Equal(t, []string{"/opt/my editor/bin/edit", "-f"}, editor)
+
+	t.Setenv("VISUAL", "'unterminated")
+	_, err = externalEditor()
+	require.ErrorContains(t, err, "invalid $VISUAL")
+}
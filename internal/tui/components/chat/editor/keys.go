@@ -2,6 +2,7 @@ package editor
 
 import (
 	"charm.land/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/util"
 )
 
 type EditorKeyMap struct {
@@ -9,6 +10,12 @@ type EditorKeyMap struct {
 	SendMessage key.Binding
 	OpenEditor  key.Binding
 	Newline     key.Binding
+	PasteImage  key.Binding
+
+	// VimNormal and VimInsert switch between the modes of the vim key
+	// bindings, when they're enabled.
+	VimNormal key.Binding
+	VimInsert key.Binding
 }
 
 func DefaultEditorKeyMap() EditorKeyMap {
@@ -32,6 +39,29 @@ func DefaultEditorKeyMap() EditorKeyMap {
 			// to reflect that.
 			key.WithHelp("ctrl+j", "newline"),
 		),
+		PasteImage: key.NewBinding(
+			key.WithKeys("ctrl+v"),
+			key.WithHelp("ctrl+v", "paste image"),
+		),
+		VimNormal: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "normal mode"),
+		),
+		VimInsert: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "insert mode"),
+		),
+	}
+}
+
+// Actions returns the bindings of the key map by the name of their action in
+// the keys options.
+func (k *EditorKeyMap) Actions() util.KeyActions {
+	return util.KeyActions{
+		"send_message": &k.SendMessage,
+		"open_editor":  &k.OpenEditor,
+		"newline":      &k.Newline,
+		"paste_image":  &k.PasteImage,
 	}
 }
 
@@ -42,6 +72,7 @@ func (k EditorKeyMap) KeyBindings() []key.Binding {
 		k.SendMessage,
 		k.OpenEditor,
 		k.Newline,
+		k.PasteImage,
 		AttachmentsKeyMaps.AttachmentDeleteMode,
 		AttachmentsKeyMaps.DeleteAllAttachments,
 		AttachmentsKeyMaps.Escape,
@@ -0,0 +1,435 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"charm.land/bubbles/v2/textarea"
+)
+
+// vimMode is the mode of the editor with vim key bindings.
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+)
+
+// maxVimUndo is the number of changes that can be undone with u.
+const maxVimUndo = 100
+
+// vim holds the state of the vim key bindings of the editor.
+type vim struct {
+	mode vimMode
+	// pending is the operator or prefix typed so far of a command taking
+	// two keys, like the d of dw.
+	pending string
+	// register holds the text deleted or yanked last, put back with p.
+	register string
+	// linewise is whether register holds whole lines.
+	linewise bool
+	// undo holds the text before the last changes, most recent last.
+	undo []buffer
+}
+
+// buffer is the text of the editor with the position of the cursor in it, in
+// runes.
+type buffer struct {
+	text []rune
+	pos  int
+}
+
+// bufferOf returns the text of ta with its cursor.
+func bufferOf(ta *textarea.Model) buffer {
+	b := buffer{text: []rune(ta.Value())}
+	for range ta.Line() {
+		b.pos = b.lineEnd() + 1
+	}
+	li := ta.LineInfo()
+	b.pos += li.StartColumn + li.ColumnOffset
+	return b
+}
+
+// apply sets the text of ta to b's and moves its cursor to b's.
+func (b buffer) apply(ta *textarea.Model) {
+	text := string(b.text)
+	if ta.Value() != text {
+		ta.SetValue(text)
+	}
+	row := strings.Count(string(b.text[:b.pos]), "\n")
+	ta.MoveToBegin()
+	for ta.Line() < row {
+		ta.CursorDown()
+	}
+	ta.SetCursorColumn(b.pos - b.lineStart())
+}
+
+// lineStart returns the position of the first character of the line of the
+// cursor.
+func (b buffer) lineStart() int {
+	i := b.pos
+	for i > 0 && b.text[i-1] != '\n' {
+		i--
+	}
+	return i
+}
+
+// lineEnd returns the position of the newline ending the line of the cursor,
+// or the end of the text on the last line.
+func (b buffer) lineEnd() int {
+	i := b.pos
+	for i < len(b.text) && b.text[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// lastChar returns the position of the last character of the line of the
+// cursor, where the cursor stays in normal mode.
+func (b buffer) lastChar() int {
+	return max(b.lineStart(), b.lineEnd()-1)
+}
+
+// firstNonBlank returns the position of the first character of the line of
+// the cursor that isn't a space.
+func (b buffer) firstNonBlank() int {
+	i, end := b.lineStart(), b.lineEnd()
+	for i < end && unicode.IsSpace(b.text[i]) {
+		i++
+	}
+	return min(i, b.lastChar())
+}
+
+// at returns b with the cursor moved to pos.
+func (b buffer) at(pos int) buffer {
+	b.pos = max(0, min(pos, len(b.text)))
+	return b
+}
+
+// line returns the position in the line after or before the one of the
+// cursor, in the same column when it's long enough.
+func (b buffer) line(down bool) int {
+	col := b.pos - b.lineStart()
+	var next buffer
+	switch {
+	case down && b.lineEnd() < len(b.text):
+		next = b.at(b.lineEnd() + 1)
+	case !down && b.lineStart() > 0:
+		next = b.at(b.lineStart() - 1)
+		next = next.at(next.lineStart())
+	default:
+		return b.pos
+	}
+	return min(next.lineStart()+col, next.lastChar())
+}
+
+// charClass groups the characters words are made of: spaces, letters and
+// digits, and punctuation.
+func charClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// wordForward returns the position of the start of the next word.
+func (b buffer) wordForward() int {
+	i := b.pos
+	if i < len(b.text) {
+		if class := charClass(b.text[i]); class != 0 {
+			for i < len(b.text) && charClass(b.text[i]) == class {
+				i++
+			}
+		}
+	}
+	for i < len(b.text) && charClass(b.text[i]) == 0 {
+		i++
+	}
+	return i
+}
+
+// wordBackward returns the position of the start of the word before the
+// cursor.
+func (b buffer) wordBackward() int {
+	i := b.pos
+	for i > 0 && charClass(b.text[i-1]) == 0 {
+		i--
+	}
+	if i > 0 {
+		class := charClass(b.text[i-1])
+		for i > 0 && charClass(b.text[i-1]) == class {
+			i--
+		}
+	}
+	return i
+}
+
+// wordEnd returns the position of the end of the word under or after the
+// cursor.
+func (b buffer) wordEnd() int {
+	i := b.pos + 1
+	for i < len(b.text) && charClass(b.text[i]) == 0 {
+		i++
+	}
+	if i >= len(b.text) {
+		return max(0, len(b.text)-1)
+	}
+	class := charClass(b.text[i])
+	for i+1 < len(b.text) && charClass(b.text[i+1]) == class {
+		i++
+	}
+	return i
+}
+
+// motion returns the position the motion key moves the cursor to, and
+// whether the character there is included when an operator applies to it.
+func (b buffer) motion(key string) (pos int, inclusive, ok bool) {
+	switch key {
+	case "h", "left":
+		return max(b.lineStart(), b.pos-1), false, true
+	case "l", "right", " ":
+		return min(b.lineEnd(), b.pos+1), false, true
+	case "j", "down":
+		return b.line(true), false, true
+	case "k", "up":
+		return b.line(false), false, true
+	case "0", "home":
+		return b.lineStart(), false, true
+	case "^":
+		return b.firstNonBlank(), false, true
+	case "$", "end":
+		return b.lineEnd(), false, true
+	case "w":
+		return b.wordForward(), false, true
+	case "b":
+		return b.wordBackward(), false, true
+	case "e":
+		return b.wordEnd(), true, true
+	case "G":
+		last := b.at(len(b.text))
+		return last.lineStart(), false, true
+	}
+	return 0, false, false
+}
+
+// lines returns the start and end of the whole lines of the cursor and of
+// the line the motion key moves to, or false if it doesn't move between
+// lines.
+func (b buffer) lines(key string) (start, end int, ok bool) {
+	var other buffer
+	switch key {
+	case "j", "down":
+		other = b.at(b.line(true))
+	case "k", "up":
+		other = b.at(b.line(false))
+	case "G":
+		other = b.at(len(b.text))
+	default:
+		return 0, 0, false
+	}
+	first, last := b, other
+	if other.pos < b.pos {
+		first, last = other, b
+	}
+	return first.lineStart(), last.lineEnd(), true
+}
+
+// replace returns b with the text between start and end replaced with s, and
+// the cursor at pos.
+func (b buffer) replace(start, end int, s string, pos int) buffer {
+	text := slices.Concat(b.text[:start], []rune(s), b.text[end:])
+	return buffer{text: text}.at(pos)
+}
+
+// deleteLines removes the lines between start and end, with their newline.
+func (b buffer) deleteLines(start, end int) buffer {
+	switch {
+	case end < len(b.text):
+		end++
+	case start > 0:
+		start--
+	}
+	b = b.replace(start, end, "", start)
+	if start > 0 && start == len(b.text) {
+		b = b.at(start - 1)
+	}
+	return b.at(b.firstNonBlank())
+}
+
+// normal applies the key typed in normal mode to b. It returns b unchanged,
+// with the key pending, when the key begins a command taking two keys.
+func (v *vim) normal(b buffer, key string) buffer {
+	if v.pending != "" {
+		op := v.pending
+		v.pending = ""
+		return v.operator(b, op, key)
+	}
+
+	switch key {
+	case "i":
+		return v.insert(b, b.pos)
+	case "a":
+		return v.insert(b, min(b.lineEnd(), b.pos+1))
+	case "I":
+		return v.insert(b, b.firstNonBlank())
+	case "A":
+		return v.insert(b, b.lineEnd())
+	case "o":
+		v.save(b)
+		v.mode = vimInsert
+		return b.replace(b.lineEnd(), b.lineEnd(), "\n", b.lineEnd()+1)
+	case "O":
+		v.save(b)
+		v.mode = vimInsert
+		return b.replace(b.lineStart(), b.lineStart(), "\n", b.lineStart())
+	case "d", "c", "y", "g":
+		v.pending = key
+		return b
+	case "x":
+		return v.operator(b, "d", "l")
+	case "X":
+		return v.operator(b, "d", "h")
+	case "s":
+		return v.operator(b, "c", "l")
+	case "D":
+		return v.operator(b, "d", "$")
+	case "C":
+		return v.operator(b, "c", "$")
+	case "S":
+		return v.operator(b, "c", "c")
+	case "Y":
+		return v.operator(b, "y", "y")
+	case "p", "P":
+		return v.put(b, key == "p")
+	case "u":
+		if len(v.undo) == 0 {
+			return b
+		}
+		prev := v.undo[len(v.undo)-1]
+		v.undo = v.undo[:len(v.undo)-1]
+		return prev
+	}
+
+	if pos, _, ok := b.motion(key); ok {
+		b = b.at(pos)
+		return b.at(min(b.pos, b.lastChar()))
+	}
+	return b
+}
+
+// operator applies the command made of the operator op, or the g prefix,
+// followed by key.
+func (v *vim) operator(b buffer, op, key string) buffer {
+	if op == "g" {
+		if key == "g" {
+			return b.at(0)
+		}
+		return b
+	}
+
+	// Operators apply to whole lines when doubled, like dd, and with the
+	// motions between lines.
+	start, end, linewise := 0, 0, key == op
+	if linewise {
+		start, end = b.lineStart(), b.lineEnd()
+	} else if start, end, linewise = b.lines(key); !linewise {
+		// cw changes to the end of the word, like ce.
+		if op == "c" && key == "w" && b.pos < len(b.text) && charClass(b.text[b.pos]) != 0 {
+			key = "e"
+		}
+		pos, inclusive, ok := b.motion(key)
+		if !ok {
+			return b
+		}
+		start, end = min(b.pos, pos), max(b.pos, pos)
+		if inclusive {
+			end = min(end+1, len(b.text))
+		}
+		// dw on the last word of a line stops at its end.
+		if key == "w" {
+			end = min(end, b.lineEnd())
+		}
+		if start == end {
+			return b
+		}
+	}
+
+	v.register = string(b.text[start:end])
+	v.linewise = linewise
+	switch op {
+	case "y":
+		if linewise {
+			return b
+		}
+		return b.at(start)
+	case "c":
+		v.save(b)
+		v.mode = vimInsert
+		return b.replace(start, end, "", start)
+	}
+	v.save(b)
+	if linewise {
+		return b.deleteLines(start, end)
+	}
+	b = b.replace(start, end, "", start)
+	return b.at(min(b.pos, b.lastChar()))
+}
+
+// put pastes the register after the cursor, or before it.
+func (v *vim) put(b buffer, after bool) buffer {
+	if v.register == "" {
+		return b
+	}
+	v.save(b)
+	if v.linewise {
+		if after {
+			end := b.lineEnd()
+			return b.replace(end, end, "\n"+v.register, end+1)
+		}
+		start := b.lineStart()
+		return b.replace(start, start, v.register+"\n", start)
+	}
+	pos := b.pos
+	if after && pos < b.lineEnd() {
+		pos++
+	}
+	return b.replace(pos, pos, v.register, pos+len([]rune(v.register))-1)
+}
+
+// insert switches to insert mode with the cursor at pos.
+func (v *vim) insert(b buffer, pos int) buffer {
+	v.save(b)
+	v.mode = vimInsert
+	return b.at(pos)
+}
+
+// escape switches back to normal mode, moving the cursor onto the character
+// before it as vim does.
+func (v *vim) escape(b buffer) buffer {
+	v.mode = vimNormal
+	v.pending = ""
+	// Nothing to undo when nothing was typed.
+	if n := len(v.undo); n > 0 && slices.Equal(v.undo[n-1].text, b.text) {
+		v.undo = v.undo[:n-1]
+	}
+	if b.pos > b.lineStart() {
+		b = b.at(b.pos - 1)
+	}
+	return b
+}
+
+// save records b to be restored by u.
+func (v *vim) save(b buffer) {
+	if len(v.undo) > 0 && slices.Equal(v.undo[len(v.undo)-1].text, b.text) {
+		return
+	}
+	v.undo = append(v.undo, buffer{text: slices.Clone(b.text), pos: b.pos})
+	if len(v.undo) > maxVimUndo {
+		v.undo = v.undo[1:]
+	}
+}
@@ -0,0 +1,106 @@
+package editor
+
+import (
+	"testing"
+
+	"charm.land/bubbles/v2/textarea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVimNormal(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		text  string
+		pos   int
+		keys  string
+		want  string
+		cur   int
+		mode  vimMode
+		yank  string
+		lines bool
+	}{
+		"word motions": {text: "fix the bug", keys: "wwb", want: "fix the bug", cur: 4},
+		"end of line":  {text: "fix\nthe bug", pos: 4, keys: "$", want: "fix\nthe bug", cur: 10},
+		"line motions": {text: "fix the bug\nnow", pos: 8, keys: "jk", want: "fix the bug\nnow", cur: 2},
+		"top":          {text: "fix\nthe\nbug", pos: 9, keys: "gg", want: "fix\nthe\nbug", cur: 0},
+		"delete char":  {text: "fix", keys: "x", want: "ix", yank: "f"},
+		"delete word":  {text: "fix the bug", keys: "dw", want: "the bug", yank: "fix "},
+		"delete last word": {
+			text: "fix the\nbug", pos: 4, keys: "dw", want: "fix \nbug", cur: 3, yank: "the",
+		},
+		"delete to end": {text: "fix the bug", pos: 3, keys: "D", want: "fix", cur: 2, yank: " the bug"},
+		"delete line": {
+			text: "fix\nthe\nbug", pos: 5, keys: "dd", want: "fix\nbug", cur: 4, yank: "the", lines: true,
+		},
+		"delete last line": {
+			text: "fix\nthe", pos: 5, keys: "dd", want: "fix", cur: 0, yank: "the", lines: true,
+		},
+		"change word": {
+			text: "fix the bug", keys: "cw", want: " the bug", mode: vimInsert, yank: "fix",
+		},
+		"yank and put": {text: "fix the bug", keys: "ywP", want: "fix fix the bug", cur: 3, yank: "fix "},
+		"yank and put line": {
+			text: "fix\nbug", keys: "yyjp", want: "fix\nbug\nfix", cur: 8, yank: "fix", lines: true,
+		},
+		"open line": {text: "fix\nbug", keys: "o", want: "fix\n\nbug", cur: 4, mode: vimInsert},
+		"append":    {text: "fix", keys: "A", want: "fix", cur: 3, mode: vimInsert},
+		"undo":      {text: "fix the bug", keys: "dwdwu", want: "the bug", yank: "the "},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			v := &vim{}
+			b := buffer{text: []rune(tc.text), pos: tc.pos}
+			for _, k := range tc.keys {
+				b = v.normal(b, string(k))
+			}
+			require.Equal(t, tc.want, string(b.text))
+			require.Equal(t, tc.cur, b.pos)
+			require.Equal(t, tc.mode, v.mode)
+			require.Equal(t, tc.yank, v.register)
+			require.Equal(t, tc.lines, v.linewise)
+		})
+	}
+}
+
+func TestVimEscape(t *testing.T) {
+	t.Parallel()
+
+	v := &vim{}
+	b := v.normal(buffer{text: []rune("fix")}, "A")
+	b = buffer{text: []rune("fix it"), pos: 6}
+	b = v.escape(b)
+	require.Equal(t, vimNormal, v.mode)
+	require.Equal(t, 5, b.pos)
+
+	b = v.normal(b, "u")
+	require.Equal(t, "fix", string(b.text))
+}
+
+func TestVimBufferTextarea(t *testing.T) {
+	t.Parallel()
+
+	ta := textarea.New()
+	ta.SetWidth(20)
+	b := buffer{text: []rune("fix the bug\nin the editor\nnow"), pos: 19}
+	b.apply(&ta)
+	require.Equal(t, 1, ta.Line())
+	require.Equal(t, b, bufferOf(&ta))
+}
+
+func TestLeaveInsertMode(t *testing.T) {
+	t.Parallel()
+
+	m := &editorCmp{textarea: textarea.New(), vim: &vim{mode: vimInsert}}
+	m.textarea.SetWidth(20)
+	m.textarea.SetValue("fix it")
+	m.LeaveInsertMode()
+	require.Equal(t, vimNormal, m.vim.mode)
+	require.Equal(t, 5, bufferOf(&m.textarea).pos)
+
+	m.LeaveInsertMode()
+	require.Equal(t, 5, bufferOf(&m.textarea).pos, "normal mode is left as it is")
+
+	(&editorCmp{textarea: textarea.New()}).LeaveInsertMode()
+}
@@ -0,0 +1,61 @@
+package messages
+
+import "strings"
+
+// completeFences prepares markdown still being streamed for rendering, so the
+// code blocks in it are highlighted as they'll be once the message is done.
+// It drops a trailing fence line still being written, whose language isn't
+// complete yet, and closes the code block left open by the text streamed so
+// far.
+func completeFences(content string) string {
+	lines := strings.Split(content, "\n")
+	partial := !strings.HasSuffix(content, "\n")
+
+	var open string
+	for i, line := range lines {
+		last := partial && i == len(lines)-1
+		trimmed := strings.TrimLeft(line, " \t")
+		if open == "" {
+			fence, ok := openingFence(trimmed)
+			if !ok {
+				continue
+			}
+			if last {
+				lines = lines[:i]
+				break
+			}
+			open = fence
+			continue
+		}
+		run := strings.TrimRight(trimmed, " \t")
+		if run == "" || strings.Trim(run, open[:1]) != "" {
+			continue
+		}
+		if len(run) >= len(open) {
+			open = ""
+		} else if last {
+			lines = lines[:i]
+		}
+	}
+
+	content = strings.Join(lines, "\n")
+	if open == "" {
+		return content
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + open
+}
+
+// openingFence returns the fence opening a code block on line, if any.
+func openingFence(line string) (string, bool) {
+	if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+		return "", false
+	}
+	fence := line[:len(line)-len(strings.TrimLeft(line, line[:1]))]
+	if fence[0] == '`' && strings.Contains(line[len(fence):], "`") {
+		return "", false
+	}
+	return fence, true
+}
@@ -0,0 +1,49 @@
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteFences(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		content, want string
+	}{
+		"no code": {
+			content: "Here's the plan",
+			want:    "Here's the plan",
+		},
+		"closed block": {
+			content: "```go\nfunc main() {}\n```\nDone",
+			want:    "```go\nfunc main() {}\n```\nDone",
+		},
+		"open block": {
+			content: "Try this:\n```go\nfunc main() {",
+			want:    "Try this:\n```go\nfunc main() {\n```",
+		},
+		"open block ending a line": {
+			content: "~~~~diff\n-a\n+b\n",
+			want:    "~~~~diff\n-a\n+b\n~~~~",
+		},
+		"language being written": {
+			content: "Try this:\n```pyt",
+			want:    "Try this:",
+		},
+		"closing fence being written": {
+			content: "```go\nfunc main() {}\n``",
+			want:    "```go\nfunc main() {}\n```",
+		},
+		"inline code": {
+			content: "Run ```go test``` first",
+			want:    "Run ```go test``` first",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.want, completeFences(tc.content))
+		})
+	}
+}
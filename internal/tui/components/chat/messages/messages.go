@@ -29,6 +29,10 @@ import (
 // CopyKey is the key binding for copying message content to the clipboard.
 var CopyKey = key.NewBinding(key.WithKeys("c", "y", "C", "Y"), key.WithHelp("c/y", "copy"))
 
+// ExpandKey is the key binding for expanding the selected tool call, or the
+// thinking of the selected message, or collapsing it back.
+var ExpandKey = key.NewBinding(key.WithKeys("enter", "o"), key.WithHelp("enter", "expand"))
+
 // ClearSelectionKey is the key binding for clearing the current selection in the chat interface.
 var ClearSelectionKey = key.NewBinding(key.WithKeys("esc", "alt+esc"), key.WithHelp("esc", "clear selection"))
 
@@ -58,6 +62,8 @@ type messageCmp struct {
 
 	// Thinking viewport for displaying reasoning content
 	thinkingViewport viewport.Model
+	hideThinking     bool // Whether the thinking is left out entirely
+	thinkingExpanded bool // Whether the finished thinking is shown in full
 }
 
 var focusedMessageBorder = lipgloss.Border{
@@ -82,6 +88,9 @@ func NewMessageCmp(msg message.Message) MessageCmp {
 		}),
 		thinkingViewport: thinkingViewport,
 	}
+	if cfg := config.Get(); cfg != nil && cfg.Options != nil && cfg.Options.TUI != nil {
+		m.hideThinking = cfg.Options.TUI.HideThinking
+	}
 	return m
 }
 
@@ -104,6 +113,10 @@ func (m *messageCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			return m, cmd
 		}
 	case tea.KeyPressMsg:
+		if key.Matches(msg, ExpandKey) && m.message.ReasoningContent().Thinking != "" {
+			m.thinkingExpanded = !m.thinkingExpanded
+			return m, nil
+		}
 		if key.Matches(msg, CopyKey) {
 			return m, tea.Sequence(
 				tea.SetClipboard(m.message.Content().Text),
@@ -121,9 +134,12 @@ func (m *messageCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 // View renders the message component based on its current state.
 // Returns different views for spinning, user, and assistant messages.
 func (m *messageCmp) View() string {
-	if m.spinning && m.message.ReasoningContent().Thinking == "" {
-		if m.message.IsSummaryMessage {
+	if m.spinning && (m.message.ReasoningContent().Thinking == "" || m.hideThinking) {
+		switch {
+		case m.message.IsSummaryMessage:
 			m.anim.SetLabel("Summarizing")
+		case m.message.ReasoningContent().Thinking != "":
+			m.anim.SetLabel("Thinking")
 		}
 		return m.style().PaddingLeft(1).Render(m.anim.View())
 	}
@@ -187,7 +203,7 @@ func (m *messageCmp) renderAssistantMessage() string {
 	finishedData := m.message.FinishPart()
 	thinkingContent := ""
 
-	if thinking || strings.TrimSpace(m.message.ReasoningContent().Thinking) != "" {
+	if !m.hideThinking && (thinking || strings.TrimSpace(m.message.ReasoningContent().Thinking) != "") {
 		m.anim.SetLabel("Thinking")
 		thinkingContent = m.renderThinkingContent()
 	} else if finished && content == "" && finishedData.Reason == message.FinishReasonEndTurn {
@@ -211,6 +227,9 @@ func (m *messageCmp) renderAssistantMessage() string {
 		if thinkingContent != "" {
 			parts = append(parts, "")
 		}
+		if !finished {
+			content = completeFences(content)
+		}
 		parts = append(parts, m.toMarkdown(content))
 	}
 
@@ -285,13 +304,19 @@ func (m *messageCmp) renderThinkingContent() string {
 	}
 
 	fullContent := strings.TrimSpace(rendered)
+	// The thinking streams in its last lines, and is collapsed to its
+	// footer once over unless expanded.
 	height := ordered.Clamp(lipgloss.Height(fullContent), 1, 10)
+	if m.thinkingExpanded {
+		height = lipgloss.Height(fullContent)
+	}
 	m.thinkingViewport.SetHeight(height)
 	m.thinkingViewport.SetWidth(m.textWidth())
 	m.thinkingViewport.SetContent(fullContent)
 	m.thinkingViewport.GotoBottom()
 	finishReason := m.message.FinishPart()
 	var footer string
+	collapsed := false
 	if reasoningContent.StartedAt > 0 {
 		duration := m.message.ThinkingDuration()
 		if reasoningContent.FinishedAt > 0 {
@@ -300,15 +325,23 @@ func (m *messageCmp) renderThinkingContent() string {
 				Title:       "Thought for",
 				Description: duration.String(),
 			}
-			if duration.String() != "0s" {
-				footer = t.S().Base.PaddingLeft(1).Render(core.Status(opts, m.textWidth()-1))
+			if duration.String() == "0s" {
+				opts = core.StatusOpts{Title: "Thought"}
+			}
+			if !m.thinkingExpanded {
+				opts.ExtraContent = t.S().Subtle.Render(ExpandKey.Help().Key + " to expand")
+				collapsed = true
 			}
+			footer = t.S().Base.PaddingLeft(1).Render(core.Status(opts, m.textWidth()-1))
 		} else if finishReason != nil && finishReason.Reason == message.FinishReasonCanceled {
 			footer = t.S().Base.PaddingLeft(1).Render(m.toMarkdown("*Canceled*"))
 		} else {
 			footer = m.anim.View()
 		}
 	}
+	if collapsed {
+		return footer
+	}
 	lineStyle := t.S().Subtle.Background(t.BgBaseLighter)
 	return lineStyle.Width(m.textWidth()).Padding(0, 1).Render(m.thinkingViewport.View()) + "\n\n" + footer
 }
@@ -378,6 +411,8 @@ type assistantSectionModel struct {
 	id                  string
 	message             message.Message
 	lastUserMessageTime time.Time
+	// cost is what the whole turn cost, over all its requests.
+	cost float64
 }
 
 // ID implements AssistantSection.
@@ -385,12 +420,13 @@ func (m *assistantSectionModel) ID() string {
 	return m.id
 }
 
-func NewAssistantSection(message message.Message, lastUserMessageTime time.Time) AssistantSection {
+func NewAssistantSection(message message.Message, lastUserMessageTime time.Time, cost float64) AssistantSection {
 	return &assistantSectionModel{
 		width:               0,
 		id:                  uuid.NewString(),
 		message:             message,
 		lastUserMessageTime: lastUserMessageTime,
+		cost:                cost,
 	}
 }
 
@@ -407,7 +443,11 @@ func (m *assistantSectionModel) View() string {
 	finishData := m.message.FinishPart()
 	finishTime := time.Unix(finishData.Time, 0)
 	duration := finishTime.Sub(m.lastUserMessageTime)
-	infoMsg := t.S().Subtle.Render(duration.String())
+	info := duration.String()
+	if m.cost > 0 {
+		info += " · " + formatCost(m.cost)
+	}
+	infoMsg := t.S().Subtle.Render(info)
 	icon := t.S().Subtle.Render(styles.ModelIcon)
 	model := config.Get().GetModel(m.message.Provider, m.message.Model)
 	if model == nil {
@@ -423,6 +463,14 @@ func (m *assistantSectionModel) View() string {
 	)
 }
 
+// formatCost formats a cost in USD, keeping small amounts readable.
+func formatCost(cost float64) string {
+	if cost < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
 func (m *assistantSectionModel) GetSize() (int, int) {
 	return m.width, 1
 }
@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageThinking(t *testing.T) {
+	t.Parallel()
+
+	msg := message.Message{
+		ID:   "msg",
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.ReasoningContent{Thinking: "Weighing the options", StartedAt: 100, FinishedAt: 112},
+			message.TextContent{Text: "The answer"},
+			message.Finish{Reason: message.FinishReasonEndTurn},
+		},
+	}
+	view := func(m *messageCmp) string {
+		return ansi.Strip(m.View())
+	}
+
+	m := NewMessageCmp(msg).(*messageCmp)
+	m.SetSize(80, 0)
+	require.Contains(t, view(m), "Thought for 12s")
+	require.NotContains(t, view(m), "Weighing the options", "finished thinking should be collapsed")
+	require.Contains(t, view(m), "The answer")
+
+	m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	require.Contains(t, view(m), "Weighing the options")
+
+	m.hideThinking = true
+	require.NotContains(t, view(m), "Thought for")
+	require.NotContains(t, view(m), "Weighing the options")
+	require.Contains(t, view(m), "The answer")
+}
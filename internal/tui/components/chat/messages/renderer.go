@@ -156,6 +156,9 @@ func (br baseRenderer) makeHeader(v *toolCallCmp, tool string, width int, params
 func (br baseRenderer) renderError(v *toolCallCmp, message string) string {
 	t := styles.CurrentTheme()
 	header := br.makeHeader(v, prettifyToolName(v.call.Name), v.textWidth(), "")
+	if v.collapsed() {
+		return header
+	}
 	errorTag := t.S().Base.Padding(0, 1).Background(t.Red).Foreground(t.White).Render("ERROR")
 	message = t.S().Base.Foreground(t.FgHalfMuted).Render(v.fit(message, v.textWidth()-3-lipgloss.Width(errorTag))) // -2 for padding and space
 	return joinHeaderBody(header, errorTag+" "+message)
@@ -170,6 +173,7 @@ func init() {
 	registry.register(tools.ViewToolName, func() renderer { return viewRenderer{} })
 	registry.register(tools.EditToolName, func() renderer { return editRenderer{} })
 	registry.register(tools.MultiEditToolName, func() renderer { return multiEditRenderer{} })
+	registry.register(tools.ApplyPatchToolName, func() renderer { return applyPatchRenderer{} })
 	registry.register(tools.WriteToolName, func() renderer { return writeRenderer{} })
 	registry.register(tools.FetchToolName, func() renderer { return simpleFetchRenderer{} })
 	registry.register(tools.AgenticFetchToolName, func() renderer { return agenticFetchRenderer{} })
@@ -178,8 +182,10 @@ func init() {
 	registry.register(tools.GrepToolName, func() renderer { return grepRenderer{} })
 	registry.register(tools.LSToolName, func() renderer { return lsRenderer{} })
 	registry.register(tools.SourcegraphToolName, func() renderer { return sourcegraphRenderer{} })
+	registry.register(tools.WebSearchToolName, func() renderer { return webSearchRenderer{} })
 	registry.register(tools.DiagnosticsToolName, func() renderer { return diagnosticsRenderer{} })
 	registry.register(agent.AgentToolName, func() renderer { return agentRenderer{} })
+	registry.register(agent.AgentOutputToolName, func() renderer { return agentOutputRenderer{} })
 }
 
 // -----------------------------------------------------------------------------
@@ -458,14 +464,14 @@ func (er editRenderer) Render(v *toolCallCmp) string {
 		}
 		// add a message to the bottom if the content was truncated
 		formatted := formatter.String()
-		if lipgloss.Height(formatted) > responseContextHeight {
+		if lipgloss.Height(formatted) > v.maxLines() {
 			contentLines := strings.Split(formatted, "\n")
 			truncateMessage := t.S().Muted.
 				Background(t.BgBaseLighter).
 				PaddingLeft(2).
 				Width(v.textWidth() - 2).
-				Render(fmt.Sprintf("… (%d lines)", len(contentLines)-responseContextHeight))
-			formatted = strings.Join(contentLines[:responseContextHeight], "\n") + "\n" + truncateMessage
+				Render(fmt.Sprintf("… (%d lines)", len(contentLines)-v.maxLines()))
+			formatted = strings.Join(contentLines[:v.maxLines()], "\n") + "\n" + truncateMessage
 		}
 		return formatted
 	})
@@ -509,14 +515,14 @@ func (mer multiEditRenderer) Render(v *toolCallCmp) string {
 		}
 		// add a message to the bottom if the content was truncated
 		formatted := formatter.String()
-		if lipgloss.Height(formatted) > responseContextHeight {
+		if lipgloss.Height(formatted) > v.maxLines() {
 			contentLines := strings.Split(formatted, "\n")
 			truncateMessage := t.S().Muted.
 				Background(t.BgBaseLighter).
 				PaddingLeft(2).
 				Width(v.textWidth() - 4).
-				Render(fmt.Sprintf("… (%d lines)", len(contentLines)-responseContextHeight))
-			formatted = strings.Join(contentLines[:responseContextHeight], "\n") + "\n" + truncateMessage
+				Render(fmt.Sprintf("… (%d lines)", len(contentLines)-v.maxLines()))
+			formatted = strings.Join(contentLines[:v.maxLines()], "\n") + "\n" + truncateMessage
 		}
 
 		// Add failed edits warning if any exist
@@ -533,6 +539,35 @@ func (mer multiEditRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Apply Patch renderer
+// -----------------------------------------------------------------------------
+
+// applyPatchRenderer handles patches with syntax-highlighted diff display
+type applyPatchRenderer struct {
+	baseRenderer
+}
+
+// Render displays the patched files and the highlighted patch
+func (apr applyPatchRenderer) Render(v *toolCallCmp) string {
+	var params tools.ApplyPatchParams
+	var args []string
+	if err := apr.unmarshalParams(v.call.Input, &params); err == nil {
+		var meta tools.ApplyPatchResponseMetadata
+		if v.call.Finished && apr.unmarshalParams(v.result.Metadata, &meta) == nil && len(meta.Files) > 0 {
+			builder := newParamBuilder().addMain(fsext.PrettyPath(meta.Files[0]))
+			if len(meta.Files) > 1 {
+				builder = builder.addKeyValue("files", fmt.Sprintf("%d", len(meta.Files)))
+			}
+			args = builder.build()
+		}
+	}
+
+	return apr.renderWithParams(v, "Apply Patch", args, func() string {
+		return renderCodeContent(v, "patch.diff", params.Patch, 0)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Write renderer
 // -----------------------------------------------------------------------------
@@ -620,6 +655,9 @@ func (fr agenticFetchRenderer) Render(v *toolCallCmp) string {
 	prompt = strings.ReplaceAll(prompt, "\n", " ")
 
 	header := fr.makeHeader(v, "Agentic Fetch", v.textWidth(), args...)
+	if v.collapsed() {
+		return header
+	}
 	if res, done := earlyState(header, v); v.cancelled && done {
 		return res
 	}
@@ -806,6 +844,28 @@ func (lr lsRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Agent output renderer
+// -----------------------------------------------------------------------------
+
+// agentOutputRenderer handles reading the result of a background agent
+type agentOutputRenderer struct {
+	baseRenderer
+}
+
+// Render displays the background agent ID and its result
+func (ar agentOutputRenderer) Render(v *toolCallCmp) string {
+	var params agent.AgentOutputParams
+	var args []string
+	if err := ar.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().addMain(params.TaskID).build()
+	}
+
+	return ar.renderWithParams(v, "Agent: Output", args, func() string {
+		return renderMarkdownContent(v, v.result.Content)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Sourcegraph renderer
 // -----------------------------------------------------------------------------
@@ -832,6 +892,31 @@ func (sr sourcegraphRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Web search renderer
+// -----------------------------------------------------------------------------
+
+// webSearchRenderer handles web searches with an optional result count
+type webSearchRenderer struct {
+	baseRenderer
+}
+
+// Render displays the search query and the results found
+func (wr webSearchRenderer) Render(v *toolCallCmp) string {
+	var params tools.WebSearchParams
+	var args []string
+	if err := wr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(params.Query).
+			addKeyValue("results", formatNonZero(params.MaxResults)).
+			build()
+	}
+
+	return wr.renderWithParams(v, "Web Search", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Diagnostics renderer
 // -----------------------------------------------------------------------------
@@ -886,10 +971,17 @@ func (tr agentRenderer) Render(v *toolCallCmp) string {
 	prompt = strings.ReplaceAll(prompt, "\n", " ")
 
 	header := tr.makeHeader(v, "Agent", v.textWidth())
+	if v.collapsed() {
+		return header
+	}
 	if res, done := earlyState(header, v); v.cancelled && done {
 		return res
 	}
-	taskTag := t.S().Base.Bold(true).Padding(0, 1).MarginLeft(2).Background(t.BlueLight).Foreground(t.White).Render("Task")
+	tag := "Task"
+	if params.Subagent != "" {
+		tag = params.Subagent
+	}
+	taskTag := t.S().Base.Bold(true).Padding(0, 1).MarginLeft(2).Background(t.BlueLight).Foreground(t.White).Render(tag)
 	remainingWidth := v.textWidth() - lipgloss.Width(header) - lipgloss.Width(taskTag) - 2
 	remainingWidth = min(remainingWidth, 120-lipgloss.Width(taskTag)-2)
 	prompt = t.S().Muted.Width(remainingWidth).Render(prompt)
@@ -978,11 +1070,14 @@ func renderParamList(nested bool, paramsWidth int, params ...string) string {
 	return t.S().Subtle.Render(ansi.Truncate(mainParam, paramsWidth, "…"))
 }
 
-// earlyState returns immediately‑rendered error/cancelled/ongoing states.
+// earlyState returns immediately‑rendered collapsed/error/cancelled/ongoing
+// states. A collapsed tool call is its header alone, its body left unrendered.
 func earlyState(header string, v *toolCallCmp) (string, bool) {
 	t := styles.CurrentTheme()
 	message := ""
 	switch {
+	case v.collapsed():
+		return header, true
 	case v.result.IsError:
 		message = v.renderToolError()
 	case v.cancelled:
@@ -990,6 +1085,8 @@ func earlyState(header string, v *toolCallCmp) (string, bool) {
 	case v.result.ToolCallID == "":
 		if v.permissionRequested && !v.permissionGranted {
 			message = t.S().Base.Foreground(t.FgSubtle).Render("Requesting permission...")
+		} else if v.output != "" {
+			return joinHeaderBody(header, renderLiveOutput(v)), true
 		} else {
 			message = t.S().Base.Foreground(t.FgSubtle).Render("Waiting for tool response...")
 		}
@@ -1012,38 +1109,71 @@ func joinHeaderBody(header, body string) string {
 
 func renderPlainContent(v *toolCallCmp, content string) string {
 	t := styles.CurrentTheme()
-	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
-	content = strings.ReplaceAll(content, "\t", "    ") // Replace tabs with spaces
-	content = strings.TrimSpace(content)
-	lines := strings.Split(content, "\n")
+	lines := plainLines(content)
 
 	width := v.textWidth() - 2
 	var out []string
 	for i, ln := range lines {
-		if i >= responseContextHeight {
+		if i >= v.maxLines() {
 			break
 		}
-		ln = ansiext.Escape(ln)
-		ln = " " + ln
-		if len(ln) > width {
-			ln = v.fit(ln, width)
-		}
+		out = append(out, renderPlainLine(v, ln, width))
+	}
+
+	if len(lines) > v.maxLines() {
 		out = append(out, t.S().Muted.
-			Width(width).
 			Background(t.BgBaseLighter).
-			Render(ln))
+			Width(width).
+			Render(fmt.Sprintf("… (%d lines)", len(lines)-v.maxLines())))
 	}
 
-	if len(lines) > responseContextHeight {
+	return strings.Join(out, "\n")
+}
+
+// renderLiveOutput renders the last lines of the output of a running tool,
+// following it as it grows, or all of them when expanded.
+func renderLiveOutput(v *toolCallCmp) string {
+	t := styles.CurrentTheme()
+	lines := plainLines(v.output)
+
+	width := v.textWidth() - 2
+	var out []string
+	if len(lines) > v.maxLines() {
 		out = append(out, t.S().Muted.
 			Background(t.BgBaseLighter).
 			Width(width).
-			Render(fmt.Sprintf("… (%d lines)", len(lines)-responseContextHeight)))
+			Render(fmt.Sprintf("… (%d lines)", len(lines)-v.maxLines())))
+		lines = lines[len(lines)-v.maxLines():]
+	}
+	for _, ln := range lines {
+		out = append(out, renderPlainLine(v, ln, width))
 	}
 
 	return strings.Join(out, "\n")
 }
 
+// plainLines returns the lines of the plain text output of a tool.
+func plainLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
+	content = strings.ReplaceAll(content, "\t", "    ") // Replace tabs with spaces
+	content = strings.TrimSpace(content)
+	return strings.Split(content, "\n")
+}
+
+// renderPlainLine renders a line of plain text output, fit to width.
+func renderPlainLine(v *toolCallCmp, ln string, width int) string {
+	t := styles.CurrentTheme()
+	ln = ansiext.Escape(ln)
+	ln = " " + ln
+	if len(ln) > width {
+		ln = v.fit(ln, width)
+	}
+	return t.S().Muted.
+		Width(width).
+		Background(t.BgBaseLighter).
+		Render(ln)
+}
+
 func renderMarkdownContent(v *toolCallCmp, content string) string {
 	t := styles.CurrentTheme()
 	content = strings.ReplaceAll(content, "\r\n", "\n")
@@ -1063,17 +1193,17 @@ func renderMarkdownContent(v *toolCallCmp, content string) string {
 
 	var out []string
 	for i, ln := range lines {
-		if i >= responseContextHeight {
+		if i >= v.maxLines() {
 			break
 		}
 		out = append(out, ln)
 	}
 
 	style := t.S().Muted.Background(t.BgBaseLighter)
-	if len(lines) > responseContextHeight {
+	if len(lines) > v.maxLines() {
 		out = append(out, style.
 			Width(width-2).
-			Render(fmt.Sprintf("… (%d lines)", len(lines)-responseContextHeight)))
+			Render(fmt.Sprintf("… (%d lines)", len(lines)-v.maxLines())))
 	}
 
 	return style.Render(strings.Join(out, "\n"))
@@ -1100,7 +1230,7 @@ func renderCodeContent(v *toolCallCmp, path, content string, offset int) string
 	t := styles.CurrentTheme()
 	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
 	content = strings.ReplaceAll(content, "\t", "    ") // Replace tabs with spaces
-	truncated := truncateHeight(content, responseContextHeight)
+	truncated := truncateHeight(content, v.maxLines())
 
 	lines := strings.Split(truncated, "\n")
 	for i, ln := range lines {
@@ -1111,10 +1241,10 @@ func renderCodeContent(v *toolCallCmp, path, content string, offset int) string
 	highlighted, _ := highlight.SyntaxHighlight(strings.Join(lines, "\n"), path, bg)
 	lines = strings.Split(highlighted, "\n")
 
-	if len(strings.Split(content, "\n")) > responseContextHeight {
+	if len(strings.Split(content, "\n")) > v.maxLines() {
 		lines = append(lines, t.S().Muted.
 			Background(bg).
-			Render(fmt.Sprintf(" …(%d lines)", len(strings.Split(content, "\n"))-responseContextHeight)))
+			Render(fmt.Sprintf(" …(%d lines)", len(strings.Split(content, "\n"))-v.maxLines())))
 	}
 
 	maxLineNumber := len(lines) + offset
@@ -1163,6 +1293,8 @@ func prettifyToolName(name string) string {
 	switch name {
 	case agent.AgentToolName:
 		return "Agent"
+	case agent.AgentOutputToolName:
+		return "Agent: Output"
 	case tools.BashToolName:
 		return "Bash"
 	case tools.JobOutputToolName:
@@ -1175,6 +1307,8 @@ func prettifyToolName(name string) string {
 		return "Edit"
 	case tools.MultiEditToolName:
 		return "Multi-Edit"
+	case tools.ApplyPatchToolName:
+		return "Apply Patch"
 	case tools.FetchToolName:
 		return "Fetch"
 	case tools.AgenticFetchToolName:
@@ -1189,6 +1323,10 @@ func prettifyToolName(name string) string {
 		return "List"
 	case tools.SourcegraphToolName:
 		return "Sourcegraph"
+	case tools.SemanticSearchToolName:
+		return "Semantic Search"
+	case tools.WebSearchToolName:
+		return "Web Search"
 	case tools.ViewToolName:
 		return "View"
 	case tools.WriteToolName:
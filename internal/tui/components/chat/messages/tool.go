@@ -1,8 +1,10 @@
 package messages
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"path/filepath"
 	"strings"
 	"time"
@@ -13,6 +15,7 @@ import (
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/message"
@@ -43,6 +46,7 @@ type ToolCallCmp interface {
 	ID() string
 	SetPermissionRequested() // Mark permission request
 	SetPermissionGranted()   // Mark permission granted
+	SetOutput(string)        // Update the output of the running tool
 }
 
 // toolCallCmp implements the ToolCallCmp interface for displaying tool calls.
@@ -59,6 +63,9 @@ type toolCallCmp struct {
 	cancelled           bool               // Whether the tool call was cancelled
 	permissionRequested bool
 	permissionGranted   bool
+	output              string // Output of the tool while it runs
+	verbosity           string // How much of the tool call is shown
+	expanded            bool   // Whether the view of the verbosity is toggled
 
 	// Animation state for pending tool calls
 	spinning bool       // Whether to show loading animation
@@ -114,6 +121,10 @@ func NewToolCallCmp(parentMessageID string, tc message.ToolCall, permissions per
 	m := &toolCallCmp{
 		call:            tc,
 		parentMessageID: parentMessageID,
+		verbosity:       config.ToolVerbosityNormal,
+	}
+	if cfg := config.Get(); cfg != nil && cfg.Options != nil && cfg.Options.TUI != nil && cfg.Options.TUI.ToolVerbosity != "" {
+		m.verbosity = cfg.Options.TUI.ToolVerbosity
 	}
 	for _, opt := range opts {
 		opt(m)
@@ -165,8 +176,11 @@ func (m *toolCallCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		}
 		return m, tea.Batch(cmds...)
 	case tea.KeyPressMsg:
-		if key.Matches(msg, CopyKey) {
+		switch {
+		case key.Matches(msg, CopyKey):
 			return m, m.copyTool()
+		case key.Matches(msg, ExpandKey):
+			m.expanded = !m.expanded
 		}
 	}
 	return m, nil
@@ -186,7 +200,47 @@ func (m *toolCallCmp) View() string {
 	if m.isNested {
 		return box.Render(r.Render(m))
 	}
-	return box.Render(r.Render(m))
+	view := r.Render(m)
+	if m.verbosity == config.ToolVerbosityDebug && !m.collapsed() {
+		view = joinHeaderBody(view, m.renderInput())
+	}
+	return box.Render(view)
+}
+
+// collapsed reports whether only the one-line summary of the tool call is
+// shown. Quiet collapses every tool call and normal the ones that finished
+// without error, while debug expands them all, unless toggled.
+func (m *toolCallCmp) collapsed() bool {
+	switch m.verbosity {
+	case config.ToolVerbosityQuiet:
+		return !m.expanded
+	case config.ToolVerbosityDebug:
+		return m.expanded
+	}
+	finished := m.result.ToolCallID != "" && !m.result.IsError
+	return finished && !m.expanded
+}
+
+// maxLines returns the number of lines of the output of the tool call shown.
+// It's all of them when expanded in normal verbosity, and in debug.
+func (m *toolCallCmp) maxLines() int {
+	if m.verbosity == config.ToolVerbosityDebug ||
+		(m.verbosity != config.ToolVerbosityQuiet && m.expanded) {
+		return math.MaxInt
+	}
+	return responseContextHeight
+}
+
+// renderInput renders the raw input of the tool call, shown in debug.
+func (m *toolCallCmp) renderInput() string {
+	if m.call.Input == "" {
+		return ""
+	}
+	var input bytes.Buffer
+	if err := json.Indent(&input, []byte(m.call.Input), "", "  "); err != nil {
+		return renderPlainContent(m, m.call.Input)
+	}
+	return renderPlainContent(m, input.String())
 }
 
 // State management methods
@@ -371,11 +425,23 @@ func (m *toolCallCmp) formatParametersForCopy() string {
 			}
 			return strings.Join(parts, "\n")
 		}
+	case tools.WebSearchToolName:
+		var params tools.WebSearchParams
+		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
+			parts := []string{fmt.Sprintf("**Query:** %s", params.Query)}
+			if params.MaxResults > 0 {
+				parts = append(parts, fmt.Sprintf("**Results:** %d", params.MaxResults))
+			}
+			return strings.Join(parts, "\n")
+		}
 	case tools.DiagnosticsToolName:
 		return "**Project:** diagnostics"
 	case agent.AgentToolName:
 		var params agent.AgentParams
 		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
+			if params.Subagent != "" {
+				return fmt.Sprintf("**Subagent:** %s\n**Task:**\n%s", params.Subagent, params.Prompt)
+			}
 			return fmt.Sprintf("**Task:**\n%s", params.Prompt)
 		}
 	}
@@ -416,7 +482,7 @@ func (m *toolCallCmp) formatResultForCopy() string {
 		return m.formatWebFetchResultForCopy()
 	case agent.AgentToolName:
 		return m.formatAgentResultForCopy()
-	case tools.DownloadToolName, tools.GrepToolName, tools.GlobToolName, tools.LSToolName, tools.SourcegraphToolName, tools.DiagnosticsToolName:
+	case tools.DownloadToolName, tools.GrepToolName, tools.GlobToolName, tools.LSToolName, tools.SourcegraphToolName, tools.WebSearchToolName, tools.DiagnosticsToolName:
 		return fmt.Sprintf("```\n%s\n```", m.result.Content)
 	default:
 		return m.result.Content
@@ -718,9 +784,15 @@ func (m *toolCallCmp) ParentMessageID() string {
 // SetToolResult updates the tool result and stops the spinning animation
 func (m *toolCallCmp) SetToolResult(result message.ToolResult) {
 	m.result = result
+	m.output = ""
 	m.spinning = false
 }
 
+// SetOutput updates the output of the tool while it runs
+func (m *toolCallCmp) SetOutput(output string) {
+	m.output = output
+}
+
 // GetToolCall returns the current tool call data
 func (m *toolCallCmp) GetToolCall() message.ToolCall {
 	return m.call
@@ -0,0 +1,65 @@
+package messages
+
+import (
+	"math"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolCallVerbosity(t *testing.T) {
+	t.Parallel()
+
+	finished := message.ToolResult{ToolCallID: "call"}
+	failed := message.ToolResult{ToolCallID: "call", IsError: true}
+
+	tests := []struct {
+		name      string
+		verbosity string
+		result    message.ToolResult
+		expanded  bool
+		collapsed bool
+		maxLines  int
+	}{
+		{"quiet running", config.ToolVerbosityQuiet, message.ToolResult{}, false, true, responseContextHeight},
+		{"quiet expanded", config.ToolVerbosityQuiet, finished, true, false, responseContextHeight},
+		{"normal running", config.ToolVerbosityNormal, message.ToolResult{}, false, false, responseContextHeight},
+		{"normal finished", config.ToolVerbosityNormal, finished, false, true, responseContextHeight},
+		{"normal failed", config.ToolVerbosityNormal, failed, false, false, responseContextHeight},
+		{"normal expanded", config.ToolVerbosityNormal, finished, true, false, math.MaxInt},
+		{"debug", config.ToolVerbosityDebug, finished, false, false, math.MaxInt},
+		{"debug collapsed", config.ToolVerbosityDebug, finished, true, true, math.MaxInt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &toolCallCmp{verbosity: tt.verbosity, result: tt.result, expanded: tt.expanded}
+			require.Equal(t, tt.collapsed, m.collapsed())
+			require.Equal(t, tt.maxLines, m.maxLines())
+		})
+	}
+}
+
+func TestCollapsedToolCallRendersHeaderOnly(t *testing.T) {
+	t.Parallel()
+
+	m := &toolCallCmp{
+		verbosity: config.ToolVerbosityNormal,
+		call:      message.ToolCall{ID: "call", Name: "tool", Finished: true},
+		result:    message.ToolResult{ToolCallID: "call", Content: "output"},
+		width:     80,
+	}
+	view := baseRenderer{}.renderWithParams(m, "Tool", []string{"arg"}, func() string {
+		t.Fatal("the body of a collapsed tool call should not be rendered")
+		return ""
+	})
+	require.NotContains(t, view, "\n")
+	require.Contains(t, view, "arg")
+
+	m.expanded = true
+	view = baseRenderer{}.renderWithParams(m, "Tool", []string{"arg"}, func() string { return "output" })
+	require.Contains(t, view, "output")
+}
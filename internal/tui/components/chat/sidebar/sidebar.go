@@ -3,12 +3,13 @@ package sidebar
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"slices"
 	"strings"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
-	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/diff"
@@ -28,6 +29,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/crush/internal/version"
+	"github.com/charmbracelet/x/ansi"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -57,6 +59,11 @@ type SessionFilesMsg struct {
 	Files []SessionFile
 }
 
+// ContextFilesMsg carries the context files given to the model.
+type ContextFilesMsg struct {
+	Files []prompt.ContextFile
+}
+
 type Sidebar interface {
 	util.Model
 	layout.Sizeable
@@ -69,6 +76,7 @@ type sidebarCmp struct {
 	session       session.Session
 	logo          string
 	cwd           string
+	contextFiles  []prompt.ContextFile
 	lspClients    *csync.Map[string, *lsp.Client]
 	compactMode   bool
 	history       history.Service
@@ -85,7 +93,7 @@ func New(history history.Service, lspClients *csync.Map[string, *lsp.Client], co
 }
 
 func (m *sidebarCmp) Init() tea.Cmd {
-	return nil
+	return loadContextFiles
 }
 
 func (m *sidebarCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
@@ -96,6 +104,11 @@ func (m *sidebarCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			m.files.Set(file.FilePath, file)
 		}
 		return m, nil
+	case ContextFilesMsg:
+		m.contextFiles = msg.Files
+		return m, nil
+	case config.Reloaded:
+		return m, loadContextFiles
 
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
@@ -141,10 +154,11 @@ func (m *sidebarCmp) View() string {
 	}
 
 	if !m.compactMode {
-		parts = append(parts,
-			m.cwd,
-			"",
-		)
+		parts = append(parts, m.cwd)
+		if len(m.contextFiles) > 0 {
+			parts = append(parts, m.contextFilesBlock())
+		}
+		parts = append(parts, "")
 	}
 	parts = append(parts,
 		m.currentModelBlock(),
@@ -313,6 +327,9 @@ func (m *sidebarCmp) calculateAvailableHeight() int {
 
 	if !m.compactMode {
 		usedHeight += 1 // CWD line
+		if len(m.contextFiles) > 0 {
+			usedHeight += 1 // Context files line
+		}
 		usedHeight += 1 // Empty line after CWD
 	}
 
@@ -505,9 +522,9 @@ func (m *sidebarCmp) mcpBlock() string {
 	}, true)
 }
 
-func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
-	t := styles.CurrentTheme()
-	// Format tokens in human-readable format (e.g., 110K, 1.2M)
+// formatTokens formats a token count in human-readable format (e.g., 110K,
+// 1.2M).
+func formatTokens(tokens int64) string {
 	var formattedTokens string
 	switch {
 	case tokens >= 1_000_000:
@@ -525,6 +542,30 @@ func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
 	if strings.HasSuffix(formattedTokens, ".0M") {
 		formattedTokens = strings.Replace(formattedTokens, ".0M", "M", 1)
 	}
+	return formattedTokens
+}
+
+// formatSessionUsage formats the tokens sent and received over the whole
+// session.
+func formatSessionUsage(promptTokens, completionTokens int64) string {
+	t := styles.CurrentTheme()
+	return t.S().Base.Foreground(t.FgSubtle).Render(
+		fmt.Sprintf("%s in · %s out", formatTokens(promptTokens), formatTokens(completionTokens)),
+	)
+}
+
+// formatCacheUsage formats the prompt tokens read from and written to the
+// provider's prompt cache over the whole session.
+func formatCacheUsage(readTokens, writeTokens int64) string {
+	t := styles.CurrentTheme()
+	return t.S().Base.Foreground(t.FgSubtle).Render(
+		fmt.Sprintf("cache %s read · %s written", formatTokens(readTokens), formatTokens(writeTokens)),
+	)
+}
+
+func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
+	t := styles.CurrentTheme()
+	formattedTokens := formatTokens(tokens)
 
 	percentage := (float64(tokens) / float64(contextWindow)) * 100
 
@@ -562,13 +603,16 @@ func (s *sidebarCmp) currentModelBlock() string {
 	}
 	if model.CanReason {
 		reasoningInfoStyle := t.S().Subtle.PaddingLeft(2)
-		switch modelProvider.Type {
-		case catwalk.TypeAnthropic:
+		switch {
+		case config.ThinksWithToggle(modelProvider.Type, model.ID):
 			formatter := cases.Title(language.English, cases.NoLower)
-			if selectedModel.Think {
-				parts = append(parts, reasoningInfoStyle.Render(formatter.String("Thinking on")))
-			} else {
+			switch {
+			case !selectedModel.Think:
 				parts = append(parts, reasoningInfoStyle.Render(formatter.String("Thinking off")))
+			case selectedModel.ThinkingBudget > 0:
+				parts = append(parts, reasoningInfoStyle.Render(formatter.String("Thinking on")+fmt.Sprintf(" (%d tokens)", selectedModel.ThinkingBudget)))
+			default:
+				parts = append(parts, reasoningInfoStyle.Render(formatter.String("Thinking on")))
 			}
 		default:
 			reasoningEffort := model.DefaultReasoningEffort
@@ -588,6 +632,12 @@ func (s *sidebarCmp) currentModelBlock() string {
 				s.session.Cost,
 			),
 		)
+		if s.session.TotalPromptTokens+s.session.TotalCompletionTokens > 0 {
+			parts = append(parts, "  "+formatSessionUsage(s.session.TotalPromptTokens, s.session.TotalCompletionTokens))
+		}
+		if s.session.TotalCacheReadTokens+s.session.TotalCacheWriteTokens > 0 {
+			parts = append(parts, "  "+formatCacheUsage(s.session.TotalCacheReadTokens, s.session.TotalCacheWriteTokens))
+		}
 	}
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -598,7 +648,7 @@ func (s *sidebarCmp) currentModelBlock() string {
 // SetSession implements Sidebar.
 func (m *sidebarCmp) SetSession(session session.Session) tea.Cmd {
 	m.session = session
-	return m.loadSessionFiles
+	return tea.Batch(m.loadSessionFiles, loadContextFiles)
 }
 
 // SetCompactMode sets the compact mode for the sidebar.
@@ -606,6 +656,36 @@ func (m *sidebarCmp) SetCompactMode(compact bool) {
 	m.compactMode = compact
 }
 
+// loadContextFiles reads the context files given to the model, which can
+// change from a session to the next.
+func loadContextFiles() tea.Msg {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+	return ContextFilesMsg{Files: prompt.LoadContextFiles(*cfg, nil)}
+}
+
+// contextFilesBlock lists the context files given to the model, marking the
+// truncated ones.
+func (m *sidebarCmp) contextFilesBlock() string {
+	t := styles.CurrentTheme()
+	workingDir := config.Get().WorkingDir()
+	names := make([]string, 0, len(m.contextFiles))
+	for _, file := range m.contextFiles {
+		name := home.Short(file.Path)
+		if rel, err := filepath.Rel(workingDir, file.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		if file.Truncated {
+			name += " (truncated)"
+		}
+		names = append(names, name)
+	}
+	line := t.S().Subtle.Render("Context ") + t.S().Muted.Render(strings.Join(names, ", "))
+	return ansi.Truncate(line, m.getMaxWidth(), "…")
+}
+
 func cwd() string {
 	cwd := config.Get().WorkingDir()
 	t := styles.CurrentTheme()
@@ -13,6 +13,7 @@ type KeyMap struct {
 	Tab,
 	LeftRight,
 	Back,
+	Cancel,
 	Copy key.Binding
 }
 
@@ -50,6 +51,10 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("esc", "alt+esc"),
 			key.WithHelp("esc", "back"),
 		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc", "alt+esc", "ctrl+c"),
+			key.WithHelp("esc", "cancel"),
+		),
 		Copy: key.NewBinding(
 			key.WithKeys("c"),
 			key.WithHelp("c", "copy url"),
@@ -1,6 +1,7 @@
 package splash
 
 import (
+	"cmp"
 	"fmt"
 	"strings"
 	"time"
@@ -14,7 +15,7 @@ import (
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/home"
-	"github.com/charmbracelet/crush/internal/oauth"
+	copilotoauth "github.com/charmbracelet/crush/internal/oauth/copilot"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
@@ -60,6 +61,10 @@ type Splash interface {
 
 	// IsShowingCopilotOAuth2 returns whether showing Copilot OAuth2 flow
 	IsShowingCopilotOAuth2() bool
+
+	// IsCopilotAuthInProgress returns whether the Copilot device flow is
+	// waiting on GitHub and can be cancelled
+	IsCopilotAuthInProgress() bool
 }
 
 const (
@@ -74,6 +79,22 @@ type (
 	SubmitAPIKeyMsg       struct{}
 )
 
+// onboardingStep is the current step of the first-run wizard.
+type onboardingStep int
+
+const (
+	// onboardingStepProvider picks the provider to use.
+	onboardingStepProvider onboardingStep = iota
+	// onboardingStepAuth authenticates with the provider, through OAuth or
+	// an API key.
+	onboardingStepAuth
+	// onboardingStepLargeModel picks the default large model.
+	onboardingStepLargeModel
+	// onboardingStepSmallModel picks the default small model and writes the
+	// config.
+	onboardingStepSmallModel
+)
+
 type splashCmp struct {
 	width, height int
 	keyMap        KeyMap
@@ -85,12 +106,16 @@ type splashCmp struct {
 	needsAPIKey      bool
 	selectedNo       bool
 
-	listHeight    int
-	modelList     *models.ModelListComponent
-	apiKeyInput   *models.APIKeyInput
-	selectedModel *models.ModelOption
-	isAPIKeyValid bool
-	apiKeyValue   string
+	// Onboarding wizard state
+	step             onboardingStep
+	listHeight       int
+	providerList     *models.ProviderListComponent
+	modelList        *models.ModelListComponent
+	apiKeyInput      *models.APIKeyInput
+	selectedProvider *catwalk.Provider
+	largeModel       *models.ModelOption
+	isAPIKeyValid    bool
+	apiKeyValue      string
 
 	// Claude state
 	claudeAuthMethodChooser     *claude.AuthMethodChooser
@@ -115,6 +140,7 @@ func New() Splash {
 	listKeyMap.DownOneItem = keyMap.Next
 	listKeyMap.UpOneItem = keyMap.Previous
 
+	providerList := models.NewProviderListComponent(listKeyMap, "Find your provider")
 	modelList := models.NewModelListComponent(listKeyMap, "Find your fave", false)
 	apiKeyInput := models.NewAPIKeyInput()
 
@@ -123,6 +149,7 @@ func New() Splash {
 		height:       0,
 		keyMap:       keyMap,
 		logoRendered: "",
+		providerList: providerList,
 		modelList:    modelList,
 		apiKeyInput:  apiKeyInput,
 		selectedNo:   false,
@@ -135,6 +162,10 @@ func New() Splash {
 
 func (s *splashCmp) SetOnboarding(onboarding bool) {
 	s.isOnboarding = onboarding
+	s.step = onboardingStepProvider
+	s.claudeAuthMethodChooser.SetOnboarding(onboarding)
+	s.claudeOAuth2.SetOnboarding(onboarding)
+	s.copilotOAuth2.SetOnboarding(onboarding)
 }
 
 func (s *splashCmp) SetProjectInit(needsInit bool) {
@@ -149,6 +180,7 @@ func (s *splashCmp) GetSize() (int, int) {
 // Init implements SplashPage.
 func (s *splashCmp) Init() tea.Cmd {
 	return tea.Batch(
+		s.providerList.Init(),
 		s.modelList.Init(),
 		s.apiKeyInput.Init(),
 		s.claudeAuthMethodChooser.Init(),
@@ -171,7 +203,11 @@ func (s *splashCmp) SetSize(width int, height int) tea.Cmd {
 	listWidth := min(60, width)
 	s.apiKeyInput.SetWidth(width - 2)
 	s.claudeAuthMethodChooser.SetWidth(min(width-2, 60))
-	return s.modelList.SetSize(listWidth, s.listHeight)
+	s.copilotOAuth2.SetWidth(width - 2)
+	return tea.Batch(
+		s.providerList.SetSize(listWidth, s.listHeight),
+		s.modelList.SetSize(listWidth, s.listHeight),
+	)
 }
 
 // Update implements SplashPage.
@@ -188,7 +224,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		if msg.State == claude.OAuthValidationStateValid {
 			cmds = append(
 				cmds,
-				s.saveAPIKeyAndContinue(msg.Token, false),
+				s.saveCredentials(msg.Token),
 				func() tea.Msg {
 					time.Sleep(5 * time.Second)
 					return claude.AuthenticationCompleteMsg{}
@@ -200,10 +236,11 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	case claude.AuthenticationCompleteMsg:
 		s.showClaudeAuthMethodChooser = false
 		s.showClaudeOAuth2 = false
-		return s, util.CmdHandler(OnboardingCompleteMsg{})
+		return s, s.startModelSelection()
 	case copilot.AuthenticationCompleteMsg:
 		s.showCopilotOAuth2 = false
-		return s, util.CmdHandler(OnboardingCompleteMsg{})
+		return s, s.startModelSelection()
+
 	case copilot.ValidationCompletedMsg:
 		var cmds []tea.Cmd
 		u, cmd := s.copilotOAuth2.Update(msg)
@@ -213,7 +250,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		if msg.Error == nil && s.copilotOAuth2.State == copilot.OAuthStateSuccess {
 			cmds = append(
 				cmds,
-				s.saveCopilotTokenAndContinue(s.copilotOAuth2.Token(), false),
+				s.saveCredentials(s.copilotOAuth2.Token()),
 				func() tea.Msg {
 					time.Sleep(3 * time.Second)
 					return copilot.AuthenticationCompleteMsg{}
@@ -228,24 +265,11 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		s.copilotOAuth2 = u.(*copilot.OAuth2)
 		return s, cmd
 	case copilot.PollingResultMsg:
-		// Forward polling result message to copilot OAuth2 component.
+		// Forward polling result message to copilot OAuth2 component. A
+		// token is validated first and reported as ValidationCompletedMsg.
 		u, cmd := s.copilotOAuth2.Update(msg)
 		s.copilotOAuth2 = u.(*copilot.OAuth2)
-
-		// If polling succeeded with a token, save it and continue.
-		var cmds []tea.Cmd
-		cmds = append(cmds, cmd)
-		if msg.Error == nil && msg.Token != "" {
-			cmds = append(
-				cmds,
-				s.saveCopilotTokenAndContinue(s.copilotOAuth2.Token(), false),
-				func() tea.Msg {
-					time.Sleep(3 * time.Second)
-					return copilot.AuthenticationCompleteMsg{}
-				},
-			)
-		}
-		return s, tea.Batch(cmds...)
+		return s, cmd
 	case models.APIKeyStateChangeMsg:
 		u, cmd := s.apiKeyInput.Update(msg)
 		s.apiKeyInput = u.(*models.APIKeyInput)
@@ -257,7 +281,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		return s, cmd
 	case SubmitAPIKeyMsg:
 		if s.isAPIKeyValid {
-			return s, s.saveAPIKeyAndContinue(s.apiKeyValue, true)
+			return s, s.submitAPIKey()
 		}
 	case tea.KeyPressMsg:
 		switch {
@@ -280,10 +304,18 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				s.claudeOAuth2 = u.(*claude.OAuth2)
 				return s, cmd
 			}
+		case s.showCopilotOAuth2 && key.Matches(msg, s.keyMap.Cancel):
+			s.copilotOAuth2.Cancel()
+			s.showCopilotOAuth2 = false
+			s.selectedProvider = nil
+			s.step = onboardingStepProvider
+			return s, util.ReportInfo("GitHub authentication cancelled")
 		case key.Matches(msg, s.keyMap.Back):
 			if s.showClaudeAuthMethodChooser {
 				s.claudeAuthMethodChooser.SetDefaults()
 				s.showClaudeAuthMethodChooser = false
+				s.selectedProvider = nil
+				s.step = onboardingStepProvider
 				return s, nil
 			}
 			if s.showClaudeOAuth2 {
@@ -292,30 +324,28 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				s.showClaudeAuthMethodChooser = true
 				return s, nil
 			}
-			if s.showCopilotOAuth2 {
-				s.copilotOAuth2.SetDefaults()
-				s.showCopilotOAuth2 = false
-				s.selectedModel = nil
-				return s, nil
-			}
 			if s.isAPIKeyValid {
 				return s, nil
 			}
 			if s.needsAPIKey {
-				if s.selectedModel.Provider.ID == catwalk.InferenceProviderAnthropic {
+				if s.selectedProvider.ID == catwalk.InferenceProviderAnthropic {
 					s.showClaudeAuthMethodChooser = true
+				} else {
+					s.selectedProvider = nil
+					s.step = onboardingStepProvider
 				}
 				s.needsAPIKey = false
-				s.selectedModel = nil
 				s.isAPIKeyValid = false
 				s.apiKeyValue = ""
 				s.apiKeyInput.Reset()
 				return s, nil
 			}
+			if s.isOnboarding {
+				return s, s.previousStep()
+			}
 		case key.Matches(msg, s.keyMap.Select):
 			if s.showClaudeAuthMethodChooser {
-				selectedItem := s.modelList.SelectedModel()
-				if selectedItem == nil {
+				if s.selectedProvider == nil {
 					return s, nil
 				}
 
@@ -323,10 +353,8 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				case claude.AuthMethodAPIKey:
 					s.showClaudeAuthMethodChooser = false
 					s.needsAPIKey = true
-					s.selectedModel = selectedItem
-					s.apiKeyInput.SetProviderName(selectedItem.Provider.Name)
+					s.apiKeyInput.SetProviderName(s.selectedProvider.Name)
 				case claude.AuthMethodOAuth2:
-					s.selectedModel = selectedItem
 					s.showClaudeAuthMethodChooser = false
 					s.showClaudeOAuth2 = true
 				}
@@ -343,34 +371,10 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				return s, cmd2
 			}
 			if s.isAPIKeyValid {
-				return s, s.saveAPIKeyAndContinue(s.apiKeyValue, true)
+				return s, s.submitAPIKey()
 			}
 			if s.isOnboarding && !s.needsAPIKey {
-				selectedItem := s.modelList.SelectedModel()
-				if selectedItem == nil {
-					return s, nil
-				}
-				if s.isProviderConfigured(string(selectedItem.Provider.ID)) {
-					cmd := s.setPreferredModel(*selectedItem)
-					s.isOnboarding = false
-					return s, tea.Batch(cmd, util.CmdHandler(OnboardingCompleteMsg{}))
-				} else {
-					if selectedItem.Provider.ID == catwalk.InferenceProviderAnthropic {
-						s.showClaudeAuthMethodChooser = true
-						return s, nil
-					}
-					// Check for GitHub Copilot provider (uses OAuth device flow).
-					if string(selectedItem.Provider.ID) == "github-copilot" {
-						s.selectedModel = selectedItem
-						s.showCopilotOAuth2 = true
-						return s, s.copilotOAuth2.StartFlow()
-					}
-					// Provider not configured, show API key input
-					s.needsAPIKey = true
-					s.selectedModel = selectedItem
-					s.apiKeyInput.SetProviderName(selectedItem.Provider.Name)
-					return s, nil
-				}
+				return s, s.nextStep()
 			} else if s.needsAPIKey {
 				// Handle API key submission
 				s.apiKeyValue = strings.TrimSpace(s.apiKeyInput.Value())
@@ -378,13 +382,13 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 					return s, nil
 				}
 
-				provider, err := s.getProvider(s.selectedModel.Provider.ID)
+				provider, err := s.getProvider(s.selectedProvider.ID)
 				if err != nil || provider == nil {
-					return s, util.ReportError(fmt.Errorf("provider %s not found", s.selectedModel.Provider.ID))
+					return s, util.ReportError(fmt.Errorf("provider %s not found", s.selectedProvider.ID))
 				}
 				providerConfig := config.ProviderConfig{
-					ID:      string(s.selectedModel.Provider.ID),
-					Name:    s.selectedModel.Provider.Name,
+					ID:      string(s.selectedProvider.ID),
+					Name:    s.selectedProvider.Name,
 					APIKey:  s.apiKeyValue,
 					Type:    provider.Type,
 					BaseURL: provider.APIEndpoint,
@@ -436,9 +440,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				return s, cmd
 			}
 			if s.isOnboarding {
-				u, cmd := s.modelList.Update(msg)
-				s.modelList = u
-				return s, cmd
+				return s, s.updateOnboardingList(msg)
 			}
 			if s.needsProjectInit {
 				s.selectedNo = false
@@ -451,9 +453,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				return s, cmd
 			}
 			if s.isOnboarding {
-				u, cmd := s.modelList.Update(msg)
-				s.modelList = u
-				return s, cmd
+				return s, s.updateOnboardingList(msg)
 			}
 			if s.needsProjectInit {
 				s.selectedNo = true
@@ -473,9 +473,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				s.apiKeyInput = u.(*models.APIKeyInput)
 				return s, cmd
 			} else if s.isOnboarding {
-				u, cmd := s.modelList.Update(msg)
-				s.modelList = u
-				return s, cmd
+				return s, s.updateOnboardingList(msg)
 			}
 		}
 	case tea.PasteMsg:
@@ -488,9 +486,7 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			s.apiKeyInput = u.(*models.APIKeyInput)
 			return s, cmd
 		} else if s.isOnboarding {
-			var cmd tea.Cmd
-			s.modelList, cmd = s.modelList.Update(msg)
-			return s, cmd
+			return s, s.updateOnboardingList(msg)
 		}
 	case spinner.TickMsg:
 		if s.showClaudeOAuth2 {
@@ -518,51 +514,130 @@ func (s *splashCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	return s, nil
 }
 
-func (s *splashCmp) saveAPIKeyAndContinue(apiKey any, close bool) tea.Cmd {
-	if s.selectedModel == nil {
+// saveCredentials stores the API key or OAuth token of the provider chosen
+// during onboarding.
+func (s *splashCmp) saveCredentials(apiKey any) tea.Cmd {
+	if s.selectedProvider == nil {
 		return nil
 	}
 
 	cfg := config.Get()
-	err := cfg.SetProviderAPIKey(string(s.selectedModel.Provider.ID), apiKey)
+	err := cfg.SetProviderAPIKey(string(s.selectedProvider.ID), apiKey)
 	if err != nil {
-		return util.ReportError(fmt.Errorf("failed to save API key: %w", err))
+		return util.ReportError(fmt.Errorf("failed to save credentials: %w", err))
 	}
+	return nil
+}
 
-	// Reset API key state and continue with model selection
+// submitAPIKey saves the verified API key and moves on to model selection.
+func (s *splashCmp) submitAPIKey() tea.Cmd {
+	if cmd := s.saveCredentials(s.apiKeyValue); cmd != nil {
+		return cmd
+	}
 	s.needsAPIKey = false
-	cmd := s.setPreferredModel(*s.selectedModel)
-	s.isOnboarding = false
-	s.selectedModel = nil
 	s.isAPIKeyValid = false
-
-	if close {
-		return tea.Batch(cmd, util.CmdHandler(OnboardingCompleteMsg{}))
-	}
-	return cmd
+	s.apiKeyValue = ""
+	s.apiKeyInput.Reset()
+	return s.startModelSelection()
 }
 
-func (s *splashCmp) saveCopilotTokenAndContinue(token *oauth.Token, close bool) tea.Cmd {
-	if s.selectedModel == nil {
+// nextStep advances the onboarding wizard with the item selected in the
+// current step.
+func (s *splashCmp) nextStep() tea.Cmd {
+	switch s.step {
+	case onboardingStepProvider:
+		provider := s.providerList.SelectedProvider()
+		if provider == nil {
+			return nil
+		}
+		s.selectedProvider = provider
+		if s.isProviderConfigured(string(provider.ID)) {
+			return s.startModelSelection()
+		}
+		s.step = onboardingStepAuth
+		switch {
+		case provider.ID == catwalk.InferenceProviderAnthropic:
+			s.showClaudeAuthMethodChooser = true
+			return nil
+		case string(provider.ID) == copilotoauth.ProviderID:
+			// GitHub Copilot uses the OAuth device flow.
+			s.showCopilotOAuth2 = true
+			return s.copilotOAuth2.StartFlow()
+		}
+		s.needsAPIKey = true
+		s.apiKeyInput.SetProviderName(provider.Name)
 		return nil
+	case onboardingStepLargeModel:
+		selectedItem := s.modelList.SelectedModel()
+		if selectedItem == nil {
+			return nil
+		}
+		s.largeModel = selectedItem
+		s.step = onboardingStepSmallModel
+		cmds := []tea.Cmd{s.modelList.SetModelType(models.SmallModelType)}
+		if knownProvider, _ := s.getProvider(selectedItem.Provider.ID); knownProvider != nil {
+			cmds = append(cmds, s.modelList.SelectModel(string(knownProvider.ID), knownProvider.DefaultSmallModelID))
+		}
+		return tea.Sequence(cmds...)
+	case onboardingStepSmallModel:
+		selectedItem := s.modelList.SelectedModel()
+		if selectedItem == nil || s.largeModel == nil {
+			return nil
+		}
+		if cmd := s.setPreferredModels(*s.largeModel, *selectedItem); cmd != nil {
+			return cmd
+		}
+		s.isOnboarding = false
+		s.selectedProvider = nil
+		s.largeModel = nil
+		return util.CmdHandler(OnboardingCompleteMsg{})
 	}
+	return nil
+}
 
-	cfg := config.Get()
-	// For Copilot, we save the OAuth token (which contains the GitHub token in RefreshToken).
-	err := cfg.SetProviderAPIKey(string(s.selectedModel.Provider.ID), token)
-	if err != nil {
-		return util.ReportError(fmt.Errorf("failed to save Copilot token: %w", err))
+// previousStep goes back one step of the onboarding wizard. Saved
+// credentials are kept.
+func (s *splashCmp) previousStep() tea.Cmd {
+	switch s.step {
+	case onboardingStepLargeModel:
+		s.step = onboardingStepProvider
+		s.selectedProvider = nil
+		return s.modelList.SetProviderFilter("")
+	case onboardingStepSmallModel:
+		s.step = onboardingStepLargeModel
+		s.largeModel = nil
+		return s.modelList.SetModelType(models.LargeModelType)
 	}
+	return nil
+}
 
-	// Reset state and continue with model selection.
-	s.showCopilotOAuth2 = false
-	cmd := s.setPreferredModel(*s.selectedModel)
-	s.isOnboarding = false
-	s.selectedModel = nil
+// startModelSelection shows the models of the chosen provider. Once the
+// provider is configured, its models are the ones fetched when the
+// credentials were saved, e.g. the live GitHub Copilot model list.
+func (s *splashCmp) startModelSelection() tea.Cmd {
+	if s.selectedProvider == nil {
+		return nil
+	}
+	providerID := string(s.selectedProvider.ID)
+	s.step = onboardingStepLargeModel
+	if pc, ok := config.Get().Providers.Get(providerID); ok && len(pc.Models) > 0 {
+		s.modelList.SetProviderModels(providerID, pc.Models)
+	}
+	cmds := []tea.Cmd{s.modelList.SetProviderFilter(providerID)}
+	if s.selectedProvider.DefaultLargeModelID != "" {
+		cmds = append(cmds, s.modelList.SelectModel(providerID, s.selectedProvider.DefaultLargeModelID))
+	}
+	return tea.Sequence(cmds...)
+}
 
-	if close {
-		return tea.Batch(cmd, util.CmdHandler(OnboardingCompleteMsg{}))
+// updateOnboardingList forwards msg to the list of the current step.
+func (s *splashCmp) updateOnboardingList(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	if s.step == onboardingStepProvider {
+		s.providerList, cmd = s.providerList.Update(msg)
+		return cmd
 	}
+	s.modelList, cmd = s.modelList.Update(msg)
 	return cmd
 }
 
@@ -590,55 +665,25 @@ func (s *splashCmp) initializeProject() tea.Cmd {
 	return tea.Sequence(cmds...)
 }
 
-func (s *splashCmp) setPreferredModel(selectedItem models.ModelOption) tea.Cmd {
+// setPreferredModels writes the large and small models picked during
+// onboarding to the config.
+func (s *splashCmp) setPreferredModels(large, small models.ModelOption) tea.Cmd {
 	cfg := config.Get()
-	model := cfg.GetModel(string(selectedItem.Provider.ID), selectedItem.Model.ID)
-	if model == nil {
-		return util.ReportError(fmt.Errorf("model %s not found for provider %s", selectedItem.Model.ID, selectedItem.Provider.ID))
-	}
-
-	selectedModel := config.SelectedModel{
-		Model:           selectedItem.Model.ID,
-		Provider:        string(selectedItem.Provider.ID),
-		ReasoningEffort: model.DefaultReasoningEffort,
-		MaxTokens:       model.DefaultMaxTokens,
-	}
-
-	err := cfg.UpdatePreferredModel(config.SelectedModelTypeLarge, selectedModel)
-	if err != nil {
-		return util.ReportError(err)
-	}
-
-	// Now lets automatically setup the small model
-	knownProvider, err := s.getProvider(selectedItem.Provider.ID)
-	if err != nil {
-		return util.ReportError(err)
-	}
-	if knownProvider == nil {
-		// for local provider we just use the same model
-		err = cfg.UpdatePreferredModel(config.SelectedModelTypeSmall, selectedModel)
-		if err != nil {
-			return util.ReportError(err)
-		}
-	} else {
-		smallModel := knownProvider.DefaultSmallModelID
-		model := cfg.GetModel(string(selectedItem.Provider.ID), smallModel)
-		// should never happen
+	for modelType, item := range map[config.SelectedModelType]models.ModelOption{
+		config.SelectedModelTypeLarge: large,
+		config.SelectedModelTypeSmall: small,
+	} {
+		model := cfg.GetModel(string(item.Provider.ID), item.Model.ID)
 		if model == nil {
-			err = cfg.UpdatePreferredModel(config.SelectedModelTypeSmall, selectedModel)
-			if err != nil {
-				return util.ReportError(err)
-			}
-			return nil
+			return util.ReportError(fmt.Errorf("model %s not found for provider %s", item.Model.ID, item.Provider.ID))
 		}
-		smallSelectedModel := config.SelectedModel{
-			Model:           smallModel,
-			Provider:        string(selectedItem.Provider.ID),
+		selectedModel := config.SelectedModel{
+			Model:           item.Model.ID,
+			Provider:        string(item.Provider.ID),
 			ReasoningEffort: model.DefaultReasoningEffort,
 			MaxTokens:       model.DefaultMaxTokens,
 		}
-		err = cfg.UpdatePreferredModel(config.SelectedModelTypeSmall, smallSelectedModel)
-		if err != nil {
+		if err := cfg.UpdatePreferredModel(modelType, selectedModel); err != nil {
 			return util.ReportError(err)
 		}
 	}
@@ -734,14 +779,14 @@ func (s *splashCmp) View() string {
 			apiKeySelector,
 		)
 	} else if s.isOnboarding {
-		modelListView := s.modelList.View()
+		title, listView := s.onboardingStepView()
 		remainingHeight := s.height - lipgloss.Height(s.logoRendered) - (SplashScreenPaddingY * 2)
 		modelSelector := t.S().Base.AlignVertical(lipgloss.Bottom).Height(remainingHeight).Render(
 			lipgloss.JoinVertical(
 				lipgloss.Left,
-				t.S().Base.PaddingLeft(1).Foreground(t.Primary).Render("To start, let’s choose a provider and model."),
+				t.S().Base.PaddingLeft(1).Foreground(t.Primary).Render(title),
 				"",
-				modelListView,
+				listView,
 			),
 		)
 		content = lipgloss.JoinVertical(
@@ -834,6 +879,9 @@ func (s *splashCmp) Cursor() *tea.Cursor {
 		}
 	} else if s.isOnboarding {
 		cursor := s.modelList.Cursor()
+		if s.step == onboardingStepProvider {
+			cursor = s.providerList.Cursor()
+		}
 		if cursor != nil {
 			return s.moveCursor(cursor)
 		}
@@ -843,6 +891,25 @@ func (s *splashCmp) Cursor() *tea.Cursor {
 	return nil
 }
 
+// onboardingStepView returns the title and list of the current onboarding
+// step.
+func (s *splashCmp) onboardingStepView() (string, string) {
+	switch s.step {
+	case onboardingStepLargeModel:
+		return fmt.Sprintf("Step 2 of 3: pick the main model for %s.", s.providerName()), s.modelList.View()
+	case onboardingStepSmallModel:
+		return "Step 3 of 3: pick a small model for quick tasks like titles.", s.modelList.View()
+	}
+	return "Step 1 of 3: to start, let’s choose a provider.", s.providerList.View()
+}
+
+func (s *splashCmp) providerName() string {
+	if s.selectedProvider == nil {
+		return ""
+	}
+	return cmp.Or(s.selectedProvider.Name, string(s.selectedProvider.ID))
+}
+
 func (s *splashCmp) isSmallScreen() bool {
 	// Consider a screen small if either the width is less than 40 or if the
 	// height is less than 20
@@ -947,7 +1014,7 @@ func (s *splashCmp) Bindings() []key.Binding {
 	} else if s.showCopilotOAuth2 {
 		return []key.Binding{
 			s.keyMap.Select,
-			s.keyMap.Back,
+			s.keyMap.Cancel,
 		}
 	} else if s.needsAPIKey {
 		return []key.Binding{
@@ -955,11 +1022,15 @@ func (s *splashCmp) Bindings() []key.Binding {
 			s.keyMap.Back,
 		}
 	} else if s.isOnboarding {
-		return []key.Binding{
+		bindings := []key.Binding{
 			s.keyMap.Select,
 			s.keyMap.Next,
 			s.keyMap.Previous,
 		}
+		if s.step != onboardingStepProvider {
+			bindings = append(bindings, s.keyMap.Back)
+		}
+		return bindings
 	} else if s.needsProjectInit {
 		return []key.Binding{
 			s.keyMap.Select,
@@ -1074,3 +1145,7 @@ func (s *splashCmp) IsClaudeOAuthComplete() bool {
 func (s *splashCmp) IsShowingCopilotOAuth2() bool {
 	return s.showCopilotOAuth2
 }
+
+func (s *splashCmp) IsCopilotAuthInProgress() bool {
+	return s.showCopilotOAuth2 && s.copilotOAuth2.IsInProgress()
+}
@@ -1,11 +1,20 @@
 package status
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"charm.land/bubbles/v2/help"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	cmpChat "github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/x/ansi"
@@ -23,8 +32,31 @@ type statusCmp struct {
 	messageTTL time.Duration
 	help       help.Model
 	keyMap     help.KeyMap
+
+	// Copilot token state, updated from transport events.
+	copilotExpiresAt time.Time
+	copilotErr       error
+	// invalidated holds the providers whose token was rejected, until a new
+	// one is saved or refreshed.
+	invalidated map[string]error
+
+	// session is the selected session, used to show how full the context
+	// window is.
+	session session.Session
+
+	// permissions tells whether YOLO mode is on for the selected session.
+	permissions permission.Service
 }
 
+const (
+	// contextMeterWidth is the number of cells of the context window gauge.
+	contextMeterWidth = 8
+	// contextWarnPercent and contextCriticalPercent are the context window
+	// usages at which the gauge turns yellow and red.
+	contextWarnPercent     = 70
+	contextCriticalPercent = 90
+)
+
 // clearMessageCmd is a command that clears status messages after a timeout
 func (m *statusCmp) clearMessageCmd(ttl time.Duration) tea.Cmd {
 	return tea.Tick(ttl, func(time.Time) tea.Msg {
@@ -53,19 +85,152 @@ func (m *statusCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		return m, m.clearMessageCmd(ttl)
 	case util.ClearStatusMsg:
 		m.info = util.InfoMsg{}
+	case cmpChat.SessionSelectedMsg:
+		m.session = msg
+	case cmpChat.SessionClearedMsg:
+		m.session = session.Session{}
+	case pubsub.Event[session.Session]:
+		if msg.Type == pubsub.UpdatedEvent && msg.Payload.ID == m.session.ID {
+			m.session = msg.Payload
+		}
+	case pubsub.Event[copilot.Event]:
+		switch msg.Payload.Type {
+		case copilot.EventTokenRefreshed:
+			m.copilotExpiresAt = msg.Payload.ExpiresAt
+			m.copilotErr = nil
+		case copilot.EventAuthFailed:
+			m.copilotErr = msg.Payload.Error
+		}
+	case pubsub.Event[oauth.TokenEvent]:
+		switch msg.Payload.Type {
+		case oauth.TokenInvalidated:
+			if m.invalidated == nil {
+				m.invalidated = make(map[string]error)
+			}
+			m.invalidated[msg.Payload.ProviderID] = msg.Payload.Error
+		case oauth.TokenSaved, oauth.TokenRefreshed:
+			delete(m.invalidated, msg.Payload.ProviderID)
+		}
 	}
 	return m, nil
 }
 
 func (m *statusCmp) View() string {
 	t := styles.CurrentTheme()
-	status := t.S().Base.Padding(0, 1, 1, 1).Render(m.help.View(m.keyMap))
 	if m.info.Msg != "" {
-		status = m.infoMsg()
+		return m.infoMsg()
+	}
+	auth := m.authStatus()
+	for _, part := range []string{m.contextMeter(), m.yoloIndicator()} {
+		if part == "" {
+			continue
+		}
+		if auth != "" {
+			auth = part + t.S().Subtle.Render(" · ") + auth
+		} else {
+			auth = part
+		}
+	}
+	m.help.SetWidth(m.width - 2 - lipgloss.Width(auth))
+	helpView := m.help.View(m.keyMap)
+	if auth != "" {
+		gap := max(1, m.width-2-lipgloss.Width(helpView)-lipgloss.Width(auth))
+		helpView = lipgloss.JoinHorizontal(lipgloss.Top, helpView, strings.Repeat(" ", gap), auth)
+	}
+	return t.S().Base.Padding(0, 1, 1, 1).Render(helpView)
+}
+
+// authStatus describes how the active provider is authenticated, and for
+// Copilot, how long the short-lived token remains valid.
+func (m *statusCmp) authStatus() string {
+	cfg := config.Get()
+	if cfg == nil || m.help.ShowAll {
+		return ""
+	}
+	agentCfg, ok := cfg.Agents[config.AgentCoder]
+	if !ok {
+		return ""
+	}
+	provider := cfg.GetProviderForModel(agentCfg.Model)
+	if provider == nil {
+		return ""
+	}
+
+	t := styles.CurrentTheme()
+	method := "API key"
+	if provider.OAuthToken != nil {
+		method = "OAuth"
+	}
+	parts := []string{provider.Name, method}
+
+	var warning string
+	if _, ok := m.invalidated[provider.ID]; ok {
+		warning = "login expired"
+	} else if provider.ID == copilot.ProviderID {
+		expiresAt := m.copilotExpiresAt
+		if expiresAt.IsZero() && provider.OAuthToken != nil && provider.OAuthToken.CopilotExpiresAt > 0 {
+			expiresAt = time.Unix(provider.OAuthToken.CopilotExpiresAt, 0)
+		}
+		switch {
+		case m.copilotErr != nil:
+			warning = "auth failed"
+		case !expiresAt.IsZero():
+			parts = append(parts, tokenExpiry(expiresAt))
+		}
+	}
+
+	status := t.S().Subtle.Render(strings.Join(parts, " · "))
+	if warning != "" {
+		status += t.S().Subtle.Render(" · ") + t.S().Error.Render(warning)
 	}
 	return status
 }
 
+// contextMeter renders a gauge of the tokens used by the selected session
+// against the context window of the current model. It turns yellow and then
+// red as the conversation approaches the limit, hinting it's time to compact.
+func (m *statusCmp) contextMeter() string {
+	cfg := config.Get()
+	if cfg == nil || m.help.ShowAll || m.session.ID == "" {
+		return ""
+	}
+	agentCfg, ok := cfg.Agents[config.AgentCoder]
+	if !ok {
+		return ""
+	}
+	model := cfg.GetModelByType(agentCfg.Model)
+	if model == nil || model.ContextWindow <= 0 {
+		return ""
+	}
+
+	t := styles.CurrentTheme()
+	used := m.session.PromptTokens + m.session.CompletionTokens
+	percentage := min(100, int(used*100/model.ContextWindow))
+	filled := min(contextMeterWidth, percentage*contextMeterWidth/100)
+
+	style := t.S().Muted
+	switch {
+	case percentage >= contextCriticalPercent:
+		style = t.S().Error
+	case percentage >= contextWarnPercent:
+		style = t.S().Warning
+	}
+	return t.S().Subtle.Render("ctx ") +
+		style.Render(strings.Repeat("▰", filled)) +
+		t.S().Subtle.Render(strings.Repeat("▱", contextMeterWidth-filled)) +
+		style.Render(fmt.Sprintf(" %d%%", percentage))
+}
+
+// yoloIndicator warns that permission requests of the selected session are
+// granted without asking.
+func (m *statusCmp) yoloIndicator() string {
+	if m.permissions == nil || !m.permissions.SessionSkipRequests(m.session.ID) {
+		return ""
+	}
+	t := styles.CurrentTheme()
+	return t.S().Base.Foreground(t.BgOverlay).Background(t.Warning).Bold(true).Padding(0, 1).Render("YOLO")
+}
+
 func (m *statusCmp) infoMsg() string {
 	t := styles.CurrentTheme()
 	message := ""
@@ -94,6 +259,14 @@ func (m *statusCmp) infoMsg() string {
 	return ansi.Truncate(infoType+message, m.width, "…")
 }
 
+func tokenExpiry(expiresAt time.Time) string {
+	remaining := time.Until(expiresAt).Round(time.Minute)
+	if remaining <= 0 {
+		return "token expired"
+	}
+	return fmt.Sprintf("token expires in %s", strings.TrimSuffix(remaining.String(), "0s"))
+}
+
 func (m *statusCmp) ToggleFullHelp() {
 	m.help.ShowAll = !m.help.ShowAll
 }
@@ -102,12 +275,13 @@ func (m *statusCmp) SetKeyMap(keyMap help.KeyMap) {
 	m.keyMap = keyMap
 }
 
-func NewStatusCmp() StatusCmp {
+func NewStatusCmp(permissions permission.Service) StatusCmp {
 	t := styles.CurrentTheme()
 	help := help.New()
 	help.Styles = t.S().Help
 	return &statusCmp{
-		messageTTL: 5 * time.Second,
-		help:       help,
+		messageTTL:  5 * time.Second,
+		help:        help,
+		permissions: permissions,
 	}
 }
@@ -0,0 +1,10 @@
+package dialogs
+
+import "github.com/charmbracelet/crush/internal/config"
+
+// Accessible returns whether dialogs should render as plain text, without
+// spinners, colors or borders, for screen readers and dumb terminals.
+func Accessible() bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.Options != nil && cfg.Options.TUI != nil && cfg.Options.TUI.Accessible
+}
@@ -0,0 +1,212 @@
+package accounts
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	AccountsDialogID dialogs.DialogID = "accounts"
+
+	defaultWidth = 60
+)
+
+// AccountSwitchedMsg is sent after the active account of a provider changed.
+type AccountSwitchedMsg struct {
+	ProviderID   string
+	ProviderName string
+	Account      string
+}
+
+// AccountsDialog lets the user switch the active account of a provider.
+type AccountsDialog interface {
+	dialogs.DialogModel
+}
+
+type accountItem struct {
+	providerID   string
+	providerName string
+	account      string
+	active       bool
+}
+
+type accountsDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	items  []accountItem
+	cursor int
+
+	keyMap KeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewAccountsDialog creates a dialog listing the stored accounts of every
+// provider.
+func NewAccountsDialog() AccountsDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	d := &accountsDialogCmp{
+		width:      defaultWidth,
+		items:      loadAccounts(),
+		keyMap:     DefaultKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+	if idx := slices.IndexFunc(d.items, func(i accountItem) bool { return i.active }); idx != -1 {
+		d.cursor = idx
+	}
+	return d
+}
+
+func loadAccounts() []accountItem {
+	var items []accountItem
+	for _, pc := range config.Get().Providers.Seq2() {
+		for _, account := range pc.Accounts {
+			items = append(items, accountItem{
+				providerID:   pc.ID,
+				providerName: cmp.Or(pc.Name, pc.ID),
+				account:      account.Name,
+				active:       account.Name == pc.ActiveAccount,
+			})
+		}
+	}
+	slices.SortFunc(items, func(a, b accountItem) int {
+		return cmp.Or(
+			cmp.Compare(a.providerName, b.providerName),
+			cmp.Compare(a.account, b.account),
+		)
+	})
+	return items
+}
+
+func (a *accountsDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (a *accountsDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		a.wWidth = msg.Width
+		a.wHeight = msg.Height
+		a.width = min(defaultWidth, a.wWidth-4)
+		a.help.SetWidth(a.width - 2)
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, a.keyMap.Close):
+			return a, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case len(a.items) == 0:
+			return a, nil
+		case key.Matches(msg, a.keyMap.Next):
+			a.cursor = (a.cursor + 1) % len(a.items)
+		case key.Matches(msg, a.keyMap.Previous):
+			a.cursor = (a.cursor - 1 + len(a.items)) % len(a.items)
+		case key.Matches(msg, a.keyMap.Select):
+			item := a.items[a.cursor]
+			if item.active {
+				return a, util.CmdHandler(dialogs.CloseDialogMsg{})
+			}
+			if err := config.Get().SwitchProviderAccount(item.providerID, item.account); err != nil {
+				return a, util.ReportError(err)
+			}
+			return a, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(AccountSwitchedMsg{
+					ProviderID:   item.providerID,
+					ProviderName: item.providerName,
+					Account:      item.account,
+				}),
+			)
+		}
+	}
+	return a, nil
+}
+
+func (a *accountsDialogCmp) View() string {
+	if a.accessible {
+		return a.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+
+	var body string
+	if len(a.items) == 0 {
+		body = t.S().Muted.PaddingLeft(1).Render("No stored accounts. Log in to a provider to add one.")
+	} else {
+		lines := make([]string, 0, len(a.items))
+		for i, item := range a.items {
+			label := fmt.Sprintf("%s · %s", item.providerName, item.account)
+			if item.active {
+				label += " (active)"
+			}
+			if i == a.cursor {
+				lines = append(lines, t.S().TextSelected.Width(a.width-2).Padding(0, 1).Render(label))
+				continue
+			}
+			lines = append(lines, t.S().Text.Padding(0, 1).Render(label))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Account", a.width-4)),
+		body,
+		"",
+		t.S().Base.Width(a.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(a.help.View(a.keyMap)),
+	)
+	return t.S().Base.
+		Width(a.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// accessibleView renders the accounts as plain text lines for screen
+// readers, marking the selected one with a leading ">".
+func (a *accountsDialogCmp) accessibleView() string {
+	lines := []string{"Switch Account"}
+	if len(a.items) == 0 {
+		lines = append(lines, "No stored accounts. Log in to a provider to add one.")
+	}
+	for i, item := range a.items {
+		prefix := "  "
+		if i == a.cursor {
+			prefix = "> "
+		}
+		label := fmt.Sprintf("%s%s, %s", prefix, item.providerName, item.account)
+		if item.active {
+			label += " (active)"
+		}
+		lines = append(lines, label)
+	}
+	lines = append(lines, "Press enter to switch, or esc to close.")
+	return lipgloss.NewStyle().Width(a.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (a *accountsDialogCmp) Position() (int, int) {
+	row := a.wHeight/4 - 2 // just a bit above the center
+	col := a.wWidth/2 - a.width/2
+	return row, col
+}
+
+func (a *accountsDialogCmp) ID() dialogs.DialogID {
+	return AccountsDialogID
+}
@@ -0,0 +1,265 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	AgentsDialogID dialogs.DialogID = "background_agents"
+
+	defaultWidth = 70
+	// maxResultLines bounds the height of the result shown below the list.
+	maxResultLines = 15
+)
+
+// AgentsDialog lists the background agents, showing their results and
+// letting the user stop the running ones.
+type AgentsDialog interface {
+	dialogs.DialogModel
+}
+
+// TaskManager gives access to the background agents.
+type TaskManager interface {
+	BackgroundTasks() []agent.BackgroundTask
+	CancelBackgroundTask(id string) bool
+}
+
+type agentsDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	manager    TaskManager
+	tasks      []agent.BackgroundTask
+	cursor     int
+	showResult bool
+
+	keyMap KeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewAgentsDialog creates a dialog listing the background agents of manager.
+func NewAgentsDialog(manager TaskManager) AgentsDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &agentsDialogCmp{
+		width:      defaultWidth,
+		manager:    manager,
+		tasks:      manager.BackgroundTasks(),
+		keyMap:     DefaultKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (a *agentsDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (a *agentsDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		a.wWidth = msg.Width
+		a.wHeight = msg.Height
+		a.width = min(defaultWidth, a.wWidth-4)
+		a.help.SetWidth(a.width - 2)
+	case pubsub.Event[agent.BackgroundTask]:
+		a.reload()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, a.keyMap.Close):
+			return a, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case len(a.tasks) == 0:
+			return a, nil
+		case key.Matches(msg, a.keyMap.Next):
+			a.cursor = (a.cursor + 1) % len(a.tasks)
+			a.showResult = false
+		case key.Matches(msg, a.keyMap.Previous):
+			a.cursor = (a.cursor - 1 + len(a.tasks)) % len(a.tasks)
+			a.showResult = false
+		case key.Matches(msg, a.keyMap.Select):
+			a.showResult = !a.showResult
+		case key.Matches(msg, a.keyMap.Cancel):
+			task := a.tasks[a.cursor]
+			if task.Done() {
+				return a, nil
+			}
+			if a.manager.CancelBackgroundTask(task.ID) {
+				return a, util.ReportInfo(fmt.Sprintf("Stopping background agent %s", task.ID))
+			}
+		}
+	}
+	return a, nil
+}
+
+// reload refreshes the tasks, keeping the cursor on the selected one.
+func (a *agentsDialogCmp) reload() {
+	var selected string
+	if a.cursor < len(a.tasks) {
+		selected = a.tasks[a.cursor].ID
+	}
+	a.tasks = a.manager.BackgroundTasks()
+	a.cursor = 0
+	for i, task := range a.tasks {
+		if task.ID == selected {
+			a.cursor = i
+			break
+		}
+	}
+}
+
+func (a *agentsDialogCmp) View() string {
+	if a.accessible {
+		return a.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+
+	var body string
+	if len(a.tasks) == 0 {
+		body = t.S().Muted.PaddingLeft(1).Render("No background agents. Ask the agent to run a task in the background to start one.")
+	} else {
+		lines := make([]string, 0, len(a.tasks))
+		for i, task := range a.tasks {
+			label := fmt.Sprintf("%s %-9s %s", task.ID, task.Status, oneLine(task.Prompt))
+			if i == a.cursor {
+				lines = append(lines, t.S().TextSelected.Width(a.width-2).Padding(0, 1).Render(a.fit(label)))
+				continue
+			}
+			style := t.S().Text
+			switch task.Status {
+			case agent.BackgroundTaskRunning:
+				style = style.Foreground(t.Warning)
+			case agent.BackgroundTaskFailed:
+				style = style.Foreground(t.Error)
+			case agent.BackgroundTaskCancelled:
+				style = t.S().Muted
+			}
+			lines = append(lines, style.Padding(0, 1).Render(a.fit(label)))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
+		if a.showResult {
+			body = lipgloss.JoinVertical(
+				lipgloss.Left,
+				body,
+				"",
+				t.S().Base.Width(a.width-4).PaddingLeft(1).Render(a.resultView()),
+			)
+		}
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Background Agents", a.width-4)),
+		body,
+		"",
+		t.S().Base.Width(a.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(a.help.View(a.keyMap)),
+	)
+	return t.S().Base.
+		Width(a.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// resultView renders the details and result of the selected task.
+func (a *agentsDialogCmp) resultView() string {
+	t := styles.CurrentTheme()
+	task := a.tasks[a.cursor]
+	details := []string{
+		t.S().Muted.Render(fmt.Sprintf("Model: %s · %s", task.Model, taskDuration(task))),
+	}
+	switch task.Status {
+	case agent.BackgroundTaskRunning:
+		details = append(details, t.S().Muted.Render("Still running."))
+	case agent.BackgroundTaskCancelled:
+		details = append(details, t.S().Muted.Render("Stopped before finishing."))
+	case agent.BackgroundTaskFailed:
+		details = append(details, t.S().Base.Foreground(t.Error).Render(task.Error))
+	default:
+		details = append(details, t.S().Text.Width(a.width-4).Render(truncateLines(task.Result, maxResultLines)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, details...)
+}
+
+// accessibleView renders the tasks as plain text lines for screen readers,
+// marking the selected one with a leading ">".
+func (a *agentsDialogCmp) accessibleView() string {
+	lines := []string{"Background Agents"}
+	if len(a.tasks) == 0 {
+		lines = append(lines, "No background agents.")
+	}
+	for i, task := range a.tasks {
+		prefix := "  "
+		if i == a.cursor {
+			prefix = "> "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s, %s: %s", prefix, task.ID, task.Status, oneLine(task.Prompt)))
+	}
+	if a.showResult && len(a.tasks) > 0 {
+		task := a.tasks[a.cursor]
+		switch task.Status {
+		case agent.BackgroundTaskFailed:
+			lines = append(lines, "Error: "+task.Error)
+		case agent.BackgroundTaskCompleted:
+			lines = append(lines, "Result:", truncateLines(task.Result, maxResultLines))
+		}
+	}
+	lines = append(lines, "Press enter to show the result, x to stop an agent, or esc to close.")
+	return lipgloss.NewStyle().Width(a.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (a *agentsDialogCmp) fit(s string) string {
+	w := a.width - 4
+	if lipgloss.Width(s) <= w {
+		return s
+	}
+	return string([]rune(s)[:max(0, w-1)]) + "…"
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncateLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n… (%d more lines, ask the agent for the full result)", len(lines)-n)
+}
+
+func taskDuration(task agent.BackgroundTask) string {
+	end := task.FinishedAt
+	if !task.Done() {
+		end = time.Now()
+	}
+	return end.Sub(task.StartedAt).Round(time.Second).String()
+}
+
+func (a *agentsDialogCmp) Position() (int, int) {
+	row := a.wHeight/4 - 2 // just a bit above the center
+	col := a.wWidth/2 - a.width/2
+	return row, col
+}
+
+func (a *agentsDialogCmp) ID() dialogs.DialogID {
+	return AgentsDialogID
+}
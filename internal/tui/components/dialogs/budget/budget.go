@@ -0,0 +1,150 @@
+package budget
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	question                        = "Send the prompt anyway?"
+	BudgetDialogID dialogs.DialogID = "budget"
+
+	maxWidth = 60
+)
+
+// BudgetDialog asks whether to send a prompt over budget.
+type BudgetDialog interface {
+	dialogs.DialogModel
+}
+
+type budgetDialogCmp struct {
+	wWidth  int
+	wHeight int
+
+	reason     string
+	send       tea.Cmd
+	selectedNo bool // true if "No" button is selected
+	keymap     KeyMap
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewBudgetDialog creates a dialog telling why the budget stops a prompt,
+// running send when the user wants it sent anyway.
+func NewBudgetDialog(reason string, send tea.Cmd) BudgetDialog {
+	return &budgetDialogCmp{
+		reason:     reason,
+		send:       send,
+		selectedNo: true, // Default to "No", it costs money
+		keymap:     DefaultKeymap(),
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (b *budgetDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles keyboard input for the budget dialog.
+func (b *budgetDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.wWidth = msg.Width
+		b.wHeight = msg.Height
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, b.keymap.LeftRight, b.keymap.Tab):
+			b.selectedNo = !b.selectedNo
+			return b, nil
+		case key.Matches(msg, b.keymap.EnterSpace):
+			if !b.selectedNo {
+				return b, tea.Sequence(util.CmdHandler(dialogs.CloseDialogMsg{}), b.send)
+			}
+			return b, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, b.keymap.Yes):
+			return b, tea.Sequence(util.CmdHandler(dialogs.CloseDialogMsg{}), b.send)
+		case key.Matches(msg, b.keymap.No, b.keymap.Close):
+			return b, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return b, nil
+}
+
+// message returns the reason the prompt was stopped, as a sentence.
+func (b *budgetDialogCmp) message() string {
+	if b.reason == "" {
+		return "The budget is spent."
+	}
+	return strings.ToUpper(b.reason[:1]) + b.reason[1:] + "."
+}
+
+func (b *budgetDialogCmp) width() int {
+	return min(maxWidth, max(lipgloss.Width(b.message()), lipgloss.Width(question)))
+}
+
+// View renders the budget dialog with Yes/No buttons.
+func (b *budgetDialogCmp) View() string {
+	if b.accessible {
+		return lipgloss.NewStyle().Width(b.width()).Render(
+			b.message() + "\n" + question + " Press y to send it, or n to cancel.",
+		)
+	}
+
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base
+	yesStyle := t.S().Text
+	noStyle := yesStyle
+
+	if b.selectedNo {
+		noStyle = noStyle.Foreground(t.White).Background(t.Secondary)
+		yesStyle = yesStyle.Background(t.BgSubtle)
+	} else {
+		yesStyle = yesStyle.Foreground(t.White).Background(t.Secondary)
+		noStyle = noStyle.Background(t.BgSubtle)
+	}
+
+	const horizontalPadding = 3
+	yesButton := yesStyle.PaddingLeft(horizontalPadding).Underline(true).Render("Y") +
+		yesStyle.PaddingRight(horizontalPadding).Render("es")
+	noButton := noStyle.PaddingLeft(horizontalPadding).Underline(true).Render("N") +
+		noStyle.PaddingRight(horizontalPadding).Render("o")
+
+	width := b.width()
+	buttons := baseStyle.Width(width).Align(lipgloss.Right).Render(
+		lipgloss.JoinHorizontal(lipgloss.Center, yesButton, "  ", noButton),
+	)
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			t.S().Warning.Width(width).Render(b.message()),
+			"",
+			question,
+			"",
+			buttons,
+		),
+	)
+
+	return baseStyle.
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (b *budgetDialogCmp) Position() (int, int) {
+	row := b.wHeight/2 - 9/2
+	col := b.wWidth/2 - (b.width()+6)/2
+	return row, col
+}
+
+func (b *budgetDialogCmp) ID() dialogs.DialogID {
+	return BudgetDialogID
+}
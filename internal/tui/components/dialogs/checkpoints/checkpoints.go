@@ -0,0 +1,210 @@
+package checkpoints
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/checkpoint"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	CheckpointsDialogID dialogs.DialogID = "checkpoints"
+
+	defaultWidth = 70
+	// maxVisible bounds the number of checkpoints listed at once.
+	maxVisible = 12
+)
+
+// CheckpointsDialog lists the checkpoints of a session, restoring the
+// selected one.
+type CheckpointsDialog interface {
+	dialogs.DialogModel
+}
+
+type checkpointsLoadedMsg struct {
+	checkpoints []checkpoint.Checkpoint
+	err         error
+}
+
+type checkpointsDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	service     checkpoint.Service
+	sessionID   string
+	checkpoints []checkpoint.Checkpoint
+	loaded      bool
+	cursor      int
+	restoring   bool
+
+	keyMap KeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewCheckpointsDialog creates a dialog listing the checkpoints of the
+// session.
+func NewCheckpointsDialog(service checkpoint.Service, sessionID string) CheckpointsDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &checkpointsDialogCmp{
+		width:      defaultWidth,
+		service:    service,
+		sessionID:  sessionID,
+		keyMap:     DefaultKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (c *checkpointsDialogCmp) Init() tea.Cmd {
+	return func() tea.Msg {
+		checkpoints, err := c.service.List(context.Background(), c.sessionID)
+		return checkpointsLoadedMsg{checkpoints: checkpoints, err: err}
+	}
+}
+
+func (c *checkpointsDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		c.wWidth = msg.Width
+		c.wHeight = msg.Height
+		c.width = min(defaultWidth, c.wWidth-4)
+		c.help.SetWidth(c.width - 2)
+	case checkpointsLoadedMsg:
+		if msg.err != nil {
+			return c, tea.Batch(util.CmdHandler(dialogs.CloseDialogMsg{}), util.ReportError(msg.err))
+		}
+		c.checkpoints = msg.checkpoints
+		c.loaded = true
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, c.keyMap.Close):
+			return c, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case len(c.checkpoints) == 0 || c.restoring:
+			return c, nil
+		case key.Matches(msg, c.keyMap.Next):
+			c.cursor = (c.cursor + 1) % len(c.checkpoints)
+		case key.Matches(msg, c.keyMap.Previous):
+			c.cursor = (c.cursor - 1 + len(c.checkpoints)) % len(c.checkpoints)
+		case key.Matches(msg, c.keyMap.Select):
+			c.restoring = true
+			selected := c.checkpoints[c.cursor]
+			return c, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				func() tea.Msg {
+					if err := c.service.Restore(context.Background(), c.sessionID, selected.ID); err != nil {
+						return util.ReportError(err)()
+					}
+					return util.ReportInfo(fmt.Sprintf("Restored checkpoint %s, the conversation is unchanged", selected.ShortID()))()
+				},
+			)
+		}
+	}
+	return c, nil
+}
+
+// visible returns the range of checkpoints shown, keeping the cursor in it.
+func (c *checkpointsDialogCmp) visible() (int, int) {
+	start := max(0, min(c.cursor-maxVisible/2, len(c.checkpoints)-maxVisible))
+	return start, min(len(c.checkpoints), start+maxVisible)
+}
+
+func (c *checkpointsDialogCmp) View() string {
+	if c.accessible {
+		return c.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+
+	var body string
+	switch {
+	case !c.loaded:
+		body = t.S().Muted.PaddingLeft(1).Render("Loading checkpoints…")
+	case len(c.checkpoints) == 0:
+		body = t.S().Muted.PaddingLeft(1).Render("No checkpoints yet. One is taken before the agent handles each prompt.")
+	default:
+		start, end := c.visible()
+		lines := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			label := c.label(c.checkpoints[i])
+			if i == c.cursor {
+				lines = append(lines, t.S().TextSelected.Width(c.width-2).Padding(0, 1).Render(c.fit(label)))
+				continue
+			}
+			lines = append(lines, t.S().Text.Padding(0, 1).Render(c.fit(label)))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Restore Checkpoint", c.width-4)),
+		body,
+		"",
+		t.S().Base.Width(c.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(c.help.View(c.keyMap)),
+	)
+	return t.S().Base.
+		Width(c.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (c *checkpointsDialogCmp) label(cp checkpoint.Checkpoint) string {
+	return fmt.Sprintf("%s %s %s", cp.ShortID(), time.Unix(cp.CreatedAt, 0).Format("Jan 2 15:04"), cp.Description)
+}
+
+// accessibleView renders the checkpoints as plain text lines for screen
+// readers, marking the selected one with a leading ">".
+func (c *checkpointsDialogCmp) accessibleView() string {
+	lines := []string{"Restore Checkpoint"}
+	switch {
+	case !c.loaded:
+		lines = append(lines, "Loading checkpoints.")
+	case len(c.checkpoints) == 0:
+		lines = append(lines, "No checkpoints yet.")
+	}
+	start, end := c.visible()
+	for i := start; i < end; i++ {
+		prefix := "  "
+		if i == c.cursor {
+			prefix = "> "
+		}
+		lines = append(lines, prefix+c.label(c.checkpoints[i]))
+	}
+	lines = append(lines, "Press enter to restore the files to a checkpoint, or esc to close.")
+	return lipgloss.NewStyle().Width(c.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (c *checkpointsDialogCmp) fit(s string) string {
+	w := c.width - 4
+	if lipgloss.Width(s) <= w {
+		return s
+	}
+	return string([]rune(s)[:max(0, w-1)]) + "…"
+}
+
+func (c *checkpointsDialogCmp) Position() (int, int) {
+	row := c.wHeight/4 - 2 // just a bit above the center
+	col := c.wWidth/2 - c.width/2
+	return row, col
+}
+
+func (c *checkpointsDialogCmp) ID() dialogs.DialogID {
+	return CheckpointsDialogID
+}
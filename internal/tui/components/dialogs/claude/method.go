@@ -101,6 +101,11 @@ func (a *AuthMethodChooser) SetWidth(w int) {
 	a.width = w
 }
 
+// SetOnboarding styles the chooser for the first-run wizard.
+func (a *AuthMethodChooser) SetOnboarding(onboarding bool) {
+	a.isOnboarding = onboarding
+}
+
 func (a *AuthMethodChooser) ToggleChoice() {
 	switch a.State {
 	case AuthMethodAPIKey:
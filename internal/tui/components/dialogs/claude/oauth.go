@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 
 	"charm.land/bubbles/v2/spinner"
 	"charm.land/bubbles/v2/textinput"
@@ -11,6 +12,7 @@ import (
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/claude"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/pkg/browser"
@@ -45,6 +47,8 @@ type OAuth2 struct {
 	ValidationState OAuthValidationState
 	width           int
 	isOnboarding    bool
+	// accessible renders plain text without a spinner.
+	accessible bool
 
 	// URL page
 	err       error
@@ -61,7 +65,8 @@ type OAuth2 struct {
 
 func NewOAuth2() *OAuth2 {
 	return &OAuth2{
-		State: OAuthStateURL,
+		State:      OAuthStateURL,
+		accessible: dialogs.Accessible(),
 	}
 }
 
@@ -117,7 +122,7 @@ func (o *OAuth2) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		o.updatePrompt()
 	}
 
-	if o.ValidationState == OAuthValidationStateVerifying {
+	if o.ValidationState == OAuthValidationStateVerifying && !o.accessible {
 		var cmd tea.Cmd
 		o.spinner, cmd = o.spinner.Update(msg)
 		cmds = append(cmds, cmd)
@@ -143,7 +148,10 @@ func (o *OAuth2) ValidationConfirm() (util.Model, tea.Cmd) {
 	case o.ValidationState == OAuthValidationStateNone || o.ValidationState == OAuthValidationStateError:
 		o.CodeInput.Blur()
 		o.ValidationState = OAuthValidationStateVerifying
-		cmds = append(cmds, o.spinner.Tick, o.validateCode)
+		cmds = append(cmds, o.validateCode)
+		if !o.accessible {
+			cmds = append(cmds, o.spinner.Tick)
+		}
 	case o.ValidationState == OAuthValidationStateValid:
 		cmds = append(cmds, func() tea.Msg { return AuthenticationCompleteMsg{} })
 	}
@@ -153,6 +161,10 @@ func (o *OAuth2) ValidationConfirm() (util.Model, tea.Cmd) {
 }
 
 func (o *OAuth2) View() string {
+	if o.accessible {
+		return o.accessibleView()
+	}
+
 	t := styles.CurrentTheme()
 
 	whiteStyle := lipgloss.NewStyle().Foreground(t.White)
@@ -213,6 +225,34 @@ func (o *OAuth2) View() string {
 	}
 }
 
+// accessibleView renders the flow as plain text lines for screen readers.
+func (o *OAuth2) accessibleView() string {
+	var lines []string
+	switch {
+	case o.err != nil:
+		lines = append(lines, "Error: "+o.err.Error())
+	case o.State == OAuthStateURL:
+		lines = append(lines, "Press enter to open the following URL in your browser:", o.URL)
+	case o.State == OAuthStateCode:
+		switch o.ValidationState {
+		case OAuthValidationStateNone:
+			lines = append(lines, "Enter the code you received.")
+		case OAuthValidationStateVerifying:
+			lines = append(lines, "Verifying code.")
+		case OAuthValidationStateValid:
+			lines = append(lines, "Code validated. Press enter to continue.")
+		case OAuthValidationStateError:
+			lines = append(lines, "Invalid code. Try again.")
+		}
+		lines = append(lines, o.CodeInput.View())
+	}
+	style := lipgloss.NewStyle().Margin(0, 1)
+	if o.width > 2 {
+		style = style.Width(o.width - 2)
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
+
 func (o *OAuth2) SetDefaults() {
 	o.State = OAuthStateURL
 	o.ValidationState = OAuthValidationStateNone
@@ -225,6 +265,16 @@ func (o *OAuth2) SetWidth(w int) {
 	o.CodeInput.SetWidth(w - 4)
 }
 
+// SetOnboarding styles the flow for the first-run wizard.
+func (o *OAuth2) SetOnboarding(onboarding bool) {
+	o.isOnboarding = onboarding
+}
+
+// SetAccessible toggles the plain text rendering for screen readers.
+func (o *OAuth2) SetAccessible(accessible bool) {
+	o.accessible = accessible
+}
+
 func (o *OAuth2) SetError(err error) {
 	o.err = err
 }
@@ -242,6 +292,10 @@ func (o *OAuth2) updatePrompt() {
 	case OAuthValidationStateNone:
 		o.CodeInput.Prompt = "> "
 	case OAuthValidationStateVerifying:
+		if o.accessible {
+			o.CodeInput.Prompt = "> "
+			break
+		}
 		o.CodeInput.Prompt = o.spinner.View() + " "
 	case OAuthValidationStateValid:
 		o.CodeInput.Prompt = styles.CheckIcon + " "
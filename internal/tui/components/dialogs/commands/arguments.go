@@ -59,6 +59,8 @@ type commandArgumentsDialogCmp struct {
 type Argument struct {
 	Name, Title, Description string
 	Required                 bool
+	// Default is the value the input starts with.
+	Default string
 }
 
 func NewCommandArgumentsDialog(
@@ -75,6 +77,7 @@ func NewCommandArgumentsDialog(
 		ti.SetWidth(40)
 		ti.SetVirtualCursor(false)
 		ti.Prompt = ""
+		ti.SetValue(arg.Default)
 
 		ti.SetStyles(t.S().TextInput)
 		// Only focus the first input initially
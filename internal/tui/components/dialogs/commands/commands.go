@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"slices"
@@ -10,19 +11,23 @@ import (
 	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
-	"github.com/charmbracelet/catwalk/pkg/catwalk"
 
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
 	"github.com/charmbracelet/crush/internal/tui/exp/list"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/crush/internal/worktree"
 )
 
 const (
@@ -33,10 +38,13 @@ const (
 
 type commandType uint
 
-func (c commandType) String() string { return []string{"System", "User", "MCP"}[c] }
+func (c commandType) String() string { return []string{"All", "System", "User", "MCP"}[c] }
 
 const (
-	SystemCommands commandType = iota
+	// AllCommands lists the commands of the other types, with the sessions
+	// to switch to and the models to switch the large model to.
+	AllCommands commandType = iota
+	SystemCommands
 	UserCommands
 	MCPPrompts
 )
@@ -65,30 +73,64 @@ type commandDialogCmp struct {
 	commandList  listModel
 	keyMap       CommandsDialogKeyMap
 	help         help.Model
-	selected     commandType           // Selected SystemCommands, UserCommands, or MCPPrompts
+	selected     commandType           // Selected AllCommands, SystemCommands, UserCommands, or MCPPrompts
 	userCommands []Command             // User-defined commands
 	mcpPrompts   *csync.Slice[Command] // MCP prompts
 	sessionID    string                // Current session ID
+	sessions     []session.Session     // Sessions to switch to
 }
 
 type (
 	SwitchSessionsMsg      struct{}
 	NewSessionsMsg         struct{}
 	SwitchModelMsg         struct{}
+	OpenLoginDialogMsg     struct{}
+	OpenAccountsDialogMsg  struct{}
+	OpenCopilotUsageMsg    struct{}
+	OpenAgentsDialogMsg    struct{}
+	OpenMCPResourcesMsg    struct{}
 	QuitMsg                struct{}
 	OpenFilePickerMsg      struct{}
 	ToggleHelpMsg          struct{}
 	ToggleCompactModeMsg   struct{}
+	ToggleDiffPaneMsg      struct{}
 	ToggleThinkingMsg      struct{}
 	OpenReasoningDialogMsg struct{}
 	OpenExternalEditorMsg  struct{}
 	ToggleYoloModeMsg      struct{}
+	ToggleMouseMsg         struct{}
+	OpenThemesDialogMsg    struct{}
 	CompactMsg             struct {
 		SessionID string
 	}
+	ResetShellMsg struct {
+		SessionID string
+	}
+	OpenForkDialogMsg struct {
+		SessionID string
+	}
+	CompareForkMsg struct {
+		SessionID string
+	}
+	ExportSessionMsg struct {
+		SessionID string
+		Format    export.Format
+	}
+	OpenCheckpointsDialogMsg struct {
+		SessionID string
+	}
+	EditSessionMsg struct {
+		SessionID string
+	}
+	OpenEditMessageDialogMsg struct {
+		SessionID string
+	}
+	OpenWorktreeDialogMsg struct{}
 )
 
-func NewCommandDialog(sessionID string) CommandsDialog {
+// NewCommandDialog returns the command palette, listing the sessions to
+// switch to along with the commands.
+func NewCommandDialog(sessionID string, sessions []session.Session) CommandsDialog {
 	keyMap := DefaultCommandsDialogKeyMap()
 	listKeyMap := list.DefaultKeyMap()
 	listKeyMap.Down.SetEnabled(false)
@@ -114,8 +156,9 @@ func NewCommandDialog(sessionID string) CommandsDialog {
 		width:       defaultWidth,
 		keyMap:      DefaultCommandsDialogKeyMap(),
 		help:        help,
-		selected:    SystemCommands,
+		selected:    AllCommands,
 		sessionID:   sessionID,
+		sessions:    sessions,
 		mcpPrompts:  csync.NewSlice[Command](),
 	}
 }
@@ -162,9 +205,6 @@ func (c *commandDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				command.Handler(command),
 			)
 		case key.Matches(msg, c.keyMap.Tab):
-			if len(c.userCommands) == 0 && c.mcpPrompts.Len() == 0 {
-				return c, nil
-			}
 			return c, c.setCommandType(c.next())
 		case key.Matches(msg, c.keyMap.Close):
 			return c, util.CmdHandler(dialogs.CloseDialogMsg{})
@@ -179,6 +219,8 @@ func (c *commandDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 
 func (c *commandDialogCmp) next() commandType {
 	switch c.selected {
+	case AllCommands:
+		return SystemCommands
 	case SystemCommands:
 		if len(c.userCommands) > 0 {
 			return UserCommands
@@ -193,9 +235,9 @@ func (c *commandDialogCmp) next() commandType {
 		}
 		fallthrough
 	case MCPPrompts:
-		return SystemCommands
+		return AllCommands
 	default:
-		return SystemCommands
+		return AllCommands
 	}
 }
 
@@ -205,9 +247,6 @@ func (c *commandDialogCmp) View() string {
 	radio := c.commandTypeRadio()
 
 	header := t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Commands", c.width-lipgloss.Width(radio)-5) + " " + radio)
-	if len(c.userCommands) == 0 && c.mcpPrompts.Len() == 0 {
-		header = t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Commands", c.width-4))
-	}
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
 		header,
@@ -240,6 +279,7 @@ func (c *commandDialogCmp) commandTypeRadio() string {
 	}
 
 	parts := []string{
+		fn(AllCommands),
 		fn(SystemCommands),
 	}
 	if len(c.userCommands) > 0 {
@@ -260,6 +300,8 @@ func (c *commandDialogCmp) setCommandType(commandType commandType) tea.Cmd {
 
 	var commands []Command
 	switch c.selected {
+	case AllCommands:
+		commands = c.allCommands()
 	case SystemCommands:
 		commands = c.defaultCommands()
 	case UserCommands:
@@ -284,6 +326,66 @@ func (c *commandDialogCmp) setCommandType(commandType commandType) tea.Cmd {
 	return c.commandList.SetItems(commandItems)
 }
 
+// allCommands returns the commands of every type, followed by the sessions
+// to switch to and the models the large model can switch to.
+func (c *commandDialogCmp) allCommands() []Command {
+	commands := c.defaultCommands()
+	commands = append(commands, c.userCommands...)
+	commands = append(commands, slices.Collect(c.mcpPrompts.Seq())...)
+	for _, sess := range c.sessions {
+		if sess.ID == c.sessionID {
+			continue
+		}
+		commands = append(commands, Command{
+			ID:          "session:" + sess.ID,
+			Title:       "Session: " + sess.Title,
+			Description: "Switch to the session",
+			Handler: func(Command) tea.Cmd {
+				return util.CmdHandler(chat.SessionSelectedMsg(sess))
+			},
+		})
+	}
+	return append(commands, modelCommands(config.Get())...)
+}
+
+// modelCommands returns the commands switching the large model to the
+// models of the enabled providers.
+func modelCommands(cfg *config.Config) []Command {
+	if cfg == nil {
+		return nil
+	}
+	current := cfg.Models[config.SelectedModelTypeLarge]
+	var commands []Command
+	for providerID, providerCfg := range cfg.Providers.Seq2() {
+		if providerCfg.Disable {
+			continue
+		}
+		for _, model := range providerCfg.Models {
+			if model.ID == current.Model && providerID == current.Provider {
+				continue
+			}
+			commands = append(commands, Command{
+				ID:          "model:" + providerID + "/" + model.ID,
+				Title:       fmt.Sprintf("Model: %s (%s)", cmp.Or(model.Name, model.ID), cmp.Or(providerCfg.Name, providerID)),
+				Description: "Switch the large model",
+				Handler: func(Command) tea.Cmd {
+					return util.CmdHandler(models.ModelSelectedMsg{
+						Model: config.SelectedModel{
+							Model:           model.ID,
+							Provider:        providerID,
+							ReasoningEffort: model.DefaultReasoningEffort,
+							MaxTokens:       model.DefaultMaxTokens,
+						},
+						ModelType: config.SelectedModelTypeLarge,
+					})
+				},
+			})
+		}
+	}
+	slices.SortFunc(commands, func(a, b Command) int { return strings.Compare(a.Title, b.Title) })
+	return commands
+}
+
 func (c *commandDialogCmp) listHeight() int {
 	listHeigh := len(c.commandList.Items()) + 2 + 4 // height based on items + 2 for the input + 4 for the sections
 	return min(listHeigh, c.wHeight/2)
@@ -341,6 +443,30 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(SwitchModelMsg{})
 			},
 		},
+		{
+			ID:          "login",
+			Title:       "Log in to Provider",
+			Description: "Log in to a provider with OAuth",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenLoginDialogMsg{})
+			},
+		},
+		{
+			ID:          "switch_account",
+			Title:       "Switch Account",
+			Description: "Switch the active account of a provider",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenAccountsDialogMsg{})
+			},
+		},
+		{
+			ID:          "background_agents",
+			Title:       "Background Agents",
+			Description: "Monitor background agents and collect their results",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenAgentsDialogMsg{})
+			},
+		},
 	}
 
 	// Only show compact command if there's an active session
@@ -354,11 +480,107 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 					SessionID: c.sessionID,
 				})
 			},
+		}, Command{
+			ID:          "reset_shell",
+			Title:       "Reset Shell",
+			Description: "Start the shell of the session over, in the project directory and with the default environment",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ResetShellMsg{
+					SessionID: c.sessionID,
+				})
+			},
+		}, Command{
+			ID:          "restore_checkpoint",
+			Title:       "Restore Checkpoint",
+			Description: "Revert the files to how they were before one of the prompts of the session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenCheckpointsDialogMsg{
+					SessionID: c.sessionID,
+				})
+			},
+		}, Command{
+			ID:          "edit_session",
+			Title:       "Rename or Tag Session",
+			Description: "Change the name of the session and the tags to filter the session picker by",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(EditSessionMsg{
+					SessionID: c.sessionID,
+				})
+			},
+		}, Command{
+			ID:          "edit_message",
+			Title:       "Edit Message",
+			Description: "Edit one of your previous messages and regenerate the conversation from it",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenEditMessageDialogMsg{
+					SessionID: c.sessionID,
+				})
+			},
+		}, Command{
+			ID:          "fork_session",
+			Title:       "Fork Session",
+			Description: "Continue in a new session from one of the messages, to explore another approach",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenForkDialogMsg{
+					SessionID: c.sessionID,
+				})
+			},
+		}, Command{
+			ID:          "compare_fork",
+			Title:       "Compare Fork",
+			Description: "Show how the session went on compared to the session it was forked from",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(CompareForkMsg{
+					SessionID: c.sessionID,
+				})
+			},
+		}, Command{
+			ID:          "export_markdown",
+			Title:       "Export Session to Markdown",
+			Description: "Save the session to a Markdown file in the project, with its tool calls, diffs and costs",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ExportSessionMsg{
+					SessionID: c.sessionID,
+					Format:    export.FormatMarkdown,
+				})
+			},
+		}, Command{
+			ID:          "export_html",
+			Title:       "Export Session to HTML",
+			Description: "Save the session to a self-contained HTML page in the project, for sharing",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ExportSessionMsg{
+					SessionID: c.sessionID,
+					Format:    export.FormatHTML,
+				})
+			},
+		})
+	}
+
+	if worktree.Current() != nil {
+		commands = append(commands, Command{
+			ID:          "review_worktree",
+			Title:       "Review Worktree",
+			Description: "Review the changes made in the worktree and merge them into your branch",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenWorktreeDialogMsg{})
+			},
 		})
 	}
 
-	// Add reasoning toggle for models that support it
 	cfg := config.Get()
+	if pc, ok := cfg.Providers.Get(copilot.ProviderID); ok && pc.OAuthToken != nil {
+		commands = append(commands, Command{
+			ID:          "copilot_usage",
+			Title:       "Copilot Usage",
+			Description: "Show GitHub Copilot plan and premium request usage",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenCopilotUsageMsg{})
+			},
+		})
+	}
+
+	// Add reasoning toggle for models that support it
 	if agentCfg, ok := cfg.Agents[config.AgentCoder]; ok {
 		providerCfg := cfg.GetProviderForModel(agentCfg.Model)
 		model := cfg.GetModelByType(agentCfg.Model)
@@ -366,7 +588,7 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 			selectedModel := cfg.Models[agentCfg.Model]
 
 			// Anthropic models: thinking toggle
-			if providerCfg.Type == catwalk.TypeAnthropic {
+			if config.ThinksWithToggle(providerCfg.Type, model.ID) {
 				status := "Enable"
 				if selectedModel.Think {
 					status = "Disable"
@@ -405,6 +627,17 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 			},
 		})
 	}
+	if c.sessionID != "" {
+		commands = append(commands, Command{
+			ID:          "toggle_diff",
+			Title:       "Toggle Diff Pane",
+			Shortcut:    "ctrl+t",
+			Description: "Show the changes to the file edited last next to the messages",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ToggleDiffPaneMsg{})
+			},
+		})
+	}
 	if c.sessionID != "" {
 		agentCfg := config.Get().Agents[config.AgentCoder]
 		model := config.Get().GetModelByType(agentCfg.Model)
@@ -421,8 +654,22 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 		}
 	}
 
-	// Add external editor command if $EDITOR is available
-	if os.Getenv("EDITOR") != "" {
+	for _, resources := range mcp.Resources() {
+		if len(resources) > 0 {
+			commands = append(commands, Command{
+				ID:          "mcp_resources",
+				Title:       "Attach MCP Resource",
+				Description: "Attach a resource of a connected MCP server to the message",
+				Handler: func(cmd Command) tea.Cmd {
+					return util.CmdHandler(OpenMCPResourcesMsg{})
+				},
+			})
+			break
+		}
+	}
+
+	// Add external editor command if $VISUAL or $EDITOR is available
+	if os.Getenv("VISUAL") != "" || os.Getenv("EDITOR") != "" {
 		commands = append(commands, Command{
 			ID:          "open_external_editor",
 			Title:       "Open External Editor",
@@ -443,6 +690,22 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(ToggleYoloModeMsg{})
 			},
 		},
+		{
+			ID:          "switch_theme",
+			Title:       "Switch Theme",
+			Description: "Choose the theme of the interface, previewing it",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenThemesDialogMsg{})
+			},
+		},
+		{
+			ID:          "toggle_mouse",
+			Title:       "Toggle Mouse",
+			Description: "Switch between scrolling and selecting with the mouse and the terminal's native selection",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ToggleMouseMsg{})
+			},
+		},
 		{
 			ID:          "toggle_help",
 			Title:       "Toggle Help",
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelCommands(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Models: map[config.SelectedModelType]config.SelectedModel{
+			config.SelectedModelTypeLarge: {Provider: "openai", Model: "gpt-4.1"},
+		},
+		Providers: csync.NewMapFrom(map[string]config.ProviderConfig{
+			"openai": {Name: "OpenAI", Models: []catwalk.Model{
+				{ID: "gpt-4.1", Name: "GPT-4.1"},
+				{ID: "o3", Name: "o3", DefaultReasoningEffort: "medium"},
+			}},
+			"ollama":    {Models: []catwalk.Model{{ID: "qwen3"}}},
+			"anthropic": {Disable: true, Models: []catwalk.Model{{ID: "claude-sonnet-4"}}},
+		}),
+	}
+
+	commands := modelCommands(cfg)
+	var titles []string
+	for _, command := range commands {
+		titles = append(titles, command.Title)
+	}
+	require.Equal(t, []string{"Model: o3 (OpenAI)", "Model: qwen3 (ollama)"}, titles)
+	require.Equal(t, models.ModelSelectedMsg{
+		Model:     config.SelectedModel{Provider: "openai", Model: "o3", ReasoningEffort: "medium"},
+		ModelType: config.SelectedModelTypeLarge,
+	}, commands[0].Handler(commands[0])())
+}
+
+func TestNextCommandType(t *testing.T) {
+	t.Parallel()
+
+	c := &commandDialogCmp{selected: AllCommands, mcpPrompts: csync.NewSlice[Command]()}
+	c.selected = c.next()
+	require.Equal(t, SystemCommands, c.selected)
+	require.Equal(t, AllCommands, c.next(), "the types without commands are skipped")
+
+	c.userCommands = []Command{{ID: "user:fix"}}
+	require.Equal(t, UserCommands, c.next())
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -14,6 +15,7 @@ import (
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/util"
 )
@@ -34,7 +36,22 @@ type commandSource struct {
 	prefix string
 }
 
-func LoadCustomCommands() ([]Command, error) {
+// argumentsName is the placeholder taking the text typed after the name of a
+// custom command in the editor.
+const argumentsName = "ARGUMENTS"
+
+// CustomCommand is a command written as a Markdown template, whose $NAME
+// placeholders are replaced with the arguments of the command.
+type CustomCommand struct {
+	ID          string
+	Name        string
+	Description string
+	Content     string
+}
+
+// LoadCustomCommandTemplates loads the custom commands of the user and of
+// the project.
+func LoadCustomCommandTemplates() ([]CustomCommand, error) {
 	cfg := config.Get()
 	if cfg == nil {
 		return nil, fmt.Errorf("config not loaded")
@@ -47,6 +64,71 @@ func LoadCustomCommands() ([]Command, error) {
 	return loader.loadAll()
 }
 
+func LoadCustomCommands() ([]Command, error) {
+	templates, err := LoadCustomCommandTemplates()
+	if err != nil {
+		return nil, err
+	}
+	commands := make([]Command, 0, len(templates))
+	for _, template := range templates {
+		commands = append(commands, Command{
+			ID:          template.ID,
+			Title:       template.ID,
+			Description: template.Description,
+			Handler: func(Command) tea.Cmd {
+				return template.Run("", nil)
+			},
+		})
+	}
+	return commands, nil
+}
+
+// FindCustomCommand returns the command of the given name, or ID, in
+// commands.
+func FindCustomCommand(commands []CustomCommand, name string) (CustomCommand, bool) {
+	for _, command := range commands {
+		if command.Name == name || command.ID == name {
+			return command, true
+		}
+	}
+	return CustomCommand{}, false
+}
+
+// Run runs the command with the attachments, replacing $ARGUMENTS with
+// arguments when they're given. The arguments dialog asks for the values of
+// the other placeholders.
+func (c CustomCommand) Run(arguments string, attachments []message.Attachment) tea.Cmd {
+	args := make(map[string]string)
+	if arguments != "" {
+		args[argumentsName] = arguments
+	}
+	var missing []string
+	for _, name := range extractArgNames(c.Content) {
+		if _, ok := args[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) == 0 {
+		return util.CmdHandler(CommandRunCustomMsg{
+			Content:     expandCommand(c.Content, args),
+			Attachments: attachments,
+		})
+	}
+	return util.CmdHandler(ShowArgumentsDialogMsg{
+		CommandID:   c.ID,
+		Description: c.Description,
+		ArgNames:    missing,
+		OnSubmit: func(values map[string]string) tea.Cmd {
+			maps.Copy(values, args)
+			return util.CmdHandler(CommandRunCustomMsg{
+				Content:     expandCommand(c.Content, values),
+				Attachments: attachments,
+			})
+		},
+	})
+}
+
 func buildCommandSources(cfg *config.Config) []commandSource {
 	var sources []commandSource
 
@@ -88,8 +170,8 @@ func getXDGCommandsDir() string {
 	return ""
 }
 
-func (l *commandLoader) loadAll() ([]Command, error) {
-	var commands []Command
+func (l *commandLoader) loadAll() ([]CustomCommand, error) {
+	var commands []CustomCommand
 
 	for _, source := range l.sources {
 		if cmds, err := l.loadFromSource(source); err == nil {
@@ -100,12 +182,12 @@ func (l *commandLoader) loadAll() ([]Command, error) {
 	return commands, nil
 }
 
-func (l *commandLoader) loadFromSource(source commandSource) ([]Command, error) {
+func (l *commandLoader) loadFromSource(source commandSource) ([]CustomCommand, error) {
 	if err := ensureDir(source.path); err != nil {
 		return nil, err
 	}
 
-	var commands []Command
+	var commands []CustomCommand
 
 	err := filepath.WalkDir(source.path, func(path string, d fs.DirEntry, err error) error {
 		if err != nil || d.IsDir() || !isMarkdownFile(d.Name()) {
@@ -124,20 +206,18 @@ func (l *commandLoader) loadFromSource(source commandSource) ([]Command, error)
 	return commands, err
 }
 
-func (l *commandLoader) loadCommand(path, baseDir, prefix string) (Command, error) {
+func (l *commandLoader) loadCommand(path, baseDir, prefix string) (CustomCommand, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return Command{}, err
+		return CustomCommand{}, err
 	}
 
 	id := buildCommandID(path, baseDir, prefix)
-	desc := fmt.Sprintf("Custom command from %s", filepath.Base(path))
-
-	return Command{
+	return CustomCommand{
 		ID:          id,
-		Title:       id,
-		Description: desc,
-		Handler:     createCommandHandler(id, desc, string(content)),
+		Name:        strings.TrimPrefix(id, prefix),
+		Description: fmt.Sprintf("Custom command from %s", filepath.Base(path)),
+		Content:     string(content),
 	}, nil
 }
 
@@ -154,36 +234,15 @@ func buildCommandID(path, baseDir, prefix string) string {
 	return prefix + strings.Join(parts, ":")
 }
 
-func createCommandHandler(id, desc, content string) func(Command) tea.Cmd {
-	return func(cmd Command) tea.Cmd {
-		args := extractArgNames(content)
-
-		if len(args) == 0 {
-			return util.CmdHandler(CommandRunCustomMsg{
-				Content: content,
-			})
+// expandCommand replaces the placeholders of content with the values of
+// args. The placeholders without a value are left as is.
+func expandCommand(content string, args map[string]string) string {
+	return namedArgPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		if value, ok := args[placeholder[1:]]; ok {
+			return value
 		}
-		return util.CmdHandler(ShowArgumentsDialogMsg{
-			CommandID:   id,
-			Description: desc,
-			ArgNames:    args,
-			OnSubmit: func(args map[string]string) tea.Cmd {
-				return execUserPrompt(content, args)
-			},
-		})
-	}
-}
-
-func execUserPrompt(content string, args map[string]string) tea.Cmd {
-	return func() tea.Msg {
-		for name, value := range args {
-			placeholder := "$" + name
-			content = strings.ReplaceAll(content, placeholder, value)
-		}
-		return CommandRunCustomMsg{
-			Content: content,
-		}
-	}
+		return placeholder
+	})
 }
 
 func extractArgNames(content string) []string {
@@ -218,7 +277,8 @@ func isMarkdownFile(name string) bool {
 }
 
 type CommandRunCustomMsg struct {
-	Content string
+	Content     string
+	Attachments []message.Attachment
 }
 
 func loadMCPPrompts() []Command {
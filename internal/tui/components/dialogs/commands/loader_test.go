@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandCommand(t *testing.T) {
+	t.Parallel()
+
+	content := "Fix $ARG in $ARGUMENTS, keeping $OTHER and $5."
+	require.Equal(t,
+		"Fix the bug in main.go, keeping $OTHER and $5.",
+		expandCommand(content, map[string]string{"ARG": "the bug", "ARGUMENTS": "main.go"}),
+	)
+}
+
+func TestLoadCommands(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fix-issue.md"), []byte("Fix issue $ARGUMENTS."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "git", "commit.md"), []byte("Commit."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("Not a command."), 0o644))
+
+	loader := &commandLoader{sources: []commandSource{{path: dir, prefix: projectCommandPrefix}}}
+	commands, err := loader.loadAll()
+	require.NoError(t, err)
+	require.Len(t, commands, 2)
+	require.Equal(t, "project:fix-issue", commands[0].ID)
+	require.Equal(t, "fix-issue", commands[0].Name)
+	require.Equal(t, "git:commit", commands[1].Name)
+
+	command, ok := FindCustomCommand(commands, "git:commit")
+	require.True(t, ok)
+	require.Equal(t, "Commit.", command.Content)
+	_, ok = FindCustomCommand(commands, "project:fix-issue")
+	require.True(t, ok)
+	_, ok = FindCustomCommand(commands, "commit")
+	require.False(t, ok)
+}
+
+func TestRunCustomCommand(t *testing.T) {
+	t.Parallel()
+
+	attachments := []message.Attachment{{FileName: "main.go", MimeType: "text/plain", Content: []byte("package main")}}
+	command := CustomCommand{ID: "user:fix", Name: "fix", Content: "Fix issue $ARGUMENTS."}
+	require.Equal(t, CommandRunCustomMsg{
+		Content:     "Fix issue #42.",
+		Attachments: attachments,
+	}, command.Run("#42", attachments)())
+
+	// The arguments not given are asked for.
+	require.Equal(t, []string{"ARGUMENTS"}, command.Run("", nil)().(ShowArgumentsDialogMsg).ArgNames)
+
+	command.Content = "Fix issue $ARGUMENTS in $FILE."
+	dialog := command.Run("#42", nil)().(ShowArgumentsDialogMsg)
+	require.Equal(t, []string{"FILE"}, dialog.ArgNames)
+	require.Equal(t, CommandRunCustomMsg{
+		Content: "Fix issue #42 in main.go.",
+	}, dialog.OnSubmit(map[string]string{"FILE": "main.go"})())
+}
@@ -0,0 +1,42 @@
+package copilot
+
+import (
+	"charm.land/bubbles/v2/key"
+)
+
+// UsageKeyMap defines the keyboard bindings for the usage dialog.
+type UsageKeyMap struct {
+	Refresh,
+	Close key.Binding
+}
+
+func DefaultUsageKeyMap() UsageKeyMap {
+	return UsageKeyMap{
+		Refresh: key.NewBinding(
+			key.WithKeys("r", "ctrl+r"),
+			key.WithHelp("r", "refresh"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "alt+esc", "enter"),
+			key.WithHelp("esc", "close"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k UsageKeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Refresh,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k UsageKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k UsageKeyMap) ShortHelp() []key.Binding {
+	return k.KeyBindings()
+}
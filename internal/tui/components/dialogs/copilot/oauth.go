@@ -1,16 +1,24 @@
 package copilot
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"charm.land/bubbles/v2/spinner"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/pkg/browser"
 )
 
 // OAuthState represents the current state of the OAuth flow.
@@ -27,7 +35,10 @@ const (
 // ValidationCompletedMsg is sent when token validation completes.
 type ValidationCompletedMsg struct {
 	Token string
-	Error error
+	// CopilotToken is the API token obtained while checking the account's
+	// Copilot access, if any.
+	CopilotToken *copilot.CopilotToken
+	Error        error
 }
 
 // AuthenticationCompleteMsg is sent when authentication is complete.
@@ -39,26 +50,60 @@ type OAuth2 struct {
 	width        int
 	isOnboarding bool
 
+	// accessible renders the flow as plain text lines, one per state
+	// change, with no spinner.
+	accessible bool
+	transcript []string
+
+	// remote is set when running over SSH, where a browser opened by crush
+	// would not be on the user's machine. The code is copied with OSC52
+	// instead.
+	remote bool
+	// noExpiry requests a new code whenever the current one expires,
+	// waiting indefinitely for the user.
+	noExpiry bool
+
 	// Device flow state.
 	deviceCode      string
 	userCode        string
 	verificationURI string
-	interval        int
-	err             error
-	token           string
+	// browserURL is the URL to open, which embeds the user code when GitHub
+	// gives one that does.
+	browserURL string
+	interval   int
+	expiresAt  time.Time
+	err        error
+	token      string
+	// copilotToken is the API token obtained when validating token, cached
+	// so the first request doesn't need another exchange.
+	copilotToken *copilot.CopilotToken
+	// retryAt is when a rate limited flow may be retried.
+	retryAt time.Time
 
 	// UI components.
-	spinner    spinner.Model
+	spinner spinner.Model
+
+	// ctx is cancelled when the flow is aborted, stopping any in-flight
+	// device flow request or token polling.
+	ctx        context.Context
 	cancelFunc context.CancelFunc
 }
 
 // NewOAuth2 creates a new OAuth2 dialog for GitHub Copilot.
 func NewOAuth2() *OAuth2 {
 	return &OAuth2{
-		State: OAuthStateInit,
+		State:      OAuthStateInit,
+		accessible: dialogs.Accessible(),
+		remote:     util.IsRemoteSession(),
+		noExpiry:   deviceFlowNoExpiry(),
 	}
 }
 
+func deviceFlowNoExpiry() bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.Options != nil && cfg.Options.DeviceFlowNoExpiry
+}
+
 // Init initializes the OAuth component UI (spinner only).
 // Call StartFlow() to actually begin the device flow.
 func (o *OAuth2) Init() tea.Cmd {
@@ -69,9 +114,27 @@ func (o *OAuth2) Init() tea.Cmd {
 		spinner.WithStyle(t.S().Base.Foreground(t.Green)),
 	)
 
+	return o.tick()
+}
+
+// tick starts the spinner animation, unless running in accessible mode.
+func (o *OAuth2) tick() tea.Cmd {
+	if o.accessible {
+		return nil
+	}
 	return o.spinner.Tick
 }
 
+// announce records a state change for the accessible view.
+func (o *OAuth2) announce(lines ...string) {
+	for _, line := range lines {
+		if n := len(o.transcript); n > 0 && o.transcript[n-1] == line {
+			continue
+		}
+		o.transcript = append(o.transcript, line)
+	}
+}
+
 // StartFlow begins the OAuth device flow. Call this when the user
 // selects GitHub Copilot as their provider.
 func (o *OAuth2) StartFlow() tea.Cmd {
@@ -86,31 +149,59 @@ func (o *OAuth2) StartFlow() tea.Cmd {
 		spinner.WithStyle(t.S().Base.Foreground(t.Green)),
 	)
 
+	o.ctx, o.cancelFunc = context.WithCancel(context.Background())
+	o.announce("Starting GitHub authentication.")
+
 	// Start the device flow.
 	return tea.Batch(
-		o.spinner.Tick,
-		o.startDeviceFlow,
+		o.tick(),
+		o.startDeviceFlow(o.ctx),
 	)
 }
 
-func (o *OAuth2) startDeviceFlow() tea.Msg {
-	slog.Info("Copilot OAuth: Starting device flow")
-	resp, err := copilot.StartDeviceFlow(context.Background())
-	if err != nil {
-		slog.Error("Copilot OAuth: Device flow failed", "error", err)
-		return ValidationCompletedMsg{Error: err}
-	}
+// Cancel aborts the in-progress device flow, stopping the spinner and any
+// pending polling, and resets the dialog.
+func (o *OAuth2) Cancel() {
+	slog.Info("Copilot OAuth: Cancelling device flow")
+	o.SetDefaults()
+}
+
+// IsInProgress returns whether the device flow is still waiting on GitHub.
+func (o *OAuth2) IsInProgress() bool {
+	return o.ctx != nil && (o.State == OAuthStateInit || o.State == OAuthStateWaitingForAuth || o.State == OAuthStateValidating)
+}
 
-	slog.Info("Copilot OAuth: Device flow started",
-		"user_code", resp.UserCode,
-		"verification_uri", resp.VerificationURI,
-		"interval", resp.Interval)
+// isCancelled returns whether the current flow has been aborted.
+func (o *OAuth2) isCancelled() bool {
+	return o.ctx == nil || o.ctx.Err() != nil
+}
 
-	return DeviceFlowStartedMsg{
-		DeviceCode:      resp.DeviceCode,
-		UserCode:        resp.UserCode,
-		VerificationURI: resp.VerificationURI,
-		Interval:        resp.Interval,
+func (o *OAuth2) startDeviceFlow(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		slog.Info("Copilot OAuth: Starting device flow")
+		resp, err := copilot.StartDeviceFlow(ctx)
+		if ctx.Err() != nil {
+			// The flow was cancelled while the request was in flight.
+			return nil
+		}
+		if err != nil {
+			slog.Error("Copilot OAuth: Device flow failed", "error", err)
+			return ValidationCompletedMsg{Error: err}
+		}
+
+		slog.Info("Copilot OAuth: Device flow started",
+			"user_code", resp.UserCode,
+			"verification_uri", resp.VerificationURI,
+			"interval", resp.Interval)
+
+		return DeviceFlowStartedMsg{
+			DeviceCode:      resp.DeviceCode,
+			UserCode:        resp.UserCode,
+			VerificationURI: resp.VerificationURI,
+			BrowserURL:      resp.BrowserURL(),
+			ExpiresIn:       resp.ExpiresIn,
+			Interval:        resp.Interval,
+		}
 	}
 }
 
@@ -119,6 +210,8 @@ type DeviceFlowStartedMsg struct {
 	DeviceCode      string
 	UserCode        string
 	VerificationURI string
+	BrowserURL      string
+	ExpiresIn       int
 	Interval        int
 }
 
@@ -128,44 +221,78 @@ func (o *OAuth2) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case DeviceFlowStartedMsg:
+		if o.isCancelled() {
+			return o, nil
+		}
 		slog.Info("Copilot OAuth: Received DeviceFlowStartedMsg",
 			"user_code", msg.UserCode,
 			"verification_uri", msg.VerificationURI)
 		o.deviceCode = msg.DeviceCode
 		o.userCode = msg.UserCode
 		o.verificationURI = msg.VerificationURI
+		o.browserURL = cmp.Or(msg.BrowserURL, msg.VerificationURI)
 		o.interval = msg.Interval
+		o.expiresAt = time.Time{}
+		if msg.ExpiresIn > 0 {
+			o.expiresAt = time.Now().Add(time.Duration(msg.ExpiresIn) * time.Second)
+		}
 		o.State = OAuthStateWaitingForAuth
+		o.announceWaiting()
+		if o.remote {
+			cmds = append(cmds, o.copyToClipboard())
+		}
 
-		// Start polling immediately - user will open browser manually.
-		ctx, cancel := context.WithCancel(context.Background())
-		o.cancelFunc = cancel
-		cmds = append(cmds, o.spinner.Tick, o.pollForToken(ctx))
+		// Start polling immediately - user opens the browser with Enter.
+		cmds = append(cmds, o.tick(), o.pollForToken(o.ctx))
 
 	case ValidationCompletedMsg:
 		slog.Info("Copilot OAuth: Received ValidationCompletedMsg", "error", msg.Error)
+		if o.isCancelled() {
+			return o, nil
+		}
 		if msg.Error != nil {
-			o.err = msg.Error
-			o.State = OAuthStateError
+			cmds = append(cmds, o.fail(msg.Error))
 		} else {
 			o.token = msg.Token
+			o.copilotToken = msg.CopilotToken
 			o.State = OAuthStateSuccess
+			o.announce("GitHub Copilot authenticated successfully.", "Press enter to continue.")
 		}
 
 	case PollingResultMsg:
 		slog.Info("Copilot OAuth: Received PollingResultMsg", "has_token", msg.Token != "", "error", msg.Error)
+		if errors.Is(msg.Error, context.Canceled) || o.isCancelled() {
+			// The user aborted the flow; nothing to report.
+			return o, nil
+		}
+		if msg.Error != nil && o.noExpiry && copilot.ClassifyError(msg.Error) == copilot.ErrorKindExpiredCode {
+			slog.Info("Copilot OAuth: Device code expired, requesting a new one")
+			cmd := o.StartFlow()
+			o.transcript = []string{"The code expired. Requesting a new one."}
+			return o, cmd
+		}
 		if msg.Error != nil {
-			o.err = msg.Error
-			o.State = OAuthStateError
+			cmds = append(cmds, o.fail(msg.Error))
 		} else if msg.Token != "" {
+			// Make sure the account can actually use Copilot before
+			// reporting success.
 			o.token = msg.Token
-			o.State = OAuthStateSuccess
+			o.State = OAuthStateValidating
+			o.announce("Authorization received. Checking Copilot access.")
+			cmds = append(cmds, o.validateToken(o.ctx))
 		}
 		// If no error and no token, keep polling (handled in polling goroutine).
+
+	case RetryReadyMsg:
+		// Re-render so the retry hint shows up.
+		if o.State == OAuthStateError {
+			o.announce("You can retry now. Press enter to retry.")
+		}
+		return o, nil
 	}
 
 	// Update spinner for states that need animation.
-	if o.State == OAuthStateInit || o.State == OAuthStateWaitingForAuth || o.State == OAuthStateValidating {
+	if !o.accessible && (o.State == OAuthStateInit || o.State == OAuthStateWaitingForAuth || o.State == OAuthStateValidating) {
 		var cmd tea.Cmd
 		o.spinner, cmd = o.spinner.Update(msg)
 		cmds = append(cmds, cmd)
@@ -179,22 +306,95 @@ func (o *OAuth2) ValidationConfirm() (util.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch o.State {
-	case OAuthStateInit, OAuthStateWaitingForAuth:
+	case OAuthStateInit:
 		// Still waiting, do nothing.
 		return o, nil
 
+	case OAuthStateWaitingForAuth:
+		if o.remote {
+			// A browser would open on the remote host.
+			return o, o.copyToClipboard()
+		}
+		_ = browser.OpenURL(o.browserURL)
+		return o, nil
+
 	case OAuthStateSuccess:
 		cmds = append(cmds, func() tea.Msg { return AuthenticationCompleteMsg{} })
 
 	case OAuthStateError:
-		// Reset and try again.
-		o.SetDefaults()
-		cmds = append(cmds, o.spinner.Tick, o.startDeviceFlow)
+		cmds = append(cmds, o.retry())
 	}
 
 	return o, tea.Batch(cmds...)
 }
 
+// RetryReadyMsg is sent once a rate limited flow may be retried.
+type RetryReadyMsg struct{}
+
+// rateLimitBackoff is how long to wait before retrying a rate limited flow.
+const rateLimitBackoff = time.Minute
+
+// fail moves the flow to the error state.
+func (o *OAuth2) fail(err error) tea.Cmd {
+	o.err = err
+	o.State = OAuthStateError
+	rateLimited := copilot.ClassifyError(err) == copilot.ErrorKindRateLimited
+	if rateLimited {
+		o.retryAt = time.Now().Add(rateLimitBackoff)
+	}
+	title, guidance, retryHint := o.errorGuidance()
+	o.announce("Error: "+title+".", guidance, retryHint+".")
+	if !rateLimited {
+		return nil
+	}
+	return tea.Tick(rateLimitBackoff, func(time.Time) tea.Msg {
+		return RetryReadyMsg{}
+	})
+}
+
+// retry recovers from the error state. Transient failures pick up where the
+// flow stopped, keeping the code the user may already have entered; anything
+// else starts over with a new code.
+func (o *OAuth2) retry() tea.Cmd {
+	if time.Now().Before(o.retryAt) {
+		return nil
+	}
+	switch copilot.ClassifyError(o.err) {
+	case copilot.ErrorKindNetwork, copilot.ErrorKindRateLimited:
+		if o.isCancelled() {
+			break
+		}
+		if o.token != "" {
+			o.err = nil
+			o.State = OAuthStateValidating
+			o.announce("Retrying. Checking Copilot access.")
+			return tea.Batch(o.tick(), o.validateToken(o.ctx))
+		}
+		if o.deviceCode != "" {
+			o.err = nil
+			o.State = OAuthStateWaitingForAuth
+			o.announce("Retrying.")
+			o.announceWaiting()
+			return tea.Batch(o.tick(), o.pollForToken(o.ctx))
+		}
+	}
+	return o.StartFlow()
+}
+
+// validateToken exchanges the GitHub token for a Copilot API token, so
+// accounts without a Copilot subscription fail at login rather than on their
+// first prompt.
+func (o *OAuth2) validateToken(ctx context.Context) tea.Cmd {
+	token := o.token
+	return func() tea.Msg {
+		copilotToken, err := copilot.ExchangeForCopilotToken(ctx, token)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return ValidationCompletedMsg{Token: token, CopilotToken: copilotToken, Error: err}
+	}
+}
+
 // PollingResultMsg is sent when polling for token completes.
 type PollingResultMsg struct {
 	Token string
@@ -210,8 +410,64 @@ func (o *OAuth2) pollForToken(ctx context.Context) tea.Cmd {
 	}
 }
 
+// embedsCode reports whether the URL to open embeds the user code.
+func (o *OAuth2) embedsCode() bool {
+	return o.browserURL != "" && o.browserURL != o.verificationURI
+}
+
+// clipboardText returns what is copied over OSC52: the URL when it already
+// embeds the code, the code itself otherwise.
+func (o *OAuth2) clipboardText() string {
+	if o.embedsCode() {
+		return o.browserURL
+	}
+	return o.userCode
+}
+
+// copyToClipboard copies the code, or the URL embedding it, to the local
+// clipboard through the terminal.
+func (o *OAuth2) copyToClipboard() tea.Cmd {
+	o.announce("Copied to your clipboard.")
+	return tea.SetClipboard(o.clipboardText())
+}
+
+// announceWaiting records the code and where to enter it.
+func (o *OAuth2) announceWaiting() {
+	action := "Press enter to open GitHub in your browser, or esc to cancel."
+	if o.remote {
+		action = "Press enter to copy it to your clipboard, or esc to cancel."
+	}
+	o.announce(
+		"Open "+o.verificationURI+" and enter the code "+o.userCode+".",
+		action,
+		"Waiting for authorization.",
+	)
+}
+
+func (o *OAuth2) instructions() string {
+	if o.remote {
+		if o.isCompact() {
+			return "enter copy · esc cancel"
+		}
+		return "Press enter to copy it again, or esc to cancel"
+	}
+	if o.isCompact() {
+		return "enter open GitHub · esc cancel"
+	}
+	if o.embedsCode() {
+		return "Press enter to open GitHub and confirm this code, or esc to cancel"
+	}
+	return "Press enter to open GitHub and enter this code, or esc to cancel"
+}
+
 // View renders the OAuth dialog.
 func (o *OAuth2) View() string {
+	if o.accessible {
+		return o.textStyle().
+			Margin(0, 1).
+			Render(strings.Join(o.transcript, "\n"))
+	}
+
 	t := styles.CurrentTheme()
 
 	whiteStyle := lipgloss.NewStyle().Foreground(t.White)
@@ -233,24 +489,45 @@ func (o *OAuth2) View() string {
 			Render(o.spinner.View() + " " + titleStyle.Render("Starting GitHub authentication..."))
 
 	case OAuthStateWaitingForAuth:
+		compact := o.isCompact()
+
 		heading := lipgloss.NewStyle().
 			Margin(0, 1).
 			Render(o.spinner.View() + " " + titleStyle.Render("Waiting for authorization..."))
 
-		urlLine := lipgloss.NewStyle().
+		// Long URLs are wrapped instead of clipped, on their own line when
+		// space is tight.
+		urlLine := titleStyle.Render("Open: ") + successStyle.Render(o.browserURL)
+		if compact {
+			urlLine = titleStyle.Render("Open:") + "\n" + successStyle.Render(o.browserURL)
+		}
+		urlLine = o.textStyle().
 			Margin(1, 1).
-			Render(titleStyle.Render("Open: ") + successStyle.Render(o.verificationURI))
+			Render(urlLine)
 
-		codeBox := lipgloss.NewStyle().
+		codeBoxStyle := lipgloss.NewStyle().
 			Margin(1, 2).
 			Padding(1, 3).
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(t.Primary).
-			Render(successStyle.Bold(true).Render(o.userCode))
+			BorderForeground(t.Primary)
+		if compact {
+			codeBoxStyle = codeBoxStyle.Margin(1, 1).Padding(0, 1)
+		}
+		codeBox := codeBoxStyle.Render(successStyle.Bold(true).Render(o.userCode))
+		if o.remote {
+			return lipgloss.JoinVertical(
+				lipgloss.Left,
+				heading,
+				o.remoteView(),
+				o.textStyle().
+					Margin(0, 1).
+					Render(mutedStyle.Render(o.instructions())),
+			)
+		}
 
-		instructions := lipgloss.NewStyle().
+		instructions := o.textStyle().
 			Margin(0, 1).
-			Render(mutedStyle.Render("Enter this code on GitHub to authorize"))
+			Render(mutedStyle.Render(o.instructions()))
 
 		return lipgloss.JoinVertical(
 			lipgloss.Left,
@@ -263,7 +540,7 @@ func (o *OAuth2) View() string {
 	case OAuthStateValidating:
 		return lipgloss.NewStyle().
 			Margin(0, 1).
-			Render(o.spinner.View() + " " + titleStyle.Render("Validating token..."))
+			Render(o.spinner.View() + " " + titleStyle.Render("Checking Copilot access..."))
 
 	case OAuthStateSuccess:
 		return lipgloss.NewStyle().
@@ -272,21 +549,18 @@ func (o *OAuth2) View() string {
 				mutedStyle.Render("Press Enter to continue"))
 
 	case OAuthStateError:
-		errMsg := "Unknown error"
-		if o.err != nil {
-			errMsg = o.err.Error()
-		}
+		title, guidance, retryHint := o.errorGuidance()
 		return lipgloss.JoinVertical(
 			lipgloss.Left,
 			lipgloss.NewStyle().
 				Margin(0, 1).
-				Render(styles.ErrorIcon+" "+errorStyle.Render("Authentication failed")),
-			lipgloss.NewStyle().
+				Render(styles.ErrorIcon+" "+errorStyle.Render(title)),
+			o.textStyle().
 				Margin(1, 1).
-				Render(mutedStyle.Render(errMsg)),
-			lipgloss.NewStyle().
+				Render(mutedStyle.Render(guidance)),
+			o.textStyle().
 				Margin(1, 1).
-				Render(mutedStyle.Render("Press Enter to try again")),
+				Render(mutedStyle.Render(retryHint)),
 		)
 
 	default:
@@ -294,19 +568,117 @@ func (o *OAuth2) View() string {
 	}
 }
 
+// remoteView renders a prominent block with everything needed to authorize
+// from another machine, as no browser can be opened over SSH.
+func (o *OAuth2) remoteView() string {
+	t := styles.CurrentTheme()
+	labelStyle := lipgloss.NewStyle().Foreground(t.FgMuted)
+	valueStyle := lipgloss.NewStyle().Foreground(t.Success)
+
+	copied := "URL"
+	if !o.embedsCode() {
+		copied = "code"
+	}
+	lines := []string{
+		lipgloss.NewStyle().Foreground(t.White).Bold(true).Render("SSH session: open this on your local machine"),
+		"",
+		labelStyle.Render("URL:  ") + valueStyle.Render(o.verificationURI),
+		labelStyle.Render("Code: ") + valueStyle.Bold(true).Render(o.userCode),
+		"",
+		labelStyle.Render(fmt.Sprintf("The %s was copied to your clipboard.", copied)),
+	}
+	switch {
+	case o.noExpiry:
+		lines = append(lines, labelStyle.Render("A new code is requested if this one expires."))
+	case !o.expiresAt.IsZero():
+		lines = append(lines, labelStyle.Render("The code expires at "+o.expiresAt.Format(time.Kitchen)+"."))
+	}
+
+	style := lipgloss.NewStyle().
+		Margin(1, 1).
+		Padding(0, 1).
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(t.Primary)
+	if o.width > 8 {
+		// Account for the margin, border and padding.
+		style = style.Width(o.width - 4)
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
+
+// errorGuidance returns the title, next-step guidance and retry hint for the
+// current error.
+func (o *OAuth2) errorGuidance() (string, string, string) {
+	errMsg := "Unknown error"
+	if o.err != nil {
+		errMsg = o.err.Error()
+	}
+
+	switch copilot.ClassifyError(o.err) {
+	case copilot.ErrorKindNoSubscription:
+		return "No Copilot subscription",
+			"This GitHub account doesn't have access to Copilot. Start a subscription or free plan at https://github.com/features/copilot, or ask your organization admin for a seat.",
+			"Press Enter to log in with another account"
+	case copilot.ErrorKindAccessDenied:
+		return "Authorization denied",
+			"The request was declined on GitHub, so Crush can't use Copilot on your behalf.",
+			"Press Enter to get a new code and try again"
+	case copilot.ErrorKindExpiredCode:
+		return "Code expired",
+			"The code expired before it was confirmed on GitHub.",
+			"Press Enter to get a new code"
+	case copilot.ErrorKindNetwork:
+		return "Could not reach GitHub",
+			"Check your internet connection. If you are behind a proxy, make sure HTTPS_PROXY is set correctly.\n\n" + errMsg,
+			"Press Enter to retry"
+	case copilot.ErrorKindRateLimited:
+		hint := "Press Enter to retry"
+		if time.Now().Before(o.retryAt) {
+			hint = fmt.Sprintf("You can retry at %s", o.retryAt.Format(time.Kitchen))
+		}
+		return "Too many requests",
+			"GitHub is rate limiting requests. Wait a moment before trying again.",
+			hint
+	}
+	return "Authentication failed", errMsg, "Press Enter to try again"
+}
+
+// compactWidth is the width under which the dialog collapses its layout.
+const compactWidth = 60
+
+// isCompact returns whether the dialog is too narrow for the full layout.
+func (o *OAuth2) isCompact() bool {
+	return o.width > 0 && o.width < compactWidth
+}
+
+// textStyle wraps text to the dialog width, minus the horizontal margin.
+func (o *OAuth2) textStyle() lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if o.width > 2 {
+		style = style.Width(o.width - 2)
+	}
+	return style
+}
+
 // SetDefaults resets the dialog to its initial state.
 func (o *OAuth2) SetDefaults() {
 	if o.cancelFunc != nil {
 		o.cancelFunc()
 		o.cancelFunc = nil
 	}
+	o.ctx = nil
 	o.State = OAuthStateInit
 	o.deviceCode = ""
 	o.userCode = ""
 	o.verificationURI = ""
+	o.browserURL = ""
 	o.interval = 0
+	o.expiresAt = time.Time{}
 	o.err = nil
 	o.token = ""
+	o.copilotToken = nil
+	o.retryAt = time.Time{}
+	o.transcript = nil
 }
 
 // SetWidth sets the dialog width.
@@ -314,10 +686,31 @@ func (o *OAuth2) SetWidth(w int) {
 	o.width = w
 }
 
+// SetOnboarding styles the flow for the first-run wizard.
+func (o *OAuth2) SetOnboarding(onboarding bool) {
+	o.isOnboarding = onboarding
+}
+
+// SetAccessible toggles the plain text rendering for screen readers.
+func (o *OAuth2) SetAccessible(accessible bool) {
+	o.accessible = accessible
+}
+
+// SetRemote toggles the SSH friendly flow, which copies the code over OSC52
+// instead of opening a browser.
+func (o *OAuth2) SetRemote(remote bool) {
+	o.remote = remote
+}
+
+// SetNoExpiry toggles requesting a new code whenever the current one
+// expires.
+func (o *OAuth2) SetNoExpiry(noExpiry bool) {
+	o.noExpiry = noExpiry
+}
+
 // SetError sets an error state.
-func (o *OAuth2) SetError(err error) {
-	o.err = err
-	o.State = OAuthStateError
+func (o *OAuth2) SetError(err error) tea.Cmd {
+	return o.fail(err)
 }
 
 // Token returns the obtained OAuth token as an oauth.Token.
@@ -327,7 +720,16 @@ func (o *OAuth2) Token() *oauth.Token {
 	}
 	// For Copilot, the GitHub OAuth token is stored as RefreshToken
 	// because it's used to obtain short-lived Copilot API tokens.
-	return &oauth.Token{
+	token := &oauth.Token{
 		RefreshToken: o.token,
+		TokenType:    "bearer",
+		ProviderID:   copilot.ProviderID,
+		Version:      oauth.TokenSchemaVersion,
+	}
+	token.SetObtainedAt()
+	if o.copilotToken != nil {
+		token.CopilotToken = o.copilotToken.Token
+		token.CopilotExpiresAt = o.copilotToken.ExpiresAt
 	}
+	return token
 }
@@ -0,0 +1,138 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2_ViewFitsWidth(t *testing.T) {
+	t.Parallel()
+
+	for _, width := range []int{40, 50, 80} {
+		o := NewOAuth2()
+		o.Init()
+		o.SetRemote(false)
+		o.SetWidth(width)
+		o.State = OAuthStateWaitingForAuth
+		o.userCode = "ABCD-1234"
+		o.verificationURI = "https://github.com/login/device"
+		o.browserURL = "https://github.com/login/device?user_code=ABCD-1234&some_long_parameter=value"
+
+		for line := range strings.SplitSeq(o.View(), "\n") {
+			require.LessOrEqual(t, lipgloss.Width(line), width, "line %q overflows width %d", line, width)
+		}
+	}
+}
+
+func TestOAuth2_CompactInstructions(t *testing.T) {
+	t.Parallel()
+
+	o := NewOAuth2()
+	o.SetRemote(false)
+	o.SetWidth(80)
+	require.Contains(t, o.instructions(), "Press enter")
+
+	o.SetWidth(40)
+	require.Equal(t, "enter open GitHub · esc cancel", o.instructions())
+}
+
+func TestOAuth2_AccessibleTranscript(t *testing.T) {
+	t.Parallel()
+
+	o := NewOAuth2()
+	o.SetAccessible(true)
+	require.Nil(t, o.Init())
+	o.ctx, o.cancelFunc = context.WithCancel(t.Context())
+	o.announce("Starting GitHub authentication.")
+
+	o.Update(DeviceFlowStartedMsg{
+		DeviceCode:      "device-code",
+		UserCode:        "ABCD-1234",
+		VerificationURI: "https://github.com/login/device",
+	})
+	o.Update(ValidationCompletedMsg{Error: errors.New("boom")})
+
+	lines := strings.Split(o.View(), "\n")
+	require.Equal(t, "Starting GitHub authentication.", strings.TrimSpace(lines[0]))
+	require.Contains(t, lines[1], "https://github.com/login/device")
+	require.Contains(t, lines[1], "ABCD-1234")
+	require.Contains(t, o.View(), "Error: Authentication failed.")
+	require.NotContains(t, o.View(), "\x1b[", "accessible view must be plain text")
+
+	o.SetDefaults()
+	require.Empty(t, strings.TrimSpace(o.View()))
+}
+
+func TestOAuth2_RemoteView(t *testing.T) {
+	t.Parallel()
+
+	for _, width := range []int{40, 80} {
+		o := NewOAuth2()
+		o.Init()
+		o.SetRemote(true)
+		o.SetWidth(width)
+		o.State = OAuthStateWaitingForAuth
+		o.userCode = "ABCD-1234"
+		o.verificationURI = "https://github.com/login/device"
+
+		view := o.View()
+		require.Contains(t, view, "SSH session")
+		require.Contains(t, view, "ABCD-1234")
+		require.Contains(t, view, "code was copied")
+		for line := range strings.SplitSeq(view, "\n") {
+			require.LessOrEqual(t, lipgloss.Width(line), width, "line %q overflows width %d", line, width)
+		}
+	}
+}
+
+func TestOAuth2_ClipboardText(t *testing.T) {
+	t.Parallel()
+
+	o := NewOAuth2()
+	o.userCode = "ABCD-1234"
+	o.verificationURI = "https://github.com/login/device"
+	require.Equal(t, "ABCD-1234", o.clipboardText())
+
+	o.browserURL = "https://github.com/login/device?user_code=ABCD-1234"
+	require.Equal(t, o.browserURL, o.clipboardText())
+}
+
+func TestOAuth2_ValidatedTokenIsCached(t *testing.T) {
+	t.Parallel()
+
+	o := NewOAuth2()
+	o.Init()
+	o.ctx, o.cancelFunc = context.WithCancel(t.Context())
+	o.State = OAuthStateValidating
+
+	o.Update(ValidationCompletedMsg{
+		Token:        "gho_token",
+		CopilotToken: &copilot.CopilotToken{Token: "tid=abc", ExpiresAt: 1234},
+	})
+	require.Equal(t, OAuthStateSuccess, o.State)
+
+	token := o.Token()
+	require.Equal(t, "gho_token", token.RefreshToken)
+	require.Equal(t, "tid=abc", token.CopilotToken)
+	require.Equal(t, int64(1234), token.CopilotExpiresAt)
+}
+
+func TestOAuth2_ValidationFailure(t *testing.T) {
+	t.Parallel()
+
+	o := NewOAuth2()
+	o.Init()
+	o.ctx, o.cancelFunc = context.WithCancel(t.Context())
+	o.State = OAuthStateValidating
+
+	o.Update(ValidationCompletedMsg{Token: "gho_token", Error: copilot.ErrNoCopilotAccess})
+	require.Equal(t, OAuthStateError, o.State)
+	title, _, _ := o.errorGuidance()
+	require.Equal(t, "No Copilot subscription", title)
+}
@@ -0,0 +1,260 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/spinner"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	UsageDialogID dialogs.DialogID = "copilot_usage"
+
+	usageDialogWidth = 50
+	usageBarWidth    = 30
+)
+
+// usageFetchedMsg is sent when the usage request completes.
+type usageFetchedMsg struct {
+	usage *copilot.Usage
+	err   error
+}
+
+// UsageDialog shows the Copilot plan, entitlements and premium request usage.
+type UsageDialog interface {
+	dialogs.DialogModel
+}
+
+type usageDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	loading bool
+	usage   *copilot.Usage
+	err     error
+
+	// accessible renders plain text without a spinner, bar or border.
+	accessible bool
+
+	spinner spinner.Model
+	keyMap  UsageKeyMap
+	help    help.Model
+}
+
+// NewUsageDialog creates a dialog showing the Copilot usage of the logged in
+// user.
+func NewUsageDialog() UsageDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &usageDialogCmp{
+		width: usageDialogWidth,
+		spinner: spinner.New(
+			spinner.WithSpinner(spinner.Dot),
+			spinner.WithStyle(t.S().Base.Foreground(t.Green)),
+		),
+		keyMap:     DefaultUsageKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (u *usageDialogCmp) Init() tea.Cmd {
+	return tea.Batch(u.tick(), u.refresh())
+}
+
+// tick starts the spinner animation, unless running in accessible mode.
+func (u *usageDialogCmp) tick() tea.Cmd {
+	if u.accessible {
+		return nil
+	}
+	return u.spinner.Tick
+}
+
+func (u *usageDialogCmp) refresh() tea.Cmd {
+	u.loading = true
+	return func() tea.Msg {
+		pc, ok := config.Get().Providers.Get(copilot.ProviderID)
+		if !ok || pc.OAuthToken == nil {
+			return usageFetchedMsg{err: errors.New("not logged in to GitHub Copilot")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		usage, err := copilot.FetchUsage(ctx, pc.OAuthToken.RefreshToken)
+		return usageFetchedMsg{usage: usage, err: err}
+	}
+}
+
+func (u *usageDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		u.wWidth = msg.Width
+		u.wHeight = msg.Height
+		u.width = min(usageDialogWidth, u.wWidth-4)
+		u.help.SetWidth(u.width - 2)
+	case usageFetchedMsg:
+		u.loading = false
+		u.usage, u.err = msg.usage, msg.err
+	case pubsub.Event[oauth.TokenEvent]:
+		if msg.Payload.ProviderID != copilot.ProviderID {
+			break
+		}
+		switch msg.Payload.Type {
+		case oauth.TokenSaved:
+			// A new login, possibly to another account.
+			if !u.loading {
+				return u, tea.Batch(u.tick(), u.refresh())
+			}
+		case oauth.TokenInvalidated:
+			u.loading = false
+			u.usage, u.err = nil, msg.Payload.Error
+		}
+	case spinner.TickMsg:
+		if u.loading {
+			var cmd tea.Cmd
+			u.spinner, cmd = u.spinner.Update(msg)
+			return u, cmd
+		}
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, u.keyMap.Close):
+			return u, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, u.keyMap.Refresh) && !u.loading:
+			return u, tea.Batch(u.tick(), u.refresh())
+		}
+	}
+	return u, nil
+}
+
+func (u *usageDialogCmp) View() string {
+	if u.accessible {
+		return u.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+
+	var body string
+	switch {
+	case u.loading:
+		body = t.S().Base.PaddingLeft(1).Render(u.spinner.View() + " Fetching usage...")
+	case u.err != nil:
+		body = t.S().Base.Width(u.width - 4).PaddingLeft(1).Foreground(t.Error).Render(u.err.Error())
+	case u.usage != nil:
+		body = u.usageView()
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Copilot Usage", u.width-4)),
+		body,
+		"",
+		t.S().Base.Width(u.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(u.help.View(u.keyMap)),
+	)
+	return t.S().Base.
+		Width(u.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (u *usageDialogCmp) usageView() string {
+	t := styles.CurrentTheme()
+	row := func(label, value string) string {
+		return t.S().Base.PaddingLeft(1).Render(
+			t.S().Muted.Width(18).Render(label) + t.S().Text.Render(value),
+		)
+	}
+
+	chat := "disabled"
+	if u.usage.ChatEnabled {
+		chat = "enabled"
+	}
+	lines := []string{
+		row("Plan", string(u.usage.Plan)),
+		row("Chat", chat),
+	}
+
+	switch {
+	case u.usage.PremiumUnlimited:
+		lines = append(lines, row("Premium requests", "unlimited"))
+	case u.usage.PremiumQuota > 0:
+		lines = append(lines,
+			row("Premium requests", fmt.Sprintf("%d / %d", u.usage.PremiumUsed, u.usage.PremiumQuota)),
+			t.S().Base.PaddingLeft(1).Render(usageBar(u.usage.PremiumUsed, u.usage.PremiumQuota)),
+		)
+	default:
+		lines = append(lines, row("Premium requests", "not available"))
+	}
+	if u.usage.ResetDate != "" {
+		lines = append(lines, row("Resets on", u.usage.ResetDate))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// accessibleView renders the usage as plain text lines for screen readers.
+func (u *usageDialogCmp) accessibleView() string {
+	lines := []string{"Copilot Usage"}
+	switch {
+	case u.loading:
+		lines = append(lines, "Fetching usage.")
+	case u.err != nil:
+		lines = append(lines, "Error: "+u.err.Error())
+	case u.usage != nil:
+		chat := "disabled"
+		if u.usage.ChatEnabled {
+			chat = "enabled"
+		}
+		lines = append(lines, "Plan: "+string(u.usage.Plan), "Chat: "+chat)
+		switch {
+		case u.usage.PremiumUnlimited:
+			lines = append(lines, "Premium requests: unlimited")
+		case u.usage.PremiumQuota > 0:
+			lines = append(lines, fmt.Sprintf("Premium requests: %d of %d used", u.usage.PremiumUsed, u.usage.PremiumQuota))
+		default:
+			lines = append(lines, "Premium requests: not available")
+		}
+		if u.usage.ResetDate != "" {
+			lines = append(lines, "Resets on: "+u.usage.ResetDate)
+		}
+	}
+	lines = append(lines, "Press r to refresh, or esc to close.")
+	return lipgloss.NewStyle().Width(u.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+// usageBar renders a progress bar of used over quota.
+func usageBar(used, quota int) string {
+	t := styles.CurrentTheme()
+	filled := min(usageBarWidth, used*usageBarWidth/quota)
+	color := t.Green
+	if used >= quota {
+		color = t.Error
+	}
+	return t.S().Base.Foreground(color).Render(strings.Repeat("█", filled)) +
+		t.S().Subtle.Render(strings.Repeat("░", usageBarWidth-filled))
+}
+
+func (u *usageDialogCmp) Position() (int, int) {
+	row := u.wHeight/4 - 2 // just a bit above the center
+	col := u.wWidth/2 - u.width/2
+	return row, col
+}
+
+func (u *usageDialogCmp) ID() dialogs.DialogID {
+	return UsageDialogID
+}
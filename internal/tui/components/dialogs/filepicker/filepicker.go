@@ -47,7 +47,7 @@ type model struct {
 	help            help.Model
 }
 
-var AllowedTypes = []string{".jpg", ".jpeg", ".png"}
+var AllowedTypes = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
 
 func NewFilePickerCmp(workingDir string) FilePicker {
 	t := styles.CurrentTheme()
@@ -0,0 +1,159 @@
+package forks
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	CompareDialogID dialogs.DialogID = "compare_fork"
+
+	compareWidth = 100
+)
+
+// CompareDialog shows how a fork and the session it was forked from went
+// on, as a diff of their transcripts.
+type CompareDialog interface {
+	dialogs.DialogModel
+}
+
+type compareDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	title  string
+	lines  []string
+	offset int
+
+	keyMap CompareKeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewCompareDialog creates a dialog showing diff, the unified diff of the
+// transcripts of a fork and its original session.
+func NewCompareDialog(title, diff string) CompareDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &compareDialogCmp{
+		width:      compareWidth,
+		title:      title,
+		lines:      strings.Split(strings.TrimRight(diff, "\n"), "\n"),
+		keyMap:     DefaultCompareKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (c *compareDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (c *compareDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		c.wWidth = msg.Width
+		c.wHeight = msg.Height
+		c.width = min(compareWidth, c.wWidth-4)
+		c.help.SetWidth(c.width - 2)
+		c.scroll(0)
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, c.keyMap.Close):
+			return c, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, c.keyMap.Down):
+			c.scroll(1)
+		case key.Matches(msg, c.keyMap.Up):
+			c.scroll(-1)
+		case key.Matches(msg, c.keyMap.PageDown):
+			c.scroll(c.height())
+		case key.Matches(msg, c.keyMap.PageUp):
+			c.scroll(-c.height())
+		}
+	}
+	return c, nil
+}
+
+// height returns the number of lines of the diff shown at once.
+func (c *compareDialogCmp) height() int {
+	return max(5, min(len(c.lines), c.wHeight-10))
+}
+
+func (c *compareDialogCmp) scroll(n int) {
+	c.offset = max(0, min(c.offset+n, len(c.lines)-c.height()))
+}
+
+func (c *compareDialogCmp) View() string {
+	if c.accessible {
+		return c.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+	end := min(len(c.lines), c.offset+c.height())
+	lines := make([]string, 0, end-c.offset)
+	for _, line := range c.lines[c.offset:end] {
+		line = c.fit(line)
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+			line = t.S().Muted.Render(line)
+		case strings.HasPrefix(line, "+"):
+			line = t.S().Success.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = t.S().Error.Render(line)
+		default:
+			line = t.S().Text.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(c.title, c.width-4)),
+		t.S().Base.PaddingLeft(1).Render(lipgloss.JoinVertical(lipgloss.Left, lines...)),
+		"",
+		t.S().Base.Width(c.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(c.help.View(c.keyMap)),
+	)
+	return t.S().Base.
+		Width(c.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// accessibleView renders the diff as plain text for screen readers.
+func (c *compareDialogCmp) accessibleView() string {
+	end := min(len(c.lines), c.offset+c.height())
+	lines := append([]string{c.title}, c.lines[c.offset:end]...)
+	lines = append(lines, "Press up and down to scroll, or esc to close.")
+	return lipgloss.NewStyle().Width(c.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (c *compareDialogCmp) fit(s string) string {
+	w := c.width - 4
+	if lipgloss.Width(s) <= w {
+		return s
+	}
+	return string([]rune(s)[:max(0, w-1)]) + "…"
+}
+
+func (c *compareDialogCmp) Position() (int, int) {
+	row := max(1, c.wHeight/2-(c.height()+8)/2)
+	col := c.wWidth/2 - c.width/2
+	return row, col
+}
+
+func (c *compareDialogCmp) ID() dialogs.DialogID {
+	return CompareDialogID
+}
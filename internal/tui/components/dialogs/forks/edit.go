@@ -0,0 +1,173 @@
+package forks
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textarea"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	EditMessageDialogID dialogs.DialogID = "edit_message"
+
+	// editHeight is the number of lines of the message shown at once.
+	editHeight = 6
+)
+
+// EditMsg asks to regenerate the conversation of the session from the
+// message, with its text replaced by Text.
+type EditMsg struct {
+	SessionID string
+	MessageID string
+	Text      string
+}
+
+type editMessageDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	sessionID string
+	messageID string
+	branch    bool
+	textarea  textarea.Model
+
+	keyMap EditKeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewEditMessageDialog creates a dialog editing the text of msg, to
+// regenerate the conversation from it once sent.
+func NewEditMessageDialog(sessionID string, msg message.Message, branch bool) dialogs.DialogModel {
+	t := styles.CurrentTheme()
+	ta := textarea.New()
+	ta.SetStyles(t.S().TextArea)
+	ta.ShowLineNumbers = false
+	ta.CharLimit = -1
+	ta.SetVirtualCursor(false)
+	ta.SetHeight(editHeight)
+	ta.SetValue(msg.Content().Text)
+	ta.Focus()
+
+	h := help.New()
+	h.Styles = t.S().Help
+	return &editMessageDialogCmp{
+		width:      defaultWidth,
+		sessionID:  sessionID,
+		messageID:  msg.ID,
+		branch:     branch,
+		textarea:   ta,
+		keyMap:     DefaultEditKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (e *editMessageDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (e *editMessageDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		e.wWidth = msg.Width
+		e.wHeight = msg.Height
+		e.width = min(defaultWidth, e.wWidth-4)
+		e.textarea.SetWidth(e.width - 4)
+		e.help.SetWidth(e.width - 2)
+		return e, nil
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, e.keyMap.Close):
+			return e, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, e.keyMap.Newline):
+			e.textarea.InsertRune('\n')
+			return e, nil
+		case key.Matches(msg, e.keyMap.Send):
+			text := strings.TrimSpace(e.textarea.Value())
+			if text == "" {
+				return e, util.ReportWarn("The message can't be empty")
+			}
+			return e, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(EditMsg{SessionID: e.sessionID, MessageID: e.messageID, Text: text}),
+			)
+		}
+	}
+	var cmd tea.Cmd
+	e.textarea, cmd = e.textarea.Update(msg)
+	return e, cmd
+}
+
+func (e *editMessageDialogCmp) infoText() string {
+	if e.branch {
+		return "Sending continues in a new session from before this message."
+	}
+	return "Sending deletes this message and the ones after it."
+}
+
+func (e *editMessageDialogCmp) View() string {
+	if e.accessible {
+		lines := []string{
+			"Edit Message",
+			e.infoText(),
+			e.textarea.Value(),
+			"Press enter to send the message, or esc to close.",
+		}
+		return lipgloss.NewStyle().Width(e.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+	}
+
+	t := styles.CurrentTheme()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Edit Message", e.width-4)),
+		t.S().Base.Padding(0, 1, 1, 1).Render(t.S().Muted.Render(e.infoText())),
+		t.S().Base.PaddingLeft(1).Render(e.textarea.View()),
+		"",
+		t.S().Base.Width(e.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(e.help.View(e.keyMap)),
+	)
+	return t.S().Base.
+		Width(e.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// editHeaderHeight is the height of the border, title and info above the
+// message.
+const editHeaderHeight = 5
+
+func (e *editMessageDialogCmp) Cursor() *tea.Cursor {
+	if e.accessible {
+		return nil
+	}
+	cursor := e.textarea.Cursor()
+	if cursor == nil {
+		return nil
+	}
+	row, col := e.Position()
+	cursor.Y += row + editHeaderHeight
+	cursor.X += col + 2
+	return cursor
+}
+
+func (e *editMessageDialogCmp) Position() (int, int) {
+	row := e.wHeight/4 - 2 // just a bit above the center
+	col := e.wWidth/2 - e.width/2
+	return row, col
+}
+
+func (e *editMessageDialogCmp) ID() dialogs.DialogID {
+	return EditMessageDialogID
+}
@@ -0,0 +1,296 @@
+// Package forks holds the dialogs forking a session at one of its messages,
+// comparing a fork with the session it was forked from and editing a
+// previous message to regenerate the conversation from it.
+package forks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	ForkDialogID dialogs.DialogID = "fork"
+	EditDialogID dialogs.DialogID = "edit"
+
+	defaultWidth = 70
+	// maxVisible bounds the number of messages listed at once.
+	maxVisible = 12
+)
+
+// ForkMsg asks to fork the session at the message.
+type ForkMsg struct {
+	SessionID string
+	MessageID string
+}
+
+// ForkDialog lists the messages of a session, forking it at the selected
+// one.
+type ForkDialog interface {
+	dialogs.DialogModel
+}
+
+type messagesLoadedMsg struct {
+	messages []message.Message
+	err      error
+}
+
+type forkDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	service   message.Service
+	sessionID string
+	messages  []message.Message
+	loaded    bool
+	cursor    int
+
+	keyMap KeyMap
+	help   help.Model
+
+	// edit lists the prompts only, to edit one of them instead of forking.
+	edit bool
+	// branch tells whether editing a prompt forks the session.
+	branch bool
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewForkDialog creates a dialog listing the messages of the session to
+// fork it at one of them.
+func NewForkDialog(service message.Service, sessionID string) ForkDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &forkDialogCmp{
+		width:      defaultWidth,
+		service:    service,
+		sessionID:  sessionID,
+		keyMap:     DefaultKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+// NewEditDialog creates a dialog listing the prompts of the session to edit
+// one of them and regenerate the conversation from it, forking the session
+// when branch is set.
+func NewEditDialog(service message.Service, sessionID string, branch bool) ForkDialog {
+	f := NewForkDialog(service, sessionID).(*forkDialogCmp)
+	f.edit = true
+	f.branch = branch
+	f.keyMap.Select.SetHelp("enter", "edit")
+	return f
+}
+
+func (f *forkDialogCmp) Init() tea.Cmd {
+	return func() tea.Msg {
+		messages, err := f.service.List(context.Background(), f.sessionID)
+		return messagesLoadedMsg{messages: messages, err: err}
+	}
+}
+
+func (f *forkDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		f.wWidth = msg.Width
+		f.wHeight = msg.Height
+		f.width = min(defaultWidth, f.wWidth-4)
+		f.help.SetWidth(f.width - 2)
+	case messagesLoadedMsg:
+		if msg.err != nil {
+			return f, tea.Batch(util.CmdHandler(dialogs.CloseDialogMsg{}), util.ReportError(msg.err))
+		}
+		for _, m := range msg.messages {
+			if f.label(m) != "" {
+				f.messages = append(f.messages, m)
+			}
+		}
+		// Forking at the latest message is the most common.
+		f.cursor = max(0, len(f.messages)-1)
+		f.loaded = true
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, f.keyMap.Close):
+			return f, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case len(f.messages) == 0:
+			return f, nil
+		case key.Matches(msg, f.keyMap.Next):
+			f.cursor = (f.cursor + 1) % len(f.messages)
+		case key.Matches(msg, f.keyMap.Previous):
+			f.cursor = (f.cursor - 1 + len(f.messages)) % len(f.messages)
+		case key.Matches(msg, f.keyMap.Select) && f.edit:
+			return f, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(dialogs.OpenDialogMsg{
+					Model: NewEditMessageDialog(f.sessionID, f.messages[f.cursor], f.branch),
+				}),
+			)
+		case key.Matches(msg, f.keyMap.Select):
+			return f, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(ForkMsg{SessionID: f.sessionID, MessageID: f.messages[f.cursor].ID}),
+			)
+		}
+	}
+	return f, nil
+}
+
+// visible returns the range of messages shown, keeping the cursor in it.
+func (f *forkDialogCmp) visible() (int, int) {
+	start := max(0, min(f.cursor-maxVisible/2, len(f.messages)-maxVisible))
+	return start, min(len(f.messages), start+maxVisible)
+}
+
+// label describes msg in a line, or returns an empty string for the
+// messages a session can't be forked at, or edited.
+func (f *forkDialogCmp) label(msg message.Message) string {
+	if f.edit && msg.Role != message.User {
+		return ""
+	}
+	var who, what string
+	switch msg.Role {
+	case message.User:
+		who, what = "You", msg.Content().Text
+	case message.Assistant:
+		who, what = "Crush", msg.Content().Text
+		if strings.TrimSpace(what) == "" {
+			var names []string
+			for _, call := range msg.ToolCalls() {
+				names = append(names, call.Name)
+			}
+			if len(names) == 0 {
+				return ""
+			}
+			what = "called " + strings.Join(names, ", ")
+		}
+	default:
+		return ""
+	}
+	what, _, _ = strings.Cut(strings.TrimSpace(what), "\n")
+	return fmt.Sprintf("%s %-5s %s", time.Unix(msg.CreatedAt, 0).Format("15:04"), who, what)
+}
+
+func (f *forkDialogCmp) View() string {
+	if f.accessible {
+		return f.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+
+	var body string
+	switch {
+	case !f.loaded:
+		body = t.S().Muted.PaddingLeft(1).Render("Loading messages…")
+	case len(f.messages) == 0:
+		body = t.S().Muted.PaddingLeft(1).Render(f.emptyText())
+	default:
+		start, end := f.visible()
+		lines := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			label := f.label(f.messages[i])
+			if i == f.cursor {
+				lines = append(lines, t.S().TextSelected.Width(f.width-2).Padding(0, 1).Render(f.fit(label)))
+				continue
+			}
+			lines = append(lines, t.S().Text.Padding(0, 1).Render(f.fit(label)))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	info := t.S().Muted.Render(f.infoText())
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(f.title(), f.width-4)),
+		t.S().Base.Padding(0, 1, 1, 1).Render(info),
+		body,
+		"",
+		t.S().Base.Width(f.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(f.help.View(f.keyMap)),
+	)
+	return t.S().Base.
+		Width(f.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// accessibleView renders the messages as plain text lines for screen
+// readers, marking the selected one with a leading ">".
+func (f *forkDialogCmp) accessibleView() string {
+	lines := []string{f.title()}
+	switch {
+	case !f.loaded:
+		lines = append(lines, "Loading messages.")
+	case len(f.messages) == 0:
+		lines = append(lines, f.emptyText())
+	}
+	start, end := f.visible()
+	for i := start; i < end; i++ {
+		prefix := "  "
+		if i == f.cursor {
+			prefix = "> "
+		}
+		lines = append(lines, prefix+f.label(f.messages[i]))
+	}
+	if f.edit {
+		lines = append(lines, "Press enter to edit a message, or esc to close.")
+	} else {
+		lines = append(lines, "Press enter to continue in a new session from a message, or esc to close.")
+	}
+	return lipgloss.NewStyle().Width(f.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (f *forkDialogCmp) title() string {
+	if f.edit {
+		return "Edit Message"
+	}
+	return "Fork Session"
+}
+
+func (f *forkDialogCmp) infoText() string {
+	if f.edit {
+		return "Edit the selected message to regenerate the conversation from it."
+	}
+	return "Continue in a new session from the selected message."
+}
+
+func (f *forkDialogCmp) emptyText() string {
+	if f.edit {
+		return "No messages to edit yet."
+	}
+	return "No messages to fork at yet."
+}
+
+func (f *forkDialogCmp) fit(s string) string {
+	w := f.width - 4
+	if lipgloss.Width(s) <= w {
+		return s
+	}
+	return string([]rune(s)[:max(0, w-1)]) + "…"
+}
+
+func (f *forkDialogCmp) Position() (int, int) {
+	row := f.wHeight/4 - 2 // just a bit above the center
+	col := f.wWidth/2 - f.width/2
+	return row, col
+}
+
+func (f *forkDialogCmp) ID() dialogs.DialogID {
+	if f.edit {
+		return EditDialogID
+	}
+	return ForkDialogID
+}
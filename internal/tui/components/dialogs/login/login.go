@@ -0,0 +1,334 @@
+package login
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	claudedialog "github.com/charmbracelet/crush/internal/tui/components/dialogs/claude"
+	copilotdialog "github.com/charmbracelet/crush/internal/tui/components/dialogs/copilot"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	LoginDialogID dialogs.DialogID = "login"
+
+	defaultWidth = 60
+)
+
+// RequiresLogin reports whether err means the user has to log in to a
+// provider again, and returns that provider's ID.
+func RequiresLogin(err error) (string, bool) {
+	if copilot.RequiresReauth(err) {
+		return copilot.ProviderID, true
+	}
+	return "", false
+}
+
+// LoginDialog lets the user log in to any OAuth-capable provider, running
+// the device or PKCE flow the provider requires.
+type LoginDialog interface {
+	dialogs.DialogModel
+}
+
+type loginDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	providers []oauth.Provider
+	cursor    int
+
+	// selected is the provider whose flow is running, nil while picking.
+	selected *oauth.Provider
+	// picked is true when the flow was started from the picker, so esc
+	// returns to it instead of closing the dialog.
+	picked bool
+	reason error
+
+	deviceFlow *copilotdialog.OAuth2
+	pkceFlow   *claudedialog.OAuth2
+
+	keyMap KeyMap
+	help   help.Model
+}
+
+// NewLoginDialog creates a login dialog listing every registered OAuth
+// provider.
+func NewLoginDialog() LoginDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &loginDialogCmp{
+		width:     defaultWidth,
+		providers: oauth.Providers(),
+		keyMap:    DefaultKeyMap(),
+		help:      h,
+	}
+}
+
+// NewLoginDialogFor creates a login dialog that goes straight to the flow of
+// the given provider. The reason, if any, is shown to explain why the user
+// needs to log in again.
+func NewLoginDialogFor(providerID string, reason error) LoginDialog {
+	l := NewLoginDialog().(*loginDialogCmp)
+	if p, ok := oauth.LookupProvider(providerID); ok {
+		l.selected = &p
+	}
+	l.reason = reason
+	return l
+}
+
+func (l *loginDialogCmp) Init() tea.Cmd {
+	if l.selected != nil {
+		return l.startFlow()
+	}
+	return nil
+}
+
+func (l *loginDialogCmp) startFlow() tea.Cmd {
+	switch l.selected.Flow {
+	case oauth.FlowDeviceCode:
+		l.deviceFlow = copilotdialog.NewOAuth2()
+		l.deviceFlow.SetWidth(l.width - 2)
+		return l.deviceFlow.StartFlow()
+	case oauth.FlowPKCE:
+		l.pkceFlow = claudedialog.NewOAuth2()
+		cmd := l.pkceFlow.Init()
+		l.pkceFlow.SetWidth(l.width - 2)
+		return cmd
+	}
+	return util.ReportError(fmt.Errorf("unsupported OAuth flow %q for %s", l.selected.Flow, l.selected.Name))
+}
+
+// stopFlow cancels the running flow and returns to the provider picker.
+func (l *loginDialogCmp) stopFlow() {
+	if l.deviceFlow != nil {
+		l.deviceFlow.Cancel()
+	}
+	l.deviceFlow = nil
+	l.pkceFlow = nil
+	l.selected = nil
+	l.reason = nil
+}
+
+func (l *loginDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		l.wWidth = msg.Width
+		l.wHeight = msg.Height
+		l.width = min(defaultWidth, l.wWidth-4)
+		l.help.SetWidth(l.width - 2)
+		return l, nil
+	case tea.KeyPressMsg:
+		return l.handleKeyPress(msg)
+	case tea.PasteMsg:
+		if l.pkceFlow != nil {
+			_, cmd := l.pkceFlow.Update(msg)
+			return l, cmd
+		}
+		return l, nil
+	case loggedInMsg:
+		return l, tea.Sequence(
+			util.CmdHandler(dialogs.CloseDialogMsg{}),
+			util.ReportInfo(fmt.Sprintf("Logged in to %s as %s", msg.provider.Name, msg.account)),
+		)
+	case claudedialog.ValidationCompletedMsg:
+		if l.pkceFlow == nil {
+			return l, nil
+		}
+		_, cmd := l.pkceFlow.Update(msg)
+		if msg.State == claudedialog.OAuthValidationStateValid {
+			return l, tea.Batch(cmd, l.saveToken(msg.Token))
+		}
+		return l, cmd
+	}
+
+	if l.deviceFlow == nil {
+		return l, nil
+	}
+	_, cmd := l.deviceFlow.Update(msg)
+	if _, ok := msg.(copilotdialog.ValidationCompletedMsg); ok && l.deviceFlow.State == copilotdialog.OAuthStateSuccess {
+		return l, tea.Batch(cmd, l.saveToken(l.deviceFlow.Token()))
+	}
+	return l, cmd
+}
+
+func (l *loginDialogCmp) handleKeyPress(msg tea.KeyPressMsg) (util.Model, tea.Cmd) {
+	if key.Matches(msg, l.keyMap.Close) {
+		if l.selected != nil && l.picked {
+			l.stopFlow()
+			return l, nil
+		}
+		return l, util.CmdHandler(dialogs.CloseDialogMsg{})
+	}
+
+	switch {
+	case l.selected == nil:
+		switch {
+		case key.Matches(msg, l.keyMap.Next) && len(l.providers) > 0:
+			l.cursor = (l.cursor + 1) % len(l.providers)
+		case key.Matches(msg, l.keyMap.Previous) && len(l.providers) > 0:
+			l.cursor = (l.cursor - 1 + len(l.providers)) % len(l.providers)
+		case key.Matches(msg, l.keyMap.Select) && len(l.providers) > 0:
+			l.selected = &l.providers[l.cursor]
+			l.picked = true
+			return l, l.startFlow()
+		}
+		return l, nil
+	case l.deviceFlow != nil:
+		if key.Matches(msg, l.keyMap.Select) {
+			_, cmd := l.deviceFlow.ValidationConfirm()
+			return l, cmd
+		}
+		return l, nil
+	case l.pkceFlow != nil:
+		if key.Matches(msg, l.keyMap.Select) {
+			_, cmd := l.pkceFlow.ValidationConfirm()
+			return l, cmd
+		}
+		_, cmd := l.pkceFlow.Update(msg)
+		return l, cmd
+	}
+	return l, nil
+}
+
+// loggedInMsg is sent once the token has been saved.
+type loggedInMsg struct {
+	provider oauth.Provider
+	account  string
+}
+
+func (l *loginDialogCmp) saveToken(token *oauth.Token) tea.Cmd {
+	if l.selected == nil || token == nil {
+		return nil
+	}
+	provider := *l.selected
+	return func() tea.Msg {
+		cfg := config.Get()
+		account := accountName(provider, token)
+		if err := cfg.SetProviderAPIKey(provider.ID, token); err != nil {
+			return util.InfoMsg{
+				Type: util.InfoTypeError,
+				Msg:  fmt.Sprintf("failed to save %s token: %v", provider.Name, err),
+			}
+		}
+		if err := cfg.SaveProviderAccount(provider.ID, account); err != nil {
+			slog.Warn("Failed to save provider account", "provider", provider.ID, "error", err)
+		}
+		return loggedInMsg{provider: provider, account: account}
+	}
+}
+
+// accountName picks the name the new credentials are stored under: the
+// identity behind the token when the provider exposes it, otherwise the
+// active account, which is being re-authenticated.
+func accountName(provider oauth.Provider, token *oauth.Token) string {
+	if provider.ID == copilot.ProviderID {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if name, err := copilot.FetchUsername(ctx, token.RefreshToken); err == nil && name != "" {
+			return name
+		}
+	}
+	if pc, ok := config.Get().Providers.Get(provider.ID); ok && pc.ActiveAccount != "" {
+		return pc.ActiveAccount
+	}
+	return "default"
+}
+
+// Close implements dialogs.CloseCallback.
+func (l *loginDialogCmp) Close() tea.Cmd {
+	if l.deviceFlow != nil {
+		l.deviceFlow.Cancel()
+	}
+	return nil
+}
+
+func (l *loginDialogCmp) View() string {
+	t := styles.CurrentTheme()
+
+	title := "Log in to a Provider"
+	var body string
+	switch {
+	case l.selected == nil:
+		body = l.providerList()
+	case l.deviceFlow != nil:
+		title = "Log in to " + l.selected.Name
+		body = l.deviceFlow.View()
+	case l.pkceFlow != nil:
+		title = "Log in to " + l.selected.Name
+		body = l.pkceFlow.View()
+	}
+	if l.reason != nil {
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			t.S().Base.Width(l.width-4).Margin(0, 1, 1, 1).Foreground(t.Error).Render(l.reason.Error()),
+			body,
+		)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(title, l.width-4)),
+		body,
+		"",
+		t.S().Base.Width(l.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(l.help.View(l.keyMap)),
+	)
+	return t.S().Base.
+		Width(l.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (l *loginDialogCmp) providerList() string {
+	t := styles.CurrentTheme()
+	if len(l.providers) == 0 {
+		return t.S().Muted.PaddingLeft(1).Render("No OAuth providers available")
+	}
+	lines := make([]string, 0, len(l.providers))
+	for i, p := range l.providers {
+		if i == l.cursor {
+			lines = append(lines, t.S().TextSelected.Width(l.width-2).Padding(0, 1).Render(p.Name))
+			continue
+		}
+		lines = append(lines, t.S().Text.Padding(0, 1).Render(p.Name))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (l *loginDialogCmp) Cursor() *tea.Cursor {
+	if l.pkceFlow == nil || l.reason != nil {
+		return nil
+	}
+	cursor := l.pkceFlow.CodeInput.Cursor()
+	if cursor == nil {
+		return nil
+	}
+	row, col := l.Position()
+	cursor.Y += row + 5 // Border + title + code input heading
+	cursor.X += col + 2
+	return cursor
+}
+
+func (l *loginDialogCmp) Position() (int, int) {
+	row := l.wHeight/4 - 2 // just a bit above the center
+	col := l.wWidth/2 - l.width/2
+	return row, col
+}
+
+func (l *loginDialogCmp) ID() dialogs.DialogID {
+	return LoginDialogID
+}
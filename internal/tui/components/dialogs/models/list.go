@@ -21,6 +21,8 @@ type ModelListComponent struct {
 	list      listModel
 	modelType int
 	providers []catwalk.Provider
+	// providerFilter restricts the list to a single provider when set.
+	providerFilter string
 }
 
 func modelKey(providerID, modelID string) string {
@@ -58,29 +60,8 @@ func NewModelListComponent(keyMap list.KeyMap, inputPlaceholder string, shouldRe
 func (m *ModelListComponent) Init() tea.Cmd {
 	var cmds []tea.Cmd
 	if len(m.providers) == 0 {
-		cfg := config.Get()
-		providers, err := config.Providers(cfg)
-		filteredProviders := []catwalk.Provider{}
-		for _, p := range providers {
-			hasAPIKeyEnv := strings.HasPrefix(p.APIKey, "$")
-			if hasAPIKeyEnv && p.ID != catwalk.InferenceProviderAzure {
-				filteredProviders = append(filteredProviders, p)
-			}
-		}
-
-		// Add GitHub Copilot provider (uses OAuth device flow, not env var API key).
-		copilotProvider := catwalk.Provider{
-			Name:                "GitHub Copilot",
-			ID:                  "github-copilot",
-			APIKey:              "$CRUSH_GITHUB_COPILOT_TOKEN",
-			Type:                catwalk.TypeOpenAICompat,
-			DefaultLargeModelID: "gpt-4.1",
-			DefaultSmallModelID: "gpt-4o",
-			Models:              copilot.DefaultModels(),
-		}
-		filteredProviders = append(filteredProviders, copilotProvider)
-
-		m.providers = filteredProviders
+		providers, err := selectableProviders()
+		m.providers = providers
 		if err != nil {
 			cmds = append(cmds, util.ReportError(err))
 		}
@@ -89,6 +70,32 @@ func (m *ModelListComponent) Init() tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// selectableProviders returns the known providers a user can pick models
+// from: the ones configured through an API key environment variable, plus
+// GitHub Copilot.
+func selectableProviders() ([]catwalk.Provider, error) {
+	providers, err := config.Providers(config.Get())
+	filteredProviders := []catwalk.Provider{}
+	for _, p := range providers {
+		hasAPIKeyEnv := strings.HasPrefix(p.APIKey, "$")
+		if hasAPIKeyEnv && p.ID != catwalk.InferenceProviderAzure {
+			filteredProviders = append(filteredProviders, p)
+		}
+	}
+
+	// Add GitHub Copilot provider (uses OAuth device flow, not env var API key).
+	copilotProvider := catwalk.Provider{
+		Name:                "GitHub Copilot",
+		ID:                  "github-copilot",
+		APIKey:              "$CRUSH_GITHUB_COPILOT_TOKEN",
+		Type:                catwalk.TypeOpenAICompat,
+		DefaultLargeModelID: "gpt-4.1",
+		DefaultSmallModelID: "gpt-4o",
+		Models:              copilot.DefaultModels(),
+	}
+	return append(filteredProviders, copilotProvider), err
+}
+
 func (m *ModelListComponent) Update(msg tea.Msg) (*ModelListComponent, tea.Cmd) {
 	u, cmd := m.list.Update(msg)
 	m.list = u.(listModel)
@@ -151,7 +158,7 @@ func (m *ModelListComponent) SetModelType(modelType int) tea.Cmd {
 		return util.ReportError(err)
 	}
 	for providerID, providerConfig := range cfg.Providers.Seq2() {
-		if providerConfig.Disable {
+		if providerConfig.Disable || !m.matchesFilter(providerID) {
 			continue
 		}
 
@@ -220,7 +227,7 @@ func (m *ModelListComponent) SetModelType(modelType int) tea.Cmd {
 	// Then add the known providers from the predefined list
 	for _, provider := range m.providers {
 		// Skip if we already added this provider as an unknown provider
-		if addedProviders[string(provider.ID)] {
+		if addedProviders[string(provider.ID)] || !m.matchesFilter(string(provider.ID)) {
 			continue
 		}
 
@@ -286,7 +293,7 @@ func (m *ModelListComponent) SetModelType(modelType int) tea.Cmd {
 		groups = append(groups, group)
 	}
 
-	if len(recentItems) > 0 {
+	if len(recentItems) > 0 && m.providerFilter == "" {
 		recentSection := list.NewItemSection("Recently used")
 		recentGroup := list.Group[list.CompletionItem[ModelOption]]{
 			Section: recentSection,
@@ -343,6 +350,34 @@ func (m *ModelListComponent) SetModelType(modelType int) tea.Cmd {
 	return tea.Sequence(cmds...)
 }
 
+// SetProviderFilter restricts the list to the models of a single provider.
+// An empty ID lists every provider again.
+func (m *ModelListComponent) SetProviderFilter(providerID string) tea.Cmd {
+	m.providerFilter = providerID
+	return m.SetModelType(m.modelType)
+}
+
+func (m *ModelListComponent) matchesFilter(providerID string) bool {
+	return m.providerFilter == "" || m.providerFilter == providerID
+}
+
+// SetProviderModels replaces the models listed for a known provider, e.g.
+// with a list fetched from the provider after authenticating. It takes effect
+// the next time the list is rebuilt.
+func (m *ModelListComponent) SetProviderModels(providerID string, models []catwalk.Model) {
+	for i, p := range m.providers {
+		if string(p.ID) == providerID {
+			m.providers[i].Models = models
+			return
+		}
+	}
+}
+
+// SelectModel moves the selection to the given model.
+func (m *ModelListComponent) SelectModel(providerID, modelID string) tea.Cmd {
+	return m.list.SetSelected(modelKey(providerID, modelID))
+}
+
 // GetModelType returns the current model type
 func (m *ModelListComponent) GetModelType() int {
 	return m.modelType
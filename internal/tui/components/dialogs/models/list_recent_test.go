@@ -367,3 +367,41 @@ func TestModelList_AllRecentsInvalid(t *testing.T) {
 	require.True(t, ok, "large key should be nil or array")
 	require.Empty(t, largeAny, "persisted recents should be empty after pruning all invalid entries")
 }
+
+func TestModelList_ProviderFilter(t *testing.T) {
+	// Pre-initialize logger to os.DevNull to prevent file lock on Windows.
+	log.Setup(os.DevNull, false)
+
+	cfgDir := t.TempDir()
+	dataDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgDir)
+	t.Setenv("XDG_DATA_HOME", dataDir)
+
+	confPath := filepath.Join(cfgDir, "crush", "crush.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(confPath), 0o755))
+	require.NoError(t, os.WriteFile(confPath, []byte(`{"options":{"disable_provider_auto_update":true}}`), 0o644))
+	dataConfDir := filepath.Join(dataDir, "crush")
+	require.NoError(t, os.MkdirAll(dataConfDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dataConfDir, "providers.json"), []byte("[]"), 0o644))
+
+	_, err := config.Init(cfgDir, dataDir, false)
+	require.NoError(t, err)
+
+	cmp := NewModelListComponent(list.DefaultKeyMap(), "Find your fave", false)
+	cmp.providers = []catwalk.Provider{
+		{ID: "p1", Name: "Provider One", Models: []catwalk.Model{{ID: "m1", Name: "Model One"}}},
+		{ID: "p2", Name: "Provider Two", Models: []catwalk.Model{{ID: "m2", Name: "Model Two"}}},
+	}
+	execCmdML(t, cmp, cmp.Init())
+	require.Len(t, cmp.list.Groups(), 2)
+
+	cmp.SetProviderModels("p2", []catwalk.Model{{ID: "m3", Name: "Model Three"}})
+	execCmdML(t, cmp, cmp.SetProviderFilter("p2"))
+	groups := cmp.list.Groups()
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Items, 1)
+	require.Equal(t, "p2:m3", groups[0].Items[0].ID())
+
+	execCmdML(t, cmp, cmp.SetProviderFilter(""))
+	require.Len(t, cmp.list.Groups(), 2)
+}
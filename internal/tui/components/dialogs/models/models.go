@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -43,6 +44,12 @@ type ModelSelectedMsg struct {
 // CloseModelDialogMsg is sent when a model is selected
 type CloseModelDialogMsg struct{}
 
+// previewModelsToggledMsg is sent once the preview models setting has been
+// persisted and the affected model lists have been refreshed.
+type previewModelsToggledMsg struct {
+	Enabled bool
+}
+
 // ModelDialog interface for the model selection dialog
 type ModelDialog interface {
 	dialogs.DialogModel
@@ -141,6 +148,15 @@ func (m *modelDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 	case claude.AuthenticationCompleteMsg:
 		return m, util.CmdHandler(dialogs.CloseDialogMsg{})
+	case previewModelsToggledMsg:
+		info := "Preview models hidden"
+		if msg.Enabled {
+			info = "Preview models shown"
+		}
+		return m, tea.Batch(
+			m.modelList.SetModelType(m.modelList.GetModelType()),
+			util.ReportInfo(info),
+		)
 	case tea.KeyPressMsg:
 		switch {
 		case key.Matches(msg, key.NewBinding(key.WithKeys("c", "C"))):
@@ -276,6 +292,8 @@ func (m *modelDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				m.modelList.SetInputPlaceholder(largeModelInputPlaceholder)
 				return m, m.modelList.SetModelType(LargeModelType)
 			}
+		case key.Matches(msg, m.keyMap.TogglePreview) && !m.needsAPIKey && !m.showClaudeAuthMethodChooser && !m.showClaudeOAuth2:
+			return m, m.togglePreviewModels()
 		case key.Matches(msg, m.keyMap.Close):
 			if m.showClaudeAuthMethodChooser {
 				m.claudeAuthMethodChooser.SetDefaults()
@@ -477,6 +495,20 @@ func (m *modelDialogCmp) modelTypeRadio() string {
 	return t.S().Base.Foreground(t.FgHalfMuted).Render(iconUnselected + " " + choices[0] + "  " + iconSelected + " " + choices[1])
 }
 
+func (m *modelDialogCmp) togglePreviewModels() tea.Cmd {
+	cfg := config.Get()
+	enabled := cfg.Options == nil || !cfg.Options.PreviewModels
+	return func() tea.Msg {
+		if err := cfg.SetPreviewModels(context.Background(), enabled); err != nil {
+			return util.InfoMsg{
+				Type: util.InfoTypeError,
+				Msg:  fmt.Sprintf("failed to update preview models: %v", err),
+			}
+		}
+		return previewModelsToggledMsg{Enabled: enabled}
+	}
+}
+
 func (m *modelDialogCmp) isProviderConfigured(providerID string) bool {
 	cfg := config.Get()
 	if _, ok := cfg.Providers.Get(providerID); ok {
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+type providerListModel = list.FilterableList[list.CompletionItem[catwalk.Provider]]
+
+// ProviderListComponent is a filterable list of the providers a user can
+// set up.
+type ProviderListComponent struct {
+	list providerListModel
+}
+
+func NewProviderListComponent(keyMap list.KeyMap, inputPlaceholder string) *ProviderListComponent {
+	t := styles.CurrentTheme()
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	providerList := list.NewFilterableList(
+		[]list.CompletionItem[catwalk.Provider]{},
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterPlaceholder(inputPlaceholder),
+		list.WithFilterListOptions(
+			list.WithKeyMap(keyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	return &ProviderListComponent{
+		list: providerList,
+	}
+}
+
+func (p *ProviderListComponent) Init() tea.Cmd {
+	providers, err := selectableProviders()
+	cmds := []tea.Cmd{p.list.Init(), p.setProviders(providers)}
+	if err != nil {
+		cmds = append(cmds, util.ReportError(err))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (p *ProviderListComponent) setProviders(known []catwalk.Provider) tea.Cmd {
+	t := styles.CurrentTheme()
+	cfg := config.Get()
+
+	providers := slices.Clone(known)
+	// Custom providers only exist in the config.
+	for providerID, providerConfig := range cfg.Providers.Seq2() {
+		if providerConfig.Disable || slices.ContainsFunc(providers, func(p catwalk.Provider) bool { return string(p.ID) == providerID }) {
+			continue
+		}
+		providers = append(providers, catwalk.Provider{
+			Name:   cmp.Or(providerConfig.Name, providerID),
+			ID:     catwalk.InferenceProvider(providerID),
+			Models: slices.Clone(providerConfig.Models),
+		})
+	}
+
+	configuredIcon := t.S().Base.Foreground(t.Success).Render(styles.CheckIcon)
+	configured := fmt.Sprintf("%s %s", configuredIcon, t.S().Subtle.Render("Configured"))
+
+	items := make([]list.CompletionItem[catwalk.Provider], 0, len(providers))
+	for _, provider := range providers {
+		opts := []list.CompletionItemOption{
+			list.WithCompletionID(string(provider.ID)),
+		}
+		if _, ok := cfg.Providers.Get(string(provider.ID)); ok {
+			opts = append(opts, list.WithCompletionShortcut(configured))
+		}
+		items = append(items, list.NewCompletionItem(cmp.Or(provider.Name, string(provider.ID)), provider, opts...))
+	}
+	return p.list.SetItems(items)
+}
+
+func (p *ProviderListComponent) Update(msg tea.Msg) (*ProviderListComponent, tea.Cmd) {
+	u, cmd := p.list.Update(msg)
+	p.list = u.(providerListModel)
+	return p, cmd
+}
+
+func (p *ProviderListComponent) View() string {
+	return p.list.View()
+}
+
+func (p *ProviderListComponent) Cursor() *tea.Cursor {
+	return p.list.Cursor()
+}
+
+func (p *ProviderListComponent) SetSize(width, height int) tea.Cmd {
+	return p.list.SetSize(width, height)
+}
+
+// SelectedProvider returns the highlighted provider, if any.
+func (p *ProviderListComponent) SelectedProvider() *catwalk.Provider {
+	s := p.list.SelectedItem()
+	if s == nil {
+		return nil
+	}
+	provider := (*s).Value()
+	return &provider
+}
@@ -11,6 +11,7 @@ type KeyMap struct {
 	Select,
 	Allow,
 	AllowSession,
+	AllowCommandPrefix,
 	Deny,
 	ToggleDiffMode,
 	ScrollDown,
@@ -41,6 +42,10 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("s", "S", "ctrl+s"),
 			key.WithHelp("s", "allow session"),
 		),
+		AllowCommandPrefix: key.NewBinding(
+			key.WithKeys("w", "W"),
+			key.WithHelp("w", "always allow command"),
+		),
 		Deny: key.NewBinding(
 			key.WithKeys("d", "D", "esc"),
 			key.WithHelp("d", "deny"),
@@ -81,6 +86,7 @@ func (k KeyMap) KeyBindings() []key.Binding {
 		k.Select,
 		k.Allow,
 		k.AllowSession,
+		k.AllowCommandPrefix,
 		k.Deny,
 		k.ToggleDiffMode,
 		k.ScrollDown,
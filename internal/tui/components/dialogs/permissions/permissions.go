@@ -26,7 +26,10 @@ type PermissionAction string
 const (
 	PermissionAllow           PermissionAction = "allow"
 	PermissionAllowForSession PermissionAction = "allow_session"
-	PermissionDeny            PermissionAction = "deny"
+	// PermissionAllowCommandPrefix always allows, in the project, the commands
+	// starting like the requested one.
+	PermissionAllowCommandPrefix PermissionAction = "allow_command_prefix"
+	PermissionDeny               PermissionAction = "deny"
 
 	PermissionsDialogID dialogs.DialogID = "permissions"
 )
@@ -50,7 +53,9 @@ type permissionDialogCmp struct {
 	height          int
 	permission      permission.PermissionRequest
 	contentViewPort viewport.Model
-	selectedOption  int // 0: Allow, 1: Allow for session, 2: Deny
+	selectedOption  int // index in options()
+	// commandPrefix is the prefix of the command that can be always allowed.
+	commandPrefix string
 
 	// Diff view state
 	defaultDiffSplitMode bool  // true for split, false for unified
@@ -70,23 +75,36 @@ type permissionDialogCmp struct {
 	keyMap KeyMap
 }
 
-func NewPermissionDialogCmp(permission permission.PermissionRequest, opts *Options) PermissionDialogCmp {
+func NewPermissionDialogCmp(request permission.PermissionRequest, opts *Options) PermissionDialogCmp {
 	if opts == nil {
 		opts = &Options{}
 	}
 
 	// Create viewport for content
 	contentViewport := viewport.New()
+	commandPrefix, _ := permission.CommandPrefix(request.Command)
 	return &permissionDialogCmp{
 		contentViewPort: contentViewport,
 		selectedOption:  0, // Default to "Allow"
-		permission:      permission,
+		permission:      request,
+		commandPrefix:   commandPrefix,
 		diffSplitMode:   opts.isSplitMode(),
 		keyMap:          DefaultKeyMap(),
 		contentDirty:    true, // Mark as dirty initially
 	}
 }
 
+// options returns the choices offered to the user, in order.
+func (p *permissionDialogCmp) options() []PermissionAction {
+	if p.permission.Confirmation {
+		return []PermissionAction{PermissionAllow, PermissionDeny}
+	}
+	if p.commandPrefix == "" {
+		return []PermissionAction{PermissionAllow, PermissionAllowForSession, PermissionDeny}
+	}
+	return []PermissionAction{PermissionAllow, PermissionAllowForSession, PermissionAllowCommandPrefix, PermissionDeny}
+}
+
 func (p *permissionDialogCmp) Init() tea.Cmd {
 	return p.contentViewPort.Init()
 }
@@ -108,10 +126,11 @@ func (p *permissionDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	case tea.KeyPressMsg:
 		switch {
 		case key.Matches(msg, p.keyMap.Right) || key.Matches(msg, p.keyMap.Tab):
-			p.selectedOption = (p.selectedOption + 1) % 3
+			p.selectedOption = (p.selectedOption + 1) % len(p.options())
 			return p, nil
 		case key.Matches(msg, p.keyMap.Left):
-			p.selectedOption = (p.selectedOption + 2) % 3
+			n := len(p.options())
+			p.selectedOption = (p.selectedOption + n - 1) % n
 		case key.Matches(msg, p.keyMap.Select):
 			return p, p.selectCurrentOption()
 		case key.Matches(msg, p.keyMap.Allow):
@@ -119,11 +138,16 @@ func (p *permissionDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				util.CmdHandler(dialogs.CloseDialogMsg{}),
 				util.CmdHandler(PermissionResponseMsg{Action: PermissionAllow, Permission: p.permission}),
 			)
-		case key.Matches(msg, p.keyMap.AllowSession):
+		case key.Matches(msg, p.keyMap.AllowSession) && !p.permission.Confirmation:
 			return p, tea.Batch(
 				util.CmdHandler(dialogs.CloseDialogMsg{}),
 				util.CmdHandler(PermissionResponseMsg{Action: PermissionAllowForSession, Permission: p.permission}),
 			)
+		case key.Matches(msg, p.keyMap.AllowCommandPrefix) && p.commandPrefix != "":
+			return p, tea.Batch(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(PermissionResponseMsg{Action: PermissionAllowCommandPrefix, Permission: p.permission}),
+			)
 		case key.Matches(msg, p.keyMap.Deny):
 			return p, tea.Batch(
 				util.CmdHandler(dialogs.CloseDialogMsg{}),
@@ -220,17 +244,7 @@ func (p *permissionDialogCmp) isMouseOverDialog(x, y int) bool {
 }
 
 func (p *permissionDialogCmp) selectCurrentOption() tea.Cmd {
-	var action PermissionAction
-
-	switch p.selectedOption {
-	case 0:
-		action = PermissionAllow
-	case 1:
-		action = PermissionAllowForSession
-	case 2:
-		action = PermissionDeny
-	}
-
+	action := p.options()[p.selectedOption]
 	return tea.Batch(
 		util.CmdHandler(PermissionResponseMsg{Action: action, Permission: p.permission}),
 		util.CmdHandler(dialogs.CloseDialogMsg{}),
@@ -241,22 +255,24 @@ func (p *permissionDialogCmp) renderButtons() string {
 	t := styles.CurrentTheme()
 	baseStyle := t.S().Base
 
-	buttons := []core.ButtonOpts{
-		{
-			Text:           "Allow",
-			UnderlineIndex: 0, // "A"
-			Selected:       p.selectedOption == 0,
-		},
-		{
-			Text:           "Allow for Session",
-			UnderlineIndex: 10, // "S" in "Session"
-			Selected:       p.selectedOption == 1,
-		},
-		{
-			Text:           "Deny",
-			UnderlineIndex: 0, // "D"
-			Selected:       p.selectedOption == 2,
-		},
+	var buttons []core.ButtonOpts
+	for i, action := range p.options() {
+		button := core.ButtonOpts{Selected: p.selectedOption == i}
+		switch action {
+		case PermissionAllow:
+			button.Text = "Allow"
+			button.UnderlineIndex = 0 // "A"
+		case PermissionAllowForSession:
+			button.Text = "Allow for Session"
+			button.UnderlineIndex = 10 // "S" in "Session"
+		case PermissionAllowCommandPrefix:
+			button.Text = fmt.Sprintf("Always Allow %q", p.commandPrefix)
+			button.UnderlineIndex = 2 // "w" in "Always"
+		case PermissionDeny:
+			button.Text = "Deny"
+			button.UnderlineIndex = 0 // "D"
+		}
+		buttons = append(buttons, button)
 	}
 
 	content := core.SelectableButtons(buttons, "  ")
@@ -381,6 +397,24 @@ func (p *permissionDialogCmp) renderHeader() string {
 			),
 			baseStyle.Render(strings.Repeat(" ", p.width)),
 		)
+	case tools.ApplyPatchToolName:
+		params := p.permission.Params.(tools.ApplyPatchPermissionsParams)
+		filesKey := t.S().Muted.Render("Files")
+		files := make([]string, len(params.Files))
+		for i, file := range params.Files {
+			files[i] = fsext.PrettyPath(file)
+		}
+		filePaths := t.S().Text.
+			Width(p.width - lipgloss.Width(filesKey)).
+			Render(fmt.Sprintf(" %s", strings.Join(files, ", ")))
+		headerParts = append(headerParts,
+			lipgloss.JoinHorizontal(
+				lipgloss.Left,
+				filesKey,
+				filePaths,
+			),
+			baseStyle.Render(strings.Repeat(" ", p.width)),
+		)
 	case tools.FetchToolName:
 		headerParts = append(headerParts,
 			baseStyle.Render(strings.Repeat(" ", p.width)),
@@ -443,6 +477,8 @@ func (p *permissionDialogCmp) getOrGenerateContent() string {
 		content = p.generateWriteContent()
 	case tools.MultiEditToolName:
 		content = p.generateMultiEditContent()
+	case tools.ApplyPatchToolName:
+		content = p.generateApplyPatchContent()
 	case tools.FetchToolName:
 		content = p.generateFetchContent()
 	case tools.AgenticFetchToolName:
@@ -505,6 +541,43 @@ func (p *permissionDialogCmp) generateBashContent() string {
 	return ""
 }
 
+func (p *permissionDialogCmp) generateApplyPatchContent() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base.Background(t.BgSubtle)
+	if pr, ok := p.permission.Params.(tools.ApplyPatchPermissionsParams); ok {
+		content := strings.ReplaceAll(strings.TrimSpace(pr.Patch), "\t", "    ")
+		lines := strings.Split(content, "\n")
+
+		width := p.width - 4
+		var out []string
+		for _, ln := range lines {
+			fg := t.FgBase
+			switch {
+			case strings.HasPrefix(ln, "+++"), strings.HasPrefix(ln, "---"), strings.HasPrefix(ln, "@@"):
+				fg = t.FgMuted
+			case strings.HasPrefix(ln, "+"):
+				fg = t.Success
+			case strings.HasPrefix(ln, "-"):
+				fg = t.Error
+			}
+			out = append(out, t.S().Muted.
+				Width(width).
+				Padding(0, 3).
+				Foreground(fg).
+				Background(t.BgSubtle).
+				Render(ln))
+		}
+
+		finalContent := baseStyle.
+			Width(p.contentViewPort.Width()).
+			Padding(1, 0).
+			Render(strings.Join(out, "\n"))
+
+		return finalContent
+	}
+	return ""
+}
+
 func (p *permissionDialogCmp) generateEditContent() string {
 	if pr, ok := p.permission.Params.(tools.EditPermissionsParams); ok {
 		formatter := core.DiffFormatter().
@@ -818,6 +891,9 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	case tools.MultiEditToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.8)
+	case tools.ApplyPatchToolName:
+		p.width = int(float64(p.wWidth) * 0.8)
+		p.height = int(float64(p.wHeight) * 0.8)
 	case tools.FetchToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.3)
@@ -0,0 +1,258 @@
+package resources
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	ResourcesDialogID dialogs.DialogID = "mcp_resources"
+
+	defaultWidth = 70
+	// maxVisibleItems bounds the number of resources shown at once.
+	maxVisibleItems = 12
+	readTimeout     = 30 * time.Second
+)
+
+// resourceReadMsg is sent when reading the selected resource completes.
+type resourceReadMsg struct {
+	attachments []message.Attachment
+	err         error
+}
+
+// ResourcesDialog lets the user attach a resource of a connected MCP server
+// to the message being written.
+type ResourcesDialog interface {
+	dialogs.DialogModel
+}
+
+type resourceItem struct {
+	server   string
+	resource *mcp.Resource
+}
+
+func (i resourceItem) title() string {
+	return cmp.Or(i.resource.Title, i.resource.Name, i.resource.URI)
+}
+
+type resourcesDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	items   []resourceItem
+	cursor  int
+	reading bool
+	err     error
+
+	keyMap KeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewResourcesDialog creates a dialog listing the resources of every
+// connected MCP server.
+func NewResourcesDialog() ResourcesDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &resourcesDialogCmp{
+		width:      defaultWidth,
+		items:      loadResources(),
+		keyMap:     DefaultKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func loadResources() []resourceItem {
+	var items []resourceItem
+	for server, resources := range mcp.Resources() {
+		for _, resource := range resources {
+			items = append(items, resourceItem{server: server, resource: resource})
+		}
+	}
+	slices.SortFunc(items, func(a, b resourceItem) int {
+		return cmp.Or(
+			cmp.Compare(a.server, b.server),
+			cmp.Compare(a.title(), b.title()),
+		)
+	})
+	return items
+}
+
+func (r *resourcesDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (r *resourcesDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.wWidth = msg.Width
+		r.wHeight = msg.Height
+		r.width = min(defaultWidth, r.wWidth-4)
+		r.help.SetWidth(r.width - 2)
+	case resourceReadMsg:
+		r.reading = false
+		if msg.err != nil {
+			r.err = msg.err
+			return r, nil
+		}
+		cmds := []tea.Cmd{util.CmdHandler(dialogs.CloseDialogMsg{})}
+		for _, attachment := range msg.attachments {
+			cmds = append(cmds, util.CmdHandler(filepicker.FilePickedMsg{Attachment: attachment}))
+		}
+		return r, tea.Sequence(cmds...)
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, r.keyMap.Close):
+			return r, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case len(r.items) == 0 || r.reading:
+			return r, nil
+		case key.Matches(msg, r.keyMap.Next):
+			r.cursor = (r.cursor + 1) % len(r.items)
+		case key.Matches(msg, r.keyMap.Previous):
+			r.cursor = (r.cursor - 1 + len(r.items)) % len(r.items)
+		case key.Matches(msg, r.keyMap.Select):
+			r.reading = true
+			r.err = nil
+			return r, readResource(r.items[r.cursor])
+		}
+	}
+	return r, nil
+}
+
+// readResource reads the contents of a resource, turning them into
+// attachments.
+func readResource(item resourceItem) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+		defer cancel()
+		contents, err := mcp.ReadResource(ctx, item.server, item.resource.URI)
+		if err != nil {
+			return resourceReadMsg{err: fmt.Errorf("failed to read %s: %w", item.title(), err)}
+		}
+		attachments := make([]message.Attachment, 0, len(contents))
+		for _, content := range contents {
+			attachments = append(attachments, message.Attachment{
+				FilePath: content.URI,
+				FileName: item.title(),
+				MimeType: content.MIMEType,
+				Content:  content.Data,
+			})
+		}
+		return resourceReadMsg{attachments: attachments}
+	}
+}
+
+// visibleItems returns the range of items to show, keeping the cursor in
+// view.
+func (r *resourcesDialogCmp) visibleItems() (int, int) {
+	start := max(0, r.cursor-maxVisibleItems+1)
+	end := min(len(r.items), start+maxVisibleItems)
+	return start, end
+}
+
+func (r *resourcesDialogCmp) View() string {
+	if r.accessible {
+		return r.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+
+	var body string
+	if len(r.items) == 0 {
+		body = t.S().Muted.PaddingLeft(1).Render("No resources available from the connected MCP servers.")
+	} else {
+		start, end := r.visibleItems()
+		lines := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			item := r.items[i]
+			label := ansi.Truncate(fmt.Sprintf("%s · %s", item.server, item.title()), r.width-4, "…")
+			if i == r.cursor {
+				lines = append(lines, t.S().TextSelected.Width(r.width-2).Padding(0, 1).Render(label))
+				continue
+			}
+			lines = append(lines, t.S().Text.Padding(0, 1).Render(label))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	var status string
+	switch {
+	case r.reading:
+		status = t.S().Muted.PaddingLeft(1).Render("Reading resource...")
+	case r.err != nil:
+		status = t.S().Base.Width(r.width - 4).PaddingLeft(1).Foreground(t.Error).Render(r.err.Error())
+	case len(r.items) > 0:
+		uri := r.items[r.cursor].resource.URI
+		status = t.S().Subtle.PaddingLeft(1).Render(ansi.Truncate(uri, r.width-4, "…"))
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Attach MCP Resource", r.width-4)),
+		body,
+		"",
+		status,
+		t.S().Base.Width(r.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(r.help.View(r.keyMap)),
+	)
+	return t.S().Base.
+		Width(r.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// accessibleView renders the resources as plain text lines for screen
+// readers, marking the selected one with a leading ">".
+func (r *resourcesDialogCmp) accessibleView() string {
+	lines := []string{"Attach MCP Resource"}
+	if len(r.items) == 0 {
+		lines = append(lines, "No resources available from the connected MCP servers.")
+	}
+	for i, item := range r.items {
+		prefix := "  "
+		if i == r.cursor {
+			prefix = "> "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s, %s (%s)", prefix, item.server, item.title(), item.resource.URI))
+	}
+	switch {
+	case r.reading:
+		lines = append(lines, "Reading resource.")
+	case r.err != nil:
+		lines = append(lines, "Error: "+r.err.Error())
+	}
+	lines = append(lines, "Press enter to attach, or esc to close.")
+	return lipgloss.NewStyle().Width(r.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (r *resourcesDialogCmp) Position() (int, int) {
+	row := r.wHeight/4 - 2 // just a bit above the center
+	col := r.wWidth/2 - r.width/2
+	return row, col
+}
+
+func (r *resourcesDialogCmp) ID() dialogs.DialogID {
+	return ResourcesDialogID
+}
@@ -1,6 +1,9 @@
 package sessions
 
 import (
+	"strings"
+	"time"
+
 	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
@@ -45,18 +48,29 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	listKeyMap.DownOneItem = keyMap.Next
 	listKeyMap.UpOneItem = keyMap.Previous
 
+	sessions, depths := forkTree(sessions)
 	items := make([]list.CompletionItem[session.Session], len(sessions))
 	if len(sessions) > 0 {
 		for i, session := range sessions {
-			items[i] = list.NewCompletionItem(session.Title, session, list.WithCompletionID(session.ID))
+			title := session.Title
+			if depths[i] > 0 {
+				title = strings.Repeat("  ", depths[i]-1) + "↳ " + title
+			}
+			items[i] = list.NewCompletionItem(
+				title,
+				session,
+				list.WithCompletionID(session.ID),
+				list.WithCompletionShortcut(sessionDetails(session)),
+			)
 		}
 	}
 
 	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
 	sessionsList := list.NewFilterableList(
 		items,
-		list.WithFilterPlaceholder("Enter a session name"),
+		list.WithFilterPlaceholder("Enter a session name, #tag, project:name or after:7d"),
 		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterQuery(filterQuery),
 		list.WithFilterListOptions(
 			list.WithKeyMap(listKeyMap),
 			list.WithWrapNavigation(),
@@ -74,6 +88,65 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	return s
 }
 
+// sessionDetails returns the tags of s and the day it was last updated.
+func sessionDetails(s session.Session) string {
+	updated := time.Unix(s.UpdatedAt, 0)
+	date := updated.Format("Jan 2")
+	if updated.Year() != time.Now().Year() {
+		date = updated.Format("Jan 2 2006")
+	}
+	if len(s.Tags) == 0 {
+		return date
+	}
+	return "#" + strings.Join(s.Tags, " #") + " · " + date
+}
+
+// filterQuery keeps the sessions selected by the filters of query, the rest
+// of it being fuzzy matched against their titles.
+func filterQuery(query string) (string, func(list.FilterableItem) bool) {
+	filter, rest := session.ParseFilter(query, time.Now())
+	if filter.IsZero() {
+		return rest, nil
+	}
+	return rest, func(item list.FilterableItem) bool {
+		i, ok := item.(list.CompletionItem[session.Session])
+		return ok && filter.Match(i.Value())
+	}
+}
+
+// forkTree orders sessions so that forks follow the session they were
+// forked from, returning how deep each is in the tree of forks.
+func forkTree(sessions []session.Session) ([]session.Session, []int) {
+	listed := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		listed[s.ID] = true
+	}
+	forks := make(map[string][]session.Session)
+	var roots []session.Session
+	for _, s := range sessions {
+		if s.ForkedFromID != "" && listed[s.ForkedFromID] {
+			forks[s.ForkedFromID] = append(forks[s.ForkedFromID], s)
+			continue
+		}
+		roots = append(roots, s)
+	}
+
+	ordered := make([]session.Session, 0, len(sessions))
+	depths := make([]int, 0, len(sessions))
+	var walk func(s session.Session, depth int)
+	walk = func(s session.Session, depth int) {
+		ordered = append(ordered, s)
+		depths = append(depths, depth)
+		for _, fork := range forks[s.ID] {
+			walk(fork, depth+1)
+		}
+	}
+	for _, s := range roots {
+		walk(s, 0)
+	}
+	return ordered, depths
+}
+
 func (s *sessionDialogCmp) Init() tea.Cmd {
 	var cmds []tea.Cmd
 	cmds = append(cmds, s.sessionsList.Init())
@@ -0,0 +1,185 @@
+// Package themes provides the dialog switching between the TUI themes,
+// previewing them as they're chosen.
+package themes
+
+import (
+	"fmt"
+	"slices"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	ThemesDialogID dialogs.DialogID = "themes"
+
+	defaultWidth int = 50
+)
+
+type listModel = list.FilterableList[list.CompletionItem[string]]
+
+type themesDialogCmp struct {
+	width   int
+	wWidth  int // Width of the terminal window
+	wHeight int // Height of the terminal window
+
+	// original is the theme in use when the dialog opened, restored when
+	// it's closed without choosing one.
+	original  string
+	themeList listModel
+	keyMap    KeyMap
+	help      help.Model
+}
+
+// NewThemesDialog creates a dialog switching to one of the registered
+// themes, applying each one as it's selected.
+func NewThemesDialog() dialogs.DialogModel {
+	keyMap := DefaultKeyMap()
+	listKeyMap := list.DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	t := styles.CurrentTheme()
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	themeList := list.NewFilterableList(
+		[]list.CompletionItem[string]{},
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterPlaceholder("Search themes"),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+			list.WithResizeByList(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+
+	return &themesDialogCmp{
+		width:     defaultWidth,
+		original:  t.Name,
+		themeList: themeList,
+		keyMap:    keyMap,
+		help:      help,
+	}
+}
+
+func (d *themesDialogCmp) Init() tea.Cmd {
+	names := styles.DefaultManager().List()
+	slices.Sort(names)
+	items := make([]list.CompletionItem[string], 0, len(names))
+	for _, name := range names {
+		opts := []list.CompletionItemOption{list.WithCompletionID(name)}
+		if name == d.original {
+			opts = append(opts, list.WithCompletionShortcut("current"))
+		}
+		items = append(items, list.NewCompletionItem(name, name, opts...))
+	}
+	return tea.Sequence(d.themeList.SetItems(items), d.themeList.SetSelected(d.original))
+}
+
+func (d *themesDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		d.wWidth = msg.Width
+		d.wHeight = msg.Height
+		return d, d.themeList.SetSize(d.listWidth(), d.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, d.keyMap.Select):
+			name := d.selected()
+			if name == "" {
+				return d, nil
+			}
+			if err := styles.DefaultManager().SetTheme(name); err != nil {
+				return d, util.ReportError(err)
+			}
+			cmds := []tea.Cmd{util.CmdHandler(dialogs.CloseDialogMsg{})}
+			if err := config.Get().SetTheme(name); err != nil {
+				cmds = append(cmds, util.ReportError(fmt.Errorf("failed to save the theme: %w", err)))
+			} else {
+				cmds = append(cmds, util.ReportInfo("Switched to the "+name+" theme"))
+			}
+			return d, tea.Sequence(cmds...)
+		case key.Matches(msg, d.keyMap.Close):
+			_ = styles.DefaultManager().SetTheme(d.original)
+			return d, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := d.themeList.Update(msg)
+			d.themeList = u.(listModel)
+			// Preview the theme selected.
+			if name := d.selected(); name != "" {
+				_ = styles.DefaultManager().SetTheme(name)
+			}
+			return d, cmd
+		}
+	}
+	return d, nil
+}
+
+// selected returns the name of the theme selected in the list, if any.
+func (d *themesDialogCmp) selected() string {
+	item := d.themeList.SelectedItem()
+	if item == nil {
+		return ""
+	}
+	return (*item).Value()
+}
+
+func (d *themesDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	header := t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Theme", d.width-4))
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		d.themeList.View(),
+		"",
+		t.S().Base.Width(d.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(d.help.View(d.keyMap)),
+	)
+	return t.S().Base.
+		Width(d.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (d *themesDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := d.themeList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			row, col := d.Position()
+			cursor.Y += row + 3
+			cursor.X += col + 2
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (d *themesDialogCmp) listWidth() int {
+	return d.width - 2
+}
+
+func (d *themesDialogCmp) listHeight() int {
+	listHeight := len(d.themeList.Items()) + 2 + 4 // height based on items + 2 for the input + 4 for the sections
+	return min(listHeight, d.wHeight/2)
+}
+
+func (d *themesDialogCmp) Position() (int, int) {
+	row := d.wHeight/4 - 2 // just a bit above the center
+	col := d.wWidth/2 - d.width/2
+	return row, col
+}
+
+func (d *themesDialogCmp) ID() dialogs.DialogID {
+	return ThemesDialogID
+}
@@ -0,0 +1,178 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/crush/internal/worktree"
+)
+
+const (
+	WorktreeDialogID dialogs.DialogID = "worktree"
+
+	defaultWidth = 70
+	// maxChangeLines bounds the height of the changes shown.
+	maxChangeLines = 15
+)
+
+// WorktreeDialog shows the changes made in the worktree, merging them into
+// the user's checkout on request.
+type WorktreeDialog interface {
+	dialogs.DialogModel
+}
+
+type changesLoadedMsg struct {
+	changes string
+	err     error
+}
+
+type worktreeDialogCmp struct {
+	width   int
+	wWidth  int
+	wHeight int
+
+	worktree *worktree.Worktree
+	changes  string
+	loaded   bool
+	merging  bool
+
+	keyMap KeyMap
+	help   help.Model
+
+	// accessible renders plain text without colors or a border.
+	accessible bool
+}
+
+// NewWorktreeDialog creates a dialog to review and merge the changes of wt.
+func NewWorktreeDialog(wt *worktree.Worktree) WorktreeDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &worktreeDialogCmp{
+		width:      defaultWidth,
+		worktree:   wt,
+		keyMap:     DefaultKeyMap(),
+		help:       h,
+		accessible: dialogs.Accessible(),
+	}
+}
+
+func (w *worktreeDialogCmp) Init() tea.Cmd {
+	return func() tea.Msg {
+		changes, err := w.worktree.Changes(context.Background())
+		return changesLoadedMsg{changes: changes, err: err}
+	}
+}
+
+func (w *worktreeDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		w.wWidth = msg.Width
+		w.wHeight = msg.Height
+		w.width = min(defaultWidth, w.wWidth-4)
+		w.help.SetWidth(w.width - 2)
+	case changesLoadedMsg:
+		if msg.err != nil {
+			return w, tea.Batch(util.CmdHandler(dialogs.CloseDialogMsg{}), util.ReportError(msg.err))
+		}
+		w.changes = msg.changes
+		w.loaded = true
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, w.keyMap.Close):
+			return w, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, w.keyMap.Merge):
+			if !w.loaded || w.changes == "" || w.merging {
+				return w, nil
+			}
+			w.merging = true
+			wt := w.worktree
+			return w, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				func() tea.Msg {
+					if err := wt.Merge(context.Background(), "Changes made with Crush"); err != nil {
+						return util.ReportError(err)()
+					}
+					return util.ReportInfo(fmt.Sprintf("Merged %s into %s", wt.Branch, w.target()))()
+				},
+			)
+		}
+	}
+	return w, nil
+}
+
+// target names what the changes are merged into.
+func (w *worktreeDialogCmp) target() string {
+	if w.worktree.BaseBranch == "" {
+		return "the checked out commit"
+	}
+	return w.worktree.BaseBranch
+}
+
+func (w *worktreeDialogCmp) body() string {
+	switch {
+	case !w.loaded:
+		return "Loading changes…"
+	case w.changes == "":
+		return "No changes yet."
+	}
+	lines := strings.Split(w.changes, "\n")
+	if len(lines) > maxChangeLines {
+		// Keep the summary line at the end.
+		lines = append(lines[:maxChangeLines-1], "…", lines[len(lines)-1])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (w *worktreeDialogCmp) View() string {
+	if w.accessible {
+		return w.accessibleView()
+	}
+
+	t := styles.CurrentTheme()
+	info := t.S().Muted.Render(fmt.Sprintf("Branch %s, merged into %s", w.worktree.Branch, w.target()))
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Review Worktree", w.width-4)),
+		t.S().Base.PaddingLeft(1).Render(info),
+		"",
+		t.S().Text.Width(w.width-4).PaddingLeft(1).Render(w.body()),
+		"",
+		t.S().Base.Width(w.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(w.help.View(w.keyMap)),
+	)
+	return t.S().Base.
+		Width(w.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// accessibleView renders the changes as plain text for screen readers.
+func (w *worktreeDialogCmp) accessibleView() string {
+	lines := []string{
+		"Review Worktree",
+		fmt.Sprintf("Branch %s, merged into %s.", w.worktree.Branch, w.target()),
+		w.body(),
+		"Press enter to merge the changes, or esc to close.",
+	}
+	return lipgloss.NewStyle().Width(w.width).Padding(0, 1).Render(strings.Join(lines, "\n"))
+}
+
+func (w *worktreeDialogCmp) Position() (int, int) {
+	row := w.wHeight/4 - 2 // just a bit above the center
+	col := w.wWidth/2 - w.width/2
+	return row, col
+}
+
+func (w *worktreeDialogCmp) ID() dialogs.DialogID {
+	return WorktreeDialogID
+}
@@ -42,6 +42,7 @@ type filterableOptions struct {
 	inputHidden bool
 	inputWidth  int
 	inputStyle  lipgloss.Style
+	parseQuery  func(query string) (string, func(FilterableItem) bool)
 }
 type filterableList[T FilterableItem] struct {
 	*list[T]
@@ -81,6 +82,14 @@ func WithFilterListOptions(opts ...ListOption) filterableListOption {
 	}
 }
 
+// WithFilterQuery sets how queries are parsed, returning the text items are
+// fuzzy matched against and whether to keep an item at all.
+func WithFilterQuery(parse func(query string) (string, func(FilterableItem) bool)) filterableListOption {
+	return func(f *filterableOptions) {
+		f.parseQuery = parse
+	}
+}
+
 func WithFilterInputWidth(inputWidth int) filterableListOption {
 	return func(f *filterableOptions) {
 		f.inputWidth = inputWidth
@@ -244,11 +253,21 @@ func (f *filterableList[T]) Filter(query string) tea.Cmd {
 	}
 
 	f.selectedItemIdx = -1
-	if query == "" || len(f.items) == 0 {
-		return f.list.SetItems(f.items)
+	items := f.items
+	if f.parseQuery != nil {
+		var keep func(FilterableItem) bool
+		query, keep = f.parseQuery(query)
+		if keep != nil {
+			items = slices.DeleteFunc(slices.Clone(f.items), func(item T) bool {
+				return !keep(item)
+			})
+		}
+	}
+	if query == "" || len(items) == 0 {
+		return f.list.SetItems(items)
 	}
 
-	matches := fuzzy.FindFrom(query, f)
+	matches := fuzzy.FindFrom(query, filterSource[T](items))
 
 	var matchedItems []T
 	resultSize := len(matches)
@@ -257,7 +276,7 @@ func (f *filterableList[T]) Filter(query string) tea.Cmd {
 	}
 	for i := range resultSize {
 		match := matches[i]
-		item := f.items[match.Index]
+		item := items[match.Index]
 		if it, ok := any(item).(HasMatchIndexes); ok {
 			it.MatchIndexes(match.MatchedIndexes)
 		}
@@ -317,3 +336,14 @@ func (f *filterableList[T]) String(i int) string {
 func (f *filterableList[T]) Len() int {
 	return len(f.items)
 }
+
+// filterSource fuzzy matches the filter values of items.
+type filterSource[T FilterableItem] []T
+
+func (s filterSource[T]) String(i int) string {
+	return s[i].FilterValue()
+}
+
+func (s filterSource[T]) Len() int {
+	return len(s)
+}
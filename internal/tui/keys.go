@@ -1,7 +1,13 @@
 package tui
 
 import (
+	"fmt"
+	"maps"
+
 	"charm.land/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/editor"
+	"github.com/charmbracelet/crush/internal/tui/page/chat"
+	"github.com/charmbracelet/crush/internal/tui/util"
 )
 
 type KeyMap struct {
@@ -43,3 +49,29 @@ func DefaultKeyMap() KeyMap {
 		),
 	}
 }
+
+// Actions returns the bindings of the key map by the name of their action in
+// the keys options.
+func (k *KeyMap) Actions() util.KeyActions {
+	return util.KeyActions{
+		"quit":     &k.Quit,
+		"help":     &k.Help,
+		"commands": &k.Commands,
+		"suspend":  &k.Suspend,
+		"models":   &k.Models,
+		"sessions": &k.Sessions,
+	}
+}
+
+// ValidateKeys checks the keys options against the actions of the key maps
+// of the TUI, which are active at the same time.
+func ValidateKeys(keys map[string][]string) error {
+	global, page, edit := DefaultKeyMap(), chat.DefaultKeyMap(), editor.DefaultEditorKeyMap()
+	actions := global.Actions()
+	maps.Copy(actions, page.Actions())
+	maps.Copy(actions, edit.Actions())
+	if err := util.ValidateKeys(keys, actions); err != nil {
+		return fmt.Errorf("invalid key bindings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKeys(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateKeys(nil))
+	require.NoError(t, ValidateKeys(map[string][]string{"sessions": {"ctrl+o"}, "open_editor": {"ctrl+e"}}))
+	require.ErrorContains(t, ValidateKeys(map[string][]string{"sessions": {"ctrl+o"}}), "invalid key bindings")
+}
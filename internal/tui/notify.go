@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// notificationSequence returns the escape sequence notifying an event of the
+// given kind: a bell, or a desktop notification with OSC 777 and OSC 9
+// along with the bell for the terminals supporting neither.
+func notificationSequence(kind, title, body string) string {
+	switch kind {
+	case config.NotificationBell:
+		return "\a"
+	case config.NotificationDesktop:
+		title, body = notificationText(title), notificationText(body)
+		return "\a" +
+			"\x1b]777;notify;" + title + ";" + body + "\x1b\\" +
+			"\x1b]9;" + title + ": " + body + "\x1b\\"
+	}
+	return ""
+}
+
+// notificationText strips the characters of s that would end the escape
+// sequence of a notification, or split its fields.
+func notificationText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		if r == ';' {
+			return ','
+		}
+		return r
+	}, s)
+}
+
+// notify notifies an event of the given kind when the terminal isn't
+// focused.
+func (a *appModel) notify(kind, title, body string) tea.Cmd {
+	if !a.unfocused {
+		return nil
+	}
+	seq := notificationSequence(kind, title, body)
+	if seq == "" {
+		return nil
+	}
+	return tea.Raw(seq)
+}
+
+// notifyPermission notifies the agent asking for permission.
+func (a *appModel) notifyPermission(req permission.PermissionRequest) tea.Cmd {
+	kind := config.Get().Options.TUI.Notifications.PermissionKind()
+	return a.notify(kind, "Crush needs permission", req.ToolName+": "+req.Description)
+}
+
+// notifyFinished tracks the runs of the agent in the selected session, and
+// notifies them finishing when they lasted long enough.
+func (a *appModel) notifyFinished(event pubsub.Event[message.Message]) tea.Cmd {
+	msg := event.Payload
+	if msg.SessionID != a.selectedSessionID {
+		return nil
+	}
+	if event.Type == pubsub.CreatedEvent && msg.Role == message.User {
+		a.runStarted = time.Now()
+		return nil
+	}
+	if msg.Role != message.Assistant || a.runStarted.IsZero() {
+		return nil
+	}
+
+	var body string
+	switch msg.FinishReason() {
+	case message.FinishReasonEndTurn:
+		body = "The agent finished its work."
+	case message.FinishReasonMaxTokens:
+		body = "The agent stopped at the maximum number of tokens."
+	case message.FinishReasonError:
+		body = "The agent stopped with an error."
+	default:
+		return nil
+	}
+	started := a.runStarted
+	a.runStarted = time.Time{}
+
+	notifications := config.Get().Options.TUI.Notifications
+	if time.Since(started) < notifications.MinRunDuration() {
+		return nil
+	}
+	return a.notify(notifications.FinishedKind(), "Crush", body)
+}
@@ -0,0 +1,19 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationSequence(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "\a", notificationSequence(config.NotificationBell, "Crush", "Done."))
+	require.Empty(t, notificationSequence(config.NotificationOff, "Crush", "Done."))
+	require.Equal(t,
+		"\a\x1b]777;notify;Crush;bash: run, then\x1b\\\x1b]9;Crush: bash: run, then\x1b\\",
+		notificationSequence(config.NotificationDesktop, "Crush", "bash: run;\x1bthen"),
+	)
+}
@@ -1,9 +1,11 @@
 package chat
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"charm.land/bubbles/v2/help"
@@ -11,6 +13,8 @@ import (
 	"charm.land/bubbles/v2/spinner"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/history"
@@ -20,6 +24,7 @@ import (
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/diffpane"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/editor"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/header"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
@@ -29,10 +34,12 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/budget"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/claude"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/copilot"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/login"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/reasoning"
 	"github.com/charmbracelet/crush/internal/tui/page"
@@ -43,6 +50,10 @@ import (
 
 var ChatPageID page.PageID = "chat"
 
+// budgetContinuePrompt goes on with a response stopped for going over the
+// budget, once the user allowed the session over it.
+const budgetContinuePrompt = "Continue where you stopped."
+
 type (
 	ChatFocusedMsg struct {
 		Focused bool
@@ -80,6 +91,9 @@ type ChatPage interface {
 	util.Model
 	layout.Help
 	IsChatFocused() bool
+	// IsAuthenticating returns whether a cancellable provider
+	// authentication flow is in progress.
+	IsAuthenticating() bool
 }
 
 // cancelTimerCmd creates a command that expires the cancel timer
@@ -105,14 +119,16 @@ type chatPage struct {
 	keyMap  KeyMap
 
 	// Components
-	header  header.Header
-	sidebar sidebar.Sidebar
-	chat    chat.MessageListCmp
-	editor  editor.Editor
-	splash  splash.Splash
+	header   header.Header
+	sidebar  sidebar.Sidebar
+	chat     chat.MessageListCmp
+	editor   editor.Editor
+	splash   splash.Splash
+	diffPane diffpane.DiffPane
 
 	// Simple state flags
 	showingDetails   bool
+	showingDiff      bool
 	isCanceling      bool
 	splashFullScreen bool
 	isOnboarding     bool
@@ -120,14 +136,17 @@ type chatPage struct {
 }
 
 func New(app *app.App) ChatPage {
+	keyMap := DefaultKeyMap()
+	keyMap.Actions().Configure(app.Config().Options.TUI.Keys)
 	return &chatPage{
 		app:         app,
-		keyMap:      DefaultKeyMap(),
+		keyMap:      keyMap,
 		header:      header.New(app.LSPClients),
 		sidebar:     sidebar.New(app.History, app.LSPClients, false),
 		chat:        chat.New(app),
 		editor:      editor.New(app),
 		splash:      splash.New(),
+		diffPane:    diffpane.New(app.History),
 		focusedPane: PanelTypeSplash,
 	}
 }
@@ -171,6 +190,18 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	case tea.KeyboardEnhancementsMsg:
 		p.keyboardEnhancements = msg
 		return p, nil
+	case config.Reloaded:
+		if msg.Keys {
+			p.keyMap = DefaultKeyMap()
+			p.keyMap.Actions().Configure(p.app.Config().Options.TUI.Keys)
+		}
+		u, cmd := p.editor.Update(msg)
+		p.editor = u.(editor.Editor)
+		cmds = append(cmds, cmd)
+		u, cmd = p.sidebar.Update(msg)
+		p.sidebar = u.(sidebar.Sidebar)
+		cmds = append(cmds, cmd)
+		return p, tea.Batch(cmds...)
 	case tea.MouseWheelMsg:
 		if p.compact {
 			msg.Y -= 1
@@ -239,7 +270,7 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		p.editor = u.(editor.Editor)
 		return p, cmd
 	case chat.SendMsg:
-		return p, p.sendMessage(msg.Text, msg.Attachments)
+		return p, p.sendMessage(msg)
 	case chat.SessionSelectedMsg:
 		return p, p.setSession(msg)
 	case splash.SubmitAPIKeyMsg:
@@ -302,7 +333,7 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 		return p, tea.Batch(cmds...)
-	case copilot.DeviceFlowStartedMsg, copilot.PollingResultMsg, copilot.ValidationCompletedMsg, copilot.AuthenticationCompleteMsg:
+	case copilot.DeviceFlowStartedMsg, copilot.PollingResultMsg, copilot.ValidationCompletedMsg, copilot.AuthenticationCompleteMsg, copilot.RetryReadyMsg:
 		if p.focusedPane == PanelTypeSplash {
 			u, cmd := p.splash.Update(msg)
 			p.splash = u.(splash.Splash)
@@ -335,12 +366,21 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
+	case sidebar.ContextFilesMsg:
+		u, cmd := p.sidebar.Update(msg)
+		p.sidebar = u.(sidebar.Sidebar)
+		return p, cmd
 	case pubsub.Event[history.File], sidebar.SessionFilesMsg:
 		u, cmd := p.sidebar.Update(msg)
 		p.sidebar = u.(sidebar.Sidebar)
 		cmds = append(cmds, cmd)
+		u, cmd = p.diffPane.Update(msg)
+		p.diffPane = u.(diffpane.DiffPane)
+		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
-	case pubsub.Event[permission.PermissionNotification]:
+	case commands.ToggleDiffPaneMsg:
+		return p, p.toggleDiff()
+	case pubsub.Event[permission.PermissionNotification], pubsub.Event[tools.ToolOutput]:
 		u, cmd := p.chat.Update(msg)
 		p.chat = u.(chat.MessageListCmp)
 		cmds = append(cmds, cmd)
@@ -351,7 +391,7 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			return p, util.ReportWarn("Agent is busy, please wait before executing a command...")
 		}
 
-		cmd := p.sendMessage(msg.Content, nil)
+		cmd := p.sendMessage(chat.SendMsg{Text: msg.Content, Attachments: msg.Attachments})
 		if cmd != nil {
 			return p, cmd
 		}
@@ -389,6 +429,9 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		case key.Matches(msg, p.keyMap.AddAttachment):
 			agentCfg := config.Get().Agents[config.AgentCoder]
 			model := config.Get().GetModelByType(agentCfg.Model)
+			if model == nil {
+				return p, util.ReportWarn("No model selected")
+			}
 			if model.SupportsImages {
 				return p, util.CmdHandler(commands.OpenFilePickerMsg{})
 			} else {
@@ -404,11 +447,20 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			return p, nil
 		case key.Matches(msg, p.keyMap.Cancel):
 			if p.session.ID != "" && p.app.AgentCoordinator.IsBusy() {
+				// The esc leaving vim insert mode is the cancel binding too,
+				// which comes first so a run can always be cancelled.
+				if p.focusedPane == PanelTypeEditor {
+					p.editor.LeaveInsertMode()
+				}
 				return p, p.cancel()
 			}
 		case key.Matches(msg, p.keyMap.Details):
 			p.toggleDetails()
 			return p, nil
+		case key.Matches(msg, p.keyMap.Diff):
+			return p, p.toggleDiff()
+		case key.Matches(msg, p.keyMap.Reasoning):
+			return p, p.switchReasoning()
 		}
 
 		switch p.focusedPane {
@@ -481,6 +533,9 @@ func (p *chatPage) View() string {
 		}
 	} else {
 		messagesView := p.chat.View()
+		if p.showingDiff {
+			messagesView = lipgloss.JoinHorizontal(lipgloss.Top, messagesView, p.diffPane.View())
+		}
 		editorView := p.editor.View()
 		if p.compact {
 			headerView := p.header.View()
@@ -572,6 +627,27 @@ func (p *chatPage) toggleThinking() tea.Cmd {
 	}
 }
 
+// switchReasoning toggles the thinking of the current model, or moves it to
+// its next reasoning effort.
+func (p *chatPage) switchReasoning() tea.Cmd {
+	cfg := config.Get()
+	agentCfg := cfg.Agents[config.AgentCoder]
+	model := cfg.GetModelByType(agentCfg.Model)
+	providerCfg := cfg.GetProviderForModel(agentCfg.Model)
+	if model == nil || providerCfg == nil || !model.CanReason {
+		return util.ReportWarn("The current model can't reason")
+	}
+	if config.ThinksWithToggle(providerCfg.Type, model.ID) {
+		return p.toggleThinking()
+	}
+	if len(model.ReasoningLevels) == 0 {
+		return util.ReportWarn("The reasoning of the current model can't be changed")
+	}
+	current := cmp.Or(cfg.Models[agentCfg.Model].ReasoningEffort, model.DefaultReasoningEffort)
+	next := model.ReasoningLevels[(slices.Index(model.ReasoningLevels, current)+1)%len(model.ReasoningLevels)]
+	return p.handleReasoningEffortSelected(next)
+}
+
 func (p *chatPage) openReasoningDialog() tea.Cmd {
 	return func() tea.Msg {
 		cfg := config.Get()
@@ -659,13 +735,13 @@ func (p *chatPage) SetSize(width, height int) tea.Cmd {
 		}
 	} else {
 		if p.compact {
-			cmds = append(cmds, p.chat.SetSize(width, height-EditorHeight-HeaderHeight))
+			cmds = append(cmds, p.setChatSize(width, height-EditorHeight-HeaderHeight))
 			p.detailsWidth = width - DetailsPositioning
 			cmds = append(cmds, p.sidebar.SetSize(p.detailsWidth-LeftRightBorders, p.detailsHeight-TopBottomBorders))
 			cmds = append(cmds, p.editor.SetSize(width, EditorHeight))
 			cmds = append(cmds, p.header.SetWidth(width-BorderWidth))
 		} else {
-			cmds = append(cmds, p.chat.SetSize(width-SideBarWidth, height-EditorHeight))
+			cmds = append(cmds, p.setChatSize(width-SideBarWidth, height-EditorHeight))
 			cmds = append(cmds, p.editor.SetSize(width, EditorHeight))
 			cmds = append(cmds, p.sidebar.SetSize(SideBarWidth, height-EditorHeight))
 		}
@@ -674,6 +750,28 @@ func (p *chatPage) SetSize(width, height int) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// setChatSize sizes the messages, and the diff pane next to them when it's
+// shown.
+func (p *chatPage) setChatSize(width, height int) tea.Cmd {
+	if !p.showingDiff {
+		return p.chat.SetSize(width, height)
+	}
+	diffWidth := width / 2
+	return tea.Batch(
+		p.chat.SetSize(width-diffWidth, height),
+		p.diffPane.SetSize(diffWidth, height),
+	)
+}
+
+// toggleDiff shows or hides the diff pane next to the messages.
+func (p *chatPage) toggleDiff() tea.Cmd {
+	if p.session.ID == "" {
+		return nil
+	}
+	p.showingDiff = !p.showingDiff
+	return p.SetSize(p.width, p.height)
+}
+
 func (p *chatPage) newSession() tea.Cmd {
 	if p.session.ID == "" {
 		return nil
@@ -698,9 +796,16 @@ func (p *chatPage) setSession(session session.Session) tea.Cmd {
 	var cmds []tea.Cmd
 	p.session = session
 
+	if p.app.AgentCoordinator != nil {
+		if err := p.app.AgentCoordinator.RecoverSession(context.Background(), session.ID); err != nil {
+			cmds = append(cmds, util.ReportError(err))
+		}
+	}
+
 	cmds = append(cmds, p.SetSize(p.width, p.height))
 	cmds = append(cmds, p.chat.SetSession(session))
 	cmds = append(cmds, p.sidebar.SetSession(session))
+	cmds = append(cmds, p.diffPane.SetSession(session))
 	cmds = append(cmds, p.header.SetSession(session))
 	cmds = append(cmds, p.editor.SetSession(session))
 
@@ -755,7 +860,7 @@ func (p *chatPage) toggleDetails() {
 	p.setShowDetails(!p.showingDetails)
 }
 
-func (p *chatPage) sendMessage(text string, attachments []message.Attachment) tea.Cmd {
+func (p *chatPage) sendMessage(msg chat.SendMsg) tea.Cmd {
 	session := p.session
 	var cmds []tea.Cmd
 	if p.session.ID == "" {
@@ -771,18 +876,48 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 	}
 	cmds = append(cmds, p.chat.GoToBottom())
 	cmds = append(cmds, func() tea.Msg {
-		_, err := p.app.AgentCoordinator.Run(context.Background(), session.ID, text, attachments...)
+		send := p.app.AgentCoordinator.Run
+		if msg.Plan {
+			send = p.app.AgentCoordinator.Plan
+		}
+		_, err := send(context.Background(), session.ID, msg.Text, msg.Attachments...)
 		if err != nil {
 			isCancelErr := errors.Is(err, context.Canceled)
 			isPermissionErr := errors.Is(err, permission.ErrorPermissionDenied)
 			if isCancelErr || isPermissionErr {
 				return nil
 			}
+			var budgetErr *agent.BudgetExceededError
+			if errors.As(err, &budgetErr) {
+				// A run stopped between two steps has its prompt in the
+				// session already, so it's gone on with instead.
+				resent := msg
+				if budgetErr.Stopped {
+					resent = chat.SendMsg{Text: budgetContinuePrompt, Plan: msg.Plan}
+				}
+				return dialogs.OpenDialogMsg{
+					Model: budget.NewBudgetDialog(budgetErr.Error(), func() tea.Msg {
+						p.app.AgentCoordinator.AllowOverBudget(session.ID)
+						return resent
+					}),
+				}
+			}
+			if providerID, ok := login.RequiresLogin(err); ok {
+				return dialogs.OpenDialogMsg{
+					Model: login.NewLoginDialogFor(providerID, err),
+				}
+			}
 			return util.InfoMsg{
 				Type: util.InfoTypeError,
 				Msg:  err.Error(),
 			}
 		}
+		if warning := p.app.AgentCoordinator.BudgetWarning(context.Background(), session.ID); warning != "" {
+			return util.InfoMsg{
+				Type: util.InfoTypeWarn,
+				Msg:  warning,
+			}
+		}
 		return nil
 	})
 	return tea.Batch(cmds...)
@@ -793,13 +928,13 @@ func (p *chatPage) Bindings() []key.Binding {
 		p.keyMap.NewSession,
 		p.keyMap.AddAttachment,
 	}
+	if p.session.ID != "" {
+		bindings = append(bindings, p.keyMap.Diff)
+	}
 	if p.app.AgentCoordinator != nil && p.app.AgentCoordinator.IsBusy() {
 		cancelBinding := p.keyMap.Cancel
 		if p.isCanceling {
-			cancelBinding = key.NewBinding(
-				key.WithKeys("esc", "alt+esc"),
-				key.WithHelp("esc", "press again to cancel"),
-			)
+			cancelBinding = withHelp(p.keyMap.Cancel, "press again to cancel")
 		}
 		bindings = append([]key.Binding{cancelBinding}, bindings...)
 	}
@@ -807,18 +942,12 @@ func (p *chatPage) Bindings() []key.Binding {
 	switch p.focusedPane {
 	case PanelTypeChat:
 		bindings = append([]key.Binding{
-			key.NewBinding(
-				key.WithKeys("tab"),
-				key.WithHelp("tab", "focus editor"),
-			),
+			withHelp(p.keyMap.Tab, "focus editor"),
 		}, bindings...)
 		bindings = append(bindings, p.chat.Bindings()...)
 	case PanelTypeEditor:
 		bindings = append([]key.Binding{
-			key.NewBinding(
-				key.WithKeys("tab"),
-				key.WithHelp("tab", "focus chat"),
-			),
+			withHelp(p.keyMap.Tab, "focus chat"),
 		}, bindings...)
 		bindings = append(bindings, p.editor.Bindings()...)
 	case PanelTypeSplash:
@@ -850,10 +979,10 @@ func (p *chatPage) Help() help.KeyMap {
 				key.WithHelp("esc", "back"),
 			),
 			// Quit
-			key.NewBinding(
+			p.configuredKey("quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -888,10 +1017,10 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
+			p.configuredKey("quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -910,10 +1039,10 @@ func (p *chatPage) Help() help.KeyMap {
 				key.WithHelp("enter", "accept"),
 			),
 			// Quit
-			key.NewBinding(
+			p.configuredKey("quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -938,10 +1067,10 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
+			p.configuredKey("quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -949,10 +1078,10 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 	case p.isProjectInit:
 		shortList = append(shortList,
-			key.NewBinding(
+			p.configuredKey("quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -980,21 +1109,12 @@ func (p *chatPage) Help() help.KeyMap {
 			return core.NewSimpleHelp(shortList, fullList)
 		}
 		if p.app.AgentCoordinator != nil && p.app.AgentCoordinator.IsBusy() {
-			cancelBinding := key.NewBinding(
-				key.WithKeys("esc", "alt+esc"),
-				key.WithHelp("esc", "cancel"),
-			)
+			cancelBinding := withHelp(p.keyMap.Cancel, "cancel")
 			if p.isCanceling {
-				cancelBinding = key.NewBinding(
-					key.WithKeys("esc", "alt+esc"),
-					key.WithHelp("esc", "press again to cancel"),
-				)
+				cancelBinding = withHelp(p.keyMap.Cancel, "press again to cancel")
 			}
 			if p.app.AgentCoordinator != nil && p.app.AgentCoordinator.QueuedPrompts(p.session.ID) > 0 {
-				cancelBinding = key.NewBinding(
-					key.WithKeys("esc", "alt+esc"),
-					key.WithHelp("esc", "clear queue"),
-				)
+				cancelBinding = withHelp(p.keyMap.Cancel, "clear queue")
 			}
 			shortList = append(shortList, cancelBinding)
 			fullList = append(fullList,
@@ -1006,49 +1126,41 @@ func (p *chatPage) Help() help.KeyMap {
 		globalBindings := []key.Binding{}
 		// we are in a session
 		if p.session.ID != "" {
-			tabKey := key.NewBinding(
-				key.WithKeys("tab"),
-				key.WithHelp("tab", "focus chat"),
-			)
+			tabKey := withHelp(p.keyMap.Tab, "focus chat")
 			if p.focusedPane == PanelTypeChat {
-				tabKey = key.NewBinding(
-					key.WithKeys("tab"),
-					key.WithHelp("tab", "focus editor"),
-				)
+				tabKey = withHelp(p.keyMap.Tab, "focus editor")
 			}
 			shortList = append(shortList, tabKey)
 			globalBindings = append(globalBindings, tabKey)
 		}
-		commandsBinding := key.NewBinding(
+		commandsBinding := p.configuredKey("commands", key.NewBinding(
 			key.WithKeys("ctrl+p"),
 			key.WithHelp("ctrl+p", "commands"),
-		)
-		modelsBinding := key.NewBinding(
+		))
+		modelsBinding := p.configuredKey("models", key.NewBinding(
 			key.WithKeys("ctrl+m", "ctrl+l"),
 			key.WithHelp("ctrl+l", "models"),
-		)
-		if p.keyboardEnhancements.Flags > 0 {
+		))
+		if p.keyboardEnhancements.Flags > 0 && slices.Contains(modelsBinding.Keys(), "ctrl+m") {
 			// non-zero flags mean we have at least key disambiguation
 			modelsBinding.SetHelp("ctrl+m", "models")
 		}
-		helpBinding := key.NewBinding(
+		helpBinding := p.configuredKey("help", key.NewBinding(
 			key.WithKeys("ctrl+g"),
 			key.WithHelp("ctrl+g", "more"),
-		)
+		))
 		globalBindings = append(globalBindings, commandsBinding, modelsBinding)
 		globalBindings = append(globalBindings,
-			key.NewBinding(
+			p.configuredKey("sessions", key.NewBinding(
 				key.WithKeys("ctrl+s"),
 				key.WithHelp("ctrl+s", "sessions"),
-			),
+			)),
 		)
 		if p.session.ID != "" {
 			globalBindings = append(globalBindings,
-				key.NewBinding(
-					key.WithKeys("ctrl+n"),
-					key.WithHelp("ctrl+n", "new sessions"),
-				))
+				withHelp(p.keyMap.NewSession, "new sessions"))
 		}
+		globalBindings = append(globalBindings, p.keyMap.Reasoning)
 		shortList = append(shortList,
 			// Commands
 			commandsBinding,
@@ -1104,18 +1216,19 @@ func (p *chatPage) Help() help.KeyMap {
 				},
 				[]key.Binding{
 					messages.CopyKey,
+					messages.ExpandKey,
 					messages.ClearSelectionKey,
 				},
 			)
 		case PanelTypeEditor:
-			newLineBinding := key.NewBinding(
+			newLineBinding := p.configuredKey("newline", key.NewBinding(
 				key.WithKeys("shift+enter", "ctrl+j"),
 				// "ctrl+j" is a common keybinding for newline in many editors. If
 				// the terminal supports "shift+enter", we substitute the help text
 				// to reflect that.
 				key.WithHelp("ctrl+j", "newline"),
-			)
-			if p.keyboardEnhancements.Flags > 0 {
+			))
+			if p.keyboardEnhancements.Flags > 0 && slices.Contains(newLineBinding.Keys(), "shift+enter") {
 				// Non-zero flags mean we have at least key disambiguation.
 				newLineBinding.SetHelp("shift+enter", newLineBinding.Help().Desc)
 			}
@@ -1123,18 +1236,15 @@ func (p *chatPage) Help() help.KeyMap {
 			fullList = append(fullList,
 				[]key.Binding{
 					newLineBinding,
-					key.NewBinding(
-						key.WithKeys("ctrl+f"),
-						key.WithHelp("ctrl+f", "add image"),
-					),
+					withHelp(p.keyMap.AddAttachment, "add image"),
 					key.NewBinding(
 						key.WithKeys("@"),
 						key.WithHelp("@", "mention file"),
 					),
-					key.NewBinding(
+					p.configuredKey("open_editor", key.NewBinding(
 						key.WithKeys("ctrl+o"),
 						key.WithHelp("ctrl+o", "open editor"),
-					),
+					)),
 				})
 
 			if p.editor.HasAttachments() {
@@ -1156,18 +1266,18 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
+			p.configuredKey("quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 			// Help
 			helpBinding,
 		)
 		fullList = append(fullList, []key.Binding{
-			key.NewBinding(
+			p.configuredKey("help", key.NewBinding(
 				key.WithKeys("ctrl+g"),
 				key.WithHelp("ctrl+g", "less"),
-			),
+			)),
 		})
 	}
 
@@ -1178,6 +1288,10 @@ func (p *chatPage) IsChatFocused() bool {
 	return p.focusedPane == PanelTypeChat
 }
 
+func (p *chatPage) IsAuthenticating() bool {
+	return p.splashFullScreen && p.splash.IsCopilotAuthInProgress()
+}
+
 // isMouseOverChat checks if the given mouse coordinates are within the chat area bounds.
 // Returns true if the mouse is over the chat area, false otherwise.
 func (p *chatPage) isMouseOverChat(x, y int) bool {
@@ -1201,6 +1315,10 @@ func (p *chatPage) isMouseOverChat(x, y int) bool {
 		chatWidth = p.width - SideBarWidth
 		chatHeight = p.height - EditorHeight
 	}
+	// The diff pane takes the right half of it.
+	if p.showingDiff {
+		chatWidth -= chatWidth / 2
+	}
 
 	// Check if mouse coordinates are within chat bounds
 	return x >= chatX && x < chatX+chatWidth && y >= chatY && y < chatY+chatHeight
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"charm.land/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/util"
 )
 
 type KeyMap struct {
@@ -10,6 +11,8 @@ type KeyMap struct {
 	Cancel        key.Binding
 	Tab           key.Binding
 	Details       key.Binding
+	Diff          key.Binding
+	Reasoning     key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -34,5 +37,41 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+d"),
 			key.WithHelp("ctrl+d", "toggle details"),
 		),
+		Diff: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "toggle diff"),
+		),
+		Reasoning: key.NewBinding(
+			key.WithKeys("alt+t"),
+			key.WithHelp("alt+t", "toggle reasoning"),
+		),
+	}
+}
+
+// Actions returns the bindings of the key map by the name of their action in
+// the keys options.
+func (k *KeyMap) Actions() util.KeyActions {
+	return util.KeyActions{
+		"new_session":    &k.NewSession,
+		"add_attachment": &k.AddAttachment,
+		"cancel":         &k.Cancel,
+		"change_focus":   &k.Tab,
+		"details":        &k.Details,
+		"diff":           &k.Diff,
+		"reasoning":      &k.Reasoning,
 	}
 }
+
+// withHelp returns a binding triggered by the keys of b, described by desc.
+func withHelp(b key.Binding, desc string) key.Binding {
+	return key.NewBinding(
+		key.WithKeys(b.Keys()...),
+		key.WithHelp(b.Help().Key, desc),
+	)
+}
+
+// configuredKey returns b triggered by the keys set for the action in the
+// keys options instead, if any.
+func (p *chatPage) configuredKey(action string, b key.Binding) key.Binding {
+	return util.ConfigureKey(b, p.app.Config().Options.TUI.Keys[action])
+}
@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+// reloadConfig applies the settings of the config files changed while
+// running that can take effect without a restart, and tells what it
+// reloaded.
+func (a *appModel) reloadConfig(msg app.ConfigReloadMsg) tea.Cmd {
+	if msg.Err != nil {
+		return util.ReportWarn(fmt.Sprintf("Failed to reload the configuration: %v", msg.Err))
+	}
+
+	var cmds []tea.Cmd
+	fresh := msg.Config
+	if fresh.Options != nil && fresh.Options.TUI != nil {
+		if err := ValidateKeys(fresh.Options.TUI.Keys); err != nil {
+			// Keep the key bindings in use.
+			fresh.Options.TUI.Keys = a.app.Config().Options.TUI.Keys
+			cmds = append(cmds, util.ReportWarn(fmt.Sprintf("Not reloading the key bindings: %v", err)))
+		}
+	}
+	reloaded := a.app.ApplyConfig(fresh)
+	if reloaded.IsZero() {
+		return tea.Batch(cmds...)
+	}
+
+	if reloaded.Theme {
+		if err := setupThemes(a.app.Config()); err != nil {
+			cmds = append(cmds, util.ReportWarn(err.Error()))
+		}
+	}
+	if reloaded.Keys {
+		pageBindings := a.keyMap.pageBindings
+		a.keyMap = DefaultKeyMap()
+		a.keyMap.Actions().Configure(a.app.Config().Options.TUI.Keys)
+		a.keyMap.pageBindings = pageBindings
+	}
+	if len(reloaded.Models) > 0 {
+		cmds = append(cmds, a.handleStateChanged(context.Background()))
+	}
+	if item, ok := a.pages[a.currentPage]; ok {
+		updated, cmd := item.Update(reloaded)
+		a.pages[a.currentPage] = updated
+		cmds = append(cmds, cmd)
+	}
+	cmds = append(cmds, util.ReportInfo("Reloaded the configuration: "+reloaded.String()))
+	return tea.Sequence(cmds...)
+}
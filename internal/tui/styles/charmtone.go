@@ -5,9 +5,12 @@ import (
 	"github.com/charmbracelet/x/exp/charmtone"
 )
 
+// DefaultThemeName is the name of the theme used when none is configured.
+const DefaultThemeName = "charmtone"
+
 func NewCharmtoneTheme() *Theme {
 	t := &Theme{
-		Name:   "charmtone",
+		Name:   DefaultThemeName,
 		IsDark: true,
 
 		Primary:   charmtone.Charple,
@@ -58,26 +61,31 @@ func NewCharmtoneTheme() *Theme {
 		Cherry:   charmtone.Cherry,
 	}
 
+	t.deriveStyles()
+
+	return t
+}
+
+// deriveStyles sets the styles of t made from its colors.
+func (t *Theme) deriveStyles() {
 	// Text selection.
-	t.TextSelection = lipgloss.NewStyle().Foreground(charmtone.Salt).Background(charmtone.Charple)
+	t.TextSelection = lipgloss.NewStyle().Foreground(t.FgSelected).Background(t.Primary)
 
 	// LSP and MCP status.
-	t.ItemOfflineIcon = lipgloss.NewStyle().Foreground(charmtone.Squid).SetString("●")
-	t.ItemBusyIcon = t.ItemOfflineIcon.Foreground(charmtone.Citron)
-	t.ItemErrorIcon = t.ItemOfflineIcon.Foreground(charmtone.Coral)
-	t.ItemOnlineIcon = t.ItemOfflineIcon.Foreground(charmtone.Guac)
+	t.ItemOfflineIcon = lipgloss.NewStyle().Foreground(t.FgMuted).SetString("●")
+	t.ItemBusyIcon = t.ItemOfflineIcon.Foreground(t.Citron)
+	t.ItemErrorIcon = t.ItemOfflineIcon.Foreground(t.Red)
+	t.ItemOnlineIcon = t.ItemOfflineIcon.Foreground(t.GreenDark)
 
 	// Editor: Yolo Mode.
-	t.YoloIconFocused = lipgloss.NewStyle().Foreground(charmtone.Oyster).Background(charmtone.Citron).Bold(true).SetString(" ! ")
-	t.YoloIconBlurred = t.YoloIconFocused.Foreground(charmtone.Pepper).Background(charmtone.Squid)
-	t.YoloDotsFocused = lipgloss.NewStyle().Foreground(charmtone.Zest).SetString(":::")
-	t.YoloDotsBlurred = t.YoloDotsFocused.Foreground(charmtone.Squid)
+	t.YoloIconFocused = lipgloss.NewStyle().Foreground(t.FgSubtle).Background(t.Citron).Bold(true).SetString(" ! ")
+	t.YoloIconBlurred = t.YoloIconFocused.Foreground(t.BgBase).Background(t.FgMuted)
+	t.YoloDotsFocused = lipgloss.NewStyle().Foreground(t.Accent).SetString(":::")
+	t.YoloDotsBlurred = t.YoloDotsFocused.Foreground(t.FgMuted)
 
 	// oAuth Chooser.
-	t.AuthBorderSelected = lipgloss.NewStyle().BorderForeground(charmtone.Guac)
-	t.AuthTextSelected = lipgloss.NewStyle().Foreground(charmtone.Julep)
-	t.AuthBorderUnselected = lipgloss.NewStyle().BorderForeground(charmtone.Iron)
-	t.AuthTextUnselected = lipgloss.NewStyle().Foreground(charmtone.Squid)
-
-	return t
+	t.AuthBorderSelected = lipgloss.NewStyle().BorderForeground(t.GreenDark)
+	t.AuthTextSelected = lipgloss.NewStyle().Foreground(t.Green)
+	t.AuthBorderUnselected = lipgloss.NewStyle().BorderForeground(t.BgOverlay)
+	t.AuthTextUnselected = lipgloss.NewStyle().Foreground(t.FgMuted)
 }
@@ -0,0 +1,138 @@
+package styles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// ThemeFile is a theme defined in a JSON or TOML file, overriding the
+// colors of the default theme.
+type ThemeFile struct {
+	Name   string `json:"name" toml:"name"`
+	IsDark *bool  `json:"is_dark,omitempty" toml:"is_dark,omitempty"`
+	// Colors are hex colors by the snake case name of the theme field they
+	// set, like bg_base.
+	Colors map[string]string `json:"colors" toml:"colors"`
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// colors returns the color fields of t by their snake case name.
+func (t *Theme) colors() map[string]*color.Color {
+	return map[string]*color.Color{
+		"primary":         &t.Primary,
+		"secondary":       &t.Secondary,
+		"tertiary":        &t.Tertiary,
+		"accent":          &t.Accent,
+		"bg_base":         &t.BgBase,
+		"bg_base_lighter": &t.BgBaseLighter,
+		"bg_subtle":       &t.BgSubtle,
+		"bg_overlay":      &t.BgOverlay,
+		"fg_base":         &t.FgBase,
+		"fg_muted":        &t.FgMuted,
+		"fg_half_muted":   &t.FgHalfMuted,
+		"fg_subtle":       &t.FgSubtle,
+		"fg_selected":     &t.FgSelected,
+		"border":          &t.Border,
+		"border_focus":    &t.BorderFocus,
+		"success":         &t.Success,
+		"error":           &t.Error,
+		"warning":         &t.Warning,
+		"info":            &t.Info,
+		"white":           &t.White,
+		"blue_light":      &t.BlueLight,
+		"blue_dark":       &t.BlueDark,
+		"blue":            &t.Blue,
+		"yellow":          &t.Yellow,
+		"citron":          &t.Citron,
+		"green":           &t.Green,
+		"green_dark":      &t.GreenDark,
+		"green_light":     &t.GreenLight,
+		"red":             &t.Red,
+		"red_dark":        &t.RedDark,
+		"red_light":       &t.RedLight,
+		"cherry":          &t.Cherry,
+	}
+}
+
+// Theme returns the theme the file defines, with the colors it doesn't set
+// taken from the default theme.
+func (f ThemeFile) Theme() (*Theme, error) {
+	if strings.TrimSpace(f.Name) == "" {
+		return nil, errors.New("theme has no name")
+	}
+	t := NewCharmtoneTheme()
+	t.Name = f.Name
+	if f.IsDark != nil {
+		t.IsDark = *f.IsDark
+	}
+	colors := t.colors()
+	for _, name := range slices.Sorted(maps.Keys(f.Colors)) {
+		field, ok := colors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown color %q in theme %q", name, f.Name)
+		}
+		value := f.Colors[name]
+		if !hexColor.MatchString(value) {
+			return nil, fmt.Errorf("invalid color %q for %q in theme %q, expected #rrggbb", value, name, f.Name)
+		}
+		*field = ParseHex(value)
+	}
+	t.deriveStyles()
+	return t, nil
+}
+
+// LoadThemes loads the themes defined in the JSON and TOML files of dir. It
+// returns the themes it could load along with the errors of the others.
+func LoadThemes(dir string) ([]*Theme, error) {
+	var paths []string
+	for _, pattern := range []string{"*.json", "*.toml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	slices.Sort(paths)
+
+	var themes []*Theme
+	var errs []error
+	for _, path := range paths {
+		theme, err := loadTheme(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		themes = append(themes, theme)
+	}
+	return themes, errors.Join(errs...)
+}
+
+func loadTheme(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f ThemeFile
+	unmarshal := json.Unmarshal
+	if filepath.Ext(path) == ".toml" {
+		unmarshal = toml.Unmarshal
+	}
+	if err := unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid theme: %w", err)
+	}
+	if f.Name == "" {
+		f.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return f.Theme()
+}
@@ -0,0 +1,56 @@
+package styles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadThemes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "paper.json"), []byte(`{
+		"is_dark": false,
+		"colors": {"bg_base": "#fafafa", "fg_base": "#202020"}
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{
+		"name": "broken",
+		"colors": {"background": "#000000"}
+	}`), 0o644))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "night.toml"), []byte(`
+name = "Night"
+is_dark = true
+
+[colors]
+bg_base = "#101018"
+primary = "#ff8800"
+`), 0o644))
+
+	themes, err := LoadThemes(dir)
+	require.ErrorContains(t, err, `unknown color "background"`)
+	require.Len(t, themes, 2)
+
+	night := themes[0]
+	require.Equal(t, "Night", night.Name)
+	require.True(t, night.IsDark)
+	require.Equal(t, "#101018", lipglossColorToHex(night.BgBase))
+	require.Equal(t, "#ff8800", lipglossColorToHex(night.Primary))
+
+	paper := themes[1]
+	require.Equal(t, "paper", paper.Name)
+	require.False(t, paper.IsDark)
+	require.Equal(t, "#fafafa", lipglossColorToHex(paper.BgBase))
+	require.Equal(t, "#202020", lipglossColorToHex(paper.FgBase))
+	require.Equal(t, lipglossColorToHex(NewCharmtoneTheme().Primary), lipglossColorToHex(paper.Primary))
+}
+
+func TestThemeFileInvalidColor(t *testing.T) {
+	t.Parallel()
+
+	_, err := ThemeFile{Name: "paper", Colors: map[string]string{"primary": "red"}}.Theme()
+	require.ErrorContains(t, err, "expected #rrggbb")
+}
@@ -221,7 +221,7 @@ func (t *Theme) buildStyles() *Styles {
 				StylePrimitive: ansi.StylePrimitive{
 					// BlockPrefix: "\n",
 					// BlockSuffix: "\n",
-					Color: stringPtr(charmtone.Smoke.Hex()),
+					Color: stringPtr(lipglossColorToHex(t.FgHalfMuted)),
 				},
 				// Margin: uintPtr(defaultMargin),
 			},
@@ -236,7 +236,7 @@ func (t *Theme) buildStyles() *Styles {
 			Heading: ansi.StyleBlock{
 				StylePrimitive: ansi.StylePrimitive{
 					BlockSuffix: "\n",
-					Color:       stringPtr(charmtone.Malibu.Hex()),
+					Color:       stringPtr(lipglossColorToHex(t.Blue)),
 					Bold:        boolPtr(true),
 				},
 			},
@@ -244,8 +244,8 @@ func (t *Theme) buildStyles() *Styles {
 				StylePrimitive: ansi.StylePrimitive{
 					Prefix:          " ",
 					Suffix:          " ",
-					Color:           stringPtr(charmtone.Zest.Hex()),
-					BackgroundColor: stringPtr(charmtone.Charple.Hex()),
+					Color:           stringPtr(lipglossColorToHex(t.Accent)),
+					BackgroundColor: stringPtr(lipglossColorToHex(t.Primary)),
 					Bold:            boolPtr(true),
 				},
 			},
@@ -272,7 +272,7 @@ func (t *Theme) buildStyles() *Styles {
 			H6: ansi.StyleBlock{
 				StylePrimitive: ansi.StylePrimitive{
 					Prefix: "###### ",
-					Color:  stringPtr(charmtone.Guac.Hex()),
+					Color:  stringPtr(lipglossColorToHex(t.GreenDark)),
 					Bold:   boolPtr(false),
 				},
 			},
@@ -286,7 +286,7 @@ func (t *Theme) buildStyles() *Styles {
 				Bold: boolPtr(true),
 			},
 			HorizontalRule: ansi.StylePrimitive{
-				Color:  stringPtr(charmtone.Charcoal.Hex()),
+				Color:  stringPtr(lipglossColorToHex(t.Border)),
 				Format: "\n--------\n",
 			},
 			Item: ansi.StylePrimitive{
@@ -305,7 +305,7 @@ func (t *Theme) buildStyles() *Styles {
 				Underline: boolPtr(true),
 			},
 			LinkText: ansi.StylePrimitive{
-				Color: stringPtr(charmtone.Guac.Hex()),
+				Color: stringPtr(lipglossColorToHex(t.GreenDark)),
 				Bold:  boolPtr(true),
 			},
 			Image: ansi.StylePrimitive{
@@ -313,21 +313,21 @@ func (t *Theme) buildStyles() *Styles {
 				Underline: boolPtr(true),
 			},
 			ImageText: ansi.StylePrimitive{
-				Color:  stringPtr(charmtone.Squid.Hex()),
+				Color:  stringPtr(lipglossColorToHex(t.FgMuted)),
 				Format: "Image: {{.text}} →",
 			},
 			Code: ansi.StyleBlock{
 				StylePrimitive: ansi.StylePrimitive{
 					Prefix:          " ",
 					Suffix:          " ",
-					Color:           stringPtr(charmtone.Coral.Hex()),
-					BackgroundColor: stringPtr(charmtone.Charcoal.Hex()),
+					Color:           stringPtr(lipglossColorToHex(t.Red)),
+					BackgroundColor: stringPtr(lipglossColorToHex(t.BgSubtle)),
 				},
 			},
 			CodeBlock: ansi.StyleCodeBlock{
 				StyleBlock: ansi.StyleBlock{
 					StylePrimitive: ansi.StylePrimitive{
-						Color: stringPtr(charmtone.Charcoal.Hex()),
+						Color: stringPtr(lipglossColorToHex(t.BgSubtle)),
 					},
 					Margin: uintPtr(defaultMargin),
 				},
@@ -411,7 +411,7 @@ func (t *Theme) buildStyles() *Styles {
 						Color: stringPtr(charmtone.Squid.Hex()),
 					},
 					Background: ansi.StylePrimitive{
-						BackgroundColor: stringPtr(charmtone.Charcoal.Hex()),
+						BackgroundColor: stringPtr(lipglossColorToHex(t.BgSubtle)),
 					},
 				},
 			},
@@ -0,0 +1,31 @@
+package tui
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// themesDir returns the directory the user themes are loaded from.
+func themesDir() string {
+	return filepath.Join(filepath.Dir(config.GlobalConfig()), "themes")
+}
+
+// setupThemes registers the user themes and switches to the configured one.
+func setupThemes(cfg *config.Config) error {
+	manager := styles.DefaultManager()
+	themes, err := styles.LoadThemes(themesDir())
+	if err != nil {
+		err = fmt.Errorf("failed to load some themes: %w", err)
+	}
+	for _, theme := range themes {
+		manager.Register(theme)
+	}
+	name := cmp.Or(cfg.Options.TUI.Theme, styles.DefaultThemeName)
+	err = errors.Join(err, manager.SetTheme(name))
+	return err
+}
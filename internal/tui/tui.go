@@ -2,8 +2,10 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
@@ -11,12 +13,18 @@ import (
 	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/event"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/shell"
 	cmpChat "github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
@@ -24,16 +32,26 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/core/status"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/accounts"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/agents"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/checkpoints"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	copilotdialog "github.com/charmbracelet/crush/internal/tui/components/dialogs/copilot"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/forks"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/login"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/permissions"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/resources"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessions"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/themes"
+	worktreedialog "github.com/charmbracelet/crush/internal/tui/components/dialogs/worktree"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/page/chat"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/crush/internal/worktree"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -77,6 +95,17 @@ type appModel struct {
 	// Chat Page Specific
 	selectedSessionID string // The ID of the currently selected session
 
+	// unfocused is whether the terminal reported losing the focus, when
+	// events are notified.
+	unfocused bool
+	// runStarted is when the agent started the run of the selected session
+	// it's working on, to notify it finishing.
+	runStarted time.Time
+
+	// themesErr is the error setting up the user themes, reported once the
+	// TUI starts.
+	themesErr error
+
 	// sendProgressBar instructs the TUI to send progress bar updates to the
 	// terminal.
 	sendProgressBar bool
@@ -84,6 +113,14 @@ type appModel struct {
 	// QueryVersion instructs the TUI to query for the terminal version when it
 	// starts.
 	QueryVersion bool
+
+	// InitialSession is the session the TUI starts with, when resuming one.
+	InitialSession *session.Session
+	// InitialPrompt is the prompt asking for a plan sent in InitialSession
+	// once the TUI starts, with InitialAttachments. The agent can only read
+	// files to answer it.
+	InitialPrompt      string
+	InitialAttachments []message.Attachment
 }
 
 // Init initializes the application model and returns initial commands.
@@ -103,6 +140,20 @@ func (a appModel) Init() tea.Cmd {
 	if a.QueryVersion {
 		cmds = append(cmds, tea.RequestTerminalVersion)
 	}
+	if a.InitialSession != nil {
+		cmd := util.CmdHandler(cmpChat.SessionSelectedMsg(*a.InitialSession))
+		if a.InitialPrompt != "" {
+			cmd = tea.Sequence(cmd, util.CmdHandler(cmpChat.SendMsg{
+				Text:        a.InitialPrompt,
+				Attachments: a.InitialAttachments,
+				Plan:        true,
+			}))
+		}
+		cmds = append(cmds, cmd)
+	}
+	if a.themesErr != nil {
+		cmds = append(cmds, util.ReportWarn(a.themesErr.Error()))
+	}
 
 	return tea.Batch(cmds...)
 }
@@ -119,6 +170,14 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if !a.sendProgressBar {
 			a.sendProgressBar = slices.Contains(msg, "WT_SESSION")
 		}
+	case tea.FocusMsg:
+		a.unfocused = false
+		return a, nil
+	case tea.BlurMsg:
+		a.unfocused = true
+		return a, nil
+	case pubsub.Event[message.Message]:
+		cmds = append(cmds, a.notifyFinished(msg))
 	case tea.TerminalVersionMsg:
 		termVersion := strings.ToLower(msg.Name)
 		// Only enable progress bar for the following terminals.
@@ -128,7 +187,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, nil
 	case tea.KeyboardEnhancementsMsg:
 		// A non-zero value means we have key disambiguation support.
-		if msg.Flags > 0 {
+		if msg.Flags > 0 && slices.Contains(a.keyMap.Models.Keys(), "ctrl+m") {
 			a.keyMap.Models.SetHelp("ctrl+m", "models")
 		}
 		for id, page := range a.pages {
@@ -145,6 +204,27 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.completions.Update(msg)
 		return a, a.handleWindowResize(msg.Width, msg.Height)
 
+	case pubsub.Event[oauth.TokenEvent]:
+		// The status bar and any open dialog pick the event up below.
+		if msg.Payload.Type == oauth.TokenInvalidated {
+			name := msg.Payload.ProviderID
+			if pc, ok := config.Get().Providers.Get(name); ok && pc.Name != "" {
+				name = pc.Name
+			}
+			cmds = append(cmds, util.ReportWarn(fmt.Sprintf("Your %s login is no longer valid. Log in again from the command palette.", name)))
+		}
+
+	case pubsub.Event[agent.BackgroundTask]:
+		// An open background agents dialog picks the event up below.
+		if task := msg.Payload; msg.Type == pubsub.UpdatedEvent {
+			switch task.Status {
+			case agent.BackgroundTaskCompleted:
+				cmds = append(cmds, util.ReportInfo(fmt.Sprintf("Background agent %s finished", task.ID)))
+			case agent.BackgroundTaskFailed:
+				cmds = append(cmds, util.ReportWarn(fmt.Sprintf("Background agent %s failed: %s", task.ID, task.Error)))
+			}
+		}
+
 	case pubsub.Event[mcp.Event]:
 		switch msg.Payload.Type {
 		case mcp.EventStateChanged:
@@ -153,6 +233,8 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, handleMCPPromptsEvent(context.Background(), msg.Payload.Name)
 		case mcp.EventToolsListChanged:
 			return a, handleMCPToolsEvent(context.Background(), msg.Payload.Name)
+		case mcp.EventResourcesListChanged:
+			return a, handleMCPResourcesEvent(context.Background(), msg.Payload.Name)
 		}
 
 	// Completions messages
@@ -196,7 +278,12 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case commands.ShowMCPPromptArgumentsDialogMsg:
 		args := make([]commands.Argument, 0, len(msg.Prompt.Arguments))
 		for _, arg := range msg.Prompt.Arguments {
-			args = append(args, commands.Argument(*arg))
+			args = append(args, commands.Argument{
+				Name:        arg.Name,
+				Title:       arg.Title,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
 		}
 		dialog := commands.NewCommandArgumentsDialog(
 			msg.Prompt.Name,
@@ -242,6 +329,42 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Model: models.NewModelDialogCmp(),
 			},
 		)
+	case commands.OpenLoginDialogMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: login.NewLoginDialog(),
+			},
+		)
+	case commands.OpenAccountsDialogMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: accounts.NewAccountsDialog(),
+			},
+		)
+	case commands.OpenAgentsDialogMsg:
+		if a.app.AgentCoordinator == nil {
+			return a, util.ReportWarn("Agent is not configured yet")
+		}
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: agents.NewAgentsDialog(a.app.AgentCoordinator),
+			},
+		)
+	case commands.OpenMCPResourcesMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: resources.NewResourcesDialog(),
+			},
+		)
+	case commands.OpenCopilotUsageMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: copilotdialog.NewUsageDialog(),
+			},
+		)
+	case accounts.AccountSwitchedMsg:
+		go a.app.UpdateAgentModel(context.TODO())
+		return a, util.ReportInfo(fmt.Sprintf("Switched %s to account %s", msg.ProviderName, msg.Account))
 	// Compact
 	case commands.CompactMsg:
 		return a, func() tea.Msg {
@@ -251,12 +374,109 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return nil
 		}
+	case commands.ResetShellMsg:
+		shell.GetSessionShells().Reset(msg.SessionID)
+		return a, util.ReportInfo("Shell reset")
+	case commands.OpenForkDialogMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: forks.NewForkDialog(a.app.Messages, msg.SessionID),
+			},
+		)
+	case forks.ForkMsg:
+		return a, func() tea.Msg {
+			fork, err := a.app.ForkSession(context.Background(), msg.SessionID, msg.MessageID)
+			if err != nil {
+				return util.ReportError(err)()
+			}
+			return cmpChat.SessionSelectedMsg(fork)
+		}
+	case commands.OpenEditMessageDialogMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: forks.NewEditDialog(a.app.Messages, msg.SessionID, a.app.Config().Options.BranchOnEdit),
+			},
+		)
+	case forks.EditMsg:
+		return a, func() tea.Msg {
+			sess, attachments, err := a.app.RegenerateFrom(context.Background(), msg.SessionID, msg.MessageID)
+			if errors.Is(err, agent.ErrSessionBusy) {
+				return util.ReportWarn("Agent is busy, please wait...")()
+			}
+			if err != nil {
+				return util.ReportError(err)()
+			}
+			return tea.Sequence(
+				util.CmdHandler(cmpChat.SessionSelectedMsg(sess)),
+				util.CmdHandler(cmpChat.SendMsg{Text: msg.Text, Attachments: attachments}),
+			)()
+		}
+	case commands.CompareForkMsg:
+		return a, func() tea.Msg {
+			diff, err := a.app.CompareFork(context.Background(), msg.SessionID)
+			if errors.Is(err, app.ErrNotForked) {
+				return util.ReportWarn("This session isn't a fork, fork a session to compare it")()
+			}
+			if err != nil {
+				return util.ReportError(err)()
+			}
+			return dialogs.OpenDialogMsg{
+				Model: forks.NewCompareDialog("Compare Fork", diff),
+			}
+		}
+	case commands.ExportSessionMsg:
+		return a, a.exportSession(msg.SessionID, msg.Format)
+	case commands.EditSessionMsg:
+		return a, a.editSession(msg.SessionID)
+	case commands.OpenWorktreeDialogMsg:
+		wt := worktree.Current()
+		if wt == nil {
+			return a, util.ReportWarn("Not working in a worktree")
+		}
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: worktreedialog.NewWorktreeDialog(wt),
+			},
+		)
+	case commands.OpenCheckpointsDialogMsg:
+		if a.app.Checkpoints == nil {
+			return a, util.ReportWarn("Checkpoints are disabled")
+		}
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: checkpoints.NewCheckpointsDialog(a.app.Checkpoints, msg.SessionID),
+			},
+		)
 	case commands.QuitMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: quit.NewQuitDialog(),
 		})
+	case commands.OpenThemesDialogMsg:
+		return a, util.CmdHandler(dialogs.OpenDialogMsg{
+			Model: themes.NewThemesDialog(),
+		})
+	case commands.ToggleMouseMsg:
+		native := !a.app.Config().Options.TUI.NativeSelection
+		if err := a.app.Config().SetNativeSelection(native); err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to save the mouse setting: %w", err))
+		}
+		if native {
+			return a, util.ReportInfo("Mouse left to the terminal for native selection")
+		}
+		return a, util.ReportInfo("Mouse scrolls and selects messages")
 	case commands.ToggleYoloModeMsg:
-		a.app.Permissions.SetSkipRequests(!a.app.Permissions.SkipRequests())
+		// Without a session, the toggle applies to the sessions to come.
+		if a.selectedSessionID == "" {
+			a.app.Permissions.SetSkipRequests(!a.app.Permissions.SkipRequests())
+		} else {
+			skip := !a.app.Permissions.SessionSkipRequests(a.selectedSessionID)
+			a.app.Permissions.SetSessionSkipRequests(a.selectedSessionID, skip)
+		}
+		state := "disabled"
+		if a.app.Permissions.SessionSkipRequests(a.selectedSessionID) {
+			state = "enabled"
+		}
+		cmds = append(cmds, util.ReportInfo("Yolo mode "+state))
 	case commands.ToggleHelpMsg:
 		a.status.ToggleFullHelp()
 		a.showingFullHelp = !a.showingFullHelp
@@ -304,17 +524,22 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return a, itemCmd
 	case pubsub.Event[permission.PermissionRequest]:
-		return a, util.CmdHandler(dialogs.OpenDialogMsg{
-			Model: permissions.NewPermissionDialogCmp(msg.Payload, &permissions.Options{
-				DiffMode: config.Get().Options.TUI.DiffMode,
+		return a, tea.Batch(
+			util.CmdHandler(dialogs.OpenDialogMsg{
+				Model: permissions.NewPermissionDialogCmp(msg.Payload, &permissions.Options{
+					DiffMode: config.Get().Options.TUI.DiffMode,
+				}),
 			}),
-		})
+			a.notifyPermission(msg.Payload),
+		)
 	case permissions.PermissionResponseMsg:
 		switch msg.Action {
 		case permissions.PermissionAllow:
 			a.app.Permissions.Grant(msg.Permission)
 		case permissions.PermissionAllowForSession:
 			a.app.Permissions.GrantPersistent(msg.Permission)
+		case permissions.PermissionAllowCommandPrefix:
+			a.app.Permissions.GrantCommandPrefix(msg.Permission)
 		case permissions.PermissionDeny:
 			a.app.Permissions.Deny(msg.Permission)
 		}
@@ -373,6 +598,8 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a, tea.Batch(cmds...)
 	// Update Available
+	case app.ConfigReloadMsg:
+		return a, a.reloadConfig(msg)
 	case pubsub.UpdateAvailableMsg:
 		// Show update notification in status bar
 		statusMsg := fmt.Sprintf("Crush update available: v%s → v%s.", msg.CurrentVersion, msg.LatestVersion)
@@ -451,7 +678,9 @@ func (a *appModel) handleWindowResize(width, height int) tea.Cmd {
 // handleKeyPressMsg processes keyboard input and routes to appropriate handlers.
 func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 	// Check this first as the user should be able to quit no matter what.
-	if key.Matches(msg, a.keyMap.Quit) {
+	// The only exception is an in-progress authentication flow, where
+	// ctrl+c cancels the flow instead.
+	if key.Matches(msg, a.keyMap.Quit) && !a.isAuthenticating() {
 		if a.dialog.ActiveDialogID() == quit.QuitDialogID {
 			return tea.Quit
 		}
@@ -495,9 +724,12 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 		if a.dialog.HasDialogs() {
 			return nil
 		}
-		return util.CmdHandler(dialogs.OpenDialogMsg{
-			Model: commands.NewCommandDialog(a.selectedSessionID),
-		})
+		return func() tea.Msg {
+			allSessions, _ := a.app.Sessions.List(context.Background())
+			return dialogs.OpenDialogMsg{
+				Model: commands.NewCommandDialog(a.selectedSessionID, allSessions),
+			}
+		}
 	case key.Matches(msg, a.keyMap.Models):
 		// if the app is not configured show no models
 		if !a.isConfigured {
@@ -550,6 +782,87 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 	}
 }
 
+// isAuthenticating reports whether the current page is running a
+// cancellable authentication flow with no dialog on top of it.
+func (a *appModel) isAuthenticating() bool {
+	if a.dialog.HasDialogs() {
+		return false
+	}
+	page, ok := a.pages[a.currentPage].(chat.ChatPage)
+	return ok && page.IsAuthenticating()
+}
+
+// exportSession saves the session to a file in the project, in format.
+func (a *appModel) exportSession(sessionID string, format export.Format) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		sess, err := a.app.Sessions.Get(ctx, sessionID)
+		if err != nil {
+			return util.ReportError(fmt.Errorf("failed to get session: %w", err))()
+		}
+		messages, err := a.app.Messages.List(ctx, sessionID)
+		if err != nil {
+			return util.ReportError(fmt.Errorf("failed to list messages: %w", err))()
+		}
+		name := export.FileName(sess, format)
+		if err := export.WriteFile(filepath.Join(a.app.Config().WorkingDir(), name), sess, messages, format); err != nil {
+			return util.ReportError(err)()
+		}
+		return util.ReportInfo("Exported session to " + name)()
+	}
+}
+
+// editSession opens a dialog to rename and tag the session.
+func (a *appModel) editSession(sessionID string) tea.Cmd {
+	return func() tea.Msg {
+		sess, err := a.app.Sessions.Get(context.Background(), sessionID)
+		if err != nil {
+			return util.ReportError(fmt.Errorf("failed to get session: %w", err))()
+		}
+		args := []commands.Argument{
+			{
+				Name:        "title",
+				Title:       "Title",
+				Description: "Enter the name of the session",
+				Default:     sess.Title,
+			},
+			{
+				Name:        "tags",
+				Title:       "Tags",
+				Description: "Enter tags separated by spaces, like bug auth",
+				Default:     strings.Join(sess.Tags, " "),
+			},
+		}
+		onSubmit := func(values map[string]string) tea.Cmd {
+			return func() tea.Msg {
+				ctx := context.Background()
+				sess, err := a.app.Sessions.Get(ctx, sessionID)
+				if err != nil {
+					return util.ReportError(fmt.Errorf("failed to get session: %w", err))()
+				}
+				if title := strings.TrimSpace(values["title"]); title != "" {
+					sess.Title = title
+				}
+				sess.Tags = session.ParseTags(values["tags"])
+				if _, err := a.app.Sessions.Save(ctx, sess); err != nil {
+					return util.ReportError(fmt.Errorf("failed to save session: %w", err))()
+				}
+				return util.ReportInfo("Session updated")()
+			}
+		}
+		return dialogs.OpenDialogMsg{
+			Model: commands.NewCommandArgumentsDialog(
+				"edit_session",
+				"Edit Session",
+				"edit_session",
+				"Rename the session and tag it to find it in the session picker",
+				args,
+				onSubmit,
+			),
+		}
+	}
+}
+
 // moveToPage handles navigation between different pages in the application.
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 	if a.app.AgentCoordinator.IsBusy() {
@@ -578,7 +891,10 @@ func (a *appModel) View() tea.View {
 	var view tea.View
 	t := styles.CurrentTheme()
 	view.AltScreen = true
-	view.MouseMode = tea.MouseModeCellMotion
+	view.ReportFocus = true
+	if !a.app.Config().Options.TUI.NativeSelection {
+		view.MouseMode = tea.MouseModeCellMotion
+	}
 	view.BackgroundColor = t.BgBase
 	if a.wWidth < 25 || a.wHeight < 15 {
 		view.SetContent(
@@ -683,16 +999,26 @@ func handleMCPToolsEvent(ctx context.Context, name string) tea.Cmd {
 	}
 }
 
+func handleMCPResourcesEvent(ctx context.Context, name string) tea.Cmd {
+	return func() tea.Msg {
+		mcp.RefreshResources(ctx, name)
+		return nil
+	}
+}
+
 // New creates and initializes a new TUI application model.
 func New(app *app.App) *appModel {
+	// Themes are set up first, as components take their styles when created.
+	themesErr := setupThemes(app.Config())
 	chatPage := chat.New(app)
 	keyMap := DefaultKeyMap()
+	keyMap.Actions().Configure(app.Config().Options.TUI.Keys)
 	keyMap.pageBindings = chatPage.Bindings()
 
 	model := &appModel{
 		currentPage: chat.ChatPageID,
 		app:         app,
-		status:      status.NewStatusCmp(),
+		status:      status.NewStatusCmp(app.Permissions),
 		loadedPages: make(map[page.PageID]bool),
 		keyMap:      keyMap,
 
@@ -702,6 +1028,7 @@ func New(app *app.App) *appModel {
 
 		dialog:      dialogs.NewDialogCmp(),
 		completions: completions.New(),
+		themesErr:   themesErr,
 	}
 
 	return model
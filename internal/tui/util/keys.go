@@ -0,0 +1,64 @@
+package util
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+)
+
+// KeyActions maps the names of the actions in the keys options to the key
+// bindings triggering them.
+type KeyActions map[string]*key.Binding
+
+// Configure replaces the keys of the actions with the ones set for them in
+// keys, keeping their help descriptions. The first key is shown in the help.
+func (a KeyActions) Configure(keys map[string][]string) {
+	for action, b := range a {
+		if k := keys[action]; len(k) > 0 {
+			*b = ConfigureKey(*b, k)
+		}
+	}
+}
+
+// ConfigureKey returns b triggered by keys instead, showing the first one in
+// the help.
+func ConfigureKey(b key.Binding, keys []string) key.Binding {
+	if len(keys) == 0 {
+		return b
+	}
+	return key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(keys[0], b.Help().Desc),
+	)
+}
+
+// ValidateKeys checks that keys only configures the known actions, and that
+// no key triggers two of them once configured.
+func ValidateKeys(keys map[string][]string, actions KeyActions) error {
+	for _, action := range slices.Sorted(maps.Keys(keys)) {
+		if _, ok := actions[action]; !ok {
+			return fmt.Errorf("unknown key action %q, expected one of %s", action, strings.Join(slices.Sorted(maps.Keys(actions)), ", "))
+		}
+		if len(keys[action]) == 0 {
+			return fmt.Errorf("no keys set for the %q action", action)
+		}
+	}
+
+	bound := make(map[string]string)
+	for _, action := range slices.Sorted(maps.Keys(actions)) {
+		k := actions[action].Keys()
+		if configured := keys[action]; len(configured) > 0 {
+			k = configured
+		}
+		for _, k := range k {
+			if other, ok := bound[k]; ok {
+				return fmt.Errorf("key %q is bound to both the %q and %q actions", k, other, action)
+			}
+			bound[k] = action
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"log/slog"
+	"os"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
@@ -17,6 +18,16 @@ type Model interface {
 	View() string
 }
 
+// IsRemoteSession returns whether crush runs over SSH.
+func IsRemoteSession() bool {
+	for _, env := range []string{"SSH_CONNECTION", "SSH_CLIENT", "SSH_TTY"} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func CmdHandler(msg tea.Msg) tea.Cmd {
 	return func() tea.Msg {
 		return msg
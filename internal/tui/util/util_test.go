@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+
+	"charm.land/bubbles/v2/key"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRemoteSession(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("SSH_CLIENT", "")
+	t.Setenv("SSH_TTY", "")
+	require.False(t, IsRemoteSession())
+
+	t.Setenv("SSH_TTY", "/dev/pts/0")
+	require.True(t, IsRemoteSession())
+}
+
+func TestValidateKeys(t *testing.T) {
+	t.Parallel()
+
+	quit := key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit"))
+	sessions := key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "sessions"))
+	actions := KeyActions{"quit": &quit, "sessions": &sessions}
+
+	require.NoError(t, ValidateKeys(nil, actions))
+	require.NoError(t, ValidateKeys(map[string][]string{"quit": {"ctrl+q"}, "sessions": {"ctrl+c"}}, actions))
+	require.ErrorContains(t, ValidateKeys(map[string][]string{"exit": {"ctrl+q"}}, actions), `unknown key action "exit"`)
+	require.ErrorContains(t, ValidateKeys(map[string][]string{"quit": {}}, actions), "no keys")
+	require.ErrorContains(t, ValidateKeys(map[string][]string{"sessions": {"ctrl+c"}}, actions), `key "ctrl+c" is bound to both`)
+
+	actions.Configure(map[string][]string{"quit": {"ctrl+q", "ctrl+c"}})
+	require.Equal(t, []string{"ctrl+q", "ctrl+c"}, quit.Keys())
+	require.Equal(t, "ctrl+q", quit.Help().Key)
+	require.Equal(t, "quit", quit.Help().Desc)
+}
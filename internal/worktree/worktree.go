@@ -0,0 +1,149 @@
+// Package worktree isolates the agent in a git worktree on a branch of its
+// own, so the branch checked out by the user is left untouched until the
+// changes are reviewed and merged back.
+package worktree
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/git"
+)
+
+// BranchPrefix is the prefix of the branches of the worktrees.
+const BranchPrefix = "crush/"
+
+// ErrNotRepository is returned when the working directory isn't in a git
+// repository.
+var ErrNotRepository = errors.New("worktree mode needs the working directory to be in a git repository")
+
+// Worktree is a worktree created for the agent to work in.
+type Worktree struct {
+	// RepoDir is the root of the user's checkout, and Dir the one of the
+	// worktree.
+	RepoDir string
+	Dir     string
+	// WorkingDir is the directory of the worktree matching the one Crush
+	// was started in.
+	WorkingDir string
+	Branch     string
+	// BaseBranch is the branch checked out when the worktree was created,
+	// empty when HEAD was detached, and BaseCommit the commit it pointed to.
+	BaseBranch string
+	BaseCommit string
+}
+
+var current atomic.Pointer[Worktree]
+
+// SetCurrent sets the worktree Crush works in.
+func SetCurrent(w *Worktree) {
+	current.Store(w)
+}
+
+// Current returns the worktree Crush works in, or nil when it works in the
+// user's checkout.
+func Current() *Worktree {
+	return current.Load()
+}
+
+// Create creates a worktree of the repository of workingDir in parentDir,
+// on a new branch starting at the checked out commit.
+func Create(ctx context.Context, workingDir, parentDir string) (*Worktree, error) {
+	root, err := git.Run(ctx, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotRepository
+	}
+	base, err := git.Run(ctx, root, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("the repository has no commit to start the worktree from: %w", err)
+	}
+	baseBranch, _ := git.Run(ctx, root, "symbolic-ref", "--quiet", "--short", "HEAD")
+
+	name := "crush-" + time.Now().Format("20060102-150405")
+	w := &Worktree{
+		RepoDir:    root,
+		Dir:        filepath.Join(parentDir, name),
+		WorkingDir: filepath.Join(parentDir, name),
+		Branch:     BranchPrefix + name,
+		BaseBranch: baseBranch,
+		BaseCommit: base,
+	}
+	if rel, err := filepath.Rel(root, workingDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		w.WorkingDir = filepath.Join(w.Dir, rel)
+	}
+	if _, err := git.Run(ctx, root, "worktree", "add", "--quiet", "-b", w.Branch, w.Dir, base); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Changes stages every change of the worktree and summarizes them, file by
+// file, since the worktree was created.
+func (w *Worktree) Changes(ctx context.Context) (string, error) {
+	if _, err := git.Run(ctx, w.Dir, "add", "--all"); err != nil {
+		return "", err
+	}
+	return git.Run(ctx, w.Dir, "diff", "--cached", "--stat", w.BaseCommit)
+}
+
+// Merge commits the pending changes of the worktree with message, and merges
+// its branch into the base branch, which must still be checked out in the
+// user's checkout. A merge that fails, like on conflicts, is aborted.
+func (w *Worktree) Merge(ctx context.Context, message string) error {
+	if err := w.checkBase(ctx); err != nil {
+		return err
+	}
+	if _, err := git.Run(ctx, w.Dir, "add", "--all"); err != nil {
+		return err
+	}
+	if _, err := git.Run(ctx, w.Dir, "diff", "--cached", "--quiet"); err != nil {
+		if _, err := git.Run(ctx, w.Dir, "commit", "--quiet", "--no-verify", "-m", message); err != nil {
+			return err
+		}
+	}
+	if _, err := git.Run(ctx, w.RepoDir, "merge", "--no-ff", "--no-edit", "-m", "Merge "+w.Branch, w.Branch); err != nil {
+		_, _ = git.Run(ctx, w.RepoDir, "merge", "--abort")
+		return fmt.Errorf("failed to merge %s, it's kept for you to merge: %w", w.Branch, err)
+	}
+	return nil
+}
+
+// checkBase returns an error when the user's checkout moved off the base
+// branch since the worktree was created, so the branch isn't merged into
+// another one.
+func (w *Worktree) checkBase(ctx context.Context) error {
+	branch, _ := git.Run(ctx, w.RepoDir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if branch == w.BaseBranch {
+		return nil
+	}
+	if w.BaseBranch == "" {
+		return fmt.Errorf("the worktree started from a detached HEAD, but %s is checked out now: check out %s again to merge %s", branch, w.BaseCommit, w.Branch)
+	}
+	return fmt.Errorf("the worktree started from %s, but %s is checked out now: check out %s again to merge %s", w.BaseBranch, cmp.Or(branch, "a detached HEAD"), w.BaseBranch, w.Branch)
+}
+
+// Merged reports whether the worktree has no pending changes, and its branch
+// is merged into the base branch.
+func (w *Worktree) Merged(ctx context.Context) bool {
+	status, err := git.Run(ctx, w.Dir, "status", "--porcelain")
+	if err != nil || status != "" {
+		return false
+	}
+	_, err = git.Run(ctx, w.RepoDir, "merge-base", "--is-ancestor", w.Branch, cmp.Or(w.BaseBranch, "HEAD"))
+	return err == nil
+}
+
+// Remove deletes the worktree and its branch.
+func (w *Worktree) Remove(ctx context.Context) error {
+	if _, err := git.Run(ctx, w.RepoDir, "worktree", "remove", "--force", w.Dir); err != nil {
+		return err
+	}
+	_, err := git.Run(ctx, w.RepoDir, "branch", "-D", w.Branch)
+	return err
+}
@@ -0,0 +1,74 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktree(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not in $PATH")
+	}
+
+	repo := t.TempDir()
+	run := func(dir string, args ...string) {
+		t.Helper()
+		_, err := git.Run(t.Context(), dir, args...)
+		require.NoError(t, err)
+	}
+	run(repo, "init", "--quiet", "-b", "main")
+	run(repo, "config", "user.name", "Test")
+	run(repo, "config", "user.email", "test@example.com")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "sub", "main.go"), []byte("package main\n"), 0o644))
+	run(repo, "add", ".")
+	run(repo, "commit", "--quiet", "-m", "initial")
+
+	_, err := Create(t.Context(), t.TempDir(), t.TempDir())
+	require.ErrorIs(t, err, ErrNotRepository)
+
+	w, err := Create(t.Context(), filepath.Join(repo, "sub"), filepath.Join(t.TempDir(), "worktrees"))
+	require.NoError(t, err)
+	require.Equal(t, "main", w.BaseBranch)
+	require.Equal(t, filepath.Join(w.Dir, "sub"), w.WorkingDir)
+	require.True(t, w.Merged(t.Context()))
+
+	require.NoError(t, os.WriteFile(filepath.Join(w.WorkingDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(w.WorkingDir, "new.go"), []byte("package main\n"), 0o644))
+	// The user's checkout is untouched.
+	content, err := os.ReadFile(filepath.Join(repo, "sub", "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", string(content))
+	require.False(t, w.Merged(t.Context()))
+
+	changes, err := w.Changes(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, changes, "sub/main.go")
+	require.Contains(t, changes, "sub/new.go")
+
+	// The branch isn't merged into another branch the user switched to.
+	run(repo, "checkout", "--quiet", "-b", "feature")
+	require.ErrorContains(t, w.Merge(t.Context(), "Agent changes"), "check out main again")
+	content, err = os.ReadFile(filepath.Join(repo, "sub", "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", string(content))
+	run(repo, "checkout", "--quiet", "main")
+
+	require.NoError(t, w.Merge(t.Context(), "Agent changes"))
+	content, err = os.ReadFile(filepath.Join(repo, "sub", "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+	require.FileExists(t, filepath.Join(repo, "sub", "new.go"))
+	require.True(t, w.Merged(t.Context()))
+
+	require.NoError(t, w.Remove(t.Context()))
+	require.NoDirExists(t, w.Dir)
+	_, err = git.Run(t.Context(), repo, "rev-parse", "--verify", w.Branch)
+	require.Error(t, err)
+}